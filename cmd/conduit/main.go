@@ -44,8 +44,12 @@ func main() {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	core.DefaultConfigProfiler.Log(&log)
 	shutdownInterceptor.Logger = &log
-	if err = core.Main(shutdownInterceptor, config, log); err != nil {
+	err = core.Main(shutdownInterceptor, config, log)
+	// the summaries of the suppressed events are logged before exiting
+	core.DefaultLogSampler.Close()
+	if err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/lightningnetwork/lnd/lnrpc/autopilotrpc"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var autopilotCommand = cli.Command{
+	Name:  "autopilot",
+	Usage: "Toggle and inspect LND's autopilot agent",
+	Subcommands: []cli.Command{
+		{
+			Name:  "enable",
+			Usage: "Start the autopilot agent",
+			Description: `
+	Starts the autopilot agent of the running LND instance. The change does not
+	survive a restart unless LndAutopilotActive is set in config.yaml.`,
+			Flags: []cli.Flag{
+				conduitDirFlag,
+			},
+			Action: autopilotEnable,
+		},
+		{
+			Name:   "disable",
+			Usage:  "Stop the autopilot agent",
+			Action: autopilotDisable,
+		},
+		{
+			Name:  "status",
+			Usage: "Show whether the autopilot agent is active along with its heuristics",
+			Description: `
+	Prints whether the autopilot agent is active and the heuristics it uses. Pass
+	--pubkey one or more times to also print the score every heuristic gives to
+	those nodes.`,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "pubkey",
+					Usage: "pubkey of a node to score",
+				},
+			},
+			Action: autopilotStatus,
+		},
+	},
+}
+
+// autopilotEnable is the action of the autopilot enable command
+func autopilotEnable(ctx *cli.Context) error {
+	client, cleanUp, err := getAutopilotClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runAutopilotModify(context.Background(), client, true, path.Join(ctx.String("conduitdir"), configFileName), os.Stdout)
+}
+
+// autopilotDisable is the action of the autopilot disable command
+func autopilotDisable(ctx *cli.Context) error {
+	client, cleanUp, err := getAutopilotClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runAutopilotModify(context.Background(), client, false, "", os.Stdout)
+}
+
+// autopilotStatus is the action of the autopilot status command
+func autopilotStatus(ctx *cli.Context) error {
+	client, cleanUp, err := getAutopilotClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runAutopilotStatus(context.Background(), client, ctx.StringSlice("pubkey"), os.Stdout)
+}
+
+// runAutopilotModify enables or disables the autopilot agent. When enabling, it warns if the config file doesn't activate autopilot on startup
+func runAutopilotModify(ctx context.Context, client autopilotrpc.AutopilotClient, enable bool, configFile string, out io.Writer) error {
+	if _, err := client.ModifyStatus(ctx, &autopilotrpc.ModifyStatusRequest{Enable: enable}); err != nil {
+		return fmt.Errorf("could not modify autopilot status: %v", err)
+	}
+	if !enable {
+		fmt.Fprintln(out, "Autopilot disabled")
+		return nil
+	}
+	fmt.Fprintln(out, "Autopilot enabled")
+	if !autopilotActiveInConfig(configFile) {
+		fmt.Fprintln(out, "Warning: LndAutopilotActive is not set in config.yaml, autopilot will be disabled after a restart")
+		fmt.Fprintln(out, "Run `conduitcli config set LndAutopilotActive true` to keep it enabled")
+	}
+	return nil
+}
+
+// autopilotActiveInConfig returns whether the config file activates autopilot when LND starts
+func autopilotActiveInConfig(filename string) bool {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	var config core.Config
+	if err = yaml.Unmarshal(raw, &config); err != nil {
+		return false
+	}
+	active, err := strconv.ParseBool(config.LndAutopilotActive)
+	return err == nil && active
+}
+
+// runAutopilotStatus prints the autopilot state, its heuristics and the scores of the given nodes
+func runAutopilotStatus(ctx context.Context, client autopilotrpc.AutopilotClient, pubkeys []string, out io.Writer) error {
+	status, err := client.Status(ctx, &autopilotrpc.StatusRequest{})
+	if err != nil {
+		return fmt.Errorf("could not query autopilot status: %v", err)
+	}
+	state := "inactive"
+	if status.Active {
+		state = "active"
+	}
+	fmt.Fprintf(out, "Autopilot: %s\n", state)
+	scores, err := client.QueryScores(ctx, &autopilotrpc.QueryScoresRequest{Pubkeys: pubkeys})
+	if err != nil {
+		return fmt.Errorf("could not query autopilot heuristics: %v", err)
+	}
+	fmt.Fprintln(out, "Heuristics:")
+	for _, result := range scores.Results {
+		fmt.Fprintf(out, "  %s\n", result.Heuristic)
+		nodes := make([]string, 0, len(result.Scores))
+		for node := range result.Scores {
+			nodes = append(nodes, node)
+		}
+		// highest scores first
+		sort.Slice(nodes, func(i, j int) bool {
+			if result.Scores[nodes[i]] != result.Scores[nodes[j]] {
+				return result.Scores[nodes[i]] > result.Scores[nodes[j]]
+			}
+			return nodes[i] < nodes[j]
+		})
+		for _, node := range nodes {
+			fmt.Fprintf(out, "    %s: %.4f\n", node, result.Scores[node])
+		}
+	}
+	return nil
+}
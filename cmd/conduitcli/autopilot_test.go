@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc/autopilotrpc"
+	"google.golang.org/grpc"
+)
+
+// fakeAutopilotClient is a `autopilotrpc.AutopilotClient` which records status changes and returns canned scores
+type fakeAutopilotClient struct {
+	autopilotrpc.AutopilotClient
+	active  bool
+	results []*autopilotrpc.QueryScoresResponse_HeuristicResult
+	scored  []string
+}
+
+func (f *fakeAutopilotClient) Status(ctx context.Context, in *autopilotrpc.StatusRequest, opts ...grpc.CallOption) (*autopilotrpc.StatusResponse, error) {
+	return &autopilotrpc.StatusResponse{Active: f.active}, nil
+}
+
+func (f *fakeAutopilotClient) ModifyStatus(ctx context.Context, in *autopilotrpc.ModifyStatusRequest, opts ...grpc.CallOption) (*autopilotrpc.ModifyStatusResponse, error) {
+	f.active = in.Enable
+	return &autopilotrpc.ModifyStatusResponse{}, nil
+}
+
+func (f *fakeAutopilotClient) QueryScores(ctx context.Context, in *autopilotrpc.QueryScoresRequest, opts ...grpc.CallOption) (*autopilotrpc.QueryScoresResponse, error) {
+	f.scored = in.Pubkeys
+	return &autopilotrpc.QueryScoresResponse{Results: f.results}, nil
+}
+
+// TestAutopilotModify ensures autopilot is toggled and that enabling it warns when the config doesn't activate it on startup
+func TestAutopilotModify(t *testing.T) {
+	client := &fakeAutopilotClient{}
+	configFile := path.Join(t.TempDir(), configFileName)
+	if err := ioutil.WriteFile(configFile, []byte("ConsoleOutput: true\n"), 0600); err != nil {
+		t.Fatalf("Error writing config fixture: %v", err)
+	}
+	var out bytes.Buffer
+	if err := runAutopilotModify(context.Background(), client, true, configFile, &out); err != nil {
+		t.Fatalf("runAutopilotModify returned an error: %v", err)
+	}
+	if !client.active {
+		t.Errorf("autopilot was not enabled")
+	}
+	if !strings.Contains(out.String(), "conduitcli config set LndAutopilotActive true") {
+		t.Errorf("output does not warn that autopilot won't survive a restart:\n%s", out.String())
+	}
+
+	if err := ioutil.WriteFile(configFile, []byte("ConsoleOutput: true\nlndautopilotactive: \"true\"\n"), 0600); err != nil {
+		t.Fatalf("Error writing config fixture: %v", err)
+	}
+	out.Reset()
+	if err := runAutopilotModify(context.Background(), client, true, configFile, &out); err != nil {
+		t.Fatalf("runAutopilotModify returned an error: %v", err)
+	}
+	if strings.Contains(out.String(), "Warning") {
+		t.Errorf("output warns although LndAutopilotActive is set:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runAutopilotModify(context.Background(), client, false, "", &out); err != nil {
+		t.Fatalf("runAutopilotModify returned an error: %v", err)
+	}
+	if client.active {
+		t.Errorf("autopilot was not disabled")
+	}
+}
+
+// TestAutopilotStatus ensures the state, heuristics and scores are printed with the highest score first
+func TestAutopilotStatus(t *testing.T) {
+	client := &fakeAutopilotClient{
+		active: true,
+		results: []*autopilotrpc.QueryScoresResponse_HeuristicResult{
+			{Heuristic: "preferential", Scores: map[string]float64{"02aa": 0.25, "02bb": 0.75}},
+			{Heuristic: "externalscore", Scores: map[string]float64{}},
+		},
+	}
+	var out bytes.Buffer
+	if err := runAutopilotStatus(context.Background(), client, []string{"02aa", "02bb"}, &out); err != nil {
+		t.Fatalf("runAutopilotStatus returned an error: %v", err)
+	}
+	if len(client.scored) != 2 {
+		t.Errorf("expected the 2 pubkeys to be scored, got %v", client.scored)
+	}
+	want := "Autopilot: active\nHeuristics:\n  preferential\n    02bb: 0.7500\n    02aa: 0.2500\n  externalscore\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
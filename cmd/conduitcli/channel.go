@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var channelCommand = cli.Command{
+	Name:  "channel",
+	Usage: "Manage LND channels",
+	Subcommands: []cli.Command{
+		forceCloseCommand,
+		channelEventsCommand,
+		channelRebalanceCommand,
+		channelPendingCommand,
+		channelExportCommand,
+		channelImportCommand,
+		channelHistoryCommand,
+		channelPoliciesCommand,
+	},
+}
+
+var forceCloseCommand = cli.Command{
+	Name:  "force-close",
+	Usage: "Force-close a channel",
+	Description: `
+	Force-closes the channel with the given channel point after displaying its
+	details. To confirm, the full channel point must be typed unless --yes is set.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "channel-point",
+			Usage: "the channel point of the channel to close, in the form txid:output",
+		},
+		cli.BoolFlag{
+			Name:  "yes",
+			Usage: "skip the confirmation prompt",
+		},
+	},
+	Action: forceClose,
+}
+
+// parseChannelPoint parses a channel point of the form txid:output
+func parseChannelPoint(s string) (*lnrpc.ChannelPoint, error) {
+	split := strings.Split(s, ":")
+	if len(split) != 2 || len(split[0]) == 0 {
+		return nil, fmt.Errorf("expected channel point of the form txid:output, got %v", s)
+	}
+	index, err := strconv.ParseUint(split[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output index %v: %v", split[1], err)
+	}
+	return &lnrpc.ChannelPoint{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{FundingTxidStr: split[0]},
+		OutputIndex: uint32(index),
+	}, nil
+}
+
+// findChannel returns the open channel with the given channel point
+func findChannel(ctx context.Context, client lnrpc.LightningClient, chanPoint string) (*lnrpc.Channel, error) {
+	resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range resp.Channels {
+		if channel.ChannelPoint == chanPoint {
+			return channel, nil
+		}
+	}
+	return nil, fmt.Errorf("no open channel with channel point %v", chanPoint)
+}
+
+// forceClose is the action of the force-close command
+func forceClose(ctx *cli.Context) error {
+	if !ctx.IsSet("channel-point") {
+		return cli.ShowCommandHelp(ctx, "force-close")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runForceClose(context.Background(), client, ctx.String("channel-point"), ctx.Bool("yes"), os.Stdin, os.Stdout)
+}
+
+// runForceClose displays the channel details, asks for confirmation and force-closes the channel
+func runForceClose(ctx context.Context, client lnrpc.LightningClient, chanPoint string, yes bool, in io.Reader, out io.Writer) error {
+	point, err := parseChannelPoint(chanPoint)
+	if err != nil {
+		return err
+	}
+	channel, err := findChannel(ctx, client, chanPoint)
+	if err != nil {
+		return err
+	}
+	info, err := client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{ChanId: channel.ChanId})
+	if err != nil {
+		return err
+	}
+	csvDelay := channel.CsvDelay
+	if channel.LocalConstraints != nil {
+		csvDelay = channel.LocalConstraints.CsvDelay
+	}
+	fmt.Fprintf(out, "Channel ID:     %d\n", info.ChannelId)
+	fmt.Fprintf(out, "Channel Point:  %s\n", info.ChanPoint)
+	fmt.Fprintf(out, "Remote Pubkey:  %s\n", channel.RemotePubkey)
+	fmt.Fprintf(out, "Capacity:       %d sats\n", info.Capacity)
+	fmt.Fprintf(out, "Local Balance:  %d sats\n", channel.LocalBalance)
+	fmt.Fprintf(out, "Remote Balance: %d sats\n", channel.RemoteBalance)
+	if !yes {
+		fmt.Fprintf(out, "This will force-close channel %d with %s. Funds will be time-locked for %d blocks. Type the channel point to confirm: ", channel.ChanId, channel.RemotePubkey, csvDelay)
+		answer, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if strings.TrimSpace(answer) != chanPoint {
+			return fmt.Errorf("channel point did not match, aborting")
+		}
+	}
+	stream, err := client.CloseChannel(ctx, &lnrpc.CloseChannelRequest{ChannelPoint: point, Force: true})
+	if err != nil {
+		return err
+	}
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if pending := update.GetClosePending(); pending != nil {
+			txid, err := chainhash.NewHash(pending.Txid)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Channel force-close broadcast, closing txid: %v\n", txid)
+			return nil
+		}
+	}
+}
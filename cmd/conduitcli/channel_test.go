@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newForceCloseClient returns a fake client with a single open channel
+func newForceCloseClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		channels: []*lnrpc.Channel{{
+			ChanId:           1234,
+			ChannelPoint:     "abcd:1",
+			RemotePubkey:     "02peer",
+			LocalBalance:     50000,
+			LocalConstraints: &lnrpc.ChannelConstraints{CsvDelay: 144},
+		}},
+		chanInfo: map[uint64]*lnrpc.ChannelEdge{1234: {ChannelId: 1234, ChanPoint: "abcd:1", Capacity: 100000}},
+		closeUpdates: []*lnrpc.CloseStatusUpdate{{
+			Update: &lnrpc.CloseStatusUpdate_ClosePending{ClosePending: &lnrpc.PendingUpdate{Txid: make([]byte, 32)}},
+		}},
+	}
+}
+
+// TestRunForceClose ensures the channel is only force-closed once the full channel point is typed
+func TestRunForceClose(t *testing.T) {
+	tables := []struct {
+		input  string
+		yes    bool
+		closed bool
+	}{
+		{"abcd:1\n", false, true},
+		{"abcd\n", false, false},
+		{"yes\n", false, false},
+		{"", true, true},
+	}
+	for _, table := range tables {
+		client := newForceCloseClient()
+		var out bytes.Buffer
+		err := runForceClose(context.Background(), client, "abcd:1", table.yes, strings.NewReader(table.input), &out)
+		if table.closed {
+			if err != nil {
+				t.Errorf("runForceClose returned an error for input %q: %v", table.input, err)
+			}
+			if len(client.closeReqs) != 1 || !client.closeReqs[0].Force {
+				t.Errorf("expected a single forced CloseChannel call for input %q", table.input)
+			}
+		} else if err == nil || len(client.closeReqs) != 0 {
+			t.Errorf("runForceClose closed the channel for input %q", table.input)
+		}
+		if !table.yes && !strings.Contains(out.String(), "This will force-close channel 1234 with 02peer. Funds will be time-locked for 144 blocks.") {
+			t.Errorf("unexpected prompt: %s", out.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/urfave/cli"
+)
+
+var channelEventsCommand = cli.Command{
+	Name:  "events",
+	Usage: "Show the recorded channel lifecycle events",
+	Description: `
+	Reads the channel events recorded by Conduit in channel_events.csv. --since
+	accepts a timestamp (2006-01-02T15:04:05Z07:00), a date (2006-01-02) or a
+	duration relative to now (i.e. 72h).`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "only show events recorded at or after this time",
+		},
+		cli.BoolFlag{
+			Name:  "csv",
+			Usage: "print the events as CSV",
+		},
+		conduitDirFlag,
+	},
+	Action: channelEvents,
+}
+
+// parseSince parses a timestamp, a date or a duration relative to now
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a timestamp, a date or a duration", s)
+}
+
+// channelEvents is the action of the channel events command
+func channelEvents(ctx *cli.Context) error {
+	since, err := parseSince(ctx.String("since"), time.Now())
+	if err != nil {
+		return err
+	}
+	events, err := core.ReadChannelEvents(core.ChannelEventsPath(&core.Config{ConduitDir: ctx.String("conduitdir")}), since)
+	if err != nil {
+		return fmt.Errorf("could not read channel events: %v", err)
+	}
+	return printChannelEvents(events, ctx.Bool("csv"), os.Stdout)
+}
+
+// printChannelEvents prints the events as a table or as CSV
+func printChannelEvents(events []*core.ChannelEvent, asCSV bool, out io.Writer) error {
+	if asCSV {
+		w := csv.NewWriter(out)
+		w.Write(core.ChannelEventsHeader)
+		for _, e := range events {
+			w.Write(e.Row())
+		}
+		w.Flush()
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tEVENT\tCHANNEL POINT\tREMOTE PUBKEY\tCAPACITY\tLOCAL\tREMOTE")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\n", e.Timestamp.UTC().Format(time.RFC3339), e.EventType, e.ChannelPoint, e.RemotePubkey, e.CapacitySats, e.LocalBalance, e.RemoteBalance)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const (
+	day                  = 24 * time.Hour
+	forwardingPageSize   = 10000
+	defaultHistoryPeriod = "30d"
+)
+
+// sparkLevels are the characters of a spark-line, from the lowest value to the highest
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+var channelHistoryCommand = cli.Command{
+	Name:      "history",
+	Usage:     "Show the estimated daily local balance of a channel",
+	ArgsUsage: "chan-id",
+	Description: `
+	Reads LND's forwarding history over --period and estimates the local balance
+	of the channel at the end of every day, working backwards from its current
+	local balance: forwards coming in through the channel add to the balance and
+	those going out through it subtract from it. Payments, invoices and
+	rebalances aren't part of the forwarding history, so the balances of
+	channels used for them are rough. --period accepts a number of days (i.e.
+	30d) or a duration (i.e. 72h).`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "period",
+			Usage: "the period of the history, relative to now",
+			Value: defaultHistoryPeriod,
+		},
+		cli.BoolFlag{
+			Name:  "sparkline",
+			Usage: "print the balances as a spark-line rather than a table",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the daily balances as JSON",
+		},
+	},
+	Action: channelHistory,
+}
+
+// dailyBalance is the forwards through a channel during a day and its estimated local balance at the end of the day
+type dailyBalance struct {
+	Date             string `json:"date"`
+	InMsat           uint64 `json:"in_msat"`
+	OutMsat          uint64 `json:"out_msat"`
+	LocalBalanceMsat int64  `json:"local_balance_msat"`
+}
+
+// parsePeriod parses a number of days, i.e. 30d, or a duration
+func parsePeriod(s string) (time.Duration, error) {
+	if days, err := strconv.ParseUint(strings.TrimSuffix(s, "d"), 10, 16); err == nil && strings.HasSuffix(s, "d") {
+		return time.Duration(days) * day, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid period %q, expected a number of days or a duration", s)
+	}
+	return d, nil
+}
+
+// channelHistory is the action of the channel history command
+func channelHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "history")
+	}
+	chanID, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid channel ID %v", ctx.Args().First())
+	}
+	period, err := parsePeriod(ctx.String("period"))
+	if err != nil {
+		return err
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	balances, err := channelBalanceHistory(context.Background(), client, chanID, period, time.Now())
+	if err != nil {
+		return err
+	}
+	switch {
+	case ctx.Bool("json"):
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(balances)
+	case ctx.Bool("sparkline"):
+		printSparkline(balances, os.Stdout)
+		return nil
+	}
+	return printBalanceHistory(balances, os.Stdout)
+}
+
+// channelBalanceHistory returns the estimated local balance of the channel at the end of every UTC day of the period, the oldest first
+func channelBalanceHistory(ctx context.Context, client lnrpc.LightningClient, chanID uint64, period time.Duration, now time.Time) ([]*dailyBalance, error) {
+	if period < day {
+		return nil, fmt.Errorf("--period must be at least a day")
+	}
+	resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	channel, err := findChannelByID(resp.Channels, chanID)
+	if err != nil {
+		return nil, err
+	}
+	now = now.UTC()
+	start := now.Add(-period).Truncate(day)
+	var balances []*dailyBalance
+	for d := start; d.Before(now); d = d.Add(day) {
+		balances = append(balances, &dailyBalance{Date: d.Format("2006-01-02")})
+	}
+	var offset uint32
+	for {
+		history, err := client.ForwardingHistory(ctx, &lnrpc.ForwardingHistoryRequest{
+			StartTime:    uint64(start.Unix()),
+			EndTime:      uint64(now.Unix()),
+			IndexOffset:  offset,
+			NumMaxEvents: forwardingPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read forwarding history: %v", err)
+		}
+		for _, event := range history.ForwardingEvents {
+			i := int(time.Unix(0, int64(event.TimestampNs)).UTC().Sub(start) / day)
+			if i < 0 || i >= len(balances) {
+				continue
+			}
+			if event.ChanIdIn == chanID {
+				balances[i].InMsat += event.AmtInMsat
+			}
+			if event.ChanIdOut == chanID {
+				balances[i].OutMsat += event.AmtOutMsat
+			}
+		}
+		if len(history.ForwardingEvents) < forwardingPageSize {
+			break
+		}
+		offset = history.LastOffsetIndex
+	}
+	// the balance at the start of the period is the current one without the forwards of the period
+	balance := channel.LocalBalance * 1000
+	for _, b := range balances {
+		balance += int64(b.OutMsat) - int64(b.InMsat)
+	}
+	for _, b := range balances {
+		balance += int64(b.InMsat) - int64(b.OutMsat)
+		b.LocalBalanceMsat = balance
+	}
+	return balances, nil
+}
+
+// printBalanceHistory prints the daily balances as a table
+func printBalanceHistory(balances []*dailyBalance, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tIN (SATS)\tOUT (SATS)\tLOCAL BALANCE (SATS)")
+	for _, b := range balances {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", b.Date, b.InMsat/1000, b.OutMsat/1000, b.LocalBalanceMsat/1000)
+	}
+	return w.Flush()
+}
+
+// printSparkline prints the daily balances as a spark-line between the lowest and the highest balance
+func printSparkline(balances []*dailyBalance, out io.Writer) {
+	if len(balances) == 0 {
+		return
+	}
+	low, high := balances[0].LocalBalanceMsat, balances[0].LocalBalanceMsat
+	for _, b := range balances {
+		if b.LocalBalanceMsat < low {
+			low = b.LocalBalanceMsat
+		}
+		if b.LocalBalanceMsat > high {
+			high = b.LocalBalanceMsat
+		}
+	}
+	line := make([]rune, len(balances))
+	for i, b := range balances {
+		level := 0
+		if high > low {
+			level = int((b.LocalBalanceMsat - low) * int64(len(sparkLevels)-1) / (high - low))
+		}
+		line[i] = sparkLevels[level]
+	}
+	fmt.Fprintf(out, "Local balance from %s to %s\n", balances[0].Date, balances[len(balances)-1].Date)
+	fmt.Fprintln(out, string(line))
+	fmt.Fprintf(out, "low %d sats, high %d sats, now %d sats\n", low/1000, high/1000, balances[len(balances)-1].LocalBalanceMsat/1000)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newHistoryClient returns a fake client with channel 7, now holding 500k sats, and a forwarding history over the last 3 days in
+// which channel 7 drained on the second day
+func newHistoryClient(now time.Time) *fakeLightningClient {
+	forward := func(daysAgo int, in, out uint64, amtMsat uint64) *lnrpc.ForwardingEvent {
+		ts := now.Truncate(day).Add(-time.Duration(daysAgo)*day + time.Hour)
+		return &lnrpc.ForwardingEvent{TimestampNs: uint64(ts.UnixNano()), ChanIdIn: in, ChanIdOut: out, AmtInMsat: amtMsat + 1000, AmtOutMsat: amtMsat}
+	}
+	return &fakeLightningClient{
+		channels: []*lnrpc.Channel{{ChanId: 3, LocalBalance: 100000}, {ChanId: 7, LocalBalance: 500000}},
+		forwards: []*lnrpc.ForwardingEvent{
+			// before the period
+			forward(5, 3, 7, 900000000),
+			forward(2, 7, 3, 100000000),
+			forward(1, 3, 7, 300000000),
+			forward(1, 3, 7, 200000000),
+			// not through channel 7
+			forward(1, 3, 9, 50000000),
+			forward(0, 7, 3, 400000000),
+		},
+	}
+}
+
+// TestChannelBalanceHistory ensures the daily balances are estimated backwards from the current balance with the forwards through the channel
+func TestChannelBalanceHistory(t *testing.T) {
+	now := time.Date(2022, 6, 10, 12, 0, 0, 0, time.UTC)
+	client := newHistoryClient(now)
+	balances, err := channelBalanceHistory(context.Background(), client, 7, 3*day, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 4 || balances[0].Date != "2022-06-07" || balances[3].Date != "2022-06-10" {
+		t.Fatalf("expected the balances of June 7 to 10, got %+v", balances)
+	}
+	expected := []int64{499998, 599999, 99999, 500000}
+	for i, b := range balances {
+		if b.LocalBalanceMsat/1000 != expected[i] {
+			t.Errorf("%s: expected a local balance of %d sats, got %d msat", b.Date, expected[i], b.LocalBalanceMsat)
+		}
+	}
+	if balances[2].OutMsat != 500000000 || balances[2].InMsat != 0 || balances[3].InMsat != 400001000 {
+		t.Errorf("unexpected forwards %+v, %+v", balances[2], balances[3])
+	}
+	if balances[3].LocalBalanceMsat != 500000000 {
+		t.Errorf("expected the current balance today, got %d msat", balances[3].LocalBalanceMsat)
+	}
+	if req := client.forwardReqs[0]; req.StartTime != uint64(time.Date(2022, 6, 7, 0, 0, 0, 0, time.UTC).Unix()) || req.EndTime != uint64(now.Unix()) {
+		t.Errorf("unexpected ForwardingHistory request %v", req)
+	}
+	if _, err = channelBalanceHistory(context.Background(), client, 8, 3*day, now); err == nil {
+		t.Error("expected an error for a channel which isn't open")
+	}
+	if _, err = channelBalanceHistory(context.Background(), client, 7, time.Hour, now); err == nil {
+		t.Error("expected an error for a period shorter than a day")
+	}
+}
+
+// TestChannelHistoryOutput ensures the balances are printed in sats and the spark-line spans the lowest to the highest balance
+func TestChannelHistoryOutput(t *testing.T) {
+	balances := []*dailyBalance{
+		{Date: "2022-06-08", InMsat: 2000, LocalBalanceMsat: 800000},
+		{Date: "2022-06-09", OutMsat: 700000, LocalBalanceMsat: 100000},
+		{Date: "2022-06-10", LocalBalanceMsat: 450000},
+	}
+	var out bytes.Buffer
+	if err := printBalanceHistory(balances, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || strings.Join(strings.Fields(lines[2]), " ") != "2022-06-09 0 700 100" {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+	out.Reset()
+	printSparkline(balances, &out)
+	if lines = strings.Split(out.String(), "\n"); lines[1] != "█▁▄" {
+		t.Errorf("unexpected spark-line:\n%s", out.String())
+	}
+}
+
+// TestParsePeriod ensures periods are given in days or as durations
+func TestParsePeriod(t *testing.T) {
+	for s, expected := range map[string]time.Duration{"30d": 30 * day, "1d": day, "72h": 72 * time.Hour} {
+		if d, err := parsePeriod(s); err != nil || d != expected {
+			t.Errorf("%s: expected %v, got %v: %v", s, expected, d, err)
+		}
+	}
+	for _, s := range []string{"", "d", "-1d", "1w"} {
+		if _, err := parsePeriod(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const almostMatureBlocks = 6
+
+// pendingTypes are the values of the --type flag of the channel pending command
+var pendingTypes = []string{"open", "close", "force-close", "waiting-close"}
+
+var channelPendingCommand = cli.Command{
+	Name:  "pending",
+	Usage: "List the channels being opened or closed",
+	Description: `
+	Lists the pending channels returned by PendingChannels. Force-closed channels
+	whose funds mature within 6 blocks are marked with a !.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the pending channels as JSON",
+		},
+		cli.StringFlag{
+			Name:  "type",
+			Usage: "only list the channels of this type, one of " + strings.Join(pendingTypes, ", "),
+		},
+	},
+	Action: channelPending,
+}
+
+// pendingRow is a pending channel of any type
+type pendingRow struct {
+	Type              string `json:"type"`
+	Txid              string `json:"txid"`
+	RemotePubkey      string `json:"remote_pubkey"`
+	LocalBalance      int64  `json:"local_balance"`
+	RemoteBalance     int64  `json:"remote_balance"`
+	BlocksTilMaturity *int32 `json:"blocks_til_maturity,omitempty"`
+	AlmostMature      bool   `json:"almost_mature"`
+}
+
+// newPendingRow returns the row of a pending channel, with the closing txid if known and the channel point's txid otherwise
+func newPendingRow(typ string, channel *lnrpc.PendingChannelsResponse_PendingChannel, closingTxid string) *pendingRow {
+	txid := closingTxid
+	if txid == "" {
+		txid = strings.Split(channel.GetChannelPoint(), ":")[0]
+	}
+	return &pendingRow{
+		Type:          typ,
+		Txid:          txid,
+		RemotePubkey:  channel.GetRemoteNodePub(),
+		LocalBalance:  channel.GetLocalBalance(),
+		RemoteBalance: channel.GetRemoteBalance(),
+	}
+}
+
+// flattenPendingChannels returns the pending channels of the given type, or of all types if typ is empty
+func flattenPendingChannels(resp *lnrpc.PendingChannelsResponse, typ string) []*pendingRow {
+	var rows []*pendingRow
+	if typ == "" || typ == "open" {
+		for _, c := range resp.PendingOpenChannels {
+			rows = append(rows, newPendingRow("open", c.Channel, ""))
+		}
+	}
+	if typ == "" || typ == "close" {
+		for _, c := range resp.PendingClosingChannels {
+			rows = append(rows, newPendingRow("close", c.Channel, c.ClosingTxid))
+		}
+	}
+	if typ == "" || typ == "force-close" {
+		for _, c := range resp.PendingForceClosingChannels {
+			row := newPendingRow("force-close", c.Channel, c.ClosingTxid)
+			blocks := c.BlocksTilMaturity
+			row.BlocksTilMaturity = &blocks
+			row.AlmostMature = blocks < almostMatureBlocks
+			rows = append(rows, row)
+		}
+	}
+	if typ == "" || typ == "waiting-close" {
+		for _, c := range resp.WaitingCloseChannels {
+			rows = append(rows, newPendingRow("waiting-close", c.Channel, c.ClosingTxid))
+		}
+	}
+	return rows
+}
+
+// channelPending is the action of the channel pending command
+func channelPending(ctx *cli.Context) error {
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runChannelPending(context.Background(), client, ctx.String("type"), ctx.Bool("json"), os.Stdout)
+}
+
+// runChannelPending prints the pending channels as a table or as JSON
+func runChannelPending(ctx context.Context, client lnrpc.LightningClient, typ string, asJSON bool, out io.Writer) error {
+	valid := typ == ""
+	for _, t := range pendingTypes {
+		valid = valid || typ == t
+	}
+	if !valid {
+		return fmt.Errorf("unknown type %q, expected one of %s", typ, strings.Join(pendingTypes, ", "))
+	}
+	resp, err := client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	rows := flattenPendingChannels(resp, typ)
+	if asJSON {
+		if rows == nil {
+			rows = []*pendingRow{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(rows)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "\tTYPE\tTXID\tREMOTE PUBKEY\tLOCAL BALANCE\tREMOTE BALANCE\tBLOCKS UNTIL MATURITY")
+	for _, row := range rows {
+		// a marker column keeps the table aligned, unlike colors
+		marker := ""
+		if row.AlmostMature {
+			marker = "!"
+		}
+		maturity := "-"
+		if row.BlocksTilMaturity != nil {
+			maturity = fmt.Sprint(*row.BlocksTilMaturity)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", marker, row.Type, row.Txid, row.RemotePubkey, row.LocalBalance, row.RemoteBalance, maturity)
+	}
+	return w.Flush()
+}
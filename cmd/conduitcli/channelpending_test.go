@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newPendingClient returns a fake client with one pending channel of each type and two force-closed channels, one of which is almost mature
+func newPendingClient() *fakeLightningClient {
+	channel := func(pubkey, point string) *lnrpc.PendingChannelsResponse_PendingChannel {
+		return &lnrpc.PendingChannelsResponse_PendingChannel{RemoteNodePub: pubkey, ChannelPoint: point, LocalBalance: 600000, RemoteBalance: 400000}
+	}
+	return &fakeLightningClient{pending: &lnrpc.PendingChannelsResponse{
+		PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{
+			{Channel: channel(peerAPubkey, "aa:0")},
+		},
+		PendingClosingChannels: []*lnrpc.PendingChannelsResponse_ClosedChannel{
+			{Channel: channel(peerBPubkey, "bb:0"), ClosingTxid: "b1"},
+		},
+		PendingForceClosingChannels: []*lnrpc.PendingChannelsResponse_ForceClosedChannel{
+			{Channel: channel(peerCPubkey, "cc:0"), ClosingTxid: "c1", BlocksTilMaturity: 3},
+			{Channel: channel(peerCPubkey, "cc:1"), ClosingTxid: "c2", BlocksTilMaturity: 144},
+		},
+		WaitingCloseChannels: []*lnrpc.PendingChannelsResponse_WaitingCloseChannel{
+			{Channel: channel(peerAPubkey, "dd:0")},
+		},
+	}}
+}
+
+// TestChannelPending ensures every type of pending channel is listed and only force closes maturing within 6 blocks are highlighted
+func TestChannelPending(t *testing.T) {
+	var out bytes.Buffer
+	if err := runChannelPending(context.Background(), newPendingClient(), "", true, &out); err != nil {
+		t.Fatalf("runChannelPending returned an error: %v", err)
+	}
+	var rows []*pendingRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	expected := []struct {
+		typ, txid    string
+		almostMature bool
+	}{
+		{"open", "aa", false},
+		{"close", "b1", false},
+		{"force-close", "c1", true},
+		{"force-close", "c2", false},
+		{"waiting-close", "dd", false},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d pending channels, got %d", len(expected), len(rows))
+	}
+	for i, row := range rows {
+		if row.Type != expected[i].typ || row.Txid != expected[i].txid || row.AlmostMature != expected[i].almostMature {
+			t.Errorf("channel %d: expected %+v, got %+v", i, expected[i], *row)
+		}
+		if (row.BlocksTilMaturity != nil) != (row.Type == "force-close") {
+			t.Errorf("channel %d: expected blocks until maturity only for force closes, got %+v", i, *row)
+		}
+	}
+}
+
+// TestChannelPendingTable ensures the almost mature force close is marked in the table and the type filter applies
+func TestChannelPendingTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := runChannelPending(context.Background(), newPendingClient(), "force-close", false, &out); err != nil {
+		t.Fatalf("runChannelPending returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 force closes, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "!" || fields[2] != "c1" || fields[len(fields)-1] != "3" {
+		t.Errorf("expected the first force close to be marked, got %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] == "!" || fields[len(fields)-1] != "144" {
+		t.Errorf("expected the second force close not to be marked, got %q", lines[2])
+	}
+	if err := runChannelPending(context.Background(), newPendingClient(), "opening", false, &out); err == nil {
+		t.Error("expected an unknown type to be rejected")
+	}
+}
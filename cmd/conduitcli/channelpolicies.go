@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const (
+	policySideLocal  = "local"
+	policySideRemote = "remote"
+)
+
+var channelPoliciesCommand = cli.Command{
+	Name:      "policies",
+	Usage:     "Show the fee policies of both sides of a channel",
+	ArgsUsage: "chan-id",
+	Description: `
+	Prints the routing policies announced by us and by the peer for the channel.
+	The remote policy is marked with a '!' when it charges more than ours for
+	every amount, i.e. its base fee and fee rate are both at least ours and one
+	of them is higher, which can make senders prefer other paths to us.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the policies as JSON",
+		},
+	},
+	Action: channelPolicies,
+}
+
+// policyRow is one side of the routing policy of a channel
+type policyRow struct {
+	Side          string `json:"side"`
+	BaseFeeMsat   int64  `json:"base_fee_msat"`
+	FeeRatePPM    int64  `json:"fee_rate_ppm"`
+	TimeLockDelta uint32 `json:"time_lock_delta"`
+	MinHTLCMsat   int64  `json:"min_htlc_msat"`
+	MaxHTLCMsat   uint64 `json:"max_htlc_msat"`
+	Disabled      bool   `json:"disabled"`
+}
+
+// channelPoliciesReport is the routing policies of both sides of a channel. A side without a policy yet is nil
+type channelPoliciesReport struct {
+	ChanID           uint64     `json:"chan_id"`
+	ChanPoint        string     `json:"chan_point"`
+	Local            *policyRow `json:"local"`
+	Remote           *policyRow `json:"remote"`
+	RemoteHigherFees bool       `json:"remote_higher_fees"`
+}
+
+// newPolicyRow returns the row of a routing policy, or nil if the side has no policy
+func newPolicyRow(side string, policy *lnrpc.RoutingPolicy) *policyRow {
+	if policy == nil {
+		return nil
+	}
+	return &policyRow{
+		Side:          side,
+		BaseFeeMsat:   policy.FeeBaseMsat,
+		FeeRatePPM:    policy.FeeRateMilliMsat,
+		TimeLockDelta: policy.TimeLockDelta,
+		MinHTLCMsat:   policy.MinHtlc,
+		MaxHTLCMsat:   policy.MaxHtlcMsat,
+		Disabled:      policy.Disabled,
+	}
+}
+
+// higherFees returns whether the remote policy charges more than the local one for every amount
+func higherFees(local, remote *policyRow) bool {
+	if local == nil || remote == nil {
+		return false
+	}
+	return remote.BaseFeeMsat >= local.BaseFeeMsat && remote.FeeRatePPM >= local.FeeRatePPM &&
+		(remote.BaseFeeMsat > local.BaseFeeMsat || remote.FeeRatePPM > local.FeeRatePPM)
+}
+
+// channelPolicies is the action of the channel policies command
+func channelPolicies(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "policies")
+	}
+	chanID, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid channel ID %v", ctx.Args().First())
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runChannelPolicies(context.Background(), client, chanID, ctx.Bool("json"), os.Stdout)
+}
+
+// channelPoliciesOf returns the local and remote routing policies of one of our channels
+func channelPoliciesOf(ctx context.Context, client lnrpc.LightningClient, chanID uint64) (*channelPoliciesReport, error) {
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+	edge, err := client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{ChanId: chanID})
+	if err != nil {
+		return nil, err
+	}
+	report := &channelPoliciesReport{ChanID: chanID, ChanPoint: edge.ChanPoint}
+	switch info.IdentityPubkey {
+	case edge.Node1Pub:
+		report.Local, report.Remote = newPolicyRow(policySideLocal, edge.Node1Policy), newPolicyRow(policySideRemote, edge.Node2Policy)
+	case edge.Node2Pub:
+		report.Local, report.Remote = newPolicyRow(policySideLocal, edge.Node2Policy), newPolicyRow(policySideRemote, edge.Node1Policy)
+	default:
+		return nil, fmt.Errorf("channel %d is not one of our channels", chanID)
+	}
+	report.RemoteHigherFees = higherFees(report.Local, report.Remote)
+	return report, nil
+}
+
+// runChannelPolicies prints the routing policies of both sides of the channel as a table or as JSON
+func runChannelPolicies(ctx context.Context, client lnrpc.LightningClient, chanID uint64, asJSON bool, out io.Writer) error {
+	report, err := channelPoliciesOf(ctx, client, chanID)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(report)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "\tSIDE\tBASE FEE (MSAT)\tFEE RATE (PPM)\tTIME LOCK DELTA\tMIN HTLC (MSAT)\tMAX HTLC (MSAT)\tDISABLED")
+	for _, row := range []*policyRow{report.Local, report.Remote} {
+		if row == nil {
+			continue
+		}
+		// a marker column keeps the table aligned, unlike colors
+		marker := ""
+		if row.Side == policySideRemote && report.RemoteHigherFees {
+			marker = "!"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%v\n", marker, row.Side, row.BaseFeeMsat, row.FeeRatePPM, row.TimeLockDelta, row.MinHTLCMsat, row.MaxHTLCMsat, row.Disabled)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if report.Local == nil || report.Remote == nil {
+		fmt.Fprintln(out, "A side of the channel hasn't announced its policy yet")
+	}
+	if report.RemoteHigherFees {
+		fmt.Fprintln(out, "The remote policy charges higher fees than ours")
+	}
+	return nil
+}
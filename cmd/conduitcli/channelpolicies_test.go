@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newPoliciesClient returns a fake client knowing a channel with the given remote and local policies, our node being the second node of the edge
+func newPoliciesClient(local, remote *lnrpc.RoutingPolicy) *fakeLightningClient {
+	return &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{IdentityPubkey: selfPubkey},
+		chanInfo: map[uint64]*lnrpc.ChannelEdge{
+			7: {ChannelId: 7, ChanPoint: "aa:0", Node1Pub: peerAPubkey, Node2Pub: selfPubkey, Node1Policy: remote, Node2Policy: local},
+		},
+	}
+}
+
+// TestChannelPoliciesHighlight ensures the remote policy is only highlighted when it charges more than ours for every amount
+func TestChannelPoliciesHighlight(t *testing.T) {
+	local := &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 100, TimeLockDelta: 40, MinHtlc: 1000, MaxHtlcMsat: 990000000}
+	tests := []struct {
+		name     string
+		remote   *lnrpc.RoutingPolicy
+		expected bool
+	}{
+		{"same fees", &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 100}, false},
+		{"higher base fee", &lnrpc.RoutingPolicy{FeeBaseMsat: 2000, FeeRateMilliMsat: 100}, true},
+		{"higher fee rate", &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 500}, true},
+		{"lower fees", &lnrpc.RoutingPolicy{FeeBaseMsat: 0, FeeRateMilliMsat: 1}, false},
+		{"higher base fee but lower fee rate", &lnrpc.RoutingPolicy{FeeBaseMsat: 5000, FeeRateMilliMsat: 10}, false},
+		{"no remote policy", nil, false},
+	}
+	for _, test := range tests {
+		report, err := channelPoliciesOf(context.Background(), newPoliciesClient(local, test.remote), 7)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if report.RemoteHigherFees != test.expected {
+			t.Errorf("%s: expected RemoteHigherFees %v, got %v", test.name, test.expected, report.RemoteHigherFees)
+		}
+	}
+}
+
+// TestChannelPolicies ensures both sides are printed, the remote one marked when its fees are higher, and that other channels are rejected
+func TestChannelPolicies(t *testing.T) {
+	local := &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 100, TimeLockDelta: 40, MinHtlc: 1000, MaxHtlcMsat: 990000000}
+	remote := &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 2500, TimeLockDelta: 144, MinHtlc: 1, MaxHtlcMsat: 500000000, Disabled: true}
+	client := newPoliciesClient(local, remote)
+	var out bytes.Buffer
+	if err := runChannelPolicies(context.Background(), client, 7, false, &out); err != nil {
+		t.Fatalf("runChannelPolicies returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header, two policies and a note, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "local 1000 100 40 1000 990000000 false" {
+		t.Errorf("unexpected local policy %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "! remote 1000 2500 144 1 500000000 true" {
+		t.Errorf("unexpected remote policy %q", lines[2])
+	}
+	out.Reset()
+	if err := runChannelPolicies(context.Background(), client, 7, true, &out); err != nil {
+		t.Fatalf("runChannelPolicies returned an error: %v", err)
+	}
+	var report channelPoliciesReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if report.ChanPoint != "aa:0" || report.Local.FeeRatePPM != 100 || report.Remote.FeeRatePPM != 2500 || !report.RemoteHigherFees {
+		t.Errorf("unexpected report %+v", report)
+	}
+	client.info.IdentityPubkey = peerBPubkey
+	if err := runChannelPolicies(context.Background(), client, 7, false, &out); err == nil {
+		t.Error("expected an error for a channel of other nodes")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const channelSnapshotVersion = 1
+
+var channelExportCommand = cli.Command{
+	Name:  "export",
+	Usage: "Export all channel data to a JSON snapshot",
+	Description: `
+	Writes the open, pending and closed channels along with a static channel
+	backup of all channels to a single JSON file, to keep a record of the node
+	when migrating it. The file can be restored with 'channel import'.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "the JSON file to write the snapshot to",
+		},
+	},
+	Action: channelExport,
+}
+
+var channelImportCommand = cli.Command{
+	Name:  "import",
+	Usage: "Restore the channel backup of a JSON snapshot",
+	Description: `
+	Verifies the checksum of a snapshot written by 'channel export' and restores
+	its static channel backup. LND then asks the peers of the channels to
+	force-close them, sweeping the funds back to the wallet. To confirm, 'restore'
+	must be typed unless --yes is set.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "input",
+			Usage: "the JSON snapshot to restore",
+		},
+		cli.BoolFlag{
+			Name:  "yes",
+			Usage: "skip the confirmation prompt",
+		},
+	},
+	Action: channelImport,
+}
+
+// channelSnapshot is the JSON file written by the channel export command. The LND responses are in proto JSON
+type channelSnapshot struct {
+	Version   int             `json:"version"`
+	CreatedAt string          `json:"created_at"`
+	Open      json.RawMessage `json:"open_channels"`
+	Pending   json.RawMessage `json:"pending_channels"`
+	Closed    json.RawMessage `json:"closed_channels"`
+	Backup    json.RawMessage `json:"channel_backup"`
+	// Checksum is the hex SHA256 of the snapshot encoded as compact JSON with an empty checksum
+	Checksum string `json:"checksum"`
+}
+
+// checksum computes the checksum of the snapshot
+func (s channelSnapshot) checksum() (string, error) {
+	s.Checksum = ""
+	// json.Marshal compacts the raw messages, so the checksum doesn't depend on the indentation of the file
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// marshalSnapshotPart encodes an LND response of the snapshot
+func marshalSnapshotPart(m proto.Message) (json.RawMessage, error) {
+	return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(m)
+}
+
+// channelExport is the action of the channel export command
+func channelExport(ctx *cli.Context) error {
+	if !ctx.IsSet("output") {
+		return cli.ShowCommandHelp(ctx, "export")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runChannelExport(context.Background(), client, ctx.String("output"), time.Now(), os.Stdout)
+}
+
+// runChannelExport fetches the channels and their backup from LND and writes the snapshot to the file
+func runChannelExport(ctx context.Context, client lnrpc.LightningClient, filename string, now time.Time, out io.Writer) error {
+	open, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return fmt.Errorf("could not list channels: %v", err)
+	}
+	pending, err := client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+	if err != nil {
+		return fmt.Errorf("could not list pending channels: %v", err)
+	}
+	closed, err := client.ClosedChannels(ctx, &lnrpc.ClosedChannelsRequest{})
+	if err != nil {
+		return fmt.Errorf("could not list closed channels: %v", err)
+	}
+	backup, err := client.ExportAllChannelBackups(ctx, &lnrpc.ChanBackupExportRequest{})
+	if err != nil {
+		return fmt.Errorf("could not export channel backup: %v", err)
+	}
+	if backup.MultiChanBackup == nil || len(backup.MultiChanBackup.MultiChanBackup) == 0 {
+		return fmt.Errorf("LND returned an empty channel backup")
+	}
+	snapshot := channelSnapshot{Version: channelSnapshotVersion, CreatedAt: now.UTC().Format(time.RFC3339)}
+	for _, part := range []struct {
+		dst *json.RawMessage
+		msg proto.Message
+	}{
+		{&snapshot.Open, open},
+		{&snapshot.Pending, pending},
+		{&snapshot.Closed, closed},
+		{&snapshot.Backup, backup},
+	} {
+		if *part.dst, err = marshalSnapshotPart(part.msg); err != nil {
+			return err
+		}
+	}
+	if snapshot.Checksum, err = snapshot.checksum(); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = utils.AtomicWriteFile(filename, append(raw, '\n'), 0600); err != nil {
+		return fmt.Errorf("could not write snapshot: %v", err)
+	}
+	fmt.Fprintf(out, "Exported %d open, %d pending and %d closed channels to %s\n", len(open.Channels), pendingChannelCount(pending), len(closed.Channels), filename)
+	return nil
+}
+
+// pendingChannelCount returns the number of channels of every pending state
+func pendingChannelCount(pending *lnrpc.PendingChannelsResponse) int {
+	return len(pending.PendingOpenChannels) + len(pending.PendingForceClosingChannels) + len(pending.WaitingCloseChannels)
+}
+
+// readChannelSnapshot reads a snapshot and verifies its checksum
+func readChannelSnapshot(filename string) (*channelSnapshot, *lnrpc.ChanBackupSnapshot, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	var snapshot channelSnapshot
+	if err = json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, nil, fmt.Errorf("invalid snapshot %v: %v", filename, err)
+	}
+	if snapshot.Version != channelSnapshotVersion {
+		return nil, nil, fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, channelSnapshotVersion)
+	}
+	sum, err := snapshot.checksum()
+	if err != nil {
+		return nil, nil, err
+	}
+	if sum != snapshot.Checksum {
+		return nil, nil, fmt.Errorf("checksum mismatch, the snapshot is corrupted or was modified")
+	}
+	var backup lnrpc.ChanBackupSnapshot
+	if err = protojson.Unmarshal(snapshot.Backup, &backup); err != nil {
+		return nil, nil, fmt.Errorf("invalid channel backup: %v", err)
+	}
+	if backup.MultiChanBackup == nil || len(backup.MultiChanBackup.MultiChanBackup) == 0 {
+		return nil, nil, fmt.Errorf("the snapshot has no channel backup")
+	}
+	return &snapshot, &backup, nil
+}
+
+// channelImport is the action of the channel import command
+func channelImport(ctx *cli.Context) error {
+	if !ctx.IsSet("input") {
+		return cli.ShowCommandHelp(ctx, "import")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runChannelImport(context.Background(), client, ctx.String("input"), ctx.Bool("yes"), os.Stdin, os.Stdout)
+}
+
+// runChannelImport reads the snapshot, asks for confirmation and restores its channel backup
+func runChannelImport(ctx context.Context, client lnrpc.LightningClient, filename string, yes bool, in io.Reader, out io.Writer) error {
+	snapshot, backup, err := readChannelSnapshot(filename)
+	if err != nil {
+		return err
+	}
+	channels := len(backup.MultiChanBackup.ChanPoints)
+	fmt.Fprintf(out, "Snapshot of %s with a backup of %d channels\n", snapshot.CreatedAt, channels)
+	if !yes {
+		fmt.Fprint(out, "The peers of the restored channels will be asked to force-close them. Type 'restore' to confirm: ")
+		answer, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if strings.TrimSpace(answer) != "restore" {
+			return fmt.Errorf("restore not confirmed, aborting")
+		}
+	}
+	_, err = client.RestoreChannelBackups(ctx, &lnrpc.RestoreChanBackupRequest{
+		Backup: &lnrpc.RestoreChanBackupRequest_MultiChanBackup{MultiChanBackup: backup.MultiChanBackup.MultiChanBackup},
+	})
+	if err != nil {
+		return fmt.Errorf("could not restore channel backup: %v", err)
+	}
+	fmt.Fprintf(out, "Restored the backup of %d channels\n", channels)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newSnapshotClient returns a fake client with an open, a pending and two closed channels
+func newSnapshotClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		channels: []*lnrpc.Channel{{ChanId: 1, RemotePubkey: "02aaa", Capacity: 100000}},
+		pending: &lnrpc.PendingChannelsResponse{PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{
+			{Channel: &lnrpc.PendingChannelsResponse_PendingChannel{RemoteNodePub: "02bbb", Capacity: 50000}},
+		}},
+		closed:     []*lnrpc.ChannelCloseSummary{{ChanId: 2, RemotePubkey: "02ccc"}, {ChanId: 3, RemotePubkey: "02ddd"}},
+		chanBackup: []byte("multi-channel-backup"),
+	}
+}
+
+// TestChannelExport ensures the snapshot holds every LND response and a checksum matching its content
+func TestChannelExport(t *testing.T) {
+	filename := path.Join(t.TempDir(), "snapshot.json")
+	var out bytes.Buffer
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := runChannelExport(context.Background(), newSnapshotClient(), filename, now, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 open, 1 pending and 2 closed channels") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot struct {
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+		Open      struct {
+			Channels []struct {
+				ChanId string `json:"chan_id"`
+			} `json:"channels"`
+		} `json:"open_channels"`
+		Pending struct {
+			PendingOpenChannels []json.RawMessage `json:"pending_open_channels"`
+		} `json:"pending_channels"`
+		Closed struct {
+			Channels []json.RawMessage `json:"channels"`
+		} `json:"closed_channels"`
+		Backup struct {
+			MultiChanBackup struct {
+				MultiChanBackup []byte `json:"multi_chan_backup"`
+			} `json:"multi_chan_backup"`
+		} `json:"channel_backup"`
+		Checksum string `json:"checksum"`
+	}
+	if err = json.Unmarshal(raw, &snapshot); err != nil {
+		t.Fatalf("invalid snapshot: %v\n%s", err, raw)
+	}
+	if snapshot.Version != channelSnapshotVersion || !snapshot.CreatedAt.Equal(now) || len(snapshot.Open.Channels) != 1 || snapshot.Open.Channels[0].ChanId != "1" ||
+		len(snapshot.Pending.PendingOpenChannels) != 1 || len(snapshot.Closed.Channels) != 2 || string(snapshot.Backup.MultiChanBackup.MultiChanBackup) != "multi-channel-backup" {
+		t.Errorf("unexpected snapshot:\n%s", raw)
+	}
+	if len(snapshot.Checksum) != 64 {
+		t.Errorf("unexpected checksum %q", snapshot.Checksum)
+	}
+	if _, _, err = readChannelSnapshot(filename); err != nil {
+		t.Errorf("the exported snapshot doesn't verify: %v", err)
+	}
+
+	// an empty backup means LND has nothing to restore
+	if err = runChannelExport(context.Background(), &fakeLightningClient{pending: &lnrpc.PendingChannelsResponse{}}, filename, now, &out); err == nil {
+		t.Error("expected an error exporting an empty backup")
+	}
+}
+
+// TestChannelImport ensures the backup of an exported snapshot is restored once confirmed and that modified snapshots are rejected
+func TestChannelImport(t *testing.T) {
+	filename := path.Join(t.TempDir(), "snapshot.json")
+	var out bytes.Buffer
+	if err := runChannelExport(context.Background(), newSnapshotClient(), filename, time.Now(), &out); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeLightningClient{}
+	if err := runChannelImport(context.Background(), client, filename, false, strings.NewReader("no\n"), &out); err == nil || len(client.restoreReqs) != 0 {
+		t.Fatalf("expected the import to abort, got %v", err)
+	}
+	if err := runChannelImport(context.Background(), client, filename, false, strings.NewReader("restore\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.restoreReqs) != 1 || string(client.restoreReqs[0].GetMultiChanBackup()) != "multi-channel-backup" {
+		t.Fatalf("unexpected restore requests: %v", client.restoreReqs)
+	}
+
+	raw, _ := ioutil.ReadFile(filename)
+	tampered := strings.Replace(string(raw), `"02ccc"`, `"02eee"`, 1)
+	if err := ioutil.WriteFile(filename, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runChannelImport(context.Background(), client, filename, true, nil, &out); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+	if len(client.restoreReqs) != 1 {
+		t.Errorf("a modified snapshot was restored")
+	}
+}
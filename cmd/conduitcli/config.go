@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/urfave/cli"
+)
+
+// conduitDirFlag is the flag pointing to the conduit directory
+var conduitDirFlag = cli.StringFlag{
+	Name:  "conduitdir",
+	Usage: "path to the conduit directory",
+	Value: utils.AppDataDir("conduit", false),
+}
+
+var configCommand = cli.Command{
+	Name:  "config",
+	Usage: "Manage the Conduit config file",
+	Subcommands: []cli.Command{
+		encryptSecretsCommand,
+		configSetCommand,
+		configSchemaCommand,
+		configHashCommand,
+	},
+}
+
+var encryptSecretsCommand = cli.Command{
+	Name:  "encrypt-secrets",
+	Usage: "Encrypt the secrets stored in config.yaml",
+	Description: `
+	Encrypts every password field of config.yaml in place using a key derived
+	from the CONDUIT_MASTER_KEY environment variable. Conduit decrypts them on
+	startup as long as CONDUIT_MASTER_KEY is set.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: encryptSecrets,
+}
+
+// encryptSecrets is the action of the config encrypt-secrets command
+func encryptSecrets(ctx *cli.Context) error {
+	enc, err := core.NewConfigEncryptionFromEnv()
+	if err != nil {
+		return err
+	}
+	count, err := enc.EncryptFile(path.Join(ctx.String("conduitdir"), configFileName))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted %d field(s)\n", count)
+	return nil
+}
+
+var configSetCommand = cli.Command{
+	Name:      "set",
+	Usage:     "Set a field of config.yaml",
+	ArgsUsage: "field value",
+	Description: `
+	Sets a Config field, i.e. LndAutopilotActive, in config.yaml leaving every
+	other field untouched. Conduit must be restarted for the change to take effect.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: configSet,
+}
+
+var configSchemaCommand = cli.Command{
+	Name:  "schema",
+	Usage: "Print the JSON Schema of config.yaml",
+	Description: `
+	Prints a JSON Schema (draft-07) document describing every field of
+	config.yaml, its type, its allowed values and whether it holds a secret.`,
+	Action: configSchema,
+}
+
+// configSchema is the action of the config schema command
+func configSchema(ctx *cli.Context) error {
+	_, err := fmt.Fprintln(os.Stdout, string(core.ExportConfigSchema()))
+	return err
+}
+
+var configHashCommand = cli.Command{
+	Name:  "hash",
+	Usage: "Print the hash of config.yaml to set as ExpectedConfigHash",
+	Description: `
+	Prints the ConfigAuditHash of config.yaml as loaded by Conduit on startup.
+	Conduit refuses to start when ExpectedConfigHash is set and differs from the
+	hash of its config. Flags passed to conduit change the hash, so deployments
+	pinning the hash should set every field in config.yaml. ExpectedConfigHash
+	itself isn't part of the hash.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: configHash,
+}
+
+// configHash is the action of the config hash command
+func configHash(ctx *cli.Context) error {
+	return runConfigHash(path.Join(ctx.String("conduitdir"), configFileName), os.Stdout)
+}
+
+// runConfigHash prints the ConfigAuditHash of the config file
+func runConfigHash(filename string, out io.Writer) error {
+	config, err := core.LoadConfigFile(filename)
+	if err != nil {
+		return fmt.Errorf("could not load config: %v", err)
+	}
+	hash, err := core.ConfigAuditHash(config)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, hash)
+	return err
+}
+
+// configSet is the action of the config set command
+func configSet(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return cli.ShowCommandHelp(ctx, "set")
+	}
+	return runConfigSet(path.Join(ctx.String("conduitdir"), configFileName), ctx.Args().Get(0), ctx.Args().Get(1), os.Stdout)
+}
+
+// runConfigSet sets a single field in the config file after checking that it's a `core.Config` field
+func runConfigSet(filename, field, value string, out io.Writer) error {
+	f, ok := reflect.TypeOf(core.Config{}).FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, field)
+	})
+	if !ok {
+		return fmt.Errorf("unknown config field %q", field)
+	}
+	// the value is written with the field's type so that it's parsed back correctly
+	var typed interface{}
+	var err error
+	switch f.Type.Kind() {
+	case reflect.String:
+		typed = value
+	case reflect.Bool:
+		typed, err = strconv.ParseBool(value)
+	case reflect.Int:
+		typed, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("config field %s of type %v can't be set from the command line", f.Name, f.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for config field %s: %v", value, f.Name, err)
+	}
+	previous, err := core.UpdateConfigFile(filename, map[string]interface{}{f.Name: typed})
+	if err != nil {
+		return fmt.Errorf("could not update config: %v", err)
+	}
+	fmt.Fprintf(out, "%s: %q -> %q\n", f.Name, previous[f.Name], value)
+	fmt.Fprintln(out, "Restart Conduit for the change to take effect")
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestConfigSet ensures fields are written with their type and that unknown fields are rejected
+func TestConfigSet(t *testing.T) {
+	filename := path.Join(t.TempDir(), configFileName)
+	if err := ioutil.WriteFile(filename, []byte("ConsoleOutput: true\nlndalias: alice\n"), 0600); err != nil {
+		t.Fatalf("Error writing config fixture: %v", err)
+	}
+	var out bytes.Buffer
+	for _, args := range [][2]string{{"lndautopilotactive", "true"}, {"ConsoleOutput", "false"}, {"LogSampleRate", "10"}} {
+		if err := runConfigSet(filename, args[0], args[1], &out); err != nil {
+			t.Fatalf("runConfigSet(%s, %s) returned an error: %v", args[0], args[1], err)
+		}
+	}
+	if !strings.Contains(out.String(), `LndAutopilotActive: "" -> "true"`) {
+		t.Errorf("output does not show the old and new value:\n%s", out.String())
+	}
+	raw, _ := ioutil.ReadFile(filename)
+	var config core.Config
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		t.Fatalf("config can't be parsed after being updated: %v\n%s", err, raw)
+	}
+	if config.LndAutopilotActive != "true" || config.ConsoleOutput || config.LogSampleRate != 10 || config.LndAlias != "alice" {
+		t.Errorf("unexpected config after update:\n%s", raw)
+	}
+	for _, args := range [][2]string{{"NotAField", "true"}, {"ConsoleOutput", "maybe"}, {"LndTLSExtraIPs", "1.2.3.4"}} {
+		if err := runConfigSet(filename, args[0], args[1], &out); err == nil {
+			t.Errorf("runConfigSet accepted field %q with value %q", args[0], args[1])
+		}
+	}
+}
+
+// TestConfigHash ensures the printed hash is accepted as ExpectedConfigHash and that it changes with the config
+func TestConfigHash(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, configFileName)
+	write := func(content string) {
+		if err := ioutil.WriteFile(filename, []byte("ConduitDir: "+dir+"\n"+content), 0600); err != nil {
+			t.Fatalf("Error writing config fixture: %v", err)
+		}
+	}
+	write("ConsoleOutput: true\nlndalias: alice\n")
+	var out bytes.Buffer
+	if err := runConfigHash(filename, &out); err != nil {
+		t.Fatal(err)
+	}
+	hash := strings.TrimSpace(out.String())
+	if len(hash) != 64 {
+		t.Fatalf("expected a SHA256, got %q", hash)
+	}
+	write("ConsoleOutput: true\nlndalias: alice\nExpectedConfigHash: " + hash + "\n")
+	config, err := core.LoadConfigFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = core.ValidateConfig(config); err != nil {
+		t.Errorf("expected the printed hash to be accepted: %v", err)
+	}
+	write("ConsoleOutput: true\nlndalias: bob\n")
+	out.Reset()
+	if err = runConfigHash(filename, &out); err != nil || strings.TrimSpace(out.String()) == hash {
+		t.Errorf("expected a different hash for a different config, got %q: %v", out.String(), err)
+	}
+}
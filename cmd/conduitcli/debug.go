@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+var debugCommand = cli.Command{
+	Name:  "debug",
+	Usage: "Inspect the running Conduit daemon",
+	Subcommands: []cli.Command{
+		debugGoroutinesCommand,
+	},
+}
+
+var debugGoroutinesCommand = cli.Command{
+	Name:  "goroutines",
+	Usage: "Dump the goroutine stacks of the daemon",
+	Description: `
+	Prints the stacks of every goroutine of the running daemon, like a SIGQUIT
+	would but without stopping it, to diagnose hangs. The daemon only answers
+	when DebugMode is set in config.yaml.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the stacks as JSON",
+		},
+	},
+	Action: debugGoroutines,
+}
+
+// goroutineDump is the JSON output of the debug goroutines command
+type goroutineDump struct {
+	Goroutines string `json:"goroutines"`
+}
+
+// debugGoroutines is the action of the debug goroutines command
+func debugGoroutines(ctx *cli.Context) error {
+	client, err := getConduitClient(ctx)
+	if err != nil {
+		return err
+	}
+	return runDebugGoroutines(context.Background(), client, ctx.Bool("json"), os.Stdout)
+}
+
+// runDebugGoroutines prints the goroutine stacks of the daemon, preceded by their count, or as JSON
+func runDebugGoroutines(ctx context.Context, client *jsonrpc.Client, asJSON bool, out io.Writer) error {
+	var stacks string
+	if err := client.Call(ctx, "conduit_debug_goroutines", nil, &stacks); err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(goroutineDump{Goroutines: stacks})
+	}
+	// the stacks are separated by blank lines
+	fmt.Fprintf(out, "%d goroutines\n\n", strings.Count(stacks, "\n\ngoroutine ")+1)
+	fmt.Fprintln(out, strings.TrimRight(stacks, "\n"))
+	return nil
+}
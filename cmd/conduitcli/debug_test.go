@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+// newDebugClient returns a client of a Conduit JSON-RPC server with the given debug mode
+func newDebugClient(t *testing.T, debugMode bool) *jsonrpc.Client {
+	log := zerolog.Nop()
+	ts := httptest.NewServer(core.NewRPCServer(&core.Config{DebugMode: debugMode}, &log).Server)
+	t.Cleanup(ts.Close)
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// TestDebugGoroutines ensures the stacks of the daemon are printed as text or JSON, and only in debug mode
+func TestDebugGoroutines(t *testing.T) {
+	client := newDebugClient(t, true)
+	var out bytes.Buffer
+	if err := runDebugGoroutines(context.Background(), client, false, &out); err != nil {
+		t.Fatalf("runDebugGoroutines returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), "goroutine 1 [") || !strings.Contains(out.String(), " goroutines\n\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	out.Reset()
+	if err := runDebugGoroutines(context.Background(), client, true, &out); err != nil {
+		t.Fatalf("runDebugGoroutines returned an error: %v", err)
+	}
+	var dump goroutineDump
+	if err := json.Unmarshal(out.Bytes(), &dump); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if !strings.Contains(dump.Goroutines, "goroutine 1 [") {
+		t.Errorf("unexpected goroutines %q", dump.Goroutines)
+	}
+	if err := runDebugGoroutines(context.Background(), newDebugClient(t, false), false, &out); !isRPCError(err) {
+		t.Errorf("expected the daemon to refuse outside of debug mode, got %v", err)
+	}
+}
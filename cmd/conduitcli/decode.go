@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var decodeCommand = cli.Command{
+	Name:  "decode",
+	Usage: "Decode Lightning payloads",
+	Subcommands: []cli.Command{
+		decodeInvoiceCommand,
+	},
+}
+
+var decodeInvoiceCommand = cli.Command{
+	Name:      "invoice",
+	Usage:     "Show the details of a BOLT11 invoice",
+	ArgsUsage: "payment_request",
+	Description: `
+	Decodes the payment request with LND and prints its destination, amount,
+	description, expiry, timestamp, payment hash and minimum final CLTV expiry.
+	A warning is printed when the invoice has expired.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the DecodePayReq response as JSON",
+		},
+	},
+	Action: decodeInvoice,
+}
+
+// decodeInvoice is the action of the decode invoice command
+func decodeInvoice(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "invoice")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runDecodeInvoice(context.Background(), client, ctx.Args().First(), ctx.Bool("json"), time.Now(), os.Stdout)
+}
+
+// invoiceExpired reports whether the decoded invoice is expired at the given time
+func invoiceExpired(payReq *lnrpc.PayReq, now time.Time) bool {
+	return !now.Before(time.Unix(payReq.Timestamp+payReq.Expiry, 0))
+}
+
+// runDecodeInvoice prints the decoded payment request as a list or as the proto JSON of the DecodePayReq response
+func runDecodeInvoice(ctx context.Context, client lnrpc.LightningClient, payReq string, asJSON bool, now time.Time, out io.Writer) error {
+	decoded, err := client.DecodePayReq(ctx, &lnrpc.PayReqString{PayReq: payReq})
+	if err != nil {
+		return fmt.Errorf("could not decode invoice: %v", err)
+	}
+	if asJSON {
+		raw, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true, EmitUnpopulated: true}.Marshal(decoded)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(raw))
+		return nil
+	}
+	amount := "any amount"
+	if decoded.NumMsat > 0 {
+		amount = fmt.Sprintf("%d msat", decoded.NumMsat)
+	}
+	description := decoded.Description
+	if description == "" && decoded.DescriptionHash != "" {
+		description = fmt.Sprintf("hash %s", decoded.DescriptionHash)
+	}
+	timestamp := time.Unix(decoded.Timestamp, 0).UTC()
+	expiresAt := timestamp.Add(time.Duration(decoded.Expiry) * time.Second)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Destination:\t%s\n", decoded.Destination)
+	fmt.Fprintf(w, "Amount:\t%s\n", amount)
+	fmt.Fprintf(w, "Description:\t%s\n", description)
+	fmt.Fprintf(w, "Timestamp:\t%s\n", timestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Expiry:\t%s (%s)\n", time.Duration(decoded.Expiry)*time.Second, expiresAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Payment hash:\t%s\n", decoded.PaymentHash)
+	fmt.Fprintf(w, "Min final CLTV expiry:\t%d\n", decoded.CltvExpiry)
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if invoiceExpired(decoded, now) {
+		fmt.Fprintf(out, "Warning: the invoice expired %s ago\n", now.Sub(expiresAt).Truncate(time.Second))
+	}
+	return nil
+}
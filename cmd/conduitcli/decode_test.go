@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// TestDecodeInvoice ensures the decoded invoice is printed with its amount and a warning once it has expired
+func TestDecodeInvoice(t *testing.T) {
+	created := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := &fakeLightningClient{payReqs: map[string]*lnrpc.PayReq{
+		"lnbc1": {Destination: "02abc", PaymentHash: "ff00", NumMsat: 1500, Description: "coffee", Timestamp: created.Unix(), Expiry: 3600, CltvExpiry: 40},
+		"lnbc0": {Destination: "02def", PaymentHash: "ee11", DescriptionHash: "aa22", Timestamp: created.Unix(), Expiry: 600, CltvExpiry: 18},
+	}}
+	var out bytes.Buffer
+	if err := runDecodeInvoice(context.Background(), client, "lnbc1", false, created.Add(30*time.Minute), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"02abc", "1500 msat", "coffee", "2022-05-01T12:00:00Z", "1h0m0s (2022-05-01T13:00:00Z)", "ff00", "Min final CLTV expiry:  40"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Warning") {
+		t.Errorf("unexpected expiry warning:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runDecodeInvoice(context.Background(), client, "lnbc0", false, created.Add(time.Hour), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"any amount", "hash aa22", "Warning: the invoice expired 50m0s ago"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runDecodeInvoice(context.Background(), client, "lnbc1", true, created, &out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if decoded["destination"] != "02abc" || decoded["num_msat"] != "1500" {
+		t.Errorf("unexpected JSON output: %v", decoded)
+	}
+
+	if err := runDecodeInvoice(context.Background(), client, "lnbc2", false, created, &out); err == nil {
+		t.Error("expected an error decoding an unknown invoice")
+	}
+}
+
+// TestInvoiceExpired ensures an invoice expires at its timestamp plus its expiry
+func TestInvoiceExpired(t *testing.T) {
+	created := time.Unix(1651406400, 0)
+	payReq := &lnrpc.PayReq{Timestamp: created.Unix(), Expiry: 60}
+	for _, c := range []struct {
+		now     time.Time
+		expired bool
+	}{
+		{created, false},
+		{created.Add(59 * time.Second), false},
+		{created.Add(time.Minute), true},
+		{created.Add(time.Hour), true},
+	} {
+		if got := invoiceExpired(payReq, c.now); got != c.expired {
+			t.Errorf("invoiceExpired at %v = %t, expected %t", c.now, got, c.expired)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	diagnoseLogLines = 500
+	redactedValue    = "[REDACTED]"
+	configFileName   = "config.yaml"
+	logFileName      = "logfile.log"
+	// diagnoseStatusTimeout bounds each call collecting the status of the daemon, which may not be running
+	diagnoseStatusTimeout = 5 * time.Second
+)
+
+// diagnoseStatusMethods are the methods of the daemon whose results make up its status
+var diagnoseStatusMethods = []string{"conduit_lnd_pid", "conduit_lnd_rpc_state", "conduit_startup_timeline", "conduit_feature_list"}
+
+// sensitiveArgRegex matches the flags of plugin arguments which are likely followed by a secret
+var sensitiveArgRegex = regexp.MustCompile(`(?i)^-.*(pass|secret|token|key|macaroon|auth)`)
+
+var diagnoseCommand = cli.Command{
+	Name:  "diagnose",
+	Usage: "Collect logs, config and system info for bug reports",
+	Description: `
+	Collects a sanitized config.yaml, the plugin manifests, the last 500 log
+	lines, the LND version, the status of the running daemon, and Go, OS and
+	Conduit version information into a ZIP archive which can be attached to a
+	bug report. Sensitive config fields and plugin arguments are always
+	redacted.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "path of the ZIP archive to write",
+			Value: "conduit-diagnose.zip",
+		},
+		conduitDirFlag,
+	},
+	Action: diagnose,
+}
+
+// diagnose writes the diagnostics archive and prints its SHA256 checksum
+func diagnose(ctx *cli.Context) error {
+	client, err := getConduitClient(ctx)
+	if err != nil {
+		return err
+	}
+	checksum, err := writeDiagnostics(ctx.String("conduitdir"), ctx.String("output"), client)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Diagnostics written to %s\n", ctx.String("output"))
+	fmt.Printf("SHA256: %s\n", checksum)
+	return nil
+}
+
+// writeDiagnostics collects all diagnostic files from conduitDir, and the status of the daemon from client, into a ZIP archive at
+// output and returns its SHA256 checksum
+func writeDiagnostics(conduitDir, output string, client *jsonrpc.Client) (string, error) {
+	out, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	archive := zip.NewWriter(out)
+	files := []struct {
+		name    string
+		collect func() ([]byte, error)
+	}{
+		{"config.yaml", func() ([]byte, error) { return sanitizedConfig(path.Join(conduitDir, configFileName)) }},
+		{"logfile.log", func() ([]byte, error) { return tailFile(path.Join(conduitDir, logFileName), diagnoseLogLines) }},
+		{"lnd_version.txt", lndVersion},
+		{"system.txt", systemInfo},
+		{"status.json", func() ([]byte, error) { return daemonStatus(client) }},
+	}
+	manifests, err := filepath.Glob(path.Join(core.PluginDir(&core.Config{ConduitDir: conduitDir}), "*.yaml"))
+	if err != nil {
+		return "", err
+	}
+	for _, manifest := range manifests {
+		manifest := manifest
+		files = append(files, struct {
+			name    string
+			collect func() ([]byte, error)
+		}{"plugins/" + filepath.Base(manifest), func() ([]byte, error) { return sanitizedPluginManifest(manifest) }})
+	}
+	for _, file := range files {
+		content, err := file.collect()
+		if err != nil {
+			content = []byte(fmt.Sprintf("could not collect %s: %v\n", file.name, err))
+		}
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return "", err
+		}
+		if _, err = w.Write(content); err != nil {
+			return "", err
+		}
+	}
+	if err = archive.Close(); err != nil {
+		return "", err
+	}
+	if _, err = out.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, out); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// daemonStatus returns the results of the status methods of the daemon by method, or the error of the methods which failed, i.e.
+// because the daemon isn't running
+func daemonStatus(client *jsonrpc.Client) ([]byte, error) {
+	status := make(map[string]interface{}, len(diagnoseStatusMethods))
+	for _, method := range diagnoseStatusMethods {
+		ctx, cancel := context.WithTimeout(context.Background(), diagnoseStatusTimeout)
+		result, err := client.CallRaw(ctx, method, nil)
+		cancel()
+		if err != nil {
+			status[method] = map[string]string{"error": err.Error()}
+			continue
+		}
+		status[method] = result
+	}
+	return json.MarshalIndent(status, "", "  ")
+}
+
+// readSanitizedYAML reads a YAML file and replaces the value of every sensitive field
+func readSanitizedYAML(filename string) (yaml.MapSlice, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config yaml.MapSlice
+	if err = yaml.Unmarshal(raw, &config); err != nil {
+		// never include a file we couldn't sanitize
+		return nil, fmt.Errorf("%s could not be parsed and was omitted: %v", filepath.Base(filename), err)
+	}
+	sensitive := make(map[string]bool)
+	for _, name := range core.SensitiveFields() {
+		sensitive[strings.ToLower(name)] = true
+	}
+	for i, item := range config {
+		if key, ok := item.Key.(string); ok && sensitive[strings.ToLower(key)] {
+			config[i].Value = redactedValue
+		}
+	}
+	return config, nil
+}
+
+// sanitizedConfig reads the config file and replaces the value of every sensitive field
+func sanitizedConfig(filename string) ([]byte, error) {
+	config, err := readSanitizedYAML(filename)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(config)
+}
+
+// sanitizedPluginManifest reads a plugin manifest and replaces the value of every sensitive field, as well as the values of the arguments
+// whose flag looks like a secret, i.e. --rpcpass=x or --api-token x
+func sanitizedPluginManifest(filename string) ([]byte, error) {
+	manifest, err := readSanitizedYAML(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range manifest {
+		args, ok := item.Value.([]interface{})
+		if key, _ := item.Key.(string); key != "Args" || !ok {
+			continue
+		}
+		// the flag of the previous argument, if it has no value of its own
+		previous := ""
+		for j, arg := range args {
+			flag, _, hasValue := strings.Cut(fmt.Sprint(arg), "=")
+			switch {
+			case hasValue && sensitiveArgRegex.MatchString(flag):
+				args[j] = flag + "=" + redactedValue
+			case !strings.HasPrefix(flag, "-") && sensitiveArgRegex.MatchString(previous):
+				args[j] = redactedValue
+			}
+			previous = ""
+			if !hasValue {
+				previous = flag
+			}
+		}
+		manifest[i].Value = args
+	}
+	return yaml.Marshal(manifest)
+}
+
+// tailFile returns the last n lines of a file
+func tailFile(filename string, n int) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// lndVersion returns the output of lnd --version
+func lndVersion() ([]byte, error) {
+	if _, err := exec.LookPath("lnd"); err != nil {
+		return nil, core.ErrLndNotFound
+	}
+	return exec.Command("lnd", "--version").CombinedOutput()
+}
+
+// systemInfo returns the Conduit and Go versions as well as the OS and architecture
+func systemInfo() ([]byte, error) {
+	info := fmt.Sprintf("%s version: %s\nGo version: %s\nOS/Arch: %s/%s\n", utils.AppName, utils.AppVersion, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return []byte(info), nil
+}
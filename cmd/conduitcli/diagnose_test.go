@@ -0,0 +1,100 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// TestWriteDiagnostics ensures the archive contains every diagnostic file, including the status of the daemon, and that secrets are
+// redacted
+func TestWriteDiagnostics(t *testing.T) {
+	conduitDir := t.TempDir()
+	config := "DefaultDir: true\nConsoleOutput: false\nLndBtcdRPCPass: hunter2\nlndbitcoindrpcpass: hunter3\n"
+	if err := ioutil.WriteFile(path.Join(conduitDir, configFileName), []byte(config), 0666); err != nil {
+		t.Fatalf("Error writing config fixture: %v", err)
+	}
+	var logLines []string
+	for i := 0; i < diagnoseLogLines+100; i++ {
+		logLines = append(logLines, fmt.Sprintf("line %d", i))
+	}
+	if err := ioutil.WriteFile(path.Join(conduitDir, logFileName), []byte(strings.Join(logLines, "\n")+"\n"), 0666); err != nil {
+		t.Fatalf("Error writing log fixture: %v", err)
+	}
+	pluginDir := path.Join(conduitDir, "plugins")
+	if err := os.Mkdir(pluginDir, 0775); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "Name: echo\nVersion: 1.0.0\nEndpoint: localhost:9090\nArgs: [--verbose, --api-token=hunter4, --rpcpass, hunter5, --port, \"9090\"]\nLndBtcdRPCPass: hunter6\n"
+	if err := ioutil.WriteFile(path.Join(pluginDir, "echo.yaml"), []byte(manifest), 0666); err != nil {
+		t.Fatalf("Error writing manifest fixture: %v", err)
+	}
+	output := path.Join(t.TempDir(), "diagnose.zip")
+	checksum, err := writeDiagnostics(conduitDir, output, newDebugClient(t, false))
+	if err != nil {
+		t.Fatalf("writeDiagnostics returned an error: %v", err)
+	}
+	raw, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Error reading archive: %v", err)
+	}
+	sum := sha256.Sum256(raw)
+	if checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("writeDiagnostics returned checksum %s, archive has checksum %s", checksum, hex.EncodeToString(sum[:]))
+	}
+	archive, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatalf("Error opening archive: %v", err)
+	}
+	defer archive.Close()
+	contents := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Error opening %s: %v", f.Name, err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(b)
+	}
+	for _, name := range []string{"config.yaml", "logfile.log", "lnd_version.txt", "system.txt", "status.json", "plugins/echo.yaml"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+	if strings.Contains(contents["config.yaml"], "hunter2") || strings.Contains(contents["config.yaml"], "hunter3") {
+		t.Errorf("config.yaml was not sanitized: %s", contents["config.yaml"])
+	}
+	if !strings.Contains(contents["config.yaml"], "ConsoleOutput: false") {
+		t.Errorf("config.yaml is missing non-sensitive fields: %s", contents["config.yaml"])
+	}
+	plugin := contents["plugins/echo.yaml"]
+	if strings.Contains(plugin, "hunter") {
+		t.Errorf("plugins/echo.yaml was not sanitized: %s", plugin)
+	}
+	if !strings.Contains(plugin, "--api-token="+redactedValue) || !strings.Contains(plugin, "--verbose") || !strings.Contains(plugin, `"9090"`) || !strings.Contains(plugin, "Endpoint: localhost:9090") {
+		t.Errorf("plugins/echo.yaml is missing non-sensitive fields: %s", plugin)
+	}
+	var status map[string]map[string]interface{}
+	if err = json.Unmarshal([]byte(contents["status.json"]), &status); err != nil {
+		t.Fatalf("status.json is not valid JSON: %v\n%s", err, contents["status.json"])
+	}
+	if _, ok := status["conduit_lnd_pid"]["pid"]; !ok {
+		t.Errorf("status.json is missing the PID of LND: %s", contents["status.json"])
+	}
+	// the methods of subsystems which aren't running report their error instead of failing the whole status
+	if _, ok := status["conduit_lnd_rpc_state"]["error"]; !ok {
+		t.Errorf("status.json is missing the error of conduit_lnd_rpc_state: %s", contents["status.json"])
+	}
+	lines := strings.Split(strings.TrimSpace(contents["logfile.log"]), "\n")
+	if len(lines) != diagnoseLogLines || lines[0] != "line 100" {
+		t.Errorf("expected the last %d log lines starting at line 100, got %d lines starting at %q", diagnoseLogLines, len(lines), lines[0])
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/sftp"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultSFTPPort   = "22"
+	backupFilePattern = "channels-%s.backup"
+)
+
+var exportChannelsCommand = cli.Command{
+	Name:  "export-channels",
+	Usage: "Export a static channel backup to a local file, S3 or SFTP",
+	Description: `
+	Exports a static channel backup (SCB) of all channels and writes it to the
+	given destination, which is one of:
+
+	file:///path/to/dir
+	s3://bucket/path      credentials are read from the environment
+	sftp://user@host/path authenticates with the SSH agent or --ssh-key`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "dest",
+			Usage: "URI of the destination directory",
+		},
+		cli.StringFlag{
+			Name:  "ssh-key",
+			Usage: "private key used to authenticate against SFTP destinations. Defaults to ~/.ssh/id_ed25519 or ~/.ssh/id_rsa",
+		},
+	},
+	Action: exportChannels,
+}
+
+// ExportDestination is a location to which channel backups can be exported
+type ExportDestination interface {
+	Write(filename string, data []byte) error
+}
+
+// fileDestination writes backups to a local directory
+type fileDestination struct {
+	dir string
+}
+
+func (d *fileDestination) Write(filename string, data []byte) error {
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		return err
+	}
+	return utils.AtomicWriteFile(filepath.Join(d.dir, filename), data, 0600)
+}
+
+// s3PutObjectAPI is the subset of the S3 client used to upload backups
+type s3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// s3Destination uploads backups to an S3 bucket
+type s3Destination struct {
+	client s3PutObjectAPI
+	bucket string
+	prefix string
+}
+
+func (d *s3Destination) Write(filename string, data []byte) error {
+	_, err := d.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(path.Join(d.prefix, filename)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// sftpDestination uploads backups to a directory on an SFTP server
+type sftpDestination struct {
+	client *sftp.Client
+	dir    string
+}
+
+func (d *sftpDestination) Write(filename string, data []byte) error {
+	if err := d.client.MkdirAll(d.dir); err != nil {
+		return err
+	}
+	name := path.Join(d.dir, filename)
+	// upload under a temporary name first so that an interrupted upload never looks like a complete backup
+	f, err := d.client.OpenFile(name+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return d.client.PosixRename(name+".tmp", name)
+}
+
+// newExportDestination parses the destination URI and returns the destination and a function to close its connection
+func newExportDestination(dest, sshKey string) (ExportDestination, func(), error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid destination %v: %v", dest, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return &fileDestination{dir: u.Path}, func() {}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, nil, fmt.Errorf("expected destination of the form s3://bucket/path, got %v", dest)
+		}
+		cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not load AWS config: %v", err)
+		}
+		return &s3Destination{client: s3.NewFromConfig(cfg), bucket: u.Host, prefix: strings.TrimPrefix(u.Path, "/")}, func() {}, nil
+	case "sftp":
+		if u.User == nil || u.Hostname() == "" {
+			return nil, nil, fmt.Errorf("expected destination of the form sftp://user@host/path, got %v", dest)
+		}
+		client, cleanUp, err := dialSFTP(u, sshKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &sftpDestination{client: client, dir: u.Path}, cleanUp, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported destination scheme %q, expected file, s3 or sftp", u.Scheme)
+	}
+}
+
+// sshAuthMethods returns the SSH agent and private key authentication methods that are available
+func sshAuthMethods(sshKey string) ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	cleanUp := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			cleanUp = func() { conn.Close() }
+		}
+	}
+	keys := []string{sshKey}
+	if sshKey == "" {
+		home, _ := os.UserHomeDir()
+		keys = []string{filepath.Join(home, ".ssh", "id_ed25519"), filepath.Join(home, ".ssh", "id_rsa")}
+	}
+	for _, key := range keys {
+		raw, err := ioutil.ReadFile(key)
+		if err != nil {
+			if sshKey != "" {
+				cleanUp()
+				return nil, nil, fmt.Errorf("could not read SSH key: %v", err)
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(raw)
+		if err != nil {
+			cleanUp()
+			return nil, nil, fmt.Errorf("could not parse SSH key %v: %v", key, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if len(methods) == 0 {
+		cleanUp()
+		return nil, nil, fmt.Errorf("no SSH agent or SSH key available")
+	}
+	return methods, cleanUp, nil
+}
+
+// dialSFTP connects to the SFTP server, verifying its host key against ~/.ssh/known_hosts
+func dialSFTP(u *url.URL, sshKey string) (*sftp.Client, func(), error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	hostKeyCallback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load known hosts: %v", err)
+	}
+	methods, closeAgent, err := sshAuthMethods(sshKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultSFTPPort
+	}
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(u.Hostname(), port), &ssh.ClientConfig{
+		User:            u.User.Username(),
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		closeAgent()
+		return nil, nil, fmt.Errorf("could not connect to %v: %v", u.Host, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		closeAgent()
+		return nil, nil, err
+	}
+	cleanUp := func() {
+		client.Close()
+		conn.Close()
+		closeAgent()
+	}
+	return client, cleanUp, nil
+}
+
+// exportChannels is the action of the export-channels command
+func exportChannels(ctx *cli.Context) error {
+	if !ctx.IsSet("dest") {
+		return cli.ShowCommandHelp(ctx, "export-channels")
+	}
+	dest, closeDest, err := newExportDestination(ctx.String("dest"), ctx.String("ssh-key"))
+	if err != nil {
+		return err
+	}
+	defer closeDest()
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	filename, err := runExportChannels(context.Background(), client, dest, time.Now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Channel backup exported to %s as %s\n", ctx.String("dest"), filename)
+	return nil
+}
+
+// runExportChannels exports a static channel backup of all channels to the destination and returns the backup's filename
+func runExportChannels(ctx context.Context, client lnrpc.LightningClient, dest ExportDestination, now func() time.Time) (string, error) {
+	resp, err := client.ExportAllChannelBackups(ctx, &lnrpc.ChanBackupExportRequest{})
+	if err != nil {
+		return "", err
+	}
+	if resp.MultiChanBackup == nil || len(resp.MultiChanBackup.MultiChanBackup) == 0 {
+		return "", fmt.Errorf("LND returned an empty channel backup")
+	}
+	filename := fmt.Sprintf(backupFilePattern, now().UTC().Format("20060102T150405Z"))
+	if err = dest.Write(filename, resp.MultiChanBackup.MultiChanBackup); err != nil {
+		return "", fmt.Errorf("could not write channel backup: %v", err)
+	}
+	return filename, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/sftp"
+)
+
+var (
+	testBackup     = []byte("scb")
+	testBackupTime = time.Date(2022, 3, 1, 12, 30, 0, 0, time.UTC)
+	testBackupName = "channels-20220301T123000Z.backup"
+)
+
+// fakeS3Client records every uploaded object
+type fakeS3Client struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*params.Bucket+"/"+*params.Key] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+// newTestSFTPClient returns a client connected to an in-memory SFTP server
+func newTestSFTPClient(t *testing.T) *sftp.Client {
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite}, sftp.InMemHandler())
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatalf("Error connecting to SFTP server: %v", err)
+	}
+	// closing the server first ends the client's receive loop so that it can close
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return client
+}
+
+// TestExportChannels ensures the backup is written to every kind of destination
+func TestExportChannels(t *testing.T) {
+	client := &fakeLightningClient{chanBackup: testBackup}
+	now := func() time.Time { return testBackupTime }
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	filename, err := runExportChannels(context.Background(), client, &fileDestination{dir: dir}, now)
+	if err != nil {
+		t.Fatalf("file export returned an error: %v", err)
+	}
+	if filename != testBackupName {
+		t.Errorf("expected filename %v, got %v", testBackupName, filename)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, testBackupName)); err != nil || !bytes.Equal(data, testBackup) {
+		t.Errorf("file export wrote %q (%v), expected %q", data, err, testBackup)
+	}
+
+	s3Client := &fakeS3Client{objects: make(map[string][]byte)}
+	if _, err = runExportChannels(context.Background(), client, &s3Destination{client: s3Client, bucket: "bucket", prefix: "lnd"}, now); err != nil {
+		t.Fatalf("S3 export returned an error: %v", err)
+	}
+	if data := s3Client.objects["bucket/lnd/"+testBackupName]; !bytes.Equal(data, testBackup) {
+		t.Errorf("S3 export uploaded %v, expected %q at bucket/lnd/%s", s3Client.objects, testBackup, testBackupName)
+	}
+
+	sftpClient := newTestSFTPClient(t)
+	if _, err = runExportChannels(context.Background(), client, &sftpDestination{client: sftpClient, dir: "/backups"}, now); err != nil {
+		t.Fatalf("SFTP export returned an error: %v", err)
+	}
+	f, err := sftpClient.Open("/backups/" + testBackupName)
+	if err != nil {
+		t.Fatalf("Error opening uploaded backup: %v", err)
+	}
+	defer f.Close()
+	if data, _ := ioutil.ReadAll(f); !bytes.Equal(data, testBackup) {
+		t.Errorf("SFTP export uploaded %q, expected %q", data, testBackup)
+	}
+}
+
+// TestNewExportDestination ensures destination URIs are validated
+func TestNewExportDestination(t *testing.T) {
+	dest, _, err := newExportDestination("file:///tmp/backups", "")
+	if err != nil {
+		t.Fatalf("newExportDestination returned an error: %v", err)
+	}
+	if d, ok := dest.(*fileDestination); !ok || d.dir != "/tmp/backups" {
+		t.Errorf("expected file destination for /tmp/backups, got %#v", dest)
+	}
+	for _, uri := range []string{"ftp://host/path", "s3:///path", "sftp://host/path"} {
+		if _, _, err := newExportDestination(uri, ""); err == nil {
+			t.Errorf("newExportDestination accepted %v", uri)
+		}
+	}
+}
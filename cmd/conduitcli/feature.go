@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/urfave/cli"
+)
+
+var featureCommand = cli.Command{
+	Name:  "feature",
+	Usage: "Toggle experimental features at runtime",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List all known feature flags and their state",
+			Flags:  []cli.Flag{conduitDirFlag},
+			Action: listFeatures,
+		},
+		{
+			Name:      "enable",
+			Usage:     "Enable a feature flag",
+			ArgsUsage: "name",
+			Flags:     []cli.Flag{conduitDirFlag},
+			Action:    toggleFeature(true),
+		},
+		{
+			Name:      "disable",
+			Usage:     "Disable a feature flag",
+			ArgsUsage: "name",
+			Flags:     []cli.Flag{conduitDirFlag},
+			Action:    toggleFeature(false),
+		},
+	},
+}
+
+// withFeatureFlags opens the metadata store in the conduit directory when the Conduit daemon isn't running
+func withFeatureFlags(ctx *cli.Context, f func(*core.FeatureFlagManager) error) error {
+	store, err := core.OpenMetadataStore(core.MetadataStorePath(&core.Config{ConduitDir: ctx.String("conduitdir")}))
+	if err != nil {
+		return fmt.Errorf("could not open metadata store: %v", err)
+	}
+	defer store.Close()
+	return f(core.NewFeatureFlagManager(store))
+}
+
+// printFeatures prints the feature flags as a table
+func printFeatures(flags []core.FeatureFlag) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tENABLED\tDESCRIPTION")
+	for _, flag := range flags {
+		fmt.Fprintf(w, "%s\t%v\t%s\n", flag.Name, flag.Enabled, flag.Description)
+	}
+	w.Flush()
+}
+
+// listFeatures is the action of the feature list command
+func listFeatures(ctx *cli.Context) error {
+	var flags []core.FeatureFlag
+	err := callConduit(ctx, "conduit_feature_list", nil, &flags)
+	if err != nil && !isRPCError(err) {
+		err = withFeatureFlags(ctx, func(m *core.FeatureFlagManager) error {
+			flags = m.List()
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	printFeatures(flags)
+	return nil
+}
+
+// toggleFeature returns the action of the feature enable and disable commands
+func toggleFeature(enable bool) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		if ctx.NArg() != 1 {
+			return cli.ShowSubcommandHelp(ctx)
+		}
+		name := ctx.Args().First()
+		method := "conduit_feature_disable"
+		if enable {
+			method = "conduit_feature_enable"
+		}
+		var flags []core.FeatureFlag
+		err := callConduit(ctx, method, map[string]string{"name": name}, &flags)
+		if err != nil && !isRPCError(err) {
+			err = withFeatureFlags(ctx, func(m *core.FeatureFlagManager) error {
+				if enable {
+					err = m.Enable(name)
+				} else {
+					err = m.Disable(name)
+				}
+				flags = m.List()
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+		printFeatures(flags)
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/urfave/cli"
+)
+
+var forwardingCommand = cli.Command{
+	Name:  "forwarding",
+	Usage: "Inspect the forwarding history exported by Conduit",
+	Subcommands: []cli.Command{
+		forwardingStatsCommand,
+	},
+}
+
+var forwardingStatsCommand = cli.Command{
+	Name:  "stats",
+	Usage: "Summarize the forwards and fees earned over a period",
+	Description: `
+	Reads the forwarding events exported by Conduit in forwarding_history.csv and
+	prints the number of forwards, the amount forwarded and the fees earned over
+	the period, in total and per outgoing channel.`,
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "period",
+			Usage: "only count the forwards of this period, relative to now",
+			Value: 24 * time.Hour,
+		},
+		conduitDirFlag,
+	},
+	Action: forwardingStats,
+}
+
+// channelForwards are the forwards through one outgoing channel
+type channelForwards struct {
+	chanID   uint64
+	forwards int
+	amtMsat  uint64
+	feeMsat  uint64
+}
+
+// forwardingStats is the action of the forwarding stats command
+func forwardingStats(ctx *cli.Context) error {
+	filename := core.ForwardingHistoryPath(&core.Config{ConduitDir: ctx.String("conduitdir")})
+	return runForwardingStats(filename, ctx.Duration("period"), time.Now(), os.Stdout)
+}
+
+// runForwardingStats prints the totals of the forwards recorded in the last period
+func runForwardingStats(filename string, period time.Duration, now time.Time, out io.Writer) error {
+	events, err := core.ReadForwardingHistory(filename, now.Add(-period))
+	if err != nil {
+		return fmt.Errorf("could not read forwarding history: %v", err)
+	}
+	total := channelForwards{}
+	byChannel := make(map[uint64]*channelForwards)
+	for _, event := range events {
+		c, ok := byChannel[event.ChanIdOut]
+		if !ok {
+			c = &channelForwards{chanID: event.ChanIdOut}
+			byChannel[event.ChanIdOut] = c
+		}
+		for _, f := range []*channelForwards{c, &total} {
+			f.forwards++
+			f.amtMsat += event.AmountOutMsat
+			f.feeMsat += event.FeeMsat
+		}
+	}
+	fmt.Fprintf(out, "Period: %v\n", period)
+	fmt.Fprintf(out, "Forwards: %d\n", total.forwards)
+	fmt.Fprintf(out, "Forwarded: %d sats\n", total.amtMsat/1000)
+	fmt.Fprintf(out, "Fees earned: %d msat (%d sats)\n", total.feeMsat, total.feeMsat/1000)
+	if len(byChannel) == 0 {
+		return nil
+	}
+	channels := make([]*channelForwards, 0, len(byChannel))
+	for _, c := range byChannel {
+		channels = append(channels, c)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].feeMsat != channels[j].feeMsat {
+			return channels[i].feeMsat > channels[j].feeMsat
+		}
+		return channels[i].chanID < channels[j].chanID
+	})
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAN ID OUT\tFORWARDS\tFORWARDED (SATS)\tFEES (MSAT)")
+	for _, c := range channels {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\n", c.chanID, c.forwards, c.amtMsat/1000, c.feeMsat)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestForwardingStats ensures only the forwards of the period are counted and channels are sorted by fees
+func TestForwardingStats(t *testing.T) {
+	filename := path.Join(t.TempDir(), "forwarding_history.csv")
+	csv := `timestamp,chan_id_in,chan_id_out,amount_in_msat,amount_out_msat,fee_msat
+2022-04-18T10:00:00Z,1,2,5005000,5000000,5000
+2022-04-20T09:00:00Z,1,2,1001000,1000000,1000
+2022-04-20T09:30:00Z,2,3,2003000,2000000,3000
+2022-04-20T09:45:00Z,3,2,3001500,3000000,1500
+`
+	if err := ioutil.WriteFile(filename, []byte(csv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	now := time.Date(2022, 4, 20, 10, 0, 0, 0, time.UTC)
+	if err := runForwardingStats(filename, 24*time.Hour, now, &out); err != nil {
+		t.Fatalf("runForwardingStats returned an error: %v", err)
+	}
+	for _, want := range []string{"Forwards: 3\n", "Forwarded: 6000 sats\n", "Fees earned: 5500 msat (5 sats)\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if i, j := strings.Index(out.String(), "\n3  "), strings.Index(out.String(), "\n2  "); i == -1 || j == -1 || i > j {
+		t.Errorf("expected channel 3 before channel 2:\n%s", out.String())
+	}
+	out.Reset()
+	if err := runForwardingStats(path.Join(t.TempDir(), "missing.csv"), time.Hour, now, &out); err != nil || !strings.Contains(out.String(), "Forwards: 0") {
+		t.Errorf("expected no forwards without a history file, got %q (%v)", out.String(), err)
+	}
+}
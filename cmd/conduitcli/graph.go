@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var nodeGraphCommand = cli.Command{
+	Name:  "graph",
+	Usage: "Inspect the channel graph",
+	Subcommands: []cli.Command{
+		graphExportCommand,
+	},
+}
+
+var graphExportCommand = cli.Command{
+	Name:  "export",
+	Usage: "Dump the channel graph known to LND to a file",
+	Description: `
+	Writes the nodes and channels returned by DescribeGraph to --output as the
+	proto JSON of the response, as GraphML or as a Graphviz DOT file in which
+	nodes are labeled with their alias and edges are weighted by capacity.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "the file to write the graph to",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "the format of the file, one of json, graphml or dot",
+			Value: "json",
+		},
+	},
+	Action: graphExport,
+}
+
+// graphMLTemplate is a minimal GraphML document with the alias of every node and the capacity of every channel
+var graphMLTemplate = template.Must(template.New("graphml").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="alias" for="node" attr.name="alias" attr.type="string"/>
+  <key id="capacity" for="edge" attr.name="capacity" attr.type="long"/>
+  <graph id="lightning" edgedefault="undirected">
+{{- range .Nodes}}
+    <node id="{{xml .PubKey}}"><data key="alias">{{xml .Alias}}</data></node>
+{{- end}}
+{{- range .Edges}}
+    <edge id="{{.ChannelId}}" source="{{xml .Node1Pub}}" target="{{xml .Node2Pub}}"><data key="capacity">{{.Capacity}}</data></edge>
+{{- end}}
+  </graph>
+</graphml>
+`))
+
+// xmlEscape escapes s for use in XML text and attributes
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// dotQuote returns s as a quoted DOT identifier
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// writeGraphDOT writes the graph as an undirected Graphviz graph
+func writeGraphDOT(graph *lnrpc.ChannelGraph, out io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("graph lightning {\n")
+	for _, node := range graph.Nodes {
+		label := node.Alias
+		if label == "" {
+			label = node.PubKey
+		}
+		fmt.Fprintf(&buf, "  %s [label=%s];\n", dotQuote(node.PubKey), dotQuote(label))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&buf, "  %s -- %s [weight=%d];\n", dotQuote(edge.Node1Pub), dotQuote(edge.Node2Pub), edge.Capacity)
+	}
+	buf.WriteString("}\n")
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// graphExport is the action of the node graph export command
+func graphExport(ctx *cli.Context) error {
+	if !ctx.IsSet("output") {
+		return cli.ShowCommandHelp(ctx, "export")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	file, err := os.Create(ctx.String("output"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = runGraphExport(context.Background(), client, ctx.String("format"), file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// runGraphExport fetches the channel graph and writes it in the given format
+func runGraphExport(ctx context.Context, client lnrpc.LightningClient, format string, out io.Writer) error {
+	if format != "json" && format != "graphml" && format != "dot" {
+		return fmt.Errorf("unknown format %q, expected json, graphml or dot", format)
+	}
+	graph, err := client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "graphml":
+		return graphMLTemplate.Execute(out, graph)
+	case "dot":
+		return writeGraphDOT(graph, out)
+	}
+	raw, err := protojson.Marshal(graph)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(raw)
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// newGraphClient returns a fake client whose channel graph has 5 nodes and 6 channels
+func newGraphClient() *fakeLightningClient {
+	pubkeys := []string{selfPubkey, peerAPubkey, peerBPubkey, peerCPubkey, "02" + strings.Repeat("ee", 31)}
+	graph := &lnrpc.ChannelGraph{}
+	for i, pubkey := range pubkeys {
+		alias := []string{"self", "alice", "bob", `carol "the <router>"`, ""}[i]
+		graph.Nodes = append(graph.Nodes, &lnrpc.LightningNode{PubKey: pubkey, Alias: alias})
+	}
+	for i, pair := range [][2]int{{0, 1}, {0, 2}, {1, 2}, {1, 3}, {2, 4}, {3, 4}} {
+		graph.Edges = append(graph.Edges, &lnrpc.ChannelEdge{
+			ChannelId: uint64(100 + i),
+			Node1Pub:  pubkeys[pair[0]],
+			Node2Pub:  pubkeys[pair[1]],
+			Capacity:  int64(1000000 * (i + 1)),
+		})
+	}
+	return &fakeLightningClient{graph: graph}
+}
+
+// TestGraphExportDOT ensures every channel is an edge weighted by its capacity and nodes are labeled by alias
+func TestGraphExportDOT(t *testing.T) {
+	var out bytes.Buffer
+	if err := runGraphExport(context.Background(), newGraphClient(), "dot", &out); err != nil {
+		t.Fatalf("runGraphExport returned an error: %v", err)
+	}
+	dot := out.String()
+	if !strings.HasPrefix(dot, "graph lightning {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("malformed DOT file:\n%s", dot)
+	}
+	if edges := strings.Count(dot, " -- "); edges != 6 {
+		t.Errorf("expected 6 edges, got %d:\n%s", edges, dot)
+	}
+	for _, want := range []string{
+		`"` + peerAPubkey + `" [label="alice"];`,
+		`[label="carol \"the <router>\""]`,
+		`"` + peerCPubkey + `" -- "02` + strings.Repeat("ee", 31) + `" [weight=6000000];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT file is missing %s:\n%s", want, dot)
+		}
+	}
+}
+
+// TestGraphExportGraphML ensures the GraphML output is valid XML with every node and channel
+func TestGraphExportGraphML(t *testing.T) {
+	var out bytes.Buffer
+	if err := runGraphExport(context.Background(), newGraphClient(), "graphml", &out); err != nil {
+		t.Fatalf("runGraphExport returned an error: %v", err)
+	}
+	var doc struct {
+		Nodes []struct {
+			ID    string `xml:"id,attr"`
+			Alias string `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid GraphML: %v\n%s", err, out.String())
+	}
+	if len(doc.Nodes) != 5 || len(doc.Edges) != 6 || doc.Nodes[3].Alias != `carol "the <router>"` {
+		t.Errorf("unexpected GraphML:\n%s", out.String())
+	}
+}
+
+// TestGraphExportJSON ensures the JSON output is the proto JSON of the graph and unknown formats are rejected
+func TestGraphExportJSON(t *testing.T) {
+	client := newGraphClient()
+	var out bytes.Buffer
+	if err := runGraphExport(context.Background(), client, "json", &out); err != nil {
+		t.Fatalf("runGraphExport returned an error: %v", err)
+	}
+	var graph lnrpc.ChannelGraph
+	if err := protojson.Unmarshal(out.Bytes(), &graph); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !proto.Equal(&graph, client.graph) {
+		t.Errorf("exported graph differs from DescribeGraph:\n%s", out.String())
+	}
+	if err := runGraphExport(context.Background(), client, "csv", &out); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const defaultWarnBlocks = 10
+
+var htlcCommand = cli.Command{
+	Name:  "htlc",
+	Usage: "Monitor HTLCs",
+	Subcommands: []cli.Command{
+		htlcListCommand,
+	},
+}
+
+var htlcListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List the in-flight HTLCs of every channel",
+	Description: `
+	Lists the pending HTLCs of every channel. HTLCs expiring within --warn-blocks
+	blocks are marked with a ! as they may indicate a stuck payment which will lead
+	to a force-close.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "pending-only",
+			Usage: "hide HTLCs which have already expired and await on-chain resolution",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the HTLCs as JSON",
+		},
+		cli.Uint64Flag{
+			Name:  "warn-blocks",
+			Usage: "highlight HTLCs expiring within this many blocks",
+			Value: defaultWarnBlocks,
+		},
+	},
+	Action: htlcList,
+}
+
+// htlcRow is an HTLC flattened out of its channel
+type htlcRow struct {
+	ChannelPoint string `json:"channel_point"`
+	AmountSat    int64  `json:"amount_sat"`
+	Direction    string `json:"direction"`
+	ExpiryHeight uint32 `json:"expiry_height"`
+	BlocksLeft   int64  `json:"blocks_left"`
+	HashLock     string `json:"hash_lock"`
+	ExpiringSoon bool   `json:"expiring_soon"`
+}
+
+// flattenHTLCs returns the pending HTLCs of every channel relative to the current block height
+func flattenHTLCs(channels []*lnrpc.Channel, height uint32, warnBlocks uint64, pendingOnly bool) []*htlcRow {
+	var rows []*htlcRow
+	for _, channel := range channels {
+		for _, htlc := range channel.PendingHtlcs {
+			blocksLeft := int64(htlc.ExpirationHeight) - int64(height)
+			if pendingOnly && blocksLeft <= 0 {
+				continue
+			}
+			direction := "outgoing"
+			if htlc.Incoming {
+				direction = "incoming"
+			}
+			rows = append(rows, &htlcRow{
+				ChannelPoint: channel.ChannelPoint,
+				AmountSat:    htlc.Amount,
+				Direction:    direction,
+				ExpiryHeight: htlc.ExpirationHeight,
+				BlocksLeft:   blocksLeft,
+				HashLock:     hex.EncodeToString(htlc.HashLock),
+				ExpiringSoon: blocksLeft <= int64(warnBlocks),
+			})
+		}
+	}
+	return rows
+}
+
+// htlcList is the action of the htlc list command
+func htlcList(ctx *cli.Context) error {
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runHTLCList(context.Background(), client, ctx.Uint64("warn-blocks"), ctx.Bool("pending-only"), ctx.Bool("json"), os.Stdout)
+}
+
+// runHTLCList prints the pending HTLCs of every channel as a table or as JSON
+func runHTLCList(ctx context.Context, client lnrpc.LightningClient, warnBlocks uint64, pendingOnly, asJSON bool, out io.Writer) error {
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	rows := flattenHTLCs(resp.Channels, info.BlockHeight, warnBlocks, pendingOnly)
+	if asJSON {
+		if rows == nil {
+			rows = []*htlcRow{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(rows)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "\tCHANNEL POINT\tAMOUNT\tDIRECTION\tEXPIRY BLOCK\tBLOCKS LEFT\tHASH LOCK")
+	for _, row := range rows {
+		// a marker column keeps the table aligned, unlike colors
+		marker := ""
+		if row.ExpiringSoon {
+			marker = "!"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\t%s\n", marker, row.ChannelPoint, row.AmountSat, row.Direction, row.ExpiryHeight, row.BlocksLeft, row.HashLock)
+	}
+	return w.Flush()
+}
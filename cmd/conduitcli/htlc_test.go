@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// TestHTLCList ensures HTLCs are flattened out of their channels and that HTLCs close to expiry are highlighted
+func TestHTLCList(t *testing.T) {
+	client := &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{BlockHeight: 700000},
+		channels: []*lnrpc.Channel{
+			{ChannelPoint: "aa:0", PendingHtlcs: []*lnrpc.HTLC{
+				{Incoming: true, Amount: 1000, HashLock: []byte{0xde, 0xad}, ExpirationHeight: 700100},
+				{Amount: 2000, HashLock: []byte{0xbe, 0xef}, ExpirationHeight: 700010},
+			}},
+			{ChannelPoint: "bb:1"},
+			{ChannelPoint: "cc:0", PendingHtlcs: []*lnrpc.HTLC{
+				{Amount: 3000, ExpirationHeight: 699990},
+			}},
+		},
+	}
+	var out bytes.Buffer
+	if err := runHTLCList(context.Background(), client, defaultWarnBlocks, false, true, &out); err != nil {
+		t.Fatalf("runHTLCList returned an error: %v", err)
+	}
+	var rows []*htlcRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	expected := []htlcRow{
+		{ChannelPoint: "aa:0", AmountSat: 1000, Direction: "incoming", ExpiryHeight: 700100, BlocksLeft: 100, HashLock: "dead"},
+		{ChannelPoint: "aa:0", AmountSat: 2000, Direction: "outgoing", ExpiryHeight: 700010, BlocksLeft: 10, HashLock: "beef", ExpiringSoon: true},
+		{ChannelPoint: "cc:0", AmountSat: 3000, Direction: "outgoing", ExpiryHeight: 699990, BlocksLeft: -10, ExpiringSoon: true},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d HTLCs, got %d", len(expected), len(rows))
+	}
+	for i, row := range rows {
+		if *row != expected[i] {
+			t.Errorf("HTLC %d: expected %+v, got %+v", i, expected[i], *row)
+		}
+	}
+	if rows := flattenHTLCs(client.channels, 700000, 5, true); len(rows) != 2 || rows[1].ExpiringSoon {
+		t.Errorf("expected 2 pending HTLCs none of which expire within 5 blocks, got %+v", rows)
+	}
+}
+
+// TestHTLCListTable ensures only HTLCs close to expiry are marked in the table
+func TestHTLCListTable(t *testing.T) {
+	client := &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{BlockHeight: 100},
+		channels: []*lnrpc.Channel{{ChannelPoint: "aa:0", PendingHtlcs: []*lnrpc.HTLC{
+			{Amount: 1000, ExpirationHeight: 200},
+			{Amount: 2000, ExpirationHeight: 105},
+		}}},
+	}
+	var out bytes.Buffer
+	if err := runHTLCList(context.Background(), client, defaultWarnBlocks, false, false, &out); err != nil {
+		t.Fatalf("runHTLCList returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || strings.HasPrefix(lines[1], "!") || !strings.HasPrefix(lines[2], "!") {
+		t.Errorf("expected only the second HTLC to be marked:\n%s", out.String())
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const (
+	defaultInvoiceLimit = 100
+	payReqDisplayLength = 24
+)
+
+var invoiceCommand = cli.Command{
+	Name:  "invoice",
+	Usage: "Inspect invoices",
+	Subcommands: []cli.Command{
+		invoiceListCommand,
+	},
+}
+
+var invoiceListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List invoices, newest first",
+	Description: `
+	Lists up to --limit invoices matching the filters, newest first. The status
+	flags can be combined, all invoices are listed when none is set. --since
+	accepts a timestamp (2006-01-02T15:04:05Z07:00), a date (2006-01-02) or a
+	duration relative to now (i.e. 72h). With --json, one invoice is printed per
+	line as soon as it's fetched.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "pending",
+			Usage: "list the open and accepted invoices",
+		},
+		cli.BoolFlag{
+			Name:  "settled",
+			Usage: "list the settled invoices",
+		},
+		cli.BoolFlag{
+			Name:  "cancelled",
+			Usage: "list the cancelled invoices",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "only list invoices created at or after this time",
+		},
+		cli.Int64Flag{
+			Name:  "amount-min",
+			Usage: "only list invoices of at least this amount in msat",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the invoices as newline delimited JSON",
+		},
+		cli.Uint64Flag{
+			Name:  "limit",
+			Usage: "the maximum number of invoices to list",
+			Value: defaultInvoiceLimit,
+		},
+	},
+	Action: invoiceList,
+}
+
+// invoiceFilter are the filters of the invoice list command
+type invoiceFilter struct {
+	pending, settled, cancelled bool
+	since                       time.Time
+	amountMinMsat               int64
+	limit                       uint64
+}
+
+// match reports whether the invoice passes the filter
+func (f *invoiceFilter) match(invoice *lnrpc.Invoice) bool {
+	if invoice.ValueMsat < f.amountMinMsat {
+		return false
+	}
+	if !f.pending && !f.settled && !f.cancelled {
+		return true
+	}
+	switch invoice.State {
+	case lnrpc.Invoice_OPEN, lnrpc.Invoice_ACCEPTED:
+		return f.pending
+	case lnrpc.Invoice_SETTLED:
+		return f.settled
+	case lnrpc.Invoice_CANCELED:
+		return f.cancelled
+	}
+	return false
+}
+
+// invoiceRow is an invoice as printed by the invoice list command
+type invoiceRow struct {
+	PaymentRequest string `json:"payment_request"`
+	AmountMsat     int64  `json:"amount_msat"`
+	Status         string `json:"status"`
+	CreationDate   int64  `json:"creation_date"`
+	SettleDate     int64  `json:"settle_date"`
+	Memo           string `json:"memo"`
+}
+
+// invoiceList is the action of the invoice list command
+func invoiceList(ctx *cli.Context) error {
+	since, err := parseSince(ctx.String("since"), time.Now())
+	if err != nil {
+		return err
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	filter := &invoiceFilter{
+		pending:       ctx.Bool("pending"),
+		settled:       ctx.Bool("settled"),
+		cancelled:     ctx.Bool("cancelled"),
+		since:         since,
+		amountMinMsat: ctx.Int64("amount-min"),
+		limit:         ctx.Uint64("limit"),
+	}
+	return runInvoiceList(context.Background(), client, filter, ctx.Bool("json"), os.Stdout)
+}
+
+// runInvoiceList pages through the invoices from the newest until the limit is reached or the invoices are older than --since
+func runInvoiceList(ctx context.Context, client lnrpc.LightningClient, filter *invoiceFilter, asJSON bool, out io.Writer) error {
+	if filter.limit == 0 {
+		return fmt.Errorf("--limit must be positive")
+	}
+	enc := json.NewEncoder(out)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if !asJSON {
+		fmt.Fprintln(w, "PAYMENT REQUEST\tAMOUNT\tSTATUS\tSETTLE DATE\tMEMO")
+	}
+	// LND can only filter out the invoices which aren't pending
+	pendingOnly := filter.pending && !filter.settled && !filter.cancelled
+	var listed, offset uint64
+	for listed < filter.limit {
+		resp, err := client.ListInvoices(ctx, &lnrpc.ListInvoiceRequest{
+			PendingOnly:    pendingOnly,
+			IndexOffset:    offset,
+			NumMaxInvoices: filter.limit,
+			Reversed:       true,
+		})
+		if err != nil {
+			return err
+		}
+		if len(resp.Invoices) == 0 {
+			break
+		}
+		// the invoices of a page are in chronological order
+		older := false
+		for i := len(resp.Invoices) - 1; i >= 0 && listed < filter.limit; i-- {
+			invoice := resp.Invoices[i]
+			if time.Unix(invoice.CreationDate, 0).Before(filter.since) {
+				older = true
+				break
+			}
+			if !filter.match(invoice) {
+				continue
+			}
+			row := &invoiceRow{
+				PaymentRequest: invoice.PaymentRequest,
+				AmountMsat:     invoice.ValueMsat,
+				Status:         invoice.State.String(),
+				CreationDate:   invoice.CreationDate,
+				SettleDate:     invoice.SettleDate,
+				Memo:           invoice.Memo,
+			}
+			listed++
+			if asJSON {
+				if err = enc.Encode(row); err != nil {
+					return err
+				}
+				continue
+			}
+			payReq := row.PaymentRequest
+			if len(payReq) > payReqDisplayLength {
+				payReq = payReq[:payReqDisplayLength] + "..."
+			}
+			settled := "-"
+			if row.SettleDate > 0 {
+				settled = time.Unix(row.SettleDate, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", payReq, row.AmountMsat, row.Status, settled, row.Memo)
+		}
+		if older || resp.FirstIndexOffset <= 1 {
+			break
+		}
+		offset = resp.FirstIndexOffset
+	}
+	if asJSON {
+		return nil
+	}
+	return w.Flush()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newInvoiceClient returns a fake client with 10 invoices created an hour apart, the newest now. Every third invoice is settled and the fifth is cancelled
+func newInvoiceClient(now time.Time) *fakeLightningClient {
+	client := &fakeLightningClient{}
+	for i := 1; i <= 10; i++ {
+		invoice := &lnrpc.Invoice{
+			AddIndex:       uint64(i),
+			Memo:           fmt.Sprintf("invoice %d", i),
+			PaymentRequest: "lnbc" + strings.Repeat(fmt.Sprint(i%10), 40),
+			ValueMsat:      int64(i * 1000),
+			CreationDate:   now.Add(-time.Duration(10-i) * time.Hour).Unix(),
+			State:          lnrpc.Invoice_OPEN,
+		}
+		switch {
+		case i%3 == 0:
+			invoice.State = lnrpc.Invoice_SETTLED
+			invoice.SettleDate = invoice.CreationDate + 60
+		case i == 5:
+			invoice.State = lnrpc.Invoice_CANCELED
+		}
+		client.invoices = append(client.invoices, invoice)
+	}
+	return client
+}
+
+// decodeInvoiceRows decodes the NDJSON output of the invoice list command
+func decodeInvoiceRows(t *testing.T, out *bytes.Buffer) []*invoiceRow {
+	t.Helper()
+	var rows []*invoiceRow
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		row := &invoiceRow{}
+		if err := dec.Decode(row); err != nil {
+			t.Fatalf("Error decoding output: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// TestInvoiceListPending ensures --pending is sent to LND as pending_only and the rows are printed newest first
+func TestInvoiceListPending(t *testing.T) {
+	client := newInvoiceClient(time.Now())
+	var out bytes.Buffer
+	if err := runInvoiceList(context.Background(), client, &invoiceFilter{pending: true, limit: 3}, true, &out); err != nil {
+		t.Fatalf("runInvoiceList returned an error: %v", err)
+	}
+	req := client.invoiceReqs[0]
+	if !req.PendingOnly || !req.Reversed || req.NumMaxInvoices != 3 || req.IndexOffset != 0 {
+		t.Errorf("unexpected ListInvoices request: %v", req)
+	}
+	rows := decodeInvoiceRows(t, &out)
+	if len(rows) != 3 || rows[0].Memo != "invoice 10" || rows[1].Memo != "invoice 8" || rows[2].Memo != "invoice 7" {
+		t.Errorf("expected the 3 newest open invoices, got %+v", rows)
+	}
+}
+
+// TestInvoiceListPaging ensures the client side filters page backwards through the invoices with the index offset until the limit or --since is reached
+func TestInvoiceListPaging(t *testing.T) {
+	now := time.Now()
+	client := newInvoiceClient(now)
+	var out bytes.Buffer
+	filter := &invoiceFilter{settled: true, cancelled: true, amountMinMsat: 2000, limit: 2}
+	if err := runInvoiceList(context.Background(), client, filter, true, &out); err != nil {
+		t.Fatalf("runInvoiceList returned an error: %v", err)
+	}
+	if rows := decodeInvoiceRows(t, &out); len(rows) != 2 || rows[0].Memo != "invoice 9" || rows[1].Memo != "invoice 6" {
+		t.Errorf("expected settled invoices 9 and 6, got %+v", rows)
+	}
+	if len(client.invoiceReqs) != 3 || client.invoiceReqs[0].PendingOnly || client.invoiceReqs[1].IndexOffset != 9 || client.invoiceReqs[2].IndexOffset != 7 {
+		t.Errorf("expected pages before invoices 9 and 7, got %v", client.invoiceReqs)
+	}
+	client = newInvoiceClient(now)
+	out.Reset()
+	filter = &invoiceFilter{since: now.Add(-150 * time.Minute), limit: 100}
+	if err := runInvoiceList(context.Background(), client, filter, true, &out); err != nil {
+		t.Fatalf("runInvoiceList returned an error: %v", err)
+	}
+	if rows := decodeInvoiceRows(t, &out); len(rows) != 3 || rows[2].Memo != "invoice 8" {
+		t.Errorf("expected the invoices of the last 2.5 hours, got %+v", rows)
+	}
+}
+
+// TestInvoiceListTable ensures payment requests are truncated and the settle date is only shown for settled invoices
+func TestInvoiceListTable(t *testing.T) {
+	client := newInvoiceClient(time.Now())
+	var out bytes.Buffer
+	if err := runInvoiceList(context.Background(), client, &invoiceFilter{limit: 2}, false, &out); err != nil {
+		t.Fatalf("runInvoiceList returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PAYMENT REQUEST") {
+		t.Fatalf("expected a header and 2 invoices, got:\n%s", out.String())
+	}
+	open := strings.Fields(lines[1])
+	if open[0] != "lnbc"+strings.Repeat("0", 20)+"..." || open[1] != "10000" || open[2] != "OPEN" || open[3] != "-" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if settled := strings.Fields(lines[2]); settled[2] != "SETTLED" || settled[3] == "-" {
+		t.Errorf("expected the settle date of invoice 9, got %q", lines[2])
+	}
+	if err := runInvoiceList(context.Background(), client, &invoiceFilter{}, false, &out); err == nil {
+		t.Error("expected a zero limit to be rejected")
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright (C) 2015-2018 Lightning Labs and The Lightning Network Developers
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/autopilotrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/wtclientrpc"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/macaroon.v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	defaultRPCServer      = "localhost:10009"
+	defaultTLSCertName    = "tls.cert"
+	defaultMacaroonName   = "admin.macaroon"
+	defaultChain          = "bitcoin"
+	defaultNetwork        = "mainnet"
+	defaultMaxMsgRecvSize = 200 * 1024 * 1024
+)
+
+var (
+	defaultLndDir = utils.AppDataDir("lnd", false)
+	// lndFlags are the global flags used to connect to LND
+	lndFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "rpcserver",
+			Value: defaultRPCServer,
+			Usage: "host:port of LND's gRPC server",
+		},
+		cli.StringFlag{
+			Name:  "lnddir",
+			Value: defaultLndDir,
+			Usage: "path to LND's base directory",
+		},
+		cli.StringFlag{
+			Name:  "tlscertpath",
+			Usage: "path to LND's TLS certificate. Defaults to <lnddir>/tls.cert",
+		},
+		cli.StringFlag{
+			Name:  "macaroonpath",
+			Usage: "path to the macaroon used to authenticate with LND. Defaults to the admin macaroon in <lnddir>",
+		},
+		cli.StringFlag{
+			Name:  "chain",
+			Value: defaultChain,
+			Usage: "the chain LND is running on, e.g. bitcoin",
+		},
+		cli.StringFlag{
+			Name:  "network",
+			Value: defaultNetwork,
+			Usage: "the network LND is running on, e.g. mainnet, testnet, etc.",
+		},
+	}
+)
+
+// rpcServerAddr returns the --rpcserver flag if set. Otherwise, for commands with a --conduitdir flag, it's the first RPC listener of config.yaml, if any
+func rpcServerAddr(ctx *cli.Context) string {
+	if ctx.GlobalIsSet("rpcserver") || ctx.String("conduitdir") == "" {
+		return ctx.GlobalString("rpcserver")
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(ctx.String("conduitdir"), configFileName))
+	if err != nil {
+		return ctx.GlobalString("rpcserver")
+	}
+	var config core.Config
+	if err = yaml.Unmarshal(raw, &config); err != nil || len(config.LndRawRPCListeners) == 0 {
+		return ctx.GlobalString("rpcserver")
+	}
+	return listenerDialAddr(config.LndRawRPCListeners[0])
+}
+
+// listenerDialAddr returns the address to dial to reach an LND listener, which may have no host, a wildcard host or no port
+func listenerDialAddr(listener string) string {
+	host, port, err := net.SplitHostPort(listener)
+	if err != nil {
+		// LND adds its default port to listeners without one
+		_, defaultPort, _ := net.SplitHostPort(defaultRPCServer)
+		host, port = listener, defaultPort
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// getClientConn dials LND's gRPC server using the TLS certificate and macaroon from the global flags
+func getClientConn(ctx *cli.Context) (*grpc.ClientConn, error) {
+	lndDir := ctx.GlobalString("lnddir")
+	tlsCertPath := ctx.GlobalString("tlscertpath")
+	if tlsCertPath == "" {
+		tlsCertPath = filepath.Join(lndDir, defaultTLSCertName)
+	}
+	macPath := ctx.GlobalString("macaroonpath")
+	if macPath == "" {
+		macPath = filepath.Join(lndDir, "data", "chain", ctx.GlobalString("chain"), ctx.GlobalString("network"), defaultMacaroonName)
+	}
+	creds, err := credentials.NewClientTLSFromFile(tlsCertPath, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %v", err)
+	}
+	macBytes, err := ioutil.ReadFile(macPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read macaroon: %v", err)
+	}
+	mac := &macaroon.Macaroon{}
+	if err = mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, fmt.Errorf("could not decode macaroon: %v", err)
+	}
+	macCred, err := macaroons.NewMacaroonCredential(mac)
+	if err != nil {
+		return nil, fmt.Errorf("could not create macaroon credential: %v", err)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(macCred),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(defaultMaxMsgRecvSize)),
+	}
+	conn, err := grpc.Dial(rpcServerAddr(ctx), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to LND: %v", err)
+	}
+	return conn, nil
+}
+
+// getLightningClient returns a `lnrpc.LightningClient` and a function to close its connection
+func getLightningClient(ctx *cli.Context) (lnrpc.LightningClient, func(), error) {
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanUp := func() {
+		conn.Close()
+	}
+	return lnrpc.NewLightningClient(conn), cleanUp, nil
+}
+
+// getAutopilotClient returns a `autopilotrpc.AutopilotClient` and a function to close its connection
+func getAutopilotClient(ctx *cli.Context) (autopilotrpc.AutopilotClient, func(), error) {
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanUp := func() {
+		conn.Close()
+	}
+	return autopilotrpc.NewAutopilotClient(conn), cleanUp, nil
+}
+
+// getWatchtowerClient returns a `wtclientrpc.WatchtowerClientClient` and a function to close its connection
+func getWatchtowerClient(ctx *cli.Context) (wtclientrpc.WatchtowerClientClient, func(), error) {
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanUp := func() {
+		conn.Close()
+	}
+	return wtclientrpc.NewWatchtowerClientClient(conn), cleanUp, nil
+}
@@ -36,8 +36,31 @@ func main() {
 	app := cli.NewApp()
 	app.Name = "conduitcli"
 	app.Usage = "Control panel for the Conduit Plugin Manager (conduit)"
+	app.Flags = append(lndFlags, jsonRPCServerFlag, jsonRPCTLSCertFlag)
 	app.Commands = []cli.Command{
 		testCommand,
+		diagnoseCommand,
+		channelCommand,
+		watchCommand,
+		configCommand,
+		featureCommand,
+		exportChannelsCommand,
+		nodeCommand,
+		paymentCommand,
+		htlcCommand,
+		autopilotCommand,
+		walletCommand,
+		forwardingCommand,
+		routingCommand,
+		secretsCommand,
+		watchtowerCommand,
+		invoiceCommand,
+		onchainCommand,
+		pluginCommand,
+		decodeCommand,
+		peerCommand,
+		debugCommand,
+		tlsCommand,
 	}
 	if err := app.Run(os.Args); err != nil {
 		fatal(err)
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc"
+)
+
+// fakeLightningClient is a `lnrpc.LightningClient` whose methods return canned responses. Calling a method without a canned response panics
+type fakeLightningClient struct {
+	lnrpc.LightningClient
+	channels     []*lnrpc.Channel
+	chanInfo     map[uint64]*lnrpc.ChannelEdge
+	closeUpdates []*lnrpc.CloseStatusUpdate
+	closeReqs    []*lnrpc.CloseChannelRequest
+	chanBackup   []byte
+	info         *lnrpc.GetInfoResponse
+	wallet       *lnrpc.WalletBalanceResponse
+	chanBalance  *lnrpc.ChannelBalanceResponse
+	policyReqs   []*lnrpc.PolicyUpdateRequest
+	policyResp   *lnrpc.PolicyUpdateResponse
+	addedInvoice *lnrpc.AddInvoiceResponse
+	routes       []*lnrpc.Route
+	routeReqs    []*lnrpc.QueryRoutesRequest
+	graph        *lnrpc.ChannelGraph
+	pending      *lnrpc.PendingChannelsResponse
+	invoices     []*lnrpc.Invoice
+	invoiceReqs  []*lnrpc.ListInvoiceRequest
+	sendReqs     []*lnrpc.SendCoinsRequest
+	feeReqs      []*lnrpc.EstimateFeeRequest
+	fee          *lnrpc.EstimateFeeResponse
+	payReqs      map[string]*lnrpc.PayReq
+	closed       []*lnrpc.ChannelCloseSummary
+	restoreReqs  []*lnrpc.RestoreChanBackupRequest
+	routeProb    float64
+	nodes        map[string]*lnrpc.NodeInfo
+	nodeReqs     []*lnrpc.NodeInfoRequest
+	payments     []*lnrpc.Payment
+	paymentReqs  []*lnrpc.ListPaymentsRequest
+	forwards     []*lnrpc.ForwardingEvent
+	forwardReqs  []*lnrpc.ForwardingHistoryRequest
+	connectReqs  []*lnrpc.ConnectPeerRequest
+	disconnected []string
+	peerErr      error
+	peers        []*lnrpc.Peer
+}
+
+func (f *fakeLightningClient) GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest, opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error) {
+	return f.info, nil
+}
+
+func (f *fakeLightningClient) WalletBalance(ctx context.Context, in *lnrpc.WalletBalanceRequest, opts ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+	return f.wallet, nil
+}
+
+func (f *fakeLightningClient) ChannelBalance(ctx context.Context, in *lnrpc.ChannelBalanceRequest, opts ...grpc.CallOption) (*lnrpc.ChannelBalanceResponse, error) {
+	return f.chanBalance, nil
+}
+
+func (f *fakeLightningClient) ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
+	return &lnrpc.ListChannelsResponse{Channels: f.channels}, nil
+}
+
+func (f *fakeLightningClient) GetChanInfo(ctx context.Context, in *lnrpc.ChanInfoRequest, opts ...grpc.CallOption) (*lnrpc.ChannelEdge, error) {
+	return f.chanInfo[in.ChanId], nil
+}
+
+func (f *fakeLightningClient) UpdateChannelPolicy(ctx context.Context, in *lnrpc.PolicyUpdateRequest, opts ...grpc.CallOption) (*lnrpc.PolicyUpdateResponse, error) {
+	f.policyReqs = append(f.policyReqs, in)
+	return f.policyResp, nil
+}
+
+func (f *fakeLightningClient) AddInvoice(ctx context.Context, in *lnrpc.Invoice, opts ...grpc.CallOption) (*lnrpc.AddInvoiceResponse, error) {
+	return f.addedInvoice, nil
+}
+
+// QueryRoutes returns the canned routes one at a time
+func (f *fakeLightningClient) QueryRoutes(ctx context.Context, in *lnrpc.QueryRoutesRequest, opts ...grpc.CallOption) (*lnrpc.QueryRoutesResponse, error) {
+	f.routeReqs = append(f.routeReqs, in)
+	if len(f.routes) == 0 {
+		return nil, fmt.Errorf("unable to find a path to destination")
+	}
+	route := f.routes[0]
+	f.routes = f.routes[1:]
+	return &lnrpc.QueryRoutesResponse{Routes: []*lnrpc.Route{route}, SuccessProb: f.routeProb}, nil
+}
+
+func (f *fakeLightningClient) CloseChannel(ctx context.Context, in *lnrpc.CloseChannelRequest, opts ...grpc.CallOption) (lnrpc.Lightning_CloseChannelClient, error) {
+	f.closeReqs = append(f.closeReqs, in)
+	return &fakeStream[lnrpc.CloseStatusUpdate]{msgs: f.closeUpdates}, nil
+}
+
+func (f *fakeLightningClient) ExportAllChannelBackups(ctx context.Context, in *lnrpc.ChanBackupExportRequest, opts ...grpc.CallOption) (*lnrpc.ChanBackupSnapshot, error) {
+	return &lnrpc.ChanBackupSnapshot{MultiChanBackup: &lnrpc.MultiChanBackup{MultiChanBackup: f.chanBackup}}, nil
+}
+
+func (f *fakeLightningClient) DescribeGraph(ctx context.Context, in *lnrpc.ChannelGraphRequest, opts ...grpc.CallOption) (*lnrpc.ChannelGraph, error) {
+	return f.graph, nil
+}
+
+func (f *fakeLightningClient) PendingChannels(ctx context.Context, in *lnrpc.PendingChannelsRequest, opts ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error) {
+	return f.pending, nil
+}
+
+// ListInvoices pages through the canned invoices, sorted by add index, backwards from the index offset like LND does for a reversed query
+func (f *fakeLightningClient) ListInvoices(ctx context.Context, in *lnrpc.ListInvoiceRequest, opts ...grpc.CallOption) (*lnrpc.ListInvoiceResponse, error) {
+	f.invoiceReqs = append(f.invoiceReqs, in)
+	var page []*lnrpc.Invoice
+	for i := len(f.invoices) - 1; i >= 0 && uint64(len(page)) < in.NumMaxInvoices; i-- {
+		invoice := f.invoices[i]
+		if in.IndexOffset != 0 && invoice.AddIndex >= in.IndexOffset {
+			continue
+		}
+		if in.PendingOnly && invoice.State != lnrpc.Invoice_OPEN && invoice.State != lnrpc.Invoice_ACCEPTED {
+			continue
+		}
+		page = append([]*lnrpc.Invoice{invoice}, page...)
+	}
+	resp := &lnrpc.ListInvoiceResponse{Invoices: page}
+	if len(page) > 0 {
+		resp.FirstIndexOffset = page[0].AddIndex
+		resp.LastIndexOffset = page[len(page)-1].AddIndex
+	}
+	return resp, nil
+}
+
+// ListPayments pages through the canned payments, sorted by payment index, backwards from the index offset like LND does for a reversed query
+func (f *fakeLightningClient) ListPayments(ctx context.Context, in *lnrpc.ListPaymentsRequest, opts ...grpc.CallOption) (*lnrpc.ListPaymentsResponse, error) {
+	f.paymentReqs = append(f.paymentReqs, in)
+	var page []*lnrpc.Payment
+	for i := len(f.payments) - 1; i >= 0 && uint64(len(page)) < in.MaxPayments; i-- {
+		payment := f.payments[i]
+		if in.IndexOffset != 0 && payment.PaymentIndex >= in.IndexOffset {
+			continue
+		}
+		if !in.IncludeIncomplete && payment.Status != lnrpc.Payment_SUCCEEDED {
+			continue
+		}
+		page = append([]*lnrpc.Payment{payment}, page...)
+	}
+	resp := &lnrpc.ListPaymentsResponse{Payments: page}
+	if len(page) > 0 {
+		resp.FirstIndexOffset = page[0].PaymentIndex
+		resp.LastIndexOffset = page[len(page)-1].PaymentIndex
+	}
+	return resp, nil
+}
+
+// ForwardingHistory pages through the canned forwarding events of the request's time range like LND does
+func (f *fakeLightningClient) ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest, opts ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error) {
+	f.forwardReqs = append(f.forwardReqs, in)
+	var inRange []*lnrpc.ForwardingEvent
+	for _, event := range f.forwards {
+		if ts := event.TimestampNs / 1e9; ts >= in.StartTime && ts <= in.EndTime {
+			inRange = append(inRange, event)
+		}
+	}
+	resp := &lnrpc.ForwardingHistoryResponse{LastOffsetIndex: in.IndexOffset}
+	for i := int(in.IndexOffset); i < len(inRange) && uint32(len(resp.ForwardingEvents)) < in.NumMaxEvents; i++ {
+		resp.ForwardingEvents = append(resp.ForwardingEvents, inRange[i])
+		resp.LastOffsetIndex = uint32(i + 1)
+	}
+	return resp, nil
+}
+
+func (f *fakeLightningClient) ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest, opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error) {
+	return &lnrpc.ListPeersResponse{Peers: f.peers}, nil
+}
+
+// ConnectPeer records the request and fails with peerErr if set
+func (f *fakeLightningClient) ConnectPeer(ctx context.Context, in *lnrpc.ConnectPeerRequest, opts ...grpc.CallOption) (*lnrpc.ConnectPeerResponse, error) {
+	f.connectReqs = append(f.connectReqs, in)
+	if f.peerErr != nil {
+		return nil, f.peerErr
+	}
+	return &lnrpc.ConnectPeerResponse{}, nil
+}
+
+// DisconnectPeer records the disconnected public key and fails with peerErr if set
+func (f *fakeLightningClient) DisconnectPeer(ctx context.Context, in *lnrpc.DisconnectPeerRequest, opts ...grpc.CallOption) (*lnrpc.DisconnectPeerResponse, error) {
+	if f.peerErr != nil {
+		return nil, f.peerErr
+	}
+	f.disconnected = append(f.disconnected, in.PubKey)
+	return &lnrpc.DisconnectPeerResponse{}, nil
+}
+
+func (f *fakeLightningClient) SendCoins(ctx context.Context, in *lnrpc.SendCoinsRequest, opts ...grpc.CallOption) (*lnrpc.SendCoinsResponse, error) {
+	f.sendReqs = append(f.sendReqs, in)
+	return &lnrpc.SendCoinsResponse{Txid: fmt.Sprintf("%064x", len(f.sendReqs))}, nil
+}
+
+func (f *fakeLightningClient) EstimateFee(ctx context.Context, in *lnrpc.EstimateFeeRequest, opts ...grpc.CallOption) (*lnrpc.EstimateFeeResponse, error) {
+	f.feeReqs = append(f.feeReqs, in)
+	return f.fee, nil
+}
+
+func (f *fakeLightningClient) DecodePayReq(ctx context.Context, in *lnrpc.PayReqString, opts ...grpc.CallOption) (*lnrpc.PayReq, error) {
+	payReq, ok := f.payReqs[in.PayReq]
+	if !ok {
+		return nil, fmt.Errorf("invalid payment request")
+	}
+	return payReq, nil
+}
+
+func (f *fakeLightningClient) GetNodeInfo(ctx context.Context, in *lnrpc.NodeInfoRequest, opts ...grpc.CallOption) (*lnrpc.NodeInfo, error) {
+	f.nodeReqs = append(f.nodeReqs, in)
+	info, ok := f.nodes[in.PubKey]
+	if !ok {
+		return nil, fmt.Errorf("unable to find node")
+	}
+	if !in.IncludeChannels {
+		return &lnrpc.NodeInfo{Node: info.Node, NumChannels: info.NumChannels, TotalCapacity: info.TotalCapacity}, nil
+	}
+	return info, nil
+}
+
+func (f *fakeLightningClient) ClosedChannels(ctx context.Context, in *lnrpc.ClosedChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error) {
+	return &lnrpc.ClosedChannelsResponse{Channels: f.closed}, nil
+}
+
+func (f *fakeLightningClient) RestoreChannelBackups(ctx context.Context, in *lnrpc.RestoreChanBackupRequest, opts ...grpc.CallOption) (*lnrpc.RestoreBackupResponse, error) {
+	f.restoreReqs = append(f.restoreReqs, in)
+	return &lnrpc.RestoreBackupResponse{}, nil
+}
+
+// fakeStream is a server stream returning the given messages followed by io.EOF
+type fakeStream[T any] struct {
+	grpc.ClientStream
+	msgs []*T
+}
+
+func (s *fakeStream[T]) Recv() (*T, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const maxAliasLength = 32
+
+var colorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var nodeCommand = cli.Command{
+	Name:  "node",
+	Usage: "Manage the LND node",
+	Subcommands: []cli.Command{
+		nodeInfoCommand,
+		nodeLookupCommand,
+		nodeGraphCommand,
+		{
+			Name:  "alias",
+			Usage: "Manage the node alias and color",
+			Subcommands: []cli.Command{
+				setAliasCommand,
+			},
+		},
+	},
+}
+
+var setAliasCommand = cli.Command{
+	Name:      "set",
+	Usage:     "Change the node alias and color",
+	ArgsUsage: "alias color",
+	Description: `
+	Writes the new alias and color (in hex format, i.e. '#3399FF') to
+	config.yaml and asks Conduit to restart LND so that they take effect.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: setAlias,
+}
+
+var nodeInfoCommand = cli.Command{
+	Name:  "info",
+	Usage: "Show the details of the local node",
+	Description: `
+	Prints the alias, pubkey and version of the node, its sync status, channel
+	and peer counts, on-chain balance and URIs. Unless --rpcserver is set, LND is
+	reached on the first LndRawRPCListeners address of config.yaml, if any.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the GetInfo response as JSON",
+		},
+	},
+	Action: nodeInfo,
+}
+
+var nodeLookupCommand = cli.Command{
+	Name:      "lookup",
+	Usage:     "Show the graph info of a remote node",
+	ArgsUsage: "pubkey",
+	Description: `
+	Prints the alias, color, total capacity, channel count, addresses and last
+	update of a node as announced in the channel graph. With --include-channels,
+	every channel of the node is listed with its capacity and the alias of the
+	peer at the other end.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the GetNodeInfo response as JSON",
+		},
+		cli.BoolFlag{
+			Name:  "include-channels",
+			Usage: "list the channels of the node",
+		},
+	},
+	Action: nodeLookup,
+}
+
+// validateAlias checks that the alias and color are accepted by LND
+func validateAlias(alias, color string) error {
+	if len(alias) > maxAliasLength {
+		return fmt.Errorf("alias must be at most %d bytes long, got %d", maxAliasLength, len(alias))
+	}
+	if !colorRegex.MatchString(color) {
+		return fmt.Errorf("invalid color %q, expected hex format i.e. '#3399FF'", color)
+	}
+	return nil
+}
+
+// setAlias is the action of the node alias set command
+func setAlias(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return cli.ShowCommandHelp(ctx, "set")
+	}
+	restart := func() error {
+		return callConduit(ctx, "conduit_lnd_restart", nil, nil)
+	}
+	return runSetAlias(path.Join(ctx.String("conduitdir"), configFileName), ctx.Args().Get(0), ctx.Args().Get(1), restart, os.Stdout)
+}
+
+// runSetAlias updates the alias and color in the config file and restarts LND
+func runSetAlias(filename, alias, color string, restart func() error, out io.Writer) error {
+	if err := validateAlias(alias, color); err != nil {
+		return err
+	}
+	previous, err := core.UpdateConfigFile(filename, map[string]interface{}{"LndAlias": alias, "LndColor": color})
+	if err != nil {
+		return fmt.Errorf("could not update config: %v", err)
+	}
+	fmt.Fprintf(out, "Alias: %q -> %q\n", previous["LndAlias"], alias)
+	fmt.Fprintf(out, "Color: %q -> %q\n", previous["LndColor"], color)
+	fmt.Fprintln(out, "Warning: LND must be restarted for the change to take effect")
+	if err = restart(); err != nil {
+		fmt.Fprintf(out, "Could not restart LND through Conduit (%v). Restart Conduit to apply the change\n", err)
+		return nil
+	}
+	fmt.Fprintln(out, "LND is restarting")
+	return nil
+}
+
+// nodeInfo is the action of the node info command
+func nodeInfo(ctx *cli.Context) error {
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runNodeInfo(context.Background(), client, ctx.Bool("json"), os.Stdout)
+}
+
+// runNodeInfo prints the details of the local node as a list or as the proto JSON of the GetInfo response
+func runNodeInfo(ctx context.Context, client lnrpc.LightningClient, asJSON bool, out io.Writer) error {
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		raw, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true, EmitUnpopulated: true}.Marshal(info)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(raw))
+		return nil
+	}
+	balance, err := client.WalletBalance(ctx, &lnrpc.WalletBalanceRequest{})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Alias:\t%s\n", info.Alias)
+	fmt.Fprintf(w, "Pubkey:\t%s\n", info.IdentityPubkey)
+	fmt.Fprintf(w, "Version:\t%s\n", info.Version)
+	fmt.Fprintf(w, "Synced to chain:\t%t\n", info.SyncedToChain)
+	fmt.Fprintf(w, "Synced to graph:\t%t\n", info.SyncedToGraph)
+	fmt.Fprintf(w, "Block height:\t%d\n", info.BlockHeight)
+	fmt.Fprintf(w, "Block hash:\t%s\n", info.BlockHash)
+	fmt.Fprintf(w, "Channels:\t%d active, %d inactive, %d pending\n", info.NumActiveChannels, info.NumInactiveChannels, info.NumPendingChannels)
+	fmt.Fprintf(w, "Peers:\t%d\n", info.NumPeers)
+	fmt.Fprintf(w, "On-chain balance:\t%d sats\n", balance.TotalBalance)
+	uris := "none"
+	if len(info.Uris) > 0 {
+		uris = strings.Join(info.Uris, "\n\t")
+	}
+	fmt.Fprintf(w, "URIs:\t%s\n", uris)
+	return w.Flush()
+}
+
+// nodeLookup is the action of the node lookup command
+func nodeLookup(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "lookup")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runNodeLookup(context.Background(), client, ctx.Args().First(), ctx.Bool("include-channels"), ctx.Bool("json"), os.Stdout)
+}
+
+// runNodeLookup prints the graph info of a node and, if includeChannels is set, its channels with the alias of their peer
+func runNodeLookup(ctx context.Context, client lnrpc.LightningClient, pubkey string, includeChannels, asJSON bool, out io.Writer) error {
+	info, err := client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: pubkey, IncludeChannels: includeChannels})
+	if err != nil {
+		return fmt.Errorf("could not find node %v: %v", pubkey, err)
+	}
+	if asJSON {
+		raw, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true, EmitUnpopulated: true}.Marshal(info)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(raw))
+		return nil
+	}
+	node := info.Node
+	if node == nil {
+		node = &lnrpc.LightningNode{PubKey: pubkey}
+	}
+	addresses := make([]string, 0, len(node.Addresses))
+	for _, addr := range node.Addresses {
+		addresses = append(addresses, addr.Addr)
+	}
+	if len(addresses) == 0 {
+		addresses = append(addresses, "none")
+	}
+	lastUpdate := "never"
+	if node.LastUpdate > 0 {
+		lastUpdate = time.Unix(int64(node.LastUpdate), 0).UTC().Format(time.RFC3339)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Alias:\t%s\n", node.Alias)
+	fmt.Fprintf(w, "Color:\t%s\n", node.Color)
+	fmt.Fprintf(w, "Pubkey:\t%s\n", pubkey)
+	fmt.Fprintf(w, "Total capacity:\t%d sats\n", info.TotalCapacity)
+	fmt.Fprintf(w, "Channels:\t%d\n", info.NumChannels)
+	fmt.Fprintf(w, "Addresses:\t%s\n", strings.Join(addresses, "\n\t"))
+	fmt.Fprintf(w, "Last update:\t%s\n", lastUpdate)
+	if err = w.Flush(); err != nil || !includeChannels {
+		return err
+	}
+	fmt.Fprintln(out)
+	aliases := map[string]string{pubkey: node.Alias}
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL ID\tCAPACITY (SATS)\tPEER PUBKEY\tPEER ALIAS")
+	for _, edge := range info.Channels {
+		peer := edge.Node1Pub
+		if peer == pubkey {
+			peer = edge.Node2Pub
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", edge.ChannelId, edge.Capacity, peer, nodeAlias(ctx, client, peer, aliases))
+	}
+	return w.Flush()
+}
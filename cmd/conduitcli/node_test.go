@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// TestSetAlias ensures the alias and color are written to the config file and that LND is restarted
+func TestSetAlias(t *testing.T) {
+	filename := path.Join(t.TempDir(), configFileName)
+	if err := ioutil.WriteFile(filename, []byte("ConsoleOutput: true\nlndalias: old-alias\n"), 0600); err != nil {
+		t.Fatalf("Error writing config fixture: %v", err)
+	}
+	restarts := 0
+	restart := func() error {
+		restarts++
+		return nil
+	}
+	var out bytes.Buffer
+	if err := runSetAlias(filename, "new-alias", "#3399FF", restart, &out); err != nil {
+		t.Fatalf("runSetAlias returned an error: %v", err)
+	}
+	if restarts != 1 {
+		t.Errorf("expected 1 restart, got %d", restarts)
+	}
+	raw, _ := ioutil.ReadFile(filename)
+	for _, want := range []string{"ConsoleOutput: true", "lndalias: new-alias", "lndcolor: '#3399FF'"} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("config is missing %q:\n%s", want, raw)
+		}
+	}
+	if !strings.Contains(out.String(), `"old-alias" -> "new-alias"`) {
+		t.Errorf("output does not show the old and new alias:\n%s", out.String())
+	}
+
+	// a failed restart still leaves the config updated
+	out.Reset()
+	failed := func() error { return fmt.Errorf("connection refused") }
+	if err := runSetAlias(filename, "newer-alias", "#000000", failed, &out); err != nil {
+		t.Fatalf("runSetAlias returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Restart Conduit") {
+		t.Errorf("output does not warn that a restart is required:\n%s", out.String())
+	}
+
+	for _, args := range [][2]string{{"alias", "3399FF"}, {"alias", "#3399FG"}, {strings.Repeat("a", 33), "#3399FF"}} {
+		if err := runSetAlias(filename, args[0], args[1], restart, &out); err == nil {
+			t.Errorf("runSetAlias accepted alias %q and color %q", args[0], args[1])
+		}
+	}
+}
+
+// testNodeInfoClient returns a fake client with the GetInfo response and wallet balance of a small node
+func testNodeInfoClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{
+			Alias:               "conduit-node",
+			IdentityPubkey:      "02abc",
+			Version:             "0.14.2-beta commit=v0.14.2-beta",
+			SyncedToChain:       true,
+			BlockHeight:         735000,
+			BlockHash:           "00000000000000000002",
+			NumActiveChannels:   3,
+			NumInactiveChannels: 1,
+			NumPendingChannels:  2,
+			NumPeers:            5,
+			Uris:                []string{"02abc@1.2.3.4:9735", "02abc@onion.onion:9735"},
+		},
+		wallet: &lnrpc.WalletBalanceResponse{TotalBalance: 150000, ConfirmedBalance: 100000, UnconfirmedBalance: 50000},
+	}
+}
+
+// TestNodeInfo ensures every detail of the node is printed
+func TestNodeInfo(t *testing.T) {
+	var out bytes.Buffer
+	if err := runNodeInfo(context.Background(), testNodeInfoClient(), false, &out); err != nil {
+		t.Fatalf("runNodeInfo returned an error: %v", err)
+	}
+	for _, want := range []string{
+		"conduit-node", "02abc", "0.14.2-beta", "735000", "00000000000000000002",
+		"3 active, 1 inactive, 2 pending", "150000 sats", "02abc@1.2.3.4:9735", "02abc@onion.onion:9735",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if !strings.Contains(strings.Join(strings.Fields(out.String()), " "), "Synced to graph: false") {
+		t.Errorf("unexpected graph sync status:\n%s", out.String())
+	}
+}
+
+// TestNodeInfoJSON ensures --json prints the proto JSON of the GetInfo response
+func TestNodeInfoJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := runNodeInfo(context.Background(), testNodeInfoClient(), true, &out); err != nil {
+		t.Fatalf("runNodeInfo returned an error: %v", err)
+	}
+	var info map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if info["alias"] != "conduit-node" || info["num_active_channels"] != float64(3) || info["synced_to_graph"] != false {
+		t.Errorf("unexpected JSON: %v", info)
+	}
+}
+
+// TestListenerDialAddr ensures LND listeners are turned into addresses which can be dialed
+func TestListenerDialAddr(t *testing.T) {
+	for listener, want := range map[string]string{
+		"localhost:10009":  "localhost:10009",
+		"0.0.0.0:10019":    "localhost:10019",
+		":10029":           "localhost:10029",
+		"[::]:10039":       "localhost:10039",
+		"192.168.1.5":      "192.168.1.5:10009",
+		"192.168.1.5:1234": "192.168.1.5:1234",
+	} {
+		if got := listenerDialAddr(listener); got != want {
+			t.Errorf("listenerDialAddr(%q) = %q, want %q", listener, got, want)
+		}
+	}
+}
+
+// newLookupClient returns a fake client knowing the node 03dddd with 3 channels, to two peers with an alias and one without
+func newLookupClient() *fakeLightningClient {
+	return &fakeLightningClient{nodes: map[string]*lnrpc.NodeInfo{
+		"03dddd": {
+			Node: &lnrpc.LightningNode{
+				PubKey:     "03dddd",
+				Alias:      "dave",
+				Color:      "#3399ff",
+				LastUpdate: 1651406400,
+				Addresses:  []*lnrpc.NodeAddress{{Network: "tcp", Addr: "1.2.3.4:9735"}, {Network: "tcp", Addr: "dave.onion:9735"}},
+			},
+			NumChannels:   3,
+			TotalCapacity: 6000000,
+			Channels: []*lnrpc.ChannelEdge{
+				{ChannelId: 101, Capacity: 1000000, Node1Pub: "02aaaa", Node2Pub: "03dddd"},
+				{ChannelId: 102, Capacity: 2000000, Node1Pub: "03dddd", Node2Pub: "03bbbb"},
+				{ChannelId: 103, Capacity: 3000000, Node1Pub: "03dddd", Node2Pub: "03eeee"},
+			},
+		},
+		"02aaaa": {Node: &lnrpc.LightningNode{Alias: "alice"}},
+		"03bbbb": {Node: &lnrpc.LightningNode{Alias: "bob"}},
+	}}
+}
+
+// TestNodeLookup ensures the node is printed with its channels and the alias of their peers only with --include-channels
+func TestNodeLookup(t *testing.T) {
+	client := newLookupClient()
+	var out bytes.Buffer
+	if err := runNodeLookup(context.Background(), client, "03dddd", false, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"dave", "#3399ff", "6000000 sats", "Channels:        3", "1.2.3.4:9735", "dave.onion:9735", "2022-05-01T12:00:00Z"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "PEER ALIAS") || client.nodeReqs[0].IncludeChannels {
+		t.Errorf("expected the channels to be left out:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runNodeLookup(context.Background(), client, "03dddd", true, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"101         1000000          02aaaa       alice", "102         2000000          03bbbb       bob", "103         3000000          03eeee"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if !client.nodeReqs[1].IncludeChannels {
+		t.Error("expected the channels to be requested")
+	}
+
+	if err := runNodeLookup(context.Background(), client, "03ffff", false, false, &out); err == nil {
+		t.Error("expected an error for an unknown node")
+	}
+}
+
+// TestNodeLookupJSON ensures --json prints the proto JSON of the GetNodeInfo response
+func TestNodeLookupJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := runNodeLookup(context.Background(), newLookupClient(), "03dddd", true, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		Node struct {
+			Alias string `json:"alias"`
+		} `json:"node"`
+		NumChannels int           `json:"num_channels"`
+		Channels    []interface{} `json:"channels"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if info.Node.Alias != "dave" || info.NumChannels != 3 || len(info.Channels) != 3 {
+		t.Errorf("unexpected JSON: %s", out.String())
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var onchainCommand = cli.Command{
+	Name:  "onchain",
+	Usage: "Manage on-chain funds",
+	Subcommands: []cli.Command{
+		onchainSendCommand,
+	},
+}
+
+var onchainSendCommand = cli.Command{
+	Name:  "send",
+	Usage: "Send an on-chain payment",
+	Description: `
+	Sends --amount sats to the --address Bitcoin address from the LND wallet and
+	prints the txid. The fee rate is estimated to confirm within --target-confs
+	blocks. With --dry-run, the fee is estimated and printed but nothing is sent.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "address",
+			Usage: "the Bitcoin address to send to",
+		},
+		cli.Int64Flag{
+			Name:  "amount",
+			Usage: "the amount to send in sats",
+		},
+		cli.IntFlag{
+			Name:  "target-confs",
+			Usage: "the number of blocks in which the transaction should confirm. Defaults to LND's default",
+		},
+		cli.IntFlag{
+			Name:  "min-confs",
+			Usage: "the minimum number of confirmations of the spent outputs, 0 to spend unconfirmed outputs",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only estimate and print the fee",
+		},
+	},
+	Action: onchainSend,
+}
+
+// onchainSendOptions are the options of the onchain send command
+type onchainSendOptions struct {
+	address     string
+	network     string
+	amountSat   int64
+	targetConfs int32
+	minConfs    int32
+	dryRun      bool
+}
+
+// onchainSend is the action of the onchain send command
+func onchainSend(ctx *cli.Context) error {
+	if !ctx.IsSet("address") || !ctx.IsSet("amount") {
+		return cli.ShowCommandHelp(ctx, "send")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	opts := &onchainSendOptions{
+		address:     ctx.String("address"),
+		network:     ctx.GlobalString("network"),
+		amountSat:   ctx.Int64("amount"),
+		targetConfs: int32(ctx.Int("target-confs")),
+		minConfs:    int32(ctx.Int("min-confs")),
+		dryRun:      ctx.Bool("dry-run"),
+	}
+	return runOnchainSend(context.Background(), client, opts, os.Stdout)
+}
+
+// runOnchainSend validates the options, then sends the coins and prints the txid or, on a dry run, prints the estimated fee
+func runOnchainSend(ctx context.Context, client lnrpc.LightningClient, opts *onchainSendOptions, out io.Writer) error {
+	if err := utils.ValidateBitcoinAddress(opts.address, opts.network); err != nil {
+		return err
+	}
+	if opts.amountSat <= 0 {
+		return fmt.Errorf("--amount must be positive")
+	}
+	if opts.targetConfs < 0 || opts.minConfs < 0 {
+		return fmt.Errorf("--target-confs and --min-confs can't be negative")
+	}
+	if opts.dryRun {
+		fee, err := client.EstimateFee(ctx, &lnrpc.EstimateFeeRequest{
+			AddrToAmount:     map[string]int64{opts.address: opts.amountSat},
+			TargetConf:       opts.targetConfs,
+			MinConfs:         opts.minConfs,
+			SpendUnconfirmed: opts.minConfs == 0,
+		})
+		if err != nil {
+			return fmt.Errorf("could not estimate fee: %v", err)
+		}
+		fmt.Fprintf(out, "Estimated fee: %d sats (%d sat/vbyte)\n", fee.FeeSat, fee.SatPerVbyte)
+		fmt.Fprintln(out, "Dry run, nothing was sent")
+		return nil
+	}
+	resp, err := client.SendCoins(ctx, &lnrpc.SendCoinsRequest{
+		Addr:             opts.address,
+		Amount:           opts.amountSat,
+		TargetConf:       opts.targetConfs,
+		MinConfs:         opts.minConfs,
+		SpendUnconfirmed: opts.minConfs == 0,
+	})
+	if err != nil {
+		return fmt.Errorf("could not send coins: %v", err)
+	}
+	fmt.Fprintf(out, "Txid: %s\n", resp.Txid)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+const onchainAddress = "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"
+
+// TestOnchainSend ensures the coins are sent with the requested confirmations and the txid is printed
+func TestOnchainSend(t *testing.T) {
+	client := &fakeLightningClient{}
+	var out bytes.Buffer
+	opts := &onchainSendOptions{address: onchainAddress, network: "mainnet", amountSat: 50000, targetConfs: 6, minConfs: 1}
+	if err := runOnchainSend(context.Background(), client, opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sendReqs) != 1 || len(client.feeReqs) != 0 {
+		t.Fatalf("expected a single SendCoins call, got %d and %d EstimateFee calls", len(client.sendReqs), len(client.feeReqs))
+	}
+	req := client.sendReqs[0]
+	if req.Addr != onchainAddress || req.Amount != 50000 || req.TargetConf != 6 || req.MinConfs != 1 || req.SpendUnconfirmed {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if want := "Txid: " + strings.Repeat("0", 63) + "1\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	opts.minConfs = 0
+	if err := runOnchainSend(context.Background(), client, opts, &out); err != nil || !client.sendReqs[1].SpendUnconfirmed {
+		t.Errorf("expected unconfirmed outputs to be spent with --min-confs 0 (%v)", err)
+	}
+}
+
+// TestOnchainSendDryRun ensures a dry run only estimates the fee
+func TestOnchainSendDryRun(t *testing.T) {
+	client := &fakeLightningClient{fee: &lnrpc.EstimateFeeResponse{FeeSat: 1410, SatPerVbyte: 10}}
+	var out bytes.Buffer
+	opts := &onchainSendOptions{address: onchainAddress, network: "mainnet", amountSat: 50000, targetConfs: 3, minConfs: 1, dryRun: true}
+	if err := runOnchainSend(context.Background(), client, opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sendReqs) != 0 || len(client.feeReqs) != 1 {
+		t.Fatalf("expected a single EstimateFee call, got %d and %d SendCoins calls", len(client.feeReqs), len(client.sendReqs))
+	}
+	if req := client.feeReqs[0]; req.AddrToAmount[onchainAddress] != 50000 || req.TargetConf != 3 || req.MinConfs != 1 {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if !strings.Contains(out.String(), "Estimated fee: 1410 sats (10 sat/vbyte)") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+// TestOnchainSendInvalid ensures invalid options are rejected before calling LND
+func TestOnchainSendInvalid(t *testing.T) {
+	client := &fakeLightningClient{}
+	invalid := []*onchainSendOptions{
+		{address: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", network: "mainnet", amountSat: 1000, minConfs: 1},
+		{address: "not-an-address", network: "mainnet", amountSat: 1000, minConfs: 1},
+		{address: onchainAddress, network: "mainnet", amountSat: 0, minConfs: 1},
+		{address: onchainAddress, network: "mainnet", amountSat: 1000, minConfs: -1},
+	}
+	for _, opts := range invalid {
+		if err := runOnchainSend(context.Background(), client, opts, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected %+v to be rejected", opts)
+		}
+	}
+	if len(client.sendReqs) != 0 || len(client.feeReqs) != 0 {
+		t.Error("expected LND not to be called")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const defaultPathfindRoutes = 1
+
+var routingPathfindCommand = cli.Command{
+	Name:  "pathfind",
+	Usage: "Find routes between two nodes without paying",
+	Description: `
+	Asks LND for up to --num-routes routes able to carry --amount msat from
+	--source, the local node unless set, to --dest and prints their hops with
+	the alias of every node, total fees, total time lock and the probability
+	of success estimated from mission control. Each route after the first
+	avoids the first hop of the previous routes. With --json, the QueryRoutes
+	responses are printed as proto JSON, one per line.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "source",
+			Usage: "the pubkey of the node the routes start from. Defaults to the local node",
+		},
+		cli.StringFlag{
+			Name:  "dest",
+			Usage: "the pubkey of the destination node",
+		},
+		cli.Int64Flag{
+			Name:  "amount",
+			Usage: "the amount to route in msat",
+		},
+		cli.IntFlag{
+			Name:  "num-routes",
+			Usage: "the maximum number of routes to find",
+			Value: defaultPathfindRoutes,
+		},
+		cli.Int64Flag{
+			Name:  "fee-limit",
+			Usage: "the maximum total fees of a route in msat",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the QueryRoutes responses as JSON",
+		},
+	},
+	Action: routingPathfind,
+}
+
+// pathfindOptions are the options of the routing pathfind command
+type pathfindOptions struct {
+	source, dest string
+	amountMsat   int64
+	numRoutes    int
+	feeLimitMsat int64
+	asJSON       bool
+}
+
+// routingPathfind is the action of the routing pathfind command
+func routingPathfind(ctx *cli.Context) error {
+	if !ctx.IsSet("dest") || !ctx.IsSet("amount") {
+		return cli.ShowCommandHelp(ctx, "pathfind")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	opts := &pathfindOptions{
+		source:       ctx.String("source"),
+		dest:         ctx.String("dest"),
+		amountMsat:   ctx.Int64("amount"),
+		numRoutes:    ctx.Int("num-routes"),
+		feeLimitMsat: ctx.Int64("fee-limit"),
+		asJSON:       ctx.Bool("json"),
+	}
+	return runRoutingPathfind(context.Background(), client, opts, os.Stdout)
+}
+
+// runRoutingPathfind queries the routes one at a time, ignoring the first hop of the routes already found, and prints them
+func runRoutingPathfind(ctx context.Context, client lnrpc.LightningClient, opts *pathfindOptions, out io.Writer) error {
+	if opts.amountMsat <= 0 {
+		return fmt.Errorf("--amount must be positive")
+	}
+	if opts.numRoutes <= 0 {
+		return fmt.Errorf("--num-routes must be positive")
+	}
+	source := opts.source
+	if source == "" {
+		info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+		if err != nil {
+			return err
+		}
+		source = info.IdentityPubkey
+	}
+	from, err := hex.DecodeString(source)
+	if err != nil {
+		return fmt.Errorf("invalid source pubkey %v: %v", source, err)
+	}
+	req := &lnrpc.QueryRoutesRequest{
+		PubKey:            opts.dest,
+		AmtMsat:           opts.amountMsat,
+		UseMissionControl: true,
+	}
+	// LND routes from the local node when the source is empty
+	if opts.source != "" {
+		req.SourcePubKey = opts.source
+	}
+	if opts.feeLimitMsat > 0 {
+		req.FeeLimit = &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_FixedMsat{FixedMsat: opts.feeLimitMsat}}
+	}
+	aliases := make(map[string]string)
+	found := 0
+	for found < opts.numRoutes {
+		resp, err := client.QueryRoutes(ctx, req)
+		if err != nil || len(resp.Routes) == 0 {
+			if found == 0 {
+				return fmt.Errorf("no route from %v to %v for %d msat: %v", source, opts.dest, opts.amountMsat, err)
+			}
+			break
+		}
+		found++
+		route := resp.Routes[0]
+		if opts.asJSON {
+			raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(raw))
+		} else if err = printRoute(ctx, client, found, source, route, resp.SuccessProb, aliases, out); err != nil {
+			return err
+		}
+		if len(route.Hops) == 0 {
+			break
+		}
+		next, err := hex.DecodeString(route.Hops[0].PubKey)
+		if err != nil {
+			return fmt.Errorf("invalid hop pubkey %v: %v", route.Hops[0].PubKey, err)
+		}
+		req.IgnoredPairs = append(req.IgnoredPairs, &lnrpc.NodePair{From: from, To: next})
+	}
+	if found < opts.numRoutes && !opts.asJSON {
+		fmt.Fprintf(out, "Found %d of the %d routes requested\n", found, opts.numRoutes)
+	}
+	return nil
+}
+
+// nodeAlias returns the alias of the node, fetched from the graph once, or an empty string if it's unknown
+func nodeAlias(ctx context.Context, client lnrpc.LightningClient, pubkey string, aliases map[string]string) string {
+	alias, ok := aliases[pubkey]
+	if !ok {
+		if info, err := client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: pubkey}); err == nil && info.Node != nil {
+			alias = info.Node.Alias
+		}
+		aliases[pubkey] = alias
+	}
+	return alias
+}
+
+// printRoute prints the summary and the hops of a route
+func printRoute(ctx context.Context, client lnrpc.LightningClient, n int, source string, route *lnrpc.Route, successProb float64, aliases map[string]string, out io.Writer) error {
+	fmt.Fprintf(out, "Route %d: %d hops, fees %d msat, time lock %d, success probability %.2f%%\n", n, len(route.Hops), route.TotalFeesMsat, route.TotalTimeLock, successProb*100)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "HOP\tCHANNEL\tPUBKEY\tALIAS\tAMOUNT (MSAT)\tFEE (MSAT)\tEXPIRY")
+	fmt.Fprintf(w, "0\t-\t%s\t%s\t%d\t-\t-\n", source, nodeAlias(ctx, client, source, aliases), route.TotalAmtMsat)
+	for i, hop := range route.Hops {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%d\t%d\n", i+1, hop.ChanId, hop.PubKey, nodeAlias(ctx, client, hop.PubKey, aliases), hop.AmtToForwardMsat, hop.FeeMsat, hop.Expiry)
+	}
+	return w.Flush()
+}
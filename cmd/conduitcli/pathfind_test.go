@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newPathfindClient returns a fake client with two routes from the local node 02aaaa to 03dddd, through 03bbbb and 03cccc
+func newPathfindClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{IdentityPubkey: testPubkey},
+		routes: []*lnrpc.Route{
+			{TotalTimeLock: 700200, TotalFeesMsat: 1500, TotalAmtMsat: 101500, Hops: []*lnrpc.Hop{
+				{ChanId: 11, PubKey: "03bbbb", AmtToForwardMsat: 100000, FeeMsat: 1500, Expiry: 700040},
+				{ChanId: 12, PubKey: "03dddd", AmtToForwardMsat: 100000, Expiry: 700040},
+			}},
+			{TotalTimeLock: 700300, TotalFeesMsat: 2500, TotalAmtMsat: 102500, Hops: []*lnrpc.Hop{
+				{ChanId: 21, PubKey: "03cccc", AmtToForwardMsat: 100000, FeeMsat: 2500, Expiry: 700040},
+				{ChanId: 22, PubKey: "03dddd", AmtToForwardMsat: 100000, Expiry: 700040},
+			}},
+		},
+		routeProb: 0.875,
+		nodes: map[string]*lnrpc.NodeInfo{
+			testPubkey: {Node: &lnrpc.LightningNode{Alias: "conduit"}},
+			"03bbbb":   {Node: &lnrpc.LightningNode{Alias: "bob"}},
+			"03dddd":   {Node: &lnrpc.LightningNode{Alias: "dave"}},
+		},
+	}
+}
+
+// TestRoutingPathfind ensures every route is printed with the aliases of its hops and that later routes avoid the first hops of earlier ones
+func TestRoutingPathfind(t *testing.T) {
+	client := newPathfindClient()
+	var out bytes.Buffer
+	opts := &pathfindOptions{dest: "03dddd", amountMsat: 100000, numRoutes: 3, feeLimitMsat: 5000}
+	if err := runRoutingPathfind(context.Background(), client, opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Route 1: 2 hops, fees 1500 msat, time lock 700200, success probability 87.50%",
+		"Route 2: 2 hops, fees 2500 msat, time lock 700300",
+		"0    -        02aaaa  conduit",
+		"1    11       03bbbb  bob",
+		"2    12       03dddd  dave",
+		"1    21       03cccc           100000",
+		"Found 2 of the 3 routes requested",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if len(client.routeReqs) != 3 {
+		t.Fatalf("expected 3 queries, got %d", len(client.routeReqs))
+	}
+	first := client.routeReqs[0]
+	if first.PubKey != "03dddd" || first.AmtMsat != 100000 || first.SourcePubKey != "" || first.FeeLimit.GetFixedMsat() != 5000 || !first.UseMissionControl {
+		t.Errorf("unexpected query: %v", first)
+	}
+	ignored := client.routeReqs[2].IgnoredPairs
+	if len(ignored) != 2 || hex.EncodeToString(ignored[0].From) != testPubkey || hex.EncodeToString(ignored[0].To) != "03bbbb" || hex.EncodeToString(ignored[1].To) != "03cccc" {
+		t.Errorf("unexpected ignored pairs: %v", ignored)
+	}
+}
+
+// TestRoutingPathfindJSON ensures --json prints the QueryRoutes responses from the given source
+func TestRoutingPathfindJSON(t *testing.T) {
+	client := newPathfindClient()
+	var out bytes.Buffer
+	opts := &pathfindOptions{source: "03eeee", dest: "03dddd", amountMsat: 100000, numRoutes: 1, asJSON: true}
+	if err := runRoutingPathfind(context.Background(), client, opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Routes []struct {
+			TotalFeesMsat string `json:"total_fees_msat"`
+		} `json:"routes"`
+		SuccessProb float64 `json:"success_prob"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if len(resp.Routes) != 1 || resp.Routes[0].TotalFeesMsat != "1500" || resp.SuccessProb != 0.875 {
+		t.Errorf("unexpected response: %s", out.String())
+	}
+	if len(client.routeReqs) != 1 || client.routeReqs[0].SourcePubKey != "03eeee" {
+		t.Errorf("unexpected queries: %v", client.routeReqs)
+	}
+
+	client.routes = nil
+	if err := runRoutingPathfind(context.Background(), client, opts, &out); err == nil {
+		t.Error("expected an error when no route is found")
+	}
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/urfave/cli"
+)
+
+const (
+	topFailureReasons = 3
+	// keysendMessageRecord is the custom record carrying the text message of a keysend payment, as used by most wallets
+	keysendMessageRecord uint64 = 34349334
+	// defaultFeeLimitPercent is the share of the amount paid in fees when --fee-limit isn't given, the same as lncli
+	defaultFeeLimitPercent   = 5
+	defaultPaymentLimit      = 100
+	paymentHashDisplayLength = 16
+)
+
+var paymentCommand = cli.Command{
+	Name:  "payment",
+	Usage: "Inspect and make payments",
+	Subcommands: []cli.Command{
+		paymentStatsCommand,
+		paymentSendCommand,
+		paymentListCommand,
+	},
+}
+
+var paymentStatsCommand = cli.Command{
+	Name:  "stats",
+	Usage: "Show success-rate analytics of outgoing payments",
+	Description: `
+	Reads the payment outcomes recorded by Conduit in payments.csv and prints the
+	number of payments, total volume, average fee, success rate and the most
+	common failure reasons over the given period.`,
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "period",
+			Usage: "only include payments completed during this period before now",
+			Value: 24 * time.Hour,
+		},
+		conduitDirFlag,
+	},
+	Action: paymentStats,
+}
+
+// paymentStats is the action of the payment stats command
+func paymentStats(ctx *cli.Context) error {
+	events, err := core.ReadPaymentEvents(core.PaymentEventsPath(&core.Config{ConduitDir: ctx.String("conduitdir")}), time.Now().Add(-ctx.Duration("period")))
+	if err != nil {
+		return fmt.Errorf("could not read payment events: %v", err)
+	}
+	printPaymentStats(events, os.Stdout)
+	return nil
+}
+
+// printPaymentStats summarizes the completed payments among the events
+func printPaymentStats(events []*core.PaymentEvent, out io.Writer) {
+	var count, succeeded int
+	var volumeMsat, feeMsat int64
+	failures := make(map[string]int)
+	for _, e := range events {
+		switch e.Status {
+		case "SUCCEEDED":
+			succeeded++
+			volumeMsat += e.AmountMsat
+			feeMsat += e.FeeMsat
+		case "FAILED":
+			failures[e.FailureReason]++
+		default:
+			// payments still in flight have no outcome yet
+			continue
+		}
+		count++
+	}
+	fmt.Fprintf(out, "Payments:       %d\n", count)
+	if count == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Total volume:   %d sats\n", volumeMsat/1000)
+	if succeeded > 0 {
+		fmt.Fprintf(out, "Average fee:    %d msat\n", feeMsat/int64(succeeded))
+	}
+	fmt.Fprintf(out, "Success rate:   %.1f%%\n", 100*float64(succeeded)/float64(count))
+	if len(failures) == 0 {
+		return
+	}
+	reasons := make([]string, 0, len(failures))
+	for reason := range failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if failures[reasons[i]] != failures[reasons[j]] {
+			return failures[reasons[i]] > failures[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	if len(reasons) > topFailureReasons {
+		reasons = reasons[:topFailureReasons]
+	}
+	fmt.Fprintln(out, "Top failure reasons:")
+	for _, reason := range reasons {
+		fmt.Fprintf(out, "  %s: %d\n", reason, failures[reason])
+	}
+}
+
+var paymentSendCommand = cli.Command{
+	Name:  "send",
+	Usage: "Send a payment directly to a node without an invoice",
+	Description: `
+	Sends --amount msat to the --dest node and waits until the payment succeeds
+	or fails. With --keysend, a random preimage is generated and sent to the
+	destination along with the payment, otherwise the hash of a preimage known
+	to the destination must be given with --payment-hash. The memo is sent as a
+	keysend message.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "dest",
+			Usage: "the hex encoded public key of the destination node",
+		},
+		cli.Int64Flag{
+			Name:  "amount",
+			Usage: "the amount to send in msat",
+		},
+		cli.StringFlag{
+			Name:  "memo",
+			Usage: "a message sent to the destination along with a keysend payment",
+		},
+		cli.BoolFlag{
+			Name:  "keysend",
+			Usage: "send the preimage to the destination so that no invoice is needed",
+		},
+		cli.StringFlag{
+			Name:  "payment-hash",
+			Usage: "the hex encoded payment hash, required without --keysend",
+		},
+		cli.Int64Flag{
+			Name:  "fee-limit",
+			Usage: "the maximum fee to pay in msat. Defaults to 5% of the amount",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "the time after which LND stops trying to find a route",
+			Value: 60 * time.Second,
+		},
+	},
+	Action: paymentSend,
+}
+
+// sendOptions are the options of the payment send command
+type sendOptions struct {
+	dest         string
+	amountMsat   int64
+	memo         string
+	keysend      bool
+	paymentHash  string
+	feeLimitMsat int64
+	timeout      time.Duration
+}
+
+// paymentSend is the action of the payment send command
+func paymentSend(ctx *cli.Context) error {
+	if !ctx.IsSet("dest") || !ctx.IsSet("amount") {
+		return cli.ShowCommandHelp(ctx, "send")
+	}
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	opts := &sendOptions{
+		dest:         ctx.String("dest"),
+		amountMsat:   ctx.Int64("amount"),
+		memo:         ctx.String("memo"),
+		keysend:      ctx.Bool("keysend"),
+		paymentHash:  ctx.String("payment-hash"),
+		feeLimitMsat: ctx.Int64("fee-limit"),
+		timeout:      ctx.Duration("timeout"),
+	}
+	return runPaymentSend(context.Background(), routerrpc.NewRouterClient(conn), opts, os.Stdout)
+}
+
+// newSendPaymentRequest builds the SendPaymentV2 request of the options
+func newSendPaymentRequest(opts *sendOptions) (*routerrpc.SendPaymentRequest, error) {
+	if opts.amountMsat <= 0 {
+		return nil, fmt.Errorf("--amount must be positive")
+	}
+	if opts.timeout < time.Second {
+		return nil, fmt.Errorf("--timeout must be at least one second")
+	}
+	dest, err := parsePubkey(opts.dest)
+	if err != nil {
+		return nil, err
+	}
+	feeLimit := opts.feeLimitMsat
+	if feeLimit == 0 {
+		feeLimit = opts.amountMsat * defaultFeeLimitPercent / 100
+	}
+	req := &routerrpc.SendPaymentRequest{
+		Dest:              dest,
+		AmtMsat:           opts.amountMsat,
+		FeeLimitMsat:      feeLimit,
+		TimeoutSeconds:    int32(opts.timeout / time.Second),
+		AllowSelfPayment:  false,
+		DestCustomRecords: make(map[uint64][]byte),
+	}
+	switch {
+	case opts.keysend && opts.paymentHash != "":
+		return nil, fmt.Errorf("--keysend and --payment-hash can't be used together")
+	case opts.keysend:
+		preimage := make([]byte, 32)
+		if _, err = rand.Read(preimage); err != nil {
+			return nil, fmt.Errorf("could not generate preimage: %v", err)
+		}
+		hash := sha256.Sum256(preimage)
+		req.PaymentHash = hash[:]
+		req.DestCustomRecords[record.KeySendType] = preimage
+		// the preimage can only be sent in a TLV onion
+		req.DestFeatures = []lnrpc.FeatureBit{lnrpc.FeatureBit_TLV_ONION_OPT}
+	case opts.paymentHash != "":
+		req.PaymentHash, err = hex.DecodeString(opts.paymentHash)
+		if err != nil || len(req.PaymentHash) != sha256.Size {
+			return nil, fmt.Errorf("invalid payment hash %v", opts.paymentHash)
+		}
+	default:
+		return nil, fmt.Errorf("--payment-hash is required without --keysend")
+	}
+	if opts.memo != "" {
+		req.DestCustomRecords[keysendMessageRecord] = []byte(opts.memo)
+	}
+	return req, nil
+}
+
+// runPaymentSend sends the payment and prints its outcome once it succeeded or failed
+func runPaymentSend(ctx context.Context, router routerrpc.RouterClient, opts *sendOptions, out io.Writer) error {
+	req, err := newSendPaymentRequest(opts)
+	if err != nil {
+		return err
+	}
+	stream, err := router.SendPaymentV2(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not send payment: %v", err)
+	}
+	for {
+		payment, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("payment %x: %v", req.PaymentHash, err)
+		}
+		switch payment.Status {
+		case lnrpc.Payment_SUCCEEDED:
+			printPayment(payment, out)
+			return nil
+		case lnrpc.Payment_FAILED:
+			fmt.Fprintf(out, "Payment hash:   %s\n", payment.PaymentHash)
+			fmt.Fprintf(out, "Status:         FAILED\n")
+			fmt.Fprintf(out, "Failure reason: %v\n", payment.FailureReason)
+			return fmt.Errorf("payment failed: %v", payment.FailureReason)
+		}
+	}
+}
+
+// printPayment prints the hash, fee and route of a succeeded payment
+func printPayment(payment *lnrpc.Payment, out io.Writer) {
+	fmt.Fprintf(out, "Payment hash:   %s\n", payment.PaymentHash)
+	fmt.Fprintf(out, "Preimage:       %s\n", payment.PaymentPreimage)
+	fmt.Fprintf(out, "Status:         SUCCEEDED\n")
+	fmt.Fprintf(out, "Fee paid:       %d msat\n", payment.FeeMsat)
+	for _, htlc := range payment.Htlcs {
+		if htlc.Status != lnrpc.HTLCAttempt_SUCCEEDED || htlc.Route == nil {
+			continue
+		}
+		hops := make([]string, len(htlc.Route.Hops))
+		for i, hop := range htlc.Route.Hops {
+			hops[i] = hop.PubKey
+		}
+		fmt.Fprintf(out, "Route:          %s\n", strings.Join(hops, " -> "))
+	}
+}
+
+var paymentListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List outgoing payments, newest first, with their failure reasons",
+	Description: `
+	Lists up to --limit payments made by LND matching the filters, newest first.
+	The status flags can be combined, all payments are listed when none is set.
+	--since accepts a timestamp (2006-01-02T15:04:05Z07:00), a date (2006-01-02)
+	or a duration relative to now (i.e. 72h). With --json, one payment is printed
+	per line as soon as it's fetched. With --analyze-failures, the failed payments
+	are counted by failure reason instead of being listed, whatever the status
+	flags.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "failed",
+			Usage: "list the failed payments",
+		},
+		cli.BoolFlag{
+			Name:  "succeeded",
+			Usage: "list the succeeded payments",
+		},
+		cli.BoolFlag{
+			Name:  "in-flight",
+			Usage: "list the payments still in flight",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "only list payments created at or after this time",
+		},
+		cli.BoolFlag{
+			Name:  "analyze-failures",
+			Usage: "count the failed payments by failure reason",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the payments as newline delimited JSON",
+		},
+		cli.Uint64Flag{
+			Name:  "limit",
+			Usage: "the maximum number of payments to list",
+			Value: defaultPaymentLimit,
+		},
+	},
+	Action: paymentList,
+}
+
+// paymentFilter are the filters of the payment list command
+type paymentFilter struct {
+	failed, succeeded, inFlight bool
+	since                       time.Time
+	limit                       uint64
+}
+
+// match reports whether the payment passes the filter
+func (f *paymentFilter) match(payment *lnrpc.Payment) bool {
+	if !f.failed && !f.succeeded && !f.inFlight {
+		return true
+	}
+	switch payment.Status {
+	case lnrpc.Payment_FAILED:
+		return f.failed
+	case lnrpc.Payment_SUCCEEDED:
+		return f.succeeded
+	case lnrpc.Payment_IN_FLIGHT:
+		return f.inFlight
+	}
+	return false
+}
+
+// paymentRow is a payment as printed by the payment list command
+type paymentRow struct {
+	PaymentHash   string `json:"payment_hash"`
+	AmountMsat    int64  `json:"amount_msat"`
+	FeeMsat       int64  `json:"fee_msat"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	CreatedAt     int64  `json:"creation_time_ns"`
+}
+
+// failureCount is the number of failed payments with a failure reason
+type failureCount struct {
+	FailureReason string `json:"failure_reason"`
+	Count         int    `json:"count"`
+}
+
+// paymentList is the action of the payment list command
+func paymentList(ctx *cli.Context) error {
+	since, err := parseSince(ctx.String("since"), time.Now())
+	if err != nil {
+		return err
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	filter := &paymentFilter{
+		failed:    ctx.Bool("failed"),
+		succeeded: ctx.Bool("succeeded"),
+		inFlight:  ctx.Bool("in-flight"),
+		since:     since,
+		limit:     ctx.Uint64("limit"),
+	}
+	if ctx.Bool("analyze-failures") {
+		return runPaymentFailureAnalysis(context.Background(), client, filter, ctx.Bool("json"), os.Stdout)
+	}
+	return runPaymentList(context.Background(), client, filter, ctx.Bool("json"), os.Stdout)
+}
+
+// listPayments pages through the payments from the newest, calling f with those passing the filter until the limit is reached or the
+// payments are older than --since
+func listPayments(ctx context.Context, client lnrpc.LightningClient, filter *paymentFilter, f func(payment *lnrpc.Payment) error) error {
+	if filter.limit == 0 {
+		return fmt.Errorf("--limit must be positive")
+	}
+	// LND only returns the failed and in flight payments along with the succeeded ones
+	includeIncomplete := filter.failed || filter.inFlight || !filter.succeeded
+	var listed, offset uint64
+	for listed < filter.limit {
+		resp, err := client.ListPayments(ctx, &lnrpc.ListPaymentsRequest{
+			IncludeIncomplete: includeIncomplete,
+			IndexOffset:       offset,
+			MaxPayments:       filter.limit,
+			Reversed:          true,
+		})
+		if err != nil {
+			return err
+		}
+		if len(resp.Payments) == 0 {
+			break
+		}
+		// the payments of a page are in chronological order
+		for i := len(resp.Payments) - 1; i >= 0 && listed < filter.limit; i-- {
+			payment := resp.Payments[i]
+			if time.Unix(0, payment.CreationTimeNs).Before(filter.since) {
+				return nil
+			}
+			if !filter.match(payment) {
+				continue
+			}
+			listed++
+			if err = f(payment); err != nil {
+				return err
+			}
+		}
+		if resp.FirstIndexOffset <= 1 {
+			break
+		}
+		offset = resp.FirstIndexOffset
+	}
+	return nil
+}
+
+// runPaymentList prints the payments passing the filter, newest first
+func runPaymentList(ctx context.Context, client lnrpc.LightningClient, filter *paymentFilter, asJSON bool, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if !asJSON {
+		fmt.Fprintln(w, "PAYMENT HASH\tAMOUNT (MSAT)\tFEE (MSAT)\tSTATUS\tFAILURE REASON\tCREATED AT")
+	}
+	err := listPayments(ctx, client, filter, func(payment *lnrpc.Payment) error {
+		row := &paymentRow{
+			PaymentHash: payment.PaymentHash,
+			AmountMsat:  payment.ValueMsat,
+			FeeMsat:     payment.FeeMsat,
+			Status:      payment.Status.String(),
+			CreatedAt:   payment.CreationTimeNs,
+		}
+		if payment.Status == lnrpc.Payment_FAILED {
+			row.FailureReason = payment.FailureReason.String()
+		}
+		if asJSON {
+			return enc.Encode(row)
+		}
+		hash := row.PaymentHash
+		if len(hash) > paymentHashDisplayLength {
+			hash = hash[:paymentHashDisplayLength] + "..."
+		}
+		reason := row.FailureReason
+		if reason == "" {
+			reason = "-"
+		}
+		created := time.Unix(0, row.CreatedAt).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", hash, row.AmountMsat, row.FeeMsat, row.Status, reason, created)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return nil
+	}
+	return w.Flush()
+}
+
+// runPaymentFailureAnalysis counts the failed payments passing the filter by failure reason and prints the reasons, most common first
+func runPaymentFailureAnalysis(ctx context.Context, client lnrpc.LightningClient, filter *paymentFilter, asJSON bool, out io.Writer) error {
+	failedOnly := *filter
+	failedOnly.failed, failedOnly.succeeded, failedOnly.inFlight = true, false, false
+	counts := make(map[string]int)
+	total := 0
+	err := listPayments(ctx, client, &failedOnly, func(payment *lnrpc.Payment) error {
+		counts[payment.FailureReason.String()]++
+		total++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	failures := make([]failureCount, 0, len(counts))
+	for reason, count := range counts {
+		failures = append(failures, failureCount{FailureReason: reason, Count: count})
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Count != failures[j].Count {
+			return failures[i].Count > failures[j].Count
+		}
+		return failures[i].FailureReason < failures[j].FailureReason
+	})
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(failures)
+	}
+	fmt.Fprintf(out, "Failed payments: %d\n", total)
+	if total == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FAILURE REASON\tCOUNT\tSHARE")
+	for _, f := range failures {
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\n", f.FailureReason, f.Count, 100*float64(f.Count)/float64(total))
+	}
+	return w.Flush()
+}
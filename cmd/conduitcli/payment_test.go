@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/record"
+	"google.golang.org/grpc"
+)
+
+// TestPrintPaymentStats ensures in flight payments are excluded and failure reasons are ranked
+func TestPrintPaymentStats(t *testing.T) {
+	events := []*core.PaymentEvent{
+		{Status: "IN_FLIGHT", AmountMsat: 1000000},
+		{Status: "SUCCEEDED", AmountMsat: 1000000, FeeMsat: 1000},
+		{Status: "SUCCEEDED", AmountMsat: 3000000, FeeMsat: 3000},
+		{Status: "FAILED", FailureReason: "FAILURE_REASON_NO_ROUTE"},
+		{Status: "FAILED", FailureReason: "FAILURE_REASON_NO_ROUTE"},
+		{Status: "FAILED", FailureReason: "FAILURE_REASON_TIMEOUT"},
+	}
+	var out bytes.Buffer
+	printPaymentStats(events, &out)
+	for _, want := range []string{"Payments:       5", "Total volume:   4000 sats", "Average fee:    2000 msat", "Success rate:   40.0%", "FAILURE_REASON_NO_ROUTE: 2\n  FAILURE_REASON_TIMEOUT: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("stats are missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// sendDest is a well formed 33 bytes public key, unlike the short keys of the rebalance routes
+var sendDest = "02" + strings.Repeat("dd", 32)
+
+func (f *fakeRouterClient) SendPaymentV2(ctx context.Context, in *routerrpc.SendPaymentRequest, opts ...grpc.CallOption) (routerrpc.Router_SendPaymentV2Client, error) {
+	f.payReqs = append(f.payReqs, in)
+	return &fakeStream[lnrpc.Payment]{msgs: f.payments}, nil
+}
+
+// TestPaymentSendKeysend ensures a keysend payment carries the preimage of its hash and the memo, and its route is printed once it succeeds
+func TestPaymentSendKeysend(t *testing.T) {
+	router := &fakeRouterClient{payments: []*lnrpc.Payment{
+		{PaymentHash: "abcd", Status: lnrpc.Payment_IN_FLIGHT},
+		{
+			PaymentHash:     "abcd",
+			PaymentPreimage: "ef01",
+			Status:          lnrpc.Payment_SUCCEEDED,
+			FeeMsat:         1200,
+			Htlcs: []*lnrpc.HTLCAttempt{
+				{Status: lnrpc.HTLCAttempt_FAILED, Route: circularRoute(0, peerCPubkey)},
+				{Status: lnrpc.HTLCAttempt_SUCCEEDED, Route: circularRoute(1200, peerAPubkey, peerBPubkey)},
+			},
+		},
+	}}
+	opts := &sendOptions{dest: sendDest, amountMsat: 100000, memo: "thanks", keysend: true, timeout: 30 * time.Second}
+	var out bytes.Buffer
+	if err := runPaymentSend(context.Background(), router, opts, &out); err != nil {
+		t.Fatalf("runPaymentSend returned an error: %v", err)
+	}
+	req := router.payReqs[0]
+	preimage := req.DestCustomRecords[record.KeySendType]
+	if hash := sha256.Sum256(preimage); len(preimage) != 32 || !bytes.Equal(hash[:], req.PaymentHash) {
+		t.Errorf("payment hash %x is not the hash of the keysend preimage %x", req.PaymentHash, preimage)
+	}
+	if string(req.DestCustomRecords[keysendMessageRecord]) != "thanks" {
+		t.Errorf("expected the memo to be sent as a keysend message, got %q", req.DestCustomRecords[keysendMessageRecord])
+	}
+	if req.AllowSelfPayment || req.AmtMsat != 100000 || req.FeeLimitMsat != 5000 || req.TimeoutSeconds != 30 {
+		t.Errorf("unexpected payment request: %v", req)
+	}
+	for _, want := range []string{"Payment hash:   abcd", "Fee paid:       1200 msat", "Route:          " + peerAPubkey + " -> " + peerBPubkey + " -> " + selfPubkey} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), peerCPubkey) {
+		t.Errorf("expected the route of the failed attempt not to be printed:\n%s", out.String())
+	}
+}
+
+// TestPaymentSendFailed ensures the failure reason of a failed payment is printed and returned
+func TestPaymentSendFailed(t *testing.T) {
+	router := &fakeRouterClient{payments: []*lnrpc.Payment{
+		{PaymentHash: "abcd", Status: lnrpc.Payment_FAILED, FailureReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE},
+	}}
+	opts := &sendOptions{dest: sendDest, amountMsat: 100000, paymentHash: strings.Repeat("ab", 32), timeout: time.Minute}
+	var out bytes.Buffer
+	err := runPaymentSend(context.Background(), router, opts, &out)
+	if err == nil || !strings.Contains(err.Error(), "FAILURE_REASON_NO_ROUTE") || !strings.Contains(out.String(), "Failure reason: FAILURE_REASON_NO_ROUTE") {
+		t.Fatalf("expected the failure reason, got %v:\n%s", err, out.String())
+	}
+	if _, ok := router.payReqs[0].DestCustomRecords[record.KeySendType]; ok {
+		t.Error("expected no preimage to be sent without --keysend")
+	}
+}
+
+// TestPaymentSendInvalid ensures invalid options are rejected before anything is sent
+func TestPaymentSendInvalid(t *testing.T) {
+	for _, opts := range []*sendOptions{
+		{dest: sendDest, amountMsat: 1000, timeout: time.Minute},
+		{dest: sendDest, amountMsat: 1000, keysend: true, paymentHash: strings.Repeat("ab", 32), timeout: time.Minute},
+		{dest: "zz", amountMsat: 1000, keysend: true, timeout: time.Minute},
+		{dest: sendDest, amountMsat: 0, keysend: true, timeout: time.Minute},
+		{dest: sendDest, amountMsat: 1000, paymentHash: "abcd", timeout: time.Minute},
+	} {
+		router := &fakeRouterClient{}
+		if err := runPaymentSend(context.Background(), router, opts, &bytes.Buffer{}); err == nil || len(router.payReqs) != 0 {
+			t.Errorf("expected options %+v to be rejected", opts)
+		}
+	}
+}
+
+// newPaymentClient returns a fake client with 10 payments made an hour apart, the newest now. Payments 2, 4 and 8 failed for lack of a
+// route, payment 6 timed out, payment 10 is in flight and the others succeeded
+func newPaymentClient(now time.Time) *fakeLightningClient {
+	client := &fakeLightningClient{}
+	for i := 1; i <= 10; i++ {
+		payment := &lnrpc.Payment{
+			PaymentIndex:   uint64(i),
+			PaymentHash:    strings.Repeat(fmt.Sprint(i%10), 64),
+			ValueMsat:      int64(i * 1000),
+			FeeMsat:        int64(i),
+			CreationTimeNs: now.Add(-time.Duration(10-i) * time.Hour).UnixNano(),
+			Status:         lnrpc.Payment_SUCCEEDED,
+		}
+		switch i {
+		case 2, 4, 8:
+			payment.Status = lnrpc.Payment_FAILED
+			payment.FailureReason = lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE
+		case 6:
+			payment.Status = lnrpc.Payment_FAILED
+			payment.FailureReason = lnrpc.PaymentFailureReason_FAILURE_REASON_TIMEOUT
+		case 10:
+			payment.Status = lnrpc.Payment_IN_FLIGHT
+		}
+		client.payments = append(client.payments, payment)
+	}
+	return client
+}
+
+// decodePaymentRows decodes the NDJSON output of the payment list command
+func decodePaymentRows(t *testing.T, out *bytes.Buffer) []*paymentRow {
+	t.Helper()
+	var rows []*paymentRow
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		row := &paymentRow{}
+		if err := dec.Decode(row); err != nil {
+			t.Fatalf("Error decoding output: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// TestPaymentListFilters ensures the status filters, --since and --limit are applied while paging backwards through the payments
+func TestPaymentListFilters(t *testing.T) {
+	now := time.Now()
+	client := newPaymentClient(now)
+	var out bytes.Buffer
+	if err := runPaymentList(context.Background(), client, &paymentFilter{succeeded: true, limit: 2}, true, &out); err != nil {
+		t.Fatalf("runPaymentList returned an error: %v", err)
+	}
+	if req := client.paymentReqs[0]; req.IncludeIncomplete || !req.Reversed || req.MaxPayments != 2 {
+		t.Errorf("unexpected ListPayments request: %v", req)
+	}
+	if rows := decodePaymentRows(t, &out); len(rows) != 2 || rows[0].AmountMsat != 9000 || rows[1].AmountMsat != 7000 {
+		t.Errorf("expected succeeded payments 9 and 7, got %+v", rows)
+	}
+	client = newPaymentClient(now)
+	out.Reset()
+	filter := &paymentFilter{failed: true, inFlight: true, limit: 3}
+	if err := runPaymentList(context.Background(), client, filter, true, &out); err != nil {
+		t.Fatalf("runPaymentList returned an error: %v", err)
+	}
+	rows := decodePaymentRows(t, &out)
+	if len(rows) != 3 || rows[0].Status != "IN_FLIGHT" || rows[0].FailureReason != "" || rows[2].FailureReason != "FAILURE_REASON_TIMEOUT" {
+		t.Errorf("expected payments 10, 8 and 6, got %+v", rows)
+	}
+	if len(client.paymentReqs) != 2 || !client.paymentReqs[0].IncludeIncomplete || client.paymentReqs[1].IndexOffset != 8 {
+		t.Errorf("expected a second page before payment 8, got %v", client.paymentReqs)
+	}
+	client = newPaymentClient(now)
+	out.Reset()
+	filter = &paymentFilter{since: now.Add(-150 * time.Minute), limit: 100}
+	if err := runPaymentList(context.Background(), client, filter, true, &out); err != nil {
+		t.Fatalf("runPaymentList returned an error: %v", err)
+	}
+	if rows := decodePaymentRows(t, &out); len(rows) != 3 || rows[2].AmountMsat != 8000 {
+		t.Errorf("expected the payments of the last 2.5 hours, got %+v", rows)
+	}
+	if err := runPaymentList(context.Background(), client, &paymentFilter{}, false, &out); err == nil {
+		t.Error("expected a zero limit to be rejected")
+	}
+}
+
+// TestPaymentListTable ensures payment hashes are truncated and the failure reason is only shown for failed payments
+func TestPaymentListTable(t *testing.T) {
+	client := newPaymentClient(time.Now())
+	var out bytes.Buffer
+	if err := runPaymentList(context.Background(), client, &paymentFilter{limit: 3}, false, &out); err != nil {
+		t.Fatalf("runPaymentList returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "PAYMENT HASH") {
+		t.Fatalf("expected a header and 3 payments, got:\n%s", out.String())
+	}
+	succeeded := strings.Fields(lines[2])
+	if succeeded[0] != strings.Repeat("9", 16)+"..." || succeeded[1] != "9000" || succeeded[2] != "9" || succeeded[3] != "SUCCEEDED" || succeeded[4] != "-" {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+	if failed := strings.Fields(lines[3]); failed[3] != "FAILED" || failed[4] != "FAILURE_REASON_NO_ROUTE" {
+		t.Errorf("expected the failure reason of payment 8, got %q", lines[3])
+	}
+}
+
+// TestPaymentFailureAnalysis ensures the failed payments are grouped by failure reason, most common first, whatever the status filters
+func TestPaymentFailureAnalysis(t *testing.T) {
+	client := newPaymentClient(time.Now())
+	var out bytes.Buffer
+	if err := runPaymentFailureAnalysis(context.Background(), client, &paymentFilter{succeeded: true, limit: 100}, true, &out); err != nil {
+		t.Fatalf("runPaymentFailureAnalysis returned an error: %v", err)
+	}
+	var failures []failureCount
+	if err := json.Unmarshal(out.Bytes(), &failures); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	expected := []failureCount{{"FAILURE_REASON_NO_ROUTE", 3}, {"FAILURE_REASON_TIMEOUT", 1}}
+	if len(failures) != 2 || failures[0] != expected[0] || failures[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, failures)
+	}
+	out.Reset()
+	if err := runPaymentFailureAnalysis(context.Background(), client, &paymentFilter{limit: 100}, false, &out); err != nil {
+		t.Fatalf("runPaymentFailureAnalysis returned an error: %v", err)
+	}
+	if s := out.String(); !strings.Contains(s, "Failed payments: 4") || !strings.Contains(s, "75.0%") {
+		t.Errorf("unexpected analysis:\n%s", s)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var peerCommand = cli.Command{
+	Name:  "peer",
+	Usage: "Manage the peers of the LND node",
+	Subcommands: []cli.Command{
+		peerListCommand,
+		peerConnectCommand,
+		peerDisconnectCommand,
+	},
+}
+
+var peerConnectCommand = cli.Command{
+	Name:      "connect",
+	Usage:     "Connect to a peer",
+	ArgsUsage: "pubkey@host:port",
+	Description: `
+	Connects to the node at the given address and prints its alias when it's
+	known from the channel graph. With --perm, LND keeps reconnecting to the
+	peer whenever the connection drops, even without channels.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "perm",
+			Usage: "keep reconnecting to the peer",
+		},
+	},
+	Action: peerConnect,
+}
+
+var peerDisconnectCommand = cli.Command{
+	Name:      "disconnect",
+	Usage:     "Disconnect from a peer",
+	ArgsUsage: "pubkey",
+	Description: `
+	Disconnects from the peer. Peers with which channels are open aren't
+	disconnected unless --force is set, since their channels can't be used
+	while disconnected.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "disconnect even if channels are open with the peer",
+		},
+	},
+	Action: peerDisconnect,
+}
+
+// parsePubkey decodes the hex public key of a node, which must be 33 bytes long
+func parsePubkey(pubkey string) ([]byte, error) {
+	raw, err := hex.DecodeString(pubkey)
+	if err != nil || len(raw) != 33 {
+		return nil, fmt.Errorf("invalid public key %v: expected 33 hex encoded bytes", pubkey)
+	}
+	return raw, nil
+}
+
+// peerName returns the alias of the peer, or its public key if the alias isn't known
+func peerName(ctx context.Context, client lnrpc.LightningClient, pubkey string) string {
+	if alias := nodeAlias(ctx, client, pubkey, make(map[string]string)); alias != "" {
+		return alias
+	}
+	return pubkey
+}
+
+// peerConnect is the action of the peer connect command
+func peerConnect(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "connect")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runPeerConnect(context.Background(), client, ctx.Args().First(), ctx.Bool("perm"), os.Stdout)
+}
+
+// runPeerConnect connects to the peer at the address of the form pubkey@host:port
+func runPeerConnect(ctx context.Context, client lnrpc.LightningClient, addr string, perm bool, out io.Writer) error {
+	split := strings.SplitN(addr, "@", 2)
+	if len(split) != 2 || split[1] == "" {
+		return fmt.Errorf("invalid address %v, expected pubkey@host:port", addr)
+	}
+	if _, err := parsePubkey(split[0]); err != nil {
+		return err
+	}
+	_, err := client.ConnectPeer(ctx, &lnrpc.ConnectPeerRequest{
+		Addr: &lnrpc.LightningAddress{Pubkey: split[0], Host: split[1]},
+		Perm: perm,
+	})
+	if err != nil {
+		return fmt.Errorf("could not connect to %v: %v", addr, err)
+	}
+	fmt.Fprintf(out, "Connected to %s\n", peerName(ctx, client, split[0]))
+	return nil
+}
+
+// peerDisconnect is the action of the peer disconnect command
+func peerDisconnect(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "disconnect")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runPeerDisconnect(context.Background(), client, ctx.Args().First(), ctx.Bool("force"), os.Stdout)
+}
+
+// runPeerDisconnect disconnects from the peer, unless channels are open with it and force isn't set
+func runPeerDisconnect(ctx context.Context, client lnrpc.LightningClient, pubkey string, force bool, out io.Writer) error {
+	if _, err := parsePubkey(pubkey); err != nil {
+		return err
+	}
+	name := peerName(ctx, client, pubkey)
+	resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	open := 0
+	for _, channel := range resp.Channels {
+		if channel.RemotePubkey == pubkey {
+			open++
+		}
+	}
+	if open > 0 {
+		fmt.Fprintf(out, "Warning: %d channel(s) are open with %s and can't be used while disconnected\n", open, name)
+		if !force {
+			return fmt.Errorf("not disconnecting from %s, set --force to disconnect anyway", name)
+		}
+	}
+	if _, err = client.DisconnectPeer(ctx, &lnrpc.DisconnectPeerRequest{PubKey: pubkey}); err != nil {
+		return fmt.Errorf("could not disconnect from %s: %v", name, err)
+	}
+	fmt.Fprintf(out, "Disconnected from %s\n", name)
+	return nil
+}
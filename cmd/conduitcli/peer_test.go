@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+var (
+	alicePubkey = "02" + strings.Repeat("a", 64)
+	bobPubkey   = "03" + strings.Repeat("b", 64)
+)
+
+// newPeerClient returns a fake client knowing the alias of alice, with which a channel is open, but not the alias of bob
+func newPeerClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		nodes:    map[string]*lnrpc.NodeInfo{alicePubkey: {Node: &lnrpc.LightningNode{PubKey: alicePubkey, Alias: "alice"}}},
+		channels: []*lnrpc.Channel{{ChanId: 1, RemotePubkey: alicePubkey}},
+	}
+}
+
+// TestPeerConnect ensures the address is split into the public key and the host and the peer is displayed by alias when known
+func TestPeerConnect(t *testing.T) {
+	client := newPeerClient()
+	var out bytes.Buffer
+	if err := runPeerConnect(context.Background(), client, alicePubkey+"@alice.example.com:9735", true, &out); err != nil {
+		t.Fatal(err)
+	}
+	req := client.connectReqs[0]
+	if req.Addr.Pubkey != alicePubkey || req.Addr.Host != "alice.example.com:9735" || !req.Perm {
+		t.Errorf("unexpected ConnectPeer request %v", req)
+	}
+	if out.String() != "Connected to alice\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	out.Reset()
+	if err := runPeerConnect(context.Background(), client, bobPubkey+"@10.0.0.2:9735", false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if client.connectReqs[1].Perm || out.String() != "Connected to "+bobPubkey+"\n" {
+		t.Errorf("expected bob to be displayed by pubkey, got %q", out.String())
+	}
+	for _, addr := range []string{alicePubkey, alicePubkey + "@", "02ab@host:9735"} {
+		if err := runPeerConnect(context.Background(), client, addr, false, &out); err == nil {
+			t.Errorf("expected %q to be rejected", addr)
+		}
+	}
+	if len(client.connectReqs) != 2 {
+		t.Errorf("expected invalid addresses not to be connected to, got %v", client.connectReqs)
+	}
+	client.peerErr = fmt.Errorf("already connected to peer")
+	if err := runPeerConnect(context.Background(), client, bobPubkey+"@10.0.0.2:9735", false, &out); err == nil || !strings.Contains(err.Error(), "already connected") {
+		t.Errorf("expected the LND error, got %v", err)
+	}
+}
+
+// TestPeerDisconnect ensures peers with open channels are only disconnected with --force
+func TestPeerDisconnect(t *testing.T) {
+	client := newPeerClient()
+	var out bytes.Buffer
+	if err := runPeerDisconnect(context.Background(), client, bobPubkey, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Disconnected from "+bobPubkey+"\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	out.Reset()
+	if err := runPeerDisconnect(context.Background(), client, alicePubkey, false, &out); err == nil {
+		t.Error("expected a peer with open channels not to be disconnected without --force")
+	}
+	if !strings.Contains(out.String(), "Warning: 1 channel(s) are open with alice") {
+		t.Errorf("expected a warning, got %q", out.String())
+	}
+	if len(client.disconnected) != 1 {
+		t.Errorf("expected only bob to be disconnected, got %v", client.disconnected)
+	}
+	out.Reset()
+	if err := runPeerDisconnect(context.Background(), client, alicePubkey, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.disconnected) != 2 || client.disconnected[1] != alicePubkey || !strings.HasSuffix(out.String(), "Disconnected from alice\n") {
+		t.Errorf("expected alice to be disconnected with --force, got %v: %q", client.disconnected, out.String())
+	}
+	if err := runPeerDisconnect(context.Background(), client, "alice", true, &out); err == nil {
+		t.Error("expected an invalid public key to be rejected")
+	}
+}
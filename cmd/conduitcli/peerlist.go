@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	color "github.com/mgutz/ansi"
+	"github.com/urfave/cli"
+)
+
+const defaultMinPeerScore = 40
+
+var peerListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List the connected peers with their score",
+	Description: `
+	Lists the connected peers with the score Conduit gives them, from 0 to 100,
+	for their forwarding volume, the days they've been connected, their ping
+	latency and the balance of the forwards they send and receive. Peers scoring
+	below --min-score are marked with ! and printed in red, unless NO_COLOR is
+	set. The peers are listed without scores when Conduit isn't running.`,
+	Flags: []cli.Flag{
+		cli.Float64Flag{
+			Name:  "min-score",
+			Value: defaultMinPeerScore,
+			Usage: "the score below which peers are highlighted",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the peers as JSON",
+		},
+	},
+	Action: peerList,
+}
+
+// peerRow is a connected peer in the output of the peer list command
+type peerRow struct {
+	Pubkey     string   `json:"pubkey"`
+	Alias      string   `json:"alias,omitempty"`
+	Address    string   `json:"address"`
+	Inbound    bool     `json:"inbound"`
+	PingMicros int64    `json:"ping_us"`
+	Score      *float64 `json:"score,omitempty"`
+	LowScore   bool     `json:"low_score"`
+}
+
+// peerList is the action of the peer list command
+func peerList(ctx *cli.Context) error {
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	var scores []core.PeerScore
+	if err = callConduit(ctx, "conduit_peer_scores", nil, &scores); isRPCErrorCode(err, jsonrpc.ErrLNDNotRunning) {
+		fmt.Fprintln(os.Stderr, "Peer scores unavailable until Conduit sees LND active")
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Peer scores unavailable: %v\n", err)
+	}
+	return runPeerList(context.Background(), client, scores, ctx.Float64("min-score"), ctx.Bool("json"), os.Getenv("NO_COLOR") == "", os.Stdout)
+}
+
+// peerRowsOf returns the connected peers in the order of their score, the peers Conduit didn't score yet last
+func peerRowsOf(ctx context.Context, client lnrpc.LightningClient, peers []*lnrpc.Peer, scores []core.PeerScore, minScore float64) []*peerRow {
+	byPubkey := make(map[string]*lnrpc.Peer, len(peers))
+	for _, peer := range peers {
+		byPubkey[peer.PubKey] = peer
+	}
+	rows := make([]*peerRow, 0, len(peers))
+	aliases := make(map[string]string)
+	add := func(peer *lnrpc.Peer, score *float64) {
+		row := &peerRow{
+			Pubkey:     peer.PubKey,
+			Alias:      nodeAlias(ctx, client, peer.PubKey, aliases),
+			Address:    peer.Address,
+			Inbound:    peer.Inbound,
+			PingMicros: peer.PingTime,
+			Score:      score,
+		}
+		row.LowScore = score != nil && *score < minScore
+		rows = append(rows, row)
+		delete(byPubkey, peer.PubKey)
+	}
+	// the scores are sorted by the daemon and may include peers disconnected since
+	for i := range scores {
+		if peer, ok := byPubkey[scores[i].Pubkey]; ok {
+			add(peer, &scores[i].Score)
+		}
+	}
+	for _, peer := range peers {
+		if _, ok := byPubkey[peer.PubKey]; ok {
+			add(peer, nil)
+		}
+	}
+	return rows
+}
+
+// runPeerList prints the connected peers with their score, highlighting those below minScore in red if colored is set
+func runPeerList(ctx context.Context, client lnrpc.LightningClient, scores []core.PeerScore, minScore float64, asJSON, colored bool, out io.Writer) error {
+	resp, err := client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		return err
+	}
+	rows := peerRowsOf(ctx, client, resp.Peers, scores, minScore)
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(rows)
+	}
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "\tPUBKEY\tALIAS\tADDRESS\tDIRECTION\tPING (MS)\tSCORE")
+	for _, row := range rows {
+		marker, score := "", "-"
+		if row.LowScore {
+			marker = "!"
+		}
+		if row.Score != nil {
+			score = fmt.Sprintf("%.0f", *row.Score)
+		}
+		direction := "outbound"
+		if row.Inbound {
+			direction = "inbound"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.1f\t%s\n", marker, row.Pubkey, row.Alias, row.Address, direction, float64(row.PingMicros)/1000, score)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	// whole lines are colored once aligned, since the escape sequences would count in the width of the columns
+	lines := strings.SplitAfter(table.String(), "\n")
+	for i, line := range lines {
+		if colored && i > 0 && i <= len(rows) && rows[i-1].LowScore {
+			line = color.Color(strings.TrimSuffix(line, "\n"), "red") + "\n"
+		}
+		if _, err = io.WriteString(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
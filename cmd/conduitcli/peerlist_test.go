@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	color "github.com/mgutz/ansi"
+)
+
+// newScoredPeerClient returns a fake client connected to alice, bob and a third peer which Conduit didn't score yet
+func newScoredPeerClient() (*fakeLightningClient, []core.PeerScore) {
+	client := newPeerClient()
+	client.peers = []*lnrpc.Peer{
+		{PubKey: bobPubkey, Address: "10.0.0.2:9735", Inbound: true, PingTime: 25000},
+		{PubKey: peerAPubkey, Address: "10.0.0.3:9735"},
+		{PubKey: alicePubkey, Address: "10.0.0.1:9735", PingTime: 1500},
+	}
+	scores := []core.PeerScore{
+		{Pubkey: alicePubkey, Score: 87.6},
+		// a peer which disconnected since it was scored
+		{Pubkey: peerBPubkey, Score: 60},
+		{Pubkey: bobPubkey, Score: 12.3},
+	}
+	return client, scores
+}
+
+// TestPeerList ensures the peers are listed best first, the unscored ones last, with those below the minimum score marked and colored
+func TestPeerList(t *testing.T) {
+	client, scores := newScoredPeerClient()
+	var out bytes.Buffer
+	if err := runPeerList(context.Background(), client, scores, 40, false, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 peers, got %q", out.String())
+	}
+	if !strings.Contains(lines[1], alicePubkey) || !strings.Contains(lines[1], "alice") || !strings.Contains(lines[1], "1.5") || !strings.HasSuffix(lines[1], "88") || strings.HasPrefix(lines[1], "!") {
+		t.Errorf("expected alice first with a good score, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "!") || !strings.Contains(lines[2], bobPubkey) || !strings.Contains(lines[2], "inbound") || !strings.HasSuffix(lines[2], "12") {
+		t.Errorf("expected bob to be marked with a low score, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], peerAPubkey) || !strings.HasSuffix(lines[3], "-") || strings.HasPrefix(lines[3], "!") {
+		t.Errorf("expected the unscored peer last without a score, got %q", lines[3])
+	}
+	if strings.Contains(out.String(), peerBPubkey) || strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("expected neither disconnected peers nor colors, got %q", out.String())
+	}
+	// the columns stay aligned once colored
+	var colored bytes.Buffer
+	if err := runPeerList(context.Background(), client, scores, 40, false, true, &colored); err != nil {
+		t.Fatal(err)
+	}
+	coloredLines := strings.Split(strings.TrimSpace(colored.String()), "\n")
+	for i, line := range coloredLines {
+		isRed := strings.HasPrefix(line, color.ColorCode("red"))
+		if isRed != (i == 2) {
+			t.Errorf("expected only bob to be red, line %d is %q", i, line)
+		}
+		if stripped := strings.TrimSuffix(strings.TrimPrefix(line, color.ColorCode("red")), color.Reset); stripped != lines[i] {
+			t.Errorf("expected line %d to be %q once uncolored, got %q", i, lines[i], stripped)
+		}
+	}
+}
+
+// TestPeerListJSON ensures the JSON output has the scores of the scored peers only
+func TestPeerListJSON(t *testing.T) {
+	client, scores := newScoredPeerClient()
+	var out bytes.Buffer
+	if err := runPeerList(context.Background(), client, scores, 50, true, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	var rows []peerRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(rows) != 3 || rows[0].Pubkey != alicePubkey || rows[1].Pubkey != bobPubkey || rows[2].Pubkey != peerAPubkey {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if rows[0].Score == nil || *rows[0].Score != 87.6 || rows[0].LowScore || !rows[1].LowScore || rows[2].Score != nil || rows[2].LowScore {
+		t.Errorf("unexpected scores %+v", rows)
+	}
+	// without the daemon, the peers are listed in the order of LND
+	out.Reset()
+	if err := runPeerList(context.Background(), client, nil, 50, true, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	var unscored []peerRow
+	if err := json.Unmarshal(out.Bytes(), &unscored); err != nil || len(unscored) != 3 || unscored[0].Pubkey != bobPubkey || unscored[0].Score != nil {
+		t.Errorf("expected the unscored peers of LND, got %+v: %v", unscored, err)
+	}
+}
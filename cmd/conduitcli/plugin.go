@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const traceFollowInterval = 500 * time.Millisecond
+
+var pluginCommand = cli.Command{
+	Name:  "plugin",
+	Usage: "Manage Conduit plugins",
+	Subcommands: []cli.Command{
+		pluginListCommand,
+		pluginTraceCommand,
+		pluginInstallCommand,
+		pluginProfileCommand,
+	},
+}
+
+var pluginListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List the plugins with their status and crashes",
+	Description: `
+	Lists the plugins of the running daemon with their version, status, the
+	number of times they crashed and when they last did. The crashes are
+	counted over the runs of Conduit.`,
+	Action: pluginList,
+}
+
+var pluginProfileCommand = cli.Command{
+	Name:  "profile",
+	Usage: "Capture profiles from plugins",
+	Subcommands: []cli.Command{
+		pluginProfileHeapCommand,
+	},
+}
+
+var pluginProfileHeapCommand = cli.Command{
+	Name:      "heap",
+	Usage:     "Capture a heap profile from a plugin",
+	ArgsUsage: "name",
+	Description: `
+	Downloads a heap profile from the pprof server the plugin serves on the
+	PprofPort of its manifest and saves it in the profiles directory of the
+	conduit directory. The profile can be inspected with go tool pprof.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: pluginProfileHeap,
+}
+
+var pluginInstallCommand = cli.Command{
+	Name:      "install",
+	Usage:     "Install a plugin from the plugin registry",
+	ArgsUsage: "name[@version]",
+	Description: `
+	Downloads the plugin's manifest and its binary for this platform from the
+	PluginRegistryURL of config.yaml, verifies the binary's checksum and installs
+	both in the plugin directory. The latest version is installed unless one is
+	given. Conduit must be restarted to launch the plugin. There's no default
+	registry, PluginRegistryURL must be set.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: pluginInstall,
+}
+
+var pluginTraceCommand = cli.Command{
+	Name:  "trace",
+	Usage: "Print the messages exchanged between plugins",
+	Description: `
+	Prints the entries of the IPC trace written by Conduit when PluginIPCTrace is
+	set: the time, sender, recipient and size of every message along with its
+	first 64 bytes in hex. With --follow, new entries are printed as they are
+	written until Ctrl-C is pressed.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "follow",
+			Usage: "keep printing new entries",
+		},
+		cli.StringFlag{
+			Name:  "plugin",
+			Usage: "only print the messages sent or received by the named plugin",
+		},
+		conduitDirFlag,
+	},
+	Action: pluginTrace,
+}
+
+// pluginTrace is the action of the plugin trace command
+func pluginTrace(ctx *cli.Context) error {
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	filename := core.IPCTracePath(&core.Config{ConduitDir: ctx.String("conduitdir")})
+	return runPluginTrace(sigCtx, filename, ctx.String("plugin"), ctx.Bool("follow"), os.Stdout)
+}
+
+// runPluginTrace prints the entries of the IPC trace involving plugin, or all of them if it's empty. When following, it waits for new entries until the context is cancelled
+func runPluginTrace(ctx context.Context, filename, plugin string, follow bool, out io.Writer) error {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no IPC trace at %v, is PluginIPCTrace enabled?", filename)
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == io.EOF {
+			if !follow {
+				return nil
+			}
+			// the rest of a partial line is read once Conduit has written it
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(traceFollowInterval):
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		var entry core.IPCTraceEntry
+		if err = json.Unmarshal([]byte(partial), &entry); err != nil {
+			return fmt.Errorf("invalid IPC trace entry %q: %v", partial, err)
+		}
+		partial = ""
+		if plugin != "" && !entry.Involves(plugin) {
+			continue
+		}
+		fmt.Fprintf(out, "%s %s -> %s %d bytes %s\n", entry.Timestamp.Format(time.RFC3339Nano), entry.From, entry.To, entry.Size, entry.Preview)
+	}
+}
+
+// pluginList is the action of the plugin list command
+func pluginList(ctx *cli.Context) error {
+	client, err := getConduitClient(ctx)
+	if err != nil {
+		return err
+	}
+	return runPluginList(context.Background(), client, os.Stdout)
+}
+
+// runPluginList prints the plugins of the daemon as a table
+func runPluginList(ctx context.Context, client *jsonrpc.Client, out io.Writer) error {
+	var plugins []core.PluginInfo
+	if err := client.Call(ctx, "conduit_plugin_list", nil, &plugins); err != nil {
+		return err
+	}
+	if len(plugins) == 0 {
+		fmt.Fprintln(out, "No plugins installed")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSTATUS\tCRASHES\tLAST CRASH")
+	for _, p := range plugins {
+		lastCrash := "-"
+		if p.LastCrash != nil {
+			lastCrash = p.LastCrash.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", p.Name, p.Version, p.Status, p.RestartCount, lastCrash)
+	}
+	return w.Flush()
+}
+
+// loadCLIConfig reads config.yaml of the conduit directory, returning a config with only the conduit directory set if it can't be read
+func loadCLIConfig(conduitDir string) *core.Config {
+	config := &core.Config{}
+	if raw, err := os.ReadFile(filepath.Join(conduitDir, configFileName)); err == nil {
+		if err = yaml.Unmarshal(raw, config); err != nil {
+			config = &core.Config{}
+		}
+	}
+	config.ConduitDir = conduitDir
+	return config
+}
+
+// pluginInstall is the action of the plugin install command
+func pluginInstall(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "install")
+	}
+	return runPluginInstall(loadCLIConfig(ctx.String("conduitdir")), ctx.Args().First(), os.Stdout)
+}
+
+// runPluginInstall installs the plugin given as name[@version]
+func runPluginInstall(config *core.Config, spec string, out io.Writer) error {
+	name, version := spec, core.PluginLatestVersion
+	if i := strings.LastIndex(spec, "@"); i != -1 {
+		name, version = spec[:i], spec[i+1:]
+	}
+	if err := core.NewPluginAutoInstaller(config).Install(name, version); err != nil {
+		return fmt.Errorf("could not install %s: %v", spec, err)
+	}
+	manifest, err := core.LoadPluginManifest(config, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Installed %s %s in %s. Restart Conduit to launch it\n", manifest.Name, manifest.Version, core.PluginDir(config))
+	return nil
+}
+
+// pluginProfileHeap is the action of the plugin profile heap command
+func pluginProfileHeap(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "heap")
+	}
+	return runPluginProfileHeap(core.NewPluginHeapProfiler(loadCLIConfig(ctx.String("conduitdir"))), ctx.Args().First(), os.Stdout)
+}
+
+// runPluginProfileHeap saves a heap profile of the plugin and prints where it was saved
+func runPluginProfileHeap(profiler *core.PluginHeapProfiler, name string, out io.Writer) error {
+	filename, err := profiler.SaveHeap(name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Saved heap profile of %s to %s\n", name, filename)
+	fmt.Fprintf(out, "Inspect it with: go tool pprof %s\n", filename)
+	return nil
+}
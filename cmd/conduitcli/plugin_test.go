@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// traceLines are IPC trace entries between three plugins
+var traceLines = []string{
+	`{"timestamp":"2022-05-01T12:00:00Z","from":"signer","to":"watcher","size":5,"preview":"68656c6c6f"}`,
+	`{"timestamp":"2022-05-01T12:00:01Z","from":"watcher","to":"backup","size":2,"preview":"6869"}`,
+	`{"timestamp":"2022-05-01T12:00:02Z","from":"backup","to":"signer","size":3,"preview":"616263"}`,
+}
+
+// writeTrace writes the lines to an IPC trace in a temporary directory and returns its path
+func writeTrace(t *testing.T, lines ...string) string {
+	t.Helper()
+	filename := path.Join(t.TempDir(), "ipc_trace.log")
+	if err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// TestPluginTrace ensures the entries are printed and filtered by plugin
+func TestPluginTrace(t *testing.T) {
+	filename := writeTrace(t, traceLines...)
+	var out bytes.Buffer
+	if err := runPluginTrace(context.Background(), filename, "", false, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "2022-05-01T12:00:00Z signer -> watcher 5 bytes 68656c6c6f\n" +
+		"2022-05-01T12:00:01Z watcher -> backup 2 bytes 6869\n" +
+		"2022-05-01T12:00:02Z backup -> signer 3 bytes 616263\n"
+	if out.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out.String())
+	}
+	out.Reset()
+	if err := runPluginTrace(context.Background(), filename, "signer", false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], "signer -> watcher") || !strings.Contains(lines[1], "backup -> signer") {
+		t.Errorf("unexpected filtered output %q", out.String())
+	}
+	if err := runPluginTrace(context.Background(), path.Join(t.TempDir(), "missing.log"), "", false, &out); err == nil || !strings.Contains(err.Error(), "PluginIPCTrace") {
+		t.Errorf("expected a missing trace to mention PluginIPCTrace, got %v", err)
+	}
+	if err := runPluginTrace(context.Background(), writeTrace(t, "not json"), "", false, &out); err == nil {
+		t.Error("expected an invalid entry to be rejected")
+	}
+}
+
+// TestPluginTraceFollow ensures entries appended while following are printed, even when written in two parts
+func TestPluginTraceFollow(t *testing.T) {
+	filename := writeTrace(t, traceLines[0])
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- runPluginTrace(ctx, filename, "backup", true, writer)
+		writer.Close()
+	}()
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	half := len(traceLines[1]) / 2
+	file.WriteString(traceLines[1][:half])
+	time.Sleep(2 * traceFollowInterval)
+	file.WriteString(traceLines[1][half:] + "\n")
+	lines := bufio.NewScanner(reader)
+	if !lines.Scan() || lines.Text() != "2022-05-01T12:00:01Z watcher -> backup 2 bytes 6869" {
+		t.Fatalf("unexpected line %q", lines.Text())
+	}
+	cancel()
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPluginInstall ensures the version given after @ is installed from the registry of config.yaml
+func TestPluginInstall(t *testing.T) {
+	binary := []byte("plugin binary")
+	sum := sha256.Sum256(binary)
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/plugins/echo/0.3.1/manifest.json":
+			json.NewEncoder(w).Encode(core.PluginRegistryManifest{
+				PluginManifest: core.PluginManifest{Name: "echo", Version: "0.3.1"},
+				Binaries:       map[string]core.PluginBinary{runtime.GOOS + "/" + runtime.GOARCH: {URL: "echo", SHA256: hex.EncodeToString(sum[:])}},
+			})
+		case "/plugins/echo/0.3.1/echo":
+			w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	if err := os.WriteFile(path.Join(dir, configFileName), []byte("PluginRegistryURL: "+ts.URL+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := runPluginInstall(loadCLIConfig(dir), "echo@0.3.1", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Installed echo 0.3.1") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+	if err := runPluginInstall(loadCLIConfig(dir), "echo", &out); err == nil {
+		t.Error("expected an error installing a version missing from the registry")
+	}
+	if want := "/plugins/echo/latest/manifest.json"; requested[len(requested)-1] != want {
+		t.Errorf("expected %v to be requested, got %v", want, requested)
+	}
+}
+
+// TestPluginProfileHeap ensures the heap profile of the plugin's pprof server is saved in the conduit directory
+func TestPluginProfileHeap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/heap" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("heap profile"))
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	config := loadCLIConfig(t.TempDir())
+	raw, err := yaml.Marshal(&core.PluginManifest{Name: "leaky", Version: "0.1.0", PprofPort: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(core.PluginDir(config), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path.Join(core.PluginDir(config), "leaky.yaml"), raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = runPluginProfileHeap(core.NewPluginHeapProfiler(config), "leaky", &out); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ := os.ReadDir(core.PluginProfileDir(config))
+	if len(profiles) != 1 || !strings.HasPrefix(profiles[0].Name(), "leaky-") || !strings.Contains(out.String(), profiles[0].Name()) {
+		t.Errorf("expected the saved profile in the output, got %v:\n%s", profiles, out.String())
+	}
+	if err = runPluginProfileHeap(core.NewPluginHeapProfiler(config), "missing", &out); err == nil {
+		t.Error("expected an error profiling a missing plugin")
+	}
+}
+
+// TestPluginList ensures the plugins are printed with their crashes
+func TestPluginList(t *testing.T) {
+	lastCrash := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := jsonrpc.NewServer()
+	server.Register("conduit_plugin_list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return []core.PluginInfo{
+			{Name: "crasher", Version: "0.1.0", Status: core.PluginFailed, RestartCount: 3, LastCrash: &lastCrash},
+			{Name: "signer", Version: "1.2.0", Status: core.PluginRunning},
+		}, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = runPluginList(context.Background(), client, &out); err != nil {
+		t.Fatalf("runPluginList returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || strings.Fields(lines[0])[3] != "CRASHES" {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "crasher 0.1.0 Failed 3 2022-05-01T12:00:00Z" {
+		t.Errorf("unexpected crashed plugin row %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "signer 1.2.0 Running 0 -" {
+		t.Errorf("unexpected running plugin row %q", lines[2])
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var channelRebalanceCommand = cli.Command{
+	Name:  "rebalance",
+	Usage: "Move liquidity between two channels with a circular payment",
+	Description: `
+	Pays an invoice of our own node out through the --from channel and back in
+	through the --to channel, moving --amount of local balance from one to the
+	other. A failing route is avoided on the next attempt, up to --max-attempts.`,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "from",
+			Usage: "the ID of the channel to send the payment out of",
+		},
+		cli.Uint64Flag{
+			Name:  "to",
+			Usage: "the ID of the channel to receive the payment through",
+		},
+		cli.Int64Flag{
+			Name:  "amount",
+			Usage: "the amount to move in msat",
+		},
+		cli.Int64Flag{
+			Name:  "max-fee-ppm",
+			Usage: "the maximum fee to pay, in ppm of the amount",
+			Value: 1000,
+		},
+		cli.IntFlag{
+			Name:  "max-attempts",
+			Usage: "the maximum number of routes to try",
+			Value: 5,
+		},
+	},
+	Action: channelRebalance,
+}
+
+// rebalanceOptions are the options of the channel rebalance command
+type rebalanceOptions struct {
+	from, to    uint64
+	amountMsat  int64
+	maxFeePPM   int64
+	maxAttempts int
+}
+
+// channelRebalance is the action of the channel rebalance command
+func channelRebalance(ctx *cli.Context) error {
+	if !ctx.IsSet("from") || !ctx.IsSet("to") || !ctx.IsSet("amount") {
+		return cli.ShowCommandHelp(ctx, "rebalance")
+	}
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	opts := &rebalanceOptions{
+		from:        ctx.Uint64("from"),
+		to:          ctx.Uint64("to"),
+		amountMsat:  ctx.Int64("amount"),
+		maxFeePPM:   ctx.Int64("max-fee-ppm"),
+		maxAttempts: ctx.Int("max-attempts"),
+	}
+	return runChannelRebalance(context.Background(), lnrpc.NewLightningClient(conn), routerrpc.NewRouterClient(conn), opts, os.Stdout)
+}
+
+// findChannelByID returns the open channel with the given ID
+func findChannelByID(channels []*lnrpc.Channel, id uint64) (*lnrpc.Channel, error) {
+	for _, channel := range channels {
+		if channel.ChanId == id {
+			return channel, nil
+		}
+	}
+	return nil, fmt.Errorf("no open channel with ID %d", id)
+}
+
+// runChannelRebalance pays an invoice of our own node over routes leaving through the from channel and coming back through the to channel
+func runChannelRebalance(ctx context.Context, client lnrpc.LightningClient, router routerrpc.RouterClient, opts *rebalanceOptions, out io.Writer) error {
+	if opts.from == opts.to {
+		return fmt.Errorf("--from and --to must be different channels")
+	}
+	if opts.amountMsat <= 0 || opts.maxFeePPM < 0 || opts.maxAttempts <= 0 {
+		return fmt.Errorf("--amount and --max-attempts must be positive and --max-fee-ppm can't be negative")
+	}
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err = findChannelByID(resp.Channels, opts.from); err != nil {
+		return err
+	}
+	to, err := findChannelByID(resp.Channels, opts.to)
+	if err != nil {
+		return err
+	}
+	lastHop, err := hex.DecodeString(to.RemotePubkey)
+	if err != nil {
+		return fmt.Errorf("invalid remote pubkey %v: %v", to.RemotePubkey, err)
+	}
+	invoice, err := client.AddInvoice(ctx, &lnrpc.Invoice{
+		Memo:      fmt.Sprintf("rebalance %d -> %d", opts.from, opts.to),
+		ValueMsat: opts.amountMsat,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create invoice: %v", err)
+	}
+	var ignored []*lnrpc.NodePair
+	succeeded := false
+	for attempt := 1; attempt <= opts.maxAttempts && !succeeded; attempt++ {
+		routes, err := client.QueryRoutes(ctx, &lnrpc.QueryRoutesRequest{
+			PubKey:            info.IdentityPubkey,
+			AmtMsat:           opts.amountMsat,
+			FeeLimit:          &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_FixedMsat{FixedMsat: opts.amountMsat * opts.maxFeePPM / 1000000}},
+			OutgoingChanId:    opts.from,
+			LastHopPubkey:     lastHop,
+			IgnoredPairs:      ignored,
+			UseMissionControl: true,
+		})
+		if err != nil || len(routes.Routes) == 0 {
+			fmt.Fprintf(out, "Attempt %d: no route found: %v\n", attempt, err)
+			break
+		}
+		route := routes.Routes[0]
+		final := route.Hops[len(route.Hops)-1]
+		final.MppRecord = &lnrpc.MPPRecord{PaymentAddr: invoice.PaymentAddr, TotalAmtMsat: opts.amountMsat}
+		fmt.Fprintf(out, "Attempt %d: %d hops, fee %d msat (%d ppm): ", attempt, len(route.Hops), route.TotalFeesMsat, route.TotalFeesMsat*1000000/opts.amountMsat)
+		htlc, err := router.SendToRouteV2(ctx, &routerrpc.SendToRouteRequest{PaymentHash: invoice.RHash, Route: route})
+		if err != nil {
+			fmt.Fprintf(out, "failed: %v\n", err)
+			break
+		}
+		if htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+			fmt.Fprintln(out, "succeeded")
+			succeeded = true
+			break
+		}
+		failure := htlc.GetFailure()
+		index := int(failure.GetFailureSourceIndex())
+		fmt.Fprintf(out, "failed: %v at hop %d\n", failure.GetCode(), index)
+		// failures of our own channel or of our node as the destination won't go away with another route
+		if index == 0 || index >= len(route.Hops) {
+			break
+		}
+		// the failing node couldn't forward over its channel to the next hop of the route
+		from, err := hex.DecodeString(route.Hops[index-1].PubKey)
+		if err != nil {
+			break
+		}
+		next, err := hex.DecodeString(route.Hops[index].PubKey)
+		if err != nil {
+			break
+		}
+		ignored = append(ignored, &lnrpc.NodePair{From: from, To: next})
+	}
+	if resp, err = client.ListChannels(ctx, &lnrpc.ListChannelsRequest{}); err != nil {
+		return err
+	}
+	for _, id := range []uint64{opts.from, opts.to} {
+		if channel, err := findChannelByID(resp.Channels, id); err == nil {
+			fmt.Fprintf(out, "Channel %d: local %d sats, remote %d sats\n", id, channel.LocalBalance, channel.RemoteBalance)
+		}
+	}
+	if !succeeded {
+		return fmt.Errorf("rebalance of %d msat from channel %d to channel %d failed", opts.amountMsat, opts.from, opts.to)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"google.golang.org/grpc"
+)
+
+const (
+	selfPubkey  = "02" + "11111111111111111111111111111111111111111111111111111111111111"
+	peerAPubkey = "02" + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	peerBPubkey = "02" + "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	peerCPubkey = "02" + "cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+// fakeRouterClient answers SendToRouteV2 with the canned HTLC attempts, in order, and streams the canned payment updates from SendPaymentV2
+type fakeRouterClient struct {
+	routerrpc.RouterClient
+	attempts []*lnrpc.HTLCAttempt
+	sent     []*routerrpc.SendToRouteRequest
+	payments []*lnrpc.Payment
+	payReqs  []*routerrpc.SendPaymentRequest
+}
+
+func (f *fakeRouterClient) SendToRouteV2(ctx context.Context, in *routerrpc.SendToRouteRequest, opts ...grpc.CallOption) (*lnrpc.HTLCAttempt, error) {
+	f.sent = append(f.sent, in)
+	attempt := f.attempts[0]
+	f.attempts = f.attempts[1:]
+	return attempt, nil
+}
+
+// circularRoute returns a route from us through the given peers and back to us
+func circularRoute(feeMsat int64, peers ...string) *lnrpc.Route {
+	route := &lnrpc.Route{TotalFeesMsat: feeMsat}
+	for _, peer := range append(peers, selfPubkey) {
+		route.Hops = append(route.Hops, &lnrpc.Hop{PubKey: peer})
+	}
+	return route
+}
+
+// newRebalanceClient returns a fake client with channel 1 to peer A and channel 2 to peer B
+func newRebalanceClient(routes ...*lnrpc.Route) *fakeLightningClient {
+	return &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{IdentityPubkey: selfPubkey},
+		channels: []*lnrpc.Channel{
+			{ChanId: 1, RemotePubkey: peerAPubkey, LocalBalance: 900000, RemoteBalance: 100000},
+			{ChanId: 2, RemotePubkey: peerBPubkey, LocalBalance: 100000, RemoteBalance: 900000},
+		},
+		addedInvoice: &lnrpc.AddInvoiceResponse{RHash: []byte{0x01}, PaymentAddr: []byte{0x02}},
+		routes:       routes,
+	}
+}
+
+// TestChannelRebalanceRetry ensures a failed route is ignored on the next attempt and the payment reaches us through the to channel
+func TestChannelRebalanceRetry(t *testing.T) {
+	client := newRebalanceClient(circularRoute(1500, peerAPubkey, peerCPubkey, peerBPubkey), circularRoute(2500, peerAPubkey, peerBPubkey))
+	router := &fakeRouterClient{attempts: []*lnrpc.HTLCAttempt{
+		{Status: lnrpc.HTLCAttempt_FAILED, Failure: &lnrpc.Failure{Code: lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE, FailureSourceIndex: 2}},
+		{Status: lnrpc.HTLCAttempt_SUCCEEDED},
+	}}
+	var out bytes.Buffer
+	opts := &rebalanceOptions{from: 1, to: 2, amountMsat: 10000000, maxFeePPM: 500, maxAttempts: 3}
+	if err := runChannelRebalance(context.Background(), client, router, opts, &out); err != nil {
+		t.Fatalf("runChannelRebalance returned an error: %v\n%s", err, out.String())
+	}
+	if len(client.routeReqs) != 2 || len(router.sent) != 2 {
+		t.Fatalf("expected 2 attempts, got %d queries and %d payments", len(client.routeReqs), len(router.sent))
+	}
+	req := client.routeReqs[0]
+	if req.PubKey != selfPubkey || req.OutgoingChanId != 1 || hex.EncodeToString(req.LastHopPubkey) != peerBPubkey || req.FeeLimit.GetFixedMsat() != 5000 {
+		t.Errorf("unexpected route query: %v", req)
+	}
+	// node 2 of the first route, peer C, couldn't forward to peer B
+	ignored := client.routeReqs[1].IgnoredPairs
+	if len(ignored) != 1 || hex.EncodeToString(ignored[0].From) != peerCPubkey || hex.EncodeToString(ignored[0].To) != peerBPubkey {
+		t.Errorf("unexpected ignored pairs: %v", ignored)
+	}
+	final := router.sent[1].Route.Hops[2]
+	if !bytes.Equal(final.MppRecord.PaymentAddr, []byte{0x02}) || final.MppRecord.TotalAmtMsat != 10000000 || !bytes.Equal(router.sent[1].PaymentHash, []byte{0x01}) {
+		t.Errorf("final hop doesn't pay our invoice: %v", final)
+	}
+	for _, want := range []string{
+		"Attempt 1: 4 hops, fee 1500 msat (150 ppm): failed: TEMPORARY_CHANNEL_FAILURE at hop 2",
+		"Attempt 2: 3 hops, fee 2500 msat (250 ppm): succeeded",
+		"Channel 1: local 900000 sats, remote 100000 sats",
+		"Channel 2: local 100000 sats, remote 900000 sats",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestChannelRebalanceGivesUp ensures the rebalance fails after the maximum number of attempts or when no route is left
+func TestChannelRebalanceGivesUp(t *testing.T) {
+	failed := &lnrpc.HTLCAttempt{Status: lnrpc.HTLCAttempt_FAILED, Failure: &lnrpc.Failure{Code: lnrpc.Failure_FEE_INSUFFICIENT, FailureSourceIndex: 1}}
+	client := newRebalanceClient(circularRoute(1, peerAPubkey, peerBPubkey), circularRoute(1, peerAPubkey, peerBPubkey), circularRoute(1, peerAPubkey, peerBPubkey))
+	router := &fakeRouterClient{attempts: []*lnrpc.HTLCAttempt{failed, failed, failed}}
+	opts := &rebalanceOptions{from: 1, to: 2, amountMsat: 1000, maxFeePPM: 1000, maxAttempts: 2}
+	var out bytes.Buffer
+	if err := runChannelRebalance(context.Background(), client, router, opts, &out); err == nil || len(router.sent) != 2 {
+		t.Errorf("expected a failure after 2 attempts, got %v after %d", err, len(router.sent))
+	}
+	client = newRebalanceClient()
+	out.Reset()
+	if err := runChannelRebalance(context.Background(), client, &fakeRouterClient{}, opts, &out); err == nil || !strings.Contains(out.String(), "no route found") {
+		t.Errorf("expected a failure without route, got %v:\n%s", err, out.String())
+	}
+	for _, opts := range []*rebalanceOptions{
+		{from: 1, to: 1, amountMsat: 1000, maxAttempts: 1},
+		{from: 1, to: 3, amountMsat: 1000, maxAttempts: 1},
+		{from: 1, to: 2, amountMsat: 0, maxAttempts: 1},
+	} {
+		if err := runChannelRebalance(context.Background(), newRebalanceClient(), &fakeRouterClient{}, opts, &out); err == nil {
+			t.Errorf("expected %+v to be rejected", opts)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const maxFeeRatePPM = 1000000
+
+var routingCommand = cli.Command{
+	Name:  "routing",
+	Usage: "Manage the routing of payments through the node",
+	Subcommands: []cli.Command{
+		routingFeesCommand,
+		routingPathfindCommand,
+	},
+}
+
+var routingFeesCommand = cli.Command{
+	Name:  "fees",
+	Usage: "Manage the channel fee policies",
+	Subcommands: []cli.Command{
+		routingFeesSetCommand,
+	},
+}
+
+var routingFeesSetCommand = cli.Command{
+	Name:  "set",
+	Usage: "Update the fee policy of one or all channels",
+	Description: `
+	Updates the base fee, the fee rate and/or the time lock delta of the channel
+	with the given channel point, or of all channels with --all. Omitted values
+	are kept as they are. --dry-run shows the changes without applying them.`,
+	Flags: []cli.Flag{
+		cli.Int64Flag{
+			Name:  "base-fee",
+			Usage: "the base fee in msat charged for every forward",
+		},
+		cli.Uint64Flag{
+			Name:  "fee-rate",
+			Usage: "the fee rate in ppm of the forwarded amount, at most 1000000",
+		},
+		cli.Uint64Flag{
+			Name:  "time-lock-delta",
+			Usage: "the CLTV delta in blocks required for forwarded HTLCs",
+		},
+		cli.StringFlag{
+			Name:  "channel-point",
+			Usage: "the channel point of the channel to update, in the form txid:output",
+		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "update every open channel",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "show what would be changed without updating the policies",
+		},
+	},
+	Action: routingFeesSet,
+}
+
+// feePolicy is the part of a channel's routing policy set by routing fees set
+type feePolicy struct {
+	baseFeeMsat   int64
+	feeRatePPM    uint32
+	timeLockDelta uint32
+}
+
+// feePolicyOptions are the options of routing fees set. Nil values are kept unchanged
+type feePolicyOptions struct {
+	baseFeeMsat   *int64
+	feeRatePPM    *uint64
+	timeLockDelta *uint32
+	chanPoint     string
+	all           bool
+	dryRun        bool
+}
+
+// apply returns the policy with the values of the options set
+func (o *feePolicyOptions) apply(p feePolicy) feePolicy {
+	if o.baseFeeMsat != nil {
+		p.baseFeeMsat = *o.baseFeeMsat
+	}
+	if o.feeRatePPM != nil {
+		p.feeRatePPM = uint32(*o.feeRatePPM)
+	}
+	if o.timeLockDelta != nil {
+		p.timeLockDelta = *o.timeLockDelta
+	}
+	return p
+}
+
+// routingFeesSet is the action of the routing fees set command
+func routingFeesSet(ctx *cli.Context) error {
+	opts := &feePolicyOptions{
+		chanPoint: ctx.String("channel-point"),
+		all:       ctx.Bool("all"),
+		dryRun:    ctx.Bool("dry-run"),
+	}
+	if ctx.IsSet("base-fee") {
+		baseFee := ctx.Int64("base-fee")
+		opts.baseFeeMsat = &baseFee
+	}
+	if ctx.IsSet("fee-rate") {
+		feeRate := ctx.Uint64("fee-rate")
+		opts.feeRatePPM = &feeRate
+	}
+	if ctx.IsSet("time-lock-delta") {
+		delta := uint32(ctx.Uint64("time-lock-delta"))
+		opts.timeLockDelta = &delta
+	}
+	if opts.chanPoint == "" && !opts.all {
+		return cli.ShowCommandHelp(ctx, "set")
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runRoutingFeesSet(context.Background(), client, opts, os.Stdout)
+}
+
+// currentFeePolicy returns our side of the routing policy of a channel
+func currentFeePolicy(ctx context.Context, client lnrpc.LightningClient, pubkey string, channel *lnrpc.Channel) (feePolicy, error) {
+	edge, err := client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{ChanId: channel.ChanId})
+	if err != nil {
+		return feePolicy{}, err
+	}
+	policy := edge.Node2Policy
+	if edge.Node1Pub == pubkey {
+		policy = edge.Node1Policy
+	}
+	if policy == nil {
+		return feePolicy{}, fmt.Errorf("channel %v has no policy yet", channel.ChannelPoint)
+	}
+	return feePolicy{baseFeeMsat: policy.FeeBaseMsat, feeRatePPM: uint32(policy.FeeRateMilliMsat), timeLockDelta: policy.TimeLockDelta}, nil
+}
+
+// runRoutingFeesSet updates the fee policy of the selected channels and prints which were updated and which failed
+func runRoutingFeesSet(ctx context.Context, client lnrpc.LightningClient, opts *feePolicyOptions, out io.Writer) error {
+	if opts.all == (opts.chanPoint != "") {
+		return fmt.Errorf("expected either --channel-point or --all")
+	}
+	if opts.baseFeeMsat == nil && opts.feeRatePPM == nil && opts.timeLockDelta == nil {
+		return fmt.Errorf("expected at least one of --base-fee, --fee-rate and --time-lock-delta")
+	}
+	if opts.feeRatePPM != nil && *opts.feeRatePPM > maxFeeRatePPM {
+		return fmt.Errorf("--fee-rate must be at most %d ppm, got %d", maxFeeRatePPM, *opts.feeRatePPM)
+	}
+	var channels []*lnrpc.Channel
+	if opts.all {
+		resp, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+		if err != nil {
+			return err
+		}
+		channels = resp.Channels
+	} else {
+		if _, err := parseChannelPoint(opts.chanPoint); err != nil {
+			return err
+		}
+		channel, err := findChannel(ctx, client, opts.chanPoint)
+		if err != nil {
+			return err
+		}
+		channels = []*lnrpc.Channel{channel}
+	}
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	plans := make([]*feePolicyPlan, len(channels))
+	for i, channel := range channels {
+		plans[i] = &feePolicyPlan{channel: channel}
+		if plans[i].current, err = currentFeePolicy(ctx, client, info.IdentityPubkey, channel); err != nil {
+			plans[i].err = err
+			continue
+		}
+		plans[i].updated = opts.apply(plans[i].current)
+	}
+	if !opts.dryRun {
+		if opts.all && opts.baseFeeMsat != nil && opts.feeRatePPM != nil && opts.timeLockDelta != nil {
+			// every channel gets the same policy so a single global update is enough
+			err = updateGlobalFeePolicy(ctx, client, opts.apply(feePolicy{}), plans)
+		} else {
+			for _, plan := range plans {
+				if plan.err == nil {
+					plan.err = updateFeePolicy(ctx, client, plan.channel.ChannelPoint, plan.updated)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return printFeePolicyPlans(plans, opts.dryRun, out)
+}
+
+// feePolicyPlan is the update of the fee policy of one channel
+type feePolicyPlan struct {
+	channel          *lnrpc.Channel
+	current, updated feePolicy
+	err              error
+}
+
+// printFeePolicyPlans prints the old and new policy of every channel and a summary
+func printFeePolicyPlans(plans []*feePolicyPlan, dryRun bool, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL POINT\tBASE FEE (MSAT)\tFEE RATE (PPM)\tTIME LOCK DELTA\tSTATUS")
+	failed := 0
+	for _, plan := range plans {
+		status := "updated"
+		if plan.err != nil {
+			status = fmt.Sprintf("failed: %v", plan.err)
+			failed++
+		} else if dryRun {
+			status = "would update"
+		}
+		c, u := plan.current, plan.updated
+		fmt.Fprintf(w, "%s\t%d -> %d\t%d -> %d\t%d -> %d\t%s\n", plan.channel.ChannelPoint, c.baseFeeMsat, u.baseFeeMsat, c.feeRatePPM, u.feeRatePPM, c.timeLockDelta, u.timeLockDelta, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: %d channels would be updated, %d failed\n", len(plans)-failed, failed)
+		return nil
+	}
+	fmt.Fprintf(out, "%d channels updated, %d failed\n", len(plans)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("could not update the fee policy of %d channels", failed)
+	}
+	return nil
+}
+
+// updateGlobalFeePolicy sets the same fee policy on every channel and reports the failures returned by LND on the matching plans
+func updateGlobalFeePolicy(ctx context.Context, client lnrpc.LightningClient, policy feePolicy, plans []*feePolicyPlan) error {
+	resp, err := client.UpdateChannelPolicy(ctx, &lnrpc.PolicyUpdateRequest{
+		Scope:         &lnrpc.PolicyUpdateRequest_Global{Global: true},
+		BaseFeeMsat:   policy.baseFeeMsat,
+		FeeRatePpm:    policy.feeRatePPM,
+		TimeLockDelta: policy.timeLockDelta,
+	})
+	if err != nil {
+		return err
+	}
+	failures := make(map[string]error, len(resp.FailedUpdates))
+	for _, failure := range resp.FailedUpdates {
+		failures[formatOutPoint(failure.Outpoint)] = fmt.Errorf("%v %v", failure.Reason, failure.UpdateError)
+	}
+	for _, plan := range plans {
+		if err, ok := failures[plan.channel.ChannelPoint]; ok && plan.err == nil {
+			plan.err = err
+		}
+	}
+	return nil
+}
+
+// formatOutPoint returns the txid:output string form of an outpoint
+func formatOutPoint(point *lnrpc.OutPoint) string {
+	if point == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", point.TxidStr, point.OutputIndex)
+}
+
+// updateFeePolicy sets the fee policy of a channel, reporting the failures returned by LND as an error
+func updateFeePolicy(ctx context.Context, client lnrpc.LightningClient, chanPoint string, policy feePolicy) error {
+	point, err := parseChannelPoint(chanPoint)
+	if err != nil {
+		return err
+	}
+	resp, err := client.UpdateChannelPolicy(ctx, &lnrpc.PolicyUpdateRequest{
+		Scope:         &lnrpc.PolicyUpdateRequest_ChanPoint{ChanPoint: point},
+		BaseFeeMsat:   policy.baseFeeMsat,
+		FeeRatePpm:    policy.feeRatePPM,
+		TimeLockDelta: policy.timeLockDelta,
+	})
+	if err != nil {
+		return err
+	}
+	for _, failure := range resp.FailedUpdates {
+		return fmt.Errorf("%v %v", failure.Reason, failure.UpdateError)
+	}
+	return nil
+}
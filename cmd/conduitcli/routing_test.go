@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+const testPubkey = "02aaaa"
+
+// newRoutingClient returns a fake client with three channels whose local policy is 1000 msat, 1 ppm and 40 blocks
+func newRoutingClient() *fakeLightningClient {
+	client := &fakeLightningClient{
+		info:       &lnrpc.GetInfoResponse{IdentityPubkey: testPubkey},
+		chanInfo:   make(map[uint64]*lnrpc.ChannelEdge),
+		policyResp: &lnrpc.PolicyUpdateResponse{},
+	}
+	ours := &lnrpc.RoutingPolicy{FeeBaseMsat: 1000, FeeRateMilliMsat: 1, TimeLockDelta: 40}
+	theirs := &lnrpc.RoutingPolicy{FeeBaseMsat: 0, FeeRateMilliMsat: 500, TimeLockDelta: 144}
+	for i, point := range []string{"aa:0", "bb:1", "cc:2"} {
+		id := uint64(i + 1)
+		client.channels = append(client.channels, &lnrpc.Channel{ChanId: id, ChannelPoint: point})
+		edge := &lnrpc.ChannelEdge{ChannelId: id, ChanPoint: point, Node1Pub: testPubkey, Node1Policy: ours, Node2Pub: "03bbbb", Node2Policy: theirs}
+		// we are node 2 of the second channel
+		if i == 1 {
+			edge.Node1Pub, edge.Node1Policy, edge.Node2Pub, edge.Node2Policy = "03bbbb", theirs, testPubkey, ours
+		}
+		client.chanInfo[id] = edge
+	}
+	return client
+}
+
+func uint32Ptr(v uint32) *uint32 { return &v }
+func uint64Ptr(v uint64) *uint64 { return &v }
+func int64Ptr(v int64) *int64    { return &v }
+
+// TestRoutingFeesSetAll ensures --all with a full policy makes a single global update and reports the failed channels
+func TestRoutingFeesSetAll(t *testing.T) {
+	client := newRoutingClient()
+	client.policyResp = &lnrpc.PolicyUpdateResponse{FailedUpdates: []*lnrpc.FailedUpdate{
+		{Outpoint: &lnrpc.OutPoint{TxidStr: "bb", OutputIndex: 1}, Reason: lnrpc.UpdateFailure_UPDATE_FAILURE_PENDING, UpdateError: "channel is pending"},
+		{Outpoint: &lnrpc.OutPoint{TxidStr: "cc", OutputIndex: 2}, Reason: lnrpc.UpdateFailure_UPDATE_FAILURE_NOT_FOUND, UpdateError: "not found"},
+	}}
+	opts := &feePolicyOptions{baseFeeMsat: int64Ptr(0), feeRatePPM: uint64Ptr(250), timeLockDelta: uint32Ptr(80), all: true}
+	var out bytes.Buffer
+	if err := runRoutingFeesSet(context.Background(), client, opts, &out); err == nil {
+		t.Error("expected an error when some channels failed")
+	}
+	if len(client.policyReqs) != 1 || !client.policyReqs[0].GetGlobal() {
+		t.Fatalf("expected a single global update, got %v", client.policyReqs)
+	}
+	if req := client.policyReqs[0]; req.BaseFeeMsat != 0 || req.FeeRatePpm != 250 || req.TimeLockDelta != 80 {
+		t.Errorf("unexpected policy update: %v", req)
+	}
+	for _, want := range []string{"1 channels updated, 2 failed", "aa:0", "1000 -> 0", "1 -> 250", "40 -> 80", "UPDATE_FAILURE_PENDING channel is pending", "UPDATE_FAILURE_NOT_FOUND not found"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestRoutingFeesSetChannel ensures omitted values keep our current policy, whichever node we are in the edge
+func TestRoutingFeesSetChannel(t *testing.T) {
+	client := newRoutingClient()
+	var out bytes.Buffer
+	opts := &feePolicyOptions{feeRatePPM: uint64Ptr(100), chanPoint: "bb:1"}
+	if err := runRoutingFeesSet(context.Background(), client, opts, &out); err != nil {
+		t.Fatalf("runRoutingFeesSet returned an error: %v", err)
+	}
+	if len(client.policyReqs) != 1 {
+		t.Fatalf("expected one update, got %d", len(client.policyReqs))
+	}
+	req := client.policyReqs[0]
+	if point := req.GetChanPoint(); point.GetFundingTxidStr() != "bb" || point.OutputIndex != 1 {
+		t.Errorf("unexpected scope: %v", req.Scope)
+	}
+	if req.BaseFeeMsat != 1000 || req.FeeRatePpm != 100 || req.TimeLockDelta != 40 {
+		t.Errorf("unexpected policy update: %v", req)
+	}
+	if !strings.Contains(out.String(), "1 channels updated, 0 failed") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+// TestRoutingFeesSetDryRun ensures a dry run doesn't call UpdateChannelPolicy
+func TestRoutingFeesSetDryRun(t *testing.T) {
+	client := newRoutingClient()
+	var out bytes.Buffer
+	opts := &feePolicyOptions{baseFeeMsat: int64Ptr(2000), all: true, dryRun: true}
+	if err := runRoutingFeesSet(context.Background(), client, opts, &out); err != nil {
+		t.Fatalf("runRoutingFeesSet returned an error: %v", err)
+	}
+	if len(client.policyReqs) != 0 {
+		t.Errorf("dry run updated policies: %v", client.policyReqs)
+	}
+	if strings.Count(out.String(), "would update") != 3 || !strings.Contains(out.String(), "1000 -> 2000") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+// TestRoutingFeesSetValidation ensures invalid options are rejected before calling LND
+func TestRoutingFeesSetValidation(t *testing.T) {
+	for name, opts := range map[string]*feePolicyOptions{
+		"fee rate too high": {feeRatePPM: uint64Ptr(1000001), all: true},
+		"no policy":         {all: true},
+		"no channel":        {feeRatePPM: uint64Ptr(1)},
+		"channel and all":   {feeRatePPM: uint64Ptr(1), chanPoint: "aa:0", all: true},
+		"bad channel point": {feeRatePPM: uint64Ptr(1), chanPoint: "aa"},
+	} {
+		client := newRoutingClient()
+		if err := runRoutingFeesSet(context.Background(), client, opts, &bytes.Buffer{}); err == nil || len(client.policyReqs) != 0 {
+			t.Errorf("%s: expected an error without updates, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+const defaultJsonRPCServer = "localhost:10010"
+
+// jsonRPCServerFlag is the global flag pointing to the Conduit JSON-RPC server
+var jsonRPCServerFlag = cli.StringFlag{
+	Name:  "jsonrpcserver",
+	Value: defaultJsonRPCServer,
+	Usage: "host:port of Conduit's JSON-RPC server",
+}
+
+// jsonRPCTLSCertFlag is the global flag pointing to the TLS certificate of the Conduit JSON-RPC server
+var jsonRPCTLSCertFlag = cli.StringFlag{
+	Name:  "jsonrpctlscertpath",
+	Usage: "path to the JsonRPCTLSCertPath certificate of Conduit's JSON-RPC server, which is then reached over HTTPS",
+}
+
+// getConduitClient returns a JSON-RPC client for the running Conduit daemon
+func getConduitClient(ctx *cli.Context) (*jsonrpc.Client, error) {
+	if certPath := ctx.GlobalString("jsonrpctlscertpath"); certPath != "" {
+		return jsonrpc.NewTLSClient(ctx.GlobalString("jsonrpcserver"), certPath)
+	}
+	return jsonrpc.NewClient(ctx.GlobalString("jsonrpcserver"))
+}
+
+// callConduit calls the given method on the running Conduit daemon
+func callConduit(ctx *cli.Context, method string, params interface{}, result interface{}) error {
+	client, err := getConduitClient(ctx)
+	if err != nil {
+		return err
+	}
+	return client.Call(context.Background(), method, params, result)
+}
+
+// isRPCError reports whether the error was returned by the Conduit daemon, as opposed to a connection error
+func isRPCError(err error) bool {
+	var rpcErr *jsonrpc.Error
+	return errors.As(err, &rpcErr)
+}
+
+// isRPCErrorCode reports whether the error was returned by the Conduit daemon with the given code
+func isRPCErrorCode(err error, code jsonrpc.ResponseErrorCode) bool {
+	var rpcErr *jsonrpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
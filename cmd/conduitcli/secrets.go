@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/urfave/cli"
+	"golang.org/x/term"
+)
+
+var secretsCommand = cli.Command{
+	Name:  "secrets",
+	Usage: "Manage the secrets stored outside of config.yaml",
+	Subcommands: []cli.Command{
+		secretsSetCommand,
+	},
+}
+
+var secretsSetCommand = cli.Command{
+	Name:      "set",
+	Usage:     "Store a password field of the config in the OS keychain",
+	ArgsUsage: "field",
+	Description: `
+	Prompts for the value of a password field of the config, i.e. LndBtcdRPCPass,
+	and stores it in the OS keychain. On systems without a keychain, the value is
+	encrypted with CONDUIT_MASTER_KEY in the Conduit metadata store. Stored
+	secrets take precedence over the values of config.yaml.`,
+	Flags: []cli.Flag{
+		conduitDirFlag,
+	},
+	Action: secretsSet,
+}
+
+// secretSetter stores secrets, it's implemented by `core.SecretStore`
+type secretSetter interface {
+	Set(key, value string) error
+}
+
+// secretsSet is the action of the secrets set command
+func secretsSet(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "set")
+	}
+	store := core.NewSecretStore(&core.Config{ConduitDir: ctx.String("conduitdir")})
+	return runSecretsSet(store, ctx.Args().Get(0), readSecret, os.Stdout)
+}
+
+// readSecret reads a value from the terminal without echoing it, or a line from stdin when it's not a terminal
+func readSecret() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		value, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(value), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// runSecretsSet prompts for the value of a masked `core.Config` field and stores it under the field name
+func runSecretsSet(store secretSetter, field string, read func() (string, error), out io.Writer) error {
+	f, ok := reflect.TypeOf(core.Config{}).FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, field)
+	})
+	if !ok {
+		return fmt.Errorf("unknown config field %q", field)
+	}
+	if _, masked := f.Tag.Lookup("default-mask"); !masked || f.Type.Kind() != reflect.String {
+		return fmt.Errorf("%s is not a password field", f.Name)
+	}
+	fmt.Fprintf(out, "Value for %s: ", f.Name)
+	value, err := read()
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("empty value, %s was not stored", f.Name)
+	}
+	if err = store.Set(f.Name, value); err != nil {
+		return fmt.Errorf("could not store %s: %v", f.Name, err)
+	}
+	fmt.Fprintf(out, "%s stored, it takes effect when Conduit restarts\n", f.Name)
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fakeSecretStore records the stored secrets
+type fakeSecretStore map[string]string
+
+func (s fakeSecretStore) Set(key, value string) error {
+	s[key] = value
+	return nil
+}
+
+// TestSecretsSet ensures only password fields are accepted and stored under their field name
+func TestSecretsSet(t *testing.T) {
+	store := fakeSecretStore{}
+	read := func() (string, error) { return "hunter2", nil }
+	var out bytes.Buffer
+	if err := runSecretsSet(store, "lndbtcdrpcpass", read, &out); err != nil {
+		t.Fatalf("runSecretsSet returned an error: %v", err)
+	}
+	if store["LndBtcdRPCPass"] != "hunter2" {
+		t.Errorf("secret not stored under the field name: %v", store)
+	}
+	for _, field := range []string{"LndAlias", "NotAField"} {
+		if err := runSecretsSet(store, field, read, &out); err == nil {
+			t.Errorf("expected %s to be rejected", field)
+		}
+	}
+	if err := runSecretsSet(store, "LndBitcoindRPCPass", func() (string, error) { return "", nil }, &out); err == nil || len(store) != 1 {
+		t.Errorf("expected an empty value to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+var tlsCommand = cli.Command{
+	Name:  "tls",
+	Usage: "Manage the TLS certificates trusted by Conduit",
+	Subcommands: []cli.Command{
+		tlsUnpinCommand,
+	},
+}
+
+var tlsUnpinCommand = cli.Command{
+	Name:  "unpin",
+	Usage: "Unpin LND's TLS certificate once LND renewed it",
+	Description: `
+	Conduit pins the public key of LND's TLS certificate the first time it
+	connects and refuses any other key afterwards. Once LND renewed its
+	certificate, unpin it so that the running daemon trusts and pins the new
+	certificate when it reconnects.`,
+	Action: tlsUnpin,
+}
+
+// tlsUnpin is the action of the tls unpin command
+func tlsUnpin(ctx *cli.Context) error {
+	client, err := getConduitClient(ctx)
+	if err != nil {
+		return err
+	}
+	return runTLSUnpin(context.Background(), client, os.Stdout)
+}
+
+// runTLSUnpin unpins LND's certificate in the daemon and prints its path
+func runTLSUnpin(ctx context.Context, client *jsonrpc.Client, out io.Writer) error {
+	var resp core.TLSUnpinResponse
+	if err := client.Call(ctx, "conduit_tls_unpin", nil, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Unpinned %s, the certificate LND presents next will be pinned\n", resp.Path)
+	return nil
+}
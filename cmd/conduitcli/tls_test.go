@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/core"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+// TestTLSUnpin ensures the unpinned certificate is printed
+func TestTLSUnpin(t *testing.T) {
+	dir := t.TempDir()
+	store, err := core.OpenMetadataStore(path.Join(dir, "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	log := zerolog.Nop()
+	server := core.NewRPCServer(&core.Config{ConduitDir: dir}, &log)
+	server.RegisterTLSPinning(core.NewTLSPinning(store, &log), "/lnd/tls.cert")
+	ts := httptest.NewServer(server.Server)
+	defer ts.Close()
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = runTLSUnpin(context.Background(), client, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Unpinned /lnd/tls.cert") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+const satsPerBTC = 100000000
+
+var walletCommand = cli.Command{
+	Name:  "wallet",
+	Usage: "Inspect the LND wallet",
+	Subcommands: []cli.Command{
+		walletBalanceCommand,
+	},
+}
+
+var walletBalanceCommand = cli.Command{
+	Name:  "balance",
+	Usage: "Show the on-chain and Lightning balances",
+	Description: `
+	Prints the confirmed and unconfirmed on-chain balances, the local and remote
+	balances of open channels and those of channels pending open, in sats and BTC.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the balances as JSON, in sats",
+		},
+	},
+	Action: walletBalance,
+}
+
+// balanceSummary is the on-chain and Lightning balances of the node in sats
+type balanceSummary struct {
+	ConfirmedOnChain   int64 `json:"confirmed_onchain_sat"`
+	UnconfirmedOnChain int64 `json:"unconfirmed_onchain_sat"`
+	TotalOnChain       int64 `json:"total_onchain_sat"`
+	LocalChannel       int64 `json:"local_channel_sat"`
+	RemoteChannel      int64 `json:"remote_channel_sat"`
+	PendingOpenLocal   int64 `json:"pending_open_local_sat"`
+	PendingOpenRemote  int64 `json:"pending_open_remote_sat"`
+}
+
+// walletBalance is the action of the wallet balance command
+func walletBalance(ctx *cli.Context) error {
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runWalletBalance(context.Background(), client, ctx.Bool("json"), os.Stdout)
+}
+
+// getBalanceSummary combines the wallet and channel balances of the node
+func getBalanceSummary(ctx context.Context, client lnrpc.LightningClient) (*balanceSummary, error) {
+	wallet, err := client.WalletBalance(ctx, &lnrpc.WalletBalanceRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get wallet balance: %v", err)
+	}
+	channels, err := client.ChannelBalance(ctx, &lnrpc.ChannelBalanceRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get channel balance: %v", err)
+	}
+	return &balanceSummary{
+		ConfirmedOnChain:   wallet.ConfirmedBalance,
+		UnconfirmedOnChain: wallet.UnconfirmedBalance,
+		TotalOnChain:       wallet.ConfirmedBalance + wallet.UnconfirmedBalance,
+		LocalChannel:       int64(channels.LocalBalance.GetSat()),
+		RemoteChannel:      int64(channels.RemoteBalance.GetSat()),
+		PendingOpenLocal:   int64(channels.PendingOpenLocalBalance.GetSat()),
+		PendingOpenRemote:  int64(channels.PendingOpenRemoteBalance.GetSat()),
+	}, nil
+}
+
+// runWalletBalance prints the balance summary as a table or as JSON
+func runWalletBalance(ctx context.Context, client lnrpc.LightningClient, asJSON bool, out io.Writer) error {
+	summary, err := getBalanceSummary(ctx, client)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(summary)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	rows := []struct {
+		name string
+		sats int64
+	}{
+		{"Confirmed on-chain", summary.ConfirmedOnChain},
+		{"Unconfirmed on-chain", summary.UnconfirmedOnChain},
+		{"Total on-chain", summary.TotalOnChain},
+		{"Local channel balance", summary.LocalChannel},
+		{"Remote channel balance", summary.RemoteChannel},
+		{"Pending open local", summary.PendingOpenLocal},
+		{"Pending open remote", summary.PendingOpenRemote},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s:\t%d sats\t%s BTC\t\n", row.name, row.sats, formatBTC(row.sats))
+	}
+	return w.Flush()
+}
+
+// formatBTC formats an amount of sats in BTC with all 8 decimals
+func formatBTC(sats int64) string {
+	sign := ""
+	if sats < 0 {
+		sign, sats = "-", -sats
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, sats/satsPerBTC, sats%satsPerBTC)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// newBalanceClient returns a fake client with canned wallet and channel balances
+func newBalanceClient() *fakeLightningClient {
+	return &fakeLightningClient{
+		wallet: &lnrpc.WalletBalanceResponse{TotalBalance: 150000000, ConfirmedBalance: 100000000, UnconfirmedBalance: 50000000},
+		chanBalance: &lnrpc.ChannelBalanceResponse{
+			LocalBalance:             &lnrpc.Amount{Sat: 2500000},
+			RemoteBalance:            &lnrpc.Amount{Sat: 1500000},
+			PendingOpenLocalBalance:  &lnrpc.Amount{Sat: 1000},
+			PendingOpenRemoteBalance: nil,
+		},
+	}
+}
+
+// TestWalletBalanceJSON ensures the totals add up and missing amounts are reported as 0
+func TestWalletBalanceJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := runWalletBalance(context.Background(), newBalanceClient(), true, &out); err != nil {
+		t.Fatalf("runWalletBalance returned an error: %v", err)
+	}
+	var summary balanceSummary
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatalf("could not parse output %s: %v", out.String(), err)
+	}
+	if summary.TotalOnChain != summary.ConfirmedOnChain+summary.UnconfirmedOnChain || summary.TotalOnChain != 150000000 {
+		t.Errorf("expected a total on-chain balance of 150000000 sats, got %+v", summary)
+	}
+	want := balanceSummary{100000000, 50000000, 150000000, 2500000, 1500000, 1000, 0}
+	if summary != want {
+		t.Errorf("expected %+v, got %+v", want, summary)
+	}
+}
+
+// TestWalletBalanceTable ensures amounts are shown in sats and BTC
+func TestWalletBalanceTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := runWalletBalance(context.Background(), newBalanceClient(), false, &out); err != nil {
+		t.Fatalf("runWalletBalance returned an error: %v", err)
+	}
+	for _, want := range []string{"150000000 sats", "1.50000000 BTC", "0.02500000 BTC", "0.00001000 BTC"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if got := formatBTC(-123456789); got != "-1.23456789" {
+		t.Errorf("formatBTC(-123456789) = %s", got)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var watchCommand = cli.Command{
+	Name:  "watch",
+	Usage: "Stream events from LND",
+	Subcommands: []cli.Command{
+		watchBlocksCommand,
+		watchInvoicesCommand,
+	},
+}
+
+var watchBlocksCommand = cli.Command{
+	Name:  "blocks",
+	Usage: "Stream new blocks as LND receives them",
+	Description: `
+	Prints the height and hash of every new block LND is notified of. Press
+	Ctrl-C to stop.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "chain",
+			Usage: "the chain to watch, either bitcoin or litecoin. Defaults to the global --chain",
+		},
+	},
+	Action: watchBlocks,
+}
+
+var watchInvoicesCommand = cli.Command{
+	Name:  "invoices",
+	Usage: "Stream invoice updates as LND receives them",
+	Description: `
+	Prints every invoice which is added, settled or cancelled with its memo,
+	amount and payment hash, along with the total amount settled since the
+	command started. Press Ctrl-C to stop.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "settled-only",
+			Usage: "don't print the invoices being added",
+		},
+		cli.Int64Flag{
+			Name:  "amount-min",
+			Usage: "only print the invoices of at least this amount in msat",
+		},
+	},
+	Action: watchInvoices,
+}
+
+// invoiceEventNames are the names of the invoice updates, by invoice state
+var invoiceEventNames = map[lnrpc.Invoice_InvoiceState]string{
+	lnrpc.Invoice_OPEN:     "ADDED",
+	lnrpc.Invoice_SETTLED:  "SETTLED",
+	lnrpc.Invoice_CANCELED: "CANCELLED",
+	lnrpc.Invoice_ACCEPTED: "ACCEPTED",
+}
+
+// watchBlocks is the action of the watch blocks command
+func watchBlocks(ctx *cli.Context) error {
+	if ctx.IsSet("chain") {
+		chain := ctx.String("chain")
+		if chain != "bitcoin" && chain != "litecoin" {
+			return fmt.Errorf("invalid chain %v, expected bitcoin or litecoin", chain)
+		}
+		if err := ctx.GlobalSet("chain", chain); err != nil {
+			return err
+		}
+	}
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return runWatchBlocks(sigCtx, chainrpc.NewChainNotifierClient(conn), os.Stdout, time.Now)
+}
+
+// runWatchBlocks prints every block epoch received until the context is cancelled or the stream ends
+func runWatchBlocks(ctx context.Context, client chainrpc.ChainNotifierClient, out io.Writer, now func() time.Time) error {
+	stream, err := client.RegisterBlockEpochNtfn(ctx, &chainrpc.BlockEpoch{})
+	if err != nil {
+		return err
+	}
+	for {
+		epoch, err := stream.Recv()
+		if err == io.EOF || status.Code(err) == codes.Canceled || ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		hash, err := chainhash.NewHash(epoch.Hash)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Block #%d %v (%s)\n", epoch.Height, hash, now().Format(time.RFC3339))
+	}
+}
+
+// watchInvoices is the action of the watch invoices command
+func watchInvoices(ctx *cli.Context) error {
+	if ctx.Int64("amount-min") < 0 {
+		return fmt.Errorf("invalid minimum amount %d msat", ctx.Int64("amount-min"))
+	}
+	client, cleanUp, err := getLightningClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return runWatchInvoices(sigCtx, client, ctx.Bool("settled-only"), ctx.Int64("amount-min"), os.Stdout)
+}
+
+// runWatchInvoices prints every invoice update of at least minMsat received until the context is cancelled or the stream ends,
+// skipping the added invoices if settledOnly is set
+func runWatchInvoices(ctx context.Context, client lnrpc.LightningClient, settledOnly bool, minMsat int64, out io.Writer) error {
+	// cancelling the context closes the stream
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.SubscribeInvoices(ctx, &lnrpc.InvoiceSubscription{})
+	if err != nil {
+		return err
+	}
+	var totalMsat int64
+	for {
+		invoice, err := stream.Recv()
+		if err == io.EOF || status.Code(err) == codes.Canceled || ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if settledOnly && invoice.State == lnrpc.Invoice_OPEN {
+			continue
+		}
+		// a settled invoice may have been paid more than requested
+		amountMsat := invoice.ValueMsat
+		if invoice.State == lnrpc.Invoice_SETTLED {
+			amountMsat = invoice.AmtPaidMsat
+		}
+		if amountMsat < minMsat {
+			continue
+		}
+		name, ok := invoiceEventNames[invoice.State]
+		if !ok {
+			name = invoice.State.String()
+		}
+		fmt.Fprintf(out, "%-9s %d msat %x %q", name, amountMsat, invoice.RHash, invoice.Memo)
+		if invoice.State == lnrpc.Invoice_SETTLED {
+			totalMsat += amountMsat
+			fmt.Fprintf(out, " (total settled %d msat)", totalMsat)
+		}
+		fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeChainNotifierClient streams the given block epochs
+type fakeChainNotifierClient struct {
+	chainrpc.ChainNotifierClient
+	epochs []*chainrpc.BlockEpoch
+}
+
+func (f *fakeChainNotifierClient) RegisterBlockEpochNtfn(ctx context.Context, in *chainrpc.BlockEpoch, opts ...grpc.CallOption) (chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient, error) {
+	return &fakeStream[chainrpc.BlockEpoch]{msgs: f.epochs}, nil
+}
+
+// TestRunWatchBlocks verifies the printed output for a 3 block sequence
+func TestRunWatchBlocks(t *testing.T) {
+	client := &fakeChainNotifierClient{}
+	for i := 0; i < 3; i++ {
+		hash := make([]byte, 32)
+		hash[0] = byte(i + 1)
+		client.epochs = append(client.epochs, &chainrpc.BlockEpoch{Height: uint32(700000 + i), Hash: hash})
+	}
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	if err := runWatchBlocks(context.Background(), client, &out, func() time.Time { return ts }); err != nil {
+		t.Fatalf("runWatchBlocks returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %v", len(lines), lines)
+	}
+	expected := "Block #700000 0000000000000000000000000000000000000000000000000000000000000001 (2022-01-01T00:00:00Z)"
+	if lines[0] != expected {
+		t.Errorf("unexpected output.\nExpected: %s\nReceived: %s", expected, lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "Block #700002 ") {
+		t.Errorf("unexpected output: %s", lines[2])
+	}
+}
+
+// fakeInvoiceClient streams the given invoice updates, then blocks until the stream is closed if block is set
+type fakeInvoiceClient struct {
+	lnrpc.LightningClient
+	invoices []*lnrpc.Invoice
+	block    bool
+}
+
+// blockingInvoiceStream returns its invoices, then blocks until its context is cancelled like a gRPC stream
+type blockingInvoiceStream struct {
+	fakeStream[lnrpc.Invoice]
+	ctx context.Context
+}
+
+func (s *blockingInvoiceStream) Recv() (*lnrpc.Invoice, error) {
+	if len(s.msgs) > 0 {
+		return s.fakeStream.Recv()
+	}
+	<-s.ctx.Done()
+	return nil, status.Error(codes.Canceled, "context canceled")
+}
+
+func (f *fakeInvoiceClient) SubscribeInvoices(ctx context.Context, in *lnrpc.InvoiceSubscription, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeInvoicesClient, error) {
+	if f.block {
+		return &blockingInvoiceStream{fakeStream: fakeStream[lnrpc.Invoice]{msgs: f.invoices}, ctx: ctx}, nil
+	}
+	return &fakeStream[lnrpc.Invoice]{msgs: f.invoices}, nil
+}
+
+// invoiceEvents returns an invoice being added, the same invoice being overpaid and another one being cancelled
+func invoiceEvents() []*lnrpc.Invoice {
+	return []*lnrpc.Invoice{
+		{Memo: "coffee", RHash: []byte{0x01, 0x02}, ValueMsat: 15000, State: lnrpc.Invoice_OPEN},
+		{Memo: "coffee", RHash: []byte{0x01, 0x02}, ValueMsat: 15000, AmtPaidMsat: 16000, State: lnrpc.Invoice_SETTLED},
+		{Memo: "tea", RHash: []byte{0x03}, ValueMsat: 2000, State: lnrpc.Invoice_CANCELED},
+	}
+}
+
+// TestRunWatchInvoices verifies the printed output for 3 invoice updates, and that the filters are applied client-side
+func TestRunWatchInvoices(t *testing.T) {
+	client := &fakeInvoiceClient{invoices: invoiceEvents()}
+	var out bytes.Buffer
+	if err := runWatchInvoices(context.Background(), client, false, 0, &out); err != nil {
+		t.Fatalf("runWatchInvoices returned an error: %v", err)
+	}
+	expected := `ADDED     15000 msat 0102 "coffee"
+SETTLED   16000 msat 0102 "coffee" (total settled 16000 msat)
+CANCELLED 2000 msat 03 "tea"
+`
+	if out.String() != expected {
+		t.Errorf("unexpected output.\nExpected: %s\nReceived: %s", expected, out.String())
+	}
+	out.Reset()
+	client.invoices = append(invoiceEvents(), &lnrpc.Invoice{Memo: "cake", RHash: []byte{0x04}, ValueMsat: 20000, AmtPaidMsat: 20000, State: lnrpc.Invoice_SETTLED})
+	if err := runWatchInvoices(context.Background(), client, true, 10000, &out); err != nil {
+		t.Fatalf("runWatchInvoices returned an error: %v", err)
+	}
+	expected = `SETTLED   16000 msat 0102 "coffee" (total settled 16000 msat)
+SETTLED   20000 msat 04 "cake" (total settled 36000 msat)
+`
+	if out.String() != expected {
+		t.Errorf("unexpected filtered output.\nExpected: %s\nReceived: %s", expected, out.String())
+	}
+}
+
+// TestRunWatchInvoicesInterrupt ensures cancelling the context, as Ctrl-C does, closes the stream and exits without an error
+func TestRunWatchInvoicesInterrupt(t *testing.T) {
+	client := &fakeInvoiceClient{invoices: invoiceEvents()[:1], block: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- runWatchInvoices(ctx, client, false, 0, &out)
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean exit, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runWatchInvoices didn't return once cancelled")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lightningnetwork/lnd/lnrpc/watchtowerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/wtclientrpc"
+	"github.com/urfave/cli"
+)
+
+var watchtowerCommand = cli.Command{
+	Name:  "watchtower",
+	Usage: "Manage the watchtowers backing up LND's channel states",
+	Description: `
+	Manages the towers of LND's watchtower client. LND must be built with the
+	wtclientrpc build tag and started with LndWtClientActive set. The status
+	subcommand shows LND's own watchtower server instead.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "add",
+			Usage:     "Register a watchtower",
+			ArgsUsage: "pubkey@host:port",
+			Action:    watchtowerAdd,
+		},
+		{
+			Name:   "list",
+			Usage:  "List the registered watchtowers",
+			Action: watchtowerList,
+		},
+		{
+			Name:      "remove",
+			Usage:     "Stop using a watchtower for new backups",
+			ArgsUsage: "pubkey",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "yes",
+					Usage: "confirm the removal",
+				},
+			},
+			Action: watchtowerRemove,
+		},
+		{
+			Name:  "status",
+			Usage: "Show the watchtower server of LND",
+			Description: `
+	Prints the pubkey and URIs of LND's watchtower server, whether it's active
+	according to config.yaml and the sessions acquired and channel states backed
+	up by LND's watchtower client. The watchtower server doesn't report the
+	sessions of its own clients. LND must be built with the watchtowerrpc build
+	tag.`,
+			Flags: []cli.Flag{
+				conduitDirFlag,
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "print the status as JSON",
+				},
+			},
+			Action: watchtowerStatus,
+		},
+	},
+}
+
+// towerStatus is the status printed by the watchtower status command.
+// Sessions and Backups are nil when LND's watchtower client isn't active
+type towerStatus struct {
+	Active    bool     `json:"active"`
+	Pubkey    string   `json:"pubkey"`
+	Listeners []string `json:"listeners"`
+	URIs      []string `json:"uris"`
+	Sessions  *uint32  `json:"sessions"`
+	Backups   *uint32  `json:"backups"`
+}
+
+// watchtowerAdd is the action of the watchtower add command
+func watchtowerAdd(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "add")
+	}
+	client, cleanUp, err := getWatchtowerClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runWatchtowerAdd(context.Background(), client, ctx.Args().First(), os.Stdout)
+}
+
+// watchtowerList is the action of the watchtower list command
+func watchtowerList(ctx *cli.Context) error {
+	client, cleanUp, err := getWatchtowerClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runWatchtowerList(context.Background(), client, os.Stdout)
+}
+
+// watchtowerRemove is the action of the watchtower remove command
+func watchtowerRemove(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, "remove")
+	}
+	client, cleanUp, err := getWatchtowerClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanUp()
+	return runWatchtowerRemove(context.Background(), client, ctx.Args().First(), ctx.Bool("yes"), os.Stdout)
+}
+
+// watchtowerStatus is the action of the watchtower status command
+func watchtowerStatus(ctx *cli.Context) error {
+	conn, err := getClientConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	active := loadCLIConfig(ctx.String("conduitdir")).LndWatchtowerActive
+	return runWatchtowerStatus(context.Background(), watchtowerrpc.NewWatchtowerClient(conn), wtclientrpc.NewWatchtowerClientClient(conn), active, ctx.Bool("json"), os.Stdout)
+}
+
+// runWatchtowerStatus prints the info of the watchtower server along with the stats of the watchtower client, if it's active
+func runWatchtowerStatus(ctx context.Context, tower watchtowerrpc.WatchtowerClient, client wtclientrpc.WatchtowerClientClient, active, asJSON bool, out io.Writer) error {
+	if !active && asJSON {
+		return printTowerStatusJSON(map[string]bool{"active": false}, out)
+	} else if !active {
+		fmt.Fprintln(out, "The watchtower server is not active. Set LndWatchtowerActive in config.yaml or start LND with --watchtower.active")
+		return nil
+	}
+	info, err := tower.GetInfo(ctx, &watchtowerrpc.GetInfoRequest{})
+	if err != nil {
+		return fmt.Errorf("could not get watchtower info: %v", err)
+	}
+	status := &towerStatus{
+		Active:    active,
+		Pubkey:    hex.EncodeToString(info.Pubkey),
+		Listeners: info.Listeners,
+		URIs:      info.Uris,
+	}
+	if stats, err := client.Stats(ctx, &wtclientrpc.StatsRequest{}); err == nil {
+		status.Sessions, status.Backups = &stats.NumSessionsAcquired, &stats.NumBackups
+	}
+	if asJSON {
+		return printTowerStatusJSON(status, out)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Active:\t%t\n", status.Active)
+	fmt.Fprintf(w, "Pubkey:\t%s\n", status.Pubkey)
+	fmt.Fprintf(w, "Listeners:\t%s\n", strings.Join(status.Listeners, ", "))
+	fmt.Fprintf(w, "URIs:\t%s\n", strings.Join(status.URIs, ", "))
+	if status.Sessions != nil {
+		fmt.Fprintf(w, "Sessions:\t%d\n", *status.Sessions)
+		fmt.Fprintf(w, "Backed up channel states:\t%d\n", *status.Backups)
+	} else {
+		fmt.Fprintln(w, "Sessions:\tunavailable, the watchtower client is not active (LndWtClientActive)")
+	}
+	return w.Flush()
+}
+
+// printTowerStatusJSON prints the status of the watchtower as indented JSON
+func printTowerStatusJSON(status interface{}, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "    ")
+	return enc.Encode(status)
+}
+
+// runWatchtowerAdd registers the watchtower of a pubkey@host:port URI
+func runWatchtowerAdd(ctx context.Context, client wtclientrpc.WatchtowerClientClient, uri string, out io.Writer) error {
+	split := strings.Split(uri, "@")
+	if len(split) != 2 || split[1] == "" {
+		return fmt.Errorf("expected watchtower URI of the form pubkey@host:port, got %v", uri)
+	}
+	pubkey, err := parsePubkey(split[0])
+	if err != nil {
+		return err
+	}
+	if _, err = client.AddTower(ctx, &wtclientrpc.AddTowerRequest{Pubkey: pubkey, Address: split[1]}); err != nil {
+		return fmt.Errorf("could not add watchtower: %v", err)
+	}
+	fmt.Fprintf(out, "Watchtower %s added\n", uri)
+	return nil
+}
+
+// runWatchtowerList prints the registered watchtowers with their sessions and backups.
+// LND doesn't record when the last backup was made, so the acknowledged and pending backups are shown instead
+func runWatchtowerList(ctx context.Context, client wtclientrpc.WatchtowerClientClient, out io.Writer) error {
+	resp, err := client.ListTowers(ctx, &wtclientrpc.ListTowersRequest{IncludeSessions: true})
+	if err != nil {
+		return fmt.Errorf("could not list watchtowers: %v", err)
+	}
+	if len(resp.Towers) == 0 {
+		fmt.Fprintln(out, "No watchtowers registered")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PUBKEY\tADDRESS\tSESSIONS\tBACKUPS\tPENDING\tACTIVE")
+	for _, tower := range resp.Towers {
+		var backups, pending uint32
+		for _, session := range tower.Sessions {
+			backups += session.NumBackups
+			pending += session.NumPendingBackups
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%t\n", hex.EncodeToString(tower.Pubkey), strings.Join(tower.Addresses, ","), tower.NumSessions, backups, pending, tower.ActiveSessionCandidate)
+	}
+	return w.Flush()
+}
+
+// runWatchtowerRemove removes a watchtower once confirmed with --yes
+func runWatchtowerRemove(ctx context.Context, client wtclientrpc.WatchtowerClientClient, pubkeyHex string, yes bool, out io.Writer) error {
+	pubkey, err := parsePubkey(pubkeyHex)
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return fmt.Errorf("removing watchtower %s stops new backups to it, pass --yes to confirm", pubkeyHex)
+	}
+	if _, err = client.RemoveTower(ctx, &wtclientrpc.RemoveTowerRequest{Pubkey: pubkey}); err != nil {
+		return fmt.Errorf("could not remove watchtower: %v", err)
+	}
+	fmt.Fprintf(out, "Watchtower %s removed\n", pubkeyHex)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc/watchtowerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/wtclientrpc"
+	"google.golang.org/grpc"
+)
+
+// fakeWatchtowerClient is a `wtclientrpc.WatchtowerClientClient` keeping the registered towers in memory
+type fakeWatchtowerClient struct {
+	wtclientrpc.WatchtowerClientClient
+	towers  []*wtclientrpc.Tower
+	removed [][]byte
+	stats   *wtclientrpc.StatsResponse
+}
+
+func (f *fakeWatchtowerClient) AddTower(ctx context.Context, in *wtclientrpc.AddTowerRequest, opts ...grpc.CallOption) (*wtclientrpc.AddTowerResponse, error) {
+	f.towers = append(f.towers, &wtclientrpc.Tower{Pubkey: in.Pubkey, Addresses: []string{in.Address}, ActiveSessionCandidate: true})
+	return &wtclientrpc.AddTowerResponse{}, nil
+}
+
+func (f *fakeWatchtowerClient) ListTowers(ctx context.Context, in *wtclientrpc.ListTowersRequest, opts ...grpc.CallOption) (*wtclientrpc.ListTowersResponse, error) {
+	return &wtclientrpc.ListTowersResponse{Towers: f.towers}, nil
+}
+
+func (f *fakeWatchtowerClient) RemoveTower(ctx context.Context, in *wtclientrpc.RemoveTowerRequest, opts ...grpc.CallOption) (*wtclientrpc.RemoveTowerResponse, error) {
+	f.removed = append(f.removed, in.Pubkey)
+	return &wtclientrpc.RemoveTowerResponse{}, nil
+}
+
+func (f *fakeWatchtowerClient) Stats(ctx context.Context, in *wtclientrpc.StatsRequest, opts ...grpc.CallOption) (*wtclientrpc.StatsResponse, error) {
+	if f.stats == nil {
+		return nil, fmt.Errorf("watchtower client not active")
+	}
+	return f.stats, nil
+}
+
+// fakeTowerServer is a `watchtowerrpc.WatchtowerClient` returning fixed info
+type fakeTowerServer struct {
+	watchtowerrpc.WatchtowerClient
+	info *watchtowerrpc.GetInfoResponse
+}
+
+func (f *fakeTowerServer) GetInfo(ctx context.Context, in *watchtowerrpc.GetInfoRequest, opts ...grpc.CallOption) (*watchtowerrpc.GetInfoResponse, error) {
+	return f.info, nil
+}
+
+var testTowerPubkey = "02" + strings.Repeat("ab", 32)
+
+// TestWatchtowerAddList ensures added towers are listed with their sessions and backups
+func TestWatchtowerAddList(t *testing.T) {
+	client := &fakeWatchtowerClient{}
+	var out bytes.Buffer
+	if err := runWatchtowerAdd(context.Background(), client, testTowerPubkey+"@tower.example.com:9911", &out); err != nil {
+		t.Fatalf("runWatchtowerAdd returned an error: %v", err)
+	}
+	if len(client.towers) != 1 || hex.EncodeToString(client.towers[0].Pubkey) != testTowerPubkey || client.towers[0].Addresses[0] != "tower.example.com:9911" {
+		t.Fatalf("unexpected towers: %v", client.towers)
+	}
+	client.towers[0].NumSessions = 2
+	client.towers[0].Sessions = []*wtclientrpc.TowerSession{{NumBackups: 10, NumPendingBackups: 1}, {NumBackups: 5}}
+	out.Reset()
+	if err := runWatchtowerList(context.Background(), client, &out); err != nil {
+		t.Fatalf("runWatchtowerList returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and a row, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != testTowerPubkey+" tower.example.com:9911 2 15 1 true" {
+		t.Errorf("unexpected row: %q", lines[1])
+	}
+}
+
+// TestWatchtowerAddInvalidURI ensures malformed URIs are rejected before calling LND
+func TestWatchtowerAddInvalidURI(t *testing.T) {
+	client := &fakeWatchtowerClient{}
+	for _, uri := range []string{"tower.example.com:9911", testTowerPubkey + "@", "abcd@tower.example.com:9911"} {
+		if err := runWatchtowerAdd(context.Background(), client, uri, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected an error for %q", uri)
+		}
+	}
+	if len(client.towers) != 0 {
+		t.Errorf("unexpected towers: %v", client.towers)
+	}
+}
+
+// TestWatchtowerRemove ensures towers are only removed with --yes
+func TestWatchtowerRemove(t *testing.T) {
+	client := &fakeWatchtowerClient{}
+	var out bytes.Buffer
+	if err := runWatchtowerRemove(context.Background(), client, testTowerPubkey, false, &out); err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("expected a confirmation error, got %v", err)
+	}
+	if len(client.removed) != 0 {
+		t.Fatalf("tower removed without confirmation")
+	}
+	if err := runWatchtowerRemove(context.Background(), client, testTowerPubkey, true, &out); err != nil {
+		t.Fatalf("runWatchtowerRemove returned an error: %v", err)
+	}
+	if len(client.removed) != 1 || hex.EncodeToString(client.removed[0]) != testTowerPubkey {
+		t.Errorf("unexpected removed towers: %v", client.removed)
+	}
+}
+
+// TestWatchtowerStatus ensures the tower info is printed with the client stats, and the flag to activate the tower is suggested when it's not active
+func TestWatchtowerStatus(t *testing.T) {
+	pubkey, _ := hex.DecodeString(testTowerPubkey)
+	tower := &fakeTowerServer{info: &watchtowerrpc.GetInfoResponse{
+		Pubkey:    pubkey,
+		Listeners: []string{"0.0.0.0:9911"},
+		Uris:      []string{testTowerPubkey + "@tower.example.com:9911"},
+	}}
+	client := &fakeWatchtowerClient{stats: &wtclientrpc.StatsResponse{NumSessionsAcquired: 3, NumBackups: 42}}
+	var out bytes.Buffer
+	if err := runWatchtowerStatus(context.Background(), tower, client, true, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Active:                    true", "Pubkey:                    " + testTowerPubkey, "@tower.example.com:9911", "Sessions:                  3", "Backed up channel states:  42"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	client.stats = nil
+	if err := runWatchtowerStatus(context.Background(), tower, client, true, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	var status towerStatus
+	if err := json.Unmarshal(out.Bytes(), &status); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if !status.Active || status.Pubkey != testTowerPubkey || len(status.URIs) != 1 || status.Sessions != nil || status.Backups != nil {
+		t.Errorf("unexpected status: %s", out.String())
+	}
+
+	out.Reset()
+	if err := runWatchtowerStatus(context.Background(), tower, client, false, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "--watchtower.active") {
+		t.Errorf("expected the --watchtower.active flag to be suggested, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runWatchtowerStatus(context.Background(), tower, client, false, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	var inactive map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &inactive); err != nil || len(inactive) != 1 || inactive["active"] != false {
+		t.Errorf(`expected {"active":false}, got %s: %v`, out.String(), err)
+	}
+}
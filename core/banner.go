@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+const banner_pubkey_chars = 8
+
+// StartupBanner prints an at-a-glance summary of the node once LND is ready
+type StartupBanner struct {
+	client lnrpc.LightningClient
+	out    io.Writer
+}
+
+// NewStartupBanner creates a StartupBanner writing to out
+func NewStartupBanner(client lnrpc.LightningClient, out io.Writer) *StartupBanner {
+	return &StartupBanner{client: client, out: out}
+}
+
+// truncatePubkey shortens a pubkey to its first and last few characters
+func truncatePubkey(pubkey string) string {
+	if len(pubkey) <= 2*banner_pubkey_chars {
+		return pubkey
+	}
+	return pubkey[:banner_pubkey_chars] + "..." + pubkey[len(pubkey)-banner_pubkey_chars:]
+}
+
+// Print fetches the node info from LND and prints the banner
+func (b *StartupBanner) Print(ctx context.Context) error {
+	info, err := b.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	channels, err := b.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	var capacity int64
+	for _, channel := range channels.Channels {
+		capacity += channel.Capacity
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(b.out)
+	t.SetTitle(fmt.Sprintf("%s %s", utils.AppName, utils.AppVersion))
+	t.AppendRows([]table.Row{
+		{"Alias", info.Alias},
+		{"Pubkey", truncatePubkey(info.IdentityPubkey)},
+		{"Channels", fmt.Sprintf("%d active, %d inactive, %d pending", info.NumActiveChannels, info.NumInactiveChannels, info.NumPendingChannels)},
+		{"Capacity", fmt.Sprintf("%d sats", capacity)},
+		{"Synced to chain", info.SyncedToChain},
+	})
+	t.Render()
+	return nil
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// TestStartupBanner ensures the banner contains the node's alias, truncated pubkey and total capacity
+func TestStartupBanner(t *testing.T) {
+	client := &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{
+			Alias:             "conduit-test-node",
+			IdentityPubkey:    "02b6a1f1e3d5c7a9b0c2d4e6f8a0b2c4d6e8f0a2b4c6d8e0f2a4b6c8d0e2f4a6b8",
+			NumActiveChannels: 2,
+			SyncedToChain:     true,
+		},
+		channels: []*lnrpc.Channel{{Capacity: 100000}, {Capacity: 250000}},
+	}
+	var out bytes.Buffer
+	if err := NewStartupBanner(client, &out).Print(context.Background()); err != nil {
+		t.Fatalf("Print returned an error: %v", err)
+	}
+	for _, want := range []string{"conduit-test-node", "02b6a1f1...e2f4a6b8", "350000 sats", "2 active"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("banner is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), client.info.IdentityPubkey) {
+		t.Errorf("banner contains the full pubkey:\n%s", out.String())
+	}
+}
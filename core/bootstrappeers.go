@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	bootstrap_peers_metadata_key = "bootstrap_peers"
+	bootstrap_peer_count         = 10
+	bootstrap_lookup_timeout     = 10 * time.Second
+	bootstrap_connect_timeout    = 30
+)
+
+// bitcoin_mainnet_dns_seeds are LND's default mainnet DNS seeds
+var bitcoin_mainnet_dns_seeds = []string{"nodes.lightning.directory", "lseed.bitcoinstats.com"}
+
+// dnsResolver is the subset of `net.Resolver` used to query the DNS seeds
+type dnsResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// BootstrapPeer is a node returned by a DNS seed
+type BootstrapPeer struct {
+	Pubkey string `json:"pubkey"`
+	Host   string `json:"host"`
+}
+
+// String returns the pubkey@host form of the peer
+func (p BootstrapPeer) String() string {
+	return fmt.Sprintf("%s@%s", p.Pubkey, p.Host)
+}
+
+// BootstrapPeerList resolves LND's DNS seeds before LND starts so that LND can be connected to peers as soon as it's up.
+// The peers are cached in the MetadataStore and reused when the seeds can't be reached
+type BootstrapPeerList struct {
+	seeds    []string
+	store    *MetadataStore
+	resolver dnsResolver
+	log      *subLogger
+}
+
+// NewBootstrapPeerList creates a BootstrapPeerList for the configured DNS seeds, or LND's default ones on mainnet. No seed is queried when network bootstrapping is disabled
+func NewBootstrapPeerList(cfg *Config, store *MetadataStore, log *zerolog.Logger) *BootstrapPeerList {
+	var seeds []string
+	if !cfg.LndNoNetBootstrap {
+		for _, tuple := range cfg.LndBitcoinDNSSeeds {
+			// the SOA server of the <primary_dns>[,<soa_primary_dns>] tuple is only needed over Tor
+			seeds = append(seeds, strings.Split(tuple, ",")[0])
+		}
+		if len(seeds) == 0 && cfg.LndBitcoinMainNet {
+			seeds = bitcoin_mainnet_dns_seeds
+		}
+	}
+	return &BootstrapPeerList{
+		seeds:    seeds,
+		store:    store,
+		resolver: net.DefaultResolver,
+		log:      NewSubLogger(log, "BOOT"),
+	}
+}
+
+// decodeSeedTarget returns the hex pubkey encoded in bech32 in the first label of a DNS seed SRV target
+func decodeSeedTarget(target string) (string, error) {
+	label := strings.Split(target, ".")[0]
+	_, words, err := bech32.Decode(label)
+	if err != nil {
+		return "", err
+	}
+	pubkey, err := bech32.ConvertBits(words, 5, 8, false)
+	if err != nil {
+		return "", err
+	}
+	if len(pubkey) != 33 {
+		return "", fmt.Errorf("invalid pubkey length %d in %v", len(pubkey), target)
+	}
+	return hex.EncodeToString(pubkey), nil
+}
+
+// resolve queries the DNS seeds for up to bootstrap_peer_count peers
+func (b *BootstrapPeerList) resolve(ctx context.Context) ([]BootstrapPeer, error) {
+	ctx, cancel := context.WithTimeout(ctx, bootstrap_lookup_timeout)
+	defer cancel()
+	var (
+		peers   []BootstrapPeer
+		lastErr error
+	)
+	for _, seed := range b.seeds {
+		_, records, err := b.resolver.LookupSRV(ctx, "nodes", "tcp", seed)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, record := range records {
+			if len(peers) >= bootstrap_peer_count {
+				return peers, nil
+			}
+			pubkey, err := decodeSeedTarget(record.Target)
+			if err != nil {
+				b.log.SubLogger.Debug().Msg(fmt.Sprintf("skipping DNS seed record %v: %v", record.Target, err))
+				continue
+			}
+			addrs, err := b.resolver.LookupHost(ctx, record.Target)
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+			peers = append(peers, BootstrapPeer{Pubkey: pubkey, Host: net.JoinHostPort(addrs[0], strconv.Itoa(int(record.Port)))})
+		}
+	}
+	if len(peers) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return peers, nil
+}
+
+// Peers resolves the DNS seeds and caches the peers. If no peer can be resolved, the peers cached by a previous run are returned
+func (b *BootstrapPeerList) Peers(ctx context.Context) []BootstrapPeer {
+	if len(b.seeds) == 0 {
+		return nil
+	}
+	peers, err := b.resolve(ctx)
+	if err == nil && len(peers) > 0 {
+		if raw, err := json.Marshal(peers); err == nil {
+			if err = b.store.Put(bootstrap_peers_metadata_key, raw); err != nil {
+				b.log.SubLogger.Warn().Msg(fmt.Sprintf("could not cache bootstrap peers: %v", err))
+			}
+		}
+		b.log.SubLogger.Info().Msg(fmt.Sprintf("Resolved %d bootstrap peers", len(peers)))
+		return peers
+	}
+	b.log.SubLogger.Warn().Msg(fmt.Sprintf("could not resolve DNS seeds, using cached bootstrap peers: %v", err))
+	raw, err := b.store.Get(bootstrap_peers_metadata_key)
+	if err != nil {
+		return nil
+	}
+	var cached []BootstrapPeer
+	if err = json.Unmarshal(raw, &cached); err != nil {
+		return nil
+	}
+	return cached
+}
+
+// Connect connects LND to every peer, logging the failures
+func (b *BootstrapPeerList) Connect(ctx context.Context, client lnrpc.LightningClient, peers []BootstrapPeer) {
+	connected := 0
+	for _, peer := range peers {
+		_, err := client.ConnectPeer(ctx, &lnrpc.ConnectPeerRequest{
+			Addr:    &lnrpc.LightningAddress{Pubkey: peer.Pubkey, Host: peer.Host},
+			Timeout: bootstrap_connect_timeout,
+		})
+		if err != nil {
+			b.log.SubLogger.Debug().Msg(fmt.Sprintf("could not connect to bootstrap peer %v: %v", peer, err))
+			continue
+		}
+		connected++
+	}
+	b.log.SubLogger.Info().Msg(fmt.Sprintf("Connected to %d of %d bootstrap peers", connected, len(peers)))
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+)
+
+// fakeResolver answers the SRV and host lookups of the DNS seeds from maps. When down, every lookup fails
+type fakeResolver struct {
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+	down  bool
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if r.down {
+		return "", nil, fmt.Errorf("lookup _%s._%s.%s: no such host", service, proto, name)
+	}
+	return "", r.srv[name], nil
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.down {
+		return nil, fmt.Errorf("lookup %s: no such host", host)
+	}
+	return r.hosts[host], nil
+}
+
+// seedTarget returns the SRV target a DNS seed returns for the given pubkey
+func seedTarget(t *testing.T, pubkey []byte, seed string) string {
+	t.Helper()
+	words, err := bech32.ConvertBits(pubkey, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label, err := bech32.Encode("ln", words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return label + "." + seed + "."
+}
+
+// testPubkey returns a 33 bytes compressed pubkey filled with b
+func testPubkey(b byte) []byte {
+	pubkey := make([]byte, 33)
+	pubkey[0] = 0x02
+	for i := 1; i < len(pubkey); i++ {
+		pubkey[i] = b
+	}
+	return pubkey
+}
+
+// newTestBootstrapPeerList returns a BootstrapPeerList over a temporary store and a fake resolver with two peers
+func newTestBootstrapPeerList(t *testing.T, resolver *fakeResolver) *BootstrapPeerList {
+	t.Helper()
+	store, err := OpenMetadataStore(MetadataStorePath(&Config{ConduitDir: t.TempDir()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	log := zerolog.Nop()
+	list := NewBootstrapPeerList(&Config{LndBitcoinDNSSeeds: []string{"seed.example.com,soa.seed.example.com"}}, store, &log)
+	list.resolver = resolver
+	return list
+}
+
+func TestBootstrapPeerListResolve(t *testing.T) {
+	first, second := seedTarget(t, testPubkey(0xaa), "seed.example.com"), seedTarget(t, testPubkey(0xbb), "seed.example.com")
+	resolver := &fakeResolver{
+		srv: map[string][]*net.SRV{"seed.example.com": {
+			{Target: first, Port: 9735},
+			{Target: "notbech32.seed.example.com.", Port: 9735},
+			{Target: second, Port: 9736},
+		}},
+		hosts: map[string][]string{first: {"10.0.0.1"}, second: {"10.0.0.2", "10.0.0.3"}},
+	}
+	list := newTestBootstrapPeerList(t, resolver)
+	peers := list.Peers(context.Background())
+	want := []BootstrapPeer{
+		{Pubkey: hex.EncodeToString(testPubkey(0xaa)), Host: "10.0.0.1:9735"},
+		{Pubkey: hex.EncodeToString(testPubkey(0xbb)), Host: "10.0.0.2:9736"},
+	}
+	if diff := cmp.Diff(want, peers); diff != "" {
+		t.Fatalf("unexpected peers (-want +got):\n%s", diff)
+	}
+	// the seeds are unreachable on the next start, the cached peers are used
+	resolver.down = true
+	if diff := cmp.Diff(want, list.Peers(context.Background())); diff != "" {
+		t.Fatalf("unexpected cached peers (-want +got):\n%s", diff)
+	}
+	client := &fakeLightningClient{}
+	list.Connect(context.Background(), client, peers)
+	if len(client.connected) != 2 || !strings.HasSuffix(client.connected[1], "@10.0.0.2:9736") {
+		t.Fatalf("unexpected connected peers: %v", client.connected)
+	}
+}
+
+func TestBootstrapPeerListNoCache(t *testing.T) {
+	list := newTestBootstrapPeerList(t, &fakeResolver{down: true})
+	if peers := list.Peers(context.Background()); len(peers) != 0 {
+		t.Fatalf("expected no peers, got %v", peers)
+	}
+}
+
+func TestBootstrapPeerListSeeds(t *testing.T) {
+	log := zerolog.Nop()
+	for _, test := range []struct {
+		name string
+		cfg  *Config
+		want []string
+	}{
+		{"mainnet defaults", &Config{LndBitcoinMainNet: true}, bitcoin_mainnet_dns_seeds},
+		{"configured seeds", &Config{LndBitcoinMainNet: true, LndBitcoinDNSSeeds: []string{"a.example.com,soa.a.example.com", "b.example.com"}}, []string{"a.example.com", "b.example.com"}},
+		{"no bootstrap", &Config{LndBitcoinMainNet: true, LndNoNetBootstrap: true}, nil},
+		{"regtest", &Config{}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, NewBootstrapPeerList(test.cfg, nil, &log).seeds); diff != "" {
+				t.Fatalf("unexpected seeds (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
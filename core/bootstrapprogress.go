@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// lndSyncRegex matches the LND block sync lines, i.e. `Syncing to block height 750000 from 700000`
+var lndSyncRegex = regexp.MustCompile(`Syncing to block height (\d+) from (\d+)`)
+
+// LNDBootstrapProgressBar renders the progress of LND's initial block sync in the console
+type LNDBootstrapProgressBar struct {
+	sync.Mutex
+	out     io.Writer
+	enabled bool
+	bar     *progressbar.ProgressBar
+	done    bool
+}
+
+// NewLNDBootstrapProgressBar creates a new LNDBootstrapProgressBar rendering on stderr. It's disabled without console output or when stderr isn't a terminal
+func NewLNDBootstrapProgressBar(cfg *Config) *LNDBootstrapProgressBar {
+	return &LNDBootstrapProgressBar{
+		out:     os.Stderr,
+		enabled: cfg.ConsoleOutput && term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// Enabled reports whether the progress bar is rendered
+func (p *LNDBootstrapProgressBar) Enabled() bool {
+	return p.enabled
+}
+
+// ParseLine implements the `LineParser` interface
+func (p *LNDBootstrapProgressBar) ParseLine(line string) {
+	if !p.enabled {
+		return
+	}
+	captures := lndSyncRegex.FindStringSubmatch(line)
+	if captures == nil {
+		return
+	}
+	target, err := strconv.ParseInt(captures[1], 10, 64)
+	if err != nil || target <= 0 {
+		return
+	}
+	height, err := strconv.ParseInt(captures[2], 10, 64)
+	if err != nil {
+		return
+	}
+	if height > target {
+		height = target
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.done {
+		return
+	}
+	if p.bar == nil {
+		p.bar = progressbar.NewOptions64(target,
+			progressbar.OptionSetWriter(p.out),
+			progressbar.OptionSetPredictTime(true),
+			progressbar.OptionClearOnFinish(),
+			progressbar.OptionSetTheme(progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+		)
+	} else if p.bar.GetMax64() != target {
+		// the chain grew while syncing
+		p.bar.ChangeMax64(target)
+	}
+	// the bar is rendered on every line, LND logs the sync progress a few times per second at most
+	p.bar.Describe(fmt.Sprintf("Block %d/%d", height, target))
+	p.bar.Set64(height)
+	if height == target {
+		p.done = true
+		p.bar.Finish()
+	}
+}
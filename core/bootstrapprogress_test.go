@@ -0,0 +1,45 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestLNDBootstrapProgressBar ensures the sync lines of LND update the bar with the block heights and the bar is cleared once synced
+func TestLNDBootstrapProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	p := &LNDBootstrapProgressBar{out: &buf, enabled: true}
+	p.ParseLine("2022-03-01 12:00:00.000 [INF] LTND: Waiting for chain backend to finish sync, start_height=700000")
+	if buf.Len() != 0 {
+		t.Fatalf("expected unrelated lines to be ignored, got %q", buf.String())
+	}
+	p.ParseLine("2022-03-01 12:00:01.000 [INF] LTND: Syncing to block height 750000 from 700000")
+	if out := buf.String(); !strings.Contains(out, "Block 700000/750000") || !strings.Contains(out, "93%") || !strings.Contains(out, "[====") || !strings.Contains(out, ">") {
+		t.Errorf("unexpected progress bar %q", out)
+	}
+	buf.Reset()
+	p.ParseLine("2022-03-01 12:00:05.000 [INF] LTND: Syncing to block height 750010 from 750010")
+	if out := buf.String(); !strings.Contains(out, "Block 750010/750010") || !p.done {
+		t.Errorf("expected the bar to reach the new chain tip and finish, got %q", out)
+	}
+	buf.Reset()
+	p.ParseLine("2022-03-01 12:00:06.000 [INF] LTND: Syncing to block height 750020 from 750011")
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be rendered once synced, got %q", buf.String())
+	}
+}
+
+// TestLNDBootstrapProgressBarDisabled ensures nothing is rendered without console output
+func TestLNDBootstrapProgressBarDisabled(t *testing.T) {
+	p := NewLNDBootstrapProgressBar(&Config{ConsoleOutput: false})
+	if p.Enabled() {
+		t.Fatal("expected the progress bar to be disabled without console output")
+	}
+	var buf bytes.Buffer
+	p.out = &buf
+	p.ParseLine("2022-03-01 12:00:01.000 [INF] LTND: Syncing to block height 750000 from 700000")
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be rendered, got %q", buf.String())
+	}
+}
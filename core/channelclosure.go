@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	channel_close_webhook_timeout = 10 * time.Second
+	channel_closure_force_close   = "force_close"
+	channel_closure_breach        = "breach"
+)
+
+// ChannelClosureNotification is the JSON body posted to the webhook for a force closed or breached channel
+type ChannelClosureNotification struct {
+	Event         string `json:"event"`
+	ChannelID     uint64 `json:"channel_id"`
+	ChannelPoint  string `json:"channel_point"`
+	RemotePubkey  string `json:"remote_pubkey"`
+	CloseType     string `json:"close_type"`
+	ClosingTxHash string `json:"closing_tx_hash"`
+	// JusticeTxHash is the transaction with which LND swept the breached channel, if it did
+	JusticeTxHash string `json:"justice_tx_hash,omitempty"`
+}
+
+// ChannelClosureDetector alerts about the channels closed without a cooperative close, which can be the sign of a misbehaving peer or of a breach
+// attempt. The closing transactions are then watched until the channels are fully resolved to report the justice transactions LND publishes
+type ChannelClosureDetector struct {
+	client  lnrpc.LightningClient
+	webhook string
+	http    *http.Client
+	log     *subLogger
+	mu      sync.Mutex
+	// watched are the force closed channels keyed by closing transaction hash
+	watched  map[string]ChannelClosureNotification
+	watching bool
+}
+
+// NewChannelClosureDetector creates a ChannelClosureDetector posting its notifications to the configured webhook
+func NewChannelClosureDetector(client lnrpc.LightningClient, cfg *Config, log *zerolog.Logger) *ChannelClosureDetector {
+	return &ChannelClosureDetector{
+		client:  client,
+		webhook: cfg.ChannelCloseWebhookURL,
+		http:    &http.Client{Timeout: channel_close_webhook_timeout},
+		log:     NewSubLogger(log, "CHCL"),
+		watched: make(map[string]ChannelClosureNotification),
+	}
+}
+
+// isForceClose returns whether a channel was closed without the agreement of both parties
+func isForceClose(closeType lnrpc.ChannelCloseSummary_ClosureType) bool {
+	switch closeType {
+	case lnrpc.ChannelCloseSummary_LOCAL_FORCE_CLOSE, lnrpc.ChannelCloseSummary_REMOTE_FORCE_CLOSE, lnrpc.ChannelCloseSummary_BREACH_CLOSE:
+		return true
+	}
+	return false
+}
+
+// Run subscribes to LND's channel events and alerts about the force closes until the stream ends or the context is cancelled
+func (d *ChannelClosureDetector) Run(ctx context.Context) error {
+	stream, err := d.client.SubscribeChannelEvents(ctx, &lnrpc.ChannelEventSubscription{})
+	if err != nil {
+		return err
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case update.GetClosedChannel() != nil && isForceClose(update.GetClosedChannel().CloseType):
+			d.onForceClose(ctx, update.GetClosedChannel())
+		case update.GetFullyResolvedChannel() != nil:
+			d.unwatch(formatChannelPoint(update.GetFullyResolvedChannel()))
+		}
+	}
+}
+
+// onForceClose alerts about a force closed channel and starts watching its closing transaction
+func (d *ChannelClosureDetector) onForceClose(ctx context.Context, summary *lnrpc.ChannelCloseSummary) {
+	notification := ChannelClosureNotification{
+		Event:         channel_closure_force_close,
+		ChannelID:     summary.ChanId,
+		ChannelPoint:  summary.ChannelPoint,
+		RemotePubkey:  summary.RemotePubkey,
+		CloseType:     summary.CloseType.String(),
+		ClosingTxHash: summary.ClosingTxHash,
+	}
+	if summary.CloseType == lnrpc.ChannelCloseSummary_BREACH_CLOSE {
+		notification.Event = channel_closure_breach
+		d.log.SubLogger.Error().Uint64("chan_id", summary.ChanId).Str("remote_pubkey", summary.RemotePubkey).Msg(fmt.Sprintf("Channel %d was breached by %s", summary.ChanId, summary.RemotePubkey))
+	} else {
+		d.log.SubLogger.Error().Uint64("chan_id", summary.ChanId).Str("remote_pubkey", summary.RemotePubkey).Msg(fmt.Sprintf("Channel %d with %s was force closed (%v)", summary.ChanId, summary.RemotePubkey, summary.CloseType))
+	}
+	d.notify(ctx, notification)
+	if summary.ClosingTxHash == "" {
+		return
+	}
+	d.mu.Lock()
+	d.watched[summary.ClosingTxHash] = notification
+	start := !d.watching
+	d.watching = true
+	d.mu.Unlock()
+	if start {
+		go d.watchTransactions(ctx)
+	}
+}
+
+// unwatch stops watching the closing transaction of a fully resolved channel
+func (d *ChannelClosureDetector) unwatch(channelPoint string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for txHash, notification := range d.watched {
+		if notification.ChannelPoint == channelPoint {
+			delete(d.watched, txHash)
+		}
+	}
+}
+
+// watchTransactions subscribes to the transactions of the wallet and reports those spending the closing transactions of the watched channels
+func (d *ChannelClosureDetector) watchTransactions(ctx context.Context) {
+	defer func() {
+		d.mu.Lock()
+		d.watching = false
+		d.mu.Unlock()
+	}()
+	stream, err := d.client.SubscribeTransactions(ctx, &lnrpc.GetTransactionsRequest{})
+	if err != nil {
+		d.log.SubLogger.Error().Msg(fmt.Sprintf("could not watch for breach transactions: %v", err))
+		return
+	}
+	for {
+		tx, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return
+		} else if err != nil {
+			d.log.SubLogger.Error().Msg(fmt.Sprintf("stopped watching for breach transactions: %v", err))
+			return
+		}
+		d.checkTransaction(ctx, tx)
+	}
+}
+
+// checkTransaction reports a transaction spending the closing transaction of a watched channel, alerting if it's a justice transaction
+func (d *ChannelClosureDetector) checkTransaction(ctx context.Context, tx *lnrpc.Transaction) {
+	raw, err := hex.DecodeString(tx.RawTxHex)
+	if err != nil {
+		return
+	}
+	msgTx := &wire.MsgTx{}
+	if err = msgTx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return
+	}
+	for _, in := range msgTx.TxIn {
+		d.mu.Lock()
+		notification, ok := d.watched[in.PreviousOutPoint.Hash.String()]
+		d.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if !strings.Contains(tx.Label, string(labels.LabelTypeJusticeTransaction)) {
+			d.log.SubLogger.Info().Uint64("chan_id", notification.ChannelID).Msg(fmt.Sprintf("Transaction %s sweeps the closing transaction of channel %d", tx.TxHash, notification.ChannelID))
+			return
+		}
+		notification.Event = channel_closure_breach
+		notification.JusticeTxHash = tx.TxHash
+		d.log.SubLogger.Error().Uint64("chan_id", notification.ChannelID).Str("remote_pubkey", notification.RemotePubkey).Msg(fmt.Sprintf("Channel %d was breached by %s, LND published the justice transaction %s", notification.ChannelID, notification.RemotePubkey, tx.TxHash))
+		d.notify(ctx, notification)
+		return
+	}
+}
+
+// notify posts the notification to the webhook, if one is configured
+func (d *ChannelClosureDetector) notify(ctx context.Context, notification ChannelClosureNotification) {
+	if d.webhook == "" {
+		return
+	}
+	if err := d.post(ctx, notification); err != nil {
+		d.log.SubLogger.Error().Msg(fmt.Sprintf("could not post channel closure notification: %v", err))
+	}
+}
+
+// post sends the notification to the webhook
+func (d *ChannelClosureDetector) post(ctx context.Context, notification ChannelClosureNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
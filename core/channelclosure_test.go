@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+// spendingTx returns the hex of a transaction spending the first output of the given transaction
+func spendingTx(t *testing.T, spent chainhash.Hash) string {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&spent, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(10000, []byte{0x00, 0x14}))
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// closedChannel returns the event of a channel closed by the given closing transaction
+func closedChannel(chanID uint64, closeType lnrpc.ChannelCloseSummary_ClosureType, closingTx string) *lnrpc.ChannelEventUpdate {
+	return &lnrpc.ChannelEventUpdate{
+		Type: lnrpc.ChannelEventUpdate_CLOSED_CHANNEL,
+		Channel: &lnrpc.ChannelEventUpdate_ClosedChannel{ClosedChannel: &lnrpc.ChannelCloseSummary{
+			ChanId: chanID, ChannelPoint: "abcd:0", RemotePubkey: "02aa", CloseType: closeType, ClosingTxHash: closingTx,
+		}},
+	}
+}
+
+// TestChannelClosureDetector ensures force closes are logged and notified, cooperative closes ignored and justice transactions reported as breaches
+func TestChannelClosureDetector(t *testing.T) {
+	notifications := make(chan ChannelClosureNotification, 4)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n ChannelClosureNotification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("could not decode notification: %v", err)
+		}
+		notifications <- n
+	}))
+	defer ts.Close()
+	closingTx := chainhash.Hash{0x01}
+	client := &fakeLightningClient{
+		updates: []*lnrpc.ChannelEventUpdate{
+			closedChannel(1, lnrpc.ChannelCloseSummary_COOPERATIVE_CLOSE, chainhash.Hash{0x02}.String()),
+			closedChannel(2, lnrpc.ChannelCloseSummary_REMOTE_FORCE_CLOSE, closingTx.String()),
+		},
+		txs: []*lnrpc.Transaction{
+			{TxHash: "unrelated", RawTxHex: spendingTx(t, chainhash.Hash{0x03})},
+			{TxHash: "justice", RawTxHex: spendingTx(t, closingTx), Label: "0:justicetx:shortchanid-2"},
+		},
+	}
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	detector := NewChannelClosureDetector(client, &Config{ChannelCloseWebhookURL: ts.URL}, &log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := detector.Run(ctx); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	expected := []ChannelClosureNotification{
+		{Event: channel_closure_force_close, ChannelID: 2, ChannelPoint: "abcd:0", RemotePubkey: "02aa", CloseType: "REMOTE_FORCE_CLOSE", ClosingTxHash: closingTx.String()},
+		{Event: channel_closure_breach, ChannelID: 2, ChannelPoint: "abcd:0", RemotePubkey: "02aa", CloseType: "REMOTE_FORCE_CLOSE", ClosingTxHash: closingTx.String(), JusticeTxHash: "justice"},
+	}
+	for _, e := range expected {
+		select {
+		case n := <-notifications:
+			if n != e {
+				t.Errorf("expected notification %+v, got %+v", e, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %+v wasn't posted", e)
+		}
+	}
+	if out := buf.String(); !strings.Contains(out, `"chan_id":2,"remote_pubkey":"02aa","message":"Channel 2 with 02aa was force closed (REMOTE_FORCE_CLOSE)"`) || strings.Contains(out, `"chan_id":1`) {
+		t.Errorf("unexpected log %s", out)
+	}
+}
+
+// TestChannelClosureDetectorUnwatch ensures the closing transaction of a fully resolved channel is no longer watched
+func TestChannelClosureDetectorUnwatch(t *testing.T) {
+	log := zerolog.Nop()
+	detector := NewChannelClosureDetector(&fakeLightningClient{}, &Config{}, &log)
+	detector.watched["closing"] = ChannelClosureNotification{ChannelPoint: "abcd:0"}
+	detector.watched["other"] = ChannelClosureNotification{ChannelPoint: "ef01:1"}
+	detector.unwatch("abcd:0")
+	if _, ok := detector.watched["closing"]; ok || len(detector.watched) != 1 {
+		t.Errorf("unexpected watched channels %v", detector.watched)
+	}
+}
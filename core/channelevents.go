@@ -0,0 +1,195 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const channel_events_file_name = "channel_events.csv"
+
+// ChannelEventsHeader is the header row of the channel events CSV
+var ChannelEventsHeader = []string{"timestamp", "event_type", "channel_point", "remote_pubkey", "capacity_sats", "local_balance_sats", "remote_balance_sats"}
+
+// ChannelEvent is a single row of the channel events CSV
+type ChannelEvent struct {
+	Timestamp     time.Time
+	EventType     string
+	ChannelPoint  string
+	RemotePubkey  string
+	CapacitySats  int64
+	LocalBalance  int64
+	RemoteBalance int64
+}
+
+// ChannelEventRecorder appends every channel lifecycle event streamed by LND to an append-only CSV file
+type ChannelEventRecorder struct {
+	client   lnrpc.LightningClient
+	filename string
+	log      *subLogger
+	now      func() time.Time
+}
+
+// ChannelEventsPath returns the path of the channel events CSV in the conduit directory
+func ChannelEventsPath(config *Config) string {
+	return path.Join(config.ConduitDir, channel_events_file_name)
+}
+
+// NewChannelEventRecorder creates a ChannelEventRecorder writing to the channel events CSV of the conduit directory
+func NewChannelEventRecorder(client lnrpc.LightningClient, config *Config, log *zerolog.Logger) *ChannelEventRecorder {
+	return &ChannelEventRecorder{
+		client:   client,
+		filename: ChannelEventsPath(config),
+		log:      NewSubLogger(log, "CHEV"),
+		now:      time.Now,
+	}
+}
+
+// Run subscribes to LND's channel events and records them until the stream ends or the context is cancelled
+func (r *ChannelEventRecorder) Run(ctx context.Context) error {
+	stream, err := r.client.SubscribeChannelEvents(ctx, &lnrpc.ChannelEventSubscription{})
+	if err != nil {
+		return err
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		event := channelEventFromUpdate(update, r.now())
+		if err = appendCSVRow(r.filename, ChannelEventsHeader, event.Row()); err != nil {
+			r.log.SubLogger.Error().Msg(fmt.Sprintf("could not record channel event: %v", err))
+		}
+	}
+}
+
+// formatChannelPoint returns the txid:output string form of a channel point
+func formatChannelPoint(point *lnrpc.ChannelPoint) string {
+	if point == nil {
+		return ""
+	}
+	if txid := point.GetFundingTxidStr(); txid != "" {
+		return fmt.Sprintf("%s:%d", txid, point.OutputIndex)
+	}
+	hash, err := chainhash.NewHash(point.GetFundingTxidBytes())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%v:%d", hash, point.OutputIndex)
+}
+
+// channelEventFromUpdate extracts the fields of a ChannelEvent from a channel event update
+func channelEventFromUpdate(update *lnrpc.ChannelEventUpdate, timestamp time.Time) *ChannelEvent {
+	event := &ChannelEvent{Timestamp: timestamp, EventType: update.Type.String()}
+	switch {
+	case update.GetOpenChannel() != nil:
+		c := update.GetOpenChannel()
+		event.ChannelPoint, event.RemotePubkey = c.ChannelPoint, c.RemotePubkey
+		event.CapacitySats, event.LocalBalance, event.RemoteBalance = c.Capacity, c.LocalBalance, c.RemoteBalance
+	case update.GetClosedChannel() != nil:
+		c := update.GetClosedChannel()
+		event.ChannelPoint, event.RemotePubkey = c.ChannelPoint, c.RemotePubkey
+		event.CapacitySats, event.LocalBalance = c.Capacity, c.SettledBalance
+	case update.GetActiveChannel() != nil:
+		event.ChannelPoint = formatChannelPoint(update.GetActiveChannel())
+	case update.GetInactiveChannel() != nil:
+		event.ChannelPoint = formatChannelPoint(update.GetInactiveChannel())
+	case update.GetFullyResolvedChannel() != nil:
+		event.ChannelPoint = formatChannelPoint(update.GetFullyResolvedChannel())
+	case update.GetPendingOpenChannel() != nil:
+		p := update.GetPendingOpenChannel()
+		if hash, err := chainhash.NewHash(p.Txid); err == nil {
+			event.ChannelPoint = fmt.Sprintf("%v:%d", hash, p.OutputIndex)
+		}
+	}
+	return event
+}
+
+// Row returns the CSV row of the event
+func (e *ChannelEvent) Row() []string {
+	return []string{
+		e.Timestamp.UTC().Format(time.RFC3339),
+		e.EventType,
+		e.ChannelPoint,
+		e.RemotePubkey,
+		strconv.FormatInt(e.CapacitySats, 10),
+		strconv.FormatInt(e.LocalBalance, 10),
+		strconv.FormatInt(e.RemoteBalance, 10),
+	}
+}
+
+// appendCSVRow appends a row to a CSV file, writing the header first if the file doesn't exist yet
+func appendCSVRow(filename string, header, row []string) error {
+	return appendCSVRows(filename, header, [][]string{row})
+}
+
+// appendCSVRows appends rows to a CSV file in a single write, writing the header first if the file doesn't exist yet
+func appendCSVRows(filename string, header []string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if !utils.FileExists(filename) {
+		w.Write(header)
+	}
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return utils.AtomicAppend(filename, buf.Bytes(), 0600)
+}
+
+// readCSVRows reads every row of a CSV file written by appendCSVRow, skipping the header
+func readCSVRows(filename string, columns int) ([][]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = columns
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		rows = rows[1:]
+	}
+	return rows, nil
+}
+
+// ReadChannelEvents reads the channel events CSV and returns the events recorded at or after since
+func ReadChannelEvents(filename string, since time.Time) ([]*ChannelEvent, error) {
+	rows, err := readCSVRows(filename, len(ChannelEventsHeader))
+	if err != nil {
+		return nil, err
+	}
+	var events []*ChannelEvent
+	for i, row := range rows {
+		event := &ChannelEvent{EventType: row[1], ChannelPoint: row[2], RemotePubkey: row[3]}
+		if event.Timestamp, err = time.Parse(time.RFC3339, row[0]); err != nil {
+			return nil, fmt.Errorf("row %d: invalid timestamp %v", i+1, row[0])
+		}
+		if event.Timestamp.Before(since) {
+			continue
+		}
+		for j, field := range []*int64{&event.CapacitySats, &event.LocalBalance, &event.RemoteBalance} {
+			if *field, err = strconv.ParseInt(row[4+j], 10, 64); err != nil {
+				return nil, fmt.Errorf("row %d: invalid %s %v", i+1, ChannelEventsHeader[4+j], row[4+j])
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+// TestChannelEventRecorder ensures every streamed event is appended to the CSV and can be read back and filtered
+func TestChannelEventRecorder(t *testing.T) {
+	txid := make([]byte, 32)
+	txid[0] = 0xab
+	client := &fakeLightningClient{updates: []*lnrpc.ChannelEventUpdate{
+		{
+			Type: lnrpc.ChannelEventUpdate_OPEN_CHANNEL,
+			Channel: &lnrpc.ChannelEventUpdate_OpenChannel{OpenChannel: &lnrpc.Channel{
+				ChannelPoint: "abcd:0", RemotePubkey: "02aa", Capacity: 100000, LocalBalance: 60000, RemoteBalance: 40000,
+			}},
+		},
+		{
+			Type: lnrpc.ChannelEventUpdate_INACTIVE_CHANNEL,
+			Channel: &lnrpc.ChannelEventUpdate_InactiveChannel{InactiveChannel: &lnrpc.ChannelPoint{
+				FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{FundingTxidBytes: txid}, OutputIndex: 1,
+			}},
+		},
+		{
+			Type: lnrpc.ChannelEventUpdate_CLOSED_CHANNEL,
+			Channel: &lnrpc.ChannelEventUpdate_ClosedChannel{ClosedChannel: &lnrpc.ChannelCloseSummary{
+				ChannelPoint: "abcd:0", RemotePubkey: "02aa", Capacity: 100000, SettledBalance: 59000,
+			}},
+		},
+	}}
+	log := zerolog.Nop()
+	recorder := NewChannelEventRecorder(client, &Config{ConduitDir: t.TempDir()}, &log)
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	recorder.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Hour)
+	}
+	// run twice to make sure the header is only written once
+	for i := 0; i < 2; i++ {
+		if err := recorder.Run(context.Background()); err != nil {
+			t.Fatalf("Run returned an error: %v", err)
+		}
+		client.updates = client.updates[:0]
+	}
+	events, err := ReadChannelEvents(recorder.filename, time.Time{})
+	if err != nil {
+		t.Fatalf("ReadChannelEvents returned an error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if e := events[0]; e.EventType != "OPEN_CHANNEL" || e.ChannelPoint != "abcd:0" || e.LocalBalance != 60000 || e.RemoteBalance != 40000 {
+		t.Errorf("unexpected open channel event: %+v", e)
+	}
+	if e := events[1]; e.ChannelPoint != "00000000000000000000000000000000000000000000000000000000000000ab:1" {
+		t.Errorf("unexpected inactive channel point: %v", e.ChannelPoint)
+	}
+	if e := events[2]; e.EventType != "CLOSED_CHANNEL" || e.LocalBalance != 59000 {
+		t.Errorf("unexpected closed channel event: %+v", e)
+	}
+	events, err = ReadChannelEvents(recorder.filename, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("ReadChannelEvents returned an error: %v", err)
+	}
+	if len(events) != 2 || events[0].EventType != "INACTIVE_CHANNEL" {
+		t.Errorf("expected the last 2 events, got %+v", events)
+	}
+	if _, err := ReadChannelEvents(path.Join(t.TempDir(), channel_events_file_name), time.Time{}); err == nil {
+		t.Errorf("ReadChannelEvents did not fail on a missing file")
+	}
+}
@@ -20,15 +20,20 @@ package core
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
 	"sync"
 
 	"github.com/TheRebelOfBabylon/Conduit/errors"
 	"github.com/TheRebelOfBabylon/Conduit/intercept"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
 	e "github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -41,7 +46,7 @@ var (
 )
 
 // parseLndLog parses the LND log to format it to zerolog
-func parseLndLog(scan *bufio.Scanner, log *zerolog.Logger, re *regexp.Regexp, shutdownChan <-chan struct{}, wg sync.WaitGroup) {
+func parseLndLog(scan *bufio.Scanner, log *zerolog.Logger, re *regexp.Regexp, filter *LNDLogFilter, output *LNDProcessOutput, aggregator *LNDLogAggregator, shutdownChan <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	logger := log.With().Str("process", "LND").Logger()
 	for scan.Scan() {
@@ -50,12 +55,20 @@ func parseLndLog(scan *bufio.Scanner, log *zerolog.Logger, re *regexp.Regexp, sh
 			return
 		default:
 			line := scan.Text()
+			output.dispatch(line)
 			captures := re.FindStringSubmatch(line)
 			// prevent panic conditions where we're looking at indices that don't exist
 			if len(captures) == 0 {
 				continue
 			}
 			logLvl, subName, text := captures[1], captures[2], captures[3]
+			// the detectors and the log stats get every line, only the log is filtered
+			if level, ok := lnd_log_abbreviations[logLvl]; ok {
+				aggregator.Record(subName, level)
+			}
+			if !filter.Allow(line) {
+				continue
+			}
 			switch logLvl {
 			case "INF":
 				logger.Info().Str("subsystem", subName).Msg(text)
@@ -79,8 +92,218 @@ func parseLndLog(scan *bufio.Scanner, log *zerolog.Logger, re *regexp.Regexp, sh
 // Main is the true entry point for Conduit
 func Main(shutdownInterceptor *intercept.Interceptor, cfg *Config, log zerolog.Logger) error {
 	var wg sync.WaitGroup
+	timeline := NewStartupTimeline()
+	timeline.Mark(StartupConduitStarted)
+	// the subscribers registered after an event was published catch up with the last events of its topic
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	logStats := NewLNDLogAggregator()
+	lndOutput := NewLNDProcessOutput()
+	feeOptimizer := NewChannelFeeOptimizer(cfg)
+	scorer := NewRoutingNodeScorer(cfg)
+	topology := NewNetworkTopologyAnalyzer()
+	lndRPC, err := NewGRPCReconnector(cfg, &log)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return err
+	}
+	mempool := NewLNDMemPoolMonitor(cfg, &log)
+	// a binary whose signature doesn't verify is never run, not even for its version
+	lndPath, err := findLndBinary(cfg, &log)
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+		return err
+	}
+	versions := NewLNDVersionCache(lndPath)
+	reporter := NewFailureReporter(cfg, versions, &log)
+	lndOutput.Register(reporter)
+	if progress := NewLNDBootstrapProgressBar(cfg); progress.Enabled() {
+		lndOutput.Register(progress)
+	}
+	var (
+		bootstrap   *BootstrapPeerList
+		pinning     *TLSPinning
+		pluginState *PluginStateStore
+		peerScorer  *LNDPeerScorer
+		rpcServer   *RPCServer
+	)
+	// starting the JSON-RPC server
+	if !cfg.LndShowVersion {
+		store, err := OpenMetadataStore(MetadataStorePath(cfg))
+		if err != nil {
+			err = e.Wrap(err, "could not open metadata store")
+			log.Error().Msg(err.Error())
+			return err
+		}
+		defer store.Close()
+		timeline.Mark(StartupStoreOpened)
+		pinning = NewTLSPinning(store, &log)
+		lndRPC.SetTLSPinning(pinning)
+		peerScorer = NewLNDPeerScorer(store, &log)
+		if change, err := CheckConfigHash(cfg, store, bus); err != nil {
+			log.Warn().Msg(fmt.Sprintf("could not compare the config to the previous run: %v", err))
+		} else if change != nil {
+			log.Info().Str("old_hash", change.OldHash).Str("new_hash", change.NewHash).Msg("Config changed since the previous run")
+		}
+		metrics := NewMetricsServer(cfg, &log)
+		pluginMetrics, err := NewPluginMetrics(metrics.Registry)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return err
+		}
+		rpcServer = NewRPCServer(cfg, &log)
+		rpcServer.SetPluginMetrics(pluginMetrics)
+		rpcServer.RegisterFeatureFlags(NewFeatureFlagManager(store))
+		rpcServer.RegisterLogStats(logStats)
+		rpcServer.RegisterFeeSuggestions(feeOptimizer)
+		rpcServer.RegisterRoutingScores(scorer)
+		rpcServer.RegisterPeerScores(peerScorer)
+		rpcServer.RegisterTopology(topology)
+		rpcServer.RegisterFeeRates(mempool)
+		rpcServer.RegisterConfigProfile(DefaultConfigProfiler)
+		rpcServer.RegisterStartupTimeline(timeline)
+		rpcServer.RegisterLNDRPCState(lndRPC)
+		rpcServer.RegisterTLSPinning(pinning, lndTLSCertPath(cfg))
+		if err := rpcServer.Start(); err != nil {
+			err = e.Wrap(err, "could not start JSON-RPC server")
+			log.Error().Msg(err.Error())
+			return err
+		}
+		defer rpcServer.Stop()
+		timeline.Mark(StartupRPCServerStarted)
+		if metrics.Enabled() {
+			if err := metrics.Start(); err != nil {
+				err = e.Wrap(err, "could not start metrics server")
+				log.Error().Msg(err.Error())
+				return err
+			}
+			defer metrics.Stop()
+		}
+		malfunctions, err := NewMalfunctionDetector(&log, metrics.Registry)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return err
+		}
+		lndOutput.Register(malfunctions)
+		memStats, err := NewMemoryStatsLogger(cfg, &log, metrics.Registry)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return err
+		}
+		go memStats.Run(shutdownInterceptor.ShutdownChannel())
+		bootstrap = NewBootstrapPeerList(cfg, store, &log)
+		pluginState = NewPluginStateStore(store)
+	}
 	// starting LND
-	_, err := startLnd(cfg, wg, &log, shutdownInterceptor)
+	if !cfg.LndShowVersion {
+		checkLndPorts(cfg, &log)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		plugins, err := NewPluginManager(cfg, &log)
+		if err != nil {
+			err = e.Wrap(err, "could not load plugin manifests")
+			log.Error().Msg(err.Error())
+			return err
+		}
+		plugins.SetStateStore(pluginState)
+		rpcServer.RegisterPlugins(plugins)
+		defer plugins.StopAll()
+		timeline.Mark(StartupPluginsLoaded)
+		if err := startPluginsBeforeLnd(ctx, cfg, plugins); err != nil {
+			log.Error().Msg(err.Error())
+			return err
+		}
+		timeline.Mark(StartupPluginsStarted)
+		markLndStarted(ctx, bus, timeline)
+		if cfg.ErrorContextEnabled {
+			go func() {
+				if version, err := versions.Version(); err == nil {
+					DefaultErrorContextEnricher.SetLNDVersion(version.String())
+				}
+			}()
+		}
+		// the DNS seeds are resolved before LND starts and the peers connected as soon as it's active
+		bootstrapPeers := bootstrap.Peers(ctx)
+		// every consumer of LND's RPC server shares the persistent connection, which outlives LND restarts. The consumers of LND's streams
+		// are run again once it's back
+		keepLndConnected(ctx, bus, lndRPC, &log, func(conn *grpc.ClientConn) {
+			timeline.Mark(StartupLndRPCActive)
+			timeline.Log(&log)
+			client, router := lnrpc.NewLightningClient(conn), routerrpc.NewRouterClient(conn)
+			// the macaroons of the plugins started from now on are baked with this connection
+			plugins.SetMacaroonConstrainer(NewMacaroonConstrainer(client))
+			if err := plugins.StartAll(plugins.Plugins(true)); err != nil {
+				log.Error().Msg(err.Error())
+			}
+			scorer.SetClient(client)
+			peerScorer.SetClient(client)
+			topology.SetClient(client)
+			go peerScorer.Run(ctx)
+			if reporter.Enabled() || cfg.ErrorContextEnabled {
+				go func() {
+					info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+					if err != nil {
+						return
+					}
+					if reporter.Enabled() {
+						reporter.SetNodePubkey(info.IdentityPubkey)
+					}
+					if cfg.ErrorContextEnabled {
+						DefaultErrorContextEnricher.SetNodeAlias(info.Alias)
+					}
+				}()
+			}
+			// LND creates its certificate on first start
+			go NewTLSCertRotationNotifier(cfg, &log).Run(ctx)
+			if len(bootstrapPeers) > 0 {
+				go bootstrap.Connect(ctx, client, bootstrapPeers)
+			}
+			if cfg.ConsoleOutput {
+				go func() {
+					if err := NewStartupBanner(client, os.Stdout).Print(ctx); err != nil {
+						log.Error().Msg(fmt.Sprintf("could not print startup banner: %v", err))
+					}
+				}()
+			}
+			channelEvents := NewChannelEventRecorder(client, cfg, &log)
+			go lndRPC.RunWhileConnected(ctx, "channel event recorder", func() error { return channelEvents.Run(ctx) })
+			closures := NewChannelClosureDetector(client, cfg, &log)
+			go lndRPC.RunWhileConnected(ctx, "channel closure detector", func() error { return closures.Run(ctx) })
+			payments := NewPaymentEventRecorder(client, router, cfg, &log)
+			go lndRPC.RunWhileConnected(ctx, "payment event recorder", func() error { return payments.Run(ctx) })
+			go lndRPC.RunWhileConnected(ctx, "fee optimizer", func() error { return feeOptimizer.Run(ctx, router) })
+			// the exporter and the liquidity monitor poll LND, so the reconnections are enough for them to survive its restarts
+			if exporter, err := NewForwardingHistoryExporter(client, cfg, &log); err != nil {
+				log.Error().Msg(err.Error())
+			} else {
+				go func() {
+					if err := exporter.Run(ctx); err != nil {
+						log.Error().Msg(fmt.Sprintf("forwarding history exporter stopped: %v", err))
+					}
+				}()
+			}
+			if monitor, err := NewChannelLiquidityMonitor(client, bus, cfg, &log); err != nil {
+				log.Error().Msg(err.Error())
+			} else {
+				go monitor.Run(ctx)
+			}
+			if cfg.LndRPCMiddlewareEnable {
+				runRPCMiddlewarePlugins(ctx, cfg, lndRPC, client, &log)
+			}
+		})
+		if cfg.LndTorActive {
+			torMonitor := NewTorBootstrapMonitor(bus, &log)
+			lndOutput.Register(torMonitor)
+			go torMonitor.Run(ctx)
+		}
+		go mempool.Run(ctx)
+		if !cfg.DisableUpdateCheck {
+			go NewLNDUpdateChecker(versions, &log).Run(ctx)
+		}
+		go watchWalletState(ctx, cfg, pinning, bus, &log)
+		monitorLndProcess(ctx, cfg, bus, &log)
+		watchLndInterfaces(ctx, cfg, pinning, bus, &log)
+	}
+	_, err = startLnd(cfg, lndPath, bus, lndOutput, logStats, reporter, &wg, &log, shutdownInterceptor)
 	if err != nil && err != ErrLndVersion {
 		err = e.Wrap(err, "could not start lnd")
 		log.Fatal().Msg(err.Error())
@@ -93,16 +316,103 @@ func Main(shutdownInterceptor *intercept.Interceptor, cfg *Config, log zerolog.L
 	return nil
 }
 
-// startLnd starts LND if it's been installed with a given config
-func startLnd(cfg *Config, wg sync.WaitGroup, log *zerolog.Logger, shutdownInterceptor *intercept.Interceptor) (*bufio.Scanner, error) {
-	// Let's check if LND is installed
-	if _, err := exec.LookPath("lnd"); err != nil {
-		log.Fatal().Msg(ErrLndNotFound.Error())
-		return nil, ErrLndNotFound
+// startPluginsBeforeLnd starts the plugins which don't require LND to be ready and waits until they are all running
+func startPluginsBeforeLnd(ctx context.Context, cfg *Config, plugins *PluginManager) error {
+	gate, err := NewStartupGate(plugins, cfg)
+	if err != nil {
+		return err
+	}
+	names := plugins.Plugins(false)
+	if err := plugins.StartAll(names); err != nil {
+		return err
+	}
+	return gate.WaitForPlugins(ctx, names)
+}
+
+// watchWalletState connects to LND once it's up and polls the wallet state until the context is cancelled
+func watchWalletState(ctx context.Context, cfg *Config, pinning *TLSPinning, bus *EventReplay, log *zerolog.Logger) {
+	conn, err := dialLnd(ctx, cfg, pinning)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Msg(fmt.Sprintf("could not connect to lnd: %v", err))
+		}
+		return
+	}
+	defer conn.Close()
+	poller := NewWalletStatePoller(lnrpc.NewStateClient(conn), bus, cfg, log)
+	if err := poller.Run(ctx); err != nil {
+		log.Warn().Msg(err.Error())
 	}
+}
+
+// monitorLndProcess watches the resource usage of the LND process once it's started, if any threshold is configured
+func monitorLndProcess(ctx context.Context, cfg *Config, bus *EventReplay, log *zerolog.Logger) {
+	if cfg.LNDCPUWarnThreshold <= 0 && cfg.LNDMemWarnBytes == 0 {
+		return
+	}
+	events, unsubscribe := bus.Subscribe(EventLndStarted, WithReplay(1))
+	go func() {
+		defer unsubscribe()
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			// LND is not supervised yet, so sustained alerts are only logged
+			monitor, err := NewLNDProcessMonitor(cfg, event.Payload.(int), log, nil)
+			if err != nil {
+				log.Error().Msg(err.Error())
+				return
+			}
+			if err = monitor.Run(ctx); err != nil {
+				log.Warn().Msg(fmt.Sprintf("LND process monitor stopped: %v", err))
+			}
+		}
+	}()
+}
+
+// markLndStarted marks the start of LND on the timeline once it's started
+func markLndStarted(ctx context.Context, bus *EventReplay, timeline *StartupTimeline) {
+	events, unsubscribe := bus.Subscribe(EventLndStarted, WithReplay(1))
+	go func() {
+		defer unsubscribe()
+		select {
+		case <-ctx.Done():
+		case <-events:
+			timeline.Mark(StartupLndStarted)
+		}
+	}()
+}
+
+// watchLndInterfaces watches the network interfaces for address changes once LND is started
+func watchLndInterfaces(ctx context.Context, cfg *Config, pinning *TLSPinning, bus *EventReplay, log *zerolog.Logger) {
+	events, unsubscribe := bus.Subscribe(EventLndStarted, WithReplay(1))
+	go func() {
+		defer unsubscribe()
+		select {
+		case <-ctx.Done():
+		case <-events:
+			NewLNDNetworkInterfaceWatcher(cfg, pinning, log).Run(ctx)
+		}
+	}()
+}
+
+// findLndBinary returns the path of the lnd binary in the PATH once its signature is verified
+func findLndBinary(cfg *Config, log *zerolog.Logger) (string, error) {
+	lndPath, err := exec.LookPath("lnd")
+	if err != nil {
+		return "", ErrLndNotFound
+	}
+	if err = verifyLndBinary(cfg, lndPath, log); err != nil {
+		return "", err
+	}
+	return lndPath, nil
+}
+
+// startLnd starts the verified lnd binary at lndPath with a given config
+func startLnd(cfg *Config, lndPath string, bus *EventReplay, lndOutput *LNDProcessOutput, logStats *LNDLogAggregator, reporter *FailureReporter, wg *sync.WaitGroup, log *zerolog.Logger, shutdownInterceptor *intercept.Interceptor) (*bufio.Scanner, error) {
 	// Check to see if we called -V, if so, we call lnd -V, display and exit
 	if cfg.LndShowVersion {
-		cmd := exec.Command("lnd", "--version")
+		cmd := exec.Command(lndPath, "--version")
 		cmdReader, err := cmd.StderrPipe()
 		if err != nil {
 			log.Fatal().Msg(fmt.Sprint(err))
@@ -125,27 +435,65 @@ func startLnd(cfg *Config, wg sync.WaitGroup, log *zerolog.Logger, shutdownInter
 		}
 		return nil, ErrLndVersion
 	}
-	// We get all LND config from our config to pass onto LND
-	args := cfg.GetConfigTagValues()
+	for {
+		// We get all LND config from our config to pass onto LND
+		args, _, err := NewLNDConfigSanitizer(log).Sanitize(cfg.GetConfigTagValues())
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+			return nil, err
+		}
+
+		// startup LND
+		cmd := exec.Command(lndPath, args...)
+		cmdReader, err := cmd.StderrPipe()
+		if err != nil {
+			log.Fatal().Msg(fmt.Sprint(err))
+			return nil, err
+		}
+		scanner := bufio.NewScanner(cmdReader)
+		re := regexp.MustCompile(lndLogRegex)
+		wg.Add(1)
+		go parseLndLog(scanner, log, re, NewLNDLogFilterFromConfig(cfg), lndOutput, logStats, shutdownInterceptor.ShutdownChannel(), wg)
+		if err := startLndProcess(cmd); err != nil {
+			log.Fatal().Msg(fmt.Sprint(err))
+			return scanner, err
+		}
+		if limits := NewProcessCGroupConfig(cfg); limits.Enabled() {
+			if err := limits.Apply(cmd.Process.Pid); err != nil {
+				log.Warn().Msg(fmt.Sprintf("LND runs without resource limits: %v", err))
+			}
+		}
+		bus.Publish(EventLndStarted, cmd.Process.Pid)
+		reporter.LndStarted()
+		err = waitLndProcess(cmd)
+		// LND stopped by conduit_lnd_restart is started again with the LND options of the reloaded config, unless Conduit is shutting down
+		if lndRestartRequested() && !isShuttingDown(shutdownInterceptor) {
+			if cfg, err = initConfig(false, &ConfigProfile{}); err != nil {
+				err = e.Wrap(err, "could not reload config")
+				log.Fatal().Msg(err.Error())
+				return scanner, err
+			}
+			log.Info().Msg("Starting LND again")
+			continue
+		}
+		if err != nil {
+			// the report is sent before exiting, as logging the crash is fatal
+			if err := reporter.Report(reporter.Event(err.Error())); err != nil {
+				log.Error().Msg(err.Error())
+			}
+			log.Fatal().Msg(fmt.Sprint(err))
+			return scanner, err
+		}
+		return scanner, nil
+	}
+}
 
-	// startup LND
-	cmd := exec.Command("lnd", args...)
-	cmdReader, err := cmd.StderrPipe()
-	if err != nil {
-		log.Fatal().Msg(fmt.Sprint(err))
-		return nil, err
-	}
-	scanner := bufio.NewScanner(cmdReader)
-	re := regexp.MustCompile(lndLogRegex)
-	wg.Add(1)
-	go parseLndLog(scanner, log, re, shutdownInterceptor.ShutdownChannel(), wg)
-	if err := cmd.Start(); err != nil {
-		log.Fatal().Msg(fmt.Sprint(err))
-		return scanner, err
-	}
-	if err := cmd.Wait(); err != nil {
-		log.Fatal().Msg(fmt.Sprint(err))
-		return scanner, err
-	}
-	return scanner, nil
+// isShuttingDown reports whether the shutdown of Conduit was requested
+func isShuttingDown(shutdownInterceptor *intercept.Interceptor) bool {
+	select {
+	case <-shutdownInterceptor.ShutdownChannel():
+		return true
+	default:
+		return false
+	}
 }
@@ -24,18 +24,18 @@ import (
 	"log"
 	"os"
 	"path"
-	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/TheRebelOfBabylon/Conduit/errors"
 	"github.com/TheRebelOfBabylon/Conduit/utils"
-	flags "github.com/jessevdk/go-flags"
 	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/watchtowerrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/wtclientrpc"
-	yaml "gopkg.in/yaml.v2"
 )
 
 type subRPCServerConfigs struct {
@@ -68,55 +68,96 @@ type subRPCServerConfigs struct {
 
 // Config is the object which will hold all of the config parameters
 type Config struct {
-	DefaultDir            bool     `yaml:"DefaultDir" long:"defaultdir" description:"Whether Conduit writes files to default directory or not"`
-	ConduitDir            string   `yaml:"ConduitDir" long:"conduitdir" description:"Path to conduit configuration file"`
-	ConsoleOutput         bool     `yaml:"ConsoleOutput" long:"console-output" description:"Whether or not Conduit prints the log to the console"`
-	ShowVersion           bool     `short:"v" long:"version" description:"Display version information and exit"`
-	LndConfigPath         string   `short:"C" long:"configfile" description:"Path to configuration file"`
-	LndShowVersion        bool     `short:"V" long:"lnd-version" description:"Display LND version information and exit"`
-	LndDataDir            string   `short:"b" long:"datadir" description:"The directory to store lnd's data within"`
-	LndSyncFreelist       bool     `long:"sync-freelist" description:"Whether the databases used within lnd should sync their freelist to disk. This is disabled by default resulting in improved memory performance during operation, but with an increase in startup time."`
-	LndTLSCertPath        string   `long:"tlscertpath" description:"Path to write the TLS certificate for lnd's RPC and REST services"`
-	LndTLSKeyPath         string   `long:"tlskeypath" description:"Path to write the TLS private key for lnd's RPC and REST services"`
-	LndTLSExtraIPs        []string `long:"tlsextraip" description:"Adds an extra ip to the generated certificate"`
-	LndTLSExtraDomains    []string `long:"tlsextradomain" description:"Adds an extra domain to the generated certificate"`
-	LndTLSAutoRefresh     bool     `long:"tlsautorefresh" description:"Re-generate TLS certificate and key if the IPs or domains are changed"`
-	LndTLSDisableAutofill bool     `long:"tlsdisableautofill" description:"Do not include the interface IPs or the system hostname in TLS certificate, use first --tlsextradomain as Common Name instead, if set"`
-	LndTLSCertDuration    string   `long:"tlscertduration" description:"The duration for which the auto-generated TLS certificate will be valid for"`
-	LndNoMacaroons        bool     `long:"no-macaroons" description:"Disable macaroon authentication, can only be used if server is not listening on a public interface."`
-	LndAdminMacPath       string   `long:"adminmacaroonpath" description:"Path to write the admin macaroon for lnd's RPC and REST services if it doesn't exist"`
-	LndReadMacPath        string   `long:"readonlymacaroonpath" description:"Path to write the read-only macaroon for lnd's RPC and REST services if it doesn't exist"`
-	LndInvoiceMacPath     string   `long:"invoicemacaroonpath" description:"Path to the invoice-only macaroon for lnd's RPC and REST services if it doesn't exist"`
-	LndLogDir             string   `long:"logdir" description:"Directory to log output."`
-	LndMaxLogFiles        string   `long:"maxlogfiles" description:"Maximum logfiles to keep (0 for no rotation)"`
-	LndMaxLogFileSize     string   `long:"maxlogfilesize" description:"Maximum logfile size in MB"`
-	LndAcceptorTimeout    string   `long:"acceptortimeout" description:"Time after which an RPCAcceptor will time out and return false if it hasn't yet received a response"`
-	LndLetsEncryptDir     string   `long:"letsencryptdir" description:"The directory to store Let's Encrypt certificates within"`
-	LndLetsEncryptListen  string   `long:"letsencryptlisten" description:"The IP:port on which lnd will listen for Let's Encrypt challenges. Let's Encrypt will always try to contact on port 80. Often non-root processes are not allowed to bind to ports lower than 1024. This configuration option allows a different port to be used, but must be used in combination with port forwarding from port 80. This configuration can also be used to specify another IP address to listen on, for example an IPv6 address."`
-	LndLetsEncryptDomain  string   `long:"letsencryptdomain" description:"Request a Let's Encrypt certificate for this domain. Note that the certicate is only requested and stored when the first rpc connection comes in."`
-	LndRawRPCListeners    []string `long:"rpclisten" description:"Add an interface/port/socket to listen for RPC connections"`
-	LndRawRESTListeners   []string `long:"restlisten" description:"Add an interface/port/socket to listen for REST connections"`
-	LndRawListeners       []string `long:"listen" description:"Add an interface/port to listen for peer connections"`
-	LndRawExternalIPs     []string `long:"externalip" description:"Add an ip:port to the list of local addresses we claim to listen on to peers. If a port is not specified, the default (9735) will be used regardless of other parameters"`
-	LndExternalHosts      []string `long:"externalhosts" description:"A set of hosts that should be periodically resolved to announce IPs for"`
-	LndRestCORS           []string `long:"restcors" description:"Add an ip:port/hostname to allow cross origin access from. To allow all origins, set as \"*\"."`
-	LndDisableListen      bool     `long:"nolisten" description:"Disable listening for incoming peer connections"`
-	LndDisableRest        bool     `long:"norest" description:"Disable REST API"`
-	LndDisableRestTLS     bool     `long:"no-rest-tls" description:"Disable TLS for REST connections"`
-	LndWSPingInterval     string   `long:"ws-ping-interval" description:"The ping interval for REST based WebSocket connections, set to 0 to disable sending ping messages from the server side"`
-	LndWSPongWait         string   `long:"ws-pong-wait" description:"The time we wait for a pong response message on REST based WebSocket connections before the connection is closed as inactive"`
-	LndNAT                bool     `long:"nat" description:"Toggle NAT traversal support (using either UPnP or NAT-PMP) to automatically advertise your external IP address to the network -- NOTE this does not support devices behind multiple NATs"`
-	LndMinBackoff         string   `long:"minbackoff" description:"Shortest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
-	LndMaxBackoff         string   `long:"maxbackoff" description:"Longest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
-	LndConnectionTimeout  string   `long:"connectiontimeout" description:"The timeout value for network connections. Valid time units are {ms, s, m, h}."`
-	LndDebugLevel         string   `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <global-level>,<subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
-	LndCPUProfile         string   `long:"cpuprofile" description:"Write CPU profile to the specified file"`
-	LndProfile            string   `long:"profile" description:"Enable HTTP profiling on either a port or host:port"`
-	LndUnsafeDisconnect   bool     `long:"unsafe-disconnect" description:"DEPRECATED: Allows the rpcserver to intentionally disconnect from peers with open channels. THIS FLAG WILL BE REMOVED IN 0.10.0"`
-	LndUnsafeReplay       bool     `long:"unsafe-replay" description:"Causes a link to replay the adds on its commitment txn after starting up, this enables testing of the sphinx replay logic."`
-	LndMaxPendingChannels string   `long:"maxpendingchannels" description:"The maximum number of incoming pending channels permitted per peer."`
-	LndBackupFilePath     string   `long:"backupfilepath" description:"The target location of the channel backup file"`
-	LndFeeURL             string   `long:"feeurl" description:"Optional URL for external fee estimation. If no URL is specified, the method for fee estimation will depend on the chosen backend and network. Must be set for neutrino on mainnet."`
+	ChannelCloseWebhookURL    string            `yaml:"ChannelCloseWebhookURL" long:"channel-close-webhook-url" default-mask:"-" description:"URL to which a notification is posted when a channel is force closed or breached. Disabled when empty"`
+	CrashWebhookSecret        string            `yaml:"CrashWebhookSecret" long:"crash-webhook-secret" default-mask:"-" description:"Secret with which crash reports are signed in the X-Conduit-Signature header"`
+	CrashWebhookURL           string            `yaml:"CrashWebhookURL" long:"crash-webhook-url" default-mask:"-" description:"URL to which a crash report is posted when LND stops unexpectedly. Reports are disabled when empty"`
+	DebugMode                 bool              `yaml:"DebugMode" long:"debug-mode" description:"Whether the current configuration is served at /debug/config on the JSON-RPC listen address and the goroutine stacks by conduit_debug_goroutines"`
+	DefaultDir                bool              `yaml:"DefaultDir" long:"defaultdir" description:"Whether Conduit writes files to default directory or not"`
+	DisableUpdateCheck        bool              `yaml:"DisableUpdateCheck" long:"disable-update-check" description:"Whether the daily check for new LND releases on GitHub is disabled"`
+	ConduitDir                string            `yaml:"ConduitDir" long:"conduitdir" description:"Path to conduit configuration file"`
+	ConsoleOutput             bool              `yaml:"ConsoleOutput" long:"console-output" description:"Whether or not Conduit prints the log to the console"`
+	ErrorContextEnabled       bool              `yaml:"ErrorContextEnabled" long:"error-context-enabled" description:"Whether the Conduit directory, LND version and node alias are added to every error log event"`
+	ExpectedConfigHash        string            `yaml:"ExpectedConfigHash" json:"-" long:"expected-config-hash" description:"ConfigAuditHash the config must have for Conduit to start, as printed by conduitcli config hash. The check is skipped when empty"`
+	FeeAPIURL                 string            `yaml:"FeeAPIURL" long:"fee-api-url" description:"URL of the API returning the recommended fee rates in the mempool.space format. Defaults to https://mempool.space/api/v1/fees/recommended"`
+	FeeWarnThreshold          uint64            `yaml:"FeeWarnThreshold" long:"fee-warn-threshold" description:"Fastest fee rate, in sat/vbyte, above which a warning is logged. Defaults to 500"`
+	ForwardingExportInterval  string            `yaml:"ForwardingExportInterval" long:"forwarding-export-interval" description:"Interval at which new LND forwarding events are appended to forwarding_history.csv. Defaults to 1h"`
+	JsonRPCDefaultTimeout     string            `yaml:"JsonRPCDefaultTimeout" long:"jsonrpc-default-timeout" description:"Maximum duration of a JSON-RPC call for the methods without a timeout of their own. Defaults to 30s"`
+	JsonRPCListen             string            `yaml:"JsonRPCListen" long:"jsonrpc-listen" description:"Address on which the Conduit JSON-RPC server listens"`
+	JsonRPCRateBurst          int               `yaml:"JsonRPCRateBurst" long:"jsonrpc-rate-burst" description:"Number of JSON-RPC calls which may exceed JsonRPCRateLimit in a burst. Defaults to 20"`
+	JsonRPCRateLimit          float64           `yaml:"JsonRPCRateLimit" long:"jsonrpc-rate-limit" description:"Maximum number of JSON-RPC calls per second, shared by the methods without a limit of their own. Defaults to 10"`
+	JsonRPCTLSCertPath        string            `yaml:"JsonRPCTLSCertPath" long:"jsonrpc-tlscertpath" description:"Path to the TLS certificate of the JSON-RPC server. The server uses plain HTTP unless both the certificate and key are set"`
+	JsonRPCTLSKeyPath         string            `yaml:"JsonRPCTLSKeyPath" long:"jsonrpc-tlskeypath" description:"Path to the TLS private key of the JSON-RPC server"`
+	LiquidityCheckInterval    string            `yaml:"LiquidityCheckInterval" long:"liquidity-check-interval" description:"Interval at which the local balance of the channels is checked. Defaults to 5m"`
+	LiquidityWarnThresholdPct float64           `yaml:"LiquidityWarnThresholdPct" long:"liquidity-warn-threshold-pct" description:"Local balance, in percent of the channel capacity, below which a warning is logged. Defaults to 10"`
+	LndCGroupCPU              int               `yaml:"LndCGroupCPU" long:"lnd-cgroup-cpu" lnd:"-" description:"CPU quota of the LND process, in percent of one core, enforced with a cgroup on Linux. Disabled when 0"`
+	LndCGroupMemMB            int               `yaml:"LndCGroupMemMB" long:"lnd-cgroup-mem-mb" lnd:"-" description:"Memory limit of the LND process, in MB, enforced with a cgroup on Linux. Disabled when 0"`
+	LNDCPUWarnThreshold       float64           `yaml:"LNDCPUWarnThreshold" long:"lnd-cpu-warn-threshold" description:"CPU usage of the LND process, in percent of one core, above which a warning is logged. Disabled when 0"`
+	LNDMemWarnBytes           uint64            `yaml:"LNDMemWarnBytes" long:"lnd-mem-warn-bytes" description:"Resident memory of the LND process, in bytes, above which a warning is logged. Disabled when 0"`
+	LNDMonitorInterval        string            `yaml:"LNDMonitorInterval" long:"lnd-monitor-interval" description:"Interval at which the LND process resource usage is sampled. Defaults to 30s"`
+	LNDRPCReconnectMaxBackoff string            `yaml:"LNDRPCReconnectMaxBackoff" long:"lnd-rpc-reconnect-max-backoff" description:"Longest wait between two attempts to reconnect to the LND gRPC server. Defaults to 1m"`
+	LNDSubsystemLevels        map[string]string `yaml:"LNDSubsystemLevels" long:"lnd-subsystem-level" description:"Minimum level of the LND log lines kept by Conduit for a subsystem, like CRTR:warn. LND still writes the dropped lines to its own log file"`
+	LNDSignatureKeyPath       string            `yaml:"LNDSignatureKeyPath" long:"lnd-signature-key-path" description:"Path to the PGP public key, armored or not, with which the LND binary must be signed"`
+	LNDSignaturePath          string            `yaml:"LNDSignaturePath" long:"lnd-signature-path" description:"Path to the detached PGP signature of the LND binary. Conduit refuses to start LND if it doesn't verify. The binary isn't verified when empty"`
+	LogSampleRate             int               `yaml:"LogSampleRate" long:"log-sample-rate" description:"Maximum number of identical log events written per sample window. Set to 0 to disable sampling"`
+	LogSampleWindow           string            `yaml:"LogSampleWindow" long:"log-sample-window" description:"Duration of the log sample window. Defaults to 1m"`
+	MemStatsInterval          string            `yaml:"MemStatsInterval" long:"memstats-interval" description:"Interval at which Go runtime memory statistics are logged. Defaults to 5m"`
+	MetricsListen             string            `yaml:"MetricsListen" long:"metrics-listen" description:"Address on which Conduit serves Prometheus metrics. Metrics are disabled when empty"`
+	PluginIPCTrace            bool              `yaml:"PluginIPCTrace" long:"plugin-ipc-trace" description:"Whether every message exchanged between plugins is recorded in ipc_trace.log, for debugging"`
+	PluginMacaroonTTL         string            `yaml:"PluginMacaroonTTL" long:"plugin-macaroon-ttl" description:"How long the macaroons baked for the plugins declaring LND capabilities are valid. Defaults to 24h"`
+	PluginRegistryURL         string            `yaml:"PluginRegistryURL" long:"plugin-registry-url" description:"URL of the registry from which conduitcli plugin install downloads plugins. Plugins can't be installed until it's set"`
+	PluginStartTimeout        string            `yaml:"PluginStartTimeout" long:"plugin-start-timeout" description:"Maximum time to wait for the plugins started before LND to be running. Defaults to 30s"`
+	SkipSignatureVerification bool              `yaml:"SkipSignatureVerification" long:"skip-signature-verification" description:"Whether LND is started without verifying the signature of its binary, even if LNDSignaturePath is set. Not recommended"`
+	SyslogNetwork             string            `yaml:"SyslogNetwork" long:"syslog-network" description:"Network used to reach the syslog server (udp, tcp or unix). Defaults to udp"`
+	SyslogAddr                string            `yaml:"SyslogAddr" long:"syslog-addr" description:"Address of the syslog server to which LND logs are forwarded. Forwarding is disabled when empty"`
+	SyslogTag                 string            `yaml:"SyslogTag" long:"syslog-tag" description:"Tag of the forwarded syslog messages. Defaults to lnd"`
+	TLSExpiryWebhookURL       string            `yaml:"TLSExpiryWebhookURL" long:"tls-expiry-webhook-url" default-mask:"-" description:"URL to which a notification is posted when a TLS certificate expires soon. Disabled when empty"`
+	TLSWarnDays               int               `yaml:"TLSWarnDays" long:"tls-warn-days" description:"Number of days before the expiry of a TLS certificate from which a warning is logged daily. Defaults to 30"`
+	ShowVersion               bool              `short:"v" long:"version" description:"Display version information and exit"`
+	LndConfigPath             string            `short:"C" long:"configfile" description:"Path to configuration file"`
+	LndShowVersion            bool              `short:"V" long:"lnd-version" lnd:"-" description:"Display LND version information and exit"`
+	LndDataDir                string            `short:"b" long:"datadir" description:"The directory to store lnd's data within"`
+	LndSyncFreelist           bool              `long:"sync-freelist" description:"Whether the databases used within lnd should sync their freelist to disk. This is disabled by default resulting in improved memory performance during operation, but with an increase in startup time."`
+	LndTLSCertPath            string            `long:"tlscertpath" description:"Path to write the TLS certificate for lnd's RPC and REST services"`
+	LndTLSKeyPath             string            `long:"tlskeypath" description:"Path to write the TLS private key for lnd's RPC and REST services"`
+	LndTLSExtraIPs            []string          `long:"tlsextraip" description:"Adds an extra ip to the generated certificate"`
+	LndTLSExtraDomains        []string          `long:"tlsextradomain" description:"Adds an extra domain to the generated certificate"`
+	LndTLSAutoRefresh         bool              `long:"tlsautorefresh" description:"Re-generate TLS certificate and key if the IPs or domains are changed"`
+	LndTLSDisableAutofill     bool              `long:"tlsdisableautofill" description:"Do not include the interface IPs or the system hostname in TLS certificate, use first --tlsextradomain as Common Name instead, if set"`
+	LndTLSCertDuration        string            `long:"tlscertduration" description:"The duration for which the auto-generated TLS certificate will be valid for"`
+	LndNoMacaroons            bool              `long:"no-macaroons" description:"Disable macaroon authentication, can only be used if server is not listening on a public interface."`
+	LndAdminMacPath           string            `long:"adminmacaroonpath" description:"Path to write the admin macaroon for lnd's RPC and REST services if it doesn't exist"`
+	LndReadMacPath            string            `long:"readonlymacaroonpath" description:"Path to write the read-only macaroon for lnd's RPC and REST services if it doesn't exist"`
+	LndInvoiceMacPath         string            `long:"invoicemacaroonpath" description:"Path to the invoice-only macaroon for lnd's RPC and REST services if it doesn't exist"`
+	LndLogDir                 string            `long:"logdir" description:"Directory to log output."`
+	LndMaxLogFiles            string            `long:"maxlogfiles" description:"Maximum logfiles to keep (0 for no rotation)"`
+	LndMaxLogFileSize         string            `long:"maxlogfilesize" description:"Maximum logfile size in MB"`
+	LndAcceptorTimeout        string            `long:"acceptortimeout" description:"Time after which an RPCAcceptor will time out and return false if it hasn't yet received a response"`
+	LndLetsEncryptDir         string            `long:"letsencryptdir" description:"The directory to store Let's Encrypt certificates within"`
+	LndLetsEncryptListen      string            `long:"letsencryptlisten" description:"The IP:port on which lnd will listen for Let's Encrypt challenges. Let's Encrypt will always try to contact on port 80. Often non-root processes are not allowed to bind to ports lower than 1024. This configuration option allows a different port to be used, but must be used in combination with port forwarding from port 80. This configuration can also be used to specify another IP address to listen on, for example an IPv6 address."`
+	LndLetsEncryptDomain      string            `long:"letsencryptdomain" description:"Request a Let's Encrypt certificate for this domain. Note that the certicate is only requested and stored when the first rpc connection comes in."`
+	LndRawRPCListeners        []string          `long:"rpclisten" description:"Add an interface/port/socket to listen for RPC connections"`
+	LndRawRESTListeners       []string          `long:"restlisten" description:"Add an interface/port/socket to listen for REST connections"`
+	LndRawListeners           []string          `long:"listen" description:"Add an interface/port to listen for peer connections"`
+	LndRawExternalIPs         []string          `long:"externalip" description:"Add an ip:port to the list of local addresses we claim to listen on to peers. If a port is not specified, the default (9735) will be used regardless of other parameters"`
+	LndExternalHosts          []string          `long:"externalhosts" description:"A set of hosts that should be periodically resolved to announce IPs for"`
+	LndRestCORS               []string          `long:"restcors" description:"Add an ip:port/hostname to allow cross origin access from. To allow all origins, set as \"*\"."`
+	LndDisableListen          bool              `long:"nolisten" description:"Disable listening for incoming peer connections"`
+	LndDisableRest            bool              `long:"norest" description:"Disable REST API"`
+	LndDisableRestTLS         bool              `long:"no-rest-tls" description:"Disable TLS for REST connections"`
+	LndWSPingInterval         string            `long:"ws-ping-interval" description:"The ping interval for REST based WebSocket connections, set to 0 to disable sending ping messages from the server side"`
+	LndWSPongWait             string            `long:"ws-pong-wait" description:"The time we wait for a pong response message on REST based WebSocket connections before the connection is closed as inactive"`
+	LndNAT                    bool              `long:"nat" description:"Toggle NAT traversal support (using either UPnP or NAT-PMP) to automatically advertise your external IP address to the network -- NOTE this does not support devices behind multiple NATs"`
+	LndMinBackoff             string            `long:"minbackoff" description:"Shortest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
+	LndMaxBackoff             string            `long:"maxbackoff" description:"Longest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
+	LndConnectionTimeout      string            `long:"connectiontimeout" description:"The timeout value for network connections. Valid time units are {ms, s, m, h}."`
+	LndDebugLevel             string            `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <global-level>,<subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
+	LndCPUProfile             string            `long:"cpuprofile" description:"Write CPU profile to the specified file"`
+	LndProfile                string            `long:"profile" description:"Enable HTTP profiling on either a port or host:port"`
+	LndUnsafeDisconnect       bool              `long:"unsafe-disconnect" description:"DEPRECATED: Allows the rpcserver to intentionally disconnect from peers with open channels. THIS FLAG WILL BE REMOVED IN 0.10.0"`
+	LndUnsafeReplay           bool              `long:"unsafe-replay" description:"Causes a link to replay the adds on its commitment txn after starting up, this enables testing of the sphinx replay logic."`
+	LndMaxPendingChannels     string            `long:"maxpendingchannels" description:"The maximum number of incoming pending channels permitted per peer."`
+	LndBackupFilePath         string            `long:"backupfilepath" description:"The target location of the channel backup file"`
+	LndFeeURL                 string            `long:"feeurl" description:"Optional URL for external fee estimation. If no URL is specified, the method for fee estimation will depend on the chosen backend and network. Must be set for neutrino on mainnet."`
 
 	LndBitcoinActive              bool     `long:"bitcoin.active" description:"If the chain should be active or not."`
 	LndBitcoinChainDir            string   `long:"bitcoin.chaindir" description:"The directory to store the chain's data within."`
@@ -400,9 +441,13 @@ type Config struct {
 	MigrateWatchOnly bool   `long:"remotesigner.migrate-wallet-to-watch-only" description:"If a wallet with private key material already exists, migrate it into a watch-only wallet on first startup. WARNING: This cannot be undone! Make sure you have backed up your seed before you use this flag! All private keys will be purged from the wallet after first unlock with this flag!"`
 }
 
+const ErrInvalidLNDLogLevel = errors.Error("invalid LND log level")
+
 var (
-	config_file_name string = "config.yaml"
-	default_dir             = func() string {
+	lnd_log_levels             = map[string]bool{"trace": true, "debug": true, "info": true, "warn": true, "error": true, "critical": true}
+	lnd_subsystem_regex        = regexp.MustCompile(`^[A-Z]{4}$`)
+	config_file_name    string = "config.yaml"
+	default_dir                = func() string {
 		return utils.AppDataDir("conduit", false)
 	}
 	default_config = func() *Config {
@@ -416,6 +461,12 @@ var (
 
 // InitConfig returns the `Config` struct with either default values, values specified in `config.yaml` or command line flags
 func InitConfig(isTesting bool) (*Config, error) {
+	return DefaultConfigProfiler.InitConfig(isTesting)
+}
+
+// initConfig is InitConfig, recording the time spent in each phase in profile
+func initConfig(isTesting bool, profile *ConfigProfile) (*Config, error) {
+	start := time.Now()
 	// Check if fmtd directory exists, if no then create it
 	if !utils.FileExists(utils.AppDataDir("conduit", false)) {
 		err := os.Mkdir(utils.AppDataDir("conduit", false), 0775)
@@ -423,36 +474,85 @@ func InitConfig(isTesting bool) (*Config, error) {
 			log.Println(err)
 		}
 	}
-	config := &Config{}
-	if utils.FileExists(path.Join(default_dir(), config_file_name)) {
-		filename, _ := filepath.Abs(path.Join(default_dir(), config_file_name))
-		config_file, err := ioutil.ReadFile(filename)
-		if err != nil {
-			log.Println(err)
-			return default_config(), nil
-		}
-		err = yaml.Unmarshal(config_file, config)
-		if err != nil {
-			log.Println(err)
-			config = default_config()
-		} else {
-			// Need to check if any config parameters aren't defined in `config.yaml` and assign them a default value
-			config = check_yaml_config(config)
-		}
-	} else {
-		config = default_config()
+	yamlSource := &YAMLSource{Filename: path.Join(default_dir(), config_file_name)}
+	fromFile, err := yamlSource.Load()
+	if err != nil {
+		return nil, err
 	}
+	// the defaults are overridden by config.yaml, then by the secrets stored with `conduitcli secrets set`, the CONDUIT_ environment variables and finally by the flags
+	merger := NewConfigMerger().AddSource(ConfigPriorityDefault, DefaultSource{}).AddSource(ConfigPriorityYAML, yamlSource)
 	if !isTesting {
-		// now to parse the flags
-		if _, err := flags.Parse(config); err != nil {
-			return nil, err
+		addOverridingSources(merger, fromFile).AddSource(ConfigPriorityFlags, &FlagSource{})
+	}
+	config, err := merger.Merge()
+	if err != nil {
+		return nil, err
+	}
+	profile.YAMLParse = time.Since(start) - merger.LoadTime(ConfigPrioritySecrets) - merger.LoadTime(ConfigPriorityEnv) - merger.LoadTime(ConfigPriorityFlags)
+	profile.EnvLoad = merger.LoadTime(ConfigPrioritySecrets) + merger.LoadTime(ConfigPriorityEnv)
+	profile.FlagParse = merger.LoadTime(ConfigPriorityFlags)
+	if config.ShowVersion {
+		fmt.Println(utils.AppName, "version", utils.AppVersion)
+		os.Exit(0)
+	}
+	start = time.Now()
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	profile.Validation = time.Since(start)
+	return config, nil
+}
+
+// addOverridingSources adds the sources overriding the config file on startup, except the flags, to the merger: the secrets stored with
+// `conduitcli secrets set` in the conduit directory of the file, or in the default one, and the CONDUIT_ environment variables
+func addOverridingSources(merger *ConfigMerger, fromFile *Config) *ConfigMerger {
+	conduitDir := fromFile.ConduitDir
+	if conduitDir == "" {
+		conduitDir = default_dir()
+	}
+	return merger.AddSource(ConfigPrioritySecrets, &SecretSource{ConduitDir: conduitDir}).AddSource(ConfigPriorityEnv, &EnvSource{})
+}
+
+// ValidateConfig checks that the config parameters have a valid format
+func ValidateConfig(config *Config) error {
+	if err := ValidateLNDLogLevel(config.LndDebugLevel); err != nil {
+		return err
+	}
+	if err := ValidateLNDSubsystemLevels(config.LNDSubsystemLevels); err != nil {
+		return err
+	}
+	if err := NewConfigHashValidator(config.ExpectedConfigHash).Validate(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidateLNDLogLevel checks that level is either a global log level or a `,`-separated list of `<subsystem>=<level>` overrides with an optional global level
+func ValidateLNDLogLevel(level string) error {
+	if level == "" || level == "show" {
+		return nil
+	}
+	global := false
+	for _, token := range strings.Split(level, ",") {
+		split := strings.Split(token, "=")
+		switch {
+		case len(split) == 1:
+			if global {
+				return fmt.Errorf("%w %q: more than one global level", ErrInvalidLNDLogLevel, level)
+			}
+			global = true
+		case len(split) == 2:
+			if !lnd_subsystem_regex.MatchString(split[0]) {
+				return fmt.Errorf("%w %q: invalid subsystem %q", ErrInvalidLNDLogLevel, level, split[0])
+			}
+		default:
+			return fmt.Errorf("%w %q: expected <subsystem>=<level>, got %q", ErrInvalidLNDLogLevel, level, token)
 		}
-		if config.ShowVersion {
-			fmt.Println(utils.AppName, "version", utils.AppVersion)
-			os.Exit(0)
+		if lvl := split[len(split)-1]; !lnd_log_levels[lvl] {
+			return fmt.Errorf("%w %q: unknown level %q", ErrInvalidLNDLogLevel, level, lvl)
 		}
 	}
-	return config, nil
+	return nil
 }
 
 // change_field changes the value of a specified field from the config struct
@@ -492,18 +592,96 @@ func check_yaml_config(config *Config) *Config {
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Field(i)
 		field_name := field_names.Field(i).Name
+		// secrets are left as is since passwords may contain a literal $
+		if f.Kind() == reflect.String && !IsSensitiveField(field_name) {
+			change_field(f, utils.ExpandEnvVars(f.String()))
+		}
 		switch field_name {
 		case "ConduitDir":
 			if f.String() == "" {
 				change_field(f, default_dir())
 				dld := v.FieldByName("DefaultDir")
 				change_field(dld, true)
+			} else {
+				change_field(f, utils.ExpandPath(f.String()))
 			}
 		}
 	}
 	return config
 }
 
+// IsSensitiveField reports whether the named `Config` field holds a secret which must never be displayed or exported in plaintext
+func IsSensitiveField(name string) bool {
+	f, ok := reflect.TypeOf(Config{}).FieldByName(name)
+	if !ok {
+		return false
+	}
+	if _, ok := f.Tag.Lookup("default-mask"); ok {
+		return true
+	}
+	return strings.Contains(f.Name, "Pass")
+}
+
+// SensitiveFields returns the names of all `Config` fields which hold secrets
+func SensitiveFields() []string {
+	var names []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if IsSensitiveField(t.Field(i).Name) {
+			names = append(names, t.Field(i).Name)
+		}
+	}
+	return names
+}
+
+// UpdateConfigFile sets the given `Config` fields in a YAML config file, leaving all other keys, comments and blank lines untouched, and returns their previous values
+func UpdateConfigFile(filename string, fields map[string]interface{}) (map[string]string, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	editor, err := newConfigFileEditor(raw)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]string)
+	for name, value := range fields {
+		entry, ok := editor.Lookup(name)
+		if !ok {
+			previous[name] = ""
+			if err = editor.Append(configFileKey(name), value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if previous[name], err = entry.Previous(); err != nil {
+			return nil, err
+		}
+		if err = editor.Set(entry, value); err != nil {
+			return nil, err
+		}
+	}
+	return previous, utils.AtomicWriteFile(filename, editor.Bytes(), info.Mode().Perm())
+}
+
+// configFileKey returns the key under which a `Config` field is stored in the YAML config file
+func configFileKey(name string) string {
+	f, ok := reflect.TypeOf(Config{}).FieldByNameFunc(func(field string) bool {
+		return strings.EqualFold(field, name)
+	})
+	if ok {
+		if tag, ok := f.Tag.Lookup("yaml"); ok {
+			return strings.Split(tag, ",")[0]
+		}
+	}
+	// yaml.v2 defaults to the lowercased field name
+	return strings.ToLower(name)
+}
+
 // getInterfaceFromReflection returns an interface from a reflection
 func getInterfaceFromReflection(fType reflect.Value) interface{} {
 	if fType.IsValid() {
@@ -512,45 +690,44 @@ func getInterfaceFromReflection(fType reflect.Value) interface{} {
 	return nil
 }
 
-// GetConfigTagValues returns a map of the LND config parameters and there values as parsed from the command line
-func (c *Config) GetConfigTagValues() []string {
-	var tags []string
+// eachLndOption calls f with the long name and value of every LND config parameter. The Lnd fields tagged `lnd:"-"` are Conduit
+// options about LND, which LND doesn't know
+func (c *Config) eachLndOption(f func(alias string, value interface{})) {
 	pv := reflect.ValueOf(c)
 	v := pv.Elem()
 	field_names := v.Type()
 	for i := 0; i < v.NumField(); i++ {
-		f := field_names.Field(i)
-		fType := v.Field(i)
-		if strings.Contains(f.Name, "Lnd") {
-			if !strings.Contains(f.Name, "ShowVersion") {
-				if alias, ok := f.Tag.Lookup("long"); ok {
-					inter := getInterfaceFromReflection(fType)
-					switch q := inter.(type) {
-					case string:
-						if q != "" {
-							tags = append(tags, fmt.Sprintf("--%v=%v", alias, q))
-						}
-					case []string:
-						if len(q) != 0 {
-							for _, arg := range q {
-								tags = append(tags, fmt.Sprintf("--%v=%v", alias, arg))
-							}
-						}
-					case bool:
-						if q {
-							tags = append(tags, fmt.Sprintf("--%v", alias))
-						}
-					case map[string]string:
-						if len(q) != 0 {
-							for jam, arg := range q {
-								tags = append(tags, fmt.Sprintf("--%v=%v:%v", alias, jam, arg))
-							}
-						}
-					}
-
-				}
+		field := field_names.Field(i)
+		if strings.Contains(field.Name, "Lnd") && field.Tag.Get("lnd") != "-" {
+			if alias, ok := field.Tag.Lookup("long"); ok {
+				f(alias, getInterfaceFromReflection(v.Field(i)))
 			}
 		}
 	}
+}
+
+// GetConfigTagValues returns a map of the LND config parameters and there values as parsed from the command line
+func (c *Config) GetConfigTagValues() []string {
+	var tags []string
+	c.eachLndOption(func(alias string, inter interface{}) {
+		switch q := inter.(type) {
+		case string:
+			if q != "" {
+				tags = append(tags, fmt.Sprintf("--%v=%v", alias, q))
+			}
+		case []string:
+			for _, arg := range q {
+				tags = append(tags, fmt.Sprintf("--%v=%v", alias, arg))
+			}
+		case bool:
+			if q {
+				tags = append(tags, fmt.Sprintf("--%v", alias))
+			}
+		case map[string]string:
+			for jam, arg := range q {
+				tags = append(tags, fmt.Sprintf("--%v=%v:%v", alias, jam, arg))
+			}
+		}
+	})
 	return tags
 }
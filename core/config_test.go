@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -162,3 +163,94 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("default_config not returning expected config. Expected: %v\tReceived: %v", d_config, default_config())
 	}
 }
+
+// TestValidateLNDLogLevel ensures simple and compound LND log levels are validated
+func TestValidateLNDLogLevel(t *testing.T) {
+	valid := []string{"", "show", "debug", "critical", "info,GRPC=debug,RPCS=trace", "GRPC=debug", "PEER=warn,info"}
+	for _, level := range valid {
+		if err := ValidateLNDLogLevel(level); err != nil {
+			t.Errorf("ValidateLNDLogLevel rejected %q: %v", level, err)
+		}
+	}
+	invalid := []string{"verbose", "info,debug", "info,GRPC", "info,GRPC=loud", "info,grpc=debug", "info,GRPC=debug=trace", "info,", "INFO"}
+	for _, level := range invalid {
+		if err := ValidateLNDLogLevel(level); !errors.Is(err, ErrInvalidLNDLogLevel) {
+			t.Errorf("ValidateLNDLogLevel accepted %q", level)
+		}
+	}
+	if err := ValidateConfig(&Config{LndDebugLevel: "info,GRPC=loud"}); err == nil {
+		t.Errorf("ValidateConfig accepted an invalid LND log level")
+	}
+}
+
+// TestCheckYAMLConfigExpandsEnvVars ensures environment variables are expanded in string fields except secrets
+func TestCheckYAMLConfigExpandsEnvVars(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CONDUIT_TEST_ALIAS", "alice")
+	config := check_yaml_config(&Config{
+		ConduitDir:         "$HOME/.conduit",
+		LndAlias:           "${CONDUIT_TEST_ALIAS}",
+		LndBitcoindRPCPass: "pa$$word",
+	})
+	if config.ConduitDir != path.Join(home, ".conduit") {
+		t.Errorf("expected ConduitDir %s, got %s", path.Join(home, ".conduit"), config.ConduitDir)
+	}
+	if config.LndAlias != "alice" {
+		t.Errorf("expected LndAlias alice, got %s", config.LndAlias)
+	}
+	if config.LndBitcoindRPCPass != "pa$$word" {
+		t.Errorf("expected the password to be left untouched, got %s", config.LndBitcoindRPCPass)
+	}
+}
+
+// TestUpdateConfigFile ensures only the lines of the updated fields change, keeping the comments and blank lines of the file
+func TestUpdateConfigFile(t *testing.T) {
+	filename := path.Join(t.TempDir(), config_file_name)
+	raw := `# Conduit config
+
+# the alias shown to peers
+LndAlias: old-alias # max 32 bytes
+LndColor: "#000000"
+
+LndTLSExtraIPs:
+	- 10.0.0.1
+	- 10.0.0.2
+# the node listens on all interfaces
+ConsoleOutput: true
+`
+	if err := ioutil.WriteFile(filename, []byte(raw), 0600); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := UpdateConfigFile(filename, map[string]interface{}{
+		"LndAlias":       "new-alias",
+		"LndTLSExtraIPs": []string{"10.0.0.3"},
+		"LndDebugLevel":  "debug",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfigFile returned an error: %v", err)
+	}
+	expected := map[string]string{"LndAlias": "old-alias", "LndTLSExtraIPs": "[10.0.0.1 10.0.0.2]", "LndDebugLevel": ""}
+	if diff := cmp.Diff(expected, previous); diff != "" {
+		t.Errorf("unexpected previous values (-want +got):\n%s", diff)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Conduit config
+
+# the alias shown to peers
+LndAlias: new-alias # max 32 bytes
+LndColor: "#000000"
+
+LndTLSExtraIPs:
+- 10.0.0.3
+# the node listens on all interfaces
+ConsoleOutput: true
+lnddebuglevel: debug
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("unexpected config file (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// configFileEntry is a top-level key of a YAML config file, its value and the lines they span
+type configFileEntry struct {
+	key     string
+	keyNode *yamlv3.Node
+	value   *yamlv3.Node
+	// start is the line of the key and end the line after the last line of the value, counted from 0
+	start, end int
+	// replaced holds the lines setting the new value, if it's set
+	replaced []string
+}
+
+// configFileEditor edits the top-level keys of a YAML config file line by line: only the lines of the values set change and the
+// missing keys are appended, so that the comments and blank lines of the file are kept
+type configFileEditor struct {
+	lines    []string
+	entries  []*configFileEntry
+	appended []string
+}
+
+// newConfigFileEditor parses the YAML config file with yaml.v3, which knows the line of every key. Leading tabs are only expanded
+// to parse the file, the lines which aren't edited are written back as is
+func newConfigFileEditor(raw []byte) (*configFileEditor, error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(raw, utf8BOM)), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	e := &configFileEditor{}
+	if text = strings.TrimSuffix(text, "\n"); text != "" {
+		e.lines = strings.Split(text, "\n")
+	}
+	expanded := make([]string, len(e.lines))
+	for i, line := range e.lines {
+		expanded[i] = expandLeadingTabs(line)
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(strings.Join(expanded, "\n")), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return e, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("%w: the config file isn't a mapping", ErrConfigInvalid)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		// the lines of yaml.v3 are counted from 1
+		e.entries = append(e.entries, &configFileEntry{key: root.Content[i].Value, keyNode: root.Content[i], value: root.Content[i+1], start: root.Content[i].Line - 1})
+	}
+	for i, entry := range e.entries {
+		entry.end = len(e.lines)
+		if i+1 < len(e.entries) {
+			entry.end = e.entries[i+1].start
+		}
+		// the blank lines and comments before the next key belong to it
+		for entry.end > entry.start+1 && isBlankOrComment(e.lines[entry.end-1]) {
+			entry.end--
+		}
+	}
+	return e, nil
+}
+
+// isBlankOrComment reports whether the YAML line is blank or a full-line comment
+func isBlankOrComment(line string) bool {
+	content := strings.TrimSpace(line)
+	return content == "" || strings.HasPrefix(content, "#")
+}
+
+// encodeConfigEntry returns the YAML lines setting key to value
+func encodeConfigEntry(key string, value interface{}) ([]string, error) {
+	out, err := yaml.Marshal(yaml.MapSlice{{Key: key, Value: value}})
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"), nil
+}
+
+// Lookup returns the entry whose key matches name case-insensitively, like yaml.v2 matches the keys to the `Config` fields
+func (e *configFileEditor) Lookup(name string) (*configFileEntry, bool) {
+	for _, entry := range e.entries {
+		if strings.EqualFold(entry.key, name) {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Previous returns the value of the entry as printed by fmt
+func (entry *configFileEntry) Previous() (string, error) {
+	var value interface{}
+	if err := entry.value.Decode(&value); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Set replaces the value of the entry, keeping the comment at the end of its line if the new value fits on one line
+func (e *configFileEditor) Set(entry *configFileEntry, value interface{}) error {
+	lines, err := encodeConfigEntry(entry.key, value)
+	if err != nil {
+		return err
+	}
+	// yaml.v3 attaches the comment to the key or to the value depending on the value
+	comment := entry.value.LineComment
+	if comment == "" {
+		comment = entry.keyNode.LineComment
+	}
+	if comment != "" && len(lines) == 1 {
+		lines[0] += " " + comment
+	}
+	entry.replaced = lines
+	return nil
+}
+
+// Append adds the key with the given value at the end of the file
+func (e *configFileEditor) Append(key string, value interface{}) error {
+	lines, err := encodeConfigEntry(key, value)
+	if err != nil {
+		return err
+	}
+	e.appended = append(e.appended, lines...)
+	return nil
+}
+
+// Bytes returns the edited file
+func (e *configFileEditor) Bytes() []byte {
+	out := make([]string, 0, len(e.lines)+len(e.appended))
+	next := 0
+	for _, entry := range e.entries {
+		if entry.replaced == nil {
+			continue
+		}
+		out = append(append(out, e.lines[next:entry.start]...), entry.replaced...)
+		next = entry.end
+	}
+	out = append(append(out, e.lines[next:]...), e.appended...)
+	return []byte(strings.Join(out, "\n") + "\n")
+}
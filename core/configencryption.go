@@ -0,0 +1,162 @@
+package core
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	ErrNoMasterKey       = errors.Error("CONDUIT_MASTER_KEY environment variable not set")
+	ErrInvalidCiphertext = errors.Error("invalid encrypted config value")
+	master_key_env       = "CONDUIT_MASTER_KEY"
+	encrypted_prefix     = "enc:"
+	key_derivation_info  = "conduit config encryption"
+)
+
+// ConfigEncryption encrypts and decrypts the `Config` fields tagged with `default-mask`
+type ConfigEncryption struct {
+	aead cipher.AEAD
+}
+
+// NewConfigEncryption derives a ChaCha20-Poly1305 key from the given secret
+func NewConfigEncryption(secret []byte) (*ConfigEncryption, error) {
+	if len(secret) == 0 {
+		return nil, ErrNoMasterKey
+	}
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(key_derivation_info)), key); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigEncryption{aead: aead}, nil
+}
+
+// NewConfigEncryptionFromEnv derives the key from the CONDUIT_MASTER_KEY environment variable
+func NewConfigEncryptionFromEnv() (*ConfigEncryption, error) {
+	return NewConfigEncryption([]byte(os.Getenv(master_key_env)))
+}
+
+// IsEncrypted reports whether the value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encrypted_prefix)
+}
+
+// Encrypt encrypts the plaintext and returns it prefixed with `enc:`
+func (c *ConfigEncryption) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encrypted_prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt. Values without the `enc:` prefix are returned unchanged
+func (c *ConfigEncryption) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encrypted_prefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// EncryptConfig encrypts every sensitive field of the config which isn't already encrypted
+func (c *ConfigEncryption) EncryptConfig(config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	for _, name := range SensitiveFields() {
+		f := v.FieldByName(name)
+		if f.String() == "" || IsEncrypted(f.String()) {
+			continue
+		}
+		enc, err := c.Encrypt(f.String())
+		if err != nil {
+			return err
+		}
+		f.SetString(enc)
+	}
+	return nil
+}
+
+// DecryptConfig decrypts every encrypted sensitive field of the config
+func (c *ConfigEncryption) DecryptConfig(config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	for _, name := range SensitiveFields() {
+		f := v.FieldByName(name)
+		dec, err := c.Decrypt(f.String())
+		if err != nil {
+			return fmt.Errorf("could not decrypt %v: %v", name, err)
+		}
+		f.SetString(dec)
+	}
+	return nil
+}
+
+// hasEncryptedFields reports whether any sensitive field of the config is encrypted
+func hasEncryptedFields(config *Config) bool {
+	v := reflect.ValueOf(config).Elem()
+	for _, name := range SensitiveFields() {
+		if IsEncrypted(v.FieldByName(name).String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncryptFile encrypts the sensitive fields of a YAML config file in place, leaving all other keys, comments and blank lines untouched
+func (c *ConfigEncryption) EncryptFile(filename string) (int, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	editor, err := newConfigFileEditor(raw)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, name := range SensitiveFields() {
+		entry, ok := editor.Lookup(name)
+		if !ok || entry.value.Kind != yamlv3.ScalarNode {
+			continue
+		}
+		value := entry.value.Value
+		if value == "" || IsEncrypted(value) {
+			continue
+		}
+		enc, err := c.Encrypt(value)
+		if err != nil {
+			return 0, err
+		}
+		if err = editor.Set(entry, enc); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, utils.AtomicWriteFile(filename, editor.Bytes(), 0600)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestConfigEncryptionFile verifies the plaintext never appears in the encrypted file and that it decrypts back
+func TestConfigEncryptionFile(t *testing.T) {
+	enc, err := NewConfigEncryption([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("Error creating ConfigEncryption: %v", err)
+	}
+	filename := path.Join(t.TempDir(), config_file_name)
+	raw := "ConsoleOutput: true\nlndbtcdrpcpass: hunter2\nlndbtcdrpcuser: satoshi\nCrashWebhookURL: https://hooks.example.com/hunter3\n"
+	if err := ioutil.WriteFile(filename, []byte(raw), 0600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	count, err := enc.EncryptFile(filename)
+	if err != nil {
+		t.Fatalf("EncryptFile returned an error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 fields to be encrypted, got %d", count)
+	}
+	b, _ := ioutil.ReadFile(filename)
+	if strings.Contains(string(b), "hunter2") || strings.Contains(string(b), "hunter3") {
+		t.Fatalf("plaintext secret found in encrypted file: %s", b)
+	}
+	if !strings.Contains(string(b), "lndbtcdrpcuser: satoshi") {
+		t.Errorf("non-secret fields were modified: %s", b)
+	}
+	// encrypting twice is a no-op
+	if count, err = enc.EncryptFile(filename); err != nil || count != 0 {
+		t.Errorf("expected re-encryption to be a no-op, got count %d and error %v", count, err)
+	}
+	config := &Config{}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		t.Fatalf("Error parsing encrypted config: %v", err)
+	}
+	if !hasEncryptedFields(config) {
+		t.Fatalf("expected the config to have encrypted fields")
+	}
+	if err := enc.DecryptConfig(config); err != nil {
+		t.Fatalf("DecryptConfig returned an error: %v", err)
+	}
+	if config.LndBtcdRPCPass != "hunter2" || config.CrashWebhookURL != "https://hooks.example.com/hunter3" {
+		t.Errorf("unexpected decrypted values: %v, %v", config.LndBtcdRPCPass, config.CrashWebhookURL)
+	}
+	// a different key can't decrypt
+	other, _ := NewConfigEncryption([]byte("wrong"))
+	config = &Config{}
+	_ = yaml.Unmarshal(b, config)
+	if err := other.DecryptConfig(config); err == nil {
+		t.Errorf("DecryptConfig succeeded with the wrong key")
+	}
+}
+
+// TestConfigEncryptionFileComments ensures encrypting a file only changes the lines of the secrets, keeping its comments and blank lines
+func TestConfigEncryptionFileComments(t *testing.T) {
+	enc, err := NewConfigEncryption([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("Error creating ConfigEncryption: %v", err)
+	}
+	filename := path.Join(t.TempDir(), config_file_name)
+	raw := "# bitcoind connection\nlndbtcdrpcuser: satoshi\n\n# rotate yearly\nlndbtcdrpcpass: hunter2 # from the vault\n\nConsoleOutput: true\n"
+	if err := ioutil.WriteFile(filename, []byte(raw), 0600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if count, err := enc.EncryptFile(filename); err != nil || count != 1 {
+		t.Fatalf("expected 1 field to be encrypted, got %d: %v", count, err)
+	}
+	b, _ := ioutil.ReadFile(filename)
+	lines := strings.Split(string(b), "\n")
+	rawLines := strings.Split(raw, "\n")
+	if len(lines) != len(rawLines) {
+		t.Fatalf("expected %d lines, got %q", len(rawLines), b)
+	}
+	for i, line := range lines {
+		if i == 4 {
+			if !strings.HasPrefix(line, "lndbtcdrpcpass: ") || !strings.HasSuffix(line, " # from the vault") || strings.Contains(line, "hunter2") {
+				t.Errorf("expected the secret to be encrypted keeping its comment, got %q", line)
+			}
+		} else if line != rawLines[i] {
+			t.Errorf("line %d: expected %q, got %q", i, rawLines[i], line)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	config_hash_metadata_key = "config_hash"
+)
+
+// ConfigChange is the payload of EventConfigChanged
+type ConfigChange struct {
+	OldHash string `json:"old_hash"`
+	NewHash string `json:"new_hash"`
+}
+
+// canonicalJSON encodes a value decoded from JSON with the keys of every object in sorted order and no whitespace
+func canonicalJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			raw, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(raw)
+			buf.WriteByte(':')
+			if err = canonicalJSON(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := canonicalJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(raw)
+	}
+	return nil
+}
+
+// ConfigAuditHash returns the hex encoded SHA256 of the config serialized as canonical JSON, so that equal configs always have the same hash.
+// It fails for the configs JSON can't encode, i.e. with a .nan or .inf float
+func ConfigAuditHash(c *Config) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("could not encode config: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// numbers are kept as written rather than rounded through float64
+	decoder.UseNumber()
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = canonicalJSON(&buf, generic); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckConfigHash compares the hash of the config to the one stored by the previous run, stores the new one and publishes EventConfigChanged if they differ.
+// It returns the change, or nil if the config is unchanged or there was no previous hash
+func CheckConfigHash(c *Config, store *MetadataStore, bus *EventReplay) (*ConfigChange, error) {
+	hash, err := ConfigAuditHash(c)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := store.Get(config_hash_metadata_key)
+	if err != nil && err != ErrKeyNotFound {
+		return nil, err
+	}
+	if string(previous) == hash {
+		return nil, nil
+	}
+	if err = store.Put(config_hash_metadata_key, []byte(hash)); err != nil {
+		return nil, err
+	}
+	if len(previous) == 0 {
+		return nil, nil
+	}
+	change := &ConfigChange{OldHash: string(previous), NewHash: hash}
+	bus.Publish(EventConfigChanged, change)
+	return change, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditHash returns the ConfigAuditHash of c, failing the test if it can't be computed
+func auditHash(t *testing.T, c *Config) string {
+	t.Helper()
+	hash, err := ConfigAuditHash(c)
+	if err != nil {
+		t.Fatalf("ConfigAuditHash returned an error: %v", err)
+	}
+	return hash
+}
+
+// TestConfigAuditHash ensures equal configs have the same hash and any differing field changes it
+func TestConfigAuditHash(t *testing.T) {
+	a, b := default_config(), default_config()
+	if auditHash(t, a) != auditHash(t, b) {
+		t.Fatal("expected equal configs to have the same hash")
+	}
+	if len(auditHash(t, a)) != 64 {
+		t.Errorf("expected a hex encoded SHA256, got %v", auditHash(t, a))
+	}
+	changes := []func(c *Config){
+		func(c *Config) { c.ConduitDir += "x" },
+		func(c *Config) { c.ConsoleOutput = !c.ConsoleOutput },
+		func(c *Config) { c.TLSWarnDays++ },
+		func(c *Config) { c.LndTLSExtraIPs = append(c.LndTLSExtraIPs, "10.0.0.1") },
+	}
+	for i, change := range changes {
+		c := default_config()
+		change(c)
+		if auditHash(t, c) == auditHash(t, a) {
+			t.Errorf("change %d: expected a different hash", i)
+		}
+	}
+	// a .nan value in config.yaml can't be encoded
+	c := default_config()
+	c.JsonRPCRateLimit = math.NaN()
+	if _, err := ConfigAuditHash(c); err == nil {
+		t.Error("expected an error for a NaN value")
+	}
+	if err := NewConfigHashValidator(auditHash(t, a)).Validate(c); !errors.Is(err, ErrConfigInvalid) {
+		t.Errorf("expected ErrConfigInvalid for a NaN value, got %v", err)
+	}
+}
+
+// TestCheckConfigHash ensures EventConfigChanged is only published when the config differs from the previous run
+func TestCheckConfigHash(t *testing.T) {
+	store, err := OpenMetadataStore(path.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	events, unsubscribe := bus.Subscribe(EventConfigChanged)
+	defer unsubscribe()
+	cfg := default_config()
+	for i := 0; i < 2; i++ {
+		if change, err := CheckConfigHash(cfg, store, bus); err != nil || change != nil {
+			t.Fatalf("run %d: expected no change, got %+v (%v)", i, change, err)
+		}
+	}
+	old := auditHash(t, cfg)
+	cfg.ConsoleOutput = !cfg.ConsoleOutput
+	change, err := CheckConfigHash(cfg, store, bus)
+	if err != nil || change == nil || change.OldHash != old || change.NewHash != auditHash(t, cfg) {
+		t.Fatalf("unexpected change %+v (%v)", change, err)
+	}
+	select {
+	case event := <-events:
+		if event.Payload.(*ConfigChange) != change {
+			t.Errorf("unexpected payload %+v", event.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected EventConfigChanged to be published")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
+
+// TestConfigHashValidator ensures a config whose hash isn't ExpectedConfigHash fails the validation
+func TestConfigHashValidator(t *testing.T) {
+	config := default_config()
+	if err := ValidateConfig(config); err != nil {
+		t.Fatalf("expected the check to be skipped without ExpectedConfigHash, got %v", err)
+	}
+	config.ExpectedConfigHash = strings.Repeat("0", 64)
+	if err := ValidateConfig(config); !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid, got %v", err)
+	}
+	// the expected hash isn't part of the hash
+	config.ExpectedConfigHash = " " + strings.ToUpper(auditHash(t, config)) + "\n"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("expected the config to match its hash, got %v", err)
+	}
+}
+
+// TestLoadConfigFile ensures the CONDUIT_ environment variables override the config file as on startup
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, config_file_name)
+	if err := ioutil.WriteFile(filename, []byte("ConduitDir: "+dir+"\nJsonRPCRateLimit: 5\nTLSWarnDays: 7\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONDUIT_JSONRPC_RATE_LIMIT", "2.5")
+	config, err := LoadConfigFile(filename)
+	if err != nil {
+		t.Fatalf("LoadConfigFile returned an error: %v", err)
+	}
+	if config.JsonRPCRateLimit != 2.5 || config.TLSWarnDays != 7 {
+		t.Errorf("expected the environment to override the file, got JsonRPCRateLimit %v and TLSWarnDays %v", config.JsonRPCRateLimit, config.TLSWarnDays)
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const ErrConfigInvalid = errors.Error("config is invalid")
+
+// ConfigHashValidator refuses configs whose ConfigAuditHash isn't the one expected by the deployment
+type ConfigHashValidator struct {
+	expected string
+}
+
+// NewConfigHashValidator creates a ConfigHashValidator expecting the given hash. An empty hash accepts every config
+func NewConfigHashValidator(expected string) *ConfigHashValidator {
+	return &ConfigHashValidator{expected: strings.ToLower(strings.TrimSpace(expected))}
+}
+
+// Validate returns an ErrConfigInvalid error if the hash of the config isn't the expected one.
+// ExpectedConfigHash isn't part of the hash since it can't contain its own hash
+func (v *ConfigHashValidator) Validate(config *Config) error {
+	if v.expected == "" {
+		return nil
+	}
+	hash, err := ConfigAuditHash(config)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfigInvalid, err)
+	}
+	if hash != v.expected {
+		return fmt.Errorf("%w: config hash %s doesn't match ExpectedConfigHash %s", ErrConfigInvalid, hash, v.expected)
+	}
+	return nil
+}
+
+// LoadConfigFile loads the config file the way Conduit does on startup, from every source but the command line flags: the defaults are
+// overridden by the file, whose environment variables are expanded and secrets encrypted with CONDUIT_MASTER_KEY decrypted, then by the
+// secrets stored with `conduitcli secrets set` and finally by the CONDUIT_ environment variables
+func LoadConfigFile(filename string) (*Config, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return nil, err
+	}
+	yamlSource := &YAMLSource{Filename: filename, Strict: true}
+	fromFile, err := yamlSource.Load()
+	if err != nil {
+		return nil, err
+	}
+	merger := NewConfigMerger().AddSource(ConfigPriorityDefault, DefaultSource{}).AddSource(ConfigPriorityYAML, yamlSource)
+	return addOverridingSources(merger, fromFile).Merge()
+}
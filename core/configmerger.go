@@ -0,0 +1,349 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	flags "github.com/jessevdk/go-flags"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Priorities of the config sources used by InitConfig. Sources with a higher priority win
+const (
+	ConfigPriorityDefault = 0
+	ConfigPriorityYAML    = 10
+	ConfigPrioritySecrets = 15
+	ConfigPriorityEnv     = 20
+	ConfigPriorityFlags   = 30
+)
+
+// ConfigSource provides the values of some of the config fields
+type ConfigSource interface {
+	Load() (*Config, error)
+}
+
+// ExplicitConfigSource is a ConfigSource which knows the fields it sets, so that it can also override a field with its zero value.
+// The fields of other sources are set when they aren't zero
+type ExplicitConfigSource interface {
+	ConfigSource
+	// SetFields returns the names of the fields set by the last Load
+	SetFields() []string
+}
+
+// prioritizedSource is a source added to a ConfigMerger
+type prioritizedSource struct {
+	priority int
+	source   ConfigSource
+}
+
+// ConfigMerger merges the configs of several sources, the fields of higher priority sources overriding those of lower priority ones
+type ConfigMerger struct {
+	sources   []prioritizedSource
+	loadTimes map[int]time.Duration
+}
+
+// NewConfigMerger creates a new ConfigMerger without sources
+func NewConfigMerger() *ConfigMerger {
+	return &ConfigMerger{loadTimes: make(map[int]time.Duration)}
+}
+
+// AddSource adds a source with the given priority. Of two sources with the same priority, the last added wins
+func (m *ConfigMerger) AddSource(priority int, source ConfigSource) *ConfigMerger {
+	m.sources = append(m.sources, prioritizedSource{priority: priority, source: source})
+	return m
+}
+
+// Merge loads every source, from the lowest priority to the highest, and returns the merged config
+func (m *ConfigMerger) Merge() (*Config, error) {
+	sources := append([]prioritizedSource{}, m.sources...)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].priority < sources[j].priority
+	})
+	m.loadTimes = make(map[int]time.Duration)
+	merged := &Config{}
+	dst := reflect.ValueOf(merged).Elem()
+	for _, s := range sources {
+		start := time.Now()
+		config, err := s.source.Load()
+		m.loadTimes[s.priority] += time.Since(start)
+		if err != nil {
+			return nil, err
+		}
+		src := reflect.ValueOf(config).Elem()
+		if explicit, ok := s.source.(ExplicitConfigSource); ok {
+			for _, name := range explicit.SetFields() {
+				if f := dst.FieldByName(name); f.IsValid() {
+					f.Set(src.FieldByName(name))
+				}
+			}
+			continue
+		}
+		for i := 0; i < src.NumField(); i++ {
+			if !src.Field(i).IsZero() {
+				dst.Field(i).Set(src.Field(i))
+			}
+		}
+	}
+	return merged, nil
+}
+
+// LoadTime returns the time spent loading the sources of the given priority during the last Merge
+func (m *ConfigMerger) LoadTime(priority int) time.Duration {
+	return m.loadTimes[priority]
+}
+
+// DefaultSource provides the default config
+type DefaultSource struct{}
+
+// Load implements the `ConfigSource` interface
+func (DefaultSource) Load() (*Config, error) {
+	return default_config(), nil
+}
+
+// YAMLSource provides the fields of a config file. The file is read once; environment variables in its values are expanded and the
+// secrets encrypted with `conduitcli config encrypt-secrets` decrypted using CONDUIT_MASTER_KEY. A missing or invalid file sets nothing
+type YAMLSource struct {
+	Filename string
+	// Strict makes Load fail on a file which can't be read or parsed rather than ignore it
+	Strict bool
+	config *Config
+	fields []string
+}
+
+// yamlFieldNames returns the names of the Config fields keyed by their name in a config file
+func yamlFieldNames() map[string]string {
+	names := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if key == "" {
+			// fields without a yaml tag are read from their lowercased name
+			key = strings.ToLower(f.Name)
+		}
+		names[key] = f.Name
+	}
+	return names
+}
+
+// Load implements the `ConfigSource` interface
+func (s *YAMLSource) Load() (*Config, error) {
+	if s.config != nil {
+		return s.config, nil
+	}
+	config := &Config{}
+	if !utils.FileExists(s.Filename) {
+		s.config = config
+		return config, nil
+	}
+	raw, err := ioutil.ReadFile(s.Filename)
+	if err != nil {
+		if s.Strict {
+			return nil, err
+		}
+		log.Println(err)
+		s.config = config
+		return config, nil
+	}
+	var keys map[string]interface{}
+	if raw, err = NewConfigPreprocessor().Process(raw); err == nil {
+		err = yaml.Unmarshal(raw, config)
+	}
+	if err == nil {
+		err = yaml.Unmarshal(raw, &keys)
+	}
+	if err != nil {
+		if s.Strict {
+			return nil, fmt.Errorf("could not parse %v: %v", s.Filename, err)
+		}
+		log.Println(err)
+		s.config = &Config{}
+		return s.config, nil
+	}
+	names := yamlFieldNames()
+	// check_yaml_config sets the conduit directory whether it's in the file or not
+	fields := []string{"ConduitDir", "DefaultDir"}
+	for key := range keys {
+		if name, ok := names[key]; ok && name != "ConduitDir" && name != "DefaultDir" {
+			fields = append(fields, name)
+		}
+	}
+	config = check_yaml_config(config)
+	if hasEncryptedFields(config) {
+		enc, err := NewConfigEncryptionFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if err = enc.DecryptConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	s.config, s.fields = config, fields
+	return config, nil
+}
+
+// SetFields implements the `ExplicitConfigSource` interface
+func (s *YAMLSource) SetFields() []string {
+	return s.fields
+}
+
+// SecretSource provides the secrets stored with `conduitcli secrets set` in the OS keyring or in the encrypted fallback store of the conduit directory
+type SecretSource struct {
+	ConduitDir string
+}
+
+// Load implements the `ConfigSource` interface
+func (s *SecretSource) Load() (*Config, error) {
+	config := &Config{}
+	if err := NewSecretStore(&Config{ConduitDir: s.ConduitDir}).ApplyToConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// EnvSource provides the fields set by CONDUIT_<FLAG> environment variables, FLAG being the long flag of the field in upper case with
+// dashes replaced by underscores, i.e. CONDUIT_PLUGIN_REGISTRY_URL for --plugin-registry-url. Lists are comma separated and maps are
+// comma separated key:value pairs. The LND flag groups aren't read from the environment
+type EnvSource struct {
+	fields []string
+}
+
+// envVarName returns the name of the environment variable of the given long flag
+func envVarName(long string) string {
+	return "CONDUIT_" + strings.ToUpper(strings.ReplaceAll(long, "-", "_"))
+}
+
+// setEnvValue parses the value of an environment variable into the given field
+func setEnvValue(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", f.Type())
+		}
+		var items []string
+		if value != "" {
+			items = strings.Split(value, ",")
+		}
+		f.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if f.Type() != reflect.TypeOf(map[string]string{}) {
+			return fmt.Errorf("unsupported type %v", f.Type())
+		}
+		m := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("expected key:value, got %q", pair)
+			}
+			m[k] = v
+		}
+		f.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %v", f.Type())
+	}
+	return nil
+}
+
+// Load implements the `ConfigSource` interface
+func (s *EnvSource) Load() (*Config, error) {
+	config := &Config{}
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	s.fields = nil
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		long := f.Tag.Get("long")
+		if long == "" || f.Tag.Get("group") != "" {
+			continue
+		}
+		name := envVarName(long)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(v.Field(i), value); err != nil {
+			return nil, fmt.Errorf("invalid %v: %v", name, err)
+		}
+		s.fields = append(s.fields, f.Name)
+	}
+	return config, nil
+}
+
+// SetFields implements the `ExplicitConfigSource` interface
+func (s *EnvSource) SetFields() []string {
+	return s.fields
+}
+
+// FlagSource provides the fields set by command line flags
+type FlagSource struct {
+	// Args are the command line arguments, os.Args[1:] when nil
+	Args   []string
+	fields []string
+}
+
+// Load implements the `ConfigSource` interface
+func (s *FlagSource) Load() (*Config, error) {
+	args := s.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	config := &Config{}
+	parser := flags.NewParser(config, flags.Default)
+	if _, err := parser.ParseArgs(args); err != nil {
+		return nil, err
+	}
+	s.fields = nil
+	groups := parser.Groups()
+	for len(groups) > 0 {
+		group := groups[0]
+		groups = append(groups[1:], group.Groups()...)
+		for _, option := range group.Options() {
+			if option.IsSet() {
+				s.fields = append(s.fields, option.Field().Name)
+			}
+		}
+	}
+	return config, nil
+}
+
+// SetFields implements the `ExplicitConfigSource` interface
+func (s *FlagSource) SetFields() []string {
+	return s.fields
+}
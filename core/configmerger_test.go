@@ -0,0 +1,114 @@
+package core
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+// staticSource provides a fixed config
+type staticSource struct {
+	config *Config
+}
+
+func (s staticSource) Load() (*Config, error) {
+	return s.config, nil
+}
+
+// TestConfigMergerPriority ensures the highest priority source wins whatever the order in which the sources are added
+func TestConfigMergerPriority(t *testing.T) {
+	low := staticSource{&Config{LndAlias: "low", TLSWarnDays: 5}}
+	high := staticSource{&Config{LndAlias: "high", LndColor: "#ffffff"}}
+	for i, merger := range []*ConfigMerger{
+		NewConfigMerger().AddSource(1, low).AddSource(2, high),
+		NewConfigMerger().AddSource(2, high).AddSource(1, low),
+	} {
+		config, err := merger.Merge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the zero values of sources without explicit fields don't override lower priorities
+		if config.LndAlias != "high" || config.LndColor != "#ffffff" || config.TLSWarnDays != 5 {
+			t.Errorf("order %d: unexpected config %+v", i, config)
+		}
+	}
+	config, err := NewConfigMerger().AddSource(1, high).AddSource(1, low).Merge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.LndAlias != "low" {
+		t.Errorf("expected the last source added with the same priority to win, got %q", config.LndAlias)
+	}
+}
+
+// TestConfigMergerSources ensures config.yaml overrides the defaults, zero values included, and that flags override config.yaml
+func TestConfigMergerSources(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, config_file_name)
+	if err := ioutil.WriteFile(filename, []byte("ConsoleOutput: false\nTLSWarnDays: 10\nlndalias: alice\nlndcolor: '#3399ff'\nNotAField: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	flagSource := &FlagSource{Args: []string{"--alias=bob", "--tls-warn-days=0"}}
+	merger := NewConfigMerger().
+		AddSource(ConfigPriorityFlags, flagSource).
+		AddSource(ConfigPriorityYAML, &YAMLSource{Filename: filename}).
+		AddSource(ConfigPriorityDefault, DefaultSource{})
+	config, err := merger.Merge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ConsoleOutput {
+		t.Error("expected ConsoleOutput: false in config.yaml to override the default")
+	}
+	if config.LndAlias != "bob" || config.TLSWarnDays != 0 || config.LndColor != "#3399ff" || config.ConduitDir != default_dir() {
+		t.Errorf("unexpected config %+v", config)
+	}
+	if merger.LoadTime(ConfigPriorityFlags) <= 0 {
+		t.Errorf("expected the flag parsing to be timed")
+	}
+
+	// a missing config file leaves the defaults
+	config, err = NewConfigMerger().AddSource(ConfigPriorityDefault, DefaultSource{}).AddSource(ConfigPriorityYAML, &YAMLSource{Filename: path.Join(dir, "missing.yaml")}).Merge()
+	if err != nil || !config.ConsoleOutput || !config.DefaultDir {
+		t.Errorf("expected the default config, got %+v: %v", config, err)
+	}
+	if _, err = NewConfigMerger().AddSource(ConfigPriorityFlags, &FlagSource{Args: []string{"--not-a-flag"}}).Merge(); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+}
+
+// TestConfigMergerEnv ensures the CONDUIT_ environment variables override config.yaml, zero values included, and are overridden by the flags
+func TestConfigMergerEnv(t *testing.T) {
+	filename := path.Join(t.TempDir(), config_file_name)
+	if err := ioutil.WriteFile(filename, []byte("TLSWarnDays: 10\nSyslogTag: yaml\nConsoleOutput: true\nPluginRegistryURL: https://yaml.example\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONDUIT_TLS_WARN_DAYS", "20")
+	t.Setenv("CONDUIT_SYSLOG_TAG", "env")
+	t.Setenv("CONDUIT_CONSOLE_OUTPUT", "false")
+	t.Setenv("CONDUIT_LND_SUBSYSTEM_LEVEL", "CRTR:WARN,HSWC:ERR")
+	t.Setenv("CONDUIT_TLSEXTRAIP", "10.0.0.1,10.0.0.2")
+	config, err := NewConfigMerger().
+		AddSource(ConfigPriorityDefault, DefaultSource{}).
+		AddSource(ConfigPriorityYAML, &YAMLSource{Filename: filename}).
+		AddSource(ConfigPriorityEnv, &EnvSource{}).
+		AddSource(ConfigPriorityFlags, &FlagSource{Args: []string{"--syslog-tag=flag"}}).
+		Merge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.TLSWarnDays != 20 || config.ConsoleOutput || config.PluginRegistryURL != "https://yaml.example" {
+		t.Errorf("expected the environment to override config.yaml, got %+v", config)
+	}
+	if config.SyslogTag != "flag" {
+		t.Errorf("expected the flag to override the environment, got %q", config.SyslogTag)
+	}
+	if config.LNDSubsystemLevels["CRTR"] != "WARN" || config.LNDSubsystemLevels["HSWC"] != "ERR" || len(config.LndTLSExtraIPs) != 2 || config.LndTLSExtraIPs[1] != "10.0.0.2" {
+		t.Errorf("unexpected map or list from the environment: %v %v", config.LNDSubsystemLevels, config.LndTLSExtraIPs)
+	}
+	t.Setenv("CONDUIT_TLS_WARN_DAYS", "soon")
+	if _, err = NewConfigMerger().AddSource(ConfigPriorityEnv, &EnvSource{}).Merge(); err == nil || !strings.Contains(err.Error(), "CONDUIT_TLS_WARN_DAYS") {
+		t.Errorf("expected an invalid CONDUIT_TLS_WARN_DAYS error, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// config_tab_width is the number of spaces replacing a tab of indentation, which YAML forbids
+const config_tab_width = 2
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+	// blockScalarRegex matches the lines starting a literal or folded block scalar, i.e. "key: |" or "- >-"
+	blockScalarRegex = regexp.MustCompile(`(:|^\s*-)\s+[|>][-+1-9]{0,2}$`)
+)
+
+// ConfigPreprocessor normalizes config files before they are parsed, working around the quirks of yaml.v2 with trailing whitespace,
+// carriage returns and tab indentation
+type ConfigPreprocessor struct{}
+
+// NewConfigPreprocessor creates a new ConfigPreprocessor
+func NewConfigPreprocessor() *ConfigPreprocessor {
+	return &ConfigPreprocessor{}
+}
+
+// expandLeadingTabs replaces the tabs starting the line with spaces, leaving the tabs after a space, which are content in block scalars
+func expandLeadingTabs(line string) string {
+	content := strings.TrimLeft(line, "\t")
+	return strings.Repeat(" ", config_tab_width*(len(line)-len(content))) + content
+}
+
+// trimTrailingWhitespace removes the trailing whitespace of the line unless it's escaped, i.e. "\ " in a double-quoted string
+func trimTrailingWhitespace(line string) string {
+	trimmed := strings.TrimRight(line, " \t")
+	if trimmed != line && strings.HasSuffix(trimmed, "\\") {
+		return line
+	}
+	return trimmed
+}
+
+// expandIndentation replaces the tabs in the indentation of the line with spaces
+func expandIndentation(line string) string {
+	content := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(content)]
+	if !strings.Contains(indent, "\t") {
+		return line
+	}
+	return strings.ReplaceAll(indent, "\t", strings.Repeat(" ", config_tab_width)) + content
+}
+
+// Process returns the YAML with \n line endings, without the byte order mark, trailing whitespace and full-line comments, and with
+// tabs of indentation replaced with spaces. The lines of block scalars are content, so only their leading tabs are replaced: their
+// trailing whitespace and comment-looking lines are kept. Processing the result again returns it unchanged
+func (p *ConfigPreprocessor) Process(raw []byte) ([]byte, error) {
+	if !utf8.Valid(raw) {
+		return nil, fmt.Errorf("%w: not valid UTF-8", ErrConfigInvalid)
+	}
+	raw = bytes.TrimPrefix(raw, utf8BOM)
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	processed := make([]string, 0, len(lines))
+	// blockIndent is the indentation of the line starting the current block scalar, or -1 outside of block scalars
+	blockIndent := -1
+	for _, line := range lines {
+		if blockIndent >= 0 {
+			// the line is in the block as it would be indented outside of it, so that processing again finds the same block
+			expanded := expandIndentation(line)
+			content := strings.TrimLeft(expanded, " \t")
+			if content == "" || len(expanded)-len(content) > blockIndent {
+				processed = append(processed, expandLeadingTabs(line))
+				continue
+			}
+			blockIndent = -1
+		}
+		line = expandIndentation(trimTrailingWhitespace(line))
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		if strings.HasPrefix(content, "#") {
+			continue
+		}
+		processed = append(processed, line)
+		if blockScalarRegex.MatchString(line) {
+			blockIndent = indent
+		}
+	}
+	return []byte(strings.Join(processed, "\n")), nil
+}
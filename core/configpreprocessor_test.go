@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestConfigPreprocessor checks every normalization and that processing twice changes nothing
+func TestConfigPreprocessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{"crlf", "LndAlias: alice\r\nTLSWarnDays: 10\r\n", "LndAlias: alice\nTLSWarnDays: 10\n"},
+		{"lone carriage return", "LndAlias: alice\rTLSWarnDays: 10", "LndAlias: alice\nTLSWarnDays: 10"},
+		{"trailing whitespace", "LndAlias: alice \t\nTLSWarnDays: 10  ", "LndAlias: alice\nTLSWarnDays: 10"},
+		{"byte order mark", "\xEF\xBB\xBFLndAlias: alice", "LndAlias: alice"},
+		{"tab indentation", "Nested:\n\tKey: value\n\t  Other: x\ty", "Nested:\n  Key: value\n    Other: x\ty"},
+		{"comments", "# Conduit config\nLndAlias: alice # inline\n  # indented\nTLSWarnDays: 10", "LndAlias: alice # inline\nTLSWarnDays: 10"},
+		{"block scalar", "Script: |\n  # not a comment  \n\n  echo\n# a comment\nLndAlias: alice", "Script: |\n  # not a comment  \n\n  echo\nLndAlias: alice"},
+		{"block scalar tabs", "Script: |+\n\t\techo\t \n  \tcat \n\t \nLndAlias: alice ", "Script: |+\n    echo\t \n  \tcat \n   \nLndAlias: alice"},
+		{"escaped trailing space", "Banner: \"hello\\ \n  world\"  ", "Banner: \"hello\\ \n  world\""},
+		{"folded sequence entry", "List:\n  - >-\n    # folded\n  # comment\n  - b", "List:\n  - >-\n    # folded\n  - b"},
+	}
+	p := NewConfigPreprocessor()
+	for _, test := range tests {
+		processed, err := p.Process([]byte(test.raw))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(processed) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, processed)
+		}
+		again, err := p.Process(processed)
+		if err != nil || !bytes.Equal(again, processed) {
+			t.Errorf("%s: processing again changed %q to %q: %v", test.name, processed, again, err)
+		}
+	}
+	if _, err := p.Process([]byte("LndAlias: \xff")); !errors.Is(err, ErrConfigInvalid) {
+		t.Errorf("expected invalid UTF-8 to be rejected, got %v", err)
+	}
+}
+
+// TestConfigPreprocessorParse ensures a config written on Windows with trailing whitespace is parsed after processing
+func TestConfigPreprocessorParse(t *testing.T) {
+	raw := []byte("ConsoleOutput: true  \r\nTLSWarnDays: 10\t\r\n# warn 10 days before expiry\r\nJsonRPCListen: localhost:8888\r\n")
+	processed, err := NewConfigPreprocessor().Process(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config Config
+	if err = yaml.Unmarshal(processed, &config); err != nil {
+		t.Fatal(err)
+	}
+	if !config.ConsoleOutput || config.TLSWarnDays != 10 || config.JsonRPCListen != "localhost:8888" {
+		t.Errorf("unexpected config: ConsoleOutput %v, TLSWarnDays %v, JsonRPCListen %q", config.ConsoleOutput, config.TLSWarnDays, config.JsonRPCListen)
+	}
+}
+
+// TestConfigPreprocessorBlockScalars ensures the values of block scalars and quoted strings are the same once processed
+func TestConfigPreprocessorBlockScalars(t *testing.T) {
+	for _, raw := range []string{
+		"Script: |\n  echo hello  \n  \n  # kept  \nOther: x\n",
+		"Script: |+\n  trailing\t\n\n",
+		"Folded: >\n  one  \n   two \n\n  three\n",
+		"List:\n  - |-\n    a \n  - b\n",
+		"Banner: \"hello\\ \n  world \"\n",
+		"Banner: 'hello  \n  world'\n",
+	} {
+		var before, after map[string]interface{}
+		if err := yaml.Unmarshal([]byte(raw), &before); err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		processed, err := NewConfigPreprocessor().Process([]byte(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = yaml.Unmarshal(processed, &after); err != nil {
+			t.Fatalf("%q: %v", processed, err)
+		}
+		if !reflect.DeepEqual(before, after) {
+			t.Errorf("processing %q changed its value from %q to %q", raw, before, after)
+		}
+	}
+}
+
+// FuzzConfigPreprocessor ensures processing never panics, is idempotent and leaves no carriage return, nor trailing whitespace outside
+// of block scalars and escapes
+func FuzzConfigPreprocessor(f *testing.F) {
+	for _, seed := range []string{
+		"LndAlias: alice\r\n",
+		"Nested:\n\tKey: value \n",
+		"Script: |\n  # kept\n# dropped\n",
+		"- >-\n\t\t#\r\r\n",
+		"Script: >\n \t kept \r\n",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+	p := NewConfigPreprocessor()
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		processed, err := p.Process(raw)
+		if err != nil {
+			return
+		}
+		again, err := p.Process(processed)
+		if err != nil || !bytes.Equal(again, processed) {
+			t.Fatalf("processing %q again changed %q to %q: %v", raw, processed, again, err)
+		}
+		// the lines of block scalars and escaped whitespace are content
+		blocks := bytes.Contains(processed, []byte{'\\'})
+		for _, line := range strings.Split(string(processed), "\n") {
+			blocks = blocks || blockScalarRegex.MatchString(line)
+			if strings.ContainsRune(line, '\r') || (!blocks && strings.TrimRight(line, " \t") != line) {
+				t.Fatalf("line %q of %q isn't normalized", line, processed)
+			}
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+// ConfigProfile is the time spent in each phase of InitConfig
+type ConfigProfile struct {
+	YAMLParse  time.Duration `json:"yaml_parse_ns"`
+	EnvLoad    time.Duration `json:"env_load_ns"`
+	FlagParse  time.Duration `json:"flag_parse_ns"`
+	Validation time.Duration `json:"validation_ns"`
+	Total      time.Duration `json:"total_ns"`
+}
+
+// ConfigProfiler times the phases of InitConfig and keeps the last profile
+type ConfigProfiler struct {
+	sync.RWMutex
+	last *ConfigProfile
+}
+
+// DefaultConfigProfiler is the profiler used by InitConfig
+var DefaultConfigProfiler = &ConfigProfiler{}
+
+// InitConfig runs InitConfig and records its profile, even if it fails
+func (p *ConfigProfiler) InitConfig(isTesting bool) (*Config, error) {
+	profile := &ConfigProfile{}
+	start := time.Now()
+	config, err := initConfig(isTesting, profile)
+	profile.Total = time.Since(start)
+	p.Lock()
+	p.last = profile
+	p.Unlock()
+	return config, err
+}
+
+// Last returns the last recorded profile, nil if InitConfig wasn't called yet
+func (p *ConfigProfiler) Last() *ConfigProfile {
+	p.RLock()
+	defer p.RUnlock()
+	return p.last
+}
+
+// Log writes the last profile as a DEBUG event. The config is parsed before the logger exists, hence the profile is logged afterwards
+func (p *ConfigProfiler) Log(log *zerolog.Logger) {
+	profile := p.Last()
+	if profile == nil {
+		return
+	}
+	NewSubLogger(log, "CFGP").SubLogger.Debug().
+		Dur("yaml_parse", profile.YAMLParse).
+		Dur("env_load", profile.EnvLoad).
+		Dur("flag_parse", profile.FlagParse).
+		Dur("validation", profile.Validation).
+		Dur("total", profile.Total).
+		Msg("Config parsed")
+}
+
+// RegisterConfigProfile registers the conduit_config_profile method
+func (s *RPCServer) RegisterConfigProfile(profiler *ConfigProfiler) {
+	s.Register("conduit_config_profile", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		profile := profiler.Last()
+		if profile == nil {
+			return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, "config was not parsed yet")
+		}
+		return profile, nil
+	})
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestConfigProfiler ensures every phase of InitConfig is measured, logged at DEBUG and served by conduit_config_profile
+func TestConfigProfiler(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	profiler := &ConfigProfiler{}
+	s.RegisterConfigProfile(profiler)
+	var result ConfigProfile
+	if err := client.Call(context.Background(), "conduit_config_profile", nil, &result); err == nil {
+		t.Error("expected an error before the config is parsed")
+	}
+	if _, err := profiler.InitConfig(true); err != nil {
+		t.Fatalf("InitConfig returned an error: %v", err)
+	}
+	profile := profiler.Last()
+	for name, d := range map[string]int64{
+		"yaml_parse": int64(profile.YAMLParse),
+		"env_load":   int64(profile.EnvLoad),
+		"flag_parse": int64(profile.FlagParse),
+		"validation": int64(profile.Validation),
+	} {
+		if d < 0 {
+			t.Errorf("expected phase %s to be measured, got %d", name, d)
+		}
+	}
+	if profile.Total < profile.YAMLParse+profile.EnvLoad+profile.FlagParse+profile.Validation {
+		t.Errorf("expected the total to include every phase, got %+v", profile)
+	}
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	profiler.Log(&log)
+	var event map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("could not parse log event %s: %v", buf.String(), err)
+	}
+	for _, field := range []string{"yaml_parse", "env_load", "flag_parse", "validation"} {
+		if _, ok := event[field]; !ok || event["level"] != "debug" {
+			t.Errorf("expected a debug event with field %s, got %s", field, buf.String())
+		}
+	}
+	if err := client.Call(context.Background(), "conduit_config_profile", nil, &result); err != nil {
+		t.Fatalf("conduit_config_profile returned an error: %v", err)
+	}
+	if result != *profile {
+		t.Errorf("expected profile %+v, got %+v", *profile, result)
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+const (
+	config_schema_draft = "http://json-schema.org/draft-07/schema#"
+)
+
+// choiceTagRegexp matches every `choice` struct tag value since reflect.StructTag only returns the first one
+var choiceTagRegexp = regexp.MustCompile(`(?:^|\s)choice:("(?:[^"\\]|\\.)*")`)
+
+// tagChoices returns all the values of the `choice` tags of a struct field
+func tagChoices(tag reflect.StructTag) []string {
+	var choices []string
+	for _, match := range choiceTagRegexp.FindAllStringSubmatch(string(tag), -1) {
+		if choice, err := strconv.Unquote(match[1]); err == nil {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// jsonSchemaType returns the JSON Schema of a Go type, without annotations
+func jsonSchemaType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaType(t.Elem())}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// ExportConfigSchema returns a JSON Schema (draft-07) document describing config.yaml. Properties are keyed by config file key, titled with the
+// command line flag and described with the `description` tag. Fields with `choice` tags get an enum and sensitive fields are write only
+func ExportConfigSchema() []byte {
+	t := reflect.TypeOf(Config{})
+	properties := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		property := jsonSchemaType(f.Type)
+		if long, ok := f.Tag.Lookup("long"); ok {
+			property["title"] = long
+		}
+		if description, ok := f.Tag.Lookup("description"); ok {
+			property["description"] = description
+		}
+		if choices := tagChoices(f.Tag); len(choices) > 0 {
+			property["enum"] = choices
+		}
+		if IsSensitiveField(f.Name) {
+			property["writeOnly"] = true
+		}
+		properties[configFileKey(f.Name)] = property
+	}
+	schema := map[string]interface{}{
+		"$schema":    config_schema_draft,
+		"title":      "Conduit configuration",
+		"type":       "object",
+		"properties": properties,
+	}
+	// a document made of maps, strings, bools and numbers always marshals
+	raw, _ := json.MarshalIndent(schema, "", "    ")
+	return raw
+}
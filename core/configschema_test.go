@@ -0,0 +1,67 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// TestExportConfigSchema validates the exported schema against the draft-07 meta-schema and checks the annotations
+func TestExportConfigSchema(t *testing.T) {
+	raw := ExportConfigSchema()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	meta, err := jsonschema.Compile(config_schema_draft)
+	if err != nil {
+		t.Fatalf("could not compile the draft-07 meta-schema: %v", err)
+	}
+	if err = meta.Validate(doc); err != nil {
+		t.Fatalf("schema does not validate against the meta-schema: %v", err)
+	}
+	properties := doc["properties"].(map[string]interface{})
+	if len(properties) != reflect.TypeOf(Config{}).NumField() {
+		t.Errorf("expected %d properties, got %d", reflect.TypeOf(Config{}).NumField(), len(properties))
+	}
+	node := properties["lndbitcoinnode"].(map[string]interface{})
+	if !reflect.DeepEqual(node["enum"], []interface{}{"btcd", "bitcoind", "neutrino", "ltcd", "litecoind", "nochainbackend"}) {
+		t.Errorf("unexpected enum for lndbitcoinnode: %v", node["enum"])
+	}
+	if node["title"] != "bitcoin.node" || node["description"] != "The blockchain interface to use." {
+		t.Errorf("unexpected annotations for lndbitcoinnode: %v", node)
+	}
+	if properties["lndbtcdrpcpass"].(map[string]interface{})["writeOnly"] != true {
+		t.Error("expected lndbtcdrpcpass to be write only")
+	}
+	if _, ok := properties["ConduitDir"].(map[string]interface{})["writeOnly"]; ok {
+		t.Error("expected ConduitDir not to be write only")
+	}
+	if properties["LogSampleRate"].(map[string]interface{})["type"] != "integer" {
+		t.Errorf("expected LogSampleRate to be an integer, got %v", properties["LogSampleRate"])
+	}
+}
+
+// TestExportConfigSchemaValidatesConfig ensures a config file using the enums is accepted and a wrong choice is rejected
+func TestExportConfigSchemaValidatesConfig(t *testing.T) {
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft7
+	if err := c.AddResource("config.json", bytes.NewReader(ExportConfigSchema())); err != nil {
+		t.Fatalf("could not add the schema: %v", err)
+	}
+	schema, err := c.Compile("config.json")
+	if err != nil {
+		t.Fatalf("could not compile the schema: %v", err)
+	}
+	valid := map[string]interface{}{"ConduitDir": "/tmp", "lndbitcoinnode": "neutrino", "LogSampleRate": json.Number("10")}
+	if err = schema.Validate(valid); err != nil {
+		t.Errorf("expected %v to be valid: %v", valid, err)
+	}
+	invalid := map[string]interface{}{"lndbitcoinnode": "electrum"}
+	if err = schema.Validate(invalid); err == nil {
+		t.Errorf("expected %v to be invalid", invalid)
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+const error_context_unknown = "unknown"
+
+// ErrorContextEnricher is a zerolog hook adding the Conduit directory, LND version and node alias to every error and fatal event
+type ErrorContextEnricher struct {
+	sync.RWMutex
+	conduitDir string
+	lndVersion string
+	nodeAlias  string
+}
+
+// DefaultErrorContextEnricher is the hook of the root logger when Config.ErrorContextEnabled is set. The LND version and node alias are unknown until they're set
+var DefaultErrorContextEnricher = NewErrorContextEnricher("")
+
+// NewErrorContextEnricher creates a new ErrorContextEnricher for the given Conduit directory
+func NewErrorContextEnricher(conduitDir string) *ErrorContextEnricher {
+	return &ErrorContextEnricher{conduitDir: conduitDir, lndVersion: error_context_unknown, nodeAlias: error_context_unknown}
+}
+
+// SetLNDVersion sets the LND version added to error events
+func (e *ErrorContextEnricher) SetLNDVersion(version string) {
+	e.Lock()
+	defer e.Unlock()
+	e.lndVersion = version
+}
+
+// SetNodeAlias sets the node alias added to error events
+func (e *ErrorContextEnricher) SetNodeAlias(alias string) {
+	e.Lock()
+	defer e.Unlock()
+	e.nodeAlias = alias
+}
+
+// Run implements the `zerolog.Hook` interface
+func (e *ErrorContextEnricher) Run(event *zerolog.Event, level zerolog.Level, msg string) {
+	if level != zerolog.ErrorLevel && level != zerolog.FatalLevel {
+		return
+	}
+	e.RLock()
+	defer e.RUnlock()
+	event.Str("conduit_dir", e.conduitDir).Str("lnd_version", e.lndVersion).Str("node_alias", e.nodeAlias)
+}
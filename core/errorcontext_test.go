@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestErrorContextEnricher ensures the context fields are added to error events only
+func TestErrorContextEnricher(t *testing.T) {
+	enricher := NewErrorContextEnricher("/home/conduit/.conduit")
+	var out bytes.Buffer
+	log := zerolog.New(&out).Hook(enricher)
+	log.Error().Msg("before LND")
+	enricher.SetLNDVersion("0.14.2-beta")
+	enricher.SetNodeAlias("conduit")
+	sub := NewSubLogger(&log, "TEST")
+	sub.SubLogger.Error().Msg("after LND")
+	sub.SubLogger.Info().Msg("all good")
+	sub.SubLogger.Warn().Msg("careful")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 events, got:\n%s", out.String())
+	}
+	expected := []map[string]string{
+		{"conduit_dir": "/home/conduit/.conduit", "lnd_version": "unknown", "node_alias": "unknown"},
+		{"conduit_dir": "/home/conduit/.conduit", "lnd_version": "0.14.2-beta", "node_alias": "conduit", "subsystem": "TEST"},
+		{"subsystem": "TEST"},
+		{"subsystem": "TEST"},
+	}
+	for i, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"conduit_dir", "lnd_version", "node_alias"} {
+			want, ok := expected[i][field]
+			if got, found := event[field]; found != ok || (ok && got != want) {
+				t.Errorf("event %d: expected %s to be %q, got %v", i, field, want, event)
+			}
+		}
+	}
+}
+
+// TestInitLoggerErrorContext ensures InitLogger adds the hook when ErrorContextEnabled is set
+func TestInitLoggerErrorContext(t *testing.T) {
+	defer func(enricher *ErrorContextEnricher) { DefaultErrorContextEnricher = enricher }(DefaultErrorContextEnricher)
+	dir := t.TempDir()
+	for _, enabled := range []bool{false, true} {
+		log, err := InitLogger(&Config{ConduitDir: dir, ErrorContextEnabled: enabled})
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Error().Bool("enabled", enabled).Msg("failed")
+	}
+	raw, err := os.ReadFile(path.Join(dir, log_file_name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], "conduit_dir") || !strings.Contains(lines[1], `"conduit_dir":"`+dir+`"`) {
+		t.Errorf("unexpected log file:\n%s", raw)
+	}
+}
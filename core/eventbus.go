@@ -0,0 +1,74 @@
+package core
+
+import (
+	"sync"
+)
+
+const event_buffer_size = 16
+
+// The topics of the events, in the dotted form <subsystem>.<event>
+const (
+	// EventWalletState is published with a WalletStateChange whenever the wallet state of LND changes
+	EventWalletState = "lnd.wallet_state"
+	// EventLndStarted is published with the PID of LND whenever Conduit starts it
+	EventLndStarted = "lnd.started"
+	// EventTorBootstrapped is published once the Tor daemon of LND is bootstrapped
+	EventTorBootstrapped = "tor.bootstrapped"
+	// EventConfigChanged is published with a ConfigChange when the config differs from the one of the previous run
+	EventConfigChanged = "conduit.config_changed"
+	// EventChannelLowLiquidity is published with a LowLiquidityAlert when the local balance of a channel drops below the threshold
+	EventChannelLowLiquidity = "channel.low_liquidity"
+)
+
+// Event is a message published on the EventBus
+type Event struct {
+	Topic   string
+	Payload interface{}
+}
+
+// EventBus is a simple in-process publish/subscribe bus used by Conduit subsystems
+type EventBus struct {
+	sync.RWMutex
+	subscribers map[string][]chan Event
+}
+
+// NewEventBus returns an empty EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[string][]chan Event)}
+}
+
+// Subscribe returns a channel receiving every event published on topic and a function to unsubscribe
+func (b *EventBus) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, event_buffer_size)
+	b.Lock()
+	b.subscribers[topic] = append(b.subscribers[topic], ch)
+	b.Unlock()
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.Lock()
+			defer b.Unlock()
+			subs := b.subscribers[topic]
+			for i, sub := range subs {
+				if sub == ch {
+					b.subscribers[topic] = append(subs[:i], subs[i+1:]...)
+					break
+				}
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish sends an event to every subscriber of topic. Slow subscribers with a full buffer miss the event rather than block the publisher
+func (b *EventBus) Publish(topic string, payload interface{}) {
+	b.RLock()
+	defer b.RUnlock()
+	for _, ch := range b.subscribers[topic] {
+		select {
+		case ch <- Event{Topic: topic, Payload: payload}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"sync"
+)
+
+// default_event_replay_size is the number of past events of every topic kept for the late subscribers of the bus of Conduit
+const default_event_replay_size = 16
+
+// eventRing is a fixed size ring buffer of events, overwriting the oldest event when full
+type eventRing struct {
+	events []Event
+	start  int
+	count  int
+}
+
+// push adds an event, dropping the oldest one if the ring is full
+func (r *eventRing) push(e Event) {
+	if len(r.events) == 0 {
+		return
+	}
+	if r.count < len(r.events) {
+		r.events[(r.start+r.count)%len(r.events)] = e
+		r.count++
+		return
+	}
+	r.events[r.start] = e
+	r.start = (r.start + 1) % len(r.events)
+}
+
+// last returns up to n of the most recent events, oldest first
+func (r *eventRing) last(n int) []Event {
+	if n > r.count {
+		n = r.count
+	}
+	events := make([]Event, 0, n)
+	for i := r.count - n; i < r.count; i++ {
+		events = append(events, r.events[(r.start+i)%len(r.events)])
+	}
+	return events
+}
+
+// subscribeOptions are the options of EventReplay.Subscribe
+type subscribeOptions struct {
+	replay int
+}
+
+// SubscribeOption configures a subscription to an EventReplay
+type SubscribeOption func(*subscribeOptions)
+
+// WithReplay makes the subscriber receive up to n past events of the topic before live ones
+func WithReplay(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.replay = n
+	}
+}
+
+// EventReplay wraps an EventBus and keeps the last events of every topic so that late subscribers can catch up
+type EventReplay struct {
+	sync.Mutex
+	bus     *EventBus
+	size    int
+	history map[string]*eventRing
+}
+
+// NewEventReplay creates a new EventReplay keeping the last size events of every topic published through it
+func NewEventReplay(bus *EventBus, size int) *EventReplay {
+	return &EventReplay{
+		bus:     bus,
+		size:    size,
+		history: make(map[string]*eventRing),
+	}
+}
+
+// Publish records the event and publishes it on the underlying EventBus
+func (r *EventReplay) Publish(topic string, payload interface{}) {
+	r.Lock()
+	defer r.Unlock()
+	ring, ok := r.history[topic]
+	if !ok {
+		ring = &eventRing{events: make([]Event, r.size)}
+		r.history[topic] = ring
+	}
+	ring.push(Event{Topic: topic, Payload: payload})
+	r.bus.Publish(topic, payload)
+}
+
+// Subscribe returns a channel receiving the events of topic and a function to unsubscribe. With WithReplay, past events are received first, in order and without gaps with the live ones
+func (r *EventReplay) Subscribe(topic string, opts ...SubscribeOption) (<-chan Event, func()) {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// subscribing and taking the snapshot under the lock guarantees no live event is missed or received twice
+	r.Lock()
+	live, unsubscribe := r.bus.Subscribe(topic)
+	var past []Event
+	if ring, ok := r.history[topic]; ok && o.replay > 0 {
+		past = ring.last(o.replay)
+	}
+	r.Unlock()
+	out := make(chan Event, event_buffer_size)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		for _, e := range past {
+			select {
+			case out <- e:
+			case <-done:
+				return
+			}
+		}
+		for e := range live {
+			select {
+			case out <- e:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// receive returns the next event of the channel or fails the test after a second
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+// TestEventReplay ensures a late subscriber receives the past events before the next live one
+func TestEventReplay(t *testing.T) {
+	replay := NewEventReplay(NewEventBus(), 10)
+	for i := 0; i < 5; i++ {
+		replay.Publish("lnd.started", i)
+	}
+	events, unsubscribe := replay.Subscribe("lnd.started", WithReplay(10))
+	defer unsubscribe()
+	replay.Publish("lnd.started", 5)
+	for i := 0; i < 6; i++ {
+		if e := receive(t, events); e.Payload != i {
+			t.Fatalf("expected event %d, got %v", i, e.Payload)
+		}
+	}
+	// subscribers without replay only receive live events
+	liveOnly, unsubscribeLive := replay.Subscribe("lnd.started")
+	defer unsubscribeLive()
+	replay.Publish("lnd.started", 6)
+	if e := receive(t, liveOnly); e.Payload != 6 {
+		t.Errorf("expected live event 6, got %v", e.Payload)
+	}
+}
+
+// TestEventReplayRing ensures only the last events are kept and that n limits the replay
+func TestEventReplayRing(t *testing.T) {
+	replay := NewEventReplay(NewEventBus(), 3)
+	for i := 0; i < 7; i++ {
+		replay.Publish("topic", i)
+	}
+	events, unsubscribe := replay.Subscribe("topic", WithReplay(2))
+	for _, want := range []int{5, 6} {
+		if e := receive(t, events); e.Payload != want {
+			t.Errorf("expected event %d, got %v", want, e.Payload)
+		}
+	}
+	unsubscribe()
+	// the channel is closed once unsubscribed
+	for range events {
+	}
+	all, unsubscribeAll := replay.Subscribe("topic", WithReplay(10))
+	defer unsubscribeAll()
+	for _, want := range []int{4, 5, 6} {
+		if e := receive(t, all); e.Payload != want {
+			t.Errorf("expected event %d, got %v", want, e.Payload)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/rs/zerolog"
+)
+
+const (
+	failure_report_log_lines = 20
+	failure_report_retries   = 3
+	failure_report_backoff   = time.Second
+	failure_report_timeout   = 10 * time.Second
+	failure_signature_header = "X-Conduit-Signature"
+	redacted_value           = "[redacted]"
+)
+
+// FailureEvent is the crash report posted to the webhook when LND stops unexpectedly
+type FailureEvent struct {
+	Timestamp      time.Time `json:"timestamp"`
+	ConduitVersion string    `json:"conduit_version"`
+	LNDVersion     string    `json:"lnd_version"`
+	Reason         string    `json:"reason"`
+	LogLines       []string  `json:"log_lines"`
+	RestartCount   int       `json:"restart_count"`
+	NodePubkey     string    `json:"node_pubkey"`
+}
+
+// FailureReporter keeps the last LND log lines and posts a FailureEvent to the configured webhook when LND crashes
+type FailureReporter struct {
+	sync.Mutex
+	url      string
+	secret   string
+	client   *http.Client
+	backoff  time.Duration
+	versions *LNDVersionCache
+	log      *subLogger
+	// secrets are the values of the sensitive config fields, replaced in the log lines of a report
+	secrets []string
+	lines   []string
+	starts  int
+	pubkey  string
+}
+
+// NewFailureReporter creates a new FailureReporter posting to Config.CrashWebhookURL
+func NewFailureReporter(cfg *Config, versions *LNDVersionCache, log *zerolog.Logger) *FailureReporter {
+	var secrets []string
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range SensitiveFields() {
+		if f := v.FieldByName(name); f.Kind() == reflect.String && f.String() != "" {
+			secrets = append(secrets, f.String())
+		}
+	}
+	return &FailureReporter{
+		url:      cfg.CrashWebhookURL,
+		secret:   cfg.CrashWebhookSecret,
+		client:   &http.Client{Timeout: failure_report_timeout},
+		backoff:  failure_report_backoff,
+		versions: versions,
+		log:      NewSubLogger(log, "CRSH"),
+		secrets:  secrets,
+	}
+}
+
+// Enabled reports whether a webhook URL is configured
+func (r *FailureReporter) Enabled() bool {
+	return r.url != ""
+}
+
+// ParseLine implements the `LineParser` interface by keeping the last LND log lines
+func (r *FailureReporter) ParseLine(line string) {
+	r.Lock()
+	defer r.Unlock()
+	r.lines = append(r.lines, line)
+	if len(r.lines) > failure_report_log_lines {
+		r.lines = r.lines[len(r.lines)-failure_report_log_lines:]
+	}
+}
+
+// LndStarted records a start of LND so that reports include the number of restarts
+func (r *FailureReporter) LndStarted() {
+	r.Lock()
+	defer r.Unlock()
+	r.starts++
+}
+
+// SetNodePubkey sets the public key of the node included in reports
+func (r *FailureReporter) SetNodePubkey(pubkey string) {
+	r.Lock()
+	defer r.Unlock()
+	r.pubkey = pubkey
+}
+
+// redact replaces the secrets found in s
+func (r *FailureReporter) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted_value)
+	}
+	return s
+}
+
+// Event returns the FailureEvent of a crash for the given reason
+func (r *FailureReporter) Event(reason string) FailureEvent {
+	r.Lock()
+	defer r.Unlock()
+	event := FailureEvent{
+		Timestamp:      time.Now().UTC(),
+		ConduitVersion: utils.AppVersion,
+		Reason:         reason,
+		LogLines:       append([]string{}, r.lines...),
+		NodePubkey:     r.pubkey,
+	}
+	if r.starts > 1 {
+		event.RestartCount = r.starts - 1
+	}
+	if r.versions != nil {
+		if version, err := r.versions.Version(); err == nil {
+			event.LNDVersion = version.String()
+		}
+	}
+	return event
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the body with the webhook secret
+func (r *FailureReporter) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(r.secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post posts the body to the webhook once
+func (r *FailureReporter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.secret != "" {
+		req.Header.Set(failure_signature_header, r.sign(body))
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Report posts the event to the webhook, retrying 3 times with exponential backoff. Secrets are redacted from the reason and log lines
+func (r *FailureReporter) Report(event FailureEvent) error {
+	if !r.Enabled() {
+		return nil
+	}
+	event.Reason = r.redact(event.Reason)
+	lines := make([]string, len(event.LogLines))
+	for i, line := range event.LogLines {
+		lines[i] = r.redact(line)
+	}
+	event.LogLines = lines
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = utils.RetryWithBackoff(context.Background(), func() (struct{}, error) {
+		return struct{}{}, r.post(body)
+	}, utils.RetryOptions{
+		MaxAttempts: 1 + failure_report_retries,
+		BaseDelay:   r.backoff,
+		OnRetry: func(attempt int, delay time.Duration, err error) {
+			r.log.SubLogger.Warn().Msg(fmt.Sprintf("could not send crash report, retrying in %v: %v", delay, err))
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not send crash report: %v", err)
+	}
+	r.log.SubLogger.Info().Msg("Crash report sent")
+	return nil
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/rs/zerolog"
+)
+
+// newTestFailureReporter returns a FailureReporter posting to a webhook failing the first failures requests and recording the others
+func newTestFailureReporter(t *testing.T, cfg *Config, failures int) (*FailureReporter, func() ([][]byte, []string, int)) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies [][]byte
+	var signatures []string
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(failure_signature_header))
+	}))
+	t.Cleanup(ts.Close)
+	cfg.CrashWebhookURL = ts.URL
+	log := zerolog.New(ioutil.Discard)
+	versions := &LNDVersionCache{read: func() ([]byte, error) { return []byte("lnd version 0.14.2-beta commit=v0.14.2-beta"), nil }}
+	r := NewFailureReporter(cfg, versions, &log)
+	r.backoff = time.Millisecond
+	return r, func() ([][]byte, []string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies, signatures, requests
+	}
+}
+
+// TestFailureReporter ensures the report carries the last 20 log lines and the node details, is signed and has its secrets redacted
+func TestFailureReporter(t *testing.T) {
+	cfg := &Config{CrashWebhookSecret: "webhooksecret", LndBitcoindRPCPass: "hunter2"}
+	r, received := newTestFailureReporter(t, cfg, 0)
+	for i := 0; i < 25; i++ {
+		r.ParseLine(fmt.Sprintf("2022-03-01 12:00:%02d.000 [INF] LTND: line %d", i, i))
+	}
+	r.ParseLine("2022-03-01 12:00:30.000 [ERR] LTND: unable to connect with bitcoind password hunter2")
+	r.LndStarted()
+	r.LndStarted()
+	r.SetNodePubkey("02abcd")
+	if err := r.Report(r.Event("exit status 1")); err != nil {
+		t.Fatalf("Report returned an error: %v", err)
+	}
+	bodies, signatures, _ := received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected one report, got %d", len(bodies))
+	}
+	mac := hmac.New(sha256.New, []byte("webhooksecret"))
+	mac.Write(bodies[0])
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signatures[0] != want {
+		t.Errorf("expected signature %s, got %s", want, signatures[0])
+	}
+	var event FailureEvent
+	if err := json.Unmarshal(bodies[0], &event); err != nil {
+		t.Fatalf("could not parse report %s: %v", bodies[0], err)
+	}
+	if event.Reason != "exit status 1" || event.ConduitVersion != utils.AppVersion || event.LNDVersion != "0.14.2-beta" || event.RestartCount != 1 || event.NodePubkey != "02abcd" || event.Timestamp.IsZero() {
+		t.Errorf("unexpected report: %s", bodies[0])
+	}
+	if len(event.LogLines) != failure_report_log_lines || !strings.HasSuffix(event.LogLines[0], "line 6") {
+		t.Errorf("expected the last %d log lines, got %v", failure_report_log_lines, event.LogLines)
+	}
+	if bytes.Contains(bodies[0], []byte("hunter2")) || bytes.Contains(bodies[0], []byte("webhooksecret")) {
+		t.Errorf("report leaks a secret: %s", bodies[0])
+	}
+}
+
+// TestFailureReporterRetry ensures a failing webhook is retried 3 times before giving up
+func TestFailureReporterRetry(t *testing.T) {
+	r, received := newTestFailureReporter(t, &Config{}, 2)
+	if err := r.Report(r.Event("signal: killed")); err != nil {
+		t.Fatalf("Report returned an error: %v", err)
+	}
+	if bodies, signatures, requests := received(); requests != 3 || len(bodies) != 1 || signatures[0] != "" {
+		t.Errorf("expected the third attempt to succeed without signature, got %d requests and signatures %v", requests, signatures)
+	}
+	r, received = newTestFailureReporter(t, &Config{}, 10)
+	if err := r.Report(r.Event("signal: killed")); err == nil {
+		t.Error("expected an error once all retries failed")
+	}
+	if _, _, requests := received(); requests != 1+failure_report_retries {
+		t.Errorf("expected %d attempts, got %d", 1+failure_report_retries, requests)
+	}
+}
+
+// TestFailureReporterDisabled ensures nothing is sent without a webhook URL
+func TestFailureReporterDisabled(t *testing.T) {
+	log := zerolog.New(ioutil.Discard)
+	r := NewFailureReporter(&Config{}, nil, &log)
+	if r.Enabled() {
+		t.Error("expected the reporter to be disabled")
+	}
+	if err := r.Report(r.Event("exit status 1")); err != nil {
+		t.Errorf("Report returned an error: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+)
+
+const (
+	ErrUnknownFeatureFlag = errors.Error("unknown feature flag")
+	feature_key_prefix    = "features/"
+)
+
+// known_feature_flags is the registry of experimental features which can be toggled at runtime
+var known_feature_flags = map[string]string{
+	"plugin-sandbox":    "Run plugins in a restricted sandbox",
+	"grpc-proxy":        "Proxy LND's gRPC API through Conduit",
+	"lnd-log-parser-v2": "Use the new LND log parser",
+}
+
+// FeatureFlag is the state of a feature flag
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// FeatureFlagManager toggles experimental features at runtime and persists their state in the MetadataStore
+type FeatureFlagManager struct {
+	store *MetadataStore
+}
+
+// NewFeatureFlagManager creates a FeatureFlagManager backed by the given store
+func NewFeatureFlagManager(store *MetadataStore) *FeatureFlagManager {
+	return &FeatureFlagManager{store: store}
+}
+
+// set persists the state of a known flag
+func (f *FeatureFlagManager) set(flag string, enabled bool) error {
+	if _, ok := known_feature_flags[flag]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFeatureFlag, flag)
+	}
+	value, _ := json.Marshal(enabled)
+	return f.store.Put(feature_key_prefix+flag, value)
+}
+
+// Enable enables the given flag
+func (f *FeatureFlagManager) Enable(flag string) error {
+	return f.set(flag, true)
+}
+
+// Disable disables the given flag
+func (f *FeatureFlagManager) Disable(flag string) error {
+	return f.set(flag, false)
+}
+
+// IsEnabled reports whether the given flag is enabled. Unknown and never set flags are disabled
+func (f *FeatureFlagManager) IsEnabled(flag string) bool {
+	value, err := f.store.Get(feature_key_prefix + flag)
+	if err != nil {
+		return false
+	}
+	var enabled bool
+	_ = json.Unmarshal(value, &enabled)
+	return enabled
+}
+
+// List returns the state of all known flags sorted by name
+func (f *FeatureFlagManager) List() []FeatureFlag {
+	var flags []FeatureFlag
+	for name, desc := range known_feature_flags {
+		flags = append(flags, FeatureFlag{Name: name, Description: desc, Enabled: f.IsEnabled(name)})
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// featureParams are the params of the conduit_feature_enable and conduit_feature_disable methods
+type featureParams struct {
+	Name string `json:"name"`
+}
+
+// RegisterFeatureFlags registers the conduit_feature_* methods
+func (s *RPCServer) RegisterFeatureFlags(flags *FeatureFlagManager) {
+	s.Register("conduit_feature_list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return flags.List(), nil
+	})
+	toggle := func(set func(string) error) jsonrpc.HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p featureParams
+			if err := json.Unmarshal(params, &p); err != nil || p.Name == "" {
+				return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INVALID_PARAMS, "expected params {\"name\": string}")
+			}
+			if err := set(p.Name); err != nil {
+				return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INVALID_PARAMS, err.Error())
+			}
+			return flags.List(), nil
+		}
+	}
+	s.Register("conduit_feature_enable", toggle(flags.Enable))
+	s.Register("conduit_feature_disable", toggle(flags.Disable))
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"path"
+	"testing"
+)
+
+// TestFeatureFlagManagerPersistence verifies feature flags persist across MetadataStore open/close cycles
+func TestFeatureFlagManagerPersistence(t *testing.T) {
+	filename := path.Join(t.TempDir(), metadata_file_name)
+	store, err := OpenMetadataStore(filename)
+	if err != nil {
+		t.Fatalf("Error opening metadata store: %v", err)
+	}
+	flags := NewFeatureFlagManager(store)
+	if flags.IsEnabled("plugin-sandbox") {
+		t.Errorf("plugin-sandbox is enabled by default")
+	}
+	if err := flags.Enable("plugin-sandbox"); err != nil {
+		t.Fatalf("Enable returned an error: %v", err)
+	}
+	if err := flags.Enable("not-a-flag"); err == nil {
+		t.Errorf("Enable accepted an unknown flag")
+	}
+	if err := flags.Disable("grpc-proxy"); err != nil {
+		t.Fatalf("Disable returned an error: %v", err)
+	}
+	store.Close()
+	store, err = OpenMetadataStore(filename)
+	if err != nil {
+		t.Fatalf("Error reopening metadata store: %v", err)
+	}
+	defer store.Close()
+	flags = NewFeatureFlagManager(store)
+	if !flags.IsEnabled("plugin-sandbox") {
+		t.Errorf("plugin-sandbox was not persisted")
+	}
+	if flags.IsEnabled("grpc-proxy") || flags.IsEnabled("not-a-flag") {
+		t.Errorf("unexpected enabled flags: %v", flags.List())
+	}
+	if len(flags.List()) != len(known_feature_flags) {
+		t.Errorf("List returned %d flags, expected %d", len(flags.List()), len(known_feature_flags))
+	}
+}
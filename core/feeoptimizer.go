@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+)
+
+const (
+	default_fee_optimizer_period = 7 * 24 * time.Hour
+	fee_high_success_rate        = 0.8
+	fee_low_success_rate         = 0.2
+	fee_adjustment_percent       = 10
+	// fee_min_attempts is the number of forward attempts below which the success rate isn't meaningful
+	fee_min_attempts = 10
+)
+
+// Fee suggestion actions
+const (
+	FeeActionLower = "lower"
+	FeeActionRaise = "raise"
+	FeeActionKeep  = "keep"
+)
+
+// FeeSuggestion is the suggested fee policy adjustment of a channel, based on its outgoing forwards
+type FeeSuggestion struct {
+	ChanId            uint64  `json:"chan_id"`
+	Forwards          int     `json:"forwards"`
+	Failures          int     `json:"failures"`
+	VolumeMsat        uint64  `json:"volume_msat"`
+	FeeRevenueMsat    uint64  `json:"fee_revenue_msat"`
+	SuccessRate       float64 `json:"success_rate"`
+	Action            string  `json:"action"`
+	AdjustmentPercent int     `json:"adjustment_percent"`
+}
+
+// ChannelFeeOptimizer suggests fee policy adjustments from the forwarding history CSV.
+// The CSV only holds settled forwards, so the failed forwards are counted from LND's HTLC events while Conduit runs
+type ChannelFeeOptimizer struct {
+	sync.Mutex
+	filename string
+	period   time.Duration
+	now      func() time.Time
+	// failures are the timestamps of the failed forwards of every outgoing channel
+	failures map[uint64][]time.Time
+}
+
+// NewChannelFeeOptimizer creates a ChannelFeeOptimizer reading the forwarding history CSV of the conduit directory
+func NewChannelFeeOptimizer(config *Config) *ChannelFeeOptimizer {
+	return &ChannelFeeOptimizer{
+		filename: ForwardingHistoryPath(config),
+		period:   default_fee_optimizer_period,
+		now:      time.Now,
+		failures: make(map[uint64][]time.Time),
+	}
+}
+
+// Run counts the failed forwards of every channel until the context is cancelled
+func (o *ChannelFeeOptimizer) Run(ctx context.Context, router routerrpc.RouterClient) error {
+	stream, err := router.SubscribeHtlcEvents(ctx, &routerrpc.SubscribeHtlcEventsRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		o.recordHtlcEvent(event)
+	}
+}
+
+// recordHtlcEvent counts the event if it's a forward which failed downstream or on our outgoing link. Every forward prunes the failures
+// of its outgoing channel which are older than the period
+func (o *ChannelFeeOptimizer) recordHtlcEvent(event *routerrpc.HtlcEvent) {
+	if event.EventType != routerrpc.HtlcEvent_FORWARD || event.OutgoingChannelId == 0 {
+		return
+	}
+	timestamp := o.now()
+	if event.TimestampNs != 0 {
+		timestamp = time.Unix(0, int64(event.TimestampNs))
+	}
+	since := o.now().Add(-o.period)
+	o.Lock()
+	defer o.Unlock()
+	o.pruneFailures(event.OutgoingChannelId, since)
+	switch event.Event.(type) {
+	case *routerrpc.HtlcEvent_ForwardFailEvent, *routerrpc.HtlcEvent_LinkFailEvent:
+		if !timestamp.Before(since) {
+			o.failures[event.OutgoingChannelId] = append(o.failures[event.OutgoingChannelId], timestamp)
+		}
+	}
+}
+
+// pruneFailures drops the failures of the channel which happened before since, and the channel once it has none left. The caller must
+// hold the lock
+func (o *ChannelFeeOptimizer) pruneFailures(id uint64, since time.Time) {
+	failures := o.failures[id]
+	kept := failures[:0]
+	for _, at := range failures {
+		if !at.Before(since) {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(o.failures, id)
+	} else {
+		o.failures[id] = kept
+	}
+}
+
+// Suggestions returns the fee suggestion of every channel which forwarded or failed to forward during the period, sorted by channel ID.
+// Channels with at least 10 attempts succeeding more than 80% of the time with at least the average volume should lower their fees, channels succeeding less than 20% of the time should raise them
+func (o *ChannelFeeOptimizer) Suggestions() ([]FeeSuggestion, error) {
+	since := o.now().Add(-o.period)
+	events, err := ReadForwardingHistory(o.filename, since)
+	if err != nil {
+		return nil, err
+	}
+	channels := make(map[uint64]*FeeSuggestion)
+	channel := func(id uint64) *FeeSuggestion {
+		s, ok := channels[id]
+		if !ok {
+			s = &FeeSuggestion{ChanId: id}
+			channels[id] = s
+		}
+		return s
+	}
+	for _, event := range events {
+		s := channel(event.ChanIdOut)
+		s.Forwards++
+		s.VolumeMsat += event.AmountOutMsat
+		s.FeeRevenueMsat += event.FeeMsat
+	}
+	o.Lock()
+	// the channels without forwards, i.e. closed ones, are pruned here
+	for id := range o.failures {
+		o.pruneFailures(id, since)
+	}
+	for id, failures := range o.failures {
+		channel(id).Failures += len(failures)
+	}
+	o.Unlock()
+	var totalVolume uint64
+	for _, s := range channels {
+		totalVolume += s.VolumeMsat
+	}
+	suggestions := make([]FeeSuggestion, 0, len(channels))
+	for _, s := range channels {
+		s.SuccessRate = float64(s.Forwards) / float64(s.Forwards+s.Failures)
+		highVolume := s.VolumeMsat > 0 && s.VolumeMsat*uint64(len(channels)) >= totalVolume
+		switch {
+		case s.Forwards+s.Failures < fee_min_attempts:
+			s.Action = FeeActionKeep
+		case s.SuccessRate > fee_high_success_rate && highVolume:
+			s.Action, s.AdjustmentPercent = FeeActionLower, -fee_adjustment_percent
+		case s.SuccessRate < fee_low_success_rate:
+			s.Action, s.AdjustmentPercent = FeeActionRaise, fee_adjustment_percent
+		default:
+			s.Action = FeeActionKeep
+		}
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].ChanId < suggestions[j].ChanId })
+	return suggestions, nil
+}
+
+// RegisterFeeSuggestions registers the conduit_fee_suggestions method
+func (s *RPCServer) RegisterFeeSuggestions(optimizer *ChannelFeeOptimizer) {
+	s.Register("conduit_fee_suggestions", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		suggestions, err := optimizer.Suggestions()
+		if err != nil {
+			return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, fmt.Sprintf("could not read forwarding history: %v", err))
+		}
+		return suggestions, nil
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+)
+
+// writeForwards appends n synthetic forwards out of chanIdOut to the forwarding history CSV
+func writeForwards(t *testing.T, filename string, at time.Time, chanIdOut uint64, n int, amountMsat uint64) {
+	t.Helper()
+	rows := make([][]string, n)
+	for i := range rows {
+		event := &ForwardingEvent{Timestamp: at, ChanIdIn: 1, ChanIdOut: chanIdOut, AmountInMsat: amountMsat + 1000, AmountOutMsat: amountMsat, FeeMsat: 1000}
+		rows[i] = event.row()
+	}
+	if err := appendCSVRows(filename, forwarding_history_header, rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// forwardFailure returns a failed forward HTLC event out of chanIdOut
+func forwardFailure(chanIdOut uint64, at time.Time) *routerrpc.HtlcEvent {
+	return &routerrpc.HtlcEvent{
+		EventType:         routerrpc.HtlcEvent_FORWARD,
+		OutgoingChannelId: chanIdOut,
+		TimestampNs:       uint64(at.UnixNano()),
+		Event:             &routerrpc.HtlcEvent_ForwardFailEvent{ForwardFailEvent: &routerrpc.ForwardFailEvent{}},
+	}
+}
+
+// TestChannelFeeOptimizerSuggestions ensures busy reliable channels are told to lower their fees and failing ones to raise them
+func TestChannelFeeOptimizerSuggestions(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	optimizer := NewChannelFeeOptimizer(&Config{ConduitDir: t.TempDir()})
+	optimizer.now = func() time.Time { return now }
+	recent, old := now.Add(-time.Hour), now.Add(-30*24*time.Hour)
+	// channel 10 is busy and reliable: 9 forwards, 1 failure
+	writeForwards(t, optimizer.filename, recent, 10, 9, 5000000)
+	optimizer.recordHtlcEvent(forwardFailure(10, recent))
+	// channel 20 fails most of the time: 1 forward, 9 failures
+	writeForwards(t, optimizer.filename, recent, 20, 1, 1000000)
+	// channel 30 is reliable but has little volume
+	writeForwards(t, optimizer.filename, recent, 30, 10, 1000)
+	for i := 0; i < 9; i++ {
+		optimizer.recordHtlcEvent(forwardFailure(20, recent))
+	}
+	// forwards and failures outside of the period are ignored
+	writeForwards(t, optimizer.filename, old, 40, 5, 5000000)
+	optimizer.recordHtlcEvent(forwardFailure(10, old))
+	// receives and settled forwards aren't failures
+	optimizer.recordHtlcEvent(&routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_RECEIVE, OutgoingChannelId: 10, Event: &routerrpc.HtlcEvent_LinkFailEvent{}})
+	optimizer.recordHtlcEvent(&routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_FORWARD, OutgoingChannelId: 10, Event: &routerrpc.HtlcEvent_SettleEvent{}})
+
+	suggestions, err := optimizer.Suggestions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		chanId   uint64
+		forwards int
+		failures int
+		action   string
+		percent  int
+	}{
+		{10, 9, 1, FeeActionLower, -10},
+		{20, 1, 9, FeeActionRaise, 10},
+		{30, 10, 0, FeeActionKeep, 0},
+	}
+	if len(suggestions) != len(want) {
+		t.Fatalf("expected %d suggestions, got %+v", len(want), suggestions)
+	}
+	for i, w := range want {
+		s := suggestions[i]
+		if s.ChanId != w.chanId || s.Forwards != w.forwards || s.Failures != w.failures || s.Action != w.action || s.AdjustmentPercent != w.percent {
+			t.Errorf("unexpected suggestion %d: %+v", i, s)
+		}
+	}
+	if suggestions[0].VolumeMsat != 45000000 || suggestions[0].FeeRevenueMsat != 9000 {
+		t.Errorf("unexpected volume or revenue: %+v", suggestions[0])
+	}
+}
+
+// TestChannelFeeOptimizerPruning ensures the failures leaving the period are forgotten, including those of channels which don't
+// forward anymore
+func TestChannelFeeOptimizerPruning(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	optimizer := NewChannelFeeOptimizer(&Config{ConduitDir: t.TempDir()})
+	optimizer.now = func() time.Time { return now }
+	optimizer.recordHtlcEvent(forwardFailure(10, now))
+	optimizer.recordHtlcEvent(forwardFailure(20, now))
+	optimizer.recordHtlcEvent(forwardFailure(30, now.Add(-2*optimizer.period)))
+	if len(optimizer.failures) != 2 {
+		t.Fatalf("expected the failure older than the period not to be kept, got %v", optimizer.failures)
+	}
+	now = now.Add(optimizer.period + time.Minute)
+	// a settled forward of channel 10 prunes its failures
+	optimizer.recordHtlcEvent(&routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_FORWARD, OutgoingChannelId: 10, Event: &routerrpc.HtlcEvent_SettleEvent{}})
+	if _, ok := optimizer.failures[10]; ok || len(optimizer.failures) != 1 {
+		t.Errorf("expected the failures of channel 10 to be pruned, got %v", optimizer.failures)
+	}
+	// channel 20 is closed and doesn't forward anymore, its failures are pruned with the suggestions
+	if _, err := optimizer.Suggestions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(optimizer.failures) != 0 {
+		t.Errorf("expected every failure to be pruned, got %v", optimizer.failures)
+	}
+}
+
+// TestFeeSuggestionsRPC ensures conduit_fee_suggestions serves the suggestions
+func TestFeeSuggestionsRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	optimizer := NewChannelFeeOptimizer(s.cfg)
+	writeForwards(t, optimizer.filename, time.Now(), 10, 1, 1000)
+	s.RegisterFeeSuggestions(optimizer)
+	var suggestions []FeeSuggestion
+	if err := client.Call(context.Background(), "conduit_fee_suggestions", nil, &suggestions); err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ChanId != 10 || suggestions[0].Action != FeeActionKeep {
+		t.Fatalf("unexpected suggestions: %+v", suggestions)
+	}
+}
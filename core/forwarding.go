@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	forwarding_history_file_name     = "forwarding_history.csv"
+	forwarding_page_size             = 1000
+	default_forwarding_export_period = 1 * time.Hour
+)
+
+// forwarding_history_header is the header row of the forwarding history CSV
+var forwarding_history_header = []string{"timestamp", "chan_id_in", "chan_id_out", "amount_in_msat", "amount_out_msat", "fee_msat"}
+
+// ForwardingEvent is a single row of the forwarding history CSV
+type ForwardingEvent struct {
+	Timestamp     time.Time
+	ChanIdIn      uint64
+	ChanIdOut     uint64
+	AmountInMsat  uint64
+	AmountOutMsat uint64
+	FeeMsat       uint64
+}
+
+// ForwardingHistoryExporter periodically appends the new entries of LND's forwarding history to a CSV file
+type ForwardingHistoryExporter struct {
+	client   lnrpc.LightningClient
+	filename string
+	log      *subLogger
+	interval time.Duration
+	// offset is the number of forwarding events already exported, queried from the start of the forwarding log
+	offset uint32
+	loaded bool
+}
+
+// ForwardingHistoryPath returns the path of the forwarding history CSV in the conduit directory
+func ForwardingHistoryPath(config *Config) string {
+	return path.Join(config.ConduitDir, forwarding_history_file_name)
+}
+
+// NewForwardingHistoryExporter creates a ForwardingHistoryExporter writing to the forwarding history CSV of the conduit directory
+func NewForwardingHistoryExporter(client lnrpc.LightningClient, config *Config, log *zerolog.Logger) (*ForwardingHistoryExporter, error) {
+	interval := default_forwarding_export_period
+	if config.ForwardingExportInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(config.ForwardingExportInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ForwardingExportInterval %v: %v", config.ForwardingExportInterval, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid ForwardingExportInterval %v: must be positive", config.ForwardingExportInterval)
+		}
+	}
+	return &ForwardingHistoryExporter{
+		client:   client,
+		filename: ForwardingHistoryPath(config),
+		log:      NewSubLogger(log, "FWDH"),
+		interval: interval,
+	}, nil
+}
+
+// Run exports the forwarding history right away and then every interval until the context is cancelled
+func (e *ForwardingHistoryExporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := e.Export(ctx); err != nil && ctx.Err() == nil {
+			e.log.SubLogger.Error().Msg(fmt.Sprintf("could not export forwarding history: %v", err))
+		} else if n > 0 {
+			e.log.SubLogger.Info().Msg(fmt.Sprintf("Exported %d forwarding events", n))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export appends the forwarding events which weren't exported yet and returns how many were written
+func (e *ForwardingHistoryExporter) Export(ctx context.Context) (int, error) {
+	// the rows already in the file are the events exported by a previous run
+	if !e.loaded {
+		if utils.FileExists(e.filename) {
+			rows, err := readCSVRows(e.filename, len(forwarding_history_header))
+			if err != nil {
+				return 0, err
+			}
+			e.offset = uint32(len(rows))
+		}
+		e.loaded = true
+	}
+	exported := 0
+	for {
+		resp, err := e.client.ForwardingHistory(ctx, &lnrpc.ForwardingHistoryRequest{
+			StartTime:    0,
+			EndTime:      uint64(time.Now().Unix()),
+			IndexOffset:  e.offset,
+			NumMaxEvents: forwarding_page_size,
+		})
+		if err != nil {
+			return exported, err
+		}
+		if len(resp.ForwardingEvents) > 0 {
+			rows := make([][]string, len(resp.ForwardingEvents))
+			for i, fwd := range resp.ForwardingEvents {
+				rows[i] = forwardingEventFromLnd(fwd).row()
+			}
+			if err = appendCSVRows(e.filename, forwarding_history_header, rows); err != nil {
+				return exported, err
+			}
+			e.offset += uint32(len(rows))
+			exported += len(rows)
+		}
+		if len(resp.ForwardingEvents) < forwarding_page_size {
+			return exported, nil
+		}
+	}
+}
+
+// forwardingEventFromLnd extracts the fields of a ForwardingEvent from an LND forwarding event
+func forwardingEventFromLnd(fwd *lnrpc.ForwardingEvent) *ForwardingEvent {
+	timestamp := time.Unix(int64(fwd.Timestamp), 0)
+	if fwd.TimestampNs != 0 {
+		timestamp = time.Unix(0, int64(fwd.TimestampNs))
+	}
+	return &ForwardingEvent{
+		Timestamp:     timestamp,
+		ChanIdIn:      fwd.ChanIdIn,
+		ChanIdOut:     fwd.ChanIdOut,
+		AmountInMsat:  fwd.AmtInMsat,
+		AmountOutMsat: fwd.AmtOutMsat,
+		FeeMsat:       fwd.FeeMsat,
+	}
+}
+
+// row returns the CSV row of the event
+func (e *ForwardingEvent) row() []string {
+	return []string{
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatUint(e.ChanIdIn, 10),
+		strconv.FormatUint(e.ChanIdOut, 10),
+		strconv.FormatUint(e.AmountInMsat, 10),
+		strconv.FormatUint(e.AmountOutMsat, 10),
+		strconv.FormatUint(e.FeeMsat, 10),
+	}
+}
+
+// ReadForwardingHistory reads the forwarding history CSV and returns the events which happened at or after since. A missing file has no events
+func ReadForwardingHistory(filename string, since time.Time) ([]*ForwardingEvent, error) {
+	rows, err := readCSVRows(filename, len(forwarding_history_header))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var events []*ForwardingEvent
+	for i, row := range rows {
+		event := &ForwardingEvent{}
+		if event.Timestamp, err = time.Parse(time.RFC3339Nano, row[0]); err != nil {
+			return nil, fmt.Errorf("row %d: invalid timestamp %v", i+1, row[0])
+		}
+		if event.Timestamp.Before(since) {
+			continue
+		}
+		for j, field := range []*uint64{&event.ChanIdIn, &event.ChanIdOut, &event.AmountInMsat, &event.AmountOutMsat, &event.FeeMsat} {
+			if *field, err = strconv.ParseUint(row[1+j], 10, 64); err != nil {
+				return nil, fmt.Errorf("row %d: invalid %s %v", i+1, forwarding_history_header[1+j], row[1+j])
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestForwardingHistoryExporterDeduplication ensures events are exported once, across exports and restarts
+func TestForwardingHistoryExporterDeduplication(t *testing.T) {
+	cfg := &Config{ConduitDir: t.TempDir(), ForwardingExportInterval: "30m"}
+	log := zerolog.Nop()
+	client := &fakeLightningClient{}
+	client.addForwards(3)
+	exporter, err := NewForwardingHistoryExporter(client, cfg, &log)
+	if err != nil {
+		t.Fatalf("NewForwardingHistoryExporter returned an error: %v", err)
+	}
+	for i, want := range []int{3, 0} {
+		if n, err := exporter.Export(context.Background()); err != nil || n != want {
+			t.Fatalf("export %d: expected %d events, got %d (%v)", i, want, n, err)
+		}
+	}
+	client.addForwards(forwarding_page_size + 2)
+	if n, err := exporter.Export(context.Background()); err != nil || n != forwarding_page_size+2 {
+		t.Fatalf("expected %d events over two pages, got %d (%v)", forwarding_page_size+2, n, err)
+	}
+	// a new exporter resumes from the rows already in the file
+	client.addForwards(1)
+	exporter, _ = NewForwardingHistoryExporter(client, cfg, &log)
+	if n, err := exporter.Export(context.Background()); err != nil || n != 1 {
+		t.Fatalf("expected 1 event after restart, got %d (%v)", n, err)
+	}
+	events, err := ReadForwardingHistory(ForwardingHistoryPath(cfg), time.Time{})
+	if err != nil {
+		t.Fatalf("ReadForwardingHistory returned an error: %v", err)
+	}
+	if len(events) != len(client.forwards) {
+		t.Fatalf("expected %d rows, got %d", len(client.forwards), len(events))
+	}
+	for i, event := range events {
+		if event.ChanIdIn != 100+uint64(i) || event.FeeMsat != 1000+uint64(i) || !event.Timestamp.Equal(time.Unix(1650000000+int64(i), 0)) {
+			t.Fatalf("row %d was exported out of order or twice: %+v", i, event)
+		}
+	}
+	since := time.Unix(1650000000+int64(len(events)-2), 0)
+	if events, _ = ReadForwardingHistory(ForwardingHistoryPath(cfg), since); len(events) != 2 {
+		t.Errorf("expected 2 events since %v, got %d", since, len(events))
+	}
+}
+
+// TestNewForwardingHistoryExporterInterval ensures the interval defaults to 1h and invalid values are rejected
+func TestNewForwardingHistoryExporterInterval(t *testing.T) {
+	log := zerolog.Nop()
+	exporter, err := NewForwardingHistoryExporter(&fakeLightningClient{}, &Config{}, &log)
+	if err != nil || exporter.interval != time.Hour {
+		t.Errorf("expected a default interval of 1h, got %v (%v)", exporter, err)
+	}
+	for _, interval := range []string{"often", "-1h"} {
+		if _, err = NewForwardingHistoryExporter(&fakeLightningClient{}, &Config{ForwardingExportInterval: interval}, &log); err == nil {
+			t.Errorf("expected interval %q to be rejected", interval)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+)
+
+const goroutine_dump_initial_size = 64 << 10
+
+// goroutineStacks returns the stacks of every goroutine, formatted like the dump of an unrecovered panic
+func goroutineStacks() string {
+	buf := make([]byte, goroutine_dump_initial_size)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		// the stacks were truncated
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// debugGoroutines is the conduit_debug_goroutines method, which returns the goroutine stacks of the daemon unless DebugMode is off
+func (s *RPCServer) debugGoroutines(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	if !s.cfg.DebugMode {
+		return nil, jsonrpc.NewError(jsonrpc.JSONRPC_METHOD_NOT_FOUND, "conduit_debug_goroutines is only available in debug mode")
+	}
+	return goroutineStacks(), nil
+}
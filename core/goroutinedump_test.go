@@ -0,0 +1,32 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+)
+
+// TestDebugGoroutines ensures conduit_debug_goroutines returns every goroutine stack in debug mode only
+func TestDebugGoroutines(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	var stacks string
+	err := client.Call(context.Background(), "conduit_debug_goroutines", nil, &stacks)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.JSONRPC_METHOD_NOT_FOUND {
+		t.Fatalf("expected the method to be unavailable outside of debug mode, got %v", err)
+	}
+	s.cfg.DebugMode = true
+	// the stacks outgrow the initial buffer
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < 1000; i++ {
+		go func() { <-done }()
+	}
+	if err = client.Call(context.Background(), "conduit_debug_goroutines", nil, &stacks); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stacks, "goroutine ") || !strings.Contains(stacks, "goroutine 1 [") || strings.Count(stacks, "\n\ngoroutine ") < 1000 {
+		t.Errorf("unexpected stacks of %d bytes:\n%.500s", len(stacks), stacks)
+	}
+}
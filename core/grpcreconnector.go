@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	ErrLNDStreamEnded                     = errors.Error("ended before Conduit stopped")
+	default_lnd_rpc_reconnect_max_backoff = time.Minute
+	lnd_rpc_reconnect_base_backoff        = time.Second
+	// LND keeps gRPC's default enforcement policy, which closes connections pinging more often than every 5 minutes
+	lnd_rpc_keepalive_time    = 5 * time.Minute
+	lnd_rpc_keepalive_timeout = 20 * time.Second
+	// LNDRPCNotDialed is the state of the connection before Dial is called
+	LNDRPCNotDialed = "NOT_DIALED"
+)
+
+// LNDRPCState is the state of the persistent connection to LND's gRPC server
+type LNDRPCState struct {
+	State string    `json:"state"`
+	Since time.Time `json:"since"`
+	// Reconnects is the number of times the connection was lost and established again
+	Reconnects int `json:"reconnects"`
+}
+
+// GRPCReconnector maintains a persistent gRPC connection to LND, reconnecting with exponential backoff whenever it drops
+type GRPCReconnector struct {
+	log         *subLogger
+	dial        func(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error)
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	// dialMu makes the callers of Dial share one connection
+	dialMu  sync.Mutex
+	mu      sync.RWMutex
+	conn    *grpc.ClientConn
+	state   LNDRPCState
+	pinning *TLSPinning
+}
+
+// NewGRPCReconnector creates a new GRPCReconnector for the LND configured in cfg
+func NewGRPCReconnector(cfg *Config, log *zerolog.Logger) (*GRPCReconnector, error) {
+	maxBackoff := default_lnd_rpc_reconnect_max_backoff
+	if cfg.LNDRPCReconnectMaxBackoff != "" {
+		var err error
+		maxBackoff, err = time.ParseDuration(cfg.LNDRPCReconnectMaxBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LNDRPCReconnectMaxBackoff %v: %v", cfg.LNDRPCReconnectMaxBackoff, err)
+		}
+	}
+	r := &GRPCReconnector{
+		log:         NewSubLogger(log, "GRPC"),
+		baseBackoff: lnd_rpc_reconnect_base_backoff,
+		maxBackoff:  maxBackoff,
+		state:       LNDRPCState{State: LNDRPCNotDialed, Since: time.Now()},
+	}
+	// the admin macaroon is read when dialing since LND creates it on first start
+	r.dial = func(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		macaroon, err := withAdminMacaroon(cfg)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.RLock()
+		pinning := r.pinning
+		r.mu.RUnlock()
+		return dialLnd(ctx, cfg, pinning, append(opts, macaroon)...)
+	}
+	return r, nil
+}
+
+// SetTLSPinning pins the TLS certificate of LND when dialing
+func (r *GRPCReconnector) SetTLSPinning(pinning *TLSPinning) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pinning = pinning
+}
+
+// Dial connects to LND and blocks until the connection is ready. The connection is kept open, and reconnected when it drops, until ctx is done
+func (r *GRPCReconnector) Dial(ctx context.Context) (*grpc.ClientConn, error) {
+	r.dialMu.Lock()
+	defer r.dialMu.Unlock()
+	if conn := r.Connection(); conn != nil {
+		return conn, nil
+	}
+	backoffConfig := backoff.DefaultConfig
+	backoffConfig.BaseDelay = r.baseBackoff
+	backoffConfig.MaxDelay = r.maxBackoff
+	conn, err := r.dial(ctx,
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: lnd_rpc_keepalive_time, Timeout: lnd_rpc_keepalive_timeout}),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffConfig, MinConnectTimeout: r.baseBackoff}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to lnd: %v", err)
+	}
+	if !waitForReady(ctx, conn) {
+		conn.Close()
+		return nil, fmt.Errorf("could not connect to lnd: %v", ctx.Err())
+	}
+	r.mu.Lock()
+	r.conn = conn
+	r.state = LNDRPCState{State: connectivity.Ready.String(), Since: time.Now()}
+	r.mu.Unlock()
+	go r.watch(ctx, conn)
+	return conn, nil
+}
+
+// waitForReady blocks until the connection is ready, returning false if ctx is done first
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) bool {
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+	return true
+}
+
+// RunWhileConnected calls run until ctx is done. Whenever run returns before, as the streams of LND do when it restarts, it's called again
+// once the connection is ready, backing off between the calls. It returns right away if Dial hasn't succeeded yet
+func (r *GRPCReconnector) RunWhileConnected(ctx context.Context, name string, run func() error) {
+	conn := r.Connection()
+	if conn == nil {
+		return
+	}
+	utils.RetryWithBackoff(ctx, func() (struct{}, error) {
+		if !waitForReady(ctx, conn) {
+			return struct{}{}, ctx.Err()
+		}
+		err := run()
+		if ctx.Err() != nil {
+			return struct{}{}, nil
+		} else if err == nil {
+			err = ErrLNDStreamEnded
+		}
+		return struct{}{}, err
+	}, utils.RetryOptions{
+		BaseDelay: r.baseBackoff,
+		MaxDelay:  r.maxBackoff,
+		RetryIf:   func(error) bool { return ctx.Err() == nil },
+		OnRetry: func(attempt int, delay time.Duration, err error) {
+			r.log.SubLogger.Warn().Msg(fmt.Sprintf("%s stopped: %v, restarting in %v", name, err, delay.Round(time.Millisecond)))
+		},
+	})
+}
+
+// watch records the state changes of the connection and closes it once ctx is done
+func (r *GRPCReconnector) watch(ctx context.Context, conn *grpc.ClientConn) {
+	state := connectivity.Ready
+	for conn.WaitForStateChange(ctx, state) {
+		previous := state
+		state = conn.GetState()
+		r.mu.Lock()
+		r.state.State = state.String()
+		r.state.Since = time.Now()
+		if state == connectivity.Ready {
+			r.state.Reconnects++
+		}
+		r.mu.Unlock()
+		switch {
+		case previous == connectivity.Ready:
+			r.log.SubLogger.Warn().Msg(fmt.Sprintf("Lost connection to LND (%v), reconnecting", state))
+		case state == connectivity.Ready:
+			r.log.SubLogger.Info().Msg("Reconnected to LND")
+		}
+	}
+	conn.Close()
+	r.mu.Lock()
+	r.state = LNDRPCState{State: connectivity.Shutdown.String(), Since: time.Now(), Reconnects: r.state.Reconnects}
+	r.mu.Unlock()
+}
+
+// Connection returns the connection to LND, or nil if Dial hasn't succeeded yet
+func (r *GRPCReconnector) Connection() *grpc.ClientConn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conn
+}
+
+// State returns the current state of the connection
+func (r *GRPCReconnector) State() LNDRPCState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+// keepLndConnected dials LND once its RPC server is active and calls f with the persistent connection
+func keepLndConnected(ctx context.Context, bus *EventReplay, reconnector *GRPCReconnector, log *zerolog.Logger, f func(conn *grpc.ClientConn)) {
+	// the last wallet state is replayed, so that an LND active before the subscription is dialed too
+	events, unsubscribe := bus.Subscribe(EventWalletState, WithReplay(1))
+	go func() {
+		defer unsubscribe()
+		if !waitForWalletState(ctx, events, lnrpc.WalletState_RPC_ACTIVE) {
+			return
+		}
+		conn, err := reconnector.Dial(ctx)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return
+		}
+		f(conn)
+	}()
+}
+
+// RegisterLNDRPCState registers the conduit_lnd_rpc_state method
+func (s *RPCServer) RegisterLNDRPCState(reconnector *GRPCReconnector) {
+	s.Register("conduit_lnd_rpc_state", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return reconnector.State(), nil
+	})
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// startDroppingServer serves gRPC on addr until the returned function is called, which drops every connection
+func startDroppingServer(t *testing.T, addr string) func() {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	go server.Serve(listener)
+	return server.Stop
+}
+
+// newTestReconnector returns a GRPCReconnector dialing addr without TLS nor macaroon and backing off for at most 50ms
+func newTestReconnector(t *testing.T, addr string) *GRPCReconnector {
+	log := zerolog.Nop()
+	r, err := NewGRPCReconnector(&Config{LNDRPCReconnectMaxBackoff: "50ms"}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.baseBackoff = 10 * time.Millisecond
+	r.dial = func(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		return grpc.DialContext(ctx, addr, append(opts, grpc.WithInsecure())...)
+	}
+	return r
+}
+
+// waitForRPCState polls the state of the reconnector until it matches or 5 seconds have passed
+func waitForRPCState(t *testing.T, r *GRPCReconnector, want connectivity.State) LNDRPCState {
+	t.Helper()
+	var state LNDRPCState
+	for i := 0; i < 500; i++ {
+		if state = r.State(); state.State == want.String() {
+			return state
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the connection to be %v, got %+v", want, state)
+	return state
+}
+
+// TestGRPCReconnector ensures the same connection is used again once the server comes back after dropping it
+func TestGRPCReconnector(t *testing.T) {
+	addr := freeTCPAddr(t)
+	stop := startDroppingServer(t, addr)
+	r := newTestReconnector(t, addr)
+	if r.Connection() != nil || r.State().State != LNDRPCNotDialed {
+		t.Fatalf("expected no connection before dialing, got %+v", r.State())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := r.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := r.Dial(ctx); err != nil || again != conn || r.Connection() != conn {
+		t.Fatalf("expected the connection to be reused, got %v", err)
+	}
+	waitForRPCState(t, r, connectivity.Ready)
+
+	stop()
+	for i := 0; r.State().State == connectivity.Ready.String() && i < 500; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state := r.State(); state.State == connectivity.Ready.String() {
+		t.Fatalf("expected the connection to drop, got %+v", state)
+	}
+	stop = startDroppingServer(t, addr)
+	defer stop()
+	if state := waitForRPCState(t, r, connectivity.Ready); state.Reconnects != 1 {
+		t.Errorf("expected 1 reconnection, got %+v", state)
+	}
+	if r.Connection() != conn {
+		t.Error("expected the connection to be kept")
+	}
+
+	cancel()
+	waitForRPCState(t, r, connectivity.Shutdown)
+}
+
+// TestGRPCReconnectorDialTimeout ensures Dial gives up once ctx is done when nothing listens
+func TestGRPCReconnectorDialTimeout(t *testing.T) {
+	r := newTestReconnector(t, freeTCPAddr(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := r.Dial(ctx); err == nil {
+		t.Fatal("expected an error dialing a closed port")
+	}
+	if r.Connection() != nil {
+		t.Error("expected no connection to be kept")
+	}
+	log := zerolog.Nop()
+	if _, err := NewGRPCReconnector(&Config{LNDRPCReconnectMaxBackoff: "soon"}, &log); err == nil {
+		t.Error("expected an error for an invalid max backoff")
+	}
+}
+
+// TestLNDRPCStateRPC ensures conduit_lnd_rpc_state returns the state of the connection
+func TestLNDRPCStateRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	s.RegisterLNDRPCState(newTestReconnector(t, freeTCPAddr(t)))
+	var state LNDRPCState
+	if err := client.Call(context.Background(), "conduit_lnd_rpc_state", nil, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.State != LNDRPCNotDialed || state.Reconnects != 0 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
+
+// TestGRPCReconnectorRunWhileConnected ensures the callers of Dial share the connection, and that run is called again once LND is back
+// whenever it returns early
+func TestGRPCReconnectorRunWhileConnected(t *testing.T) {
+	addr := freeTCPAddr(t)
+	stop := startDroppingServer(t, addr)
+	r := newTestReconnector(t, addr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conns := make(chan *grpc.ClientConn, 3)
+	for i := 0; i < cap(conns); i++ {
+		go func() {
+			conn, _ := r.Dial(ctx)
+			conns <- conn
+		}()
+	}
+	conn := <-conns
+	if conn == nil || <-conns != conn || <-conns != conn {
+		t.Fatal("expected the concurrent callers of Dial to share the connection")
+	}
+	calls := 0
+	restarted := make(chan func(), 1)
+	running := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.RunWhileConnected(ctx, "test stream", func() error {
+			calls++
+			if state := conn.GetState(); state != connectivity.Ready {
+				t.Errorf("expected run to be called once connected, got %v", state)
+			}
+			switch calls {
+			case 1:
+				// LND restarts
+				stop()
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					restarted <- startDroppingServer(t, addr)
+				}()
+				return errors.New("stream reset")
+			case 2:
+				// the stream ends cleanly
+				return nil
+			}
+			close(running)
+			<-ctx.Done()
+			return nil
+		})
+	}()
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected run to be called 3 times")
+	}
+	cancel()
+	<-done
+	(<-restarted)()
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	ipc_trace_file_name = "ipc_trace.log"
+	// ipc_trace_preview_size is the number of bytes of every message written to the trace
+	ipc_trace_preview_size = 64
+)
+
+// IPCTraceEntry is a line of the IPC trace, describing a message sent by a plugin to another
+type IPCTraceEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Size      int       `json:"size"`
+	// Preview is the hex encoded first 64 bytes of the message
+	Preview string `json:"preview"`
+}
+
+// Involves reports whether the named plugin sent or received the message
+func (e *IPCTraceEntry) Involves(plugin string) bool {
+	return e.From == plugin || e.To == plugin
+}
+
+// IPCTracePath returns the path of the IPC trace in the conduit directory
+func IPCTracePath(config *Config) string {
+	return path.Join(config.ConduitDir, ipc_trace_file_name)
+}
+
+// PluginCommunicationLog appends an NDJSON entry to the IPC trace for every message sent through the PluginIPCBus
+type PluginCommunicationLog struct {
+	sync.Mutex
+	file *os.File
+	now  func() time.Time
+}
+
+// OpenPluginCommunicationLog opens the IPC trace at filename, appending to it if it exists
+func OpenPluginCommunicationLog(filename string) (*PluginCommunicationLog, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &PluginCommunicationLog{file: file, now: time.Now}, nil
+}
+
+// Record appends the entry of a message sent by from to to
+func (l *PluginCommunicationLog) Record(from, to string, msg []byte) error {
+	preview := msg
+	if len(preview) > ipc_trace_preview_size {
+		preview = preview[:ipc_trace_preview_size]
+	}
+	line, err := json.Marshal(&IPCTraceEntry{
+		Timestamp: l.now().UTC(),
+		From:      from,
+		To:        to,
+		Size:      len(msg),
+		Preview:   hex.EncodeToString(preview),
+	})
+	if err != nil {
+		return err
+	}
+	l.Lock()
+	defer l.Unlock()
+	// a single write so that readers following the trace never see half a line
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the IPC trace
+func (l *PluginCommunicationLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readIPCTrace decodes every entry of the IPC trace, failing if a line isn't a JSON object
+func readIPCTrace(t *testing.T, filename string) []IPCTraceEntry {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []IPCTraceEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry IPCTraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid trace line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestPluginCommunicationLogFormat ensures entries are NDJSON with the size and a 64 bytes preview of the message
+func TestPluginCommunicationLogFormat(t *testing.T) {
+	filename := path.Join(t.TempDir(), ipc_trace_file_name)
+	trace, err := OpenPluginCommunicationLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	trace.now = func() time.Time { return now }
+	if err = trace.Record("signer", "watcher", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err = trace.Record("watcher", "signer", bytes.Repeat([]byte{0xab}, 100)); err != nil {
+		t.Fatal(err)
+	}
+	trace.Close()
+	raw, _ := os.ReadFile(filename)
+	first := strings.SplitN(string(raw), "\n", 2)[0]
+	if want := `{"timestamp":"2022-05-01T12:00:00Z","from":"signer","to":"watcher","size":5,"preview":"68656c6c6f"}`; first != want {
+		t.Errorf("expected %s, got %s", want, first)
+	}
+	entries := readIPCTrace(t, filename)
+	if len(entries) != 2 || entries[1].Size != 100 || entries[1].Preview != strings.Repeat("ab", 64) {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if !entries[1].Involves("signer") || !entries[1].Involves("watcher") || entries[1].Involves("other") {
+		t.Error("unexpected Involves result")
+	}
+}
+
+// TestPluginIPCBusTrace ensures the bus records the messages sent through it
+func TestPluginIPCBusTrace(t *testing.T) {
+	bus := newTestIPCBus(t, "a", "b")
+	filename := path.Join(t.TempDir(), ipc_trace_file_name)
+	trace, err := OpenPluginCommunicationLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.SetTrace(trace)
+	a := newTestIPCClient(t, bus, "a", "b")
+	b := newTestIPCClient(t, bus, "b", "a")
+	inbox := ipcInbox(b)
+	pingUntilConnected(t, a, "b", inbox)
+	if err = a.Send("b", []byte("payload")); err != nil {
+		t.Fatal(err)
+	}
+	for m := range inbox {
+		if string(m.msg) == "payload" {
+			break
+		}
+	}
+	entries := readIPCTrace(t, filename)
+	last := entries[len(entries)-1]
+	if last.From != "a" || last.To != "b" || last.Size != 7 || last.Preview != "7061796c6f6164" {
+		t.Errorf("unexpected trace entry %+v", last)
+	}
+	// the pings sent before b connected are traced even though they were dropped
+	for _, entry := range entries[:len(entries)-1] {
+		if entry.Preview != "70696e67" {
+			t.Errorf("unexpected trace entry %+v", entry)
+		}
+	}
+}
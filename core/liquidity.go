@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_liquidity_check_interval = 5 * time.Minute
+	default_liquidity_warn_pct       = 10.0
+)
+
+// LowLiquidityAlert is the payload of EventChannelLowLiquidity
+type LowLiquidityAlert struct {
+	ChanId        uint64  `json:"chan_id"`
+	ChannelPoint  string  `json:"channel_point"`
+	Capacity      int64   `json:"capacity"`
+	LocalBalance  int64   `json:"local_balance"`
+	RemoteBalance int64   `json:"remote_balance"`
+	LocalPct      float64 `json:"local_pct"`
+}
+
+// ChannelLiquidityMonitor periodically checks the local balance of the channels and alerts when it drops below a percentage of their capacity
+type ChannelLiquidityMonitor struct {
+	client    lnrpc.LightningClient
+	bus       *EventReplay
+	log       *subLogger
+	interval  time.Duration
+	threshold float64
+	// low are the channels already below the threshold, alerted once until they recover
+	low map[uint64]bool
+}
+
+// NewChannelLiquidityMonitor creates a new ChannelLiquidityMonitor publishing its alerts on the given event bus
+func NewChannelLiquidityMonitor(client lnrpc.LightningClient, bus *EventReplay, config *Config, log *zerolog.Logger) (*ChannelLiquidityMonitor, error) {
+	interval := default_liquidity_check_interval
+	if config.LiquidityCheckInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(config.LiquidityCheckInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LiquidityCheckInterval %v: %v", config.LiquidityCheckInterval, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid LiquidityCheckInterval %v: must be positive", config.LiquidityCheckInterval)
+		}
+	}
+	threshold := config.LiquidityWarnThresholdPct
+	if threshold <= 0 {
+		threshold = default_liquidity_warn_pct
+	}
+	return &ChannelLiquidityMonitor{
+		client:    client,
+		bus:       bus,
+		log:       NewSubLogger(log, "LIQM"),
+		interval:  interval,
+		threshold: threshold,
+		low:       make(map[uint64]bool),
+	}, nil
+}
+
+// Run checks the channels right away and then every interval until the context is cancelled
+func (m *ChannelLiquidityMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			m.log.SubLogger.Error().Msg(fmt.Sprintf("could not check channel liquidity: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check lists the channels, logs a warning and publishes EventChannelLowLiquidity for every channel whose local balance just dropped below the threshold, and returns the alerts
+func (m *ChannelLiquidityMonitor) Check(ctx context.Context) ([]LowLiquidityAlert, error) {
+	resp, err := m.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var alerts []LowLiquidityAlert
+	open := make(map[uint64]bool, len(resp.Channels))
+	for _, channel := range resp.Channels {
+		open[channel.ChanId] = true
+		if channel.Capacity <= 0 {
+			continue
+		}
+		pct := 100 * float64(channel.LocalBalance) / float64(channel.Capacity)
+		if pct >= m.threshold {
+			delete(m.low, channel.ChanId)
+			continue
+		}
+		if m.low[channel.ChanId] {
+			continue
+		}
+		m.low[channel.ChanId] = true
+		alert := LowLiquidityAlert{
+			ChanId:        channel.ChanId,
+			ChannelPoint:  channel.ChannelPoint,
+			Capacity:      channel.Capacity,
+			LocalBalance:  channel.LocalBalance,
+			RemoteBalance: channel.RemoteBalance,
+			LocalPct:      pct,
+		}
+		m.log.SubLogger.Warn().Uint64("chan_id", alert.ChanId).Int64("local_balance", alert.LocalBalance).Int64("remote_balance", alert.RemoteBalance).Msg(fmt.Sprintf("Local balance of channel %d is %.1f%% of its capacity, below %.1f%%", alert.ChanId, pct, m.threshold))
+		m.bus.Publish(EventChannelLowLiquidity, alert)
+		alerts = append(alerts, alert)
+	}
+	// closed channels are forgotten
+	for chanId := range m.low {
+		if !open[chanId] {
+			delete(m.low, chanId)
+		}
+	}
+	return alerts, nil
+}
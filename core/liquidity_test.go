@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+// TestChannelLiquidityMonitor ensures an alert fires once when a channel drops below the threshold and again after it recovered
+func TestChannelLiquidityMonitor(t *testing.T) {
+	client := &fakeLightningClient{}
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	events, unsubscribe := bus.Subscribe(EventChannelLowLiquidity)
+	defer unsubscribe()
+	log := zerolog.Nop()
+	monitor, err := NewChannelLiquidityMonitor(client, bus, &Config{LiquidityWarnThresholdPct: 20}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setBalances := func(local ...int64) {
+		client.channels = nil
+		for i, balance := range local {
+			client.channels = append(client.channels, &lnrpc.Channel{ChanId: uint64(i + 1), Capacity: 1000000, LocalBalance: balance, RemoteBalance: 1000000 - balance})
+		}
+	}
+	steps := []struct {
+		local []int64
+		want  []uint64
+	}{
+		// exactly at the threshold isn't below it
+		{[]int64{200000, 500000}, nil},
+		{[]int64{199999, 500000}, []uint64{1}},
+		// still low, already alerted
+		{[]int64{100000, 100000}, []uint64{2}},
+		// channel 1 recovers and drops again
+		{[]int64{300000, 100000}, nil},
+		{[]int64{0, 100000}, []uint64{1}},
+	}
+	for i, step := range steps {
+		setBalances(step.local...)
+		alerts, err := monitor.Check(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != len(step.want) {
+			t.Fatalf("step %d: expected alerts for %v, got %+v", i, step.want, alerts)
+		}
+		for j, chanId := range step.want {
+			if alerts[j].ChanId != chanId {
+				t.Errorf("step %d: expected an alert for channel %d, got %+v", i, chanId, alerts[j])
+			}
+			select {
+			case event := <-events:
+				alert := event.Payload.(LowLiquidityAlert)
+				if alert.ChanId != chanId || alert.LocalBalance+alert.RemoteBalance != 1000000 || alert.Capacity != 1000000 {
+					t.Errorf("step %d: unexpected event %+v", i, alert)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("step %d: expected an event for channel %d", i, chanId)
+			}
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+	if _, err = NewChannelLiquidityMonitor(client, bus, &Config{LiquidityCheckInterval: "soon"}, &log); err == nil {
+		t.Error("expected an invalid interval to be rejected")
+	}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+)
+
+const (
+	live_config_path   = "/debug/config"
+	sensitive_value    = "***"
+	live_config_layout = `<!DOCTYPE html>
+<html>
+<head><title>Conduit configuration</title></head>
+<body>
+<h1>Conduit configuration</h1>
+<table border="1">
+<tr><th>Name</th><th>Value</th><th>Description</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Value}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
+)
+
+// liveConfigTemplate escapes the values so that config values can't inject markup in the page
+var liveConfigTemplate = template.Must(template.New("config").Parse(live_config_layout))
+
+// liveConfigRow is a row of the configuration table
+type liveConfigRow struct {
+	Name        string
+	Value       string
+	Description string
+}
+
+// LiveConfigView is an `http.Handler` rendering the current `Config` as an HTML table. It answers 404 unless `Config.DebugMode` is set
+type LiveConfigView struct {
+	cfg *Config
+}
+
+// NewLiveConfigView creates a new LiveConfigView of the given config
+func NewLiveConfigView(cfg *Config) *LiveConfigView {
+	return &LiveConfigView{cfg: cfg}
+}
+
+// ServeHTTP implements the `http.Handler` interface
+func (v *LiveConfigView) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !v.cfg.DebugMode {
+		http.NotFound(w, r)
+		return
+	}
+	value := reflect.ValueOf(v.cfg).Elem()
+	rows := make([]liveConfigRow, value.NumField())
+	for i := range rows {
+		f := value.Type().Field(i)
+		rows[i] = liveConfigRow{Name: f.Name, Value: fmt.Sprint(value.Field(i).Interface()), Description: f.Tag.Get("description")}
+		if IsSensitiveField(f.Name) {
+			rows[i].Value = sensitive_value
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := liveConfigTemplate.Execute(w, rows); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
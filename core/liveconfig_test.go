@@ -0,0 +1,42 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestLiveConfigViewDisabled ensures the config isn't served outside of debug mode
+func TestLiveConfigViewDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewLiveConfigView(&Config{LndAlias: "conduit"}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, live_config_path, nil))
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "conduit") {
+		t.Errorf("expected a 404 without the config, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestLiveConfigView ensures every field is rendered with its description, secrets are masked and values are escaped
+func TestLiveConfigView(t *testing.T) {
+	cfg := &Config{DebugMode: true, LndAlias: "<script>alert(1)</script>", LndBtcdRPCPass: "hunter2", CrashWebhookURL: "https://hooks.example.com/hunter3", LogSampleRate: 42}
+	rec := httptest.NewRecorder()
+	NewLiveConfigView(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, live_config_path, nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	for _, want := range []string{
+		"<td>LogSampleRate</td><td>42</td><td>Maximum number of identical log events written per sample window. Set to 0 to disable sampling</td>",
+		"<td>LndBtcdRPCPass</td><td>***</td>",
+		"<td>CrashWebhookURL</td><td>***</td>",
+		"<td>DebugMode</td><td>true</td>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "hunter2") || strings.Contains(body, "hunter3") || strings.Contains(body, "<script>") {
+		t.Errorf("page leaks a secret or unescaped markup:\n%s", body)
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/macaroon.v2"
+)
+
+const (
+	default_lnd_rpc_addr   = "localhost:10009"
+	default_tls_cert_name  = "tls.cert"
+	default_macaroon_name  = "admin.macaroon"
+	lnd_tls_cert_poll_time = 1 * time.Second
+)
+
+// lndTLSCertPath returns the path of LND's TLS certificate
+func lndTLSCertPath(config *Config) string {
+	if config.LndTLSCertPath != "" {
+		return config.LndTLSCertPath
+	}
+	return filepath.Join(utils.AppDataDir("lnd", false), default_tls_cert_name)
+}
+
+// lndNetwork returns the bitcoin network LND is configured to run on
+func lndNetwork(config *Config) string {
+	switch {
+	case config.LndBitcoinTestNet3:
+		return "testnet"
+	case config.LndBitcoinSimNet:
+		return "simnet"
+	case config.LndBitcoinRegTest:
+		return "regtest"
+	case config.LndBitcoinSigNet:
+		return "signet"
+	default:
+		return "mainnet"
+	}
+}
+
+// lndAdminMacaroonPath returns the path of LND's admin macaroon
+func lndAdminMacaroonPath(config *Config) string {
+	if config.LndAdminMacPath != "" {
+		return config.LndAdminMacPath
+	}
+	dataDir := config.LndDataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(utils.AppDataDir("lnd", false), "data")
+	}
+	return filepath.Join(dataDir, "chain", "bitcoin", lndNetwork(config), default_macaroon_name)
+}
+
+// withAdminMacaroon returns a dial option authenticating every call with LND's admin macaroon
+func withAdminMacaroon(config *Config) (grpc.DialOption, error) {
+	macBytes, err := ioutil.ReadFile(lndAdminMacaroonPath(config))
+	if err != nil {
+		return nil, fmt.Errorf("could not read macaroon: %v", err)
+	}
+	mac := &macaroon.Macaroon{}
+	if err = mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, fmt.Errorf("could not decode macaroon: %v", err)
+	}
+	macCred, err := macaroons.NewMacaroonCredential(mac)
+	if err != nil {
+		return nil, fmt.Errorf("could not create macaroon credential: %v", err)
+	}
+	return grpc.WithPerRPCCredentials(macCred), nil
+}
+
+// lndRPCAddr returns the address of LND's gRPC server
+func lndRPCAddr(config *Config) string {
+	if len(config.LndRawRPCListeners) == 0 {
+		return default_lnd_rpc_addr
+	}
+	addr := config.LndRawRPCListeners[0]
+	if host, port, err := net.SplitHostPort(addr); err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		return net.JoinHostPort("localhost", port)
+	} else if err != nil && !strings.Contains(addr, ":") {
+		return net.JoinHostPort("localhost", addr)
+	}
+	return addr
+}
+
+// dialLnd waits for LND's TLS certificate to be written and then dials LND's gRPC server. The certificate is pinned unless pinning is nil
+func dialLnd(ctx context.Context, config *Config, pinning *TLSPinning, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	ticker := time.NewTicker(lnd_tls_cert_poll_time)
+	defer ticker.Stop()
+	certPath := lndTLSCertPath(config)
+	for !utils.FileExists(certPath) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	var creds grpc.DialOption
+	if pinning != nil {
+		var err error
+		if creds, err = pinning.Pin(certPath); err != nil {
+			return nil, err
+		}
+	} else {
+		tlsCreds, err := credentials.NewClientTLSFromFile(certPath, "")
+		if err != nil {
+			return nil, err
+		}
+		creds = grpc.WithTransportCredentials(tlsCreds)
+	}
+	opts = append([]grpc.DialOption{creds}, opts...)
+	return grpc.DialContext(ctx, lndRPCAddr(config), opts...)
+}
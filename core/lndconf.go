@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+const (
+	lnd_conf_main_section = "Application Options"
+)
+
+var (
+	// lnd_conf_sections maps option namespaces to the name of their group in LND's config when the two differ
+	lnd_conf_sections = map[string]string{
+		"bitcoin":   "Bitcoin",
+		"litecoin":  "Litecoin",
+		"autopilot": "Autopilot",
+		"tor":       "Tor",
+	}
+	// lnd_conf_excluded are the options which make no sense in lnd.conf
+	lnd_conf_excluded = map[string]bool{
+		"configfile": true,
+	}
+)
+
+// LNDConfigGenerator writes a native lnd.conf from Conduit's `Config`
+type LNDConfigGenerator struct{}
+
+// NewLNDConfigGenerator creates a new LNDConfigGenerator
+func NewLNDConfigGenerator() *LNDConfigGenerator {
+	return &LNDConfigGenerator{}
+}
+
+// Generate writes every LND config parameter set in c to w in the INI format of lnd.conf, one section per namespace
+func (g *LNDConfigGenerator) Generate(c *Config, w io.Writer) error {
+	file := ini.Empty(ini.LoadOptions{AllowShadows: true})
+	// the main section always comes first, even when empty
+	if _, err := file.NewSection(lnd_conf_main_section); err != nil {
+		return err
+	}
+	var err error
+	c.eachLndOption(func(alias string, inter interface{}) {
+		if err != nil || lnd_conf_excluded[alias] {
+			return
+		}
+		var values []string
+		switch q := inter.(type) {
+		case string:
+			if q != "" {
+				values = []string{q}
+			}
+		case []string:
+			values = q
+		case bool:
+			if q {
+				values = []string{"true"}
+			}
+		case map[string]string:
+			// sorted so that the same config always generates the same file
+			keys := make([]string, 0, len(q))
+			for k := range q {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				values = append(values, fmt.Sprintf("%v:%v", k, q[k]))
+			}
+		}
+		if len(values) == 0 {
+			return
+		}
+		var section *ini.Section
+		section, err = file.NewSection(lndConfSection(alias))
+		if err != nil {
+			return
+		}
+		for _, value := range values {
+			if _, err = section.NewKey(alias, value); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// lndConfSection returns the lnd.conf section of an option from its namespace
+func lndConfSection(alias string) string {
+	i := strings.Index(alias, ".")
+	if i == -1 {
+		return lnd_conf_main_section
+	}
+	namespace := alias[:i]
+	if section, ok := lnd_conf_sections[namespace]; ok {
+		return section
+	}
+	return namespace
+}
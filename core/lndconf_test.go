@@ -0,0 +1,39 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestLNDConfigGenerator ensures the generated lnd.conf matches the golden file
+func TestLNDConfigGenerator(t *testing.T) {
+	cfg := &Config{
+		ConsoleOutput:         true,
+		LndShowVersion:        true,
+		LndConfigPath:         "/home/user/.lnd/lnd.conf",
+		LndDataDir:            "/home/user/.lnd/data",
+		LndAlias:              "conduit",
+		LndTLSExtraIPs:        []string{"10.0.0.1", "10.0.0.2"},
+		LndBitcoinActive:      true,
+		LndBitcoinMainNet:     true,
+		LndBitcoinNode:        "bitcoind",
+		LndBitcoindRPCUser:    "user",
+		LndAutopilotActive:    "true",
+		LndAutopilotHeuristic: map[string]string{"top_centrality": "0.5", "preferential": "0.5"},
+		LndTorActive:          true,
+	}
+	var out bytes.Buffer
+	if err := NewLNDConfigGenerator().Generate(cfg, &out); err != nil {
+		t.Fatalf("Generate returned an error: %v", err)
+	}
+	golden, err := ioutil.ReadFile("testdata/lnd.conf.golden")
+	if err != nil {
+		t.Fatalf("Error reading golden file: %v", err)
+	}
+	if diff := cmp.Diff(string(golden), out.String()); diff != "" {
+		t.Errorf("generated lnd.conf differs from the golden file (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	color "github.com/mgutz/ansi"
+	"github.com/rs/zerolog"
+)
+
+// lnd_log_colors are the colors of the LND log levels, as mgutz/ansi styles
+var lnd_log_colors = map[string]string{
+	"TRC": "magenta",
+	"DBG": "cyan",
+	"INF": "green",
+	"WRN": "yellow",
+	"ERR": "red",
+	"CRT": "red+b",
+}
+
+// noColor reports whether the NO_COLOR environment variable asks for output without colors, see https://no-color.org
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// LNDLogColorizer writes the LND log events emitted by parseLndLog to the console with their level and subsystem colored, in front of the message as LND prints them.
+// Other events are written unchanged
+type LNDLogColorizer struct {
+	out     io.Writer
+	enabled bool
+	levels  map[string]string
+}
+
+// NewLNDLogColorizer creates a new LNDLogColorizer writing to the given console writer. Colors are disabled when NO_COLOR is set
+func NewLNDLogColorizer(out io.Writer) *LNDLogColorizer {
+	levels := make(map[string]string, len(lnd_log_abbreviations))
+	for abbreviation, level := range lnd_log_abbreviations {
+		levels[level.String()] = abbreviation
+	}
+	return &LNDLogColorizer{out: out, enabled: !noColor(), levels: levels}
+}
+
+// Write implements the `io.Writer` interface
+func (c *LNDLogColorizer) Write(p []byte) (int, error) {
+	if !c.enabled || !bytes.Contains(p, []byte(`"process":"LND"`)) {
+		return c.out.Write(p)
+	}
+	var event map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&event); err != nil || event["process"] != "LND" {
+		return c.out.Write(p)
+	}
+	level, _ := event[zerolog.LevelFieldName].(string)
+	abbreviation, ok := c.levels[level]
+	if !ok {
+		return c.out.Write(p)
+	}
+	style := lnd_log_colors[abbreviation]
+	// the console writer prints a level it doesn't know as is, see InitLogger
+	event[zerolog.LevelFieldName] = color.Color("["+abbreviation+"]", style)
+	// the console writer quotes field values with escape sequences, so the subsystem is moved in front of the message as LND prints it
+	if subsystem, ok := event["subsystem"].(string); ok {
+		message, _ := event[zerolog.MessageFieldName].(string)
+		event[zerolog.MessageFieldName] = color.Color(subsystem, style) + ": " + message
+		delete(event, "subsystem")
+	}
+	colored, err := json.Marshal(event)
+	if err != nil {
+		return c.out.Write(p)
+	}
+	if _, err = c.out.Write(append(colored, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
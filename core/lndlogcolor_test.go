@@ -0,0 +1,59 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// newColorizerLogger returns a logger writing to a console writer through an LNDLogColorizer, the console writer printing levels as is
+func newColorizerLogger(out *bytes.Buffer) zerolog.Logger {
+	console := zerolog.ConsoleWriter{Out: out, NoColor: true, FormatLevel: func(i interface{}) string { return fmt.Sprint(i) }}
+	return zerolog.New(NewLNDLogColorizer(console))
+}
+
+// TestLNDLogColorizer ensures the level and subsystem of LND log events are colored and other events are left alone
+func TestLNDLogColorizer(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	var out bytes.Buffer
+	log := newColorizerLogger(&out)
+	lnd := log.With().Str("process", "LND").Logger()
+	for _, c := range []struct {
+		event *zerolog.Event
+		code  string
+		level string
+	}{
+		{lnd.Info(), "\x1b[0;32m", "INF"},
+		{lnd.Warn(), "\x1b[0;33m", "WRN"},
+		{lnd.Error(), "\x1b[0;31m", "ERR"},
+		{lnd.WithLevel(zerolog.FatalLevel), "\x1b[0;1;31m", "CRT"},
+		{lnd.Trace(), "\x1b[0;35m", "TRC"},
+		{lnd.Debug(), "\x1b[0;36m", "DBG"},
+	} {
+		out.Reset()
+		c.event.Str("subsystem", "HSWC").Msg("forwarded")
+		want := fmt.Sprintf("%s[%s]\x1b[0m %sHSWC\x1b[0m: forwarded process=LND\n", c.code, c.level, c.code)
+		if !strings.HasSuffix(out.String(), want) {
+			t.Errorf("expected %q to end with %q", out.String(), want)
+		}
+	}
+	out.Reset()
+	log.Info().Str("subsystem", "RPCS").Msg("conduit")
+	if strings.Contains(out.String(), "\x1b[") || !strings.Contains(out.String(), "info") {
+		t.Errorf("unexpected colors in a Conduit event: %q", out.String())
+	}
+}
+
+// TestLNDLogColorizerNoColor ensures NO_COLOR disables the colors
+func TestLNDLogColorizerNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	var out bytes.Buffer
+	log := newColorizerLogger(&out)
+	log.Info().Str("process", "LND").Str("subsystem", "HSWC").Msg("forwarded")
+	if strings.Contains(out.String(), "\x1b[") || !strings.Contains(out.String(), "info") {
+		t.Errorf("unexpected colors with NO_COLOR set: %q", out.String())
+	}
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// lnd_log_level_names maps the LND log level names, as accepted by --debuglevel, to zerolog levels
+var lnd_log_level_names = map[string]zerolog.Level{
+	"trace":    zerolog.TraceLevel,
+	"debug":    zerolog.DebugLevel,
+	"info":     zerolog.InfoLevel,
+	"warn":     zerolog.WarnLevel,
+	"error":    zerolog.ErrorLevel,
+	"critical": zerolog.FatalLevel,
+}
+
+// parseLNDLogFilterLevel returns the level of either an LND level name or abbreviation, in any case
+func parseLNDLogFilterLevel(level string) (zerolog.Level, bool) {
+	if lvl, ok := lnd_log_abbreviations[strings.ToUpper(level)]; ok {
+		return lvl, true
+	}
+	lvl, ok := lnd_log_level_names[strings.ToLower(level)]
+	return lvl, ok
+}
+
+// LNDLogFilter drops the LND log lines of the subsystems too verbose at their level, like CRTR and HSWC at INFO, before they are parsed.
+// It must not be changed once LND's output is being parsed
+type LNDLogFilter struct {
+	minLevels map[string]zerolog.Level
+}
+
+// NewLNDLogFilter creates an LNDLogFilter keeping every line
+func NewLNDLogFilter() *LNDLogFilter {
+	return &LNDLogFilter{minLevels: make(map[string]zerolog.Level)}
+}
+
+// NewLNDLogFilterFromConfig creates an LNDLogFilter with the minimum levels of the LNDSubsystemLevels parameter, which ValidateConfig checked
+func NewLNDLogFilterFromConfig(cfg *Config) *LNDLogFilter {
+	f := NewLNDLogFilter()
+	for subsystem, minLevel := range cfg.LNDSubsystemLevels {
+		f.AddFilter(subsystem, minLevel)
+	}
+	return f
+}
+
+// AddFilter drops the lines of the subsystem below minLevel, an LND level name like warn or abbreviation like WRN. An unknown level is ignored
+func (f *LNDLogFilter) AddFilter(subsystem, minLevel string) *LNDLogFilter {
+	if lvl, ok := parseLNDLogFilterLevel(minLevel); ok {
+		f.minLevels[strings.ToUpper(subsystem)] = lvl
+	}
+	return f
+}
+
+// Allow reports whether the line is kept. Lines which don't have the format of LND log lines are always kept
+func (f *LNDLogFilter) Allow(line string) bool {
+	if f == nil || len(f.minLevels) == 0 {
+		return true
+	}
+	// the line is split by hand since it's done before the regex match, for every line LND writes: "2006-01-02 15:04:05.000 [INF] CRTR: text"
+	start := strings.Index(line, " [")
+	if start < 0 || len(line) < start+12 || line[start+5:start+7] != "] " || line[start+11] != ':' {
+		return true
+	}
+	minLevel, ok := f.minLevels[line[start+7:start+11]]
+	if !ok {
+		return true
+	}
+	level, ok := lnd_log_abbreviations[line[start+2:start+5]]
+	return !ok || level >= minLevel
+}
+
+// ValidateLNDSubsystemLevels checks that the keys of levels are LND subsystems and the values LND level names or abbreviations
+func ValidateLNDSubsystemLevels(levels map[string]string) error {
+	subsystems := make([]string, 0, len(levels))
+	for subsystem := range levels {
+		subsystems = append(subsystems, subsystem)
+	}
+	// the first invalid subsystem is always the same one
+	sort.Strings(subsystems)
+	for _, subsystem := range subsystems {
+		level := levels[subsystem]
+		if !lnd_subsystem_regex.MatchString(strings.ToUpper(subsystem)) {
+			return fmt.Errorf("%w %s=%s: invalid subsystem %q", ErrInvalidLNDLogLevel, subsystem, level, subsystem)
+		}
+		if _, ok := parseLNDLogFilterLevel(level); !ok {
+			return fmt.Errorf("%w %s=%s: unknown level %q", ErrInvalidLNDLogLevel, subsystem, level, level)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestLNDLogFilter ensures a filter on CRTR=WARN drops the INFO lines of CRTR only
+func TestLNDLogFilter(t *testing.T) {
+	filter := NewLNDLogFilter().AddFilter("CRTR", "WARN").AddFilter("hswc", "err").AddFilter("PEER", "loud")
+	tests := []struct {
+		line  string
+		allow bool
+	}{
+		{"2022-01-01 12:00:00.000 [INF] CRTR: Pruning channel graph", false},
+		{"2022-01-01 12:00:00.000 [DBG] CRTR: Processing ChannelEdgePolicy", false},
+		{"2022-01-01 12:00:00.000 [WRN] CRTR: Unable to fetch channel", true},
+		{"2022-01-01 12:00:00.000 [ERR] CRTR: Unable to prune", true},
+		{"2022-01-01 12:00:00.000 [WRN] HSWC: Link is slow", false},
+		{"2022-01-01 12:00:00.000 [CRT] HSWC: Switch stopped", true},
+		{"2022-01-01 12:00:00.000 [INF] LTND: Version: 0.14.2-beta", true},
+		// the filter with an unknown level is ignored
+		{"2022-01-01 12:00:00.000 [INF] PEER: New peer", true},
+		{"unable to load config", true},
+		{"2022-01-01 12:00:00.000 [INF] CRTR", true},
+	}
+	for _, test := range tests {
+		if allow := filter.Allow(test.line); allow != test.allow {
+			t.Errorf("expected Allow(%q) to be %v", test.line, test.allow)
+		}
+	}
+	var nilFilter *LNDLogFilter
+	if !nilFilter.Allow(tests[0].line) {
+		t.Error("expected a nil filter to keep every line")
+	}
+}
+
+// lineRecorder is a LineParser recording the lines it's given
+type lineRecorder struct {
+	lines []string
+}
+
+func (r *lineRecorder) ParseLine(line string) {
+	r.lines = append(r.lines, line)
+}
+
+// TestParseLndLogFilter ensures the filtered lines aren't logged but still reach the LND output detectors and the log stats
+func TestParseLndLogFilter(t *testing.T) {
+	lines := strings.Join([]string{
+		"2022-01-01 12:00:00.000 [INF] CRTR: Pruning channel graph",
+		"2022-01-01 12:00:01.000 [ERR] CRTR: Unable to prune",
+		"2022-01-01 12:00:02.000 [INF] LTND: Active chain: Bitcoin",
+	}, "\n")
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	output := NewLNDProcessOutput()
+	recorder := &lineRecorder{}
+	output.Register(recorder)
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte("LNDSubsystemLevels:\n  CRTR: WARN\n"), cfg); err != nil {
+		t.Fatalf("could not parse LNDSubsystemLevels: %v", err)
+	}
+	stats := NewLNDLogAggregator()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	parseLndLog(bufio.NewScanner(strings.NewReader(lines)), &log, regexp.MustCompile(lndLogRegex), NewLNDLogFilterFromConfig(cfg), output, stats, make(chan struct{}), &wg)
+	logged := buf.String()
+	if strings.Contains(logged, "Pruning channel graph") || !strings.Contains(logged, "Unable to prune") || !strings.Contains(logged, "Active chain") {
+		t.Errorf("expected only the INFO line of CRTR to be dropped, got %s", logged)
+	}
+	if len(recorder.lines) != 3 {
+		t.Errorf("expected every line to be dispatched, got %v", recorder.lines)
+	}
+	if counts := stats.Counts(); counts["CRTR"][zerolog.InfoLevel] != 1 || counts["CRTR"][zerolog.ErrorLevel] != 1 {
+		t.Errorf("expected the dropped line to be counted, got %v", counts)
+	}
+}
+
+// TestValidateLNDSubsystemLevels ensures the subsystems and levels of LNDSubsystemLevels are checked
+func TestValidateLNDSubsystemLevels(t *testing.T) {
+	if err := ValidateLNDSubsystemLevels(map[string]string{"CRTR": "WARN", "hswc": "error", "PEER": "INF"}); err != nil {
+		t.Errorf("expected valid levels, got %v", err)
+	}
+	for _, levels := range []map[string]string{{"CRTR": "loud"}, {"ROUTER": "warn"}, {"CRTR": ""}} {
+		if err := ValidateLNDSubsystemLevels(levels); !errors.Is(err, ErrInvalidLNDLogLevel) {
+			t.Errorf("expected ErrInvalidLNDLogLevel for %v, got %v", levels, err)
+		}
+	}
+}
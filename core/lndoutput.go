@@ -0,0 +1,37 @@
+package core
+
+import (
+	"sync"
+)
+
+// LineParser is implemented by the subsystems which watch the raw output of the LND process
+type LineParser interface {
+	ParseLine(line string)
+}
+
+// LNDProcessOutput dispatches every line written by the LND process to the registered LineParsers
+type LNDProcessOutput struct {
+	sync.RWMutex
+	parsers []LineParser
+}
+
+// NewLNDProcessOutput creates a new LNDProcessOutput without parsers
+func NewLNDProcessOutput() *LNDProcessOutput {
+	return &LNDProcessOutput{}
+}
+
+// Register adds a LineParser receiving every subsequent line of the LND output
+func (o *LNDProcessOutput) Register(p LineParser) {
+	o.Lock()
+	defer o.Unlock()
+	o.parsers = append(o.parsers, p)
+}
+
+// dispatch passes a line of the LND output to every registered LineParser, in registration order
+func (o *LNDProcessOutput) dispatch(line string) {
+	o.RLock()
+	defer o.RUnlock()
+	for _, p := range o.parsers {
+		p.ParseLine(line)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+)
+
+const (
+	ErrLndNotRunning     = errors.Error("LND isn't running")
+	ErrLndRestartPending = errors.Error("LND is already restarting")
+	// LND is restarted at most once every lnd_restart_interval, as its startup takes a while
+	lnd_restart_interval = 5 * time.Minute
+)
+
+var (
+	// lnd_process_id is the PID of the LND process started by Conduit, 0 while LND isn't running. It's only accessed atomically,
+	// the atomic.Int64 type requiring Go 1.19
+	lnd_process_id int64
+	// lnd_restart_requested is 1 once Conduit stopped LND to restart it, so that startLnd starts it again instead of handling its exit as a crash
+	lnd_restart_requested int32
+)
+
+// LNDProcessIDResponse is the result of the conduit_lnd_pid method
+type LNDProcessIDResponse struct {
+	PID int `json:"pid"`
+}
+
+// LNDProcessID returns the PID of the LND process started by Conduit, 0 if LND isn't running
+func LNDProcessID() int {
+	return int(atomic.LoadInt64(&lnd_process_id))
+}
+
+// startLndProcess starts the LND process and records its PID
+func startLndProcess(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&lnd_process_id, int64(cmd.Process.Pid))
+	return nil
+}
+
+// waitLndProcess waits for the LND process to exit and resets its PID
+func waitLndProcess(cmd *exec.Cmd) error {
+	defer atomic.StoreInt64(&lnd_process_id, 0)
+	return cmd.Wait()
+}
+
+// RestartLND stops the LND process started by Conduit, which startLnd then starts again with the reloaded config
+func RestartLND() error {
+	pid := LNDProcessID()
+	if pid == 0 {
+		return ErrLndNotRunning
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if !atomic.CompareAndSwapInt32(&lnd_restart_requested, 0, 1) {
+		return ErrLndRestartPending
+	}
+	if err = process.Signal(os.Interrupt); err != nil {
+		atomic.StoreInt32(&lnd_restart_requested, 0)
+		return fmt.Errorf("could not stop LND: %v", err)
+	}
+	return nil
+}
+
+// lndRestartRequested reports whether LND exited because Conduit restarts it, and clears the request
+func lndRestartRequested() bool {
+	return atomic.SwapInt32(&lnd_restart_requested, 0) == 1
+}
+
+// lndPID is the conduit_lnd_pid method, which tells operators running several nodes which process is the LND of this Conduit
+func (s *RPCServer) lndPID(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return LNDProcessIDResponse{PID: LNDProcessID()}, nil
+}
+
+// lndRestart is the conduit_lnd_restart method, which restarts LND so that the changes of its config file take effect
+func (s *RPCServer) lndRestart(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	if err := RestartLND(); err == ErrLndNotRunning {
+		return nil, jsonrpc.NewError(jsonrpc.ErrLNDNotRunning, err.Error())
+	} else if err != nil {
+		return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, err.Error())
+	}
+	s.log.SubLogger.Info().Msg("Restarting LND")
+	return nil, nil
+}
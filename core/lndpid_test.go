@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+)
+
+// TestLNDProcessID ensures the PID of the LND process is served while it runs and reset once it exits
+func TestLNDProcessID(t *testing.T) {
+	_, client := newTestRPCServer(t)
+	var resp LNDProcessIDResponse
+	if err := client.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err != nil || resp.PID != 0 {
+		t.Fatalf("expected a zero PID before LND is started, got %d: %v", resp.PID, err)
+	}
+	// the fake plugin binary stands for LND, running until interrupted
+	cmd := exec.Command(os.Args[0], "-test.run=TestFakePlugin")
+	cmd.Env = append(os.Environ(), "CONDUIT_FAKE_PLUGIN=run")
+	if err := startLndProcess(cmd); err != nil {
+		t.Fatalf("startLndProcess returned an error: %v", err)
+	}
+	if pid := LNDProcessID(); pid == 0 || pid != cmd.Process.Pid {
+		t.Errorf("expected the PID %d, got %d", cmd.Process.Pid, pid)
+	}
+	if err := client.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err != nil || resp.PID != cmd.Process.Pid {
+		t.Errorf("expected conduit_lnd_pid to return %d, got %d: %v", cmd.Process.Pid, resp.PID, err)
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	// the interrupt may kill the process before it handles it, which Wait reports as an error
+	waitLndProcess(cmd)
+	if pid := LNDProcessID(); pid != 0 {
+		t.Errorf("expected a zero PID once LND stopped, got %d", pid)
+	}
+	if err := client.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err != nil || resp.PID != 0 {
+		t.Errorf("expected conduit_lnd_pid to return 0 once LND stopped, got %d: %v", resp.PID, err)
+	}
+}
+
+// TestLNDRestart ensures conduit_lnd_restart stops LND so that it's started again, at most once every lnd_restart_interval
+func TestLNDRestart(t *testing.T) {
+	if err := RestartLND(); err != ErrLndNotRunning {
+		t.Errorf("expected ErrLndNotRunning before LND is started, got %v", err)
+	}
+	_, client := newTestRPCServer(t)
+	cmd := exec.Command(os.Args[0], "-test.run=TestFakePlugin")
+	cmd.Env = append(os.Environ(), "CONDUIT_FAKE_PLUGIN=run")
+	if err := startLndProcess(cmd); err != nil {
+		t.Fatalf("startLndProcess returned an error: %v", err)
+	}
+	if err := client.Call(context.Background(), "conduit_lnd_restart", nil, nil); err != nil {
+		t.Fatalf("conduit_lnd_restart returned an error: %v", err)
+	}
+	waitLndProcess(cmd)
+	if !lndRestartRequested() {
+		t.Error("expected LND's exit to be reported as a restart")
+	}
+	if lndRestartRequested() {
+		t.Error("expected the restart request to be cleared once reported")
+	}
+	err := client.Call(context.Background(), "conduit_lnd_restart", nil, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrRateLimited {
+		t.Errorf("expected a second restart to be rate limited, got %v", err)
+	}
+}
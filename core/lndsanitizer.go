@@ -0,0 +1,87 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const ErrLNDConfigConflict = errors.Error("mutually exclusive LND options")
+
+// lndFlagExclusion is a group of LND flags which can't be set together
+type lndFlagExclusion struct {
+	// flags are in order of preference, the first one set is kept and the others removed
+	flags  []string
+	reason string
+	// fatal groups can't be resolved without guessing what the operator meant, so they are reported rather than removed
+	fatal bool
+}
+
+// lnd_flag_exclusions are the mutually exclusive flags LND 0.14 refuses to start with
+var lnd_flag_exclusions = []lndFlagExclusion{
+	{flags: []string{"bitcoin.mainnet", "bitcoin.testnet", "bitcoin.simnet", "bitcoin.regtest", "bitcoin.signet"}, reason: "only one bitcoin network can be selected", fatal: true},
+	{flags: []string{"litecoin.mainnet", "litecoin.testnet", "litecoin.simnet", "litecoin.regtest", "litecoin.signet"}, reason: "only one litecoin network can be selected", fatal: true},
+	{flags: []string{"bitcoin.active", "litecoin.active"}, reason: "bitcoin and litecoin can't be active together", fatal: true},
+	{flags: []string{"noseedbackup", "wallet-unlock-password-file"}, reason: "the wallet can't be both created without a seed backup and unlocked with a password file", fatal: true},
+	{flags: []string{"nolisten", "tor.v2"}, reason: "inbound connections over Tor need listening to be enabled"},
+	{flags: []string{"nolisten", "tor.v3"}, reason: "inbound connections over Tor need listening to be enabled"},
+	{flags: []string{"tor.v3", "tor.v2"}, reason: "either tor.v2 or tor.v3 can be set, tor.v2 being deprecated"},
+	{flags: []string{"nolisten", "nat"}, reason: "NAT traversal can't be used when listening is disabled"},
+	{flags: []string{"externalhosts", "nat"}, reason: "NAT traversal can't be used along with external hosts"},
+}
+
+// lndFlagName returns the name of the flag of a --name or --name=value tag
+func lndFlagName(tag string) string {
+	return strings.SplitN(strings.TrimPrefix(tag, "--"), "=", 2)[0]
+}
+
+// LNDConfigSanitizer removes the LND flags conflicting with others before LND is started, since LND refuses to start with them
+type LNDConfigSanitizer struct {
+	log *subLogger
+}
+
+// NewLNDConfigSanitizer creates a new LNDConfigSanitizer
+func NewLNDConfigSanitizer(log *zerolog.Logger) *LNDConfigSanitizer {
+	return &LNDConfigSanitizer{log: NewSubLogger(log, "SANI")}
+}
+
+// Sanitize returns the tags without those conflicting with a preferred flag, and the removed tags. Conflicts which can't be resolved safely,
+// like two networks, return ErrLNDConfigConflict
+func (s *LNDConfigSanitizer) Sanitize(tags []string) ([]string, []string, error) {
+	set := make(map[string]bool)
+	for _, tag := range tags {
+		set[lndFlagName(tag)] = true
+	}
+	// reasons are keyed by removed flag
+	reasons := make(map[string]string)
+	for _, exclusion := range lnd_flag_exclusions {
+		var present []string
+		for _, flag := range exclusion.flags {
+			if _, removed := reasons[flag]; set[flag] && !removed {
+				present = append(present, flag)
+			}
+		}
+		if len(present) < 2 {
+			continue
+		}
+		if exclusion.fatal {
+			return nil, nil, fmt.Errorf("%w --%s: %s", ErrLNDConfigConflict, strings.Join(present, " and --"), exclusion.reason)
+		}
+		for _, flag := range present[1:] {
+			reasons[flag] = fmt.Sprintf("conflicts with --%s, %s", present[0], exclusion.reason)
+		}
+	}
+	var valid, removed []string
+	for _, tag := range tags {
+		reason, ok := reasons[lndFlagName(tag)]
+		if !ok {
+			valid = append(valid, tag)
+			continue
+		}
+		removed = append(removed, tag)
+		s.log.SubLogger.Warn().Msg(fmt.Sprintf("Removed %s from the LND options: it %s", tag, reason))
+	}
+	return valid, removed, nil
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestLNDConfigSanitizer ensures every known conflict is resolved by removing the same flags, or reported when it can't be
+func TestLNDConfigSanitizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		valid   []string
+		removed []string
+		fatal   bool
+	}{
+		{"no conflict", []string{"--bitcoin.active", "--bitcoin.mainnet", "--alias=alice"}, []string{"--bitcoin.active", "--bitcoin.mainnet", "--alias=alice"}, nil, false},
+		{"bitcoin networks", []string{"--bitcoin.mainnet", "--bitcoin.testnet"}, nil, nil, true},
+		{"bitcoin signet and regtest", []string{"--bitcoin.regtest", "--bitcoin.signet"}, nil, nil, true},
+		{"litecoin networks", []string{"--litecoin.simnet", "--litecoin.mainnet"}, nil, nil, true},
+		{"active chains", []string{"--bitcoin.active", "--litecoin.active"}, nil, nil, true},
+		{"seed backup and unlock file", []string{"--noseedbackup", "--wallet-unlock-password-file=/pw"}, nil, nil, true},
+		{"nolisten and tor.v2", []string{"--tor.v2", "--nolisten"}, []string{"--nolisten"}, []string{"--tor.v2"}, false},
+		{"nolisten and tor.v3", []string{"--nolisten", "--tor.v3"}, []string{"--nolisten"}, []string{"--tor.v3"}, false},
+		{"tor versions", []string{"--tor.active", "--tor.v2", "--tor.v3"}, []string{"--tor.active", "--tor.v3"}, []string{"--tor.v2"}, false},
+		{"nolisten and nat", []string{"--nat", "--nolisten"}, []string{"--nolisten"}, []string{"--nat"}, false},
+		{"external hosts and nat", []string{"--externalhosts=a.example", "--nat", "--externalhosts=b.example"}, []string{"--externalhosts=a.example", "--externalhosts=b.example"}, []string{"--nat"}, false},
+		{"nolisten, tor and nat", []string{"--nolisten", "--tor.v2", "--tor.v3", "--nat"}, []string{"--nolisten"}, []string{"--tor.v2", "--tor.v3", "--nat"}, false},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		log := zerolog.New(&buf)
+		valid, removed, err := NewLNDConfigSanitizer(&log).Sanitize(test.tags)
+		if test.fatal {
+			if !errors.Is(err, ErrLNDConfigConflict) {
+				t.Errorf("%s: expected ErrLNDConfigConflict, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(valid, test.valid) || !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf("%s: expected %v without %v, got %v without %v", test.name, test.valid, test.removed, valid, removed)
+		}
+		if warnings := strings.Count(buf.String(), `"level":"warn"`); warnings != len(test.removed) {
+			t.Errorf("%s: expected %d warnings, got %d: %s", test.name, len(test.removed), warnings, buf.String())
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/openpgp"
+)
+
+const (
+	ErrLNDSignatureInvalid = errors.Error("LND binary signature verification failed")
+	pgp_armor_header       = "-----BEGIN"
+)
+
+// LNDSignatureVerifier verifies the detached PGP signature of the LND binary so that a tampered binary is never run
+type LNDSignatureVerifier struct{}
+
+// NewLNDSignatureVerifier creates a new LNDSignatureVerifier
+func NewLNDSignatureVerifier() *LNDSignatureVerifier {
+	return &LNDSignatureVerifier{}
+}
+
+// isArmored reports whether the PGP data is ASCII armored
+func isArmored(raw []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(raw), []byte(pgp_armor_header))
+}
+
+// Verify checks that the signature, armored or not, of the binary was made by a key of the public key file
+func (v *LNDSignatureVerifier) Verify(binaryPath, signaturePath, pubKeyPath string) error {
+	rawKey, err := ioutil.ReadFile(pubKeyPath)
+	if err != nil {
+		return fmt.Errorf("%w: could not read public key: %v", ErrLNDSignatureInvalid, err)
+	}
+	var keyring openpgp.EntityList
+	if isArmored(rawKey) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(rawKey))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(rawKey))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: invalid public key %v: %v", ErrLNDSignatureInvalid, pubKeyPath, err)
+	}
+	signature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return fmt.Errorf("%w: could not read signature: %v", ErrLNDSignatureInvalid, err)
+	}
+	binary, err := os.Open(binaryPath)
+	if err != nil {
+		return fmt.Errorf("%w: could not read binary: %v", ErrLNDSignatureInvalid, err)
+	}
+	defer binary.Close()
+	if isArmored(signature) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, binary, bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, binary, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v with %v: %v", ErrLNDSignatureInvalid, binaryPath, signaturePath, err)
+	}
+	return nil
+}
+
+// verifyLndBinary verifies the signature of the LND binary when LNDSignaturePath is set, unless SkipSignatureVerification is
+func verifyLndBinary(cfg *Config, lndPath string, log *zerolog.Logger) error {
+	if cfg.SkipSignatureVerification {
+		log.Warn().Msg("!!! SkipSignatureVerification is set: LND is started WITHOUT verifying the signature of its binary. A tampered binary could steal your funds !!!")
+		return nil
+	}
+	if cfg.LNDSignaturePath == "" {
+		return nil
+	}
+	if cfg.LNDSignatureKeyPath == "" {
+		return fmt.Errorf("%w: LNDSignatureKeyPath is required with LNDSignaturePath", ErrLNDSignatureInvalid)
+	}
+	if err := NewLNDSignatureVerifier().Verify(lndPath, cfg.LNDSignaturePath, cfg.LNDSignatureKeyPath); err != nil {
+		return err
+	}
+	log.Info().Msg(fmt.Sprintf("Verified the signature of %v", lndPath))
+	return nil
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// newTestPGPKey generates a PGP key pair and writes its public key, armored or not, to the directory
+func newTestPGPKey(t *testing.T, dir, name string, armored bool) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if armored {
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = entity.Serialize(w); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	} else if err = entity.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	keyPath := path.Join(dir, name+".pub")
+	if err = os.WriteFile(keyPath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return entity, keyPath
+}
+
+// signTestBinary writes a detached signature, armored or not, of the binary made with the key
+func signTestBinary(t *testing.T, entity *openpgp.Entity, binaryPath string, armored bool) string {
+	t.Helper()
+	binary, err := os.ReadFile(binaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if armored {
+		err = openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(binary), nil)
+	} else {
+		err = openpgp.DetachSign(&buf, entity, bytes.NewReader(binary), nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigPath := binaryPath + ".sig"
+	if armored {
+		sigPath = binaryPath + ".asc"
+	}
+	if err = os.WriteFile(sigPath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return sigPath
+}
+
+// TestLNDSignatureVerifier ensures armored and binary signatures of the binary verify with the signer's key only
+func TestLNDSignatureVerifier(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := path.Join(dir, "lnd")
+	if err := os.WriteFile(binaryPath, []byte("the real lnd"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	signer, signerKey := newTestPGPKey(t, dir, "signer", true)
+	_, signerBinaryKey := newTestPGPKey(t, dir, "signer-binary", false)
+	_, otherKey := newTestPGPKey(t, dir, "other", true)
+	armoredSig := signTestBinary(t, signer, binaryPath, true)
+	binarySig := signTestBinary(t, signer, binaryPath, false)
+	verifier := NewLNDSignatureVerifier()
+	for _, sig := range []string{armoredSig, binarySig} {
+		if err := verifier.Verify(binaryPath, sig, signerKey); err != nil {
+			t.Errorf("expected %v to verify: %v", sig, err)
+		}
+		if err := verifier.Verify(binaryPath, sig, otherKey); !errors.Is(err, ErrLNDSignatureInvalid) {
+			t.Errorf("expected %v not to verify with another key, got %v", sig, err)
+		}
+	}
+	if err := verifier.Verify(binaryPath, armoredSig, signerBinaryKey); !errors.Is(err, ErrLNDSignatureInvalid) {
+		t.Errorf("expected the signature not to verify with another unarmored key, got %v", err)
+	}
+	if err := os.WriteFile(binaryPath, []byte("a tampered lnd"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(binaryPath, armoredSig, signerKey); !errors.Is(err, ErrLNDSignatureInvalid) {
+		t.Errorf("expected the tampered binary not to verify, got %v", err)
+	}
+	if err := verifier.Verify(binaryPath, path.Join(dir, "missing.asc"), signerKey); !errors.Is(err, ErrLNDSignatureInvalid) {
+		t.Errorf("expected a missing signature not to verify, got %v", err)
+	}
+}
+
+// TestVerifyLndBinary ensures the binary is only verified when LNDSignaturePath is set and SkipSignatureVerification isn't
+func TestVerifyLndBinary(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := path.Join(dir, "lnd")
+	if err := os.WriteFile(binaryPath, []byte("lnd"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	_, key := newTestPGPKey(t, dir, "signer", true)
+	log := zerolog.Nop()
+	badSig := path.Join(dir, "lnd.asc")
+	if err := os.WriteFile(badSig, []byte("not a signature"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		cfg    *Config
+		refuse bool
+	}{
+		{"no signature", &Config{}, false},
+		{"bad signature", &Config{LNDSignaturePath: badSig, LNDSignatureKeyPath: key}, true},
+		{"no key", &Config{LNDSignaturePath: badSig}, true},
+		{"skipped", &Config{LNDSignaturePath: badSig, LNDSignatureKeyPath: key, SkipSignatureVerification: true}, false},
+	}
+	for _, test := range tests {
+		if err := verifyLndBinary(test.cfg, binaryPath, &log); (err != nil) != test.refuse {
+			t.Errorf("%s: expected refusal %v, got %v", test.name, test.refuse, err)
+		}
+	}
+}
+
+// TestFindLndBinary ensures the lnd binary of the PATH is only returned once verified, so that its version isn't read from an unverified binary
+func TestFindLndBinary(t *testing.T) {
+	dir := t.TempDir()
+	log := zerolog.Nop()
+	t.Setenv("PATH", dir)
+	if _, err := findLndBinary(&Config{}, &log); err != ErrLndNotFound {
+		t.Errorf("expected ErrLndNotFound without lnd in the PATH, got %v", err)
+	}
+	binaryPath := path.Join(dir, "lnd")
+	if err := os.WriteFile(binaryPath, []byte("lnd"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	_, key := newTestPGPKey(t, dir, "signer", true)
+	badSig := path.Join(dir, "lnd.asc")
+	if err := os.WriteFile(badSig, []byte("not a signature"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := findLndBinary(&Config{LNDSignaturePath: badSig, LNDSignatureKeyPath: key}, &log); !errors.Is(err, ErrLNDSignatureInvalid) {
+		t.Errorf("expected ErrLNDSignatureInvalid for a bad signature, got %v", err)
+	}
+	if lndPath, err := findLndBinary(&Config{}, &log); err != nil || lndPath != binaryPath {
+		t.Errorf("expected %v, got %v: %v", binaryPath, lndPath, err)
+	}
+}
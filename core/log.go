@@ -6,6 +6,9 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mattn/go-colorable"
 	color "github.com/mgutz/ansi"
@@ -37,7 +40,7 @@ func InitLogger(config *Config) (zerolog.Logger, error) {
 	var (
 		log_file *os.File
 		err      error
-		logger   zerolog.Logger
+		writer   zerolog.LevelWriter
 	)
 	log_file, err = os.OpenFile(path.Join(config.ConduitDir, log_file_name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0775)
 	if err != nil {
@@ -50,6 +53,11 @@ func InitLogger(config *Config) (zerolog.Logger, error) {
 		} else {
 			output.Out = os.Stderr
 		}
+		paint := color.Color
+		if noColor() {
+			output.NoColor = true
+			paint = func(s, style string) string { return s }
+		}
 		output.FormatLevel = func(i interface{}) string {
 			var msg string
 			switch v := i.(type) {
@@ -57,28 +65,56 @@ func InitLogger(config *Config) (zerolog.Logger, error) {
 				x := fmt.Sprintf("%v", v)
 				switch x {
 				case "info":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "green")
+					msg = paint(strings.ToUpper("["+x+"]"), "green")
 				case "panic":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "red")
+					msg = paint(strings.ToUpper("["+x+"]"), "red")
 				case "fatal":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "red")
+					msg = paint(strings.ToUpper("["+x+"]"), "red")
 				case "error":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "red")
+					msg = paint(strings.ToUpper("["+x+"]"), "red")
 				case "warn":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "yellow")
+					msg = paint(strings.ToUpper("["+x+"]"), "yellow")
 				case "debug":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "yellow")
+					msg = paint(strings.ToUpper("["+x+"]"), "yellow")
 				case "trace":
-					msg = color.Color(strings.ToUpper("["+x+"]"), "magenta")
+					msg = paint(strings.ToUpper("["+x+"]"), "magenta")
+				default:
+					// LND levels are already formatted by the LNDLogColorizer
+					msg = x
 				}
 			}
 			return msg + fmt.Sprintf("\t")
 		}
-		multi := zerolog.MultiLevelWriter(output, log_file)
-		logger = zerolog.New(multi).With().Timestamp().Logger()
+		writer = zerolog.MultiLevelWriter(NewLNDLogColorizer(output), log_file)
 	} else {
-		logger = zerolog.New(log_file).With().Timestamp().Logger()
+		writer = zerolog.MultiLevelWriter(log_file)
+	}
+	if config.SyslogAddr != "" {
+		forwarder, err := NewLNDLogForwarder(config)
+		if err != nil {
+			return zerolog.Logger{}, fmt.Errorf("log: could not connect to syslog server %v: %v", config.SyslogAddr, err)
+		}
+		writer = zerolog.MultiLevelWriter(writer, forwarder)
+	}
+	if config.LogSampleRate > 0 {
+		window := default_log_sample_window
+		if config.LogSampleWindow != "" {
+			window, err = time.ParseDuration(config.LogSampleWindow)
+			if err != nil {
+				return zerolog.Logger{}, fmt.Errorf("log: invalid LogSampleWindow %v: %v", config.LogSampleWindow, err)
+			}
+		}
+		DefaultLogSampler.Close()
+		DefaultLogSampler = NewLogSampler(writer, config.LogSampleRate, window)
+		DefaultLogSampler.FlushEvery(window)
+		writer = DefaultLogSampler
 	}
+	logger := zerolog.New(writer).With().Timestamp().Logger()
+	if config.ErrorContextEnabled {
+		DefaultErrorContextEnricher = NewErrorContextEnricher(config.ConduitDir)
+		logger = logger.Hook(DefaultErrorContextEnricher)
+	}
+	DefaultRegistry.SetRoot(&logger)
 	return logger, nil
 }
 
@@ -92,6 +128,81 @@ func NewSubLogger(l *zerolog.Logger, subsystem string) *subLogger {
 	return &s
 }
 
+// SubsystemLogger is the logger of a subsystem registered in a SubsystemRegistry. It follows the root logger of the registry, so it can
+// be used from several goroutines while the root logger is replaced
+type SubsystemLogger struct {
+	Subsystem string
+	// logger holds the `zerolog.Logger` of the subsystem
+	logger atomic.Value
+}
+
+// newSubsystemLogger creates the logger of the subsystem from the root logger
+func newSubsystemLogger(root *zerolog.Logger, subsystem string) *SubsystemLogger {
+	l := &SubsystemLogger{Subsystem: subsystem}
+	l.setRoot(root)
+	return l
+}
+
+// setRoot derives the logger of the subsystem from a new root logger
+func (l *SubsystemLogger) setRoot(root *zerolog.Logger) {
+	l.logger.Store(root.With().Str("subsystem", l.Subsystem).Logger())
+}
+
+// Logger returns the current logger of the subsystem
+func (l *SubsystemLogger) Logger() *zerolog.Logger {
+	logger := l.logger.Load().(zerolog.Logger)
+	return &logger
+}
+
+// SubsystemRegistry creates the loggers of named subsystems from a root logger and keeps them so that they can be retrieved by name
+type SubsystemRegistry struct {
+	sync.RWMutex
+	root    zerolog.Logger
+	loggers map[string]*SubsystemLogger
+}
+
+// DefaultRegistry is the registry of the root logger created by InitLogger. Loggers registered before InitLogger is called discard their logs until then
+var DefaultRegistry = NewSubsystemRegistry(nil)
+
+// NewSubsystemRegistry creates a new SubsystemRegistry for the given root logger. A nil root logger discards all logs
+func NewSubsystemRegistry(root *zerolog.Logger) *SubsystemRegistry {
+	r := &SubsystemRegistry{root: zerolog.Nop(), loggers: make(map[string]*SubsystemLogger)}
+	if root != nil {
+		r.root = *root
+	}
+	return r
+}
+
+// SetRoot replaces the root logger, including in the loggers already registered
+func (r *SubsystemRegistry) SetRoot(root *zerolog.Logger) {
+	r.Lock()
+	defer r.Unlock()
+	r.root = *root
+	for _, l := range r.loggers {
+		l.setRoot(&r.root)
+	}
+}
+
+// Register returns the logger of the named subsystem, creating it if it isn't registered yet
+func (r *SubsystemRegistry) Register(name string) *SubsystemLogger {
+	r.Lock()
+	defer r.Unlock()
+	if l, ok := r.loggers[name]; ok {
+		return l
+	}
+	l := newSubsystemLogger(&r.root, name)
+	r.loggers[name] = l
+	return l
+}
+
+// Get returns the logger of the named subsystem if it's registered
+func (r *SubsystemRegistry) Get(name string) (*SubsystemLogger, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	l, ok := r.loggers[name]
+	return l, ok
+}
+
 // LogWithErrors is a method which takes a log level and message as a string and writes the corresponding log. Returns an error if the log level doesn't exist
 func (s subLogger) LogWithErrors(level, msg string) error {
 	if lvl, ok := log_level[level]; ok {
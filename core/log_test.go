@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/rs/zerolog"
 )
 
 // TestInitLoggerOutput makes sure both console and logfile output work
@@ -134,3 +136,47 @@ func TestLogWithErrors(t *testing.T) {
 		}
 	}
 }
+
+// TestSubsystemRegistry ensures a registered logger can be retrieved, registering it twice returns the same logger and a new root logger is picked up
+func TestSubsystemRegistry(t *testing.T) {
+	registry := NewSubsystemRegistry(nil)
+	if _, ok := registry.Get("PLUG"); ok {
+		t.Error("expected an unregistered subsystem not to be found")
+	}
+	registered := registry.Register("PLUG")
+	registered.Logger().Info().Msg("discarded until the root logger is set")
+	if got, ok := registry.Get("PLUG"); !ok || got != registered {
+		t.Errorf("expected Get to return the registered logger, got %v", got)
+	}
+	if again := registry.Register("PLUG"); again != registered {
+		t.Error("expected registering twice to return the existing logger")
+	}
+	var buf bytes.Buffer
+	root := zerolog.New(&buf)
+	registry.SetRoot(&root)
+	registered.Logger().Info().Msg("now logged")
+	if out := buf.String(); !strings.Contains(out, `"subsystem":"PLUG"`) || !strings.Contains(out, "now logged") || strings.Contains(out, "discarded") {
+		t.Errorf("unexpected output after setting the root logger: %s", out)
+	}
+	if registry.Register("OTHR").Subsystem != "OTHR" {
+		t.Error("expected the logger to be named after its subsystem")
+	}
+}
+
+// TestSubsystemRegistrySetRootConcurrent ensures the root logger can be replaced while registered loggers are in use, run with -race
+func TestSubsystemRegistrySetRootConcurrent(t *testing.T) {
+	registry := NewSubsystemRegistry(nil)
+	registered := registry.Register("PLUG")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			registered.Logger().Info().Msg("logged while the root logger changes")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		root := zerolog.New(io.Discard)
+		registry.SetRoot(&root)
+	}
+	<-done
+}
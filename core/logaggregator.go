@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// log_aggregator_buckets is the number of one second buckets of the sliding window, i.e. one minute
+	log_aggregator_buckets = 60
+)
+
+// lnd_log_abbreviations maps the LND log level abbreviations to zerolog levels
+var lnd_log_abbreviations = map[string]zerolog.Level{
+	"TRC": zerolog.TraceLevel,
+	"DBG": zerolog.DebugLevel,
+	"INF": zerolog.InfoLevel,
+	"WRN": zerolog.WarnLevel,
+	"ERR": zerolog.ErrorLevel,
+	"CRT": zerolog.FatalLevel,
+}
+
+// logBucket holds the counts of the log events of one second
+type logBucket struct {
+	second int64
+	counts map[string]map[zerolog.Level]int64
+}
+
+// SubsystemLogStats are the log event counts of an LND subsystem over the last minute
+type SubsystemLogStats struct {
+	Subsystem string           `json:"subsystem"`
+	Errors    int64            `json:"errors"`
+	Levels    map[string]int64 `json:"levels"`
+}
+
+// LNDLogAggregator counts the LND log events per subsystem and level over a sliding window of one minute
+type LNDLogAggregator struct {
+	sync.Mutex
+	now     func() time.Time
+	buckets [log_aggregator_buckets]logBucket
+}
+
+// NewLNDLogAggregator creates a new, empty LNDLogAggregator
+func NewLNDLogAggregator() *LNDLogAggregator {
+	return &LNDLogAggregator{now: time.Now}
+}
+
+// Record counts a log event of the given subsystem and level
+func (a *LNDLogAggregator) Record(subsystem string, level zerolog.Level) {
+	a.Lock()
+	defer a.Unlock()
+	second := a.now().Unix()
+	b := &a.buckets[second%log_aggregator_buckets]
+	// the bucket still holds the counts of a second which left the window
+	if b.second != second || b.counts == nil {
+		b.second = second
+		b.counts = make(map[string]map[zerolog.Level]int64)
+	}
+	levels, ok := b.counts[subsystem]
+	if !ok {
+		levels = make(map[zerolog.Level]int64)
+		b.counts[subsystem] = levels
+	}
+	levels[level]++
+}
+
+// Counts returns the number of log events per subsystem and level over the last minute
+func (a *LNDLogAggregator) Counts() map[string]map[zerolog.Level]int64 {
+	a.Lock()
+	defer a.Unlock()
+	now := a.now().Unix()
+	counts := make(map[string]map[zerolog.Level]int64)
+	for _, b := range a.buckets {
+		if b.counts == nil || now-b.second >= log_aggregator_buckets || b.second > now {
+			continue
+		}
+		for subsystem, levels := range b.counts {
+			total, ok := counts[subsystem]
+			if !ok {
+				total = make(map[zerolog.Level]int64)
+				counts[subsystem] = total
+			}
+			for level, n := range levels {
+				total[level] += n
+			}
+		}
+	}
+	return counts
+}
+
+// Stats returns the counts of the last minute sorted by number of errors, highest first, then by subsystem
+func (a *LNDLogAggregator) Stats() []SubsystemLogStats {
+	stats := []SubsystemLogStats{}
+	for subsystem, levels := range a.Counts() {
+		s := SubsystemLogStats{Subsystem: subsystem, Levels: make(map[string]int64, len(levels))}
+		for level, n := range levels {
+			s.Levels[level.String()] = n
+			if level >= zerolog.ErrorLevel {
+				s.Errors += n
+			}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Errors != stats[j].Errors {
+			return stats[i].Errors > stats[j].Errors
+		}
+		return stats[i].Subsystem < stats[j].Subsystem
+	})
+	return stats
+}
+
+// RegisterLogStats registers the conduit_log_stats method
+func (s *RPCServer) RegisterLogStats(aggregator *LNDLogAggregator) {
+	s.Register("conduit_log_stats", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return aggregator.Stats(), nil
+	})
+}
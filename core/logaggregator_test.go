@@ -0,0 +1,70 @@
+package core
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestLNDLogAggregatorWindow ensures counts older than a minute expire and stats are sorted by errors
+func TestLNDLogAggregatorWindow(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	a := NewLNDLogAggregator()
+	a.now = func() time.Time { return now }
+	a.Record("PEER", zerolog.ErrorLevel)
+	a.Record("PEER", zerolog.ErrorLevel)
+	a.Record("PEER", zerolog.InfoLevel)
+	now = now.Add(30 * time.Second)
+	a.Record("HSWC", zerolog.ErrorLevel)
+	a.Record("PEER", zerolog.ErrorLevel)
+	stats := a.Stats()
+	if len(stats) != 2 || stats[0].Subsystem != "PEER" || stats[0].Errors != 3 || stats[0].Levels["info"] != 1 {
+		t.Fatalf("unexpected stats after 30s: %+v", stats)
+	}
+	if stats[1].Subsystem != "HSWC" || stats[1].Errors != 1 {
+		t.Fatalf("unexpected stats after 30s: %+v", stats)
+	}
+	// the events of the first second leave the window
+	now = now.Add(30 * time.Second)
+	counts := a.Counts()
+	if counts["PEER"][zerolog.ErrorLevel] != 1 || counts["PEER"][zerolog.InfoLevel] != 0 || counts["HSWC"][zerolog.ErrorLevel] != 1 {
+		t.Fatalf("unexpected counts after 60s: %v", counts)
+	}
+	// ties are sorted by subsystem
+	if stats = a.Stats(); stats[0].Subsystem != "HSWC" || stats[1].Subsystem != "PEER" {
+		t.Errorf("unexpected order after 60s: %+v", stats)
+	}
+	// a bucket reused a minute later starts from zero
+	a.Record("PEER", zerolog.WarnLevel)
+	if counts = a.Counts(); counts["PEER"][zerolog.ErrorLevel] != 1 || counts["PEER"][zerolog.WarnLevel] != 1 {
+		t.Errorf("unexpected counts after reusing a bucket: %v", counts)
+	}
+	now = now.Add(2 * time.Minute)
+	if stats = a.Stats(); len(stats) != 0 {
+		t.Errorf("expected all counts to expire, got %+v", stats)
+	}
+}
+
+// TestParseLndLogFeedsAggregator ensures every parsed LND log line is counted
+func TestParseLndLogFeedsAggregator(t *testing.T) {
+	lines := strings.Join([]string{
+		"2022-04-20 10:00:00.000 [ERR] PEER: unable to read message",
+		"2022-04-20 10:00:00.001 [WRN] PEER: ping timeout",
+		"2022-04-20 10:00:00.002 [INF] LTND: Active chain: Bitcoin",
+		"not an lnd log line",
+	}, "\n")
+	a := NewLNDLogAggregator()
+	log := zerolog.Nop()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	parseLndLog(bufio.NewScanner(strings.NewReader(lines)), &log, regexp.MustCompile(lndLogRegex), nil, NewLNDProcessOutput(), a, make(chan struct{}), &wg)
+	counts := a.Counts()
+	if counts["PEER"][zerolog.ErrorLevel] != 1 || counts["PEER"][zerolog.WarnLevel] != 1 || counts["LTND"][zerolog.InfoLevel] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
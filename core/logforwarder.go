@@ -0,0 +1,99 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_syslog_network = "udp"
+	default_syslog_tag     = "lnd"
+)
+
+// syslog_priorities maps the zerolog levels produced by parseLndLog to syslog priorities
+var syslog_priorities = map[zerolog.Level]syslog.Priority{
+	zerolog.TraceLevel: syslog.LOG_DEBUG,   // TRC
+	zerolog.DebugLevel: syslog.LOG_DEBUG,   // DBG
+	zerolog.InfoLevel:  syslog.LOG_INFO,    // INF
+	zerolog.WarnLevel:  syslog.LOG_WARNING, // WRN
+	zerolog.ErrorLevel: syslog.LOG_ERR,     // ERR
+	zerolog.FatalLevel: syslog.LOG_CRIT,    // CRT
+	zerolog.PanicLevel: syslog.LOG_EMERG,
+}
+
+// LNDLogForwarder is a `zerolog.LevelWriter` which forwards the parsed LND log events to a syslog server
+type LNDLogForwarder struct {
+	writer *syslog.Writer
+}
+
+// NewLNDLogForwarder connects to the syslog server configured in the given config
+func NewLNDLogForwarder(config *Config) (*LNDLogForwarder, error) {
+	network, tag := config.SyslogNetwork, config.SyslogTag
+	if network == "" {
+		network = default_syslog_network
+	}
+	if tag == "" {
+		tag = default_syslog_tag
+	}
+	w, err := syslog.Dial(network, config.SyslogAddr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &LNDLogForwarder{writer: w}, nil
+}
+
+// Write implements the `io.Writer` interface
+func (f *LNDLogForwarder) Write(p []byte) (int, error) {
+	var event struct {
+		Level string `json:"level"`
+	}
+	_ = json.Unmarshal(p, &event)
+	level, err := zerolog.ParseLevel(event.Level)
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+	return f.WriteLevel(level, p)
+}
+
+// WriteLevel implements the `zerolog.LevelWriter` interface. Events which weren't emitted by parseLndLog are ignored
+func (f *LNDLogForwarder) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var event struct {
+		Process   string `json:"process"`
+		Subsystem string `json:"subsystem"`
+		Message   string `json:"message"`
+	}
+	if err := json.Unmarshal(p, &event); err != nil || event.Process != "LND" {
+		return len(p), nil
+	}
+	msg := fmt.Sprintf("[%s] %s", event.Subsystem, event.Message)
+	var err error
+	switch syslog_priorities[level] {
+	case syslog.LOG_DEBUG:
+		err = f.writer.Debug(msg)
+	case syslog.LOG_WARNING:
+		err = f.writer.Warning(msg)
+	case syslog.LOG_ERR:
+		err = f.writer.Err(msg)
+	case syslog.LOG_CRIT:
+		err = f.writer.Crit(msg)
+	case syslog.LOG_EMERG:
+		err = f.writer.Emerg(msg)
+	default:
+		err = f.writer.Info(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog server
+func (f *LNDLogForwarder) Close() error {
+	return f.writer.Close()
+}
@@ -0,0 +1,34 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package core
+
+import (
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const ErrSyslogUnsupported = errors.Error("syslog forwarding is not supported on this platform")
+
+// LNDLogForwarder is not supported on Windows and Plan 9 since `log/syslog` isn't available
+type LNDLogForwarder struct{}
+
+// NewLNDLogForwarder always returns ErrSyslogUnsupported
+func NewLNDLogForwarder(config *Config) (*LNDLogForwarder, error) {
+	return nil, ErrSyslogUnsupported
+}
+
+// Write implements the `io.Writer` interface
+func (f *LNDLogForwarder) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel implements the `zerolog.LevelWriter` interface
+func (f *LNDLogForwarder) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close implements the `io.Closer` interface
+func (f *LNDLogForwarder) Close() error {
+	return nil
+}
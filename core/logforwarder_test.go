@@ -0,0 +1,46 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package core
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestLNDLogForwarder ensures parsed LND log events are sent to the syslog server with the right priority
+func TestLNDLogForwarder(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting UDP server: %v", err)
+	}
+	defer conn.Close()
+	forwarder, err := NewLNDLogForwarder(&Config{SyslogAddr: conn.LocalAddr().String(), SyslogTag: "conduit-test"})
+	if err != nil {
+		t.Fatalf("Error creating forwarder: %v", err)
+	}
+	defer forwarder.Close()
+	log := zerolog.New(forwarder)
+	// events which weren't emitted by parseLndLog are not forwarded
+	log.Info().Msg("conduit event")
+	lndLog := log.With().Str("process", "LND").Logger()
+	lndLog.Error().Str("subsystem", "PEER").Msg("unable to connect")
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Error reading syslog message: %v", err)
+	}
+	msg := string(buf[:n])
+	// LOG_DAEMON|LOG_ERR
+	if !strings.HasPrefix(msg, "<27>") {
+		t.Errorf("unexpected syslog priority: %s", msg)
+	}
+	if !strings.Contains(msg, "conduit-test") || !strings.Contains(msg, "[PEER] unable to connect") {
+		t.Errorf("unexpected syslog message: %s", msg)
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_log_sample_window = time.Minute
+	log_sample_prefix_len     = 32
+)
+
+// sampleKey identifies a family of identical log events
+type sampleKey struct {
+	level     zerolog.Level
+	subsystem string
+	prefix    string
+}
+
+// sampleWindow tracks the number of events written and suppressed for a sampleKey during the current window
+type sampleWindow struct {
+	start      time.Time
+	written    int
+	suppressed int
+}
+
+// DefaultLogSampler is the sampler of the root logger created by InitLogger, nil when Config.LogSampleRate isn't set
+var DefaultLogSampler *LogSampler
+
+// LogSampler is a `zerolog.LevelWriter` which writes at most `rate` events per window for each unique (level, subsystem, message prefix) triple.
+// Errors and above are never sampled. The number of suppressed events is logged once the window closes
+type LogSampler struct {
+	sync.Mutex
+	out       zerolog.LevelWriter
+	summary   zerolog.Logger
+	rate      int
+	window    time.Duration
+	windows   map[sampleKey]*sampleWindow
+	now       func() time.Time
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewLogSampler wraps the given writer in a LogSampler
+func NewLogSampler(out zerolog.LevelWriter, rate int, window time.Duration) *LogSampler {
+	return &LogSampler{
+		out:     out,
+		summary: zerolog.New(out).With().Timestamp().Logger(),
+		rate:    rate,
+		window:  window,
+		windows: make(map[sampleKey]*sampleWindow),
+		now:     time.Now,
+		quit:    make(chan struct{}),
+	}
+}
+
+// FlushEvery calls Flush at the given interval until the sampler is closed
+func (s *LogSampler) FlushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Flush()
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the flushes started by FlushEvery and logs the suppressed event summaries of all windows, closed or not. Closing a nil sampler does nothing
+func (s *LogSampler) Close() {
+	if s == nil {
+		return
+	}
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		s.wg.Wait()
+		s.Lock()
+		defer s.Unlock()
+		for key, w := range s.windows {
+			s.closeWindow(key, w)
+			delete(s.windows, key)
+		}
+	})
+}
+
+// Write implements the `io.Writer` interface. Events written without a level are never sampled
+func (s *LogSampler) Write(p []byte) (int, error) {
+	return s.out.Write(p)
+}
+
+// WriteLevel implements the `zerolog.LevelWriter` interface. Errors, fatal and panic events are always written, so that the last lines before
+// a crash aren't dropped
+func (s *LogSampler) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level >= zerolog.ErrorLevel {
+		return s.out.WriteLevel(level, p)
+	}
+	var event struct {
+		Subsystem string `json:"subsystem"`
+		Message   string `json:"message"`
+	}
+	if err := json.Unmarshal(p, &event); err != nil {
+		return s.out.WriteLevel(level, p)
+	}
+	if len(event.Message) > log_sample_prefix_len {
+		event.Message = event.Message[:log_sample_prefix_len]
+	}
+	key := sampleKey{level: level, subsystem: event.Subsystem, prefix: event.Message}
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	w, ok := s.windows[key]
+	if !ok {
+		w = &sampleWindow{start: now}
+		s.windows[key] = w
+	} else if now.Sub(w.start) >= s.window {
+		s.closeWindow(key, w)
+		w.start, w.written, w.suppressed = now, 0, 0
+	}
+	if w.written >= s.rate {
+		w.suppressed++
+		return len(p), nil
+	}
+	w.written++
+	return s.out.WriteLevel(level, p)
+}
+
+// closeWindow logs the number of events suppressed during the window, if any
+func (s *LogSampler) closeWindow(key sampleKey, w *sampleWindow) {
+	if w.suppressed == 0 {
+		return
+	}
+	event := s.summary.WithLevel(key.level)
+	if key.subsystem != "" {
+		event = event.Str("subsystem", key.subsystem)
+	}
+	event.Int("suppressed", w.suppressed).Msg(fmt.Sprintf("%d messages suppressed: %s...", w.suppressed, key.prefix))
+}
+
+// Flush logs the suppressed event summaries of all windows which have closed
+func (s *LogSampler) Flush() {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= s.window {
+			s.closeWindow(key, w)
+			delete(s.windows, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestLogSampler emits a burst of identical events and verifies only `rate` of them are written followed by a summary
+func TestLogSampler(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	sampler := NewLogSampler(zerolog.MultiLevelWriter(&buf), 3, time.Minute)
+	sampler.now = func() time.Time { return now }
+	log := zerolog.New(sampler)
+	for i := 0; i < 10; i++ {
+		log.Info().Str("subsystem", "PEER").Msg("unable to connect to peer, retrying")
+	}
+	// a different subsystem is sampled separately
+	log.Info().Str("subsystem", "CRTR").Msg("unable to connect to peer, retrying")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines to be written, got %d: %v", len(lines), lines)
+	}
+	// once the window closes, the summary is written
+	now = now.Add(time.Minute)
+	sampler.Flush()
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines to be written, got %d: %v", len(lines), lines)
+	}
+	if !strings.Contains(lines[4], "7 messages suppressed") || !strings.Contains(lines[4], `"subsystem":"PEER"`) {
+		t.Errorf("unexpected summary line: %s", lines[4])
+	}
+	// a new window allows events again
+	log.Info().Str("subsystem", "PEER").Msg("unable to connect to peer, retrying")
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Errorf("expected 6 lines to be written, got %d: %v", len(lines), lines)
+	}
+}
+
+// TestLogSamplerErrors ensures errors and above are never sampled
+func TestLogSamplerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	sampler := NewLogSampler(zerolog.MultiLevelWriter(&buf), 1, time.Minute)
+	log := zerolog.New(sampler)
+	for i := 0; i < 5; i++ {
+		log.Error().Str("subsystem", "LNDR").Msg("[CRT] unable to start server")
+		log.WithLevel(zerolog.FatalLevel).Str("subsystem", "LNDR").Msg("shutting down")
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 10 {
+		t.Errorf("expected every error to be written, got %d lines: %v", len(lines), lines)
+	}
+}
+
+// TestLogSamplerClose ensures Close stops the periodic flushes and writes the summaries of the windows still open
+func TestLogSamplerClose(t *testing.T) {
+	var buf bytes.Buffer
+	sampler := NewLogSampler(zerolog.MultiLevelWriter(&buf), 1, time.Hour)
+	sampler.FlushEvery(time.Millisecond)
+	log := zerolog.New(sampler)
+	for i := 0; i < 3; i++ {
+		log.Info().Str("subsystem", "PEER").Msg("unable to connect to peer, retrying")
+	}
+	sampler.Close()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "2 messages suppressed") {
+		t.Fatalf("expected the event and its summary, got %v", lines)
+	}
+	sampler.Close()
+	var nilSampler *LogSampler
+	nilSampler.Close()
+}
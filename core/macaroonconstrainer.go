@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"gopkg.in/macaroon.v2"
+)
+
+const (
+	ErrInvalidCapability           = errors.Error("invalid LND capability")
+	ErrPluginMacaroonUnavailable   = errors.Error("plugin macaroons can't be baked before LND is active")
+	default_plugin_macaroon_ttl    = 24 * time.Hour
+	plugin_macaroon_dir_name       = "macaroons"
+	plugin_macaroon_bake_timeout   = 10 * time.Second
+	plugin_macaroon_file_extension = ".macaroon"
+)
+
+// MacaroonConstrainer bakes macaroons restricted to the capabilities a plugin declares and expiring after a while
+type MacaroonConstrainer struct {
+	client lnrpc.LightningClient
+}
+
+// NewMacaroonConstrainer creates a MacaroonConstrainer baking macaroons with the given LND client
+func NewMacaroonConstrainer(client lnrpc.LightningClient) *MacaroonConstrainer {
+	return &MacaroonConstrainer{client: client}
+}
+
+// parseCapabilities turns capabilities of the form entity:action, i.e. "offchain:read", into macaroon permissions
+func parseCapabilities(capabilities []string) ([]*lnrpc.MacaroonPermission, error) {
+	permissions := make([]*lnrpc.MacaroonPermission, 0, len(capabilities))
+	for _, capability := range capabilities {
+		parts := strings.Split(capability, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w %q: expected entity:action", ErrInvalidCapability, capability)
+		}
+		permissions = append(permissions, &lnrpc.MacaroonPermission{Entity: parts[0], Action: parts[1]})
+	}
+	return permissions, nil
+}
+
+// BakeForPlugin bakes a macaroon granting only the given capabilities which expires once ttl has passed, and returns it serialized
+func (c *MacaroonConstrainer) BakeForPlugin(plugin string, capabilities []string, ttl time.Duration) ([]byte, error) {
+	if len(capabilities) == 0 {
+		return nil, fmt.Errorf("%w: plugin %s declares no capabilities", ErrInvalidCapability, plugin)
+	}
+	if ttl < time.Second {
+		return nil, fmt.Errorf("macaroon of plugin %s must be valid for at least a second, got %v", plugin, ttl)
+	}
+	permissions, err := parseCapabilities(capabilities)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), plugin_macaroon_bake_timeout)
+	defer cancel()
+	resp, err := c.client.BakeMacaroon(ctx, &lnrpc.BakeMacaroonRequest{Permissions: permissions})
+	if err != nil {
+		return nil, fmt.Errorf("could not bake macaroon of plugin %s: %v", plugin, err)
+	}
+	raw, err := hex.DecodeString(resp.Macaroon)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode macaroon of plugin %s: %v", plugin, err)
+	}
+	mac := &macaroon.Macaroon{}
+	if err = mac.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("could not decode macaroon of plugin %s: %v", plugin, err)
+	}
+	// BakeMacaroon has no expiry, so the time-before caveat is added to the baked macaroon
+	mac, err = macaroons.AddConstraints(mac, macaroons.TimeoutConstraint(int64(ttl/time.Second)))
+	if err != nil {
+		return nil, fmt.Errorf("could not constrain macaroon of plugin %s: %v", plugin, err)
+	}
+	return mac.MarshalBinary()
+}
+
+// PluginMacaroonDir returns the directory where the macaroons baked for the plugins are written
+func PluginMacaroonDir(cfg *Config) string {
+	return path.Join(cfg.ConduitDir, plugin_macaroon_dir_name)
+}
+
+// pluginMacaroonTTL returns how long the macaroons baked for the plugins are valid
+func pluginMacaroonTTL(cfg *Config) (time.Duration, error) {
+	if cfg.PluginMacaroonTTL == "" {
+		return default_plugin_macaroon_ttl, nil
+	}
+	ttl, err := time.ParseDuration(cfg.PluginMacaroonTTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PluginMacaroonTTL %v: %v", cfg.PluginMacaroonTTL, err)
+	}
+	return ttl, nil
+}
+
+// writePluginMacaroon bakes the macaroon of the plugin and writes it, readable only by the user, to the plugin macaroon directory. It returns the path of the macaroon
+func writePluginMacaroon(cfg *Config, constrainer *MacaroonConstrainer, name string, capabilities []string, ttl time.Duration) (string, error) {
+	mac, err := constrainer.BakeForPlugin(name, capabilities, ttl)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(PluginMacaroonDir(cfg), 0700); err != nil {
+		return "", err
+	}
+	macPath := path.Join(PluginMacaroonDir(cfg), name+plugin_macaroon_file_extension)
+	if err = os.WriteFile(macPath, mac, 0600); err != nil {
+		return "", err
+	}
+	return macPath, nil
+}
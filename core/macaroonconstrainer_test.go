@@ -0,0 +1,142 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/macaroon.v2"
+)
+
+// macaroonExpiry returns the time of the time-before caveat of the serialized macaroon
+func macaroonExpiry(t *testing.T, raw []byte) time.Time {
+	t.Helper()
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, caveat := range mac.Caveats() {
+		if condition := string(caveat.Id); strings.HasPrefix(condition, "time-before ") {
+			expiry, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(condition, "time-before "))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return expiry
+		}
+	}
+	t.Fatalf("macaroon has no time-before caveat: %v", mac.Caveats())
+	return time.Time{}
+}
+
+// TestMacaroonConstrainer ensures the macaroon is baked with the capabilities of the plugin and expires after the ttl
+func TestMacaroonConstrainer(t *testing.T) {
+	client := &fakeLightningClient{}
+	constrainer := NewMacaroonConstrainer(client)
+	ttl := 2 * time.Hour
+	raw, err := constrainer.BakeForPlugin("rebalancer", []string{"offchain:read", "offchain:write"}, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Now().Add(ttl)
+	if expiry := macaroonExpiry(t, raw); expiry.Sub(expected) > time.Second || expected.Sub(expiry) > time.Second {
+		t.Errorf("expected the macaroon to expire around %v, got %v", expected, expiry)
+	}
+	if len(client.bakeReqs) != 1 {
+		t.Fatalf("expected one BakeMacaroon call, got %d", len(client.bakeReqs))
+	}
+	permissions := client.bakeReqs[0].Permissions
+	if len(permissions) != 2 || permissions[0].Entity != "offchain" || permissions[0].Action != "read" || permissions[1].Action != "write" {
+		t.Errorf("unexpected permissions: %v", permissions)
+	}
+
+	if _, err = constrainer.BakeForPlugin("rebalancer", []string{"offchain"}, ttl); !errors.Is(err, ErrInvalidCapability) {
+		t.Errorf("expected an invalid capability error, got %v", err)
+	}
+	if _, err = constrainer.BakeForPlugin("rebalancer", []string{"offchain:read"}, time.Millisecond); err == nil {
+		t.Error("expected an error for a ttl under a second")
+	}
+	if len(client.bakeReqs) != 1 {
+		t.Errorf("expected no macaroon to be baked for invalid requests, got %d calls", len(client.bakeReqs))
+	}
+}
+
+// TestPluginManagerMacaroon ensures plugins declaring LND capabilities are started with the path of their macaroon
+func TestPluginManagerMacaroon(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	cfg := &Config{ConduitDir: t.TempDir(), PluginMacaroonTTL: "1h"}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "rebalancer", RequiresLNDReady: true, LNDCapabilities: []string{"offchain:read"}})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.StopAll()
+	if err = m.Start("rebalancer"); !errors.Is(err, ErrPluginMacaroonUnavailable) {
+		t.Fatalf("expected the plugin not to start before LND is active, got %v", err)
+	}
+	m.SetMacaroonConstrainer(NewMacaroonConstrainer(&fakeLightningClient{}))
+	if err = m.Start("rebalancer"); err != nil {
+		t.Fatal(err)
+	}
+	m.mu.RLock()
+	env := m.processes["rebalancer"].cmd.Env
+	m.mu.RUnlock()
+	var macPath string
+	for _, v := range env {
+		if strings.HasPrefix(v, "CONDUIT_LND_MACAROON=") {
+			macPath = strings.TrimPrefix(v, "CONDUIT_LND_MACAROON=")
+		}
+	}
+	raw, err := os.ReadFile(macPath)
+	if err != nil {
+		t.Fatalf("could not read the plugin macaroon %q: %v", macPath, err)
+	}
+	expected := time.Now().Add(time.Hour)
+	if expiry := macaroonExpiry(t, raw); expiry.Sub(expected) > time.Second || expected.Sub(expiry) > time.Second {
+		t.Errorf("expected the macaroon to expire around %v, got %v", expected, expiry)
+	}
+	if info, _ := os.Stat(macPath); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected the macaroon to be readable only by the user, got %v", info.Mode())
+	}
+}
+
+// TestPluginManagerMacaroonUnlocked ensures the manager can be queried while LND bakes the macaroon of a starting plugin
+func TestPluginManagerMacaroonUnlocked(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	cfg := &Config{ConduitDir: t.TempDir(), PluginMacaroonTTL: "1h"}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "rebalancer", RequiresLNDReady: true, LNDCapabilities: []string{"offchain:read"}})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.StopAll()
+	client := &fakeLightningClient{baking: make(chan struct{})}
+	m.SetMacaroonConstrainer(NewMacaroonConstrainer(client))
+	started := make(chan error)
+	go func() { started <- m.Start("rebalancer") }()
+	<-client.baking
+	status := make(chan PluginStatus)
+	go func() {
+		s, _ := m.Status("rebalancer")
+		status <- s
+	}()
+	select {
+	case s := <-status:
+		if s != PluginStopped {
+			t.Errorf("expected the plugin to be stopped while its macaroon is baked, got %v", s)
+		}
+	case <-time.After(time.Second):
+		client.baking <- struct{}{}
+		<-started
+		t.Fatal("Status blocked while the macaroon was baked")
+	}
+	client.baking <- struct{}{}
+	if err = <-started; err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// Malfunction severities
+const (
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+// Pattern is a known LND log pattern revealing a malfunction, with the hint given to the operator when it's seen
+type Pattern struct {
+	Regex    string
+	Severity string
+	Code     string
+	Remedy   string
+}
+
+// default_malfunction_patterns are the patterns every MalfunctionDetector starts with
+var default_malfunction_patterns = []Pattern{
+	{
+		Regex:    `(?i)unable to add block`,
+		Severity: SeverityError,
+		Code:     "chain_block_rejected",
+		Remedy:   "Check that the chain backend is synced and reachable, the chain state may have to be rescanned",
+	},
+	{
+		Regex:    `(?i)breach retribution`,
+		Severity: SeverityCritical,
+		Code:     "channel_breach",
+		Remedy:   "A peer broadcast a revoked state, keep LND online until the justice transaction confirms",
+	},
+	{
+		Regex:    `(?i)zombie channel`,
+		Severity: SeverityWarning,
+		Code:     "zombie_channel",
+		Remedy:   "The channel's peer has been offline for a long time, consider closing the channel",
+	},
+}
+
+// compiledPattern is a Pattern with its compiled regular expression
+type compiledPattern struct {
+	Pattern
+	regex *regexp.Regexp
+}
+
+// MalfunctionDetector watches the LND output for known malfunction patterns and logs a structured alert for every match
+type MalfunctionDetector struct {
+	sync.RWMutex
+	log      *subLogger
+	patterns []compiledPattern
+	counter  *prometheus.CounterVec
+}
+
+// NewMalfunctionDetector creates a new MalfunctionDetector with the default patterns and registers its counter with the given registerer
+func NewMalfunctionDetector(log *zerolog.Logger, registerer prometheus.Registerer) (*MalfunctionDetector, error) {
+	d := &MalfunctionDetector{
+		log: NewSubLogger(log, "MALF"),
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics_namespace,
+			Subsystem: "lnd",
+			Name:      "malfunctions_total",
+			Help:      "Number of LND log lines matching a known malfunction pattern",
+		}, []string{"code", "severity"}),
+	}
+	for _, p := range default_malfunction_patterns {
+		if err := d.Register(p); err != nil {
+			return nil, err
+		}
+	}
+	if registerer != nil {
+		if err := registerer.Register(d.counter); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Register adds a pattern to the detector
+func (d *MalfunctionDetector) Register(p Pattern) error {
+	switch p.Severity {
+	case SeverityWarning, SeverityError, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q of pattern %s", p.Severity, p.Code)
+	}
+	regex, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return fmt.Errorf("invalid regex of pattern %s: %v", p.Code, err)
+	}
+	d.Lock()
+	defer d.Unlock()
+	d.patterns = append(d.patterns, compiledPattern{Pattern: p, regex: regex})
+	return nil
+}
+
+// ParseLine implements the `LineParser` interface
+func (d *MalfunctionDetector) ParseLine(line string) {
+	d.RLock()
+	defer d.RUnlock()
+	for _, p := range d.patterns {
+		if !p.regex.MatchString(line) {
+			continue
+		}
+		d.counter.WithLabelValues(p.Code, p.Severity).Inc()
+		event := d.log.SubLogger.Warn()
+		if p.Severity != SeverityWarning {
+			event = d.log.SubLogger.Error()
+		}
+		event.Str("severity", p.Severity).Str("code", p.Code).Str("remedy", p.Remedy).Str("line", line).Msg(fmt.Sprintf("LND malfunction detected: %s", p.Code))
+	}
+}
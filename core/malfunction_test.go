@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+)
+
+// TestMalfunctionDetector ensures canned LND log lines matching the default patterns are logged with their code, severity and remedy and counted
+func TestMalfunctionDetector(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	registry := prometheus.NewRegistry()
+	d, err := NewMalfunctionDetector(&log, registry)
+	if err != nil {
+		t.Fatalf("NewMalfunctionDetector returned an error: %v", err)
+	}
+	cases := []struct {
+		line     string
+		code     string
+		severity string
+		level    string
+	}{
+		{
+			line:     "2022-03-01 12:00:00.000 [ERR] CRTR: unable to add block 0000000000000000000a1b2c: checkpoint mismatch",
+			code:     "chain_block_rejected",
+			severity: SeverityError,
+			level:    "error",
+		},
+		{
+			line:     "2022-03-01 12:00:01.000 [INF] BRAR: Breach retribution for ChannelPoint(4f1c...:1) started",
+			code:     "channel_breach",
+			severity: SeverityCritical,
+			level:    "error",
+		},
+		{
+			line:     "2022-03-01 12:00:02.000 [INF] CRTR: Marking zombie channel 712345x1234x1 as a zombie",
+			code:     "zombie_channel",
+			severity: SeverityWarning,
+			level:    "warn",
+		},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		d.ParseLine(c.line)
+		var event map[string]interface{}
+		if err = json.Unmarshal(buf.Bytes(), &event); err != nil {
+			t.Fatalf("could not parse log event for %q: %v", c.line, err)
+		}
+		if event["subsystem"] != "MALF" || event["level"] != c.level || event["code"] != c.code || event["severity"] != c.severity {
+			t.Errorf("unexpected log event for %q: %s", c.line, buf.String())
+		}
+		if remedy, ok := event["remedy"].(string); !ok || remedy == "" {
+			t.Errorf("log event for %q is missing a remedy: %s", c.line, buf.String())
+		}
+		if v := testutil.ToFloat64(d.counter.WithLabelValues(c.code, c.severity)); v != 1 {
+			t.Errorf("expected counter of %s to be 1, got %v", c.code, v)
+		}
+	}
+	buf.Reset()
+	d.ParseLine("2022-03-01 12:00:03.000 [INF] LTND: Waiting for chain backend to finish sync")
+	if buf.Len() != 0 {
+		t.Errorf("expected a healthy line not to be logged, got %s", buf.String())
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "conduit_lnd_malfunctions_total" {
+		t.Errorf("expected the malfunctions counter to be registered, got %v", families)
+	}
+}
+
+// TestMalfunctionDetectorRegister ensures custom patterns are matched and invalid ones are rejected
+func TestMalfunctionDetectorRegister(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	d, err := NewMalfunctionDetector(&log, nil)
+	if err != nil {
+		t.Fatalf("NewMalfunctionDetector returned an error: %v", err)
+	}
+	if err = d.Register(Pattern{Regex: "(", Severity: SeverityError, Code: "broken"}); err == nil {
+		t.Error("expected an invalid regex to be rejected")
+	}
+	if err = d.Register(Pattern{Regex: "x", Severity: "fatal", Code: "broken"}); err == nil {
+		t.Error("expected an invalid severity to be rejected")
+	}
+	if err = d.Register(Pattern{Regex: `database .* corrupt`, Severity: SeverityCritical, Code: "db_corruption", Remedy: "Restore the channel database from a backup"}); err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	d.ParseLine("2022-03-01 12:00:04.000 [CRT] LTND: database file channel.db is corrupt")
+	if !strings.Contains(buf.String(), `"code":"db_corruption"`) {
+		t.Errorf("expected the custom pattern to match, got %s", buf.String())
+	}
+}
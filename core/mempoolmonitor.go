@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_fee_api_url        = "https://mempool.space/api/v1/fees/recommended"
+	default_fee_warn_threshold = 500
+	mempool_poll_interval      = 5 * time.Minute
+	fee_api_timeout            = 30 * time.Second
+)
+
+// FeeRates are the recommended on-chain fee rates in sat/vbyte, in the format of the mempool.space API
+type FeeRates struct {
+	FastestFee  uint64    `json:"fastestFee"`
+	HalfHourFee uint64    `json:"halfHourFee"`
+	HourFee     uint64    `json:"hourFee"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// LNDMemPoolMonitor periodically fetches the recommended fee rates and warns when they're high enough to make closing channels expensive
+type LNDMemPoolMonitor struct {
+	sync.RWMutex
+	url       string
+	client    *http.Client
+	threshold uint64
+	interval  time.Duration
+	log       *subLogger
+	rates     *FeeRates
+}
+
+// NewLNDMemPoolMonitor creates a new LNDMemPoolMonitor using the FeeAPIURL and FeeWarnThreshold parameters
+func NewLNDMemPoolMonitor(cfg *Config, log *zerolog.Logger) *LNDMemPoolMonitor {
+	url := cfg.FeeAPIURL
+	if url == "" {
+		url = default_fee_api_url
+	}
+	threshold := cfg.FeeWarnThreshold
+	if threshold == 0 {
+		threshold = default_fee_warn_threshold
+	}
+	return &LNDMemPoolMonitor{
+		url:       url,
+		client:    &http.Client{Timeout: fee_api_timeout},
+		threshold: threshold,
+		interval:  mempool_poll_interval,
+		log:       NewSubLogger(log, "MPMN"),
+	}
+}
+
+// Run fetches the fee rates right away and then every interval until the context is cancelled
+func (m *LNDMemPoolMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			m.log.SubLogger.Debug().Msg(fmt.Sprintf("could not fetch fee rates: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the fee rates and warns if the fastest one is above the threshold
+func (m *LNDMemPoolMonitor) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %v from %v", resp.Status, m.url)
+	}
+	var rates FeeRates
+	if err = json.NewDecoder(resp.Body).Decode(&rates); err != nil {
+		return fmt.Errorf("could not decode fee rates: %v", err)
+	}
+	rates.UpdatedAt = time.Now()
+	m.Lock()
+	m.rates = &rates
+	m.Unlock()
+	if rates.FastestFee > m.threshold {
+		m.log.SubLogger.Warn().Uint64("fastest_fee", rates.FastestFee).Uint64("half_hour_fee", rates.HalfHourFee).Uint64("hour_fee", rates.HourFee).Msg(fmt.Sprintf("Mempool fees are very high (%d sat/vbyte), closing channels will be expensive", rates.FastestFee))
+	}
+	return nil
+}
+
+// Rates returns the last fetched fee rates, nil if none were fetched yet
+func (m *LNDMemPoolMonitor) Rates() *FeeRates {
+	m.RLock()
+	defer m.RUnlock()
+	return m.rates
+}
+
+// RegisterFeeRates registers the conduit_fee_rates method
+func (s *RPCServer) RegisterFeeRates(monitor *LNDMemPoolMonitor) {
+	s.Register("conduit_fee_rates", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		rates := monitor.Rates()
+		if rates == nil {
+			return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, "fee rates were not fetched yet")
+		}
+		return rates, nil
+	})
+}
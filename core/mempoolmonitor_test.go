@@ -0,0 +1,68 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// newTestMemPoolMonitor returns an LNDMemPoolMonitor querying a fake fee API returning the given fastest fee
+func newTestMemPoolMonitor(t *testing.T, fastestFee uint64, buf *bytes.Buffer) *LNDMemPoolMonitor {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"fastestFee":%d,"halfHourFee":%d,"hourFee":%d,"economyFee":1,"minimumFee":1}`, fastestFee, fastestFee/2, fastestFee/4)
+	}))
+	t.Cleanup(ts.Close)
+	log := zerolog.New(buf)
+	return NewLNDMemPoolMonitor(&Config{FeeAPIURL: ts.URL}, &log)
+}
+
+func TestLNDMemPoolMonitorWarns(t *testing.T) {
+	for _, test := range []struct {
+		fastestFee uint64
+		warn       bool
+	}{
+		{20, false},
+		{500, false},
+		{800, true},
+	} {
+		var buf bytes.Buffer
+		monitor := newTestMemPoolMonitor(t, test.fastestFee, &buf)
+		if err := monitor.Check(context.Background()); err != nil {
+			t.Fatalf("Check returned an error: %v", err)
+		}
+		rates := monitor.Rates()
+		if rates == nil || rates.FastestFee != test.fastestFee || rates.HalfHourFee != test.fastestFee/2 || rates.HourFee != test.fastestFee/4 {
+			t.Fatalf("unexpected rates %+v", rates)
+		}
+		if warned := strings.Contains(buf.String(), `"level":"warn"`); warned != test.warn {
+			t.Errorf("fastest fee %d: expected warning %v, got log %q", test.fastestFee, test.warn, buf.String())
+		}
+	}
+}
+
+func TestFeeRatesRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	var buf bytes.Buffer
+	monitor := newTestMemPoolMonitor(t, 42, &buf)
+	s.RegisterFeeRates(monitor)
+	var rates FeeRates
+	if err := client.Call(context.Background(), "conduit_fee_rates", nil, &rates); err == nil {
+		t.Fatal("expected an error before the first fetch")
+	}
+	if err := monitor.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(context.Background(), "conduit_fee_rates", nil, &rates); err != nil {
+		t.Fatal(err)
+	}
+	if rates.FastestFee != 42 || rates.HourFee != 10 {
+		t.Errorf("unexpected rates %+v", rates)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_memstats_interval = 5 * time.Minute
+	bytes_per_mb              = 1024 * 1024
+)
+
+// MemoryStatsLogger periodically logs the Go runtime memory statistics of Conduit
+type MemoryStatsLogger struct {
+	log        *subLogger
+	interval   time.Duration
+	alloc      prometheus.Gauge
+	totalAlloc prometheus.Gauge
+	sys        prometheus.Gauge
+	gcCount    prometheus.Gauge
+	goroutines prometheus.Gauge
+}
+
+// NewMemoryStatsLogger creates a new MemoryStatsLogger and registers its gauges with the given registerer
+func NewMemoryStatsLogger(cfg *Config, log *zerolog.Logger, registerer prometheus.Registerer) (*MemoryStatsLogger, error) {
+	interval := default_memstats_interval
+	if cfg.MemStatsInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(cfg.MemStatsInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MemStatsInterval %v: %v", cfg.MemStatsInterval, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid MemStatsInterval %v: must be positive", cfg.MemStatsInterval)
+		}
+	}
+	gauge := func(name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metrics_namespace,
+			Subsystem: "memstats",
+			Name:      name,
+			Help:      help,
+		})
+	}
+	m := &MemoryStatsLogger{
+		log:        NewSubLogger(log, "MEMS"),
+		interval:   interval,
+		alloc:      gauge("alloc_mb", "Heap memory currently allocated in MB"),
+		totalAlloc: gauge("total_alloc_mb", "Cumulative heap memory allocated in MB"),
+		sys:        gauge("sys_mb", "Memory obtained from the OS in MB"),
+		gcCount:    gauge("gc_count", "Number of completed GC cycles"),
+		goroutines: gauge("goroutines", "Number of goroutines"),
+	}
+	if registerer != nil {
+		for _, g := range []prometheus.Gauge{m.alloc, m.totalAlloc, m.sys, m.gcCount, m.goroutines} {
+			if err := registerer.Register(g); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// Run logs the memory statistics every interval until the quit channel is closed
+func (m *MemoryStatsLogger) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			m.logStats()
+		}
+	}
+}
+
+// logStats reads the runtime memory statistics, updates the gauges and emits them as a debug event
+func (m *MemoryStatsLogger) logStats() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	alloc := float64(stats.Alloc) / bytes_per_mb
+	totalAlloc := float64(stats.TotalAlloc) / bytes_per_mb
+	sys := float64(stats.Sys) / bytes_per_mb
+	goroutines := runtime.NumGoroutine()
+	m.alloc.Set(alloc)
+	m.totalAlloc.Set(totalAlloc)
+	m.sys.Set(sys)
+	m.gcCount.Set(float64(stats.NumGC))
+	m.goroutines.Set(float64(goroutines))
+	m.log.SubLogger.Debug().
+		Float64("alloc_mb", alloc).
+		Float64("total_alloc_mb", totalAlloc).
+		Float64("sys_mb", sys).
+		Uint32("gc_count", stats.NumGC).
+		Int("goroutines", goroutines).
+		Msg("memory stats")
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// TestMemoryStatsLogger ensures the memory stats are logged at the configured interval with all fields and exposed as gauges
+func TestMemoryStatsLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	registry := prometheus.NewRegistry()
+	m, err := NewMemoryStatsLogger(&Config{MemStatsInterval: "10ms"}, &log, registry)
+	if err != nil {
+		t.Fatalf("NewMemoryStatsLogger returned an error: %v", err)
+	}
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		m.Run(quit)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(quit)
+	<-done
+	line, err := buf.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("no memory stats were logged: %v", err)
+	}
+	var event map[string]interface{}
+	if err = json.Unmarshal(line, &event); err != nil {
+		t.Fatalf("could not parse log event %s: %v", line, err)
+	}
+	if event["level"] != "debug" || event["subsystem"] != "MEMS" {
+		t.Errorf("expected a debug event of subsystem MEMS, got %s", line)
+	}
+	for _, field := range []string{"alloc_mb", "total_alloc_mb", "sys_mb", "gc_count", "goroutines"} {
+		if _, ok := event[field]; !ok {
+			t.Errorf("log event is missing field %s: %s", field, line)
+		}
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %v", err)
+	}
+	if len(families) != 5 {
+		t.Errorf("expected 5 gauges to be registered, got %d", len(families))
+	}
+	for _, family := range families {
+		if family.GetName() == "conduit_memstats_goroutines" && family.GetMetric()[0].GetGauge().GetValue() < 1 {
+			t.Errorf("expected the goroutines gauge to be set, got %v", family.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+}
+
+// TestMemoryStatsLoggerInvalidInterval ensures an invalid interval is rejected
+func TestMemoryStatsLoggerInvalidInterval(t *testing.T) {
+	log := zerolog.Nop()
+	for _, interval := range []string{"soon", "-1m", "0s"} {
+		if _, err := NewMemoryStatsLogger(&Config{MemStatsInterval: interval}, &log, nil); err == nil {
+			t.Errorf("expected interval %q to be rejected", interval)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	ErrKeyNotFound        = errors.Error("key not found in metadata store")
+	metadata_file_name    = "metadata.db"
+	metadata_open_timeout = time.Second
+)
+
+var metadata_bucket = []byte("metadata")
+
+// MetadataStore is a persistent key-value store for Conduit's runtime state
+type MetadataStore struct {
+	db *bolt.DB
+}
+
+// MetadataStorePath returns the path of the metadata store in the Conduit directory
+func MetadataStorePath(cfg *Config) string {
+	return path.Join(cfg.ConduitDir, metadata_file_name)
+}
+
+// OpenMetadataStore opens or creates the metadata store at the given path. Only one process may have the store open at a time
+func OpenMetadataStore(filename string) (*MetadataStore, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: metadata_open_timeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(metadata_bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &MetadataStore{db: db}, nil
+}
+
+// Get returns the value stored under key or ErrKeyNotFound
+func (m *MetadataStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := m.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metadata_bucket).Get([]byte(key))
+		if v == nil {
+			return ErrKeyNotFound
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+// Put stores value under key
+func (m *MetadataStore) Put(key string, value []byte) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metadata_bucket).Put([]byte(key), value)
+	})
+}
+
+// Delete removes key from the store. Deleting a missing key is not an error
+func (m *MetadataStore) Delete(key string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metadata_bucket).Delete([]byte(key))
+	})
+}
+
+// List returns all key-value pairs whose key starts with prefix
+func (m *MetadataStore) List(prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	err := m.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(metadata_bucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			values[string(k)] = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return values, err
+}
+
+// Close closes the store
+func (m *MetadataStore) Close() error {
+	return m.db.Close()
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+const (
+	metrics_namespace = "conduit"
+)
+
+// MetricsServer serves the Prometheus metrics registered by Conduit subsystems
+type MetricsServer struct {
+	Registry   *prometheus.Registry
+	cfg        *Config
+	log        *subLogger
+	httpServer *http.Server
+}
+
+// NewMetricsServer creates a new MetricsServer with an empty registry
+func NewMetricsServer(cfg *Config, log *zerolog.Logger) *MetricsServer {
+	return &MetricsServer{
+		Registry: prometheus.NewRegistry(),
+		cfg:      cfg,
+		log:      NewSubLogger(log, "METR"),
+	}
+}
+
+// Enabled returns whether an address to serve the metrics on has been configured
+func (s *MetricsServer) Enabled() bool {
+	return s.cfg.MetricsListen != ""
+}
+
+// Start listens on the configured address and serves the metrics at /metrics in a goroutine
+func (s *MetricsServer) Start() error {
+	listener, err := net.Listen("tcp", s.cfg.MetricsListen)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.Registry, promhttp.HandlerOpts{}))
+	s.httpServer = &http.Server{Handler: mux}
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.log.SubLogger.Error().Msg(fmt.Sprintf("metrics server stopped: %v", err))
+		}
+	}()
+	s.log.SubLogger.Info().Msg(fmt.Sprintf("Prometheus metrics served on %v/metrics", listener.Addr()))
+	return nil
+}
+
+// Stop gracefully shuts down the metrics server
+func (s *MetricsServer) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(context.Background())
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const (
+	ErrInvalidMnemonicLength   = errors.Error("mnemonic must have 12, 18 or 24 words")
+	ErrUnknownMnemonicWord     = errors.Error("word is not in the BIP-39 English word list")
+	ErrInvalidMnemonicChecksum = errors.Error("invalid mnemonic checksum")
+	max_mnemonic_suggestions   = 5
+	bip39_bits_per_word        = 11
+)
+
+var (
+	//go:embed bip39_english.txt
+	bip39EnglishWordList   string
+	valid_mnemonic_lengths = map[int]bool{12: true, 18: true, 24: true}
+)
+
+// MnemonicValidator checks recovery seeds against the BIP-39 English word list
+type MnemonicValidator struct {
+	words []string
+	index map[string]int
+}
+
+// NewMnemonicValidator creates a new MnemonicValidator from the embedded word list
+func NewMnemonicValidator() *MnemonicValidator {
+	words := strings.Fields(bip39EnglishWordList)
+	index := make(map[string]int, len(words))
+	for i, word := range words {
+		index[word] = i
+	}
+	return &MnemonicValidator{words: words, index: index}
+}
+
+// Validate checks the word count, that every word is in the word list and the BIP-39 checksum of the mnemonic
+func (v *MnemonicValidator) Validate(words []string) error {
+	if !valid_mnemonic_lengths[len(words)] {
+		return fmt.Errorf("%w, got %d", ErrInvalidMnemonicLength, len(words))
+	}
+	// every word encodes 11 bits, the last bit of every 32 is part of the checksum
+	bits := make([]bool, 0, len(words)*bip39_bits_per_word)
+	for i, word := range words {
+		n, ok := v.index[strings.ToLower(word)]
+		if !ok {
+			return fmt.Errorf("word %d %q: %w", i+1, word, ErrUnknownMnemonicWord)
+		}
+		for b := bip39_bits_per_word - 1; b >= 0; b-- {
+			bits = append(bits, n&(1<<b) != 0)
+		}
+	}
+	checksumBits := len(bits) / 33
+	entropy := make([]byte, (len(bits)-checksumBits)/8)
+	for i := range entropy {
+		for b := 0; b < 8; b++ {
+			if bits[i*8+b] {
+				entropy[i] |= 1 << (7 - b)
+			}
+		}
+	}
+	hash := sha256.Sum256(entropy)
+	for i := 0; i < checksumBits; i++ {
+		if bits[len(entropy)*8+i] != (hash[0]&(1<<(7-i)) != 0) {
+			return ErrInvalidMnemonicChecksum
+		}
+	}
+	return nil
+}
+
+// SuggestCorrection returns up to 5 words of the word list closest to the given word by Levenshtein distance
+func (v *MnemonicValidator) SuggestCorrection(word string) []string {
+	word = strings.ToLower(word)
+	distances := make(map[string]int, len(v.words))
+	for _, w := range v.words {
+		distances[w] = levenshtein(word, w)
+	}
+	suggestions := make([]string, len(v.words))
+	copy(suggestions, v.words)
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	return suggestions[:max_mnemonic_suggestions]
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
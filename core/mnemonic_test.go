@@ -0,0 +1,65 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestMnemonicValidator ensures known-good BIP-39 seeds are accepted and known-bad seeds are rejected with the right error
+func TestMnemonicValidator(t *testing.T) {
+	v := NewMnemonicValidator()
+	if len(v.words) != 2048 {
+		t.Fatalf("expected 2048 words in the word list, got %d", len(v.words))
+	}
+	good := []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		"Abandon Abandon Abandon Abandon Abandon Abandon Abandon Abandon Abandon Abandon Abandon About",
+	}
+	for _, seed := range good {
+		if err := v.Validate(strings.Fields(seed)); err != nil {
+			t.Errorf("Validate(%q) returned an error: %v", seed, err)
+		}
+	}
+	bad := []struct {
+		seed string
+		err  error
+	}{
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", ErrInvalidMnemonicChecksum},
+		{"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo", ErrInvalidMnemonicChecksum},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandn about", ErrUnknownMnemonicWord},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", ErrInvalidMnemonicLength},
+		{"", ErrInvalidMnemonicLength},
+	}
+	for _, c := range bad {
+		if err := v.Validate(strings.Fields(c.seed)); !errors.Is(err, c.err) {
+			t.Errorf("Validate(%q) returned %v, expected %v", c.seed, err, c.err)
+		}
+	}
+}
+
+// TestSuggestCorrection ensures the closest words are suggested first
+func TestSuggestCorrection(t *testing.T) {
+	v := NewMnemonicValidator()
+	suggestions := v.SuggestCorrection("abandn")
+	if len(suggestions) != max_mnemonic_suggestions {
+		t.Fatalf("expected %d suggestions, got %v", max_mnemonic_suggestions, suggestions)
+	}
+	if suggestions[0] != "abandon" {
+		t.Errorf("expected abandon to be suggested first, got %v", suggestions)
+	}
+	if suggestions = v.SuggestCorrection("ZOO"); suggestions[0] != "zoo" {
+		t.Errorf("expected zoo to be suggested first, got %v", suggestions)
+	}
+	for _, c := range []struct {
+		a, b     string
+		distance int
+	}{{"", "abc", 3}, {"kitten", "sitting", 3}, {"same", "same", 0}} {
+		if d := levenshtein(c.a, c.b); d != c.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, expected %d", c.a, c.b, d, c.distance)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc"
+	"gopkg.in/macaroon.v2"
+)
+
+// fakeStream is a server stream returning the given messages followed by io.EOF
+type fakeStream[T any] struct {
+	grpc.ClientStream
+	msgs []*T
+}
+
+func (s *fakeStream[T]) Recv() (*T, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+// fakeLightningClient is a `lnrpc.LightningClient` whose methods return canned responses. Calling a method without a canned response panics
+type fakeLightningClient struct {
+	lnrpc.LightningClient
+	// the lock guards the payments, which are added while the recorder lists them
+	sync.Mutex
+	info     *lnrpc.GetInfoResponse
+	channels []*lnrpc.Channel
+	// channelsErr is returned by ListChannels if set, as LND does when it's shutting down
+	channelsErr error
+	peers       []*lnrpc.Peer
+	fees        []*lnrpc.ChannelFeeReport
+	graph       *lnrpc.ChannelGraph
+	forwards    []*lnrpc.ForwardingEvent
+	payments    []*lnrpc.Payment
+	// latest receives a value every time the latest payment is requested, if set
+	latest    chan struct{}
+	updates   []*lnrpc.ChannelEventUpdate
+	txs       []*lnrpc.Transaction
+	connected []string
+	bakeReqs  []*lnrpc.BakeMacaroonRequest
+	// baking, if set, receives a value once a macaroon is being baked and is then received from before it's baked, as LND taking a
+	// while to bake it
+	baking chan struct{}
+}
+
+func (f *fakeLightningClient) GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest, opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error) {
+	return f.info, nil
+}
+
+func (f *fakeLightningClient) ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
+	if f.channelsErr != nil {
+		return nil, f.channelsErr
+	}
+	return &lnrpc.ListChannelsResponse{Channels: f.channels}, nil
+}
+
+func (f *fakeLightningClient) ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest, opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error) {
+	return &lnrpc.ListPeersResponse{Peers: f.peers}, nil
+}
+
+func (f *fakeLightningClient) FeeReport(ctx context.Context, in *lnrpc.FeeReportRequest, opts ...grpc.CallOption) (*lnrpc.FeeReportResponse, error) {
+	return &lnrpc.FeeReportResponse{ChannelFees: f.fees}, nil
+}
+
+func (f *fakeLightningClient) DescribeGraph(ctx context.Context, in *lnrpc.ChannelGraphRequest, opts ...grpc.CallOption) (*lnrpc.ChannelGraph, error) {
+	return f.graph, nil
+}
+
+// ForwardingHistory serves the forwards between the start and end times of the request, honoring the index offset and the maximum
+// number of events
+func (f *fakeLightningClient) ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest, opts ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error) {
+	var events []*lnrpc.ForwardingEvent
+	for _, event := range f.forwards {
+		if event.Timestamp >= in.StartTime && event.Timestamp <= in.EndTime {
+			events = append(events, event)
+		}
+	}
+	start := int(in.IndexOffset)
+	if start > len(events) {
+		start = len(events)
+	}
+	end := start + int(in.NumMaxEvents)
+	if end > len(events) {
+		end = len(events)
+	}
+	return &lnrpc.ForwardingHistoryResponse{ForwardingEvents: events[start:end], LastOffsetIndex: uint32(end)}, nil
+}
+
+// addForwards appends n forwarding events, one second apart
+func (f *fakeLightningClient) addForwards(n int) {
+	for i := 0; i < n; i++ {
+		index := uint64(len(f.forwards))
+		f.forwards = append(f.forwards, &lnrpc.ForwardingEvent{
+			TimestampNs: uint64(time.Unix(1650000000+int64(index), 0).UnixNano()),
+			ChanIdIn:    100 + index,
+			ChanIdOut:   200 + index,
+			AmtInMsat:   1001000 + index,
+			AmtOutMsat:  1000000,
+			FeeMsat:     1000 + index,
+		})
+	}
+}
+
+// ListPayments serves the payments after the index offset, or the latest payment when reversed
+func (f *fakeLightningClient) ListPayments(ctx context.Context, in *lnrpc.ListPaymentsRequest, opts ...grpc.CallOption) (*lnrpc.ListPaymentsResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	resp := &lnrpc.ListPaymentsResponse{}
+	if in.Reversed {
+		if f.latest != nil {
+			f.latest <- struct{}{}
+		}
+		if len(f.payments) > 0 {
+			resp.Payments = f.payments[len(f.payments)-1:]
+		}
+		return resp, nil
+	}
+	for _, payment := range f.payments {
+		if payment.PaymentIndex > in.IndexOffset && uint64(len(resp.Payments)) < in.MaxPayments {
+			resp.Payments = append(resp.Payments, payment)
+			resp.LastIndexOffset = payment.PaymentIndex
+		}
+	}
+	return resp, nil
+}
+
+// addPayment appends a payment to the payments database
+func (f *fakeLightningClient) addPayment(payment *lnrpc.Payment) {
+	f.Lock()
+	defer f.Unlock()
+	payment.PaymentIndex = uint64(len(f.payments) + 1)
+	f.payments = append(f.payments, payment)
+}
+
+func (f *fakeLightningClient) SubscribeChannelEvents(ctx context.Context, in *lnrpc.ChannelEventSubscription, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeChannelEventsClient, error) {
+	return &fakeStream[lnrpc.ChannelEventUpdate]{msgs: f.updates}, nil
+}
+
+func (f *fakeLightningClient) SubscribeTransactions(ctx context.Context, in *lnrpc.GetTransactionsRequest, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeTransactionsClient, error) {
+	return &fakeStream[lnrpc.Transaction]{msgs: f.txs}, nil
+}
+
+// ConnectPeer records the peers LND is asked to connect to
+func (f *fakeLightningClient) ConnectPeer(ctx context.Context, in *lnrpc.ConnectPeerRequest, opts ...grpc.CallOption) (*lnrpc.ConnectPeerResponse, error) {
+	f.connected = append(f.connected, fmt.Sprintf("%s@%s", in.Addr.Pubkey, in.Addr.Host))
+	return &lnrpc.ConnectPeerResponse{}, nil
+}
+
+// BakeMacaroon bakes unconstrained macaroons and records the requests
+func (f *fakeLightningClient) BakeMacaroon(ctx context.Context, in *lnrpc.BakeMacaroonRequest, opts ...grpc.CallOption) (*lnrpc.BakeMacaroonResponse, error) {
+	if f.baking != nil {
+		f.baking <- struct{}{}
+		<-f.baking
+	}
+	f.bakeReqs = append(f.bakeReqs, in)
+	mac, err := macaroon.New([]byte("root key"), []byte("0"), "lnd", macaroon.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := mac.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &lnrpc.BakeMacaroonResponse{Macaroon: hex.EncodeToString(raw)}, nil
+}
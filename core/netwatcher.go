@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const default_interface_scan_interval = 5 * time.Minute
+
+// interfaceIPs returns the sorted IPs of the network interfaces which may end up in the TLS certificate, i.e. without loopback and link-local addresses
+func interfaceIPs(addrs []net.Addr) []string {
+	var ips []string
+	for _, addr := range addrs {
+		var ip net.IP
+		switch a := addr.(type) {
+		case *net.IPNet:
+			ip = a.IP
+		case *net.IPAddr:
+			ip = a.IP
+		}
+		if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ip.String())
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// LNDNetworkInterfaceWatcher periodically scans the network interfaces and, when their addresses change, restarts LND to refresh its TLS certificate if
+// LndTLSAutoRefresh is set. LND has no signal to refresh its certificate and only regenerates it on startup
+type LNDNetworkInterfaceWatcher struct {
+	log         *subLogger
+	pinning     *TLSPinning
+	certPath    string
+	interval    time.Duration
+	autoRefresh bool
+	addrs       func() ([]net.Addr, error)
+	restart     func() error
+	known       []string
+	scanned     bool
+}
+
+// NewLNDNetworkInterfaceWatcher creates a new LNDNetworkInterfaceWatcher unpinning LND's certificate from pinning when it's refreshed
+func NewLNDNetworkInterfaceWatcher(cfg *Config, pinning *TLSPinning, log *zerolog.Logger) *LNDNetworkInterfaceWatcher {
+	return &LNDNetworkInterfaceWatcher{
+		log:         NewSubLogger(log, "NETW"),
+		pinning:     pinning,
+		certPath:    lndTLSCertPath(cfg),
+		interval:    default_interface_scan_interval,
+		autoRefresh: cfg.LndTLSAutoRefresh,
+		addrs:       net.InterfaceAddrs,
+		restart:     RestartLND,
+	}
+}
+
+// Run scans the interfaces right away and then every interval until the context is cancelled
+func (w *LNDNetworkInterfaceWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(); err != nil {
+			w.log.SubLogger.Warn().Msg(err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check scans the interfaces and reports whether their addresses changed since the previous scan. The first scan only records the addresses
+func (w *LNDNetworkInterfaceWatcher) Check() (bool, error) {
+	addrs, err := w.addrs()
+	if err != nil {
+		return false, fmt.Errorf("could not list network interfaces: %v", err)
+	}
+	ips := interfaceIPs(addrs)
+	previous := w.known
+	w.known = ips
+	if !w.scanned {
+		w.scanned = true
+		return false, nil
+	}
+	if strings.Join(previous, ",") == strings.Join(ips, ",") {
+		return false, nil
+	}
+	w.log.SubLogger.Info().Strs("previous", previous).Strs("current", ips).Msg("Network interface addresses changed")
+	if !w.autoRefresh {
+		w.log.SubLogger.Warn().Msg("LND's TLS certificate may not cover the new addresses. Set LndTLSAutoRefresh and restart LND to refresh it")
+		return true, nil
+	}
+	if err = w.refreshTLSCert(); err != nil {
+		return true, fmt.Errorf("could not refresh LND's TLS certificate: %v", err)
+	}
+	w.log.SubLogger.Info().Msg("Restarted LND to refresh its TLS certificate")
+	return true, nil
+}
+
+// refreshTLSCert restarts LND, which regenerates its certificate on startup, and unpins the certificate so that the regenerated one is trusted
+func (w *LNDNetworkInterfaceWatcher) refreshTLSCert() error {
+	if err := w.restart(); err != nil {
+		return err
+	}
+	return w.pinning.Unpin(w.certPath)
+}
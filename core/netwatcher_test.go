@@ -0,0 +1,132 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// newTestInterfaceWatcher returns a watcher whose interfaces have the addresses pointed to by current, whose restarts of LND are counted in
+// restarts and whose pinned certificate is stored in store
+func newTestInterfaceWatcher(t *testing.T, autoRefresh bool, current *[]string, restarts *int, store *MetadataStore, buf *bytes.Buffer) *LNDNetworkInterfaceWatcher {
+	log := zerolog.New(buf)
+	cfg := &Config{LndTLSAutoRefresh: autoRefresh, LndTLSCertPath: path.Join(t.TempDir(), "tls.cert")}
+	w := NewLNDNetworkInterfaceWatcher(cfg, NewTLSPinning(store, &log), &log)
+	w.addrs = func() ([]net.Addr, error) {
+		var addrs []net.Addr
+		for _, cidr := range *current {
+			ip, ipNet, _ := net.ParseCIDR(cidr)
+			addrs = append(addrs, &net.IPNet{IP: ip, Mask: ipNet.Mask})
+		}
+		return addrs, nil
+	}
+	w.restart = func() error {
+		*restarts++
+		return nil
+	}
+	return w
+}
+
+// openTestMetadataStore opens a MetadataStore in a temporary directory
+func openTestMetadataStore(t *testing.T) *MetadataStore {
+	t.Helper()
+	store, err := OpenMetadataStore(path.Join(t.TempDir(), metadata_file_name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// TestLNDNetworkInterfaceWatcher ensures LND is restarted and its certificate unpinned when the addresses change, but not on the first scan nor
+// when only loopback and link-local addresses change
+func TestLNDNetworkInterfaceWatcher(t *testing.T) {
+	current := []string{"127.0.0.1/8", "10.0.0.5/24", "fe80::1/64"}
+	var restarts int
+	var buf bytes.Buffer
+	store := openTestMetadataStore(t)
+	w := newTestInterfaceWatcher(t, true, &current, &restarts, store, &buf)
+	if err := store.Put(tls_pin_metadata_key+w.certPath, []byte("fingerprint")); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := w.Check(); err != nil || changed {
+		t.Fatalf("expected the first scan to only record the addresses, got %v, %v", changed, err)
+	}
+	current = []string{"127.0.0.1/8", "10.0.0.5/24", "fe80::2/64"}
+	if changed, _ := w.Check(); changed || restarts != 0 {
+		t.Errorf("expected a link-local change to be ignored, got %v and %d restarts", changed, restarts)
+	}
+	current = []string{"127.0.0.1/8", "10.0.0.9/24"}
+	if changed, err := w.Check(); err != nil || !changed {
+		t.Fatalf("expected the new IP to be detected, got %v, %v", changed, err)
+	}
+	if restarts != 1 {
+		t.Errorf("expected LND to be restarted once, got %d restarts", restarts)
+	}
+	if _, err := store.Get(tls_pin_metadata_key + w.certPath); err != ErrKeyNotFound {
+		t.Errorf("expected the certificate to be unpinned, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `"previous":["10.0.0.5"],"current":["10.0.0.9"]`) {
+		t.Errorf("expected the change to be logged, got %s", buf.String())
+	}
+	if changed, _ := w.Check(); changed || restarts != 1 {
+		t.Errorf("expected unchanged addresses not to restart LND, got %d restarts", restarts)
+	}
+}
+
+// TestLNDNetworkInterfaceWatcherNoAutoRefresh ensures LND isn't restarted without LndTLSAutoRefresh
+func TestLNDNetworkInterfaceWatcherNoAutoRefresh(t *testing.T) {
+	current := []string{"10.0.0.5/24"}
+	var restarts int
+	var buf bytes.Buffer
+	w := newTestInterfaceWatcher(t, false, &current, &restarts, openTestMetadataStore(t), &buf)
+	w.Check()
+	current = []string{"10.0.0.9/24"}
+	if changed, _ := w.Check(); !changed || restarts != 0 || !strings.Contains(buf.String(), "LndTLSAutoRefresh") {
+		t.Errorf("expected the change to be logged without restarting LND, got %d restarts: %s", restarts, buf.String())
+	}
+}
+
+// TestLNDNetworkInterfaceWatcherRestartsLND ensures the running LND process is stopped for startLnd to start it again, regenerating its certificate
+func TestLNDNetworkInterfaceWatcherRestartsLND(t *testing.T) {
+	current := []string{"10.0.0.5/24"}
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	w := NewLNDNetworkInterfaceWatcher(&Config{LndTLSAutoRefresh: true}, NewTLSPinning(openTestMetadataStore(t), &log), &log)
+	w.addrs = func() ([]net.Addr, error) {
+		ip, ipNet, _ := net.ParseCIDR(current[0])
+		return []net.Addr{&net.IPNet{IP: ip, Mask: ipNet.Mask}}, nil
+	}
+	// the fake plugin binary stands for LND, running until interrupted
+	cmd := exec.Command(os.Args[0], "-test.run=TestFakePlugin")
+	cmd.Env = append(os.Environ(), "CONDUIT_FAKE_PLUGIN=run")
+	if err := startLndProcess(cmd); err != nil {
+		t.Fatalf("startLndProcess returned an error: %v", err)
+	}
+	w.Check()
+	current = []string{"10.0.0.9/24"}
+	if changed, err := w.Check(); !changed || err != nil {
+		t.Fatalf("expected LND to be restarted, got %v, %v", changed, err)
+	}
+	done := make(chan struct{})
+	go func() {
+		waitLndProcess(cmd)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("expected LND to be stopped")
+	}
+	if !lndRestartRequested() {
+		t.Error("expected LND's exit to be reported as a restart")
+	}
+}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	payment_events_file_name = "payments.csv"
+	payment_page_size        = 100
+	payment_poll_time        = 1 * time.Minute
+)
+
+// payment_events_header is the header row of the payments CSV
+var payment_events_header = []string{"timestamp", "payment_hash", "amount_msat", "fee_msat", "status", "failure_reason", "num_hops"}
+
+// PaymentEvent is a single row of the payments CSV, recorded every time a payment changes status
+type PaymentEvent struct {
+	Timestamp     time.Time
+	PaymentHash   string
+	AmountMsat    int64
+	FeeMsat       int64
+	Status        string
+	FailureReason string
+	NumHops       int
+}
+
+// PaymentEventRecorder appends the outcome of every outgoing payment to an append-only CSV file.
+// LND 0.14 has no payment subscription, so outgoing HTLC events (and a slow ticker, for payments failing before any HTLC is sent) trigger a scan of the payments database
+type PaymentEventRecorder struct {
+	client   lnrpc.LightningClient
+	router   routerrpc.RouterClient
+	filename string
+	log      *subLogger
+	now      func() time.Time
+	interval time.Duration
+	// offset is the highest payment index scanned so far
+	offset uint64
+	// inFlight maps the index of every payment still in flight to its hash
+	inFlight map[uint64]string
+}
+
+// PaymentEventsPath returns the path of the payments CSV in the conduit directory
+func PaymentEventsPath(config *Config) string {
+	return path.Join(config.ConduitDir, payment_events_file_name)
+}
+
+// NewPaymentEventRecorder creates a PaymentEventRecorder writing to the payments CSV of the conduit directory
+func NewPaymentEventRecorder(client lnrpc.LightningClient, router routerrpc.RouterClient, config *Config, log *zerolog.Logger) *PaymentEventRecorder {
+	return &PaymentEventRecorder{
+		client:   client,
+		router:   router,
+		filename: PaymentEventsPath(config),
+		log:      NewSubLogger(log, "PYEV"),
+		now:      time.Now,
+		interval: payment_poll_time,
+		inFlight: make(map[uint64]string),
+	}
+}
+
+// Run records payment outcomes until the context is cancelled. Payments made before Run was called are not recorded
+func (r *PaymentEventRecorder) Run(ctx context.Context) error {
+	last, err := r.client.ListPayments(ctx, &lnrpc.ListPaymentsRequest{IncludeIncomplete: true, Reversed: true, MaxPayments: 1})
+	if err != nil {
+		return err
+	}
+	if len(last.Payments) > 0 {
+		r.offset = last.Payments[0].PaymentIndex
+	}
+	stream, err := r.router.SubscribeHtlcEvents(ctx, &routerrpc.SubscribeHtlcEventsRequest{})
+	if err != nil {
+		return err
+	}
+	trigger := make(chan struct{}, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() == nil {
+					r.log.SubLogger.Warn().Msg(fmt.Sprintf("HTLC event stream ended: %v", err))
+				}
+				return
+			}
+			if event.EventType != routerrpc.HtlcEvent_SEND {
+				continue
+			}
+			select {
+			case trigger <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-trigger:
+		case <-ticker.C:
+		}
+		if err := r.scan(ctx); err != nil && ctx.Err() == nil {
+			r.log.SubLogger.Error().Msg(fmt.Sprintf("could not scan payments: %v", err))
+		}
+	}
+}
+
+// scan records the status of every new payment and of every payment which was in flight during the previous scan
+func (r *PaymentEventRecorder) scan(ctx context.Context) error {
+	from := r.offset
+	for index := range r.inFlight {
+		if index-1 < from {
+			from = index - 1
+		}
+	}
+	for {
+		resp, err := r.client.ListPayments(ctx, &lnrpc.ListPaymentsRequest{IncludeIncomplete: true, IndexOffset: from, MaxPayments: payment_page_size})
+		if err != nil {
+			return err
+		}
+		for _, payment := range resp.Payments {
+			if err = r.record(payment); err != nil {
+				return err
+			}
+		}
+		if len(resp.Payments) < payment_page_size {
+			return nil
+		}
+		from = resp.LastIndexOffset
+	}
+}
+
+// record appends a row for the payment if it's new or if its status changed since the last scan
+func (r *PaymentEventRecorder) record(payment *lnrpc.Payment) error {
+	_, wasInFlight := r.inFlight[payment.PaymentIndex]
+	isNew := payment.PaymentIndex > r.offset
+	if !isNew && (!wasInFlight || payment.Status == lnrpc.Payment_IN_FLIGHT) {
+		return nil
+	}
+	event := paymentEventFromPayment(payment, r.now())
+	if err := appendCSVRow(r.filename, payment_events_header, event.row()); err != nil {
+		return err
+	}
+	if isNew {
+		r.offset = payment.PaymentIndex
+	}
+	if payment.Status == lnrpc.Payment_IN_FLIGHT {
+		r.inFlight[payment.PaymentIndex] = payment.PaymentHash
+	} else {
+		delete(r.inFlight, payment.PaymentIndex)
+	}
+	return nil
+}
+
+// paymentEventFromPayment extracts the fields of a PaymentEvent from a payment
+func paymentEventFromPayment(payment *lnrpc.Payment, timestamp time.Time) *PaymentEvent {
+	event := &PaymentEvent{
+		Timestamp:   timestamp,
+		PaymentHash: payment.PaymentHash,
+		AmountMsat:  payment.ValueMsat,
+		FeeMsat:     payment.FeeMsat,
+		Status:      payment.Status.String(),
+	}
+	if payment.Status == lnrpc.Payment_FAILED {
+		event.FailureReason = payment.FailureReason.String()
+	}
+	// the number of hops of the settled attempt, or of the last attempt if none settled
+	for _, htlc := range payment.Htlcs {
+		if htlc.Route == nil {
+			continue
+		}
+		event.NumHops = len(htlc.Route.Hops)
+		if htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+			break
+		}
+	}
+	return event
+}
+
+// row returns the CSV row of the event
+func (e *PaymentEvent) row() []string {
+	return []string{
+		e.Timestamp.UTC().Format(time.RFC3339),
+		e.PaymentHash,
+		strconv.FormatInt(e.AmountMsat, 10),
+		strconv.FormatInt(e.FeeMsat, 10),
+		e.Status,
+		e.FailureReason,
+		strconv.Itoa(e.NumHops),
+	}
+}
+
+// ReadPaymentEvents reads the payments CSV and returns the events recorded at or after since. A missing file has no events
+func ReadPaymentEvents(filename string, since time.Time) ([]*PaymentEvent, error) {
+	rows, err := readCSVRows(filename, len(payment_events_header))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var events []*PaymentEvent
+	for i, row := range rows {
+		event := &PaymentEvent{PaymentHash: row[1], Status: row[4], FailureReason: row[5]}
+		if event.Timestamp, err = time.Parse(time.RFC3339, row[0]); err != nil {
+			return nil, fmt.Errorf("row %d: invalid timestamp %v", i+1, row[0])
+		}
+		if event.Timestamp.Before(since) {
+			continue
+		}
+		if event.AmountMsat, err = strconv.ParseInt(row[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid amount_msat %v", i+1, row[2])
+		}
+		if event.FeeMsat, err = strconv.ParseInt(row[3], 10, 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid fee_msat %v", i+1, row[3])
+		}
+		if event.NumHops, err = strconv.Atoi(row[6]); err != nil {
+			return nil, fmt.Errorf("row %d: invalid num_hops %v", i+1, row[6])
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// fakeRouterClient streams the HTLC events sent on its channel
+type fakeRouterClient struct {
+	routerrpc.RouterClient
+	events chan *routerrpc.HtlcEvent
+}
+
+func (f *fakeRouterClient) SubscribeHtlcEvents(ctx context.Context, in *routerrpc.SubscribeHtlcEventsRequest, opts ...grpc.CallOption) (routerrpc.Router_SubscribeHtlcEventsClient, error) {
+	return &fakeHtlcEventStream{events: f.events}, nil
+}
+
+type fakeHtlcEventStream struct {
+	grpc.ClientStream
+	events chan *routerrpc.HtlcEvent
+}
+
+func (s *fakeHtlcEventStream) Recv() (*routerrpc.HtlcEvent, error) {
+	event, ok := <-s.events
+	if !ok {
+		return nil, io.EOF
+	}
+	return event, nil
+}
+
+// waitForPaymentEvents polls the payments CSV until it contains n events
+func waitForPaymentEvents(t *testing.T, filename string, n int) []*PaymentEvent {
+	deadline := time.Now().Add(time.Second)
+	for {
+		events, _ := ReadPaymentEvents(filename, time.Time{})
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPaymentEventRecorder ensures new payments and status changes are recorded once each and that older payments are skipped
+func TestPaymentEventRecorder(t *testing.T) {
+	client := &fakeLightningClient{latest: make(chan struct{}, 1)}
+	client.addPayment(&lnrpc.Payment{PaymentHash: "old", Status: lnrpc.Payment_SUCCEEDED})
+	router := &fakeRouterClient{events: make(chan *routerrpc.HtlcEvent)}
+	log := zerolog.Nop()
+	recorder := NewPaymentEventRecorder(client, router, &Config{ConduitDir: t.TempDir()}, &log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- recorder.Run(ctx) }()
+	// wait for the recorder to skip the existing payments
+	<-client.latest
+
+	route := &lnrpc.Route{Hops: []*lnrpc.Hop{{}, {}, {}}}
+	inFlight := &lnrpc.Payment{PaymentHash: "a", ValueMsat: 1000000, Status: lnrpc.Payment_IN_FLIGHT, Htlcs: []*lnrpc.HTLCAttempt{{Route: route}}}
+	client.addPayment(inFlight)
+	client.addPayment(&lnrpc.Payment{PaymentHash: "b", ValueMsat: 5000, Status: lnrpc.Payment_FAILED, FailureReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE})
+	// receives never trigger a scan
+	router.events <- &routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_RECEIVE}
+	router.events <- &routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_SEND}
+	events := waitForPaymentEvents(t, recorder.filename, 2)
+
+	client.Lock()
+	inFlight.Status, inFlight.FeeMsat = lnrpc.Payment_SUCCEEDED, 1500
+	inFlight.Htlcs[0].Status = lnrpc.HTLCAttempt_SUCCEEDED
+	client.Unlock()
+	router.events <- &routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_SEND}
+	events = waitForPaymentEvents(t, recorder.filename, 3)
+	// another scan must not record anything new
+	router.events <- &routerrpc.HtlcEvent{EventType: routerrpc.HtlcEvent_SEND}
+	close(router.events)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	events = waitForPaymentEvents(t, recorder.filename, 3)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 payment events, got %d: %+v", len(events), events)
+	}
+	if e := events[0]; e.PaymentHash != "a" || e.Status != "IN_FLIGHT" || e.NumHops != 3 {
+		t.Errorf("unexpected in flight event: %+v", e)
+	}
+	if e := events[1]; e.PaymentHash != "b" || e.Status != "FAILED" || e.FailureReason != "FAILURE_REASON_NO_ROUTE" {
+		t.Errorf("unexpected failed event: %+v", e)
+	}
+	if e := events[2]; e.PaymentHash != "a" || e.Status != "SUCCEEDED" || e.FeeMsat != 1500 || e.AmountMsat != 1000000 {
+		t.Errorf("unexpected succeeded event: %+v", e)
+	}
+}
+
+// TestReadPaymentEventsMissingFile ensures a node which hasn't recorded any payment yet has no events
+func TestReadPaymentEventsMissingFile(t *testing.T) {
+	events, err := ReadPaymentEvents(PaymentEventsPath(&Config{ConduitDir: t.TempDir()}), time.Time{})
+	if err != nil || len(events) != 0 {
+		t.Errorf("expected no events and no error, got %+v: %v", events, err)
+	}
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	peer_uptime_key_prefix      = "peer_uptime/"
+	peer_score_poll_interval    = 10 * time.Minute
+	peer_score_period           = 30 * 24 * time.Hour
+	peer_score_ping_samples     = 144
+	peer_score_ping_percentile  = 0.9
+	peer_score_full_uptime_days = 30
+	peer_score_volume_weight    = 40
+	peer_score_uptime_weight    = 30
+	peer_score_latency_weight   = 15
+	peer_score_balance_weight   = 15
+)
+
+// PeerScore is the contribution of a connected peer to the routing of the node, scored from 0 to 100
+type PeerScore struct {
+	Pubkey           string  `json:"pubkey"`
+	VolumeMsat       uint64  `json:"volume_msat"`
+	DaysConnected    float64 `json:"days_connected"`
+	PingP90Micros    int64   `json:"ping_p90_us"`
+	InboundForwards  int     `json:"inbound_forwards"`
+	OutboundForwards int     `json:"outbound_forwards"`
+	// InboundShare is the ratio of inbound to outbound forwards, as the share of the forwards entering through the peer so that it stays bounded
+	InboundShare float64 `json:"inbound_share"`
+	Score        float64 `json:"score"`
+}
+
+// peerUptime is how long a peer has been connected over the runs of Conduit, as stored in the MetadataStore
+type peerUptime struct {
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	ConnectedSeconds int64     `json:"connected_seconds"`
+}
+
+// peerSample is what is known of a connected peer when scoring it
+type peerSample struct {
+	pubkey        string
+	daysConnected float64
+	pings         []int64
+}
+
+// LNDPeerScorer periodically scores the connected peers from their uptime, kept in the MetadataStore, their ping latency and the forwards through their channels
+type LNDPeerScorer struct {
+	sync.RWMutex
+	store    *MetadataStore
+	client   lnrpc.LightningClient
+	interval time.Duration
+	period   time.Duration
+	now      func() time.Time
+	log      *subLogger
+	// pings are the last ping times of the peers, in microseconds
+	pings  map[string][]int64
+	scores []PeerScore
+}
+
+// NewLNDPeerScorer creates a new LNDPeerScorer keeping the uptime of the peers in the given store
+func NewLNDPeerScorer(store *MetadataStore, log *zerolog.Logger) *LNDPeerScorer {
+	return &LNDPeerScorer{
+		store:    store,
+		interval: peer_score_poll_interval,
+		period:   peer_score_period,
+		now:      time.Now,
+		log:      NewSubLogger(log, "PSCR"),
+		pings:    make(map[string][]int64),
+	}
+}
+
+// SetClient sets the LND client used to list the peers and the forwards, once LND is active
+func (p *LNDPeerScorer) SetClient(client lnrpc.LightningClient) {
+	p.Lock()
+	defer p.Unlock()
+	p.client = client
+}
+
+// Run scores the peers right away and then every interval until the context is cancelled
+func (p *LNDPeerScorer) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Check(ctx); err != nil && err != ErrLNDNotActive && ctx.Err() == nil {
+			p.log.SubLogger.Debug().Msg(fmt.Sprintf("could not score peers: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check updates the uptime and the ping times of the connected peers and scores them
+func (p *LNDPeerScorer) Check(ctx context.Context) error {
+	p.RLock()
+	client := p.client
+	p.RUnlock()
+	if client == nil {
+		return ErrLNDNotActive
+	}
+	peers, err := client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		return err
+	}
+	channels, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return err
+	}
+	now := p.now()
+	events, err := p.forwards(ctx, client, now)
+	if err != nil {
+		return err
+	}
+	samples := make([]peerSample, 0, len(peers.Peers))
+	p.Lock()
+	for _, peer := range peers.Peers {
+		uptime, err := p.recordUptime(peer.PubKey, now)
+		if err != nil {
+			p.Unlock()
+			return err
+		}
+		// LND reports 0 until the first pong
+		if peer.PingTime > 0 {
+			pings := append(p.pings[peer.PubKey], peer.PingTime)
+			if len(pings) > peer_score_ping_samples {
+				pings = pings[len(pings)-peer_score_ping_samples:]
+			}
+			p.pings[peer.PubKey] = pings
+		}
+		samples = append(samples, peerSample{
+			pubkey:        peer.PubKey,
+			daysConnected: float64(uptime.ConnectedSeconds) / (24 * 60 * 60),
+			pings:         p.pings[peer.PubKey],
+		})
+	}
+	p.scores = scoreConnectedPeers(samples, channels.Channels, events)
+	p.Unlock()
+	return nil
+}
+
+// forwards returns the forwards of the scoring period
+func (p *LNDPeerScorer) forwards(ctx context.Context, client lnrpc.LightningClient, now time.Time) ([]*lnrpc.ForwardingEvent, error) {
+	var events []*lnrpc.ForwardingEvent
+	var offset uint32
+	for {
+		resp, err := client.ForwardingHistory(ctx, &lnrpc.ForwardingHistoryRequest{
+			StartTime:    uint64(now.Add(-p.period).Unix()),
+			EndTime:      uint64(now.Unix()),
+			IndexOffset:  offset,
+			NumMaxEvents: forwarding_page_size,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, resp.ForwardingEvents...)
+		if len(resp.ForwardingEvents) < forwarding_page_size {
+			return events, nil
+		}
+		offset = resp.LastOffsetIndex
+	}
+}
+
+// recordUptime adds the time since the peer was last seen to its connected time, unless it was disconnected in between, and stores it
+func (p *LNDPeerScorer) recordUptime(pubkey string, now time.Time) (peerUptime, error) {
+	var uptime peerUptime
+	raw, err := p.store.Get(peer_uptime_key_prefix + pubkey)
+	if err == nil {
+		if err = json.Unmarshal(raw, &uptime); err != nil {
+			return peerUptime{}, fmt.Errorf("invalid uptime of peer %s: %v", pubkey, err)
+		}
+	} else if err != ErrKeyNotFound {
+		return peerUptime{}, err
+	}
+	if uptime.FirstSeen.IsZero() {
+		uptime.FirstSeen = now
+	} else if elapsed := now.Sub(uptime.LastSeen); elapsed > 0 && elapsed <= 2*p.interval {
+		// a peer which wasn't seen at the previous poll may have been disconnected for most of the time since
+		uptime.ConnectedSeconds += int64(elapsed / time.Second)
+	}
+	uptime.LastSeen = now
+	if raw, err = json.Marshal(uptime); err != nil {
+		return peerUptime{}, err
+	}
+	if err = p.store.Put(peer_uptime_key_prefix+pubkey, raw); err != nil {
+		return peerUptime{}, err
+	}
+	return uptime, nil
+}
+
+// Scores returns the scores of the connected peers as of the last check, best first
+func (p *LNDPeerScorer) Scores() ([]PeerScore, error) {
+	p.RLock()
+	defer p.RUnlock()
+	if p.scores == nil {
+		return nil, ErrLNDNotActive
+	}
+	return p.scores, nil
+}
+
+// pingPercentile returns the ping time below which the given share of the pings are, 0 without pings
+func pingPercentile(pings []int64, percentile float64) int64 {
+	if len(pings) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), pings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(percentile*float64(len(sorted)-1))]
+}
+
+// scoreConnectedPeers scores the peers, best first.
+// The volume weighs 40 points relative to the best peer, the days connected 30 points up to 30 days, the 90th percentile of the ping times
+// 15 points by rank among the peers and the balance between inbound and outbound forwards 15 points, a peer only sending or receiving getting none
+func scoreConnectedPeers(samples []peerSample, channels []*lnrpc.Channel, events []*lnrpc.ForwardingEvent) []PeerScore {
+	owners := make(map[uint64]string, len(channels))
+	for _, channel := range channels {
+		owners[channel.ChanId] = channel.RemotePubkey
+	}
+	scores := make(map[string]*PeerScore, len(samples))
+	for _, sample := range samples {
+		scores[sample.pubkey] = &PeerScore{
+			Pubkey:        sample.pubkey,
+			DaysConnected: sample.daysConnected,
+			PingP90Micros: pingPercentile(sample.pings, peer_score_ping_percentile),
+		}
+	}
+	// forwards through channels which are closed now, or with peers which aren't connected, are ignored
+	for _, event := range events {
+		if s, ok := scores[owners[event.ChanIdIn]]; ok {
+			s.InboundForwards++
+			s.VolumeMsat += event.AmtInMsat
+		}
+		if s, ok := scores[owners[event.ChanIdOut]]; ok {
+			s.OutboundForwards++
+			s.VolumeMsat += event.AmtOutMsat
+		}
+	}
+	var maxVolume uint64
+	var pinged []int64
+	for _, s := range scores {
+		if s.VolumeMsat > maxVolume {
+			maxVolume = s.VolumeMsat
+		}
+		if s.PingP90Micros > 0 {
+			pinged = append(pinged, s.PingP90Micros)
+		}
+	}
+	result := make([]PeerScore, 0, len(scores))
+	for _, s := range scores {
+		if maxVolume > 0 {
+			s.Score += peer_score_volume_weight * float64(s.VolumeMsat) / float64(maxVolume)
+		}
+		if s.DaysConnected >= peer_score_full_uptime_days {
+			s.Score += peer_score_uptime_weight
+		} else {
+			s.Score += peer_score_uptime_weight * s.DaysConnected / peer_score_full_uptime_days
+		}
+		if s.PingP90Micros > 0 {
+			slower := 0
+			for _, ping := range pinged {
+				if ping > s.PingP90Micros {
+					slower++
+				}
+			}
+			if len(pinged) == 1 {
+				s.Score += peer_score_latency_weight
+			} else {
+				s.Score += peer_score_latency_weight * float64(slower) / float64(len(pinged)-1)
+			}
+		}
+		if forwards := s.InboundForwards + s.OutboundForwards; forwards > 0 {
+			s.InboundShare = float64(s.InboundForwards) / float64(forwards)
+			balance := 1 - 2*s.InboundShare
+			if balance < 0 {
+				balance = -balance
+			}
+			s.Score += peer_score_balance_weight * (1 - balance)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Pubkey < result[j].Pubkey
+	})
+	return result
+}
+
+// RegisterPeerScores registers the conduit_peer_scores method
+func (s *RPCServer) RegisterPeerScores(scorer *LNDPeerScorer) {
+	s.Register("conduit_peer_scores", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		scores, err := scorer.Scores()
+		if err != nil {
+			return nil, lndRPCError(err, "score peers")
+		}
+		return scores, nil
+	})
+}
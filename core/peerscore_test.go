@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+)
+
+// forwardsBetween returns count forwards of amtMsat from the channel in to the channel out at the given time
+func forwardsBetween(at time.Time, in, out uint64, count int, amtMsat uint64) []*lnrpc.ForwardingEvent {
+	events := make([]*lnrpc.ForwardingEvent, count)
+	for i := range events {
+		events[i] = &lnrpc.ForwardingEvent{Timestamp: uint64(at.Unix()), ChanIdIn: in, ChanIdOut: out, AmtInMsat: amtMsat, AmtOutMsat: amtMsat}
+	}
+	return events
+}
+
+// TestScoreConnectedPeers ensures every component of the score is weighed as documented
+func TestScoreConnectedPeers(t *testing.T) {
+	samples := []peerSample{
+		// a forwards the most, in both directions, has been connected for long and answers pings the fastest
+		{pubkey: "a", daysConnected: 45, pings: []int64{1000, 1200, 900}},
+		// b forwards as much to a as it receives from it, is young and slow
+		{pubkey: "b", daysConnected: 3, pings: []int64{5000, 90000, 6000}},
+		// c never forwarded anything and was never pinged
+		{pubkey: "c", daysConnected: 15},
+	}
+	channels := []*lnrpc.Channel{{ChanId: 1, RemotePubkey: "a"}, {ChanId: 2, RemotePubkey: "b"}, {ChanId: 3, RemotePubkey: "c"}}
+	var events []*lnrpc.ForwardingEvent
+	events = append(events, forwardsBetween(time.Now(), 1, 2, 3, 1000)...)
+	events = append(events, forwardsBetween(time.Now(), 2, 1, 3, 1000)...)
+	// forwards through closed channels are ignored
+	events = append(events, forwardsBetween(time.Now(), 9, 1, 100, 1000)...)
+	scores := scoreConnectedPeers(samples, channels, events)
+	if len(scores) != 3 || scores[0].Pubkey != "a" || scores[1].Pubkey != "b" || scores[2].Pubkey != "c" {
+		t.Fatalf("expected a, b and c in that order, got %+v", scores)
+	}
+	a, b, c := scores[0], scores[1], scores[2]
+	if a.VolumeMsat != 106000 || a.InboundForwards != 3 || a.OutboundForwards != 103 {
+		t.Errorf("unexpected forwards of a: %+v", a)
+	}
+	if b.VolumeMsat != 6000 || b.InboundForwards != 3 || b.OutboundForwards != 3 || b.InboundShare != 0.5 {
+		t.Errorf("unexpected forwards of b: %+v", b)
+	}
+	if a.PingP90Micros != 1000 || b.PingP90Micros != 6000 || c.PingP90Micros != 0 {
+		t.Errorf("unexpected ping percentiles %d, %d and %d", a.PingP90Micros, b.PingP90Micros, c.PingP90Micros)
+	}
+	// a: full volume, full uptime, fastest ping and 3 inbound out of 106 forwards
+	wantA := 40 + 30 + 15 + 15*(1-math.Abs(1-2*3.0/106))
+	// b: 6/106 of the volume, 3 days out of 30, slowest ping and balanced forwards
+	wantB := 40*6000.0/106000 + 30*3.0/30 + 0 + 15
+	// c: half of the uptime only
+	wantC := 15.0
+	for _, check := range []struct {
+		score PeerScore
+		want  float64
+	}{{a, wantA}, {b, wantB}, {c, wantC}} {
+		if math.Abs(check.score.Score-check.want) > 1e-9 {
+			t.Errorf("expected %s to score %f, got %f", check.score.Pubkey, check.want, check.score.Score)
+		}
+	}
+}
+
+// TestLNDPeerScorerUptime ensures the connected time of the peers is kept across stores, and doesn't count the time a peer wasn't seen
+func TestLNDPeerScorerUptime(t *testing.T) {
+	storePath := path.Join(t.TempDir(), metadata_file_name)
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := &fakeLightningClient{
+		peers:    []*lnrpc.Peer{{PubKey: "a", PingTime: 2000}, {PubKey: "b"}},
+		channels: []*lnrpc.Channel{{ChanId: 1, RemotePubkey: "a"}, {ChanId: 2, RemotePubkey: "b"}},
+		forwards: append(forwardsBetween(now.Add(-time.Hour), 1, 2, 2, 1000), forwardsBetween(now.Add(-60*24*time.Hour), 2, 1, 5, 1000)...),
+	}
+	check := func(at time.Time) []PeerScore {
+		t.Helper()
+		store, err := OpenMetadataStore(storePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		log := zerolog.Nop()
+		scorer := NewLNDPeerScorer(store, &log)
+		scorer.now = func() time.Time { return at }
+		if _, err = scorer.Scores(); !errors.Is(err, ErrLNDNotActive) {
+			t.Errorf("expected ErrLNDNotActive before the first check, got %v", err)
+		}
+		if err = scorer.Check(context.Background()); !errors.Is(err, ErrLNDNotActive) {
+			t.Errorf("expected ErrLNDNotActive without a client, got %v", err)
+		}
+		scorer.SetClient(client)
+		if err = scorer.Check(context.Background()); err != nil {
+			t.Fatalf("Check returned an error: %v", err)
+		}
+		scores, err := scorer.Scores()
+		if err != nil {
+			t.Fatalf("Scores returned an error: %v", err)
+		}
+		return scores
+	}
+	if scores := check(now); len(scores) != 2 || scores[0].DaysConnected != 0 || scores[1].DaysConnected != 0 {
+		t.Fatalf("expected two new peers, got %+v", scores)
+	}
+	// b disconnects
+	client.peers = client.peers[:1]
+	scores := check(now.Add(peer_score_poll_interval))
+	if len(scores) != 1 || scores[0].Pubkey != "a" {
+		t.Fatalf("expected only a to be scored, got %+v", scores)
+	}
+	if want := peer_score_poll_interval.Hours() / 24; math.Abs(scores[0].DaysConnected-want) > 1e-9 {
+		t.Errorf("expected a to be connected for %f days, got %f", want, scores[0].DaysConnected)
+	}
+	// the forwards older than the period are ignored, and those with disconnected peers aren't credited
+	if scores[0].OutboundForwards != 0 || scores[0].InboundForwards != 2 || scores[0].VolumeMsat != 2000 {
+		t.Errorf("unexpected forwards of a: %+v", scores[0])
+	}
+	// b reconnects long after, its disconnected time isn't counted
+	client.peers = append(client.peers, &lnrpc.Peer{PubKey: "b"})
+	scores = check(now.Add(time.Hour))
+	for _, score := range scores {
+		if score.Pubkey == "b" && score.DaysConnected != 0 {
+			t.Errorf("expected b not to be credited while disconnected, got %f days", score.DaysConnected)
+		}
+	}
+}
+
+// TestPeerScoresRPC ensures conduit_peer_scores fails with ErrLNDNotRunning until the peers are first scored
+func TestPeerScoresRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	store, err := OpenMetadataStore(path.Join(t.TempDir(), metadata_file_name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	log := zerolog.Nop()
+	scorer := NewLNDPeerScorer(store, &log)
+	s.RegisterPeerScores(scorer)
+	var scores []PeerScore
+	err = client.Call(context.Background(), "conduit_peer_scores", nil, &scores)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrLNDNotRunning {
+		t.Fatalf("expected ErrLNDNotRunning before the peers are scored, got %v", err)
+	}
+	scorer.SetClient(&fakeLightningClient{peers: []*lnrpc.Peer{{PubKey: "a"}}})
+	if err = scorer.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Call(context.Background(), "conduit_peer_scores", nil, &scores); err != nil || len(scores) != 1 || scores[0].Pubkey != "a" {
+		t.Fatalf("expected the score of a, got %+v: %v", scores, err)
+	}
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	ErrPluginNotFound = errors.Error("plugin not found")
+	plugin_dir_name   = "plugins"
+)
+
+// PluginManifest is the sidecar YAML file describing a plugin
+type PluginManifest struct {
+	Name     string `yaml:"Name"`
+	Version  string `yaml:"Version"`
+	Endpoint string `yaml:"Endpoint"`
+	// Methods are the JSON-RPC methods served on the endpoint. The calls to other methods are recorded as calls to an unknown method
+	Methods []string `yaml:"Methods,omitempty"`
+	// Executable is the plugin binary launched by Conduit, relative to the plugin directory unless absolute. Plugins without one are run externally
+	Executable string   `yaml:"Executable"`
+	Args       []string `yaml:"Args"`
+	// DependsOn are the plugins which must be started before this one
+	DependsOn []string `yaml:"DependsOn"`
+	// RequiresLNDReady plugins are started once LND's RPC server is active, all others are started before LND
+	RequiresLNDReady bool `yaml:"RequiresLNDReady"`
+	// RPCMiddleware is set by plugins implementing the rpcmiddleware_handle_request and rpcmiddleware_handle_response methods
+	RPCMiddleware bool `yaml:"RPCMiddleware"`
+	// LNDCapabilities are the LND permissions, of the form entity:action, granted to the plugin through the macaroon baked before it starts
+	LNDCapabilities []string `yaml:"LNDCapabilities,omitempty"`
+	// PprofPort is the port on which the plugin serves net/http/pprof on localhost, if it does
+	PprofPort int `yaml:"PprofPort,omitempty"`
+	// ResourceLimits are the resources the plugin process can use at most
+	ResourceLimits ResourceLimits `yaml:"ResourceLimits,omitempty"`
+	// HealthCheck describes how the health of the plugin can be checked, if it can
+	HealthCheck PluginHealthCheck `yaml:"HealthCheck,omitempty"`
+	// Requires are the versions of the software the plugin is compatible with
+	Requires PluginRequirements `yaml:"Requires,omitempty"`
+}
+
+// PluginRequirements is the compatibility section of a plugin manifest
+type PluginRequirements struct {
+	// Conduit is the range of Conduit versions the plugin works with, i.e. ">=0.2.0 <1.0.0"
+	Conduit string `yaml:"Conduit"`
+}
+
+// ResourceLimits is the resource limits section of a plugin manifest. Zero values leave the limits inherited from Conduit
+type ResourceLimits struct {
+	// MaxMemoryMB limits the virtual memory of the process
+	MaxMemoryMB int `yaml:"MaxMemoryMB"`
+	// MaxOpenFiles limits the number of file descriptors the process can open
+	MaxOpenFiles int `yaml:"MaxOpenFiles"`
+	// MaxCPUSeconds limits the CPU time of the process, which is killed once it's used up
+	MaxCPUSeconds int `yaml:"MaxCPUSeconds"`
+}
+
+// PluginHealthCheck is the health check section of a plugin manifest
+type PluginHealthCheck struct {
+	// Endpoint is the URL answering health checks
+	Endpoint string `yaml:"Endpoint"`
+}
+
+// PluginDir returns the directory in which plugin binaries and their manifests are stored
+func PluginDir(cfg *Config) string {
+	return path.Join(cfg.ConduitDir, plugin_dir_name)
+}
+
+// LoadPluginManifests reads and validates every `.yaml` manifest in the given directory and returns them keyed by plugin name. The plugins
+// depending on a plugin which isn't there are left out, see loadPluginManifests
+func LoadPluginManifests(dir string) (map[string]*PluginManifest, error) {
+	manifests, _, err := loadPluginManifests(dir)
+	return manifests, err
+}
+
+// loadPluginManifests is LoadPluginManifests, also returning the reason each plugin was left out. A plugin whose dependencies are missing is
+// left out so that the others still start, as are the plugins depending on it
+func loadPluginManifests(dir string) (map[string]*PluginManifest, []error, error) {
+	files, err := filepath.Glob(path.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, nil, err
+	}
+	manifests := make(map[string]*PluginManifest)
+	validator := NewPluginManifestValidator()
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		m := &PluginManifest{}
+		// unknown fields are rejected since they are most likely misspelled
+		if err = yaml.UnmarshalStrict(raw, m); err != nil {
+			return nil, nil, fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, filepath.Base(file), err)
+		}
+		if errs := validator.Validate(m); len(errs) > 0 {
+			return nil, nil, fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, filepath.Base(file), joinValidationErrors(errs))
+		}
+		manifests[m.Name] = m
+	}
+	// leaving a plugin out can leave the plugins depending on it without dependency, so the check is repeated until none is left out
+	var skipped []error
+	for removed := true; removed; {
+		removed = false
+		for name, m := range manifests {
+			if errs := validator.ValidateDependencies(m, manifests); len(errs) > 0 {
+				skipped = append(skipped, fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, name, joinValidationErrors(errs)))
+				delete(manifests, name)
+				removed = true
+			}
+		}
+	}
+	return manifests, skipped, nil
+}
+
+// joinValidationErrors returns the problems found in a manifest as a single message
+func joinValidationErrors(errs []ValidationError) string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// LoadPluginManifest returns the manifest of the named plugin
+func LoadPluginManifest(cfg *Config, name string) (*PluginManifest, error) {
+	manifests, err := LoadPluginManifests(PluginDir(cfg))
+	if err != nil {
+		return nil, err
+	}
+	m, ok := manifests[name]
+	if !ok {
+		return nil, ErrPluginNotFound
+	}
+	return m, nil
+}
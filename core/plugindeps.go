@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const (
+	ErrPluginDependencyCycle = errors.Error("plugin dependency cycle")
+)
+
+// DFS colors of the plugins in the dependency graph
+const (
+	dfs_unvisited = iota
+	dfs_visiting
+	dfs_visited
+)
+
+// PluginDependencyGraph is the graph of plugins and the plugins they depend on
+type PluginDependencyGraph struct {
+	edges map[string][]string
+}
+
+// NewPluginDependencyGraph builds the dependency graph of the given manifests. Dependencies on unknown plugins are ignored
+func NewPluginDependencyGraph(manifests map[string]*PluginManifest) *PluginDependencyGraph {
+	edges := make(map[string][]string, len(manifests))
+	for name, manifest := range manifests {
+		var deps []string
+		for _, dep := range manifest.DependsOn {
+			if _, ok := manifests[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		sort.Strings(deps)
+		edges[name] = deps
+	}
+	return &PluginDependencyGraph{edges: edges}
+}
+
+// nodes returns the sorted names of the plugins so that the traversal is deterministic
+func (g *PluginDependencyGraph) nodes() []string {
+	names := make([]string, 0, len(g.edges))
+	for name := range g.edges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// walk does a depth first traversal of the graph calling onCycle for every back edge and onDone once all dependencies of a plugin are visited
+func (g *PluginDependencyGraph) walk(onCycle func(cycle []string), onDone func(name string)) {
+	colors := make(map[string]int, len(g.edges))
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		colors[name] = dfs_visiting
+		stack = append(stack, name)
+		for _, dep := range g.edges[name] {
+			switch colors[dep] {
+			case dfs_unvisited:
+				visit(dep)
+			case dfs_visiting:
+				// the path from dep to the top of the stack closes a cycle
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycle := append(append([]string{}, stack[i:]...), dep)
+						onCycle(cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		colors[name] = dfs_visited
+		onDone(name)
+	}
+	for _, name := range g.nodes() {
+		if colors[name] == dfs_unvisited {
+			visit(name)
+		}
+	}
+}
+
+// Cycles returns every dependency cycle found in the graph as a path starting and ending with the same plugin, i.e. ["a", "b", "c", "a"]
+func (g *PluginDependencyGraph) Cycles() [][]string {
+	var cycles [][]string
+	g.walk(func(cycle []string) { cycles = append(cycles, cycle) }, func(string) {})
+	return cycles
+}
+
+// Order returns the plugins sorted so that every plugin comes after its dependencies. It returns an error listing all cycles if there are any
+func (g *PluginDependencyGraph) Order() ([]string, error) {
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		paths := make([]string, len(cycles))
+		for i, cycle := range cycles {
+			paths[i] = strings.Join(cycle, " -> ")
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPluginDependencyCycle, strings.Join(paths, "; "))
+	}
+	var order []string
+	g.walk(func([]string) {}, func(name string) { order = append(order, name) })
+	return order, nil
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestPluginDependencyGraphCycles ensures every independent cycle of the graph is reported
+func TestPluginDependencyGraphCycles(t *testing.T) {
+	manifests := map[string]*PluginManifest{
+		"a": {Name: "a", DependsOn: []string{"b"}},
+		"b": {Name: "b", DependsOn: []string{"c"}},
+		"c": {Name: "c", DependsOn: []string{"a"}},
+		"d": {Name: "d", DependsOn: []string{"e"}},
+		"e": {Name: "e", DependsOn: []string{"d", "missing"}},
+		"f": {Name: "f", DependsOn: []string{"a"}},
+	}
+	g := NewPluginDependencyGraph(manifests)
+	want := [][]string{{"a", "b", "c", "a"}, {"d", "e", "d"}}
+	if diff := cmp.Diff(want, g.Cycles()); diff != "" {
+		t.Errorf("unexpected cycles (-want +got):\n%s", diff)
+	}
+	_, err := g.Order()
+	if !errors.Is(err, ErrPluginDependencyCycle) {
+		t.Fatalf("expected ErrPluginDependencyCycle, got %v", err)
+	}
+	for _, cycle := range []string{"a -> b -> c -> a", "d -> e -> d"} {
+		if !strings.Contains(err.Error(), cycle) {
+			t.Errorf("error %q does not report cycle %s", err, cycle)
+		}
+	}
+}
+
+// TestPluginDependencyGraphOrder ensures plugins come after their dependencies
+func TestPluginDependencyGraphOrder(t *testing.T) {
+	manifests := map[string]*PluginManifest{
+		"app":    {Name: "app", DependsOn: []string{"signer", "db"}},
+		"db":     {Name: "db"},
+		"signer": {Name: "signer", DependsOn: []string{"db"}},
+	}
+	g := NewPluginDependencyGraph(manifests)
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+	order, err := g.Order()
+	if err != nil {
+		t.Fatalf("Order returned an error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"db", "signer", "app"}, order); diff != "" {
+		t.Errorf("unexpected order (-want +got):\n%s", diff)
+	}
+}
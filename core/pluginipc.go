@@ -0,0 +1,353 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ErrIPCPeerUnknown   = errors.Error("unknown IPC peer")
+	ErrIPCClosed        = errors.Error("IPC client closed")
+	ErrIPCFrameTooLarge = errors.Error("IPC message too large")
+	plugin_ipc_dir_name = "ipc"
+	plugin_ipc_env      = "CONDUIT_IPC_"
+	// ipc_max_frame_size bounds the length prefix so that a corrupted stream can't make us allocate gigabytes
+	ipc_max_frame_size = 16 * 1024 * 1024
+)
+
+// writeIPCFrame writes msg prefixed with its 4 bytes big endian length
+func writeIPCFrame(w io.Writer, msg []byte) error {
+	if len(msg) > ipc_max_frame_size {
+		return ErrIPCFrameTooLarge
+	}
+	frame := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	copy(frame[4:], msg)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readIPCFrame reads a message written by writeIPCFrame
+func readIPCFrame(r io.Reader) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > ipc_max_frame_size {
+		return nil, ErrIPCFrameTooLarge
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// PluginIPCDir returns the directory of the plugin IPC sockets in the conduit directory
+func PluginIPCDir(config *Config) string {
+	return path.Join(config.ConduitDir, plugin_ipc_dir_name)
+}
+
+// ipcSocketName returns the name of the socket shared by two plugins, the same whichever way round they're given
+func ipcSocketName(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return fmt.Sprintf("%s-%s.sock", a, b)
+}
+
+// ipcEnvName returns the environment variable holding the path of the socket to the given peer, i.e. CONDUIT_IPC_MY_PLUGIN
+func ipcEnvName(peer string) string {
+	return plugin_ipc_env + strings.ToUpper(strings.ReplaceAll(peer, "-", "_"))
+}
+
+// ipcPair is the socket shared by two plugins and the connections they opened on it
+type ipcPair struct {
+	sync.Mutex
+	a, b     string
+	listener net.Listener
+	conns    map[string]net.Conn
+}
+
+// PluginIPCBus relays messages between plugins over one Unix socket per pair of plugins.
+// Each plugin connects to the socket of a pair, sends its name as the first message and then exchanges length prefixed messages with the other plugin
+type PluginIPCBus struct {
+	dir   string
+	log   *subLogger
+	mu    sync.Mutex
+	pairs map[string]*ipcPair
+	trace *PluginCommunicationLog
+}
+
+// NewPluginIPCBus creates a new PluginIPCBus for the given plugins with its sockets in dir
+func NewPluginIPCBus(dir string, plugins []string, log *zerolog.Logger) *PluginIPCBus {
+	names := append([]string{}, plugins...)
+	sort.Strings(names)
+	pairs := make(map[string]*ipcPair)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			pairs[ipcSocketName(a, b)] = &ipcPair{a: a, b: b, conns: make(map[string]net.Conn)}
+		}
+	}
+	return &PluginIPCBus{
+		dir:   dir,
+		log:   NewSubLogger(log, "PIPC"),
+		pairs: pairs,
+	}
+}
+
+// Listen creates the socket of every pair of plugins and starts relaying their messages
+func (b *PluginIPCBus) Listen() error {
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, pair := range b.pairs {
+		if pair.listener != nil {
+			continue
+		}
+		socket := path.Join(b.dir, name)
+		// a socket left behind by a previous run would make listening fail
+		os.Remove(socket)
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			return fmt.Errorf("could not listen on %v: %v", socket, err)
+		}
+		pair.listener = listener
+		go b.accept(pair)
+	}
+	return nil
+}
+
+// SetTrace records every message sent through the bus in the given trace, which is closed along with the bus. It must be called before Listen
+func (b *PluginIPCBus) SetTrace(trace *PluginCommunicationLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trace = trace
+}
+
+// Env returns the environment variables giving the named plugin the path of the socket to every other plugin
+func (b *PluginIPCBus) Env(name string) []string {
+	var env []string
+	for socket, pair := range b.pairs {
+		switch name {
+		case pair.a:
+			env = append(env, fmt.Sprintf("%s=%s", ipcEnvName(pair.b), path.Join(b.dir, socket)))
+		case pair.b:
+			env = append(env, fmt.Sprintf("%s=%s", ipcEnvName(pair.a), path.Join(b.dir, socket)))
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// accept serves the connections of the plugins of a pair until the listener is closed
+func (b *PluginIPCBus) accept(pair *ipcPair) {
+	for {
+		conn, err := pair.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.relay(pair, conn)
+	}
+}
+
+// relay forwards the messages of a plugin to the other plugin of the pair
+func (b *PluginIPCBus) relay(pair *ipcPair, conn net.Conn) {
+	defer conn.Close()
+	hello, err := readIPCFrame(conn)
+	if err != nil {
+		return
+	}
+	from := string(hello)
+	var to string
+	switch from {
+	case pair.a:
+		to = pair.b
+	case pair.b:
+		to = pair.a
+	default:
+		b.log.SubLogger.Warn().Msg(fmt.Sprintf("rejected IPC connection from %q on %v", from, ipcSocketName(pair.a, pair.b)))
+		return
+	}
+	pair.Lock()
+	if previous, ok := pair.conns[from]; ok {
+		// the plugin reconnected, i.e. after a restart
+		previous.Close()
+	}
+	pair.conns[from] = conn
+	pair.Unlock()
+	defer func() {
+		pair.Lock()
+		if pair.conns[from] == conn {
+			delete(pair.conns, from)
+		}
+		pair.Unlock()
+	}()
+	for {
+		msg, err := readIPCFrame(conn)
+		if err != nil {
+			return
+		}
+		// traced before relaying so that the trace also shows the messages which are dropped
+		if b.trace != nil {
+			if err = b.trace.Record(from, to, msg); err != nil {
+				b.log.SubLogger.Debug().Msg(fmt.Sprintf("could not trace IPC message from %s to %s: %v", from, to, err))
+			}
+		}
+		pair.Lock()
+		peer, ok := pair.conns[to]
+		if ok {
+			err = writeIPCFrame(peer, msg)
+		}
+		pair.Unlock()
+		if !ok {
+			b.log.SubLogger.Debug().Msg(fmt.Sprintf("dropped IPC message from %s: %s is not connected", from, to))
+		} else if err != nil {
+			b.log.SubLogger.Debug().Msg(fmt.Sprintf("could not relay IPC message from %s to %s: %v", from, to, err))
+		}
+	}
+}
+
+// Close closes every socket and connection of the bus
+func (b *PluginIPCBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, pair := range b.pairs {
+		if pair.listener == nil {
+			continue
+		}
+		pair.listener.Close()
+		pair.listener = nil
+		pair.Lock()
+		for _, conn := range pair.conns {
+			conn.Close()
+		}
+		pair.Unlock()
+		os.Remove(path.Join(b.dir, name))
+	}
+	if b.trace != nil {
+		b.trace.Close()
+		b.trace = nil
+	}
+	return nil
+}
+
+// ipcMessage is a message received by a PluginIPCClient
+type ipcMessage struct {
+	from string
+	msg  []byte
+}
+
+// PluginIPCClient is the plugin side of the PluginIPCBus, connected to every other plugin
+type PluginIPCClient struct {
+	name     string
+	mu       sync.Mutex
+	conns    map[string]net.Conn
+	messages chan ipcMessage
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewPluginIPCClient connects the named plugin to the given sockets, keyed by the name of the plugin at the other end
+func NewPluginIPCClient(name string, sockets map[string]string) (*PluginIPCClient, error) {
+	c := &PluginIPCClient{
+		name:     name,
+		conns:    make(map[string]net.Conn, len(sockets)),
+		messages: make(chan ipcMessage, event_buffer_size),
+		done:     make(chan struct{}),
+	}
+	for peer, socket := range sockets {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("could not connect to %s: %v", peer, err)
+		}
+		if err = writeIPCFrame(conn, []byte(name)); err != nil {
+			conn.Close()
+			c.Close()
+			return nil, err
+		}
+		c.conns[peer] = conn
+		go c.read(peer, conn)
+	}
+	return c, nil
+}
+
+// NewPluginIPCClientFromEnv connects a plugin launched by Conduit to the other plugins, using the environment set by the PluginManager
+func NewPluginIPCClientFromEnv() (*PluginIPCClient, error) {
+	sockets := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, plugin_ipc_env) {
+			continue
+		}
+		split := strings.SplitN(strings.TrimPrefix(kv, plugin_ipc_env), "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		// plugin names are lowercase with dashes, see ipcEnvName
+		sockets[strings.ToLower(strings.ReplaceAll(split[0], "_", "-"))] = split[1]
+	}
+	return NewPluginIPCClient(os.Getenv("CONDUIT_PLUGIN_NAME"), sockets)
+}
+
+// read queues the messages received from a peer until the connection is closed
+func (c *PluginIPCClient) read(peer string, conn net.Conn) {
+	for {
+		msg, err := readIPCFrame(conn)
+		if err != nil {
+			return
+		}
+		select {
+		case c.messages <- ipcMessage{from: peer, msg: msg}:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Send sends a message to the named plugin
+func (c *PluginIPCClient) Send(to string, msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn, ok := c.conns[to]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrIPCPeerUnknown, to)
+	}
+	return writeIPCFrame(conn, msg)
+}
+
+// Recv blocks until a message is received from any plugin and returns it along with the name of its sender
+func (c *PluginIPCClient) Recv() (string, []byte, error) {
+	select {
+	case m := <-c.messages:
+		return m.from, m.msg, nil
+	case <-c.done:
+		return "", nil, ErrIPCClosed
+	}
+}
+
+// Close closes the connections to every plugin
+func (c *PluginIPCClient) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, conn := range c.conns {
+			conn.Close()
+		}
+	})
+	return nil
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// newTestIPCBus starts a PluginIPCBus for the given plugins in a temporary directory
+func newTestIPCBus(t *testing.T, plugins ...string) *PluginIPCBus {
+	t.Helper()
+	log := zerolog.Nop()
+	bus := NewPluginIPCBus(t.TempDir(), plugins, &log)
+	if err := bus.Listen(); err != nil {
+		t.Fatalf("Listen returned an error: %v", err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+// newTestIPCClient connects the named plugin to the sockets of its pairs on the bus
+func newTestIPCClient(t *testing.T, bus *PluginIPCBus, name string, peers ...string) *PluginIPCClient {
+	t.Helper()
+	sockets := make(map[string]string)
+	for _, peer := range peers {
+		sockets[peer] = path.Join(bus.dir, ipcSocketName(name, peer))
+	}
+	client, err := NewPluginIPCClient(name, sockets)
+	if err != nil {
+		t.Fatalf("NewPluginIPCClient returned an error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// ipcInbox returns a channel receiving the messages of the client until it's closed
+func ipcInbox(c *PluginIPCClient) <-chan ipcMessage {
+	inbox := make(chan ipcMessage, event_buffer_size)
+	go func() {
+		defer close(inbox)
+		for {
+			from, msg, err := c.Recv()
+			if err != nil {
+				return
+			}
+			inbox <- ipcMessage{from: from, msg: msg}
+		}
+	}()
+	return inbox
+}
+
+// pingUntilConnected sends pings from c to peer until a message is received in inbox, since messages are dropped until both ends are connected
+func pingUntilConnected(t *testing.T, c *PluginIPCClient, peer string, inbox <-chan ipcMessage) ipcMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := c.Send(peer, []byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-inbox:
+			return m
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatalf("no message received from %s", peer)
+	return ipcMessage{}
+}
+
+func TestIPCFrame(t *testing.T) {
+	var buf bytes.Buffer
+	for _, msg := range [][]byte{[]byte("hello"), {}, bytes.Repeat([]byte{0xff}, 70000)} {
+		if err := writeIPCFrame(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []int{5, 0, 70000} {
+		msg, err := readIPCFrame(&buf)
+		if err != nil || len(msg) != want {
+			t.Fatalf("expected a %d bytes message, got %d bytes (%v)", want, len(msg), err)
+		}
+	}
+	// a length prefix above the maximum is rejected without reading the payload
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := readIPCFrame(&buf); err != ErrIPCFrameTooLarge {
+		t.Fatalf("expected ErrIPCFrameTooLarge, got %v", err)
+	}
+}
+
+func TestPluginIPCBusEnv(t *testing.T) {
+	log := zerolog.Nop()
+	bus := NewPluginIPCBus("/conduit/ipc", []string{"signer", "fee-bot", "watcher"}, &log)
+	env := bus.Env("signer")
+	want := []string{"CONDUIT_IPC_FEE_BOT=/conduit/ipc/fee-bot-signer.sock", "CONDUIT_IPC_WATCHER=/conduit/ipc/signer-watcher.sock"}
+	if len(env) != len(want) || env[0] != want[0] || env[1] != want[1] {
+		t.Fatalf("unexpected env %v", env)
+	}
+}
+
+// TestPluginIPCBusRoundTrip ensures messages are relayed both ways with the sender's name, with a round trip below 1 ms
+func TestPluginIPCBusRoundTrip(t *testing.T) {
+	bus := newTestIPCBus(t, "alpha", "beta")
+	alpha := newTestIPCClient(t, bus, "alpha", "beta")
+	beta := newTestIPCClient(t, bus, "beta", "alpha")
+	alphaInbox, betaInbox := ipcInbox(alpha), ipcInbox(beta)
+	if m := pingUntilConnected(t, alpha, "beta", betaInbox); m.from != "alpha" {
+		t.Fatalf("unexpected sender %q", m.from)
+	}
+	// drop the pings which arrived late
+	time.Sleep(50 * time.Millisecond)
+	for len(betaInbox) > 0 {
+		<-betaInbox
+	}
+	go func() {
+		for m := range betaInbox {
+			beta.Send(m.from, m.msg)
+		}
+	}()
+	const rounds = 200
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		if err := alpha.Send("beta", []byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-alphaInbox:
+			if m.from != "beta" || string(m.msg) != "ping" {
+				t.Fatalf("unexpected message %q from %q", m.msg, m.from)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the reply")
+		}
+	}
+	if latency := time.Since(start) / rounds; latency >= time.Millisecond {
+		t.Errorf("average round trip %v is not below 1ms", latency)
+	}
+	if err := alpha.Send("gamma", []byte("ping")); err == nil {
+		t.Error("expected sending to an unknown plugin to fail")
+	}
+}
+
+// TestPluginIPCBusRejectsUnknownPlugin ensures only the two plugins of a pair can use its socket
+func TestPluginIPCBusRejectsUnknownPlugin(t *testing.T) {
+	bus := newTestIPCBus(t, "alpha", "beta")
+	conn, err := net.Dial("unix", path.Join(bus.dir, ipcSocketName("alpha", "beta")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = writeIPCFrame(conn, []byte("gamma")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = readIPCFrame(conn); err == nil {
+		t.Fatal("expected the connection to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("connection of an unknown plugin was not closed")
+	}
+}
+
+// TestPluginManagerIPC ensures plugins launched by the PluginManager get the paths of their sockets
+func TestPluginManagerIPC(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "ipc-echo")
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "echo"})
+	// tester is never launched, the test connects in its place
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "tester"})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	defer m.StopAll()
+	if err = m.Start("echo"); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	tester := newTestIPCClient(t, m.ipc, "tester", "echo")
+	// the echo plugin sends the ping back once it's connected
+	if m := pingUntilConnected(t, tester, "echo", ipcInbox(tester)); m.from != "echo" || string(m.msg) != "ping" {
+		t.Fatalf("unexpected reply %q from %q", m.msg, m.from)
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const ErrResourceLimitsUnsupported = errors.Error("plugin resource limits are not supported on this platform")
+
+// PluginResourceLimiter keeps runaway plugins from starving Conduit by limiting the resources of their processes
+type PluginResourceLimiter struct {
+	log *subLogger
+}
+
+// NewPluginResourceLimiter creates a new PluginResourceLimiter
+func NewPluginResourceLimiter(log *zerolog.Logger) *PluginResourceLimiter {
+	return &PluginResourceLimiter{log: NewSubLogger(log, "RLIM")}
+}
+
+// set reports whether any limit is set
+func (l ResourceLimits) set() bool {
+	return l.MaxMemoryMB > 0 || l.MaxOpenFiles > 0 || l.MaxCPUSeconds > 0
+}
+
+// Apply sets the resource limits of the started command. Platforms where the limits of a running process can't be set only log a warning
+func (r *PluginResourceLimiter) Apply(cmd *exec.Cmd, limits ResourceLimits) error {
+	if !limits.set() {
+		return nil
+	}
+	if cmd.Process == nil {
+		return fmt.Errorf("resource limits can only be applied to a started process")
+	}
+	err := setProcessLimits(cmd.Process.Pid, limits)
+	if err == ErrResourceLimitsUnsupported {
+		r.log.SubLogger.Warn().Msg(fmt.Sprintf("Process %d runs without resource limits: %v", cmd.Process.Pid, err))
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not limit the resources of process %d: %v", cmd.Process.Pid, err)
+	}
+	return nil
+}
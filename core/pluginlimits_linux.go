@@ -0,0 +1,26 @@
+package core
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// setProcessLimits sets the soft and hard limits of the running process with prlimit(2), since Go can't set the limits of a command before exec
+func setProcessLimits(pid int, limits ResourceLimits) error {
+	rlimits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_AS, uint64(limits.MaxMemoryMB) * 1024 * 1024},
+		{unix.RLIMIT_NOFILE, uint64(limits.MaxOpenFiles)},
+		{unix.RLIMIT_CPU, uint64(limits.MaxCPUSeconds)},
+	}
+	for _, rlimit := range rlimits {
+		if rlimit.value == 0 {
+			continue
+		}
+		if err := unix.Prlimit(pid, rlimit.resource, &unix.Rlimit{Cur: rlimit.value, Max: rlimit.value}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
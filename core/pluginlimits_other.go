@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+// setProcessLimits is not supported on this platform. On macOS, processes can only set their own limits and `launchctl limit` changes
+// those of every process launched afterwards, Conduit included
+func setProcessLimits(pid int, limits ResourceLimits) error {
+	return ErrResourceLimitsUnsupported
+}
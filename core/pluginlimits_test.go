@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// readProcLimit returns the soft and hard limits of a resource of the process from /proc/<pid>/limits
+func readProcLimit(t *testing.T, pid int, resource string) string {
+	t.Helper()
+	raw, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(resource + `\s+(\S+)\s+(\S+)`).FindStringSubmatch(string(raw))
+	if match == nil {
+		t.Fatalf("no %s limit in:\n%s", resource, raw)
+	}
+	return match[1] + " " + match[2]
+}
+
+// TestPluginResourceLimiter ensures the limits of a started process are set, on Linux, and that unset limits are left alone
+func TestPluginResourceLimiter(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only set on Linux")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=TestFakePlugin")
+	cmd.Env = append(os.Environ(), "CONDUIT_FAKE_PLUGIN=run")
+	log := zerolog.Nop()
+	limiter := NewPluginResourceLimiter(&log)
+	if err := limiter.Apply(cmd, ResourceLimits{MaxOpenFiles: 64}); err == nil {
+		t.Error("expected an error limiting a command which isn't started")
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	cpuBefore := readProcLimit(t, cmd.Process.Pid, "Max cpu time")
+	if err := limiter.Apply(cmd, ResourceLimits{MaxMemoryMB: 8192, MaxOpenFiles: 64}); err != nil {
+		t.Fatal(err)
+	}
+	if limit := readProcLimit(t, cmd.Process.Pid, "Max address space"); limit != "8589934592 8589934592" {
+		t.Errorf("unexpected address space limit %v", limit)
+	}
+	if limit := readProcLimit(t, cmd.Process.Pid, "Max open files"); limit != "64 64" {
+		t.Errorf("unexpected open files limit %v", limit)
+	}
+	if limit := readProcLimit(t, cmd.Process.Pid, "Max cpu time"); limit != cpuBefore {
+		t.Errorf("expected the CPU time limit to be left at %v, got %v", cpuBefore, limit)
+	}
+}
+
+// TestPluginManagerResourceLimits ensures the PluginManager limits the plugins with the ResourceLimits of their manifest
+func TestPluginManagerResourceLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only set on Linux")
+	}
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "limited", ResourceLimits: ResourceLimits{MaxCPUSeconds: 600}})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Start("limited"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.StopAll()
+	m.mu.RLock()
+	pid := m.processes["limited"].cmd.Process.Pid
+	m.mu.RUnlock()
+	if limit := readProcLimit(t, pid, "Max cpu time"); limit != "600 600" {
+		t.Errorf("unexpected CPU time limit %v", limit)
+	}
+}
@@ -0,0 +1,440 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ErrPluginNoExecutable   = errors.Error("plugin does not declare an executable")
+	ErrPluginAlreadyStarted = errors.Error("plugin already started")
+	PluginStarting          = PluginStatus("Starting")
+	PluginRunning           = PluginStatus("Running")
+	PluginStopped           = PluginStatus("Stopped")
+	PluginFailed            = PluginStatus("Failed")
+	plugin_ready_interval   = 100 * time.Millisecond
+	plugin_stop_timeout     = 5 * time.Second
+)
+
+// PluginStatus is the state of a plugin process launched by the PluginManager
+type PluginStatus string
+
+// ManagedProcess is a plugin process launched by the PluginManager
+type ManagedProcess struct {
+	Manifest *PluginManifest
+	Status   PluginStatus
+	// PluginState counts the crashes of the plugin, over the runs of Conduit if a PluginStateStore is set
+	PluginState
+	cmd      *exec.Cmd
+	stopping bool
+	done     chan struct{}
+}
+
+// PluginManager launches the plugins declaring an executable in their manifest and keeps track of their status
+type PluginManager struct {
+	cfg       *Config
+	log       *subLogger
+	manifests map[string]*PluginManifest
+	mu        sync.RWMutex
+	processes map[string]*ManagedProcess
+	ipc       *PluginIPCBus
+	// ipcListening is set once the IPC sockets are created, before the first plugin starts
+	ipcListening bool
+	// macaroons bakes the macaroons of the plugins declaring LND capabilities, once LND is active
+	macaroons   *MacaroonConstrainer
+	macaroonTTL time.Duration
+	limiter     *PluginResourceLimiter
+	// state persists the crashes of the plugins, if set
+	state *PluginStateStore
+}
+
+// NewPluginManager creates a new PluginManager from the manifests in the plugin directory
+func NewPluginManager(cfg *Config, log *zerolog.Logger) (*PluginManager, error) {
+	manifests, skipped, err := loadPluginManifests(PluginDir(cfg))
+	if err != nil {
+		return nil, err
+	}
+	pluginLog := NewSubLogger(log, "PLGN")
+	for _, err := range skipped {
+		pluginLog.SubLogger.Error().Msg(fmt.Sprintf("plugin disabled: %v", err))
+	}
+	checker, err := NewPluginVersionConstraintChecker(utils.AppVersion)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(manifests))
+	for name := range manifests {
+		names = append(names, name)
+	}
+	macaroonTTL, err := pluginMacaroonTTL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var launched []string
+	for _, name := range names {
+		manifest := manifests[name]
+		// an incompatible plugin fails the load rather than misbehaving once started
+		if err = checker.Check(manifest); err != nil {
+			pluginLog.SubLogger.Error().Str("plugin", name).Str("conduit_version", utils.AppVersion).Str("constraint", manifest.Requires.Conduit).Msg(err.Error())
+			return nil, err
+		}
+		if manifest.Executable != "" {
+			launched = append(launched, name)
+		}
+	}
+	return &PluginManager{
+		cfg:         cfg,
+		log:         pluginLog,
+		manifests:   manifests,
+		processes:   make(map[string]*ManagedProcess),
+		ipc:         NewPluginIPCBus(PluginIPCDir(cfg), launched, log),
+		macaroonTTL: macaroonTTL,
+		limiter:     NewPluginResourceLimiter(log),
+	}, nil
+}
+
+// SetMacaroonConstrainer sets the MacaroonConstrainer baking the macaroons of the plugins started from now on
+func (m *PluginManager) SetMacaroonConstrainer(constrainer *MacaroonConstrainer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.macaroons = constrainer
+}
+
+// SetStateStore sets the PluginStateStore in which the crashes of the plugins started from now on are persisted
+func (m *PluginManager) SetStateStore(state *PluginStateStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+}
+
+// Plugins returns the sorted names of the plugins launched by Conduit which do or don't require LND to be ready
+func (m *PluginManager) Plugins(requiresLNDReady bool) []string {
+	var names []string
+	for name, manifest := range m.manifests {
+		if manifest.Executable != "" && manifest.RequiresLNDReady == requiresLNDReady {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start launches the named plugin. The plugin is Running once its process is up and, if it declares an endpoint, accepts connections
+func (m *PluginManager) Start(name string) error {
+	manifest, ok := m.manifests[name]
+	if !ok {
+		return ErrPluginNotFound
+	}
+	if manifest.Executable == "" {
+		return fmt.Errorf("%s: %w", name, ErrPluginNoExecutable)
+	}
+	// the macaroon is baked before locking the manager, since LND can take a while to bake it
+	macPath, err := m.bakeMacaroon(name, manifest)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started(name) {
+		return fmt.Errorf("%s: %w", name, ErrPluginAlreadyStarted)
+	}
+	executable := manifest.Executable
+	if !filepath.IsAbs(executable) {
+		executable = path.Join(PluginDir(m.cfg), executable)
+	}
+	if !m.ipcListening {
+		if m.cfg.PluginIPCTrace {
+			if trace, err := OpenPluginCommunicationLog(IPCTracePath(m.cfg)); err != nil {
+				m.log.SubLogger.Warn().Msg(fmt.Sprintf("plugin IPC messages are not traced: %v", err))
+			} else {
+				m.ipc.SetTrace(trace)
+			}
+		}
+		if err := m.ipc.Listen(); err != nil {
+			m.log.SubLogger.Warn().Msg(fmt.Sprintf("plugins are started without IPC: %v", err))
+			// closes the sockets created before the failure and the trace
+			m.ipc.Close()
+		} else {
+			m.ipcListening = true
+		}
+	}
+	cmd := exec.Command(executable, manifest.Args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CONDUIT_PLUGIN_NAME=%s", name))
+	if m.ipcListening {
+		cmd.Env = append(cmd.Env, m.ipc.Env(name)...)
+	}
+	if macPath != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("CONDUIT_LND_MACAROON=%s", macPath))
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not start plugin %s: %v", name, err)
+	}
+	if err := m.limiter.Apply(cmd, manifest.ResourceLimits); err != nil {
+		// a plugin which can't be limited isn't left running unconstrained
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("could not start plugin %s: %v", name, err)
+	}
+	p := &ManagedProcess{Manifest: manifest, Status: PluginStarting, cmd: cmd, done: make(chan struct{})}
+	if previous, ok := m.processes[name]; ok {
+		p.PluginState = previous.PluginState
+	} else if m.state != nil {
+		state, err := m.state.Load(name)
+		if err != nil {
+			m.log.SubLogger.Warn().Msg(fmt.Sprintf("could not load the state of plugin %s: %v", name, err))
+		}
+		p.PluginState = state
+	}
+	m.processes[name] = p
+	m.log.SubLogger.Info().Msg(fmt.Sprintf("Started plugin %s with PID %d", name, cmd.Process.Pid))
+	go m.wait(name, p)
+	go m.waitUntilReady(name, p)
+	return nil
+}
+
+// started reports whether the named plugin is starting or running. The caller must hold the lock of the manager
+func (m *PluginManager) started(name string) bool {
+	p, ok := m.processes[name]
+	return ok && (p.Status == PluginStarting || p.Status == PluginRunning)
+}
+
+// bakeMacaroon writes the macaroon of the plugin's LND capabilities and returns its path, which is empty for a plugin without any
+func (m *PluginManager) bakeMacaroon(name string, manifest *PluginManifest) (string, error) {
+	if len(manifest.LNDCapabilities) == 0 {
+		return "", nil
+	}
+	m.mu.RLock()
+	constrainer, started := m.macaroons, m.started(name)
+	m.mu.RUnlock()
+	if started {
+		return "", fmt.Errorf("%s: %w", name, ErrPluginAlreadyStarted)
+	} else if constrainer == nil {
+		return "", fmt.Errorf("%s: %w", name, ErrPluginMacaroonUnavailable)
+	}
+	return writePluginMacaroon(m.cfg, constrainer, name, manifest.LNDCapabilities, m.macaroonTTL)
+}
+
+// StartAll starts the named plugins, each one after the plugins it depends on. Nothing is started if the dependencies form a cycle
+func (m *PluginManager) StartAll(names []string) error {
+	order, err := NewPluginDependencyGraph(m.manifests).Order()
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+	for _, name := range order {
+		if !selected[name] {
+			continue
+		}
+		if err := m.Start(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait updates the status of the plugin once its process exits
+func (m *PluginManager) wait(name string, p *ManagedProcess) {
+	err := p.cmd.Wait()
+	m.mu.RLock()
+	crashed, store := !p.stopping, m.state
+	m.mu.RUnlock()
+	// the store is written without the lock since it syncs to disk
+	var persisted *PluginState
+	crashedAt := time.Now()
+	if crashed && store != nil {
+		if state, storeErr := store.RecordCrash(name, crashedAt); storeErr != nil {
+			m.log.SubLogger.Warn().Msg(fmt.Sprintf("could not persist the crash of plugin %s: %v", name, storeErr))
+		} else {
+			persisted = &state
+		}
+	}
+	m.mu.Lock()
+	if !crashed {
+		p.Status = PluginStopped
+	} else {
+		p.Status = PluginFailed
+		if persisted != nil {
+			p.PluginState = *persisted
+		} else {
+			p.RestartCount++
+			p.LastCrash = crashedAt
+		}
+	}
+	status := p.Status
+	m.mu.Unlock()
+	close(p.done)
+	if status == PluginFailed {
+		m.log.SubLogger.Error().Msg(fmt.Sprintf("Plugin %s exited unexpectedly: %v", name, err))
+	} else {
+		m.log.SubLogger.Info().Msg(fmt.Sprintf("Plugin %s stopped", name))
+	}
+}
+
+// waitUntilReady marks the plugin as Running once its endpoint accepts connections
+func (m *PluginManager) waitUntilReady(name string, p *ManagedProcess) {
+	if p.Manifest.Endpoint != "" {
+		network, addr := endpointDialTarget(p.Manifest.Endpoint)
+		for {
+			conn, err := net.DialTimeout(network, addr, plugin_ready_interval)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			select {
+			case <-p.done:
+				return
+			case <-time.After(plugin_ready_interval):
+			}
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.Status == PluginStarting {
+		p.Status = PluginRunning
+		m.log.SubLogger.Info().Msg(fmt.Sprintf("Plugin %s is running", name))
+	}
+}
+
+// Status returns the status of the named plugin
+func (m *PluginManager) Status(name string) (PluginStatus, error) {
+	if _, ok := m.manifests[name]; !ok {
+		return "", ErrPluginNotFound
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.processes[name]
+	if !ok {
+		return PluginStopped, nil
+	}
+	return p.Status, nil
+}
+
+// State returns the crashes of the named plugin, as persisted when it isn't started yet
+func (m *PluginManager) State(name string) (PluginState, error) {
+	if _, ok := m.manifests[name]; !ok {
+		return PluginState{}, ErrPluginNotFound
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.processes[name]; ok {
+		return p.PluginState, nil
+	}
+	if m.state == nil {
+		return PluginState{}, nil
+	}
+	return m.state.Load(name)
+}
+
+// PluginInfo is a plugin as listed by the conduit_plugin_list method
+type PluginInfo struct {
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Status  PluginStatus `json:"status"`
+	// RestartCount is the number of times the plugin crashed
+	RestartCount int `json:"restart_count"`
+	// LastCrash is when the plugin last crashed, if it ever did
+	LastCrash *time.Time `json:"last_crash,omitempty"`
+}
+
+// List returns the status and crashes of every plugin, sorted by name
+func (m *PluginManager) List() ([]PluginInfo, error) {
+	names := make([]string, 0, len(m.manifests))
+	for name := range m.manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	plugins := make([]PluginInfo, 0, len(names))
+	for _, name := range names {
+		status, err := m.Status(name)
+		if err != nil {
+			return nil, err
+		}
+		state, err := m.State(name)
+		if err != nil {
+			return nil, err
+		}
+		info := PluginInfo{Name: name, Version: m.manifests[name].Version, Status: status, RestartCount: state.RestartCount}
+		if !state.LastCrash.IsZero() {
+			info.LastCrash = &state.LastCrash
+		}
+		plugins = append(plugins, info)
+	}
+	return plugins, nil
+}
+
+// RegisterPlugins registers the conduit_plugin_list method
+func (s *RPCServer) RegisterPlugins(plugins *PluginManager) {
+	s.Register("conduit_plugin_list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		list, err := plugins.List()
+		if err != nil {
+			return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, fmt.Sprintf("could not list the plugins: %v", err))
+		}
+		return list, nil
+	})
+}
+
+// StopAll interrupts every running plugin, kills those still running after 5 seconds and closes the IPC sockets
+func (m *PluginManager) StopAll() {
+	m.mu.Lock()
+	var running []*ManagedProcess
+	for _, p := range m.processes {
+		if p.Status == PluginStarting || p.Status == PluginRunning {
+			p.stopping = true
+			running = append(running, p)
+		}
+	}
+	m.mu.Unlock()
+	for _, p := range running {
+		if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+			p.cmd.Process.Kill()
+		}
+	}
+	for _, p := range running {
+		select {
+		case <-p.done:
+		case <-time.After(plugin_stop_timeout):
+			p.cmd.Process.Kill()
+			<-p.done
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ipcListening {
+		m.ipc.Close()
+		m.ipcListening = false
+	}
+}
+
+// endpointDialTarget returns the network and address to dial to reach a plugin endpoint
+func endpointDialTarget(endpoint string) (string, string) {
+	if strings.HasPrefix(endpoint, "unix://") {
+		return "unix", strings.TrimPrefix(endpoint, "unix://")
+	}
+	for _, scheme := range []string{"tcp://", "http://", "https://"} {
+		endpoint = strings.TrimPrefix(endpoint, scheme)
+	}
+	// drop any path of the endpoint URL
+	if i := strings.Index(endpoint, "/"); i != -1 {
+		endpoint = endpoint[:i]
+	}
+	return "tcp", endpoint
+}
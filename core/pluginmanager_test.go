@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestFakePlugin is not a real test. It's the fake plugin binary: launched by the PluginManager with CONDUIT_FAKE_PLUGIN set, it waits CONDUIT_FAKE_PLUGIN_DELAY, listens on CONDUIT_FAKE_PLUGIN_LISTEN and runs until interrupted. CONDUIT_FAKE_PLUGIN=crash exits right away and CONDUIT_FAKE_PLUGIN=ipc-echo sends back every IPC message
+func TestFakePlugin(t *testing.T) {
+	mode := os.Getenv("CONDUIT_FAKE_PLUGIN")
+	if mode == "" {
+		return
+	}
+	if mode == "crash" {
+		os.Exit(1)
+	}
+	if mode == "ipc-echo" {
+		client, err := NewPluginIPCClientFromEnv()
+		if err != nil {
+			os.Exit(3)
+		}
+		for {
+			from, msg, err := client.Recv()
+			if err != nil {
+				os.Exit(0)
+			}
+			client.Send(from, msg)
+		}
+	}
+	if delay, err := time.ParseDuration(os.Getenv("CONDUIT_FAKE_PLUGIN_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+	if addr := os.Getenv("CONDUIT_FAKE_PLUGIN_LISTEN"); addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			os.Exit(2)
+		}
+		defer listener.Close()
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	os.Exit(0)
+}
+
+// writeFakePluginManifest writes the manifest of a plugin running the fake plugin binary to the plugin directory of cfg
+func writeFakePluginManifest(t *testing.T, cfg *Config, manifest *PluginManifest) {
+	t.Helper()
+	manifest.Version = "0.1.0"
+	manifest.Executable = os.Args[0]
+	manifest.Args = []string{"-test.run=TestFakePlugin"}
+	raw, err := yaml.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Error marshalling manifest: %v", err)
+	}
+	if err = os.MkdirAll(PluginDir(cfg), 0775); err != nil {
+		t.Fatalf("Error creating plugin directory: %v", err)
+	}
+	if err = ioutil.WriteFile(path.Join(PluginDir(cfg), manifest.Name+".yaml"), raw, 0644); err != nil {
+		t.Fatalf("Error writing manifest: %v", err)
+	}
+}
+
+// freeTCPAddr returns a local address nothing listens on
+func freeTCPAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error finding a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// waitForStatus polls the status of the plugin until it matches or a second has passed
+func waitForStatus(m *PluginManager, name string, want PluginStatus) error {
+	var status PluginStatus
+	for i := 0; i < 100; i++ {
+		status, _ = m.Status(name)
+		if status == want {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("expected plugin %s to be %s, got %s", name, want, status)
+}
+
+// TestPluginManager ensures plugins are launched, reported as running once their endpoint is up, and stopped
+func TestPluginManager(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	addr := freeTCPAddr(t)
+	t.Setenv("CONDUIT_FAKE_PLUGIN_LISTEN", addr)
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "signer", Endpoint: "tcp://" + addr})
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "late", RequiresLNDReady: true})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	if names := m.Plugins(false); len(names) != 1 || names[0] != "signer" {
+		t.Errorf("expected only signer to start before LND, got %v", names)
+	}
+	if names := m.Plugins(true); len(names) != 1 || names[0] != "late" {
+		t.Errorf("expected only late to start after LND, got %v", names)
+	}
+	if status, _ := m.Status("signer"); status != PluginStopped {
+		t.Errorf("expected signer to be stopped before being started, got %s", status)
+	}
+	if err = m.Start("signer"); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err = m.Start("signer"); err == nil {
+		t.Errorf("expected starting a running plugin to fail")
+	}
+	if err = waitForStatus(m, "signer", PluginRunning); err != nil {
+		t.Fatal(err)
+	}
+	m.StopAll()
+	if status, _ := m.Status("signer"); status != PluginStopped {
+		t.Errorf("expected signer to be stopped, got %s", status)
+	}
+	if _, err = m.Status("unknown"); err != ErrPluginNotFound {
+		t.Errorf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+// TestPluginManagerCrash ensures a plugin exiting on its own is reported as failed
+func TestPluginManagerCrash(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "crash")
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "crasher", Endpoint: freeTCPAddr(t)})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	if err = m.Start("crasher"); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err = waitForStatus(m, "crasher", PluginFailed); err != nil {
+		t.Fatal(err)
+	}
+}
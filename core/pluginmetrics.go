@@ -0,0 +1,71 @@
+package core
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// plugin_metric_unknown_method is the method label of the calls to methods not declared in the plugin manifest, which keeps the
+// callers from creating a series per method name
+const plugin_metric_unknown_method = "unknown"
+
+// pluginMetricMethod returns the method label of a call to the method of the plugin
+func pluginMetricMethod(manifest *PluginManifest, method string) string {
+	for _, m := range manifest.Methods {
+		if m == method {
+			return method
+		}
+	}
+	return plugin_metric_unknown_method
+}
+
+// PluginMetrics counts the calls made to the plugins, their errors and their duration, per plugin and method
+type PluginMetrics struct {
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewPluginMetrics creates a new PluginMetrics and registers its collectors with the given registerer
+func NewPluginMetrics(registerer prometheus.Registerer) (*PluginMetrics, error) {
+	labels := []string{"plugin", "method"}
+	m := &PluginMetrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics_namespace,
+			Subsystem: "plugin",
+			Name:      "calls_total",
+			Help:      "Number of JSON-RPC calls made to a plugin",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics_namespace,
+			Subsystem: "plugin",
+			Name:      "errors_total",
+			Help:      "Number of JSON-RPC calls to a plugin which failed",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metrics_namespace,
+			Subsystem: "plugin",
+			Name:      "duration_seconds",
+			Help:      "Duration of the JSON-RPC calls made to a plugin",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+	if registerer != nil {
+		for _, c := range []prometheus.Collector{m.calls, m.errors, m.duration} {
+			if err := registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// Observe records a call to the method of the plugin which took duration and failed if err isn't nil
+func (m *PluginMetrics) Observe(plugin, method string, duration time.Duration, err error) {
+	m.calls.WithLabelValues(plugin, method).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(plugin, method).Inc()
+	}
+	m.duration.WithLabelValues(plugin, method).Observe(duration.Seconds())
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+)
+
+// TestPluginMetrics ensures calls, errors and durations are counted per plugin and method
+func TestPluginMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := NewPluginMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Observe("rebalancer", "run", 2*time.Second, nil)
+	m.Observe("rebalancer", "run", time.Second, errors.New("no route"))
+	m.Observe("rebalancer", "status", 10*time.Millisecond, nil)
+	m.Observe("watcher", "run", time.Millisecond, errors.New("timeout"))
+	expected := map[[2]string][2]float64{
+		{"rebalancer", "run"}:    {2, 1},
+		{"rebalancer", "status"}: {1, 0},
+		{"watcher", "run"}:       {1, 1},
+	}
+	for labels, counts := range expected {
+		if calls := testutil.ToFloat64(m.calls.WithLabelValues(labels[0], labels[1])); calls != counts[0] {
+			t.Errorf("%v: expected %v calls, got %v", labels, counts[0], calls)
+		}
+		if errs := testutil.ToFloat64(m.errors.WithLabelValues(labels[0], labels[1])); errs != counts[1] {
+			t.Errorf("%v: expected %v errors, got %v", labels, counts[1], errs)
+		}
+	}
+	if n := testutil.CollectAndCount(m.duration); n != 3 {
+		t.Errorf("expected 3 duration histograms, got %d", n)
+	}
+	if n, err := testutil.GatherAndCount(registry, "conduit_plugin_calls_total", "conduit_plugin_errors_total", "conduit_plugin_duration_seconds"); err != nil || n != 9 {
+		t.Errorf("expected the 9 series to be registered, got %d: %v", n, err)
+	}
+	if _, err = NewPluginMetrics(registry); err == nil {
+		t.Error("expected registering the metrics twice to fail")
+	}
+}
+
+// TestPluginCallMetrics ensures the calls forwarded by conduit_plugin_call are recorded, those to undeclared methods as calls to an
+// unknown method, and those to unknown plugins aren't
+func TestPluginCallMetrics(t *testing.T) {
+	log := zerolog.Nop()
+	s := NewRPCServer(&Config{ConduitDir: t.TempDir()}, &log)
+	m, err := NewPluginMetrics(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPluginMetrics(m)
+	ts := httptest.NewServer(s.Server)
+	defer ts.Close()
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := jsonrpc.NewServer()
+	plugin.Register("ping", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "pong", nil
+	})
+	ps := httptest.NewServer(plugin)
+	defer ps.Close()
+	if err = os.Mkdir(PluginDir(s.cfg), 0775); err != nil {
+		t.Fatal(err)
+	}
+	manifest := fmt.Sprintf("Name: pinger\nVersion: 0.1.0\nEndpoint: %s\nMethods: [ping]\n", ps.URL)
+	if err = ioutil.WriteFile(path.Join(PluginDir(s.cfg), "pinger.yaml"), []byte(manifest), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []map[string]string{
+		{"plugin": "pinger", "method": "ping"},
+		{"plugin": "pinger", "method": "ping"},
+		{"plugin": "pinger", "method": "missing"},
+		{"plugin": "pinger", "method": "undeclared-1"},
+		{"plugin": "unknown", "method": "ping"},
+	} {
+		client.Call(context.Background(), "conduit_plugin_call", call, nil)
+	}
+	if calls := testutil.ToFloat64(m.calls.WithLabelValues("pinger", "ping")); calls != 2 {
+		t.Errorf("expected 2 ping calls, got %v", calls)
+	}
+	if errs := testutil.ToFloat64(m.errors.WithLabelValues("pinger", plugin_metric_unknown_method)); errs != 2 {
+		t.Errorf("expected the calls to undeclared methods to be counted as errors of an unknown method, got %v", errs)
+	}
+	if n := testutil.CollectAndCount(m.calls); n != 2 {
+		t.Errorf("expected only the calls to pinger to be counted, got %d series", n)
+	}
+}
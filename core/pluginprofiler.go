@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const (
+	ErrPluginNoPprof           = errors.Error("plugin doesn't serve pprof")
+	plugin_profile_dir_name    = "profiles"
+	plugin_profile_timeout     = time.Minute
+	plugin_profile_max         = 256 * 1024 * 1024
+	plugin_profile_time_format = "20060102T150405Z"
+)
+
+// PluginHeapProfiler captures heap profiles from the plugins declaring a PprofPort in their manifest
+type PluginHeapProfiler struct {
+	pluginDir  string
+	profileDir string
+	host       string
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewPluginHeapProfiler creates a PluginHeapProfiler for the plugins of the plugin directory, saving profiles in the profile directory
+func NewPluginHeapProfiler(cfg *Config) *PluginHeapProfiler {
+	return &PluginHeapProfiler{
+		pluginDir:  PluginDir(cfg),
+		profileDir: PluginProfileDir(cfg),
+		host:       "localhost",
+		client:     &http.Client{Timeout: plugin_profile_timeout},
+		now:        time.Now,
+	}
+}
+
+// PluginProfileDir returns the directory where the profiles captured from the plugins are saved
+func PluginProfileDir(cfg *Config) string {
+	return path.Join(cfg.ConduitDir, plugin_profile_dir_name)
+}
+
+// CaptureHeap downloads a heap profile from the pprof server of the named plugin
+func (p *PluginHeapProfiler) CaptureHeap(plugin string) ([]byte, error) {
+	manifests, err := LoadPluginManifests(p.pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := manifests[plugin]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, plugin)
+	}
+	if m.PprofPort == 0 {
+		return nil, fmt.Errorf("%w: %s has no PprofPort in its manifest", ErrPluginNoPprof, plugin)
+	}
+	u := fmt.Sprintf("http://%s:%d/debug/pprof/heap", p.host, m.PprofPort)
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("could not capture heap profile of %s: %v", plugin, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not capture heap profile of %s: %v returned %v", plugin, u, resp.Status)
+	}
+	profile, err := ioutil.ReadAll(io.LimitReader(resp.Body, plugin_profile_max+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not capture heap profile of %s: %v", plugin, err)
+	}
+	if len(profile) > plugin_profile_max {
+		return nil, fmt.Errorf("heap profile of %s is larger than %d bytes", plugin, plugin_profile_max)
+	}
+	return profile, nil
+}
+
+// SaveHeap captures a heap profile of the named plugin and saves it as <plugin>-<timestamp>.pprof in the profile directory. It returns the
+// path of the profile
+func (p *PluginHeapProfiler) SaveHeap(plugin string) (string, error) {
+	profile, err := p.CaptureHeap(plugin)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(p.profileDir, 0700); err != nil {
+		return "", err
+	}
+	filename := path.Join(p.profileDir, fmt.Sprintf("%s-%s.pprof", plugin, p.now().UTC().Format(plugin_profile_time_format)))
+	if err = ioutil.WriteFile(filename, profile, 0600); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
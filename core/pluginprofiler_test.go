@@ -0,0 +1,73 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestPprofServer serves a fake heap profile and returns its port
+func newTestPprofServer(t *testing.T, profile []byte) int {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/heap", func(w http.ResponseWriter, r *http.Request) { w.Write(profile) })
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestPluginHeapProfiler ensures the profile is downloaded from the plugin's pprof server and saved in the profile directory
+func TestPluginHeapProfiler(t *testing.T) {
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "leaky", PprofPort: newTestPprofServer(t, []byte("heap profile"))})
+	profiler := NewPluginHeapProfiler(cfg)
+	profiler.now = func() time.Time { return time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC) }
+	filename, err := profiler.SaveHeap("leaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := path.Join(cfg.ConduitDir, "profiles", "leaky-20220304T050607Z.pprof"); filename != expected {
+		t.Errorf("expected the profile to be saved as %v, got %v", expected, filename)
+	}
+	if raw, err := os.ReadFile(filename); err != nil || string(raw) != "heap profile" {
+		t.Errorf("unexpected profile %q: %v", raw, err)
+	}
+}
+
+// TestPluginHeapProfilerErrors ensures plugins which are unknown, don't serve pprof or fail to answer aren't profiled
+func TestPluginHeapProfilerErrors(t *testing.T) {
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "quiet"})
+	// nothing is served on the port of a closed server
+	closed := httptest.NewServer(http.NotFoundHandler())
+	_, port, _ := net.SplitHostPort(closed.Listener.Addr().String())
+	closed.Close()
+	p, _ := strconv.Atoi(port)
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "gone", PprofPort: p})
+	profiler := NewPluginHeapProfiler(cfg)
+	if _, err := profiler.CaptureHeap("missing"); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("expected ErrPluginNotFound, got %v", err)
+	}
+	if _, err := profiler.CaptureHeap("quiet"); !errors.Is(err, ErrPluginNoPprof) {
+		t.Errorf("expected ErrPluginNoPprof, got %v", err)
+	}
+	if _, err := profiler.SaveHeap("gone"); err == nil {
+		t.Error("expected an error profiling a plugin which doesn't answer")
+	}
+	if _, err := os.Stat(PluginProfileDir(cfg)); !os.IsNotExist(err) {
+		t.Errorf("expected no profile directory, got %v", err)
+	}
+}
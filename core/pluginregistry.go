@@ -0,0 +1,166 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	ErrPluginChecksumMismatch = errors.Error("plugin checksum mismatch")
+	ErrPluginNoBinary         = errors.Error("no plugin binary for this platform")
+	ErrPluginNoRegistry       = errors.Error("no plugin registry")
+	// PluginLatestVersion is the version installed when none is given, served by the registry as an alias of the latest release
+	PluginLatestVersion     = "latest"
+	plugin_registry_timeout = 5 * time.Minute
+	plugin_manifest_max     = 1024 * 1024
+	plugin_binary_max       = 512 * 1024 * 1024
+)
+
+// PluginBinary is the binary of a plugin for one platform in the registry
+type PluginBinary struct {
+	// URL of the binary, relative to the manifest unless absolute
+	URL string `json:"URL"`
+	// SHA256 is the hex checksum of the binary
+	SHA256 string `json:"SHA256"`
+}
+
+// PluginRegistryManifest is the manifest.json of a plugin version in the registry: the sidecar manifest of the plugin in JSON, along with its binaries keyed by GOOS/GOARCH, i.e. linux/amd64
+type PluginRegistryManifest struct {
+	PluginManifest
+	Binaries map[string]PluginBinary `json:"Binaries"`
+}
+
+// PluginAutoInstaller installs plugins from a registry serving <RegistryURL>/plugins/<name>/<version>/manifest.json
+type PluginAutoInstaller struct {
+	registry string
+	dir      string
+	platform string
+	client   *http.Client
+}
+
+// NewPluginAutoInstaller creates a PluginAutoInstaller installing from Config.PluginRegistryURL into the plugin directory. There's no
+// default registry: the checksums come from the registry itself, so it must be one the operator trusts
+func NewPluginAutoInstaller(cfg *Config) *PluginAutoInstaller {
+	return &PluginAutoInstaller{
+		registry: strings.TrimSuffix(cfg.PluginRegistryURL, "/"),
+		dir:      PluginDir(cfg),
+		platform: runtime.GOOS + "/" + runtime.GOARCH,
+		client:   &http.Client{Timeout: plugin_registry_timeout},
+	}
+}
+
+// get downloads the body of the URL, failing when it's larger than max bytes
+func (i *PluginAutoInstaller) get(u string, max int64) ([]byte, error) {
+	resp, err := i.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %v: %v", u, resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("%v is larger than %d bytes", u, max)
+	}
+	return body, nil
+}
+
+// Install downloads the given version of the named plugin and its binary for the current platform, verifies the binary's checksum
+// and writes it along with its sidecar manifest in the plugin directory. Installing an installed plugin replaces it
+func (i *PluginAutoInstaller) Install(name, version string) error {
+	if i.registry == "" {
+		return fmt.Errorf("%w: set PluginRegistryURL in config.yaml to a registry you trust", ErrPluginNoRegistry)
+	}
+	if !pluginNameRegex.MatchString(name) {
+		return fmt.Errorf("%w: %q must only contain lowercase letters, digits and dashes", ErrInvalidPluginManifest, name)
+	}
+	if version == "" {
+		version = PluginLatestVersion
+	}
+	manifestURL := fmt.Sprintf("%s/plugins/%s/%s/manifest.json", i.registry, name, url.PathEscape(version))
+	raw, err := i.get(manifestURL, plugin_manifest_max)
+	if err != nil {
+		return err
+	}
+	var manifest PluginRegistryManifest
+	if err = json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, manifestURL, err)
+	}
+	if manifest.Name != name {
+		return fmt.Errorf("%w %v: expected plugin %q, got %q", ErrInvalidPluginManifest, manifestURL, name, manifest.Name)
+	}
+	if version != PluginLatestVersion && manifest.Version != version {
+		return fmt.Errorf("%w %v: expected version %q, got %q", ErrInvalidPluginManifest, manifestURL, version, manifest.Version)
+	}
+	binary, ok := manifest.Binaries[i.platform]
+	if !ok {
+		return fmt.Errorf("%w: %s %s has no %s binary", ErrPluginNoBinary, name, manifest.Version, i.platform)
+	}
+	base, _ := url.Parse(manifestURL)
+	binaryURL, err := base.Parse(binary.URL)
+	if err != nil {
+		return fmt.Errorf("%w %v: invalid binary URL %q: %v", ErrInvalidPluginManifest, manifestURL, binary.URL, err)
+	}
+	data, err := i.get(binaryURL.String(), plugin_binary_max)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, binary.SHA256) {
+		return fmt.Errorf("%w: %v has checksum %v, expected %v", ErrPluginChecksumMismatch, binaryURL, got, binary.SHA256)
+	}
+	// the binary is launched by Conduit from the plugin directory, see PluginManager.Start
+	executable := name
+	if strings.HasPrefix(i.platform, "windows/") {
+		executable += ".exe"
+	}
+	sidecar := manifest.PluginManifest
+	sidecar.Executable = executable
+	validator := NewPluginManifestValidator()
+	if errs := validator.Validate(&sidecar); len(errs) > 0 {
+		return fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, manifestURL, joinValidationErrors(errs))
+	}
+	// the plugins it depends on must be installed first
+	installed, err := LoadPluginManifests(i.dir)
+	if err != nil {
+		return err
+	}
+	installed[name] = &sidecar
+	if errs := validator.ValidateDependencies(&sidecar, installed); len(errs) > 0 {
+		return fmt.Errorf("%w %v: %v", ErrInvalidPluginManifest, manifestURL, joinValidationErrors(errs))
+	}
+	sidecarYAML, err := yaml.Marshal(&sidecar)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(i.dir, 0700); err != nil {
+		return err
+	}
+	if err = utils.AtomicWriteFile(path.Join(i.dir, executable), data, 0755); err != nil {
+		return fmt.Errorf("could not write plugin binary: %v", err)
+	}
+	// the manifest is written last so that the plugin is only loaded once its binary is in place
+	if err = utils.AtomicWriteFile(path.Join(i.dir, name+".yaml"), sidecarYAML, 0600); err != nil {
+		return fmt.Errorf("could not write plugin manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+)
+
+// newTestRegistry serves version 1.2.0 of the echo plugin, also as its latest version, with a binary for the current platform and a plan9 one with a wrong checksum
+func newTestRegistry(t *testing.T, binary []byte) *httptest.Server {
+	sum := sha256.Sum256(binary)
+	manifest := PluginRegistryManifest{
+		PluginManifest: PluginManifest{Name: "echo", Version: "1.2.0", Endpoint: "localhost:9090", Args: []string{"--port", "9090"}, RequiresLNDReady: true},
+		Binaries: map[string]PluginBinary{
+			runtime.GOOS + "/" + runtime.GOARCH: {URL: "echo.bin", SHA256: hex.EncodeToString(sum[:])},
+			"plan9/386":                         {URL: "/plugins/echo/1.2.0/echo.bin", SHA256: hex.EncodeToString(make([]byte, 32))},
+		},
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	for _, version := range []string{"1.2.0", PluginLatestVersion} {
+		mux.HandleFunc("/plugins/echo/"+version+"/manifest.json", func(w http.ResponseWriter, r *http.Request) { w.Write(raw) })
+		mux.HandleFunc("/plugins/echo/"+version+"/echo.bin", func(w http.ResponseWriter, r *http.Request) { w.Write(binary) })
+	}
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestPluginAutoInstaller ensures the binary and the sidecar manifest are written to the plugin directory and loaded as any other plugin
+func TestPluginAutoInstaller(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho hello\n")
+	ts := newTestRegistry(t, binary)
+	cfg := &Config{ConduitDir: t.TempDir(), PluginRegistryURL: ts.URL + "/"}
+	installer := NewPluginAutoInstaller(cfg)
+	if err := installer.Install("echo", ""); err != nil {
+		t.Fatal(err)
+	}
+	executable := "echo"
+	if runtime.GOOS == "windows" {
+		executable += ".exe"
+	}
+	raw, err := os.ReadFile(path.Join(PluginDir(cfg), executable))
+	if err != nil || string(raw) != string(binary) {
+		t.Fatalf("unexpected plugin binary %q: %v", raw, err)
+	}
+	if info, _ := os.Stat(path.Join(PluginDir(cfg), executable)); runtime.GOOS != "windows" && info.Mode().Perm()&0100 == 0 {
+		t.Errorf("plugin binary isn't executable: %v", info.Mode())
+	}
+	manifest, err := LoadPluginManifest(cfg, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != "1.2.0" || manifest.Executable != executable || manifest.Endpoint != "localhost:9090" || len(manifest.Args) != 2 || !manifest.RequiresLNDReady {
+		t.Errorf("unexpected sidecar manifest: %+v", manifest)
+	}
+	if err = installer.Install("echo", "1.2.0"); err != nil {
+		t.Errorf("could not reinstall the plugin: %v", err)
+	}
+}
+
+// TestPluginAutoInstallerErrors ensures nothing is installed when the plugin can't be found, has no binary for the platform or the binary is corrupted
+func TestPluginAutoInstallerErrors(t *testing.T) {
+	ts := newTestRegistry(t, []byte("binary"))
+	cfg := &Config{ConduitDir: t.TempDir(), PluginRegistryURL: ts.URL}
+	installer := NewPluginAutoInstaller(cfg)
+	if err := installer.Install("echo", "1.3.0"); err == nil {
+		t.Error("expected an error installing a missing version")
+	}
+	if err := installer.Install("../echo", "1.2.0"); !errors.Is(err, ErrInvalidPluginManifest) {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+	installer.platform = "freebsd/arm64"
+	if err := installer.Install("echo", "1.2.0"); !errors.Is(err, ErrPluginNoBinary) {
+		t.Errorf("expected a missing binary error, got %v", err)
+	}
+	installer.platform = "plan9/386"
+	if err := installer.Install("echo", "1.2.0"); !errors.Is(err, ErrPluginChecksumMismatch) {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+	if _, err := os.Stat(PluginDir(cfg)); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be installed, got %v", err)
+	}
+	if err := NewPluginAutoInstaller(&Config{ConduitDir: cfg.ConduitDir}).Install("echo", ""); !errors.Is(err, ErrPluginNoRegistry) {
+		t.Errorf("expected a missing registry error without PluginRegistryURL, got %v", err)
+	}
+}
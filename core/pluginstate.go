@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	plugin_state_key_prefix      = "plugins/"
+	plugin_state_restart_count   = "/restart_count"
+	plugin_state_last_crash      = "/last_crash"
+	plugin_state_last_crash_form = time.RFC3339Nano
+)
+
+// PluginState is what is known of the crashes of a plugin over the runs of Conduit
+type PluginState struct {
+	// RestartCount is the number of times the plugin crashed
+	RestartCount int
+	// LastCrash is when the plugin last crashed, zero if it never did
+	LastCrash time.Time
+}
+
+// PluginStateStore persists the crashes of the plugins in the MetadataStore, so that their crash rate can be followed across Conduit restarts
+type PluginStateStore struct {
+	store *MetadataStore
+}
+
+// NewPluginStateStore creates a PluginStateStore keeping the plugin states in the given store
+func NewPluginStateStore(store *MetadataStore) *PluginStateStore {
+	return &PluginStateStore{store: store}
+}
+
+// Load returns the state of the named plugin, which is zero for a plugin which never crashed
+func (s *PluginStateStore) Load(name string) (PluginState, error) {
+	var state PluginState
+	raw, err := s.store.Get(plugin_state_key_prefix + name + plugin_state_restart_count)
+	if err == nil {
+		if state.RestartCount, err = strconv.Atoi(string(raw)); err != nil {
+			return PluginState{}, fmt.Errorf("invalid restart count of plugin %s: %v", name, err)
+		}
+	} else if err != ErrKeyNotFound {
+		return PluginState{}, err
+	}
+	raw, err = s.store.Get(plugin_state_key_prefix + name + plugin_state_last_crash)
+	if err == nil {
+		if state.LastCrash, err = time.Parse(plugin_state_last_crash_form, string(raw)); err != nil {
+			return PluginState{}, fmt.Errorf("invalid last crash time of plugin %s: %v", name, err)
+		}
+	} else if err != ErrKeyNotFound {
+		return PluginState{}, err
+	}
+	return state, nil
+}
+
+// RecordCrash increments the restart count of the named plugin, records when it crashed and returns its new state
+func (s *PluginStateStore) RecordCrash(name string, at time.Time) (PluginState, error) {
+	state, err := s.Load(name)
+	if err != nil {
+		return PluginState{}, err
+	}
+	state.RestartCount++
+	state.LastCrash = at
+	if err = s.store.Put(plugin_state_key_prefix+name+plugin_state_restart_count, []byte(strconv.Itoa(state.RestartCount))); err != nil {
+		return PluginState{}, err
+	}
+	if err = s.store.Put(plugin_state_key_prefix+name+plugin_state_last_crash, []byte(at.UTC().Format(plugin_state_last_crash_form))); err != nil {
+		return PluginState{}, err
+	}
+	return state, nil
+}
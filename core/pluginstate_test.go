@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// crashPlugin starts the crasher plugin of a new PluginManager persisting its crashes in the store at storePath, and returns its state once it crashed
+func crashPlugin(t *testing.T, cfg *Config, storePath string) PluginState {
+	t.Helper()
+	store, err := OpenMetadataStore(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	defer m.StopAll()
+	m.SetStateStore(NewPluginStateStore(store))
+	if err = m.Start("crasher"); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err = waitForStatus(m, "crasher", PluginFailed); err != nil {
+		t.Fatal(err)
+	}
+	state, err := m.State("crasher")
+	if err != nil {
+		t.Fatalf("State returned an error: %v", err)
+	}
+	return state
+}
+
+// TestPluginStatePersistence ensures the crashes of a plugin are counted across PluginManager and store restarts
+func TestPluginStatePersistence(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "crash")
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "crasher", Endpoint: freeTCPAddr(t)})
+	storePath := path.Join(t.TempDir(), metadata_file_name)
+	before := time.Now()
+	first := crashPlugin(t, cfg, storePath)
+	if first.RestartCount != 1 || first.LastCrash.Before(before) {
+		t.Fatalf("expected one crash after %v, got %d at %v", before, first.RestartCount, first.LastCrash)
+	}
+	second := crashPlugin(t, cfg, storePath)
+	if second.RestartCount != 2 || !second.LastCrash.After(first.LastCrash) {
+		t.Errorf("expected a second crash after %v, got %d at %v", first.LastCrash, second.RestartCount, second.LastCrash)
+	}
+	store, err := OpenMetadataStore(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	state, err := NewPluginStateStore(store).Load("crasher")
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if state.RestartCount != 2 || !state.LastCrash.Equal(second.LastCrash) {
+		t.Errorf("expected the stored state to be %+v, got %+v", second, state)
+	}
+	if state, err = NewPluginStateStore(store).Load("unknown"); err != nil || state.RestartCount != 0 || !state.LastCrash.IsZero() {
+		t.Errorf("expected a plugin which never crashed to have a zero state, got %+v: %v", state, err)
+	}
+}
+
+// TestPluginStateWithoutStore ensures crashes are still counted in memory when no PluginStateStore is set
+func TestPluginStateWithoutStore(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "crash")
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "crasher", Endpoint: freeTCPAddr(t)})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err = m.Start("crasher"); err != nil {
+			t.Fatalf("Start returned an error: %v", err)
+		}
+		if err = waitForStatus(m, "crasher", PluginFailed); err != nil {
+			t.Fatal(err)
+		}
+		if state, _ := m.State("crasher"); state.RestartCount != i {
+			t.Errorf("expected %d crashes, got %d", i, state.RestartCount)
+		}
+	}
+}
+
+// TestPluginListRPC ensures conduit_plugin_list reports the status and crashes of every plugin
+func TestPluginListRPC(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "crash")
+	s, client := newTestRPCServer(t)
+	writeFakePluginManifest(t, s.cfg, &PluginManifest{Name: "crasher", Version: "0.1.0", Endpoint: freeTCPAddr(t)})
+	writeFakePluginManifest(t, s.cfg, &PluginManifest{Name: "idle", Version: "0.2.0", Endpoint: freeTCPAddr(t)})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(s.cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	s.RegisterPlugins(m)
+	before := time.Now()
+	if err = m.Start("crasher"); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err = waitForStatus(m, "crasher", PluginFailed); err != nil {
+		t.Fatal(err)
+	}
+	var plugins []PluginInfo
+	if err := client.Call(context.Background(), "conduit_plugin_list", nil, &plugins); err != nil {
+		t.Fatalf("conduit_plugin_list returned an error: %v", err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("expected 2 plugins, got %+v", plugins)
+	}
+	if p := plugins[0]; p.Name != "crasher" || p.Version != "0.1.0" || p.Status != PluginFailed || p.RestartCount != 1 || p.LastCrash == nil || p.LastCrash.Before(before) {
+		t.Errorf("unexpected crashed plugin: %+v", p)
+	}
+	if p := plugins[1]; p.Name != "idle" || p.Status != PluginStopped || p.RestartCount != 0 || p.LastCrash != nil {
+		t.Errorf("unexpected idle plugin: %+v", p)
+	}
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/blang/semver/v4"
+)
+
+const (
+	ErrInvalidPluginManifest = errors.Error("invalid plugin manifest")
+	// shell_metacharacters can't appear in plugin arguments. Arguments are never passed through a shell, so they are most likely a mistake
+	shell_metacharacters = "|&;<>()$`\\\"'*?[]{}~#!\n"
+)
+
+// pluginNameRegex matches the valid plugin names
+var pluginNameRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// ValidationError is a problem found in a field of a plugin manifest
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// PluginManifestValidator checks plugin manifests before they are loaded
+type PluginManifestValidator struct{}
+
+// NewPluginManifestValidator creates a new PluginManifestValidator
+func NewPluginManifestValidator() *PluginManifestValidator {
+	return &PluginManifestValidator{}
+}
+
+// Validate returns every problem found in the manifest, or nil if it's valid. Its dependencies are checked against the other manifests by ValidateDependencies
+func (v *PluginManifestValidator) Validate(m *PluginManifest) []ValidationError {
+	var errs []ValidationError
+	if m.Name == "" {
+		errs = append(errs, ValidationError{"Name", "is required"})
+	} else if !pluginNameRegex.MatchString(m.Name) {
+		errs = append(errs, ValidationError{"Name", fmt.Sprintf("%q must only contain lowercase letters, digits and dashes", m.Name)})
+	}
+	if _, err := semver.Parse(m.Version); err != nil {
+		errs = append(errs, ValidationError{"Version", fmt.Sprintf("%q is not a valid semantic version: %v", m.Version, err)})
+	}
+	for i, arg := range m.Args {
+		if j := strings.IndexAny(arg, shell_metacharacters); j != -1 {
+			errs = append(errs, ValidationError{fmt.Sprintf("Args[%d]", i), fmt.Sprintf("%q contains the shell metacharacter %q", arg, arg[j])})
+		}
+	}
+	for i, dep := range m.DependsOn {
+		if !pluginNameRegex.MatchString(dep) {
+			errs = append(errs, ValidationError{fmt.Sprintf("DependsOn[%d]", i), fmt.Sprintf("%q is not a valid plugin name", dep)})
+		}
+	}
+	if len(m.LNDCapabilities) > 0 {
+		if _, err := parseCapabilities(m.LNDCapabilities); err != nil {
+			errs = append(errs, ValidationError{"LNDCapabilities", err.Error()})
+		}
+		if !m.RequiresLNDReady {
+			errs = append(errs, ValidationError{"LNDCapabilities", "requires RequiresLNDReady since macaroons are baked once LND is active"})
+		}
+	}
+	if m.PprofPort < 0 || m.PprofPort > 65535 {
+		errs = append(errs, ValidationError{"PprofPort", fmt.Sprintf("%d is not a valid port", m.PprofPort)})
+	}
+	limits := []struct {
+		field string
+		value int
+	}{
+		{"ResourceLimits.MaxMemoryMB", m.ResourceLimits.MaxMemoryMB},
+		{"ResourceLimits.MaxOpenFiles", m.ResourceLimits.MaxOpenFiles},
+		{"ResourceLimits.MaxCPUSeconds", m.ResourceLimits.MaxCPUSeconds},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			errs = append(errs, ValidationError{limit.field, fmt.Sprintf("%d can't be negative", limit.value)})
+		}
+	}
+	if m.HealthCheck.Endpoint != "" {
+		if u, err := url.Parse(m.HealthCheck.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, ValidationError{"HealthCheck.Endpoint", fmt.Sprintf("%q is not a valid URL", m.HealthCheck.Endpoint)})
+		}
+	}
+	return errs
+}
+
+// ValidateDependencies returns a problem for every plugin the manifest depends on which isn't one of the given manifests
+func (v *PluginManifestValidator) ValidateDependencies(m *PluginManifest, manifests map[string]*PluginManifest) []ValidationError {
+	var errs []ValidationError
+	for i, dep := range m.DependsOn {
+		if _, ok := manifests[dep]; !ok {
+			errs = append(errs, ValidationError{fmt.Sprintf("DependsOn[%d]", i), fmt.Sprintf("plugin %q not found", dep)})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestPluginManifestValidator checks every rule against malformed manifests
+func TestPluginManifestValidator(t *testing.T) {
+	valid := func() *PluginManifest {
+		return &PluginManifest{
+			Name:        "rebalancer-2",
+			Version:     "1.2.3-beta.1+build.5",
+			Args:        []string{"--interval=5m", "-v"},
+			DependsOn:   []string{"signer"},
+			HealthCheck: PluginHealthCheck{Endpoint: "http://localhost:8080/health"},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(m *PluginManifest)
+		fields []string
+	}{
+		{"valid", func(m *PluginManifest) {}, nil},
+		{"missing name", func(m *PluginManifest) { m.Name = "" }, []string{"Name"}},
+		{"uppercase name", func(m *PluginManifest) { m.Name = "Rebalancer" }, []string{"Name"}},
+		{"name with a space", func(m *PluginManifest) { m.Name = "re balancer" }, []string{"Name"}},
+		{"missing version", func(m *PluginManifest) { m.Version = "" }, []string{"Version"}},
+		{"v prefixed version", func(m *PluginManifest) { m.Version = "v1.2.3" }, []string{"Version"}},
+		{"partial version", func(m *PluginManifest) { m.Version = "1.2" }, []string{"Version"}},
+		{"pipe in args", func(m *PluginManifest) { m.Args = []string{"-v", "--out=a|b"} }, []string{"Args[1]"}},
+		{"command substitution in args", func(m *PluginManifest) { m.Args = []string{"$(rm -rf /)"} }, []string{"Args[0]"}},
+		{"path as dependency", func(m *PluginManifest) { m.DependsOn = []string{"../signer"} }, []string{"DependsOn[0]"}},
+		{"relative health check", func(m *PluginManifest) { m.HealthCheck.Endpoint = "/health" }, []string{"HealthCheck.Endpoint"}},
+		{"malformed health check", func(m *PluginManifest) { m.HealthCheck.Endpoint = "http://[::1" }, []string{"HealthCheck.Endpoint"}},
+		{"capabilities", func(m *PluginManifest) {
+			m.LNDCapabilities, m.RequiresLNDReady = []string{"offchain:read", "invoices:write"}, true
+		}, nil},
+		{"malformed capability", func(m *PluginManifest) { m.LNDCapabilities, m.RequiresLNDReady = []string{"offchain"}, true }, []string{"LNDCapabilities"}},
+		{"capabilities before LND", func(m *PluginManifest) { m.LNDCapabilities = []string{"offchain:read"} }, []string{"LNDCapabilities"}},
+		{"pprof port", func(m *PluginManifest) { m.PprofPort = 6060 }, nil},
+		{"invalid pprof port", func(m *PluginManifest) { m.PprofPort = 65536 }, []string{"PprofPort"}},
+		{"resource limits", func(m *PluginManifest) { m.ResourceLimits = ResourceLimits{MaxMemoryMB: 512, MaxCPUSeconds: 3600} }, nil},
+		{"negative resource limits", func(m *PluginManifest) {
+			m.ResourceLimits = ResourceLimits{MaxMemoryMB: -1, MaxOpenFiles: -1}
+		}, []string{"ResourceLimits.MaxMemoryMB", "ResourceLimits.MaxOpenFiles"}},
+		{"everything wrong", func(m *PluginManifest) {
+			*m = PluginManifest{Args: []string{"a;b"}, DependsOn: []string{"x"}, HealthCheck: PluginHealthCheck{Endpoint: "nope"}}
+		}, []string{"Name", "Version", "Args[0]", "HealthCheck.Endpoint"}},
+	}
+	validator := NewPluginManifestValidator()
+	for _, test := range tests {
+		m := valid()
+		test.modify(m)
+		var fields []string
+		for _, err := range validator.Validate(m) {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("%s: expected errors on %v, got %v", test.name, test.fields, validator.Validate(m))
+		}
+	}
+}
+
+// TestPluginManifestValidatorDependencies ensures the dependencies are checked against the other manifests
+func TestPluginManifestValidatorDependencies(t *testing.T) {
+	manifests := map[string]*PluginManifest{"signer": {Name: "signer"}}
+	validator := NewPluginManifestValidator()
+	if errs := validator.ValidateDependencies(&PluginManifest{DependsOn: []string{"signer"}}, manifests); errs != nil {
+		t.Errorf("expected a known dependency to be valid, got %v", errs)
+	}
+	errs := validator.ValidateDependencies(&PluginManifest{DependsOn: []string{"signer", "missing"}}, manifests)
+	if len(errs) != 1 || errs[0].Field != "DependsOn[1]" {
+		t.Errorf("expected an error on DependsOn[1], got %v", errs)
+	}
+}
+
+// TestLoadPluginManifestsMissingDependency ensures the plugins whose dependencies are missing are left out without failing the load
+func TestLoadPluginManifestsMissingDependency(t *testing.T) {
+	dir := t.TempDir()
+	for name, manifest := range map[string]string{
+		// the manifest file name doesn't have to be the plugin name
+		"signer-v1.yaml":  "Name: signer\nVersion: 1.0.0\n",
+		"rebalancer.yaml": "Name: rebalancer\nVersion: 1.0.0\nDependsOn: [signer]\n",
+		"watcher.yaml":    "Name: watcher\nVersion: 1.0.0\nDependsOn: [missing]\n",
+		"reporter.yaml":   "Name: reporter\nVersion: 1.0.0\nDependsOn: [watcher]\n",
+	} {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifests, skipped, err := loadPluginManifests(dir)
+	if err != nil {
+		t.Fatalf("expected the load to succeed, got %v", err)
+	}
+	var names []string
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"rebalancer", "signer"}) {
+		t.Errorf("expected only rebalancer and signer to be loaded, got %v", names)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected watcher and reporter to be left out, got %v", skipped)
+	}
+}
+
+// TestLoadPluginManifestsInvalid ensures unknown fields and invalid manifests are rejected when loading
+func TestLoadPluginManifestsInvalid(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"unknown field": "Name: signer\nVersion: 1.0.0\nExecutible: signer\n",
+		"invalid":       "Name: Signer\nVersion: 1.0\n",
+	} {
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(path.Join(dir, "signer.yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadPluginManifests(dir)
+		if err == nil || !strings.Contains(err.Error(), string(ErrInvalidPluginManifest)+" signer.yaml") {
+			t.Errorf("%s: expected ErrInvalidPluginManifest, got %v", name, err)
+		}
+	}
+	if _, err := LoadPluginManifests(path.Join(os.TempDir(), "conduit-no-such-dir")); err != nil {
+		t.Errorf("expected no error for a missing plugin directory, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/blang/semver/v4"
+)
+
+const (
+	ErrPluginIncompatible = errors.Error("plugin is not compatible with this version of Conduit")
+)
+
+// PluginVersionConstraintChecker checks that plugins are compatible with the running version of Conduit
+type PluginVersionConstraintChecker struct {
+	version semver.Version
+}
+
+// NewPluginVersionConstraintChecker creates a new PluginVersionConstraintChecker for the given Conduit version, usually utils.AppVersion
+func NewPluginVersionConstraintChecker(version string) (*PluginVersionConstraintChecker, error) {
+	v, err := semver.Parse(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Conduit version %q: %v", version, err)
+	}
+	return &PluginVersionConstraintChecker{version: v}, nil
+}
+
+// Check returns an error if the plugin declares a range of Conduit versions which is invalid or doesn't include the running version. Plugins without one are compatible with every version
+func (c *PluginVersionConstraintChecker) Check(m *PluginManifest) error {
+	if m.Requires.Conduit == "" {
+		return nil
+	}
+	constraint, err := semver.ParseRange(m.Requires.Conduit)
+	if err != nil {
+		return fmt.Errorf("%w %v: Requires.Conduit %q is not a valid version range: %v", ErrInvalidPluginManifest, m.Name, m.Requires.Conduit, err)
+	}
+	if !constraint(c.version) {
+		return fmt.Errorf("%w: %s requires Conduit %s but this is Conduit %s", ErrPluginIncompatible, m.Name, m.Requires.Conduit, c.version)
+	}
+	return nil
+}
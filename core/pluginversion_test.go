@@ -0,0 +1,68 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/utils"
+	"github.com/rs/zerolog"
+)
+
+// TestPluginVersionConstraintChecker ensures version ranges are enforced around major version bumps
+func TestPluginVersionConstraintChecker(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		ok         bool
+	}{
+		{"0.1.0", "", true},
+		{"0.1.0", ">=0.2.0 <1.0.0", false},
+		{"0.2.0", ">=0.2.0 <1.0.0", true},
+		{"0.99.99", ">=0.2.0 <1.0.0", true},
+		// pre-releases of the next major version come before it
+		{"1.0.0-rc.1", ">=0.2.0 <1.0.0", true},
+		{"1.0.0", ">=0.2.0 <1.0.0", false},
+		{"1.0.0", ">=1.0.0 <2.0.0", true},
+		{"1.9.3", ">=1.0.0 <2.0.0", true},
+		{"2.0.0", ">=1.0.0 <2.0.0", false},
+		{"2.0.0", "<1.0.0 || >=2.0.0", true},
+		{"0.9.0", ">0.9.0", false},
+	}
+	for _, test := range tests {
+		checker, err := NewPluginVersionConstraintChecker(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checker.Check(&PluginManifest{Name: "plugin", Requires: PluginRequirements{Conduit: test.constraint}})
+		if test.ok && err != nil {
+			t.Errorf("expected Conduit %s to satisfy %q, got %v", test.version, test.constraint, err)
+		} else if !test.ok && !errors.Is(err, ErrPluginIncompatible) {
+			t.Errorf("expected Conduit %s not to satisfy %q, got %v", test.version, test.constraint, err)
+		}
+	}
+	checker, _ := NewPluginVersionConstraintChecker("0.1.0")
+	if err := checker.Check(&PluginManifest{Name: "plugin", Requires: PluginRequirements{Conduit: "^0.1 maybe"}}); !errors.Is(err, ErrInvalidPluginManifest) {
+		t.Errorf("expected an invalid range to be rejected, got %v", err)
+	}
+	if _, err := NewPluginVersionConstraintChecker("v1"); err == nil {
+		t.Error("expected an invalid Conduit version to be rejected")
+	}
+}
+
+// TestPluginManagerIncompatible ensures an incompatible plugin fails the load and the versions are logged
+func TestPluginManagerIncompatible(t *testing.T) {
+	cfg := &Config{ConduitDir: t.TempDir()}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "future", Requires: PluginRequirements{Conduit: ">=99.0.0"}})
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	if _, err := NewPluginManager(cfg, &log); !errors.Is(err, ErrPluginIncompatible) {
+		t.Fatalf("expected ErrPluginIncompatible, got %v", err)
+	}
+	for _, want := range []string{`"conduit_version":"` + utils.AppVersion + `"`, `"constraint":">=99.0.0"`, `"plugin":"future"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in the log, got %s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ErrNoFreePort = errors.Error("no free port found")
+	max_port      = 65535
+)
+
+// default_lnd_ports are the ports LND listens on when no listeners are configured
+var default_lnd_ports = []struct {
+	name string
+	port int
+}{
+	{"listen", 9735},
+	{"rpclisten", 10009},
+	{"restlisten", 8080},
+}
+
+// PortAllocator finds free TCP ports on a given host
+type PortAllocator struct {
+	Host string
+}
+
+// NewPortAllocator creates a new PortAllocator for the given host. An empty host means all interfaces
+func NewPortAllocator(host string) *PortAllocator {
+	return &PortAllocator{Host: host}
+}
+
+// IsFree reports whether the given TCP port can be bound
+func (p *PortAllocator) IsFree(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort(p.Host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// Allocate returns preferredPort if it's free, otherwise the next free port above it
+func (p *PortAllocator) Allocate(preferredPort int) (int, error) {
+	return p.AllocateRange(preferredPort, max_port)
+}
+
+// AllocateRange returns the first free port between start and end inclusively
+func (p *PortAllocator) AllocateRange(start, end int) (int, error) {
+	if start < 1 || end > max_port || start > end {
+		return 0, fmt.Errorf("invalid port range %d-%d", start, end)
+	}
+	for port := start; port <= end; port++ {
+		if p.IsFree(port) {
+			return port, nil
+		}
+	}
+	return 0, ErrNoFreePort
+}
+
+// checkLndPorts warns about any of LND's default ports already in use and suggests a free alternative.
+// Ports are only checked for listeners which aren't explicitly configured
+func checkLndPorts(cfg *Config, log *zerolog.Logger) {
+	configured := map[string]bool{
+		"listen":     len(cfg.LndRawListeners) != 0 || cfg.LndDisableListen,
+		"rpclisten":  len(cfg.LndRawRPCListeners) != 0,
+		"restlisten": len(cfg.LndRawRESTListeners) != 0 || cfg.LndDisableRest,
+	}
+	allocator := NewPortAllocator("")
+	for _, p := range default_lnd_ports {
+		if configured[p.name] || allocator.IsFree(p.port) {
+			continue
+		}
+		if alt, err := allocator.Allocate(p.port + 1); err == nil {
+			log.Warn().Msg(fmt.Sprintf("Port %d used by LND's default --%s is already in use. Consider setting --%s=localhost:%d", p.port, p.name, p.name, alt))
+		} else {
+			log.Warn().Msg(fmt.Sprintf("Port %d used by LND's default --%s is already in use", p.port, p.name))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"net"
+	"testing"
+)
+
+// TestPortAllocator binds a port and verifies Allocate skips it
+func TestPortAllocator(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error binding port: %v", err)
+	}
+	defer l.Close()
+	taken := l.Addr().(*net.TCPAddr).Port
+	allocator := NewPortAllocator("127.0.0.1")
+	if allocator.IsFree(taken) {
+		t.Errorf("IsFree reported bound port %d as free", taken)
+	}
+	port, err := allocator.Allocate(taken)
+	if err != nil {
+		t.Fatalf("Allocate returned an error: %v", err)
+	}
+	if port <= taken {
+		t.Errorf("Allocate returned %d, expected a port above %d", port, taken)
+	}
+	if _, err := allocator.AllocateRange(taken, taken); err != ErrNoFreePort {
+		t.Errorf("expected ErrNoFreePort for a range containing only a bound port, got %v", err)
+	}
+	if _, err := allocator.AllocateRange(10, 5); err == nil {
+		t.Errorf("AllocateRange accepted an invalid range")
+	}
+}
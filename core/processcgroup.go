@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const (
+	ErrCGroupUnsupported = errors.Error("cgroups are not supported on this platform")
+	ErrCGroupUnavailable = errors.Error("cgroups v2 is not available")
+	lnd_cgroup_name      = "conduit-lnd"
+	// cgroup_cpu_period is the cpu.max period in microseconds, the quota is a share of it
+	cgroup_cpu_period = 100000
+)
+
+// ProcessCGroupConfig holds the CPU and memory limits of the LND process, enforced with a cgroups v2 group
+type ProcessCGroupConfig struct {
+	CPUQuotaPercent int
+	MemoryLimitMB   int
+	root            string
+}
+
+// NewProcessCGroupConfig creates a new ProcessCGroupConfig from the LndCGroupCPU and LndCGroupMemMB parameters
+func NewProcessCGroupConfig(cfg *Config) *ProcessCGroupConfig {
+	return &ProcessCGroupConfig{
+		CPUQuotaPercent: cfg.LndCGroupCPU,
+		MemoryLimitMB:   cfg.LndCGroupMemMB,
+		root:            cgroup_root,
+	}
+}
+
+// Enabled returns whether any limit is set
+func (c *ProcessCGroupConfig) Enabled() bool {
+	return c.CPUQuotaPercent > 0 || c.MemoryLimitMB > 0
+}
+
+// Apply creates the conduit-lnd cgroup, writes the limits and moves the process into it
+func (c *ProcessCGroupConfig) Apply(pid int) error {
+	if c.root == "" {
+		return ErrCGroupUnsupported
+	}
+	// cgroup.controllers only exists on the unified cgroups v2 hierarchy
+	if _, err := os.Stat(path.Join(c.root, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%w: %v", ErrCGroupUnavailable, err)
+	}
+	// the controllers may already be enabled for the children of the root group, or not be delegated to us
+	_ = ioutil.WriteFile(path.Join(c.root, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+	group := path.Join(c.root, lnd_cgroup_name)
+	if err := os.MkdirAll(group, 0755); err != nil {
+		return fmt.Errorf("could not create cgroup %v: %v", group, err)
+	}
+	if c.CPUQuotaPercent > 0 {
+		quota := c.CPUQuotaPercent * cgroup_cpu_period / 100
+		if err := ioutil.WriteFile(path.Join(group, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cgroup_cpu_period)), 0644); err != nil {
+			return fmt.Errorf("could not set the CPU limit: %v", err)
+		}
+	}
+	if c.MemoryLimitMB > 0 {
+		limit := uint64(c.MemoryLimitMB) * 1024 * 1024
+		if err := ioutil.WriteFile(path.Join(group, "memory.max"), []byte(strconv.FormatUint(limit, 10)), 0644); err != nil {
+			return fmt.Errorf("could not set the memory limit: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(group, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("could not add process %d to cgroup %v: %v", pid, group, err)
+	}
+	return nil
+}
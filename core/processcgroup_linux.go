@@ -0,0 +1,4 @@
+package core
+
+// cgroup_root is where the cgroups v2 hierarchy is mounted
+const cgroup_root = "/sys/fs/cgroup"
@@ -0,0 +1,7 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+// cgroup_root is empty since cgroups only exist on Linux
+const cgroup_root = ""
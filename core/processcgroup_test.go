@@ -0,0 +1,82 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+// newTestCGroupRoot returns a temporary directory looking like a cgroups v2 mount point
+func newTestCGroupRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(root, "cgroup.controllers"), []byte("cpu io memory pids"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// readCGroupFile returns the content of a file of the conduit-lnd cgroup
+func readCGroupFile(t *testing.T, root, name string) string {
+	t.Helper()
+	raw, err := ioutil.ReadFile(path.Join(root, lnd_cgroup_name, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestProcessCGroupConfigApply(t *testing.T) {
+	root := newTestCGroupRoot(t)
+	limits := NewProcessCGroupConfig(&Config{LndCGroupCPU: 150, LndCGroupMemMB: 512})
+	limits.root = root
+	if err := limits.Apply(4242); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"cpu.max":      "150000 100000",
+		"memory.max":   "536870912",
+		"cgroup.procs": "4242",
+	} {
+		if got := readCGroupFile(t, root, name); got != want {
+			t.Errorf("unexpected %v: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestProcessCGroupConfigMemoryOnly(t *testing.T) {
+	root := newTestCGroupRoot(t)
+	limits := &ProcessCGroupConfig{MemoryLimitMB: 1, root: root}
+	if err := limits.Apply(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadFile(path.Join(root, lnd_cgroup_name, "cpu.max")); err == nil {
+		t.Fatal("cpu.max written without a CPU limit")
+	}
+}
+
+func TestProcessCGroupConfigUnavailable(t *testing.T) {
+	// a cgroups v1 hierarchy has no cgroup.controllers
+	limits := &ProcessCGroupConfig{MemoryLimitMB: 512, root: t.TempDir()}
+	if err := limits.Apply(1); !errors.Is(err, ErrCGroupUnavailable) {
+		t.Fatalf("expected ErrCGroupUnavailable, got %v", err)
+	}
+	limits.root = ""
+	if err := limits.Apply(1); err != ErrCGroupUnsupported {
+		t.Fatalf("expected ErrCGroupUnsupported, got %v", err)
+	}
+}
+
+func TestProcessCGroupConfigNotPassedToLnd(t *testing.T) {
+	cfg := &Config{LndCGroupCPU: 50, LndCGroupMemMB: 512}
+	if !NewProcessCGroupConfig(cfg).Enabled() {
+		t.Fatal("expected the limits to be enabled")
+	}
+	cfg.eachLndOption(func(alias string, value interface{}) {
+		if strings.Contains(alias, "cgroup") || alias == "lnd-version" {
+			t.Fatalf("unexpected LND option %v", alias)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ErrProcessStatsUnsupported   = errors.Error("process resource usage can't be read on this platform")
+	default_lnd_monitor_interval = 30 * time.Second
+	lnd_monitor_restart_after    = 5
+	// proc_clock_ticks is the USER_HZ unit of the CPU times in /proc/<pid>/stat, which is 100 on every mainstream Linux
+	proc_clock_ticks = 100
+)
+
+// processSample is the cumulative CPU time and resident memory of a process at a point in time
+type processSample struct {
+	cpuTime time.Duration
+	rss     uint64
+}
+
+// readProcStat reads the CPU time and resident memory of a process from <procRoot>/<pid>/stat
+func readProcStat(procRoot string, pid int) (processSample, error) {
+	raw, err := ioutil.ReadFile(path.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return processSample{}, err
+	}
+	// the command name is in parentheses and may contain spaces, the other fields follow the last parenthesis
+	stat := string(raw)
+	i := strings.LastIndex(stat, ")")
+	if i == -1 {
+		return processSample{}, fmt.Errorf("malformed stat file of process %d", pid)
+	}
+	// fields[0] is the 3rd field of the stat file, the process state
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 22 {
+		return processSample{}, fmt.Errorf("malformed stat file of process %d", pid)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+	pages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+	return processSample{
+		cpuTime: time.Duration(utime+stime) * time.Second / proc_clock_ticks,
+		rss:     pages * uint64(os.Getpagesize()),
+	}, nil
+}
+
+// LNDProcessMonitor periodically samples the CPU and memory usage of the LND process and warns when they cross the configured thresholds
+type LNDProcessMonitor struct {
+	log          *subLogger
+	pid          int
+	interval     time.Duration
+	cpuThreshold float64
+	memThreshold uint64
+	sample       func(pid int) (processSample, error)
+	restart      func() error
+	last         processSample
+	lastAt       time.Time
+	consecutive  int
+}
+
+// NewLNDProcessMonitor creates a new LNDProcessMonitor for the given LND process. If restart is not nil, it's called after 5 consecutive warnings
+func NewLNDProcessMonitor(cfg *Config, pid int, log *zerolog.Logger, restart func() error) (*LNDProcessMonitor, error) {
+	interval := default_lnd_monitor_interval
+	if cfg.LNDMonitorInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(cfg.LNDMonitorInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LNDMonitorInterval %v: %v", cfg.LNDMonitorInterval, err)
+		}
+	}
+	return &LNDProcessMonitor{
+		log:          NewSubLogger(log, "LPMN"),
+		pid:          pid,
+		interval:     interval,
+		cpuThreshold: cfg.LNDCPUWarnThreshold,
+		memThreshold: cfg.LNDMemWarnBytes,
+		sample:       readProcessSample,
+		restart:      restart,
+	}, nil
+}
+
+// Run samples the LND process every interval until the context is cancelled or the process can't be read anymore
+func (m *LNDProcessMonitor) Run(ctx context.Context) error {
+	if err := m.check(time.Now()); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := m.check(now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// check takes a new sample and compares the CPU usage since the previous sample and the resident memory to the thresholds
+func (m *LNDProcessMonitor) check(now time.Time) error {
+	sample, err := m.sample(m.pid)
+	if err != nil {
+		return err
+	}
+	previous, previousAt := m.last, m.lastAt
+	m.last, m.lastAt = sample, now
+	// the CPU usage needs two samples
+	if previousAt.IsZero() {
+		return nil
+	}
+	cpu := 100 * float64(sample.cpuTime-previous.cpuTime) / float64(now.Sub(previousAt))
+	var alerts []string
+	if m.cpuThreshold > 0 && cpu > m.cpuThreshold {
+		alerts = append(alerts, fmt.Sprintf("CPU usage %.1f%% is above %.1f%%", cpu, m.cpuThreshold))
+	}
+	if m.memThreshold > 0 && sample.rss > m.memThreshold {
+		alerts = append(alerts, fmt.Sprintf("resident memory %d bytes is above %d bytes", sample.rss, m.memThreshold))
+	}
+	if len(alerts) == 0 {
+		m.consecutive = 0
+		return nil
+	}
+	m.consecutive++
+	m.log.SubLogger.Warn().Float64("cpu_percent", cpu).Uint64("rss_bytes", sample.rss).Msg(fmt.Sprintf("LND %s", strings.Join(alerts, " and ")))
+	if m.consecutive >= lnd_monitor_restart_after && m.restart != nil {
+		m.log.SubLogger.Warn().Msg(fmt.Sprintf("Restarting LND after %d consecutive warnings", m.consecutive))
+		m.consecutive = 0
+		if err = m.restart(); err != nil {
+			m.log.SubLogger.Error().Msg(fmt.Sprintf("could not restart LND: %v", err))
+		}
+	}
+	return nil
+}
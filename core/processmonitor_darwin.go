@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcessSample reads the CPU time and resident memory of a process with ps since reading the mach_task_basic_info of another process requires its task port, which only root can get
+func readProcessSample(pid int) (processSample, error) {
+	out, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return processSample{}, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return processSample{}, fmt.Errorf("unexpected ps output for process %d: %q", pid, out)
+	}
+	rssKB, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+	cpuTime, err := parsePSTime(fields[1])
+	if err != nil {
+		return processSample{}, err
+	}
+	return processSample{cpuTime: cpuTime, rss: rssKB * 1024}, nil
+}
+
+// parsePSTime parses the [[dd-]hh:]mm:ss.cc CPU time format of ps
+func parsePSTime(s string) (time.Duration, error) {
+	var days int
+	if i := strings.Index(s, "-"); i != -1 {
+		d, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, err
+		}
+		days, s = d, s[i+1:]
+	}
+	parts := strings.Split(s, ":")
+	var total time.Duration
+	for _, part := range parts[:len(parts)-1] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		total = (total + time.Duration(n)) * 60
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(days)*24*time.Hour + total*time.Second + time.Duration(seconds*float64(time.Second)), nil
+}
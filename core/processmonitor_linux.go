@@ -0,0 +1,6 @@
+package core
+
+// readProcessSample reads the CPU time and resident memory of a process from procfs
+func readProcessSample(pid int) (processSample, error) {
+	return readProcStat("/proc", pid)
+}
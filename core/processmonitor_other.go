@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package core
+
+// readProcessSample is not supported on this platform
+func readProcessSample(pid int) (processSample, error) {
+	return processSample{}, ErrProcessStatsUnsupported
+}
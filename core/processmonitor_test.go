@@ -0,0 +1,102 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// writeProcStat writes a stat file for the process to the fake proc directory
+func writeProcStat(t *testing.T, procRoot string, pid int, utime, stime, rssPages uint64) {
+	t.Helper()
+	dir := path.Join(procRoot, fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Error creating fake proc directory: %v", err)
+	}
+	stat := fmt.Sprintf("%d (lnd (main)) S 1 %d %d 0 -1 4194560 1234 0 0 0 %d %d 0 0 20 0 24 0 5678 987654321 %d 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n", pid, pid, pid, utime, stime, rssPages)
+	if err := ioutil.WriteFile(path.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatalf("Error writing fake stat file: %v", err)
+	}
+}
+
+// TestReadProcStat ensures the CPU time and resident memory are parsed from the stat file
+func TestReadProcStat(t *testing.T) {
+	procRoot := t.TempDir()
+	writeProcStat(t, procRoot, 42, 250, 50, 1000)
+	sample, err := readProcStat(procRoot, 42)
+	if err != nil {
+		t.Fatalf("readProcStat returned an error: %v", err)
+	}
+	if sample.cpuTime != 3*time.Second {
+		t.Errorf("expected 3s of CPU time, got %v", sample.cpuTime)
+	}
+	if sample.rss != 1000*uint64(os.Getpagesize()) {
+		t.Errorf("expected %d bytes of resident memory, got %d", 1000*os.Getpagesize(), sample.rss)
+	}
+	if _, err = readProcStat(procRoot, 43); err == nil {
+		t.Errorf("expected an error reading a missing process")
+	}
+}
+
+// TestLNDProcessMonitor ensures warnings are logged above the thresholds and that LND is restarted after 5 consecutive warnings
+func TestLNDProcessMonitor(t *testing.T) {
+	procRoot := t.TempDir()
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	cfg := &Config{LNDCPUWarnThreshold: 80, LNDMemWarnBytes: 2000 * uint64(os.Getpagesize())}
+	restarts := 0
+	m, err := NewLNDProcessMonitor(cfg, 42, &log, func() error {
+		restarts++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewLNDProcessMonitor returned an error: %v", err)
+	}
+	m.sample = func(pid int) (processSample, error) {
+		return readProcStat(procRoot, pid)
+	}
+	now := time.Now()
+	var ticks uint64
+	// every second the process uses 0.5s of CPU time with 1000 pages of memory
+	for i := 0; i < 3; i++ {
+		writeProcStat(t, procRoot, 42, ticks, 0, 1000)
+		if err = m.check(now.Add(time.Duration(i) * time.Second)); err != nil {
+			t.Fatalf("check returned an error: %v", err)
+		}
+		ticks += 50
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no warnings below the thresholds, got %s", buf.String())
+	}
+	// now it uses a whole core
+	for i := 3; i < 3+lnd_monitor_restart_after; i++ {
+		writeProcStat(t, procRoot, 42, ticks, 0, 1000)
+		if err = m.check(now.Add(time.Duration(i) * time.Second)); err != nil {
+			t.Fatalf("check returned an error: %v", err)
+		}
+		ticks += 100
+	}
+	if !strings.Contains(buf.String(), `"level":"warn"`) || !strings.Contains(buf.String(), "CPU usage 100.0% is above 80.0%") {
+		t.Errorf("expected CPU warnings, got %s", buf.String())
+	}
+	if restarts != 0 {
+		t.Errorf("expected no restart before %d consecutive warnings, got %d", lnd_monitor_restart_after, restarts)
+	}
+	writeProcStat(t, procRoot, 42, ticks, 0, 3000)
+	if err = m.check(now.Add(time.Duration(3+lnd_monitor_restart_after) * time.Second)); err != nil {
+		t.Fatalf("check returned an error: %v", err)
+	}
+	if restarts != 1 {
+		t.Errorf("expected LND to be restarted once, got %d", restarts)
+	}
+	if !strings.Contains(buf.String(), "resident memory") {
+		t.Errorf("expected a memory warning, got %s", buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	default_jsonrpc_rate_limit = 10
+	default_jsonrpc_rate_burst = 20
+)
+
+// RPCRateLimiter limits the rate of JSON-RPC calls. Methods with a limit of their own have their own token bucket, all others share the global one
+type RPCRateLimiter struct {
+	sync.RWMutex
+	global  *rate.Limiter
+	methods map[string]*rate.Limiter
+}
+
+// NewRPCRateLimiter creates a new RPCRateLimiter whose global limit allows rps calls per second with bursts of burst calls
+func NewRPCRateLimiter(rps float64, burst int) *RPCRateLimiter {
+	return &RPCRateLimiter{
+		global:  rate.NewLimiter(rate.Limit(rps), burst),
+		methods: make(map[string]*rate.Limiter),
+	}
+}
+
+// SetMethodLimit limits the given method to rps calls per second with bursts of burst calls, i.e. 1.0/300 and 1 for at most one call every 5 minutes
+func (l *RPCRateLimiter) SetMethodLimit(method string, rps float64, burst int) {
+	l.Lock()
+	defer l.Unlock()
+	l.methods[method] = rate.NewLimiter(rate.Limit(rps), burst)
+}
+
+// Allow reports whether a call to the given method is allowed now, consuming a token if it is
+func (l *RPCRateLimiter) Allow(method string) bool {
+	l.RLock()
+	limiter, ok := l.methods[method]
+	l.RUnlock()
+	if !ok {
+		limiter = l.global
+	}
+	return limiter.Allow()
+}
+
+// Middleware rejects the calls exceeding the limit of their method with ErrRateLimited
+func (l *RPCRateLimiter) Middleware(method string, next jsonrpc.HandlerFunc) jsonrpc.HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		if !l.Allow(method) {
+			return nil, jsonrpc.NewError(jsonrpc.ErrRateLimited, fmt.Sprintf("rate limit of %s exceeded", method))
+		}
+		return next(ctx, params)
+	}
+}
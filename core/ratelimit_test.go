@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+// TestRPCRateLimiter ensures rapid calls beyond the burst of a method, or of the global limit, are rejected with ErrRateLimited
+func TestRPCRateLimiter(t *testing.T) {
+	log := zerolog.Nop()
+	s := NewRPCServer(&Config{ConduitDir: t.TempDir(), JsonRPCRateLimit: 0.001, JsonRPCRateBurst: 3}, &log)
+	ts := httptest.NewServer(s.Server)
+	defer ts.Close()
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(ctx context.Context, params json.RawMessage) (interface{}, error) { return true, nil }
+	s.Register("conduit_test_restart", noop)
+	s.Register("conduit_test_status", noop)
+	s.Register("conduit_test_info", noop)
+	// at most one call every 5 minutes
+	s.RateLimiter().SetMethodLimit("conduit_test_restart", 1.0/300, 1)
+	count := func(method string, calls int) int {
+		succeeded := 0
+		for i := 0; i < calls; i++ {
+			var result bool
+			err := client.Call(context.Background(), method, nil, &result)
+			var rpcErr *jsonrpc.Error
+			if err == nil {
+				succeeded++
+			} else if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.ErrRateLimited {
+				t.Fatalf("unexpected error calling %s: %v", method, err)
+			}
+		}
+		return succeeded
+	}
+	if n := count("conduit_test_restart", 5); n != 1 {
+		t.Errorf("expected 1 call to the limited method to succeed, got %d", n)
+	}
+	// the methods without a limit share the global burst of 3, not affected by the limited method
+	if n := count("conduit_test_status", 2) + count("conduit_test_info", 3); n != 3 {
+		t.Errorf("expected 3 calls under the global limit to succeed, got %d", n)
+	}
+}
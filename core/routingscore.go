@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+const (
+	default_routing_score_period = 30 * 24 * time.Hour
+	routing_score_revenue_weight = 0.5
+	routing_score_volume_weight  = 0.3
+	routing_score_uptime_weight  = 0.2
+	routing_score_open_threshold = 0.7
+	routing_score_close_uptime   = 0.5
+)
+
+// Routing score actions
+const (
+	RoutingActionOpen  = "open"
+	RoutingActionClose = "close"
+	RoutingActionKeep  = "keep"
+)
+
+// RoutingScore is the routing performance of the channels with a peer over the scoring period
+type RoutingScore struct {
+	Pubkey            string  `json:"pubkey"`
+	Channels          int     `json:"channels"`
+	Forwards          int     `json:"forwards"`
+	VolumeMsat        uint64  `json:"volume_msat"`
+	FeeRevenueMsat    uint64  `json:"fee_revenue_msat"`
+	RevenuePerDayMsat float64 `json:"revenue_per_day_msat"`
+	Uptime            float64 `json:"uptime"`
+	FeeRatePpm        int64   `json:"fee_rate_ppm"`
+	Score             float64 `json:"score"`
+	Action            string  `json:"action"`
+}
+
+// RoutingNodeScorer ranks the channel peers from the forwarding history CSV and the open channels of LND
+type RoutingNodeScorer struct {
+	sync.Mutex
+	filename string
+	period   time.Duration
+	now      func() time.Time
+	client   lnrpc.LightningClient
+}
+
+// NewRoutingNodeScorer creates a RoutingNodeScorer reading the forwarding history CSV of the conduit directory
+func NewRoutingNodeScorer(config *Config) *RoutingNodeScorer {
+	return &RoutingNodeScorer{
+		filename: ForwardingHistoryPath(config),
+		period:   default_routing_score_period,
+		now:      time.Now,
+	}
+}
+
+// SetClient sets the LND client used to list the channels, once LND is active
+func (r *RoutingNodeScorer) SetClient(client lnrpc.LightningClient) {
+	r.Lock()
+	defer r.Unlock()
+	r.client = client
+}
+
+// Scores returns the score of every peer with an open channel, best first.
+// The revenue and volume of a forward are credited to the peer of the outgoing channel. The score weighs the revenue per day by 0.5, the volume by 0.3,
+// both relative to the best peer, and the uptime by 0.2. Peers scoring at least 0.7 are worth opening another channel with,
+// peers online less than half of the time or which didn't forward anything during a whole period are worth closing
+func (r *RoutingNodeScorer) Scores(ctx context.Context) ([]RoutingScore, error) {
+	r.Lock()
+	client := r.client
+	r.Unlock()
+	if client == nil {
+		return nil, ErrLNDNotActive
+	}
+	channels, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	fees, err := client.FeeReport(ctx, &lnrpc.FeeReportRequest{})
+	if err != nil {
+		return nil, err
+	}
+	events, err := ReadForwardingHistory(r.filename, r.now().Add(-r.period))
+	if err != nil {
+		return nil, err
+	}
+	feeRates := make(map[uint64]int64, len(fees.ChannelFees))
+	for _, fee := range fees.ChannelFees {
+		feeRates[fee.ChanId] = fee.FeePerMil
+	}
+	return scorePeers(channels.Channels, feeRates, events, r.period), nil
+}
+
+// peerStats accumulates the figures of the channels with a peer
+type peerStats struct {
+	score    RoutingScore
+	uptime   int64
+	lifetime int64
+	oldest   time.Duration
+	feeRates int64
+}
+
+// scorePeers computes the score of the peers of the given channels, best first
+func scorePeers(channels []*lnrpc.Channel, feeRates map[uint64]int64, events []*ForwardingEvent, period time.Duration) []RoutingScore {
+	peers := make(map[string]*peerStats)
+	byChannel := make(map[uint64]*peerStats, len(channels))
+	for _, channel := range channels {
+		p, ok := peers[channel.RemotePubkey]
+		if !ok {
+			p = &peerStats{score: RoutingScore{Pubkey: channel.RemotePubkey}}
+			peers[channel.RemotePubkey] = p
+		}
+		p.score.Channels++
+		p.uptime += channel.Uptime
+		p.lifetime += channel.Lifetime
+		if lifetime := time.Duration(channel.Lifetime) * time.Second; lifetime > p.oldest {
+			p.oldest = lifetime
+		}
+		p.feeRates += feeRates[channel.ChanId]
+		byChannel[channel.ChanId] = p
+	}
+	// forwards through channels which are closed now are ignored
+	for _, event := range events {
+		if p, ok := byChannel[event.ChanIdOut]; ok {
+			p.score.Forwards++
+			p.score.VolumeMsat += event.AmountOutMsat
+			p.score.FeeRevenueMsat += event.FeeMsat
+		}
+	}
+	var maxRevenue float64
+	var maxVolume uint64
+	for _, p := range peers {
+		// a peer whose channels are younger than the period earned its revenue over their lifetime
+		days := period
+		if p.oldest > 0 && p.oldest < days {
+			days = p.oldest
+		}
+		p.score.RevenuePerDayMsat = float64(p.score.FeeRevenueMsat) / (days.Hours() / 24)
+		if p.lifetime > 0 {
+			p.score.Uptime = float64(p.uptime) / float64(p.lifetime)
+		}
+		p.score.FeeRatePpm = p.feeRates / int64(p.score.Channels)
+		if p.score.RevenuePerDayMsat > maxRevenue {
+			maxRevenue = p.score.RevenuePerDayMsat
+		}
+		if p.score.VolumeMsat > maxVolume {
+			maxVolume = p.score.VolumeMsat
+		}
+	}
+	scores := make([]RoutingScore, 0, len(peers))
+	for _, p := range peers {
+		s := p.score
+		if maxRevenue > 0 {
+			s.Score += routing_score_revenue_weight * s.RevenuePerDayMsat / maxRevenue
+		}
+		if maxVolume > 0 {
+			s.Score += routing_score_volume_weight * float64(s.VolumeMsat) / float64(maxVolume)
+		}
+		s.Score += routing_score_uptime_weight * s.Uptime
+		switch {
+		case p.lifetime > 0 && s.Uptime < routing_score_close_uptime, s.Forwards == 0 && p.oldest >= period:
+			s.Action = RoutingActionClose
+		case s.Score >= routing_score_open_threshold:
+			s.Action = RoutingActionOpen
+		default:
+			s.Action = RoutingActionKeep
+		}
+		scores = append(scores, s)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Pubkey < scores[j].Pubkey
+	})
+	return scores
+}
+
+// RegisterRoutingScores registers the conduit_routing_scores method
+func (s *RPCServer) RegisterRoutingScores(scorer *RoutingNodeScorer) {
+	s.Register("conduit_routing_scores", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		scores, err := scorer.Scores(ctx)
+		if err != nil {
+			return nil, lndRPCError(err, "score routing peers")
+		}
+		return scores, nil
+	})
+}
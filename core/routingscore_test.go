@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// scoringChannel returns an open channel with the given lifetime and fraction of it spent online
+func scoringChannel(chanId uint64, pubkey string, lifetime time.Duration, uptime float64) *lnrpc.Channel {
+	seconds := int64(lifetime / time.Second)
+	return &lnrpc.Channel{ChanId: chanId, RemotePubkey: pubkey, Lifetime: seconds, Uptime: int64(float64(seconds) * uptime)}
+}
+
+// TestRoutingNodeScorerRanking ensures peers are ranked by revenue, volume and uptime and told whether to open or close channels
+func TestRoutingNodeScorerRanking(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	scorer := NewRoutingNodeScorer(&Config{ConduitDir: t.TempDir()})
+	scorer.now = func() time.Time { return now }
+	scorer.SetClient(&fakeLightningClient{
+		channels: []*lnrpc.Channel{
+			// a earns the most and is always online
+			scoringChannel(10, "a", 60*day, 1),
+			// b has two young channels and earned less per day
+			scoringChannel(20, "b", 10*day, 0.9),
+			scoringChannel(21, "b", 10*day, 0.9),
+			// c is mostly offline
+			scoringChannel(30, "c", 60*day, 0.4),
+			// d is online but never forwarded anything
+			scoringChannel(40, "d", 60*day, 1),
+		},
+		fees: []*lnrpc.ChannelFeeReport{{ChanId: 10, FeePerMil: 100}, {ChanId: 20, FeePerMil: 200}, {ChanId: 21, FeePerMil: 400}},
+	})
+	recent := now.Add(-time.Hour)
+	writeForwards(t, scorer.filename, recent, 10, 10, 5000000)
+	writeForwards(t, scorer.filename, recent, 20, 2, 5000000)
+	writeForwards(t, scorer.filename, recent, 30, 1, 1000000)
+	// forwards out of closed channels and outside of the period are ignored
+	writeForwards(t, scorer.filename, recent, 99, 50, 5000000)
+	writeForwards(t, scorer.filename, now.Add(-40*day), 40, 5, 5000000)
+
+	scores, err := scorer.Scores(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		pubkey   string
+		channels int
+		forwards int
+		action   string
+	}{
+		{"a", 1, 10, RoutingActionOpen},
+		{"b", 2, 2, RoutingActionKeep},
+		{"d", 1, 0, RoutingActionClose},
+		{"c", 1, 1, RoutingActionClose},
+	}
+	if len(scores) != len(want) {
+		t.Fatalf("expected %d scores, got %+v", len(want), scores)
+	}
+	for i, w := range want {
+		s := scores[i]
+		if s.Pubkey != w.pubkey || s.Channels != w.channels || s.Forwards != w.forwards || s.Action != w.action {
+			t.Errorf("unexpected score %d: %+v", i, s)
+		}
+	}
+	if a := scores[0]; a.Score != 1 || a.VolumeMsat != 50000000 || a.FeeRevenueMsat != 10000 || a.FeeRatePpm != 100 {
+		t.Errorf("unexpected score of a: %+v", a)
+	}
+	// b's revenue is spread over the 10 days of its channels rather than the 30 days of the period
+	if b := scores[1]; b.RevenuePerDayMsat != 200 || b.Uptime != 0.9 || b.FeeRatePpm != 300 {
+		t.Errorf("unexpected score of b: %+v", b)
+	}
+}
+
+// TestRoutingScoresRPC ensures conduit_routing_scores fails with ErrLNDNotRunning until LND is active and then serves the scores
+func TestRoutingScoresRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	scorer := NewRoutingNodeScorer(s.cfg)
+	s.RegisterRoutingScores(scorer)
+	var scores []RoutingScore
+	var rpcErr *jsonrpc.Error
+	if err := client.Call(context.Background(), "conduit_routing_scores", nil, &scores); !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.ErrLNDNotRunning {
+		t.Fatalf("expected ErrLNDNotRunning before LND is active, got %v", err)
+	}
+	// the failures of LND are internal errors
+	scorer.SetClient(&fakeLightningClient{channelsErr: errors.New("server is shutting down")})
+	if err := client.Call(context.Background(), "conduit_routing_scores", nil, &scores); !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.JSONRPC_INTERNAL_ERR {
+		t.Fatalf("expected an internal error when LND fails, got %v", err)
+	}
+	scorer.SetClient(&fakeLightningClient{channels: []*lnrpc.Channel{scoringChannel(10, "a", time.Hour, 1)}})
+	if err := client.Call(context.Background(), "conduit_routing_scores", nil, &scores); err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 1 || scores[0].Pubkey != "a" || scores[0].Action != RoutingActionKeep {
+		t.Fatalf("unexpected scores: %+v", scores)
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// RPCMiddlewarePlugin is implemented by plugins which intercept LND's gRPC calls through LND's RPC middleware interface.
+// Messages are passed as JSON. Returning a nil message leaves the intercepted message unchanged, returning an error rejects the call
+type RPCMiddlewarePlugin interface {
+	Name() string
+	HandleRequest(method string, req json.RawMessage) (json.RawMessage, error)
+	HandleResponse(method string, resp json.RawMessage) (json.RawMessage, error)
+}
+
+// jsonRPCMiddleware is an RPCMiddlewarePlugin forwarding intercepted messages to the JSON-RPC endpoint of a plugin
+type jsonRPCMiddleware struct {
+	name   string
+	client *jsonrpc.Client
+}
+
+// middlewareParams are the params of the rpcmiddleware_handle_request and rpcmiddleware_handle_response plugin methods
+type middlewareParams struct {
+	Method  string          `json:"method"`
+	Message json.RawMessage `json:"message"`
+}
+
+func (m *jsonRPCMiddleware) Name() string {
+	return m.name
+}
+
+func (m *jsonRPCMiddleware) call(pluginMethod, method string, msg json.RawMessage) (json.RawMessage, error) {
+	res, err := m.client.CallRaw(context.Background(), pluginMethod, mustMarshal(middlewareParams{Method: method, Message: msg}))
+	if err != nil {
+		return nil, err
+	}
+	if string(res) == "null" {
+		return nil, nil
+	}
+	return res, nil
+}
+
+func (m *jsonRPCMiddleware) HandleRequest(method string, req json.RawMessage) (json.RawMessage, error) {
+	return m.call("rpcmiddleware_handle_request", method, req)
+}
+
+func (m *jsonRPCMiddleware) HandleResponse(method string, resp json.RawMessage) (json.RawMessage, error) {
+	return m.call("rpcmiddleware_handle_response", method, resp)
+}
+
+// mustMarshal marshals values which can't fail to marshal
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// RPCMiddlewarePlugins returns the plugins whose manifest declares them as RPC middleware
+func RPCMiddlewarePlugins(manifests map[string]*PluginManifest) ([]RPCMiddlewarePlugin, error) {
+	var plugins []RPCMiddlewarePlugin
+	for name, manifest := range manifests {
+		if !manifest.RPCMiddleware {
+			continue
+		}
+		client, err := jsonrpc.NewClient(manifest.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", name, err)
+		}
+		plugins = append(plugins, &jsonRPCMiddleware{name: name, client: client})
+	}
+	return plugins, nil
+}
+
+// RunRPCMiddleware registers the plugin as RPC middleware with LND and handles intercepted messages until the stream ends.
+// Only calls made with a macaroon carrying a custom caveat named after the plugin are intercepted
+func RunRPCMiddleware(ctx context.Context, client lnrpc.LightningClient, plugin RPCMiddlewarePlugin) error {
+	stream, err := client.RegisterRPCMiddleware(ctx)
+	if err != nil {
+		return err
+	}
+	err = stream.Send(&lnrpc.RPCMiddlewareResponse{
+		MiddlewareMessage: &lnrpc.RPCMiddlewareResponse_Register{Register: &lnrpc.MiddlewareRegistration{
+			MiddlewareName:           plugin.Name(),
+			CustomMacaroonCaveatName: plugin.Name(),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		feedback := interceptFeedback(plugin, req)
+		err = stream.Send(&lnrpc.RPCMiddlewareResponse{
+			RefMsgId:          req.RequestId,
+			MiddlewareMessage: &lnrpc.RPCMiddlewareResponse_Feedback{Feedback: feedback},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// interceptFeedback passes an intercepted message to the plugin and returns its verdict
+func interceptFeedback(plugin RPCMiddlewarePlugin, req *lnrpc.RPCMiddlewareRequest) *lnrpc.InterceptFeedback {
+	var (
+		msg    *lnrpc.RPCMessage
+		handle func(string, json.RawMessage) (json.RawMessage, error)
+	)
+	switch {
+	case req.GetRequest() != nil:
+		msg, handle = req.GetRequest(), plugin.HandleRequest
+	case req.GetResponse() != nil:
+		msg, handle = req.GetResponse(), plugin.HandleResponse
+	default:
+		// stream authentication carries no message
+		return &lnrpc.InterceptFeedback{}
+	}
+	raw, err := rpcMessageToJSON(msg)
+	if err != nil {
+		return &lnrpc.InterceptFeedback{Error: err.Error()}
+	}
+	replacement, err := handle(msg.MethodFullUri, raw)
+	if err != nil {
+		return &lnrpc.InterceptFeedback{Error: err.Error()}
+	}
+	// LND 0.14 only allows replacing responses
+	if replacement == nil || req.GetResponse() == nil {
+		return &lnrpc.InterceptFeedback{}
+	}
+	serialized, err := rpcMessageFromJSON(msg.TypeName, replacement)
+	if err != nil {
+		return &lnrpc.InterceptFeedback{Error: fmt.Sprintf("plugin %s returned an invalid %s: %v", plugin.Name(), msg.TypeName, err)}
+	}
+	return &lnrpc.InterceptFeedback{ReplaceResponse: true, ReplacementSerialized: serialized}
+}
+
+// newRPCMessage returns an empty message of the named protobuf type
+func newRPCMessage(typeName string) (proto.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message type %s", typeName)
+	}
+	return mt.New().Interface(), nil
+}
+
+// rpcMessageToJSON decodes the serialized protobuf message into JSON
+func rpcMessageToJSON(msg *lnrpc.RPCMessage) (json.RawMessage, error) {
+	m, err := newRPCMessage(msg.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	if err = proto.Unmarshal(msg.Serialized, m); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(m)
+}
+
+// rpcMessageFromJSON encodes a JSON message into the serialized protobuf message of the named type
+func rpcMessageFromJSON(typeName string, raw json.RawMessage) ([]byte, error) {
+	m, err := newRPCMessage(typeName)
+	if err != nil {
+		return nil, err
+	}
+	if err = protojson.Unmarshal(raw, m); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(m)
+}
+
+// runRPCMiddlewarePlugins registers every RPC middleware plugin with LND, and again whenever LND drops it
+func runRPCMiddlewarePlugins(ctx context.Context, cfg *Config, reconnector *GRPCReconnector, client lnrpc.LightningClient, log *zerolog.Logger) {
+	manifests, err := LoadPluginManifests(PluginDir(cfg))
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not load plugin manifests: %v", err))
+		return
+	}
+	plugins, err := RPCMiddlewarePlugins(manifests)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+	for _, plugin := range plugins {
+		go func(plugin RPCMiddlewarePlugin) {
+			reconnector.RunWhileConnected(ctx, "RPC middleware "+plugin.Name(), func() error {
+				return RunRPCMiddleware(ctx, client, plugin)
+			})
+		}(plugin)
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeMiddlewareServer plays LND's side of the RPC middleware stream: it sends the given intercepts and collects the feedback
+type fakeMiddlewareServer struct {
+	lnrpc.UnimplementedLightningServer
+	intercepts   []*lnrpc.RPCMiddlewareRequest
+	registration chan *lnrpc.MiddlewareRegistration
+	feedback     chan *lnrpc.RPCMiddlewareResponse
+}
+
+func (s *fakeMiddlewareServer) RegisterRPCMiddleware(stream lnrpc.Lightning_RegisterRPCMiddlewareServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.registration <- msg.GetRegister()
+	for _, intercept := range s.intercepts {
+		if err = stream.Send(intercept); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.feedback <- resp
+	}
+	return nil
+}
+
+// aliasMiddleware rejects GetInfo requests from a forbidden macaroon and rewrites the alias of GetInfo responses
+type aliasMiddleware struct{}
+
+func (m *aliasMiddleware) Name() string {
+	return "alias-rewriter"
+}
+
+func (m *aliasMiddleware) HandleRequest(method string, req json.RawMessage) (json.RawMessage, error) {
+	return nil, fmt.Errorf("%s is not allowed", method)
+}
+
+func (m *aliasMiddleware) HandleResponse(method string, resp json.RawMessage) (json.RawMessage, error) {
+	var info map[string]interface{}
+	if err := json.Unmarshal(resp, &info); err != nil {
+		return nil, err
+	}
+	info["alias"] = strings.ToUpper(info["alias"].(string))
+	return json.Marshal(info)
+}
+
+// TestRunRPCMiddleware ensures the plugin is registered and that its verdicts are sent back to LND
+func TestRunRPCMiddleware(t *testing.T) {
+	response, _ := proto.Marshal(&lnrpc.GetInfoResponse{Alias: "conduit", NumPeers: 3})
+	srv := &fakeMiddlewareServer{
+		intercepts: []*lnrpc.RPCMiddlewareRequest{
+			{RequestId: 1, InterceptType: &lnrpc.RPCMiddlewareRequest_StreamAuth{StreamAuth: &lnrpc.StreamAuth{MethodFullUri: "/lnrpc.Lightning/SubscribeInvoices"}}},
+			{RequestId: 2, InterceptType: &lnrpc.RPCMiddlewareRequest_Request{Request: &lnrpc.RPCMessage{MethodFullUri: "/lnrpc.Lightning/GetInfo", TypeName: "lnrpc.GetInfoRequest"}}},
+			{RequestId: 3, InterceptType: &lnrpc.RPCMiddlewareRequest_Response{Response: &lnrpc.RPCMessage{MethodFullUri: "/lnrpc.Lightning/GetInfo", TypeName: "lnrpc.GetInfoResponse", Serialized: response}}},
+		},
+		registration: make(chan *lnrpc.MiddlewareRegistration, 1),
+		feedback:     make(chan *lnrpc.RPCMiddlewareResponse, 3),
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	server := grpc.NewServer()
+	lnrpc.RegisterLightningServer(server, srv)
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error dialing gRPC server: %v", err)
+	}
+	defer conn.Close()
+	if err = RunRPCMiddleware(context.Background(), lnrpc.NewLightningClient(conn), &aliasMiddleware{}); err != nil {
+		t.Fatalf("RunRPCMiddleware returned an error: %v", err)
+	}
+	if reg := <-srv.registration; reg.MiddlewareName != "alias-rewriter" || reg.CustomMacaroonCaveatName != "alias-rewriter" || reg.ReadOnlyMode {
+		t.Errorf("unexpected registration: %v", reg)
+	}
+	if auth := (<-srv.feedback).GetFeedback(); auth.Error != "" || auth.ReplaceResponse {
+		t.Errorf("stream auth was not accepted: %v", auth)
+	}
+	if fb := <-srv.feedback; fb.RefMsgId != 2 || fb.GetFeedback().Error != "/lnrpc.Lightning/GetInfo is not allowed" {
+		t.Errorf("request was not rejected: %v", fb)
+	}
+	fb := <-srv.feedback
+	if fb.RefMsgId != 3 || !fb.GetFeedback().ReplaceResponse {
+		t.Fatalf("response was not replaced: %v", fb)
+	}
+	info := &lnrpc.GetInfoResponse{}
+	if err := proto.Unmarshal(fb.GetFeedback().ReplacementSerialized, info); err != nil {
+		t.Fatalf("Error decoding replacement: %v", err)
+	}
+	if info.Alias != "CONDUIT" || info.NumPeers != 3 {
+		t.Errorf("unexpected replacement: %v", info)
+	}
+}
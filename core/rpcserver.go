@@ -1 +1,156 @@
 package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// ErrLNDNotActive is returned by the subsystems querying LND until its RPC server is active
+	ErrLNDNotActive         = errors.Error("LND is not active yet")
+	default_jsonrpc_listen  = "localhost:10010"
+	default_jsonrpc_timeout = 30 * time.Second
+)
+
+// RPCServer is the Conduit JSON-RPC server
+type RPCServer struct {
+	*jsonrpc.Server
+	cfg        *Config
+	log        *subLogger
+	httpServer *http.Server
+	limiter    *RPCRateLimiter
+	// pluginMetrics records the calls forwarded to the plugins, if set
+	pluginMetrics *PluginMetrics
+}
+
+// NewRPCServer creates a new RPCServer and registers all Conduit methods. Calls are limited to JsonRPCDefaultTimeout unless registered with a timeout,
+// and to JsonRPCRateLimit calls per second unless their method has a limit of its own
+func NewRPCServer(cfg *Config, log *zerolog.Logger) *RPCServer {
+	s := &RPCServer{
+		Server: jsonrpc.NewServer(),
+		cfg:    cfg,
+		log:    NewSubLogger(log, "RPCS"),
+	}
+	timeout := default_jsonrpc_timeout
+	if cfg.JsonRPCDefaultTimeout != "" {
+		if d, err := time.ParseDuration(cfg.JsonRPCDefaultTimeout); err != nil {
+			s.log.SubLogger.Warn().Msg(fmt.Sprintf("invalid JsonRPCDefaultTimeout %v, using %v: %v", cfg.JsonRPCDefaultTimeout, timeout, err))
+		} else {
+			timeout = d
+		}
+	}
+	s.SetDefaultTimeout(timeout)
+	rps, burst := cfg.JsonRPCRateLimit, cfg.JsonRPCRateBurst
+	if rps <= 0 {
+		rps = default_jsonrpc_rate_limit
+	}
+	if burst <= 0 {
+		burst = default_jsonrpc_rate_burst
+	}
+	s.limiter = NewRPCRateLimiter(rps, burst)
+	s.Use(s.limiter.Middleware)
+	s.Register("conduit_plugin_call", s.pluginCall)
+	s.Register("conduit_debug_goroutines", s.debugGoroutines)
+	s.Register("conduit_lnd_pid", s.lndPID)
+	s.Register("conduit_lnd_restart", s.lndRestart)
+	s.limiter.SetMethodLimit("conduit_lnd_restart", 1/lnd_restart_interval.Seconds(), 1)
+	return s
+}
+
+// lndRPCError returns the JSON-RPC error of a method which failed to query LND: ErrLNDNotRunning until LND is active, and an internal
+// error saying what the method couldn't do otherwise
+func lndRPCError(err error, action string) error {
+	if err == ErrLNDNotActive {
+		return jsonrpc.NewError(jsonrpc.ErrLNDNotRunning, err.Error())
+	}
+	return jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, fmt.Sprintf("could not %s: %v", action, err))
+}
+
+// SetPluginMetrics sets the PluginMetrics recording the calls forwarded to the plugins. It must be called before the server is started
+func (s *RPCServer) SetPluginMetrics(metrics *PluginMetrics) {
+	s.pluginMetrics = metrics
+}
+
+// RateLimiter returns the rate limiter of the JSON-RPC calls, to set the limits of specific methods
+func (s *RPCServer) RateLimiter() *RPCRateLimiter {
+	return s.limiter
+}
+
+// Start listens on the configured address and serves JSON-RPC requests, and the live config view in debug mode, in a goroutine
+func (s *RPCServer) Start() error {
+	addr := s.cfg.JsonRPCListen
+	if addr == "" {
+		addr = default_jsonrpc_listen
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", s.Server)
+	mux.Handle(live_config_path, NewLiveConfigView(s.cfg))
+	s.httpServer = &http.Server{Handler: mux}
+	go func() {
+		var err error
+		if s.cfg.JsonRPCTLSCertPath != "" && s.cfg.JsonRPCTLSKeyPath != "" {
+			err = s.httpServer.ServeTLS(listener, s.cfg.JsonRPCTLSCertPath, s.cfg.JsonRPCTLSKeyPath)
+		} else {
+			err = s.httpServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.log.SubLogger.Error().Msg(fmt.Sprintf("JSON-RPC server stopped: %v", err))
+		}
+	}()
+	s.log.SubLogger.Info().Msg(fmt.Sprintf("JSON-RPC server listening on %v", listener.Addr()))
+	return nil
+}
+
+// Stop gracefully shuts down the JSON-RPC server
+func (s *RPCServer) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(context.Background())
+}
+
+// pluginCallParams are the params of the conduit_plugin_call method
+type pluginCallParams struct {
+	Plugin string          `json:"plugin"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// pluginCall forwards a call to the JSON-RPC server declared in the plugin's manifest and returns its result
+func (s *RPCServer) pluginCall(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p pluginCallParams
+	if err := json.Unmarshal(params, &p); err != nil || p.Plugin == "" || p.Method == "" {
+		return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INVALID_PARAMS, "expected params {\"plugin\": string, \"method\": string, \"params\": any}")
+	}
+	manifest, err := LoadPluginManifest(s.cfg, p.Plugin)
+	if err == ErrPluginNotFound {
+		return nil, jsonrpc.NewError(jsonrpc.ErrPluginNotFound, fmt.Sprintf("plugin %s not found", p.Plugin))
+	} else if err != nil {
+		return nil, jsonrpc.NewError(jsonrpc.ErrConfigInvalid, fmt.Sprintf("could not load plugin manifests: %v", err))
+	}
+	if manifest.Endpoint == "" {
+		return nil, jsonrpc.NewError(jsonrpc.ErrConfigInvalid, fmt.Sprintf("plugin %s does not declare a JSON-RPC endpoint", p.Plugin))
+	}
+	client, err := jsonrpc.NewClient(manifest.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := client.CallRaw(ctx, p.Method, p.Params)
+	if s.pluginMetrics != nil {
+		s.pluginMetrics.Observe(p.Plugin, pluginMetricMethod(manifest, p.Method), time.Since(start), err)
+	}
+	return res, err
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+)
+
+// newTestRPCServer creates an RPCServer using a temporary Conduit directory and serves it with httptest
+func newTestRPCServer(t *testing.T) (*RPCServer, *jsonrpc.Client) {
+	cfg := &Config{ConduitDir: t.TempDir()}
+	log := zerolog.Nop()
+	s := NewRPCServer(cfg, &log)
+	ts := httptest.NewServer(s.Server)
+	t.Cleanup(ts.Close)
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	return s, client
+}
+
+// TestPluginCall ensures conduit_plugin_call forwards calls to the endpoint declared in the plugin manifest
+func TestPluginCall(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	plugin := jsonrpc.NewServer()
+	plugin.Register("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return params, nil
+	})
+	plugin.Register("fail", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, jsonrpc.NewError(-32050, "plugin failure")
+	})
+	ps := httptest.NewServer(plugin)
+	defer ps.Close()
+	if err := os.Mkdir(PluginDir(s.cfg), 0775); err != nil {
+		t.Fatalf("Error creating plugin directory: %v", err)
+	}
+	manifest := fmt.Sprintf("Name: echoer\nVersion: 0.1.0\nEndpoint: %s\n", ps.URL)
+	if err := ioutil.WriteFile(path.Join(PluginDir(s.cfg), "echoer.yaml"), []byte(manifest), 0666); err != nil {
+		t.Fatalf("Error writing manifest: %v", err)
+	}
+	var result map[string]string
+	params := map[string]interface{}{"plugin": "echoer", "method": "echo", "params": map[string]string{"hello": "world"}}
+	if err := client.Call(context.Background(), "conduit_plugin_call", params, &result); err != nil {
+		t.Fatalf("conduit_plugin_call returned an error: %v", err)
+	}
+	if result["hello"] != "world" {
+		t.Errorf("conduit_plugin_call returned unexpected result: %v", result)
+	}
+	// errors from the plugin are passed through
+	params["method"] = "fail"
+	err := client.Call(context.Background(), "conduit_plugin_call", params, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != -32050 {
+		t.Errorf("expected the plugin error to be passed through, got: %v", err)
+	}
+	// unknown plugins return ErrPluginNotFound
+	params["plugin"] = "unknown"
+	err = client.Call(context.Background(), "conduit_plugin_call", params, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrPluginNotFound {
+		t.Errorf("expected ErrPluginNotFound for an unknown plugin, got: %v", err)
+	}
+	// plugins without an endpoint return ErrConfigInvalid
+	if err := ioutil.WriteFile(path.Join(PluginDir(s.cfg), "noendpoint.yaml"), []byte("Name: noendpoint\nVersion: 0.1.0\n"), 0666); err != nil {
+		t.Fatalf("Error writing manifest: %v", err)
+	}
+	params["plugin"] = "noendpoint"
+	err = client.Call(context.Background(), "conduit_plugin_call", params, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrConfigInvalid {
+		t.Errorf("expected ErrConfigInvalid for a plugin without an endpoint, got: %v", err)
+	}
+}
+
+// TestRPCServerDefaultTimeout ensures JsonRPCDefaultTimeout applies to the registered methods
+func TestRPCServerDefaultTimeout(t *testing.T) {
+	log := zerolog.Nop()
+	s := NewRPCServer(&Config{ConduitDir: t.TempDir(), JsonRPCDefaultTimeout: "20ms"}, &log)
+	s.Register("conduit_test_hang", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil, ctx.Err()
+	})
+	ts := httptest.NewServer(s.Server)
+	defer ts.Close()
+	client, err := jsonrpc.NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	err = client.Call(context.Background(), "conduit_test_hang", nil, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrMethodTimeout {
+		t.Fatalf("expected ErrMethodTimeout, got %v", err)
+	}
+}
+
+// TestRPCServerTLS ensures a client trusting JsonRPCTLSCertPath calls the methods of the server over HTTPS, and that a plain HTTP client can't
+func TestRPCServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "rpc.cert"), path.Join(dir, "rpc.key")
+	cert := newServerCert(t, certPath)
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	log := zerolog.Nop()
+	s := NewRPCServer(&Config{ConduitDir: dir, JsonRPCListen: addr, JsonRPCTLSCertPath: certPath, JsonRPCTLSKeyPath: keyPath}, &log)
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	client, err := jsonrpc.NewTLSClient(addr, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp LNDProcessIDResponse
+	if err = client.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err != nil {
+		t.Errorf("could not call the server over HTTPS: %v", err)
+	}
+	plain, err := jsonrpc.NewClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plain.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err == nil {
+		t.Error("expected a plain HTTP call to fail")
+	}
+	// a certificate other than the server's isn't trusted
+	otherPath := path.Join(dir, "other.cert")
+	newServerCert(t, otherPath)
+	other, err := jsonrpc.NewTLSClient(addr, otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = other.Call(context.Background(), "conduit_lnd_pid", nil, &resp); err == nil {
+		t.Error("expected a server with another certificate not to be trusted")
+	}
+}
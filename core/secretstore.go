@@ -0,0 +1,166 @@
+package core
+
+import (
+	"reflect"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/zalando/go-keyring"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	ErrSecretNotFound         = errors.Error("secret not found")
+	ErrSecretStoreUnavailable = errors.Error("no OS keychain available and CONDUIT_MASTER_KEY not set")
+	secret_store_service      = "conduit"
+	secret_store_metadata_key = "secret/"
+)
+
+// Keyring is the subset of the OS keychain used by the SecretStore
+type Keyring interface {
+	Set(service, user, password string) error
+	Get(service, user string) (string, error)
+	Delete(service, user string) error
+}
+
+// systemKeyring is the OS keychain: the Keychain on macOS, the Secret Service on Linux and the Credential Manager on Windows
+type systemKeyring struct{}
+
+func (systemKeyring) Set(service, user, password string) error {
+	return keyring.Set(service, user, password)
+}
+func (systemKeyring) Get(service, user string) (string, error) { return keyring.Get(service, user) }
+func (systemKeyring) Delete(service, user string) error        { return keyring.Delete(service, user) }
+
+// SecretStore stores sensitive config values in the OS keychain. Without a keychain, they are encrypted with CONDUIT_MASTER_KEY in the MetadataStore
+type SecretStore struct {
+	keyring   Keyring
+	storePath string
+	// encryption returns the cipher of the fallback store, it fails when no master key is set
+	encryption func() (*ConfigEncryption, error)
+}
+
+// NewSecretStore creates a SecretStore using the OS keychain and falling back to the MetadataStore of the conduit directory
+func NewSecretStore(cfg *Config) *SecretStore {
+	return &SecretStore{
+		keyring:    systemKeyring{},
+		storePath:  MetadataStorePath(cfg),
+		encryption: NewConfigEncryptionFromEnv,
+	}
+}
+
+// withFallback opens the encrypted fallback store for the duration of f
+func (s *SecretStore) withFallback(f func(store *MetadataStore, enc *ConfigEncryption) error) error {
+	enc, err := s.encryption()
+	if err != nil {
+		return ErrSecretStoreUnavailable
+	}
+	store, err := OpenMetadataStore(s.storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return f(store, enc)
+}
+
+// Set stores the secret value under key
+func (s *SecretStore) Set(key, value string) error {
+	if err := s.keyring.Set(secret_store_service, key, value); err == nil {
+		return nil
+	}
+	return s.withFallback(func(store *MetadataStore, enc *ConfigEncryption) error {
+		sealed, err := enc.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		return store.Put(secret_store_metadata_key+key, []byte(sealed))
+	})
+}
+
+// secretFallback is the encrypted fallback store of a series of lookups, opened the first time a secret isn't in the keychain
+type secretFallback struct {
+	s      *SecretStore
+	opened bool
+	store  *MetadataStore
+	enc    *ConfigEncryption
+	err    error
+}
+
+// open returns the fallback store, opening it on the first call
+func (f *secretFallback) open() (*MetadataStore, *ConfigEncryption, error) {
+	if !f.opened {
+		f.opened = true
+		if f.enc, f.err = f.s.encryption(); f.err != nil {
+			f.err = ErrSecretStoreUnavailable
+		} else {
+			f.store, f.err = OpenMetadataStore(f.s.storePath)
+		}
+	}
+	return f.store, f.enc, f.err
+}
+
+// Close closes the fallback store if it was opened
+func (f *secretFallback) Close() error {
+	if f.store == nil {
+		return nil
+	}
+	return f.store.Close()
+}
+
+// get returns the secret stored under key in the keychain, or in the fallback store, which holds the secrets set while the keychain
+// was unavailable
+func (s *SecretStore) get(key string, fallback *secretFallback) (string, error) {
+	value, keyringErr := s.keyring.Get(secret_store_service, key)
+	if keyringErr == nil {
+		return value, nil
+	}
+	store, enc, err := fallback.open()
+	// without a master key, or while the daemon has the fallback store open, the keychain is the only store of the secrets
+	if keyringErr == keyring.ErrNotFound && (err == ErrSecretStoreUnavailable || err == bolt.ErrTimeout) {
+		return "", ErrSecretNotFound
+	} else if err != nil {
+		return "", err
+	}
+	sealed, err := store.Get(secret_store_metadata_key + key)
+	if err == ErrKeyNotFound {
+		return "", ErrSecretNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return enc.Decrypt(string(sealed))
+}
+
+// Get returns the secret stored under key or ErrSecretNotFound
+func (s *SecretStore) Get(key string) (string, error) {
+	fallback := &secretFallback{s: s}
+	defer fallback.Close()
+	return s.get(key, fallback)
+}
+
+// Delete removes the secret stored under key. Deleting a missing secret is not an error
+func (s *SecretStore) Delete(key string) error {
+	err := s.keyring.Delete(secret_store_service, key)
+	if err == nil || err == keyring.ErrNotFound {
+		return nil
+	}
+	return s.withFallback(func(store *MetadataStore, enc *ConfigEncryption) error {
+		return store.Delete(secret_store_metadata_key + key)
+	})
+}
+
+// ApplyToConfig replaces the sensitive fields of the config with the secrets stored under their field name, opening the fallback store
+// at most once. Fields without a stored secret keep their YAML value
+func (s *SecretStore) ApplyToConfig(config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	fallback := &secretFallback{s: s}
+	defer fallback.Close()
+	for _, name := range SensitiveFields() {
+		value, err := s.get(name, fallback)
+		if err == ErrSecretNotFound || err == ErrSecretStoreUnavailable {
+			continue
+		} else if err != nil {
+			return err
+		}
+		v.FieldByName(name).SetString(value)
+	}
+	return nil
+}
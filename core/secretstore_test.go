@@ -0,0 +1,164 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// fakeKeyring is an in-memory Keyring. When unavailable, every call fails like on a system without a keychain
+type fakeKeyring struct {
+	secrets     map[string]string
+	unavailable bool
+}
+
+func (k *fakeKeyring) Set(service, user, password string) error {
+	if k.unavailable {
+		return fmt.Errorf("dbus: no session bus")
+	}
+	k.secrets[service+"/"+user] = password
+	return nil
+}
+
+func (k *fakeKeyring) Get(service, user string) (string, error) {
+	if k.unavailable {
+		return "", fmt.Errorf("dbus: no session bus")
+	}
+	password, ok := k.secrets[service+"/"+user]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return password, nil
+}
+
+func (k *fakeKeyring) Delete(service, user string) error {
+	if k.unavailable {
+		return fmt.Errorf("dbus: no session bus")
+	}
+	if _, ok := k.secrets[service+"/"+user]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(k.secrets, service+"/"+user)
+	return nil
+}
+
+// newTestSecretStore returns a SecretStore using a fake keyring and a temporary fallback store
+func newTestSecretStore(t *testing.T, kr *fakeKeyring, masterKey string) *SecretStore {
+	return &SecretStore{
+		keyring:    kr,
+		storePath:  MetadataStorePath(&Config{ConduitDir: t.TempDir()}),
+		encryption: func() (*ConfigEncryption, error) { return NewConfigEncryption([]byte(masterKey)) },
+	}
+}
+
+// testSecretStoreRoundTrip sets, gets and deletes a secret
+func testSecretStoreRoundTrip(t *testing.T, s *SecretStore) {
+	t.Helper()
+	if _, err := s.Get("LndBtcdRPCPass"); err != ErrSecretNotFound {
+		t.Fatalf("expected ErrSecretNotFound before Set, got %v", err)
+	}
+	if err := s.Set("LndBtcdRPCPass", "hunter2"); err != nil {
+		t.Fatalf("Set returned an error: %v", err)
+	}
+	if value, err := s.Get("LndBtcdRPCPass"); err != nil || value != "hunter2" {
+		t.Fatalf("expected hunter2, got %q (%v)", value, err)
+	}
+	if err := s.Delete("LndBtcdRPCPass"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+	if _, err := s.Get("LndBtcdRPCPass"); err != ErrSecretNotFound {
+		t.Errorf("expected ErrSecretNotFound after Delete, got %v", err)
+	}
+	if err := s.Delete("LndBtcdRPCPass"); err != nil {
+		t.Errorf("deleting a missing secret returned an error: %v", err)
+	}
+}
+
+// TestSecretStoreKeyring ensures secrets go to the keychain when there's one
+func TestSecretStoreKeyring(t *testing.T) {
+	kr := &fakeKeyring{secrets: make(map[string]string)}
+	s := newTestSecretStore(t, kr, "")
+	testSecretStoreRoundTrip(t, s)
+	s.Set("LndBitcoindRPCPass", "secret")
+	if kr.secrets["conduit/LndBitcoindRPCPass"] != "secret" {
+		t.Errorf("secret not stored in the keyring: %v", kr.secrets)
+	}
+}
+
+// TestSecretStoreFallback ensures secrets are encrypted in the metadata store without a keychain
+func TestSecretStoreFallback(t *testing.T) {
+	s := newTestSecretStore(t, &fakeKeyring{unavailable: true}, "master key")
+	testSecretStoreRoundTrip(t, s)
+	if err := s.Set("LndBitcoindRPCPass", "secret"); err != nil {
+		t.Fatalf("Set returned an error: %v", err)
+	}
+	store, err := OpenMetadataStore(s.storePath)
+	if err != nil {
+		t.Fatalf("could not open the fallback store: %v", err)
+	}
+	raw, err := store.Get(secret_store_metadata_key + "LndBitcoindRPCPass")
+	store.Close()
+	if err != nil || !IsEncrypted(string(raw)) || strings.Contains(string(raw), "secret") {
+		t.Errorf("expected the secret to be encrypted, got %q (%v)", raw, err)
+	}
+	// without a master key there is nowhere to store secrets
+	s = newTestSecretStore(t, &fakeKeyring{unavailable: true}, "")
+	if err := s.Set("LndBitcoindRPCPass", "secret"); err != ErrSecretStoreUnavailable {
+		t.Errorf("expected ErrSecretStoreUnavailable, got %v", err)
+	}
+}
+
+// TestSecretStoreApplyToConfig ensures stored secrets replace the YAML values of sensitive fields only
+func TestSecretStoreApplyToConfig(t *testing.T) {
+	kr := &fakeKeyring{secrets: map[string]string{"conduit/LndBtcdRPCPass": "from keychain", "conduit/LndAlias": "ignored"}}
+	config := &Config{LndBtcdRPCPass: "from yaml", LndBitcoindRPCPass: "kept", LndAlias: "alias"}
+	if err := newTestSecretStore(t, kr, "").ApplyToConfig(config); err != nil {
+		t.Fatalf("ApplyToConfig returned an error: %v", err)
+	}
+	if config.LndBtcdRPCPass != "from keychain" || config.LndBitcoindRPCPass != "kept" || config.LndAlias != "alias" {
+		t.Errorf("unexpected config after ApplyToConfig: %+v", config)
+	}
+	// a missing keychain and master key leaves the config untouched
+	config = &Config{LndBtcdRPCPass: "from yaml"}
+	if err := newTestSecretStore(t, &fakeKeyring{unavailable: true}, "").ApplyToConfig(config); err != nil || config.LndBtcdRPCPass != "from yaml" {
+		t.Errorf("expected the YAML value to be kept, got %q (%v)", config.LndBtcdRPCPass, err)
+	}
+}
+
+// TestSecretStoreKeyringFallback ensures the secrets set while the keychain was unavailable are found once it's available again, and
+// that loading the config opens the fallback store once
+func TestSecretStoreKeyringFallback(t *testing.T) {
+	kr := &fakeKeyring{secrets: make(map[string]string), unavailable: true}
+	s := newTestSecretStore(t, kr, "master key")
+	if err := s.Set("LndBtcdRPCPass", "from fallback"); err != nil {
+		t.Fatalf("Set returned an error: %v", err)
+	}
+	kr.unavailable = false
+	if value, err := s.Get("LndBtcdRPCPass"); err != nil || value != "from fallback" {
+		t.Fatalf("expected the secret of the fallback store, got %q (%v)", value, err)
+	}
+	opened := 0
+	encryption := s.encryption
+	s.encryption = func() (*ConfigEncryption, error) {
+		opened++
+		return encryption()
+	}
+	config := &Config{}
+	if err := s.ApplyToConfig(config); err != nil || config.LndBtcdRPCPass != "from fallback" {
+		t.Fatalf("expected the secret of the fallback store to be applied, got %q (%v)", config.LndBtcdRPCPass, err)
+	}
+	if opened != 1 {
+		t.Errorf("expected the fallback store to be opened once, got %d", opened)
+	}
+	// while the daemon has the fallback store open, the secrets missing from the keychain are not found
+	store, err := OpenMetadataStore(s.storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err = s.Get("LndBitcoindRPCPass"); err != ErrSecretNotFound {
+		t.Errorf("expected ErrSecretNotFound while the fallback store is open, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+)
+
+const (
+	ErrPluginStartTimeout        = errors.Error("timed out waiting for plugins to start")
+	ErrPluginStartFailed         = errors.Error("plugin failed to start")
+	default_plugin_start_timeout = 30 * time.Second
+	startup_gate_interval        = 100 * time.Millisecond
+)
+
+// StartupGate holds the LND launch until the plugins which must run before LND are running
+type StartupGate struct {
+	manager *PluginManager
+	timeout time.Duration
+}
+
+// NewStartupGate creates a new StartupGate polling the given PluginManager
+func NewStartupGate(manager *PluginManager, cfg *Config) (*StartupGate, error) {
+	timeout := default_plugin_start_timeout
+	if cfg.PluginStartTimeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cfg.PluginStartTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PluginStartTimeout %v: %v", cfg.PluginStartTimeout, err)
+		}
+	}
+	return &StartupGate{manager: manager, timeout: timeout}, nil
+}
+
+// WaitForPlugins blocks until all named plugins are running. It returns an error if one of them fails or they aren't all running within the timeout
+func (g *StartupGate) WaitForPlugins(ctx context.Context, names []string) error {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	ticker := time.NewTicker(startup_gate_interval)
+	defer ticker.Stop()
+	for {
+		var waiting []string
+		for _, name := range names {
+			status, err := g.manager.Status(name)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			switch status {
+			case PluginRunning:
+			case PluginStarting:
+				waiting = append(waiting, name)
+			default:
+				return fmt.Errorf("%s is %s: %w", name, strings.ToLower(string(status)), ErrPluginStartFailed)
+			}
+		}
+		if len(waiting) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("%w after %v: %s", ErrPluginStartTimeout, g.timeout, strings.Join(waiting, ", "))
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
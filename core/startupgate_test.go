@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestStartupGate ensures LND is only launched once the plugins started before it are running
+func TestStartupGate(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	t.Setenv("CONDUIT_FAKE_PLUGIN_DELAY", "300ms")
+	addr := freeTCPAddr(t)
+	t.Setenv("CONDUIT_FAKE_PLUGIN_LISTEN", addr)
+	cfg := &Config{ConduitDir: t.TempDir(), PluginStartTimeout: "10s"}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "signer", Endpoint: "tcp://" + addr})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	defer m.StopAll()
+	if err = startPluginsBeforeLnd(context.Background(), cfg, m); err != nil {
+		t.Fatalf("startPluginsBeforeLnd returned an error: %v", err)
+	}
+	// LND would be spawned right after the gate opens
+	if status, _ := m.Status("signer"); status != PluginRunning {
+		t.Errorf("the gate opened while signer is %s", status)
+	}
+}
+
+// TestStartupGateTimeout ensures the gate reports the plugins that didn't start in time
+func TestStartupGateTimeout(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "run")
+	// the plugin never listens on its endpoint
+	t.Setenv("CONDUIT_FAKE_PLUGIN_LISTEN", "")
+	cfg := &Config{ConduitDir: t.TempDir(), PluginStartTimeout: "300ms"}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "slow", Endpoint: freeTCPAddr(t)})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	defer m.StopAll()
+	if err = startPluginsBeforeLnd(context.Background(), cfg, m); !errors.Is(err, ErrPluginStartTimeout) {
+		t.Errorf("expected ErrPluginStartTimeout, got %v", err)
+	}
+}
+
+// TestStartupGateFailedPlugin ensures the gate fails right away when a plugin crashes
+func TestStartupGateFailedPlugin(t *testing.T) {
+	t.Setenv("CONDUIT_FAKE_PLUGIN", "crash")
+	cfg := &Config{ConduitDir: t.TempDir(), PluginStartTimeout: "10s"}
+	writeFakePluginManifest(t, cfg, &PluginManifest{Name: "crasher", Endpoint: freeTCPAddr(t)})
+	log := zerolog.Nop()
+	m, err := NewPluginManager(cfg, &log)
+	if err != nil {
+		t.Fatalf("NewPluginManager returned an error: %v", err)
+	}
+	if err = startPluginsBeforeLnd(context.Background(), cfg, m); !errors.Is(err, ErrPluginStartFailed) {
+		t.Errorf("expected ErrPluginStartFailed, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Startup steps marked on the StartupTimeline
+const (
+	StartupConduitStarted   = "conduit_started"
+	StartupStoreOpened      = "metadata_store_opened"
+	StartupRPCServerStarted = "rpc_server_started"
+	StartupPluginsLoaded    = "plugins_loaded"
+	StartupPluginsStarted   = "plugins_started"
+	StartupLndStarted       = "lnd_started"
+	StartupLndRPCActive     = "lnd_rpc_active"
+)
+
+// StartupMark is a step of the startup and when it was reached
+type StartupMark struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+	// ElapsedMs is the time since the first mark in milliseconds
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+// StartupTimeline records when each step of the startup is reached, to find out which step makes it slow
+type StartupTimeline struct {
+	sync.Mutex
+	now   func() time.Time
+	marks []StartupMark
+}
+
+// NewStartupTimeline creates a new empty StartupTimeline
+func NewStartupTimeline() *StartupTimeline {
+	return &StartupTimeline{now: time.Now}
+}
+
+// Mark records that the named step is reached now
+func (t *StartupTimeline) Mark(name string) {
+	t.Lock()
+	defer t.Unlock()
+	mark := StartupMark{Name: name, Time: t.now()}
+	if len(t.marks) > 0 {
+		mark.ElapsedMs = mark.Time.Sub(t.marks[0].Time).Milliseconds()
+	}
+	t.marks = append(t.marks, mark)
+}
+
+// Marks returns the marks in the order they were recorded
+func (t *StartupTimeline) Marks() []StartupMark {
+	t.Lock()
+	defer t.Unlock()
+	return append([]StartupMark{}, t.marks...)
+}
+
+// find returns the last mark with the given name
+func (t *StartupTimeline) find(name string) (StartupMark, bool) {
+	for i := len(t.marks) - 1; i >= 0; i-- {
+		if t.marks[i].Name == name {
+			return t.marks[i], true
+		}
+	}
+	return StartupMark{}, false
+}
+
+// Duration returns the time elapsed between the from and to marks, or 0 if either of them isn't recorded
+func (t *StartupTimeline) Duration(from, to string) time.Duration {
+	t.Lock()
+	defer t.Unlock()
+	start, ok := t.find(from)
+	if !ok {
+		return 0
+	}
+	end, ok := t.find(to)
+	if !ok {
+		return 0
+	}
+	return end.Time.Sub(start.Time)
+}
+
+// String returns the timeline as a table of the marks with the time since the first mark and since the previous one
+func (t *StartupTimeline) String() string {
+	marks := t.Marks()
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tELAPSED\tDELTA")
+	for i, mark := range marks {
+		var delta time.Duration
+		if i > 0 {
+			delta = mark.Time.Sub(marks[i-1].Time)
+		}
+		fmt.Fprintf(w, "%s\t%dms\t%dms\n", mark.Name, mark.ElapsedMs, delta.Milliseconds())
+	}
+	w.Flush()
+	return b.String()
+}
+
+// Log writes the timeline table to the debug log
+func (t *StartupTimeline) Log(log *zerolog.Logger) {
+	log.Debug().Msg(fmt.Sprintf("Startup timeline:\n%s", t.String()))
+}
+
+// RegisterStartupTimeline registers the conduit_startup_timeline method
+func (s *RPCServer) RegisterStartupTimeline(timeline *StartupTimeline) {
+	s.Register("conduit_startup_timeline", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return timeline.Marks(), nil
+	})
+}
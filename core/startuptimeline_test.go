@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestStartupTimeline ensures the marks are kept in order with the time elapsed since the first one
+func TestStartupTimeline(t *testing.T) {
+	timeline := NewStartupTimeline()
+	start := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	timeline.now = func() time.Time { return now }
+	steps := []struct {
+		name  string
+		after time.Duration
+	}{
+		{StartupConduitStarted, 0},
+		{StartupStoreOpened, 15 * time.Millisecond},
+		{StartupRPCServerStarted, 5 * time.Millisecond},
+		{StartupLndStarted, 1200 * time.Millisecond},
+		{StartupLndRPCActive, 3 * time.Second},
+	}
+	for _, step := range steps {
+		now = now.Add(step.after)
+		timeline.Mark(step.name)
+	}
+	marks := timeline.Marks()
+	if len(marks) != len(steps) {
+		t.Fatalf("expected %d marks, got %+v", len(steps), marks)
+	}
+	for i, mark := range marks {
+		if mark.Name != steps[i].name {
+			t.Errorf("expected mark %d to be %s, got %s", i, steps[i].name, mark.Name)
+		}
+		if i > 0 && mark.Time.Before(marks[i-1].Time) {
+			t.Errorf("mark %s is before %s", mark.Name, marks[i-1].Name)
+		}
+	}
+	if marks[4].ElapsedMs != 4220 {
+		t.Errorf("unexpected elapsed time of %s: %dms", marks[4].Name, marks[4].ElapsedMs)
+	}
+	if d := timeline.Duration(StartupRPCServerStarted, StartupLndStarted); d != 1200*time.Millisecond {
+		t.Errorf("unexpected duration: %v", d)
+	}
+	if d := timeline.Duration(StartupConduitStarted, StartupPluginsLoaded); d != 0 {
+		t.Errorf("expected no duration to a missing mark, got %v", d)
+	}
+	table := timeline.String()
+	for _, want := range []string{"rpc_server_started     20ms     5ms", "lnd_rpc_active         4220ms   3000ms"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table is missing %q:\n%s", want, table)
+		}
+	}
+}
+
+// TestStartupTimelineRealClock ensures durations measured with the real clock are non-negative
+func TestStartupTimelineRealClock(t *testing.T) {
+	timeline := NewStartupTimeline()
+	var out bytes.Buffer
+	log := zerolog.New(&out).Level(zerolog.DebugLevel)
+	for _, name := range []string{StartupConduitStarted, StartupPluginsLoaded, StartupPluginsStarted} {
+		timeline.Mark(name)
+	}
+	if d := timeline.Duration(StartupConduitStarted, StartupPluginsStarted); d < 0 {
+		t.Errorf("negative duration %v", d)
+	}
+	for _, mark := range timeline.Marks() {
+		if mark.ElapsedMs < 0 {
+			t.Errorf("negative elapsed time %+v", mark)
+		}
+	}
+	timeline.Log(&log)
+	if !strings.Contains(out.String(), `"level":"debug"`) || !strings.Contains(out.String(), "plugins_started") {
+		t.Errorf("unexpected log: %s", out.String())
+	}
+}
+
+// TestStartupTimelineRPC ensures conduit_startup_timeline returns the marks
+func TestStartupTimelineRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	timeline := NewStartupTimeline()
+	timeline.Mark(StartupConduitStarted)
+	timeline.Mark(StartupRPCServerStarted)
+	s.RegisterStartupTimeline(timeline)
+	var marks []StartupMark
+	if err := client.Call(context.Background(), "conduit_startup_timeline", nil, &marks); err != nil {
+		t.Fatal(err)
+	}
+	if len(marks) != 2 || marks[0].Name != StartupConduitStarted || marks[1].Name != StartupRPCServerStarted {
+		t.Fatalf("unexpected marks: %+v", marks)
+	}
+}
+
+// TestMarkLndStartedReplay ensures the start of LND is marked even when it's published before the timeline subscribes to it
+func TestMarkLndStartedReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	bus.Publish(EventLndStarted, 4242)
+	timeline := NewStartupTimeline()
+	markLndStarted(ctx, bus, timeline)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if marks := timeline.Marks(); len(marks) == 1 && marks[0].Name == StartupLndStarted {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the start of LND to be marked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
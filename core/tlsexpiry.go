@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_tls_warn_days      = 30
+	tls_expiry_check_period    = 24 * time.Hour
+	tls_expiry_webhook_timeout = 10 * time.Second
+	tls_cert_name_lnd          = "lnd"
+	tls_cert_name_jsonrpc      = "jsonrpc"
+)
+
+// TLSExpiryNotification is the JSON body posted to the webhook for a certificate about to expire
+type TLSExpiryNotification struct {
+	Certificate string    `json:"certificate"`
+	Path        string    `json:"path"`
+	NotAfter    time.Time `json:"not_after"`
+	DaysLeft    int       `json:"days_left"`
+}
+
+// readCertificate returns the first certificate of a PEM file
+func readCertificate(filename string) (*x509.Certificate, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate in %v", filename)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate %v: %v", filename, err)
+	}
+	return cert, nil
+}
+
+// readCertExpiry returns the expiry of the first certificate of a PEM file
+func readCertExpiry(filename string) (time.Time, error) {
+	cert, err := readCertificate(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// TLSCertRotationNotifier checks daily whether the TLS certificates of LND and of the JSON-RPC server expire soon and warns the operator
+type TLSCertRotationNotifier struct {
+	certs    map[string]string
+	warnDays int
+	webhook  string
+	client   *http.Client
+	log      *subLogger
+	now      func() time.Time
+}
+
+// NewTLSCertRotationNotifier creates a new TLSCertRotationNotifier for the configured certificates
+func NewTLSCertRotationNotifier(cfg *Config, log *zerolog.Logger) *TLSCertRotationNotifier {
+	certs := map[string]string{tls_cert_name_lnd: lndTLSCertPath(cfg)}
+	if cfg.JsonRPCTLSCertPath != "" {
+		certs[tls_cert_name_jsonrpc] = cfg.JsonRPCTLSCertPath
+	}
+	warnDays := cfg.TLSWarnDays
+	if warnDays == 0 {
+		warnDays = default_tls_warn_days
+	}
+	return &TLSCertRotationNotifier{
+		certs:    certs,
+		warnDays: warnDays,
+		webhook:  cfg.TLSExpiryWebhookURL,
+		client:   &http.Client{Timeout: tls_expiry_webhook_timeout},
+		log:      NewSubLogger(log, "TLSN"),
+		now:      time.Now,
+	}
+}
+
+// Run checks the certificates right away and then daily until the context is cancelled
+func (n *TLSCertRotationNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(tls_expiry_check_period)
+	defer ticker.Stop()
+	for {
+		n.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check warns about every certificate expiring within the configured number of days and returns their notifications
+func (n *TLSCertRotationNotifier) Check(ctx context.Context) []TLSExpiryNotification {
+	var expiring []TLSExpiryNotification
+	for name, filename := range n.certs {
+		notAfter, err := readCertExpiry(filename)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			n.log.SubLogger.Warn().Msg(err.Error())
+			continue
+		}
+		left := notAfter.Sub(n.now())
+		if left > time.Duration(n.warnDays)*24*time.Hour {
+			continue
+		}
+		notification := TLSExpiryNotification{
+			Certificate: name,
+			Path:        filename,
+			NotAfter:    notAfter,
+			DaysLeft:    int(left.Hours() / 24),
+		}
+		expiring = append(expiring, notification)
+		if left <= 0 {
+			n.log.SubLogger.Warn().Str("path", filename).Time("not_after", notAfter).Msg(fmt.Sprintf("The %s TLS certificate expired on %v", name, notAfter.Format(time.RFC3339)))
+		} else {
+			n.log.SubLogger.Warn().Str("path", filename).Time("not_after", notAfter).Msg(fmt.Sprintf("The %s TLS certificate expires in %d days, rotate it before %v", name, notification.DaysLeft, notAfter.Format(time.RFC3339)))
+		}
+		if n.webhook != "" {
+			if err := n.notify(ctx, notification); err != nil {
+				n.log.SubLogger.Error().Msg(fmt.Sprintf("could not post TLS expiry notification: %v", err))
+			}
+		}
+	}
+	return expiring
+}
+
+// notify posts the notification to the webhook
+func (n *TLSCertRotationNotifier) notify(ctx context.Context, notification TLSExpiryNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
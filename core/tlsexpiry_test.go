@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// writeTestCert writes a self-signed certificate expiring at notAfter and returns its path
+func writeTestCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"conduit test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	filename := path.Join(t.TempDir(), "tls.cert")
+	if err = ioutil.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	return filename
+}
+
+// TestTLSCertRotationNotifier ensures a certificate expiring in 29 days is warned about and posted to the webhook while one expiring in a year isn't
+func TestTLSCertRotationNotifier(t *testing.T) {
+	var posted []TLSExpiryNotification
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n TLSExpiryNotification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("could not decode notification: %v", err)
+		}
+		posted = append(posted, n)
+	}))
+	defer ts.Close()
+	cfg := &Config{
+		LndTLSCertPath:      writeTestCert(t, time.Now().Add(29*24*time.Hour+time.Hour)),
+		JsonRPCTLSCertPath:  writeTestCert(t, time.Now().Add(365*24*time.Hour)),
+		TLSExpiryWebhookURL: ts.URL,
+	}
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	n := NewTLSCertRotationNotifier(cfg, &log)
+	expiring := n.Check(context.Background())
+	if len(expiring) != 1 || expiring[0].Certificate != tls_cert_name_lnd || expiring[0].DaysLeft != 29 {
+		t.Fatalf("expected the LND certificate to expire in 29 days, got %+v", expiring)
+	}
+	if !strings.Contains(buf.String(), `"level":"warn"`) || !strings.Contains(buf.String(), "expires in 29 days") {
+		t.Errorf("expected a warning, got %s", buf.String())
+	}
+	if len(posted) != 1 || posted[0].Path != cfg.LndTLSCertPath {
+		t.Errorf("expected the LND certificate to be posted to the webhook, got %+v", posted)
+	}
+}
+
+// TestTLSCertRotationNotifierWarnDays ensures the warning window is configurable and missing certificates are skipped
+func TestTLSCertRotationNotifierWarnDays(t *testing.T) {
+	cfg := &Config{
+		LndTLSCertPath:     writeTestCert(t, time.Now().Add(29*24*time.Hour+time.Hour)),
+		JsonRPCTLSCertPath: path.Join(t.TempDir(), "missing.cert"),
+		TLSWarnDays:        7,
+	}
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	if expiring := NewTLSCertRotationNotifier(cfg, &log).Check(context.Background()); len(expiring) != 0 || buf.Len() != 0 {
+		t.Errorf("expected nothing to be reported, got %+v: %s", expiring, buf.String())
+	}
+}
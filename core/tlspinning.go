@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	ErrTLSPinMismatch    = errors.Error("TLS certificate doesn't match the pinned certificate")
+	tls_pin_metadata_key = "tls_pins/"
+)
+
+// spkiFingerprint returns the hex SHA256 fingerprint of the subject public key info of a certificate
+func spkiFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
+// TLSPinning pins the public key of LND's TLS certificate the first time Conduit connects to LND and rejects any other key afterwards,
+// so that a certificate replaced along with the endpoint isn't trusted. The pins are kept in the MetadataStore
+type TLSPinning struct {
+	store *MetadataStore
+	log   *subLogger
+	mu    sync.Mutex
+}
+
+// NewTLSPinning creates a TLSPinning keeping its pins in the given store
+func NewTLSPinning(store *MetadataStore, log *zerolog.Logger) *TLSPinning {
+	return &TLSPinning{store: store, log: NewSubLogger(log, "TLSP")}
+}
+
+// Pin returns a dial option trusting the certificate of certPath only if its public key is the pinned one. Without a pin yet, the key of
+// the certificate is pinned once a connection presenting it succeeds
+func (p *TLSPinning) Pin(certPath string) (grpc.DialOption, error) {
+	cert, err := readCertificate(certPath)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := spkiFingerprint(cert)
+	key := tls_pin_metadata_key + certPath
+	pinned, err := p.store.Get(key)
+	if err != nil && err != ErrKeyNotFound {
+		return nil, err
+	}
+	if pinned != nil && string(pinned) != fingerprint {
+		return nil, fmt.Errorf("%w: %v has the SPKI fingerprint %s, the pinned one is %s", ErrTLSPinMismatch, certPath, fingerprint, pinned)
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the chain is verified against the certificate read again on every handshake, so that the reconnections of a persistent
+		// connection trust the certificate LND renewed once it's unpinned
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return p.verifyConnection(certPath, state)
+		},
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// verifyConnection checks that the server presents the certificate of certPath for the name it's dialed with, and that the certificate
+// has the pinned public key, pinning it if there's no pin yet
+func (p *TLSPinning) verifyConnection(certPath string, state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: no certificate presented", ErrTLSPinMismatch)
+	}
+	cert, err := readCertificate(certPath)
+	if err != nil {
+		return err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: state.ServerName}); err != nil {
+		return err
+	}
+	rawCerts := make([][]byte, len(state.PeerCertificates))
+	for i, c := range state.PeerCertificates {
+		rawCerts[i] = c.Raw
+	}
+	return p.verify(tls_pin_metadata_key+certPath, spkiFingerprint(cert), rawCerts)
+}
+
+// verify checks that the leaf certificate presented by the server has the expected fingerprint, and that it's the pinned one or pins it
+// if there's no pin yet
+func (p *TLSPinning) verify(key, fingerprint string, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("%w: no certificate presented", ErrTLSPinMismatch)
+	}
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return err
+	}
+	if got := spkiFingerprint(leaf); got != fingerprint {
+		p.log.SubLogger.Error().Str("fingerprint", got).Msg(fmt.Sprintf("LND presented a TLS certificate with an unexpected public key, expected the SPKI fingerprint %s", fingerprint))
+		return fmt.Errorf("%w: got the SPKI fingerprint %s, expected %s", ErrTLSPinMismatch, got, fingerprint)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pinned, err := p.store.Get(key)
+	if err == nil && string(pinned) != fingerprint {
+		return fmt.Errorf("%w: got the SPKI fingerprint %s, the pinned one is %s", ErrTLSPinMismatch, fingerprint, pinned)
+	}
+	if err == ErrKeyNotFound {
+		if err = p.store.Put(key, []byte(fingerprint)); err != nil {
+			p.log.SubLogger.Warn().Msg(fmt.Sprintf("could not pin LND's TLS certificate: %v", err))
+		} else {
+			p.log.SubLogger.Info().Str("fingerprint", fingerprint).Msg("Pinned LND's TLS certificate")
+		}
+	}
+	return nil
+}
+
+// Unpin removes the pin of the certificate of certPath, so that the next certificate LND presents is pinned instead. It's needed once LND renews its certificate
+func (p *TLSPinning) Unpin(certPath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.store.Delete(tls_pin_metadata_key + certPath); err != nil {
+		return err
+	}
+	p.log.SubLogger.Info().Str("path", certPath).Msg("Unpinned LND's TLS certificate")
+	return nil
+}
+
+// TLSUnpinResponse is the result of the conduit_tls_unpin method
+type TLSUnpinResponse struct {
+	Path string `json:"path"`
+}
+
+// RegisterTLSPinning registers the conduit_tls_unpin method, which unpins LND's certificate of certPath once LND renewed it. The persistent
+// connection to LND pins the renewed certificate when it reconnects
+func (s *RPCServer) RegisterTLSPinning(pinning *TLSPinning, certPath string) {
+	s.Register("conduit_tls_unpin", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		if err := pinning.Unpin(certPath); err != nil {
+			return nil, jsonrpc.NewError(jsonrpc.JSONRPC_INTERNAL_ERR, fmt.Sprintf("could not unpin LND's TLS certificate: %v", err))
+		}
+		return TLSUnpinResponse{Path: certPath}, nil
+	})
+}
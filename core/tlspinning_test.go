@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// newServerCert returns a self-signed certificate for localhost, like the one LND generates, and writes it to certPath
+func newServerCert(t *testing.T, certPath string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"conduit test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	if err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// startTLSServer serves gRPC with the given certificate on a free port and returns its address
+func startTLSServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// dialPinned dials addr with the pinned certificate of certPath
+func dialPinned(pinning *TLSPinning, certPath, addr string) error {
+	creds, err := pinning.Pin(certPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, creds, grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// TestTLSPinning ensures the certificate is pinned on the first connection and that another certificate is rejected afterwards
+func TestTLSPinning(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenMetadataStore(path.Join(dir, metadata_file_name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	log := zerolog.Nop()
+	pinning := NewTLSPinning(store, &log)
+	certPath := path.Join(dir, "tls.cert")
+	lndCert := newServerCert(t, certPath)
+	lndAddr := startTLSServer(t, lndCert)
+	if err = dialPinned(pinning, certPath, lndAddr); err != nil {
+		t.Fatalf("could not connect to the genuine server: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(lndCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned, err := store.Get(tls_pin_metadata_key + certPath); err != nil || string(pinned) != spkiFingerprint(leaf) {
+		t.Fatalf("expected the SPKI fingerprint %s to be pinned, got %s: %v", spkiFingerprint(leaf), pinned, err)
+	}
+	// an attacker replacing the endpoint is rejected during the handshake
+	otherPath := path.Join(t.TempDir(), "tls.cert")
+	otherCert := newServerCert(t, otherPath)
+	otherAddr := startTLSServer(t, otherCert)
+	if err = dialPinned(pinning, certPath, otherAddr); err == nil {
+		t.Error("expected a server with another certificate to be rejected")
+	}
+	if err = pinning.verify(tls_pin_metadata_key+certPath, spkiFingerprint(leaf), otherCert.Certificate); !errors.Is(err, ErrTLSPinMismatch) {
+		t.Errorf("expected ErrTLSPinMismatch for another certificate, got %v", err)
+	}
+	// an attacker replacing the certificate file as well is rejected before dialing
+	raw, err := ioutil.ReadFile(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(certPath, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = dialPinned(pinning, certPath, otherAddr); !errors.Is(err, ErrTLSPinMismatch) {
+		t.Errorf("expected ErrTLSPinMismatch for a replaced certificate file, got %v", err)
+	}
+	// once unpinned, the new certificate is trusted and pinned
+	if err = pinning.Unpin(certPath); err != nil {
+		t.Fatal(err)
+	}
+	if err = dialPinned(pinning, certPath, otherAddr); err != nil {
+		t.Errorf("could not connect after unpinning: %v", err)
+	}
+	if err = dialPinned(pinning, certPath, lndAddr); err == nil {
+		t.Error("expected the previously pinned server to be rejected once the new certificate is pinned")
+	}
+}
+
+// TestTLSUnpinRPC ensures a dial option keeps working once LND renewed its certificate and conduit_tls_unpin was called, as the
+// reconnections of the persistent connection need
+func TestTLSUnpinRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	dir := t.TempDir()
+	store, err := OpenMetadataStore(path.Join(dir, metadata_file_name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	log := zerolog.Nop()
+	pinning := NewTLSPinning(store, &log)
+	certPath := path.Join(dir, "tls.cert")
+	s.RegisterTLSPinning(pinning, certPath)
+	lndAddr := startTLSServer(t, newServerCert(t, certPath))
+	creds, err := pinning.Pin(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := func(addr string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, addr, creds, grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	if err = dial(lndAddr); err != nil {
+		t.Fatalf("could not connect to LND: %v", err)
+	}
+	// LND renews its certificate
+	renewedAddr := startTLSServer(t, newServerCert(t, certPath))
+	if err = dial(renewedAddr); err == nil {
+		t.Fatal("expected the renewed certificate to be rejected while the previous one is pinned")
+	}
+	var resp TLSUnpinResponse
+	if err = client.Call(context.Background(), "conduit_tls_unpin", nil, &resp); err != nil || resp.Path != certPath {
+		t.Fatalf("expected %v to be unpinned, got %+v: %v", certPath, resp, err)
+	}
+	if err = dial(renewedAddr); err != nil {
+		t.Errorf("could not connect with the renewed certificate once unpinned: %v", err)
+	}
+	if err = dial(lndAddr); err == nil {
+		t.Error("expected the previous certificate to be rejected once the renewed one is pinned")
+	}
+}
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// topology_max_sources bounds the number of BFS sources of the betweenness approximation, keeping the analysis of the whole Lightning graph to seconds
+	topology_max_sources = 256
+	topology_top_nodes   = 50
+	blocks_per_day       = 144
+	location_tor         = "tor"
+	location_unknown     = "unknown"
+)
+
+// TopologyReport is the position of the node in the Lightning graph
+type TopologyReport struct {
+	Pubkey string `json:"pubkey"`
+	Nodes  int    `json:"nodes"`
+	Edges  int    `json:"edges"`
+	// Betweenness is the estimated number of shortest paths between other nodes going through the node
+	Betweenness float64 `json:"betweenness"`
+	// PercentileRank is the percentage of the other nodes with a lower betweenness
+	PercentileRank float64 `json:"percentile_rank"`
+	// Sources is the number of nodes the shortest paths were computed from, all nodes when the betweenness is exact
+	Sources               int            `json:"sources"`
+	Peers                 int            `json:"peers"`
+	AverageChannelAgeDays float64        `json:"average_channel_age_days"`
+	PeerLocations         map[string]int `json:"peer_locations"`
+	TopNodePeers          int            `json:"top_node_peers"`
+}
+
+// NetworkTopologyAnalyzer reports the centrality of the node in the Lightning graph along with the diversity of its peers
+type NetworkTopologyAnalyzer struct {
+	sync.Mutex
+	client     lnrpc.LightningClient
+	maxSources int
+	topNodes   int
+	lookupAddr func(addr string) ([]string, error)
+}
+
+// NewNetworkTopologyAnalyzer creates a new NetworkTopologyAnalyzer
+func NewNetworkTopologyAnalyzer() *NetworkTopologyAnalyzer {
+	return &NetworkTopologyAnalyzer{
+		maxSources: topology_max_sources,
+		topNodes:   topology_top_nodes,
+		lookupAddr: net.LookupAddr,
+	}
+}
+
+// SetClient sets the LND client used to fetch the graph, once LND is active
+func (a *NetworkTopologyAnalyzer) SetClient(client lnrpc.LightningClient) {
+	a.Lock()
+	defer a.Unlock()
+	a.client = client
+}
+
+// Analyze fetches the graph from LND and computes the report of the node
+func (a *NetworkTopologyAnalyzer) Analyze(ctx context.Context) (*TopologyReport, error) {
+	a.Lock()
+	client := a.client
+	a.Unlock()
+	if client == nil {
+		return nil, ErrLNDNotActive
+	}
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+	graph, err := client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return nil, err
+	}
+	self := info.IdentityPubkey
+	adjacency := graphAdjacency(graph)
+	centrality, sources := betweenness(adjacency, a.maxSources)
+	report := &TopologyReport{
+		Pubkey:        self,
+		Nodes:         len(adjacency),
+		Edges:         len(graph.Edges),
+		Betweenness:   centrality[self],
+		Sources:       sources,
+		Peers:         len(adjacency[self]),
+		PeerLocations: make(map[string]int),
+	}
+	lower := 0
+	for node, c := range centrality {
+		if node != self && c < report.Betweenness {
+			lower++
+		}
+	}
+	if len(centrality) > 1 {
+		report.PercentileRank = 100 * float64(lower) / float64(len(centrality)-1)
+	}
+	// the block height of a channel is encoded in its short channel ID
+	var ages, channels uint64
+	capacities := make(map[string]int64)
+	for _, edge := range graph.Edges {
+		capacities[edge.Node1Pub] += edge.Capacity
+		capacities[edge.Node2Pub] += edge.Capacity
+		if edge.Node1Pub != self && edge.Node2Pub != self {
+			continue
+		}
+		if height := lnwire.NewShortChanIDFromInt(edge.ChannelId).BlockHeight; height <= info.BlockHeight {
+			ages += uint64(info.BlockHeight - height)
+			channels++
+		}
+	}
+	if channels > 0 {
+		report.AverageChannelAgeDays = float64(ages) / float64(channels) / blocks_per_day
+	}
+	for _, peer := range topNodesByCapacity(capacities, self, a.topNodes) {
+		if adjacency[self][peer] {
+			report.TopNodePeers++
+		}
+	}
+	addresses := make(map[string][]*lnrpc.NodeAddress, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		addresses[node.PubKey] = node.Addresses
+	}
+	for peer := range adjacency[self] {
+		report.PeerLocations[a.location(addresses[peer])]++
+	}
+	return report, nil
+}
+
+// location returns where a node is, as the country code top-level domain of its DNS name, "tor" for onion services or "unknown".
+// The names of IP addresses are found with a reverse DNS lookup
+func (a *NetworkTopologyAnalyzer) location(addresses []*lnrpc.NodeAddress) string {
+	for _, address := range addresses {
+		host, _, err := net.SplitHostPort(address.Addr)
+		if err != nil {
+			host = address.Addr
+		}
+		if strings.HasSuffix(host, ".onion") {
+			return location_tor
+		}
+		if net.ParseIP(host) != nil {
+			names, err := a.lookupAddr(host)
+			if err != nil || len(names) == 0 {
+				continue
+			}
+			host = names[0]
+		}
+		labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+		// generic top-level domains such as .com say nothing of the location
+		if tld := strings.ToLower(labels[len(labels)-1]); len(labels) > 1 && len(tld) == 2 {
+			return tld
+		}
+	}
+	return location_unknown
+}
+
+// graphAdjacency returns the neighbours of every node of the graph
+func graphAdjacency(graph *lnrpc.ChannelGraph) map[string]map[string]bool {
+	adjacency := make(map[string]map[string]bool, len(graph.Nodes))
+	add := func(a, b string) {
+		if adjacency[a] == nil {
+			adjacency[a] = make(map[string]bool)
+		}
+		if b != "" {
+			adjacency[a][b] = true
+		}
+	}
+	for _, node := range graph.Nodes {
+		add(node.PubKey, "")
+	}
+	for _, edge := range graph.Edges {
+		add(edge.Node1Pub, edge.Node2Pub)
+		add(edge.Node2Pub, edge.Node1Pub)
+	}
+	return adjacency
+}
+
+// betweenness computes the betweenness centrality of every node of the undirected graph with Brandes' algorithm.
+// When the graph has more than maxSources nodes, the shortest paths are only computed from maxSources evenly spread nodes and the result is extrapolated.
+// It returns the centralities and the number of sources used
+func betweenness(adjacency map[string]map[string]bool, maxSources int) (map[string]float64, int) {
+	nodes := make([]string, 0, len(adjacency))
+	for node := range adjacency {
+		nodes = append(nodes, node)
+	}
+	// sorted so that the sampled sources, and so the result, are deterministic
+	sort.Strings(nodes)
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+	neighbours := make([][]int, len(nodes))
+	for i, node := range nodes {
+		for peer := range adjacency[node] {
+			neighbours[i] = append(neighbours[i], index[peer])
+		}
+	}
+	sources := make([]int, 0, len(nodes))
+	if len(nodes) <= maxSources {
+		for i := range nodes {
+			sources = append(sources, i)
+		}
+	} else {
+		for i := 0; i < maxSources; i++ {
+			sources = append(sources, i*len(nodes)/maxSources)
+		}
+	}
+	centrality := make([]float64, len(nodes))
+	sigma := make([]float64, len(nodes))
+	dist := make([]int, len(nodes))
+	delta := make([]float64, len(nodes))
+	predecessors := make([][]int, len(nodes))
+	for _, s := range sources {
+		for i := range nodes {
+			sigma[i], dist[i], delta[i], predecessors[i] = 0, -1, 0, predecessors[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		queue := []int{s}
+		var stack []int
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range neighbours[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessors[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+	// every path of the undirected graph is counted from both of its ends
+	scale := 0.5 * float64(len(nodes)) / float64(len(sources))
+	result := make(map[string]float64, len(nodes))
+	for i, node := range nodes {
+		result[node] = centrality[i] * scale
+	}
+	return result, len(sources)
+}
+
+// topNodesByCapacity returns the n nodes other than self with the largest total channel capacity
+func topNodesByCapacity(capacities map[string]int64, self string, n int) []string {
+	nodes := make([]string, 0, len(capacities))
+	for node := range capacities {
+		if node != self {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if capacities[nodes[i]] != capacities[nodes[j]] {
+			return capacities[nodes[i]] > capacities[nodes[j]]
+		}
+		return nodes[i] < nodes[j]
+	})
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
+
+// RegisterTopology registers the conduit_topology method
+func (s *RPCServer) RegisterTopology(analyzer *NetworkTopologyAnalyzer) {
+	s.Register("conduit_topology", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		report, err := analyzer.Analyze(ctx)
+		if err != nil {
+			return nil, lndRPCError(err, "analyze the network topology")
+		}
+		return report, nil
+	})
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/TheRebelOfBabylon/Conduit/jsonrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// graphEdge returns a channel between two nodes confirmed at the given height
+func graphEdge(a, b string, height uint32, capacity int64) *lnrpc.ChannelEdge {
+	id := lnwire.ShortChannelID{BlockHeight: height, TxIndex: 1}.ToUint64()
+	return &lnrpc.ChannelEdge{ChannelId: id, Node1Pub: a, Node2Pub: b, Capacity: capacity}
+}
+
+// syntheticGraph returns a 20 node graph where self is the only bridge between two rings of 9 nodes, a0 to a8 and b0 to b8, and the only peer of the leaf c
+func syntheticGraph() *lnrpc.ChannelGraph {
+	graph := &lnrpc.ChannelGraph{}
+	for _, ring := range []string{"a", "b"} {
+		for i := 0; i < 9; i++ {
+			graph.Nodes = append(graph.Nodes, &lnrpc.LightningNode{PubKey: fmt.Sprintf("%s%d", ring, i)})
+			graph.Edges = append(graph.Edges, graphEdge(fmt.Sprintf("%s%d", ring, i), fmt.Sprintf("%s%d", ring, (i+1)%9), 500, 1000))
+		}
+	}
+	graph.Nodes = append(graph.Nodes, &lnrpc.LightningNode{PubKey: "self"}, &lnrpc.LightningNode{PubKey: "c"})
+	graph.Edges = append(graph.Edges,
+		graphEdge("self", "a0", 856, 1000000),
+		graphEdge("b0", "self", 712, 1000),
+		graphEdge("self", "c", 712, 1000),
+	)
+	for _, node := range graph.Nodes {
+		switch node.PubKey {
+		case "a0":
+			node.Addresses = []*lnrpc.NodeAddress{{Network: "tcp", Addr: "198.51.100.7:9735"}}
+		case "b0":
+			node.Addresses = []*lnrpc.NodeAddress{{Network: "tcp", Addr: "abcdefghijklmnop.onion:9735"}}
+		case "c":
+			node.Addresses = []*lnrpc.NodeAddress{{Network: "tcp", Addr: "203.0.113.1:9735"}, {Network: "tcp", Addr: "ln.example.com:9735"}}
+		}
+	}
+	return graph
+}
+
+// TestNetworkTopologyAnalyzer ensures the centrality, channel age, peer locations and top node peers of the node are reported
+func TestNetworkTopologyAnalyzer(t *testing.T) {
+	analyzer := NewNetworkTopologyAnalyzer()
+	analyzer.topNodes = 1
+	analyzer.lookupAddr = func(addr string) ([]string, error) {
+		if addr == "198.51.100.7" {
+			return []string{"node.example.de."}, nil
+		}
+		return nil, errors.Error("no such host")
+	}
+	analyzer.SetClient(&fakeLightningClient{
+		info:  &lnrpc.GetInfoResponse{IdentityPubkey: "self", BlockHeight: 1000},
+		graph: syntheticGraph(),
+	})
+	report, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Nodes != 20 || report.Edges != 21 || report.Peers != 3 || report.Sources != 20 {
+		t.Errorf("unexpected graph size: %+v", report)
+	}
+	// every path from a ring to the other ring or to c goes through self
+	if report.PercentileRank != 100 {
+		t.Errorf("expected self to be the most central node, got %+v", report)
+	}
+	// the channels are 144, 288 and 288 blocks old
+	if math.Abs(report.AverageChannelAgeDays-5.0/3) > 1e-9 {
+		t.Errorf("unexpected average channel age: %v", report.AverageChannelAgeDays)
+	}
+	// a0 has the largest capacity
+	if report.TopNodePeers != 1 {
+		t.Errorf("unexpected top node peers: %v", report.TopNodePeers)
+	}
+	// .com says nothing of the location of c
+	want := map[string]int{"de": 1, location_tor: 1, location_unknown: 1}
+	if len(report.PeerLocations) != len(want) {
+		t.Fatalf("unexpected peer locations: %v", report.PeerLocations)
+	}
+	for location, n := range want {
+		if report.PeerLocations[location] != n {
+			t.Errorf("unexpected peer locations: %v", report.PeerLocations)
+		}
+	}
+}
+
+// TestBetweenness ensures the exact betweenness counts shortest paths and the approximation stays close to it
+func TestBetweenness(t *testing.T) {
+	path := graphAdjacency(&lnrpc.ChannelGraph{Edges: []*lnrpc.ChannelEdge{graphEdge("a", "b", 1, 1), graphEdge("b", "c", 1, 1)}})
+	centrality, sources := betweenness(path, topology_max_sources)
+	if sources != 3 || centrality["a"] != 0 || centrality["b"] != 1 || centrality["c"] != 0 {
+		t.Fatalf("unexpected betweenness of a path: %v", centrality)
+	}
+	adjacency := graphAdjacency(syntheticGraph())
+	exact, _ := betweenness(adjacency, topology_max_sources)
+	approximate, sources := betweenness(adjacency, 10)
+	if sources != 10 {
+		t.Fatalf("expected 10 sources, got %d", sources)
+	}
+	for node, c := range approximate {
+		if node != "self" && c >= approximate["self"] {
+			t.Errorf("expected self to stay the most central node, %s has %v over %v", node, c, approximate["self"])
+		}
+	}
+	if math.Abs(approximate["self"]-exact["self"]) > 0.5*exact["self"] {
+		t.Errorf("approximate betweenness of self %v too far from %v", approximate["self"], exact["self"])
+	}
+}
+
+// TestTopologyRPC ensures conduit_topology fails with ErrLNDNotRunning until LND is active and then serves the report
+func TestTopologyRPC(t *testing.T) {
+	s, client := newTestRPCServer(t)
+	analyzer := NewNetworkTopologyAnalyzer()
+	s.RegisterTopology(analyzer)
+	var report TopologyReport
+	err := client.Call(context.Background(), "conduit_topology", nil, &report)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.ErrLNDNotRunning {
+		t.Fatalf("expected ErrLNDNotRunning before LND is active, got %v", err)
+	}
+	analyzer.SetClient(&fakeLightningClient{
+		info:  &lnrpc.GetInfoResponse{IdentityPubkey: "a", BlockHeight: 10},
+		graph: &lnrpc.ChannelGraph{Edges: []*lnrpc.ChannelEdge{graphEdge("a", "b", 1, 1)}},
+	})
+	if err := client.Call(context.Background(), "conduit_topology", nil, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Pubkey != "a" || report.Nodes != 2 || report.Peers != 1 || report.PeerLocations[location_unknown] != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
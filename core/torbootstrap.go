@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	default_tor_stall_timeout  = 60 * time.Second
+	tor_bootstrap_check_period = 5 * time.Second
+)
+
+// torBootstrapRegex matches the Tor bootstrap progress lines, i.e. `Bootstrapped 45% (requesting_descriptors): Asking for relay descriptors`
+var torBootstrapRegex = regexp.MustCompile(`Bootstrapped (\d{1,3})%(?: \(([^)]*)\))?`)
+
+// TorBootstrapMonitor follows the Tor bootstrap progress in the LND output, publishing EventTorBootstrapped once done and warning when it stalls
+type TorBootstrapMonitor struct {
+	sync.Mutex
+	bus          *EventReplay
+	log          *subLogger
+	stallTimeout time.Duration
+	now          func() time.Time
+	started      bool
+	progress     int
+	reason       string
+	lastProgress time.Time
+	done         bool
+	warned       bool
+}
+
+// NewTorBootstrapMonitor creates a new TorBootstrapMonitor publishing on the given EventReplay
+func NewTorBootstrapMonitor(bus *EventReplay, log *zerolog.Logger) *TorBootstrapMonitor {
+	return &TorBootstrapMonitor{
+		bus:          bus,
+		log:          NewSubLogger(log, "TORB"),
+		stallTimeout: default_tor_stall_timeout,
+		now:          time.Now,
+	}
+}
+
+// ParseLine implements the `LineParser` interface
+func (m *TorBootstrapMonitor) ParseLine(line string) {
+	captures := torBootstrapRegex.FindStringSubmatch(line)
+	if captures == nil {
+		return
+	}
+	progress, err := strconv.Atoi(captures[1])
+	if err != nil || progress > 100 {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.done {
+		return
+	}
+	if !m.started || progress > m.progress {
+		m.started, m.progress, m.reason = true, progress, captures[2]
+		m.lastProgress, m.warned = m.now(), false
+		m.log.SubLogger.Debug().Msg(fmt.Sprintf("Tor bootstrapped %d%% (%s)", progress, captures[2]))
+	}
+	if progress == 100 {
+		m.done = true
+		m.log.SubLogger.Info().Msg("Tor bootstrap complete, circuits established")
+		m.bus.Publish(EventTorBootstrapped, nil)
+	}
+}
+
+// Run checks for a stalled bootstrap until the context is cancelled
+func (m *TorBootstrapMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(tor_bootstrap_check_period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// check warns once per stall when the bootstrap didn't progress for the stall timeout
+func (m *TorBootstrapMonitor) check() {
+	m.Lock()
+	defer m.Unlock()
+	if !m.started || m.done || m.warned {
+		return
+	}
+	if stalled := m.now().Sub(m.lastProgress); stalled >= m.stallTimeout {
+		m.warned = true
+		m.log.SubLogger.Warn().Msg(fmt.Sprintf("Tor bootstrap stalled at %d%% (%s) for %v. Consider restarting Tor", m.progress, m.reason, stalled.Round(time.Second)))
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// feedLndOutput passes the given LND output through parseLndLog with the monitor registered
+func feedLndOutput(lines []string, monitor *TorBootstrapMonitor, log *zerolog.Logger) {
+	output := NewLNDProcessOutput()
+	output.Register(monitor)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	scanner := bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))
+	parseLndLog(scanner, log, regexp.MustCompile(lndLogRegex), nil, output, NewLNDLogAggregator(), make(chan struct{}), &wg)
+}
+
+// readTorFixture returns the lines of the canned LND output with Tor bootstrap lines
+func readTorFixture(t *testing.T) []string {
+	raw, err := os.ReadFile(path.Join("testdata", "lnd_tor_output.log"))
+	if err != nil {
+		t.Fatalf("could not read fixture: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(raw)), "\n")
+}
+
+// TestTorBootstrapMonitorComplete ensures EventTorBootstrapped is published once when Tor reaches 100%
+func TestTorBootstrapMonitorComplete(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	events, unsubscribe := bus.Subscribe(EventTorBootstrapped)
+	defer unsubscribe()
+	monitor := NewTorBootstrapMonitor(bus, &log)
+	lines := readTorFixture(t)
+	feedLndOutput(append(lines, lines[len(lines)-2]), monitor, &log)
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("EventTorBootstrapped was not published")
+	}
+	select {
+	case <-events:
+		t.Error("EventTorBootstrapped was published twice")
+	default:
+	}
+	if !strings.Contains(buf.String(), "Tor bootstrap complete") {
+		t.Errorf("missing INFO log: %s", buf.String())
+	}
+	monitor.now = func() time.Time { return time.Now().Add(time.Hour) }
+	monitor.check()
+	if strings.Contains(buf.String(), "stalled") {
+		t.Errorf("completed bootstrap reported as stalled: %s", buf.String())
+	}
+}
+
+// TestTorBootstrapMonitorStall ensures a single warning is logged when Tor stops progressing for 60s
+func TestTorBootstrapMonitorStall(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	monitor := NewTorBootstrapMonitor(NewEventReplay(NewEventBus(), default_event_replay_size), &log)
+	now := time.Unix(1650000000, 0)
+	monitor.now = func() time.Time { return now }
+	// the output stops at 75%
+	feedLndOutput(readTorFixture(t)[:8], monitor, &log)
+	now = now.Add(59 * time.Second)
+	monitor.check()
+	if strings.Contains(buf.String(), "stalled") {
+		t.Fatalf("stall reported too early: %s", buf.String())
+	}
+	now = now.Add(time.Second)
+	monitor.check()
+	monitor.check()
+	if n := strings.Count(buf.String(), "Tor bootstrap stalled at 75% (enough_dirinfo) for 1m0s"); n != 1 {
+		t.Errorf("expected one stall warning, got %d: %s", n, buf.String())
+	}
+	// progress resets the stall timer
+	monitor.ParseLine("Bootstrapped 90% (ap_handshake_done): Handshake finished")
+	monitor.check()
+	if n := strings.Count(buf.String(), "stalled"); n != 1 {
+		t.Errorf("expected no new stall warning after progress, got %d warnings", n)
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	lnd_latest_release_url      = "https://api.github.com/repos/lightningnetwork/lnd/releases/latest"
+	default_update_check_period = 24 * time.Hour
+	update_check_timeout        = 30 * time.Second
+)
+
+// lndVersionRegex matches the version in the output of lnd --version, i.e. lnd version 0.14.2-beta commit=v0.14.2-beta
+var lndVersionRegex = regexp.MustCompile(`version (\S+)`)
+
+// parseLNDVersion parses an LND version or release tag, with or without the leading v
+func parseLNDVersion(s string) (semver.Version, error) {
+	return semver.Parse(strings.TrimPrefix(strings.TrimSpace(s), "v"))
+}
+
+// LNDVersionCache runs lnd --version once and keeps the installed version
+type LNDVersionCache struct {
+	sync.Mutex
+	read    func() ([]byte, error)
+	version *semver.Version
+}
+
+// NewLNDVersionCache creates a new LNDVersionCache for the lnd binary at lndPath, whose signature must be verified first
+func NewLNDVersionCache(lndPath string) *LNDVersionCache {
+	return &LNDVersionCache{
+		read: func() ([]byte, error) {
+			return exec.Command(lndPath, "--version").CombinedOutput()
+		},
+	}
+}
+
+// Version returns the installed LND version
+func (c *LNDVersionCache) Version() (semver.Version, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.version != nil {
+		return *c.version, nil
+	}
+	out, err := c.read()
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("could not run lnd --version: %v", err)
+	}
+	match := lndVersionRegex.FindSubmatch(out)
+	if match == nil {
+		return semver.Version{}, fmt.Errorf("unexpected lnd --version output %q", out)
+	}
+	version, err := parseLNDVersion(string(match[1]))
+	if err != nil {
+		return semver.Version{}, err
+	}
+	c.version = &version
+	return version, nil
+}
+
+// githubRelease is the part of a GitHub release we need
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+}
+
+// LNDUpdateChecker periodically compares the latest LND release on GitHub to the installed version and logs when an upgrade is available
+type LNDUpdateChecker struct {
+	url      string
+	client   *http.Client
+	versions *LNDVersionCache
+	interval time.Duration
+	log      *subLogger
+}
+
+// NewLNDUpdateChecker creates a new LNDUpdateChecker checking once a day
+func NewLNDUpdateChecker(versions *LNDVersionCache, log *zerolog.Logger) *LNDUpdateChecker {
+	return &LNDUpdateChecker{
+		url:      lnd_latest_release_url,
+		client:   &http.Client{Timeout: update_check_timeout},
+		versions: versions,
+		interval: default_update_check_period,
+		log:      NewSubLogger(log, "UPDT"),
+	}
+}
+
+// Run checks for an update right away and then every interval until the context is cancelled
+func (c *LNDUpdateChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if _, _, err := c.Check(ctx); err != nil && ctx.Err() == nil {
+			c.log.SubLogger.Debug().Msg(fmt.Sprintf("could not check for LND updates: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the latest LND release and returns it along with whether it's newer than the installed version
+func (c *LNDUpdateChecker) Check(ctx context.Context) (string, bool, error) {
+	installed, err := c.versions.Version()
+	if err != nil {
+		return "", false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("unexpected status %v from %v", resp.Status, c.url)
+	}
+	var release githubRelease
+	if err = json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", false, fmt.Errorf("could not decode release: %v", err)
+	}
+	latest, err := parseLNDVersion(release.TagName)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid release tag %q: %v", release.TagName, err)
+	}
+	if !latest.GT(installed) {
+		return release.TagName, false, nil
+	}
+	c.log.SubLogger.Info().Str("installed", installed.String()).Str("latest", release.TagName).Msg(fmt.Sprintf("LND %s is available, installed version is %s", release.TagName, installed))
+	return release.TagName, true, nil
+}
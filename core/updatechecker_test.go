@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// newTestUpdateChecker returns an LNDUpdateChecker querying a fake GitHub API returning tag as the latest release, for an installed LND of the given version output
+func newTestUpdateChecker(t *testing.T, tag, versionOutput string, buf *bytes.Buffer) *LNDUpdateChecker {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/lightningnetwork/lnd/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"tag_name": %q, "name": "lnd %s", "prerelease": false}`, tag, tag)
+	}))
+	t.Cleanup(ts.Close)
+	log := zerolog.New(buf)
+	versions := &LNDVersionCache{read: func() ([]byte, error) { return []byte(versionOutput), nil }}
+	checker := NewLNDUpdateChecker(versions, &log)
+	checker.url = ts.URL + "/repos/lightningnetwork/lnd/releases/latest"
+	return checker
+}
+
+func TestLNDUpdateChecker(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		tag     string
+		upgrade bool
+	}{
+		{"newer release", "v0.15.0-beta", true},
+		{"same release", "v0.14.2-beta", false},
+		{"older release", "v0.13.4-beta", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			checker := newTestUpdateChecker(t, test.tag, "lnd version 0.14.2-beta commit=v0.14.2-beta\n", &buf)
+			latest, upgrade, err := checker.Check(context.Background())
+			if err != nil {
+				t.Fatalf("Check returned an error: %v", err)
+			}
+			if latest != test.tag || upgrade != test.upgrade {
+				t.Fatalf("expected %v, %v, got %v, %v", test.tag, test.upgrade, latest, upgrade)
+			}
+			logged := strings.Contains(buf.String(), `"latest":"`+test.tag+`"`)
+			if logged != test.upgrade {
+				t.Errorf("unexpected log output: %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestLNDVersionCache(t *testing.T) {
+	calls := 0
+	cache := &LNDVersionCache{read: func() ([]byte, error) {
+		calls++
+		return []byte("lnd version 0.14.2-beta commit=v0.14.2-beta"), nil
+	}}
+	for i := 0; i < 2; i++ {
+		version, err := cache.Version()
+		if err != nil || version.String() != "0.14.2-beta" {
+			t.Fatalf("unexpected version %v (%v)", version, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("lnd --version ran %d times", calls)
+	}
+	cache = &LNDVersionCache{read: func() ([]byte, error) { return []byte("unknown flag"), nil }}
+	if _, err := cache.Version(); err == nil {
+		t.Error("expected an error for an unexpected output")
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TheRebelOfBabylon/Conduit/errors"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ErrWalletStateUnsupported = errors.Error("the running LND version does not support the state service")
+	wallet_state_poll_time    = 1 * time.Second
+)
+
+// WalletStateChange is the payload of EventWalletState events
+type WalletStateChange struct {
+	Previous lnrpc.WalletState
+	Current  lnrpc.WalletState
+}
+
+// WalletStatePoller polls LND's state service and publishes wallet state changes on the event bus
+type WalletStatePoller struct {
+	client     lnrpc.StateClient
+	bus        *EventReplay
+	log        *subLogger
+	interval   time.Duration
+	unlockFile string
+}
+
+// NewWalletStatePoller creates a WalletStatePoller publishing on the given event bus
+func NewWalletStatePoller(client lnrpc.StateClient, bus *EventReplay, config *Config, log *zerolog.Logger) *WalletStatePoller {
+	return &WalletStatePoller{
+		client:     client,
+		bus:        bus,
+		log:        NewSubLogger(log, "WLLT"),
+		interval:   wallet_state_poll_time,
+		unlockFile: config.LndWalletUnlockPasswordFile,
+	}
+}
+
+// Run polls the wallet state until the context is cancelled. It returns ErrWalletStateUnsupported if LND has no state service
+func (p *WalletStatePoller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	// until we've heard from LND, it hasn't started as far as we're concerned
+	previous := lnrpc.WalletState_WAITING_TO_START
+	for {
+		resp, err := p.client.GetState(ctx, &lnrpc.GetStateRequest{})
+		if status.Code(err) == codes.Unimplemented {
+			return ErrWalletStateUnsupported
+		} else if err != nil && ctx.Err() == nil {
+			p.log.SubLogger.Debug().Msg(fmt.Sprintf("could not get wallet state: %v", err))
+		} else if err == nil && resp.State != previous {
+			p.onChange(previous, resp.State)
+			previous = resp.State
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// onChange publishes the state change and tells the operator what to do when the wallet needs their attention
+func (p *WalletStatePoller) onChange(previous, current lnrpc.WalletState) {
+	p.log.SubLogger.Info().Msg(fmt.Sprintf("wallet state changed from %s to %s", previous, current))
+	p.bus.Publish(EventWalletState, WalletStateChange{Previous: previous, Current: current})
+	switch current {
+	case lnrpc.WalletState_NON_EXISTING:
+		p.log.SubLogger.Warn().Msg("no wallet exists yet. Create one with lncli create")
+	case lnrpc.WalletState_LOCKED:
+		if p.unlockFile != "" {
+			p.log.SubLogger.Info().Msg(fmt.Sprintf("waiting for LND to unlock the wallet using %s", p.unlockFile))
+		} else {
+			p.log.SubLogger.Warn().Msg("wallet is locked. Unlock it with lncli unlock or set wallet-unlock-password-file")
+		}
+	}
+}
+
+// waitForWalletState blocks until a change to the given wallet state is received. It returns false if the context is cancelled first
+func waitForWalletState(ctx context.Context, events <-chan Event, state lnrpc.WalletState) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if change, ok := event.Payload.(WalletStateChange); ok && change.Current == state {
+				return true
+			}
+		}
+	}
+}
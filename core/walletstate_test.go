@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// fakeStateServer returns the next state of its sequence on every GetState call, repeating the last one
+type fakeStateServer struct {
+	lnrpc.UnimplementedStateServer
+	sync.Mutex
+	states []lnrpc.WalletState
+}
+
+func (s *fakeStateServer) GetState(ctx context.Context, req *lnrpc.GetStateRequest) (*lnrpc.GetStateResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+	state := s.states[0]
+	if len(s.states) > 1 {
+		s.states = s.states[1:]
+	}
+	return &lnrpc.GetStateResponse{State: state}, nil
+}
+
+// newTestStateClient serves the given state server over a local gRPC connection
+func newTestStateClient(t *testing.T, srv lnrpc.StateServer) lnrpc.StateClient {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	server := grpc.NewServer()
+	lnrpc.RegisterStateServer(server, srv)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error dialing gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return lnrpc.NewStateClient(conn)
+}
+
+// TestWalletStatePoller ensures every state change is published exactly once
+func TestWalletStatePoller(t *testing.T) {
+	srv := &fakeStateServer{states: []lnrpc.WalletState{
+		lnrpc.WalletState_NON_EXISTING,
+		lnrpc.WalletState_NON_EXISTING,
+		lnrpc.WalletState_LOCKED,
+		lnrpc.WalletState_UNLOCKED,
+	}}
+	bus := NewEventReplay(NewEventBus(), default_event_replay_size)
+	events, unsubscribe := bus.Subscribe(EventWalletState)
+	defer unsubscribe()
+	log := zerolog.Nop()
+	poller := NewWalletStatePoller(newTestStateClient(t, srv), bus, &Config{}, &log)
+	poller.interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- poller.Run(ctx) }()
+	expected := []WalletStateChange{
+		{lnrpc.WalletState_WAITING_TO_START, lnrpc.WalletState_NON_EXISTING},
+		{lnrpc.WalletState_NON_EXISTING, lnrpc.WalletState_LOCKED},
+		{lnrpc.WalletState_LOCKED, lnrpc.WalletState_UNLOCKED},
+	}
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			if got := event.Payload.(WalletStateChange); got != want {
+				t.Errorf("expected state change %v, got %v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for state change %v", want)
+		}
+	}
+	// the state no longer changes so nothing else should be published
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event: %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned an error: %v", err)
+	}
+}
+
+// TestWalletStatePollerUnsupported ensures the poller gives up when LND has no state service
+func TestWalletStatePollerUnsupported(t *testing.T) {
+	log := zerolog.Nop()
+	poller := NewWalletStatePoller(newTestStateClient(t, &lnrpc.UnimplementedStateServer{}), NewEventReplay(NewEventBus(), default_event_replay_size), &Config{}, &log)
+	if err := poller.Run(context.Background()); err != ErrWalletStateUnsupported {
+		t.Errorf("expected ErrWalletStateUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package intercept
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalsToCatch are the signals requesting a shutdown
+var signalsToCatch = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+	syscall.SIGQUIT,
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package intercept
+
+import "os"
+
+// signalsToCatch are the signals requesting a shutdown. Windows has no SIGTERM or SIGQUIT to send to a process,
+// only Ctrl+C and Ctrl+Break are delivered as os.Interrupt. Stopping Conduit with taskkill /F or a service manager isn't graceful
+var signalsToCatch = []os.Signal{
+	os.Interrupt,
+}
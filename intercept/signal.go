@@ -26,7 +26,6 @@ import (
 	"os"
 	"os/signal"
 	"sync/atomic"
-	"syscall"
 
 	"github.com/rs/zerolog"
 )
@@ -120,12 +119,6 @@ func InitInterceptor() (*Interceptor, error) {
 		shutdownRequestChannel: make(chan struct{}),
 		quit:                   make(chan struct{}),
 	}
-	signalsToCatch := []os.Signal{
-		os.Interrupt,
-		os.Kill,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-	}
 	signal.Notify(interceptor.interruptChannel, signalsToCatch...)
 	go interceptor.mainInterruptHandler()
 	return &interceptor, nil
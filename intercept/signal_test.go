@@ -0,0 +1,19 @@
+package intercept
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSignalsToCatch ensures an interrupt requests a shutdown on every platform
+func TestSignalsToCatch(t *testing.T) {
+	if len(signalsToCatch) == 0 {
+		t.Fatal("no signal is caught")
+	}
+	for _, s := range signalsToCatch {
+		if s == os.Interrupt {
+			return
+		}
+	}
+	t.Errorf("os.Interrupt is not caught: %v", signalsToCatch)
+}
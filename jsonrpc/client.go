@@ -0,0 +1,122 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Client calls methods on a remote JSON-RPC server over HTTP
+type Client struct {
+	url        string
+	httpClient *http.Client
+	nextID     uint64
+}
+
+// NewClient creates a new client for the given endpoint. Endpoints can be of the form `unix:///path/to/socket`, `tcp://host:port`, `http://host:port` or `host:port`
+func NewClient(endpoint string) (*Client, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		socket := strings.TrimPrefix(endpoint, "unix://")
+		if socket == "" {
+			return nil, fmt.Errorf("jsonrpc: invalid endpoint %s", endpoint)
+		}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Client{url: "http://unix", httpClient: &http.Client{Transport: transport}}, nil
+	case strings.HasPrefix(endpoint, "tcp://"):
+		return &Client{url: "http://" + strings.TrimPrefix(endpoint, "tcp://"), httpClient: &http.Client{}}, nil
+	case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+		return &Client{url: endpoint, httpClient: &http.Client{}}, nil
+	case endpoint != "":
+		return &Client{url: "http://" + endpoint, httpClient: &http.Client{}}, nil
+	}
+	return nil, fmt.Errorf("jsonrpc: empty endpoint")
+}
+
+// NewTLSClient creates a new client for the given endpoint over HTTPS, trusting the certificate at certPath, i.e. the JsonRPCTLSCertPath of
+// Conduit. Endpoints can be of the form `https://host:port`, `tcp://host:port` or `host:port`
+func NewTLSClient(endpoint, certPath string) (*Client, error) {
+	raw, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: could not read TLS certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("jsonrpc: no PEM certificate in %s", certPath)
+	}
+	var url string
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		url = endpoint
+	case strings.HasPrefix(endpoint, "tcp://"):
+		url = "https://" + strings.TrimPrefix(endpoint, "tcp://")
+	case strings.HasPrefix(endpoint, "unix://"), strings.HasPrefix(endpoint, "http://"):
+		return nil, fmt.Errorf("jsonrpc: endpoint %s can't be used with TLS", endpoint)
+	case endpoint != "":
+		url = "https://" + endpoint
+	default:
+		return nil, fmt.Errorf("jsonrpc: empty endpoint")
+	}
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}}
+	return &Client{url: url, httpClient: &http.Client{Transport: transport}}, nil
+}
+
+// CallRaw calls the given method with already serialized params and returns the raw result
+func (c *Client) CallRaw(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	id, _ := json.Marshal(atomic.AddUint64(&c.nextID, 1))
+	body, err := json.Marshal(Request{JSONRPC: version, Method: method, Params: params, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	var resp Response
+	if err = json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("jsonrpc: could not decode response: %v", err)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+// Call calls the given method with params and decodes the result into result, if non-nil
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	var raw json.RawMessage
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	res, err := c.CallRaw(ctx, method, raw)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(res) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res, result)
+}
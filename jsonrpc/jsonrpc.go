@@ -0,0 +1,67 @@
+// Package jsonrpc implements a minimal JSON-RPC 2.0 server and client over HTTP
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResponseErrorCode is the code of a JSON-RPC error object
+type ResponseErrorCode int
+
+// Error codes defined by the JSON-RPC 2.0 specification. The JSONRPC_SERVER_ERR_MIN to JSONRPC_SERVER_ERR_MAX range is reserved for application errors
+const (
+	JSONRPC_PARSE_ERR        ResponseErrorCode = -32700
+	JSONRPC_INVALID_REQUEST  ResponseErrorCode = -32600
+	JSONRPC_METHOD_NOT_FOUND ResponseErrorCode = -32601
+	JSONRPC_INVALID_PARAMS   ResponseErrorCode = -32602
+	JSONRPC_INTERNAL_ERR     ResponseErrorCode = -32603
+	JSONRPC_SERVER_ERR_MIN   ResponseErrorCode = -32099
+	JSONRPC_SERVER_ERR_MAX   ResponseErrorCode = -32000
+)
+
+// Conduit specific error codes in the JSONRPC_SERVER_ERR_MIN to JSONRPC_SERVER_ERR_MAX range
+const (
+	ErrLNDNotRunning      ResponseErrorCode = -32000
+	ErrPluginNotFound     ResponseErrorCode = -32001
+	ErrConfigInvalid      ResponseErrorCode = -32002
+	ErrShutdownInProgress ResponseErrorCode = -32003
+	ErrAuthRequired       ResponseErrorCode = -32004
+	ErrRateLimited        ResponseErrorCode = -32005
+	ErrMethodTimeout      ResponseErrorCode = -32006
+)
+
+const version = "2.0"
+
+// Request is a JSON-RPC 2.0 request object
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response object
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is a JSON-RPC 2.0 error object. It implements the error interface so handlers can return it directly
+type Error struct {
+	Code    ResponseErrorCode `json:"code"`
+	Message string            `json:"message"`
+	Data    interface{}       `json:"data,omitempty"`
+}
+
+// Error implements the golang standard library error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+// NewError creates a new JSON-RPC error with the given code and message
+func NewError(code ResponseErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
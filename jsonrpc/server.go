@@ -0,0 +1,209 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var methodNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// HandlerFunc handles the params of a JSON-RPC call and returns a JSON serializable result
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// MiddlewareFunc wraps the handler of the named method, i.e. to reject calls before they reach it
+type MiddlewareFunc func(method string, next HandlerFunc) HandlerFunc
+
+// Server dispatches JSON-RPC requests received over HTTP to the registered handlers
+type Server struct {
+	sync.RWMutex
+	methods     map[string]HandlerFunc
+	timeouts    map[string]time.Duration
+	middlewares []MiddlewareFunc
+	// defaultTimeout applies to the methods registered without a timeout, there's no limit when 0
+	defaultTimeout time.Duration
+}
+
+// NewServer creates a new JSON-RPC server with no registered methods
+func NewServer() *Server {
+	return &Server{
+		methods:  make(map[string]HandlerFunc),
+		timeouts: make(map[string]time.Duration),
+	}
+}
+
+// Register adds a handler for the given method name, replacing any existing handler. The handler is subject to the default timeout
+func (s *Server) Register(name string, handler HandlerFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.methods[name] = handler
+	delete(s.timeouts, name)
+}
+
+// RegisterWithTimeout adds a handler for the given method name, replacing any existing handler. Calls not returning within the timeout are cancelled and fail with ErrMethodTimeout
+func (s *Server) RegisterWithTimeout(name string, timeout time.Duration, handler HandlerFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.methods[name] = handler
+	s.timeouts[name] = timeout
+}
+
+// SetDefaultTimeout sets the timeout of the methods registered without one. A timeout of 0 disables it
+func (s *Server) SetDefaultTimeout(timeout time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.defaultTimeout = timeout
+}
+
+// Use adds a middleware wrapping the handlers of every method. Middlewares run in the order they were added, within the method timeout
+func (s *Server) Use(middleware MiddlewareFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.middlewares = append(s.middlewares, middleware)
+}
+
+// RegisterBatch adds all the given handlers at once. The whole batch is rejected if a name is empty, contains characters other than [a-z0-9_] or is already registered
+func (s *Server) RegisterBatch(methods map[string]HandlerFunc) error {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	// sorted so that the reported error doesn't depend on map order
+	sort.Strings(names)
+	s.Lock()
+	defer s.Unlock()
+	for _, name := range names {
+		if !methodNameRegex.MatchString(name) {
+			return fmt.Errorf("jsonrpc: invalid method name %q", name)
+		}
+		if _, ok := s.methods[name]; ok {
+			return fmt.Errorf("jsonrpc: method %s is already registered", name)
+		}
+		if methods[name] == nil {
+			return fmt.Errorf("jsonrpc: method %s has no handler", name)
+		}
+	}
+	for name, handler := range methods {
+		s.methods[name] = handler
+		delete(s.timeouts, name)
+	}
+	return nil
+}
+
+// Unregister removes the handler of the given method name, if any
+func (s *Server) Unregister(name string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.methods, name)
+	delete(s.timeouts, name)
+}
+
+// handler returns the handler registered for the given method name wrapped by the middlewares, along with its timeout
+func (s *Server) handler(name string) (HandlerFunc, time.Duration, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	h, ok := s.methods[name]
+	timeout, explicit := s.timeouts[name]
+	if !explicit {
+		timeout = s.defaultTimeout
+	}
+	if ok {
+		for i := len(s.middlewares) - 1; i >= 0; i-- {
+			h = s.middlewares[i](name, h)
+		}
+	}
+	return h, timeout, ok
+}
+
+// callWithTimeout runs the handler with a context cancelled after the timeout. It returns as soon as the timeout expires, even if the handler ignores the cancellation
+func callWithTimeout(ctx context.Context, name string, timeout time.Duration, h HandlerFunc, params json.RawMessage) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := h(ctx, params)
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, NewError(ErrMethodTimeout, fmt.Sprintf("%s timed out after %v", name, timeout))
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, Response{Error: NewError(JSONRPC_PARSE_ERR, err.Error())})
+		return
+	}
+	writeResponse(w, s.call(r.Context(), req))
+}
+
+// call executes a single request and builds its response
+func (s *Server) call(ctx context.Context, req Request) Response {
+	resp := Response{ID: req.ID}
+	if req.JSONRPC != version || req.Method == "" {
+		resp.Error = NewError(JSONRPC_INVALID_REQUEST, "invalid request")
+		return resp
+	}
+	h, timeout, ok := s.handler(req.Method)
+	if !ok {
+		resp.Error = NewError(JSONRPC_METHOD_NOT_FOUND, "method not found: "+req.Method)
+		return resp
+	}
+	var (
+		result interface{}
+		err    error
+	)
+	if timeout > 0 {
+		result, err = callWithTimeout(ctx, req.Method, timeout, h, req.Params)
+	} else {
+		result, err = h(ctx, req.Params)
+	}
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			resp.Error = rpcErr
+		} else {
+			resp.Error = NewError(JSONRPC_INTERNAL_ERR, err.Error())
+		}
+		return resp
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = NewError(JSONRPC_INTERNAL_ERR, err.Error())
+		return resp
+	}
+	resp.Result = raw
+	return resp
+}
+
+// writeResponse writes a JSON-RPC response to the HTTP response writer
+func writeResponse(w http.ResponseWriter, resp Response) {
+	resp.JSONRPC = version
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
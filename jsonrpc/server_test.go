@@ -0,0 +1,151 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func echo(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return params, nil
+}
+
+// TestRegisterBatch ensures a batch is registered entirely or not at all
+func TestRegisterBatch(t *testing.T) {
+	s := NewServer()
+	s.Register("existing_method", echo)
+	invalid := []map[string]HandlerFunc{
+		{"new_method": echo, "": echo},
+		{"new_method": echo, "Bad-Name": echo},
+		{"new_method": echo, "existing_method": echo},
+		{"new_method": echo, "nil_method": nil},
+	}
+	for _, batch := range invalid {
+		if err := s.RegisterBatch(batch); err == nil {
+			t.Errorf("RegisterBatch accepted %v", batch)
+		}
+		if _, _, ok := s.handler("new_method"); ok {
+			t.Fatalf("RegisterBatch partially registered a rejected batch")
+		}
+	}
+	if err := s.RegisterBatch(map[string]HandlerFunc{"method_1": echo, "method_2": echo}); err != nil {
+		t.Fatalf("RegisterBatch returned an error: %v", err)
+	}
+	for _, name := range []string{"existing_method", "method_1", "method_2"} {
+		if _, _, ok := s.handler(name); !ok {
+			t.Errorf("method %s is not registered", name)
+		}
+	}
+	resp := s.call(context.Background(), Request{JSONRPC: version, Method: "method_1", Params: json.RawMessage(`"hi"`)})
+	if resp.Error != nil || string(resp.Result) != `"hi"` {
+		t.Errorf("unexpected response from a batch registered method: %+v", resp)
+	}
+}
+
+// TestUnregister ensures an unregistered method can no longer be called and can be registered again
+func TestUnregister(t *testing.T) {
+	s := NewServer()
+	s.Register("plugin_method", echo)
+	s.Unregister("plugin_method")
+	// unregistering an unknown method is a no-op
+	s.Unregister("unknown_method")
+	resp := s.call(context.Background(), Request{JSONRPC: version, Method: "plugin_method"})
+	if resp.Error == nil || resp.Error.Code != JSONRPC_METHOD_NOT_FOUND {
+		t.Errorf("expected method not found, got %+v", resp)
+	}
+	if err := s.RegisterBatch(map[string]HandlerFunc{"plugin_method": echo}); err != nil {
+		t.Errorf("could not register an unregistered method again: %v", err)
+	}
+}
+
+// slow returns a handler blocking until the delay expires or its context is done, in which case the context error is sent to cancelled
+func slow(delay time.Duration, cancelled chan<- error) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		case <-time.After(delay):
+			return "done", nil
+		}
+	}
+}
+
+// TestRegisterWithTimeout ensures a slow handler is cancelled at its deadline and the call fails with ErrMethodTimeout
+func TestRegisterWithTimeout(t *testing.T) {
+	s := NewServer()
+	cancelled := make(chan error, 1)
+	s.RegisterWithTimeout("slow_method", 50*time.Millisecond, slow(time.Minute, cancelled))
+	start := time.Now()
+	resp := s.call(context.Background(), Request{JSONRPC: version, Method: "slow_method"})
+	elapsed := time.Since(start)
+	if resp.Error == nil || resp.Error.Code != ErrMethodTimeout {
+		t.Fatalf("expected ErrMethodTimeout, got %+v", resp)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("call returned after %v", elapsed)
+	}
+	select {
+	case err := <-cancelled:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected the handler context to exceed its deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("the handler context was not cancelled")
+	}
+	// a handler returning in time is unaffected
+	s.RegisterWithTimeout("fast_method", time.Second, slow(time.Millisecond, cancelled))
+	if resp = s.call(context.Background(), Request{JSONRPC: version, Method: "fast_method"}); resp.Error != nil || string(resp.Result) != `"done"` {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+// TestDefaultTimeout ensures the default timeout applies to methods registered without one, and not to those with an explicit timeout
+func TestDefaultTimeout(t *testing.T) {
+	s := NewServer()
+	cancelled := make(chan error, 2)
+	s.Register("default_method", slow(200*time.Millisecond, cancelled))
+	s.RegisterWithTimeout("explicit_method", time.Second, slow(200*time.Millisecond, cancelled))
+	// without a default timeout the slow handler completes
+	if resp := s.call(context.Background(), Request{JSONRPC: version, Method: "default_method"}); resp.Error != nil {
+		t.Fatalf("unexpected error %+v", resp.Error)
+	}
+	s.SetDefaultTimeout(20 * time.Millisecond)
+	if resp := s.call(context.Background(), Request{JSONRPC: version, Method: "default_method"}); resp.Error == nil || resp.Error.Code != ErrMethodTimeout {
+		t.Errorf("expected ErrMethodTimeout, got %+v", resp)
+	}
+	if resp := s.call(context.Background(), Request{JSONRPC: version, Method: "explicit_method"}); resp.Error != nil {
+		t.Errorf("the default timeout overrode the explicit one: %+v", resp.Error)
+	}
+}
+
+// TestUse ensures middlewares wrap every method in the order they were added and can reject calls
+func TestUse(t *testing.T) {
+	s := NewServer()
+	s.Register("echo_method", echo)
+	var order []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		s.Use(func(method string, next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				order = append(order, name+":"+method)
+				if string(params) == `"reject"` {
+					return nil, NewError(ErrRateLimited, "rejected")
+				}
+				return next(ctx, params)
+			}
+		})
+	}
+	resp := s.call(context.Background(), Request{JSONRPC: version, Method: "echo_method", Params: json.RawMessage(`"hi"`)})
+	if resp.Error != nil || string(resp.Result) != `"hi"` {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(order) != 2 || order[0] != "first:echo_method" || order[1] != "second:echo_method" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+	resp = s.call(context.Background(), Request{JSONRPC: version, Method: "echo_method", Params: json.RawMessage(`"reject"`)})
+	if resp.Error == nil || resp.Error.Code != ErrRateLimited {
+		t.Errorf("expected the middleware to reject the call, got %+v", resp)
+	}
+}
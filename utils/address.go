@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// base58AddressRegex matches P2PKH and P2SH addresses
+	base58AddressRegex = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{25,35}$`)
+	// bech32AddressRegex matches lowercase segwit addresses, the human readable part followed by 1 and the data part
+	bech32AddressRegex = regexp.MustCompile(`^([a-z]+)1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{8,87}$`)
+)
+
+// addressPrefixes are the first characters of the base58 addresses and the human readable part of the bech32 addresses of every network
+var addressPrefixes = map[string]struct {
+	base58 string
+	bech32 string
+}{
+	"mainnet": {"13", "bc"},
+	"testnet": {"mn2", "tb"},
+	"signet":  {"mn2", "tb"},
+	"regtest": {"mn2", "bcrt"},
+}
+
+// ValidateBitcoinAddress checks that address looks like a Bitcoin address of the given network. It doesn't verify the checksum, LND does before spending.
+// Addresses of networks other than mainnet, testnet, signet and regtest are only checked against the address formats
+func ValidateBitcoinAddress(address, network string) error {
+	prefixes, known := addressPrefixes[network]
+	// bech32 addresses are either all lowercase or all uppercase
+	if lower := strings.ToLower(address); address == lower || address == strings.ToUpper(address) {
+		if match := bech32AddressRegex.FindStringSubmatch(lower); match != nil {
+			if known && match[1] != prefixes.bech32 {
+				return fmt.Errorf("%v is not a %s address", address, network)
+			}
+			return nil
+		}
+	}
+	if base58AddressRegex.MatchString(address) {
+		if known && !strings.ContainsRune(prefixes.base58, rune(address[0])) {
+			return fmt.Errorf("%v is not a %s address", address, network)
+		}
+		return nil
+	}
+	return fmt.Errorf("%v is not a valid Bitcoin address", address)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+// TestValidateBitcoinAddress ensures addresses are checked against the formats and prefixes of their network
+func TestValidateBitcoinAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		ok      bool
+	}{
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "mainnet", true},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "mainnet", true},
+		{"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "mainnet", true},
+		{"BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ", "mainnet", true},
+		{"bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297", "mainnet", true},
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "testnet", true},
+		{"2MzQwSSnBHWHqSAqtTVQ6v47XtaisrJa1Vc", "testnet", true},
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", "testnet", true},
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", "regtest", true},
+		{"sb1qw508d6qejxtdg4y5r3zarvary0c5xw7k3n6qa5", "simnet", true},
+		// addresses of another network
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", "mainnet", false},
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "mainnet", false},
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "testnet", false},
+		{"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "regtest", false},
+		// malformed addresses
+		{"", "mainnet", false},
+		{"bc1qAr0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "mainnet", false},
+		{"bc1qbr0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "mainnet", false},
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN0", "mainnet", false},
+		{"1short", "mainnet", false},
+	}
+	for _, test := range tests {
+		err := ValidateBitcoinAddress(test.address, test.network)
+		if test.ok && err != nil {
+			t.Errorf("expected %q to be a valid %s address, got %v", test.address, test.network, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("expected %q not to be a valid %s address", test.address, test.network)
+		}
+	}
+}
@@ -54,3 +54,62 @@ func UniqueFileName(path string) string {
 	}
 	return path
 }
+
+// AtomicWriteFile writes data to a temporary file in the same directory and renames it over filename, so readers never observe a partially written file
+func AtomicWriteFile(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// AtomicAppend appends data to filename in a single write so that concurrent appenders never interleave their records
+func AtomicAppend(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ExpandEnvVars replaces $VAR and ${VAR} in s with the value of the environment variable. Unset variables are replaced by an empty string
+func ExpandEnvVars(s string) string {
+	return os.ExpandEnv(s)
+}
+
+// ExpandPath expands the environment variables in s and returns the absolute path
+func ExpandPath(s string) string {
+	expanded := ExpandEnvVars(s)
+	if expanded == "" {
+		return expanded
+	}
+	abs, err := filepath.Abs(expanded)
+	if err != nil {
+		return expanded
+	}
+	return abs
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExpandEnvVars ensures both variable syntaxes are expanded and unset variables expand to an empty string
+func TestExpandEnvVars(t *testing.T) {
+	t.Setenv("CONDUIT_TEST_VAR", "value")
+	os.Unsetenv("CONDUIT_TEST_UNSET")
+	for in, want := range map[string]string{
+		"$CONDUIT_TEST_VAR/dir":   "value/dir",
+		"${CONDUIT_TEST_VAR}dir":  "valuedir",
+		"$CONDUIT_TEST_UNSET/dir": "/dir",
+		"${CONDUIT_TEST_UNSET}":   "",
+		"no variables":            "no variables",
+	} {
+		if got := ExpandEnvVars(in); got != want {
+			t.Errorf("ExpandEnvVars(%q) = %q, expected %q", in, got, want)
+		}
+	}
+}
+
+// TestExpandPath ensures paths are expanded to absolute paths
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if got := ExpandPath("$HOME/foo"); got != filepath.Join(home, "foo") {
+		t.Errorf("ExpandPath($HOME/foo) = %q, expected %q", got, filepath.Join(home, "foo"))
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error getting working directory: %v", err)
+	}
+	if got := ExpandPath("relative/../foo"); got != filepath.Join(wd, "foo") {
+		t.Errorf("ExpandPath(relative/../foo) = %q, expected %q", got, filepath.Join(wd, "foo"))
+	}
+	if got := ExpandPath(""); got != "" {
+		t.Errorf("ExpandPath(\"\") = %q, expected an empty string", got)
+	}
+}
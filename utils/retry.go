@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const defaultRetryMultiplier = 2
+
+var (
+	// jitter is seeded explicitly since the global source of math/rand is deterministic before Go 1.20, which would make every process wait the same
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMu sync.Mutex
+)
+
+// RetryOptions configures RetryWithBackoff
+type RetryOptions struct {
+	// MaxAttempts is the number of calls of the operation, including the first one. The operation is retried until it succeeds when 0
+	MaxAttempts int
+	// BaseDelay is the longest wait before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the longest wait between two attempts. Not capped when 0
+	MaxDelay time.Duration
+	// Multiplier is by how much the longest wait grows after each attempt. Defaults to 2
+	Multiplier float64
+	// RetryIf reports whether the operation is retried after the given error. Every error is retried when nil
+	RetryIf func(error) bool
+	// OnRetry, if set, is called with the number of the failed attempt, starting at 1, before waiting delay to retry
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// backoff returns the wait after the given failed attempt, a random duration up to the exponentially growing longest wait, following the
+// full jitter algorithm so that clients failing together don't retry together
+func (o RetryOptions) backoff(attempt int) time.Duration {
+	multiplier := o.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+	ceiling := float64(o.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if o.MaxDelay > 0 && ceiling > float64(o.MaxDelay) {
+		ceiling = float64(o.MaxDelay)
+	}
+	// the float overflows int64 after enough attempts without MaxDelay, and a float doesn't hold MaxInt64 exactly
+	if ceiling > math.MaxInt64/2 {
+		ceiling = math.MaxInt64 / 2
+	}
+	if ceiling < 1 {
+		return 0
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitter.Int63n(int64(ceiling) + 1))
+}
+
+// RetryWithBackoff calls op until it succeeds, returns an error RetryIf rejects or MaxAttempts is reached, and returns the result of the last
+// call. The waits between the attempts grow exponentially with full jitter. It returns the error of the context if it's done before op succeeds
+func RetryWithBackoff[T any](ctx context.Context, op func() (T, error), opts RetryOptions) (T, error) {
+	var zero T
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		result, err := op()
+		if err == nil {
+			return result, nil
+		}
+		if (opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts) || (opts.RetryIf != nil && !opts.RetryIf(err)) {
+			return result, err
+		}
+		delay := opts.backoff(attempt)
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+// TestRetryWithBackoffAttempts ensures the operation is called until it succeeds or MaxAttempts is reached
+func TestRetryWithBackoffAttempts(t *testing.T) {
+	calls := 0
+	result, err := RetryWithBackoff(context.Background(), func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errTransient
+		}
+		return 42, nil
+	}, RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond})
+	if err != nil || result != 42 || calls != 3 {
+		t.Errorf("expected 42 after 3 calls, got %d after %d calls: %v", result, calls, err)
+	}
+	calls = 0
+	var retries []int
+	_, err = RetryWithBackoff(context.Background(), func() (string, error) {
+		calls++
+		return "", errTransient
+	}, RetryOptions{MaxAttempts: 4, BaseDelay: time.Millisecond, OnRetry: func(attempt int, delay time.Duration, err error) {
+		retries = append(retries, attempt)
+	}})
+	if !errors.Is(err, errTransient) || calls != 4 {
+		t.Errorf("expected the last error after 4 calls, got %d calls: %v", calls, err)
+	}
+	if len(retries) != 3 || retries[0] != 1 || retries[2] != 3 {
+		t.Errorf("expected OnRetry to be called after the 3 first attempts, got %v", retries)
+	}
+}
+
+// TestRetryWithBackoffDelay ensures the waits are jittered up to the exponentially growing longest wait, capped by MaxDelay
+func TestRetryWithBackoffDelay(t *testing.T) {
+	opts := RetryOptions{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 3}
+	for attempt, ceiling := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 30 * time.Millisecond, 3: 50 * time.Millisecond, 60: 50 * time.Millisecond} {
+		var longest time.Duration
+		for i := 0; i < 1000; i++ {
+			delay := opts.backoff(attempt)
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("expected the wait after attempt %d to be at most %v, got %v", attempt, ceiling, delay)
+			}
+			if delay > longest {
+				longest = delay
+			}
+		}
+		// the waits are spread over the whole range
+		if longest < ceiling/2 {
+			t.Errorf("expected waits close to %v after attempt %d, the longest was %v", ceiling, attempt, longest)
+		}
+	}
+	if delay := (RetryOptions{BaseDelay: time.Second}).backoff(200); delay < 0 {
+		t.Errorf("expected the wait without MaxDelay not to overflow, got %v", delay)
+	}
+	// the total wait of 3 retries is at most 10+20+40ms with the default multiplier
+	start := time.Now()
+	RetryWithBackoff(context.Background(), func() (struct{}, error) {
+		return struct{}{}, errTransient
+	}, RetryOptions{MaxAttempts: 4, BaseDelay: 10 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > 70*time.Millisecond+100*time.Millisecond {
+		t.Errorf("expected the retries to take at most 70ms, took %v", elapsed)
+	}
+}
+
+// TestRetryWithBackoffRetryIf ensures errors rejected by RetryIf are returned right away
+func TestRetryWithBackoffRetryIf(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	calls := 0
+	_, err := RetryWithBackoff(context.Background(), func() (int, error) {
+		calls++
+		if calls == 2 {
+			return 0, errPermanent
+		}
+		return 0, errTransient
+	}, RetryOptions{BaseDelay: time.Millisecond, RetryIf: func(err error) bool { return errors.Is(err, errTransient) }})
+	if !errors.Is(err, errPermanent) || calls != 2 {
+		t.Errorf("expected the permanent error after 2 calls, got %d calls: %v", calls, err)
+	}
+}
+
+// TestRetryWithBackoffContext ensures the retries stop once the context is done
+func TestRetryWithBackoffContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := RetryWithBackoff(ctx, func() (int, error) {
+		calls++
+		cancel()
+		return 0, errTransient
+	}, RetryOptions{BaseDelay: time.Hour})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("expected context.Canceled after 1 call, got %d calls: %v", calls, err)
+	}
+	if _, err = RetryWithBackoff(ctx, func() (int, error) {
+		t.Error("expected the operation not to be called with a done context")
+		return 0, nil
+	}, RetryOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}